@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aretw0/trellis/internal/cli"
+	"github.com/spf13/cobra"
+)
+
+var debugCmd = &cobra.Command{
+	Use:   "debug [dir]",
+	Short: "Step through the flow with breakpoints, context editing and fake tool results",
+	Long: `Starts an interactive step debugger on the flow, paused at the entry node.
+
+Set breakpoints on node IDs, tool names or conditions; move with step, next and continue;
+inspect and edit the context and system context at a stop; evaluate conditions and
+templates against the current state; and inject a fake tool result instead of running
+the tool. Type 'help' at the prompt for the commands.
+
+Tools are executed from the same registry as 'run'. The same commands are available over
+HTTP with 'trellis serve --debugger' (POST /debug/sessions).`,
+	Example: `  trellis debug ./flow --break pay --break "tool charge"
+  trellis debug ./flow --context '{"plan": "pro"}' --break "if input == 'cancel'"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		if !cmd.Flags().Changed("dir") && len(args) > 0 {
+			dir = args[0]
+		}
+		strict, _ := cmd.Flags().GetBool("strict")
+		contextStr, _ := cmd.Flags().GetString("context")
+		toolsPath, _ := cmd.Flags().GetString("tools")
+		unsafeInline, _ := cmd.Flags().GetBool("unsafe-inline")
+		breakpoints, _ := cmd.Flags().GetStringArray("break")
+
+		err := cli.Debug(cmd.Context(), cli.DebugOptions{
+			RepoPath:     dir,
+			Strict:       strict,
+			Context:      contextStr,
+			ToolsPath:    toolsPath,
+			UnsafeInline: unsafeInline,
+			Breakpoints:  breakpoints,
+		}, os.Stdin, os.Stdout)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(debugCmd)
+	debugCmd.Flags().StringArray("break", nil, "Breakpoint set before starting: <node>, 'tool <name>' or 'if <condition>' (repeatable)")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/pkg/adapters/loam"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [dir]",
+	Short: "Write the flow back to node files or a single-file flow",
+	Long: `Loads the flow (a directory, single-file flow, .trellis bundle or --rev) and writes the
+resolved graph to --output: a directory of node files, or a single file when the output
+ends in .yaml, .yml, .json or .md. Templates and _defaults are already applied; tool sets
+shared by several nodes become a '_tools' library. Loading the export yields the same graph.
+
+Existing files are never overwritten. Package nodes (pkg:) cannot be exported.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		repoPath, _ := cmd.Flags().GetString("dir")
+		if !cmd.Flags().Changed("dir") && len(args) > 0 {
+			repoPath = args[0]
+		}
+		out, _ := cmd.Flags().GetString("output")
+		if out == "" {
+			fmt.Println("Error: --output is required")
+			os.Exit(1)
+		}
+
+		var opts []trellis.Option
+		if rev, _ := cmd.Flags().GetString("rev"); rev != "" {
+			opts = append(opts, trellis.WithRevision(rev))
+		}
+		engine, err := trellis.New(repoPath, opts...)
+		if err != nil {
+			fmt.Printf("Error initializing trellis: %v\n", err)
+			os.Exit(1)
+		}
+		nodes, err := engine.Inspect()
+		if err != nil {
+			fmt.Printf("Error inspecting graph: %v\n", err)
+			os.Exit(1)
+		}
+
+		exportOpts := []loam.ExportOption{loam.WithExportEntry(engine.EntryNode())}
+		if m := engine.Manifest(); m != nil && m.Name != "" {
+			exportOpts = append(exportOpts, loam.WithExportName(m.Name))
+		}
+		switch strings.ToLower(filepath.Ext(out)) {
+		case ".yaml", ".yml", ".json", ".md":
+			err = loam.ExportFile(out, nodes, exportOpts...)
+		default:
+			err = loam.ExportDir(out, nodes, exportOpts...)
+		}
+		if err != nil {
+			fmt.Printf("Error exporting graph: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("exported %d nodes to %s\n", len(nodes), out)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringP("output", "o", "", "Output directory, or flow file (.yaml, .yml, .json, .md)")
+}
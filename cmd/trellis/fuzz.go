@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aretw0/trellis/internal/cli"
+	"github.com/aretw0/trellis/internal/fuzz"
+	"github.com/spf13/cobra"
+)
+
+var fuzzCmd = &cobra.Command{
+	Use:   "fuzz [dir]",
+	Short: "Explore the flow's state space looking for crashes and dead ends",
+	Long: `Drives the flow from its entry node through every input it can derive from the graph
+(choice options, yes/no for confirms, the literals of input conditions, defaults and signals)
+and every tool outcome (success, error, denial), without executing tools. States are
+deduplicated by node and context, so loops are explored once.
+
+Reports panics, render and navigation errors, tool errors no node handles, states from which
+the flow can no longer end, loops that never ask for input, and paths that exhaust the flow
+budget. With --out, each finding's shortest path is written as a 'trellis test' scenario.
+
+Exits with 1 when there are findings.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		if !cmd.Flags().Changed("dir") && len(args) > 0 {
+			dir = args[0]
+		}
+		strict, _ := cmd.Flags().GetBool("strict")
+		contextStr, _ := cmd.Flags().GetString("context")
+		strategy, _ := cmd.Flags().GetString("strategy")
+		maxDepth, _ := cmd.Flags().GetInt("max-depth")
+		maxStates, _ := cmd.Flags().GetInt("max-states")
+		inputs, _ := cmd.Flags().GetStringArray("input")
+		out, _ := cmd.Flags().GetString("out")
+		format, _ := cmd.Flags().GetString("format")
+
+		report, err := cli.Fuzz(cmd.Context(), cli.FuzzOptions{
+			RepoPath:  dir,
+			Strict:    strict,
+			Strategy:  strategy,
+			MaxDepth:  maxDepth,
+			MaxStates: maxStates,
+			Context:   contextStr,
+			Inputs:    inputs,
+			Out:       out,
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := fuzz.Write(os.Stdout, format, report); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(report.Findings) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fuzzCmd)
+	fuzzCmd.Flags().String("strategy", fuzz.BreadthFirst, "Exploration order: bfs (shortest paths first) or dfs")
+	fuzzCmd.Flags().Int("max-depth", fuzz.DefaultMaxDepth, "Maximum inputs, signals and tool outcomes along a path")
+	fuzzCmd.Flags().Int("max-states", fuzz.DefaultMaxStates, "Maximum distinct states explored")
+	fuzzCmd.Flags().StringArray("input", nil, "Extra input tried at open-ended prompts (repeatable)")
+	fuzzCmd.Flags().String("out", "", "Write the reproducers of the findings to this scenario file (e.g. fuzz.test.yaml)")
+	fuzzCmd.Flags().String("format", "text", "Output format: text or json")
+}
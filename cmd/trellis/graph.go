@@ -18,14 +18,19 @@ import (
 var graphCmd = &cobra.Command{
 	Use:   "graph",
 	Short: "Export the flow graph visualization",
-	Long:  `Inspects the repository and outputs a Mermaid diagram (graph TD) representing the flow logic.`,
+	Long: `Inspects the repository and outputs a Mermaid diagram (graph TD) representing the flow logic.
+With --resolved, prints the effective definition of each node instead (after extends and _defaults).`,
 	Run: func(cmd *cobra.Command, args []string) {
 		repoPath, _ := cmd.Flags().GetString("dir")
 		if !cmd.Flags().Changed("dir") && len(args) > 0 {
 			repoPath = args[0]
 		}
 
-		engine, err := trellis.New(repoPath)
+		var opts []trellis.Option
+		if rev, _ := cmd.Flags().GetString("rev"); rev != "" {
+			opts = append(opts, trellis.WithRevision(rev))
+		}
+		engine, err := trellis.New(repoPath, opts...)
 		if err != nil {
 			fmt.Printf("Error initializing trellis: %v\n", err)
 			os.Exit(1)
@@ -37,6 +42,17 @@ var graphCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if resolved, _ := cmd.Flags().GetBool("resolved"); resolved {
+			ids, _ := cmd.Flags().GetStringSlice("node")
+			output, err := graph.GenerateResolved(nodes, ids...)
+			if err != nil {
+				fmt.Printf("Error resolving nodes: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(output)
+			return
+		}
+
 		var overlay *graph.GraphOverlay
 		sessionID, _ := cmd.Flags().GetString("session")
 		if sessionID != "" {
@@ -67,4 +83,6 @@ var graphCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(graphCmd)
 	graphCmd.Flags().String("session", "", "Overlay session state (history & current node) on the graph")
+	graphCmd.Flags().Bool("resolved", false, "Print the effective node definitions (after extends and _defaults) instead of the diagram")
+	graphCmd.Flags().StringSlice("node", nil, "With --resolved, only print these node IDs")
 }
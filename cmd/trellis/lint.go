@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aretw0/trellis/internal/cli"
+	"github.com/aretw0/trellis/internal/lint"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [dir]",
+	Short: "Run the static analyzer over the flow",
+	Long: `Loads every node and reports problems by rule: dangling references in any edge kind,
+unreachable nodes, dead ends, template keys no node writes, save_to into sys, tool nodes
+without do, do+wait conflicts, invalid timeouts and calls to tools missing from the registry.
+
+Silence a finding with a comment in the node source, on the line above the node or inside it:
+  # trellis:ignore unreachable
+  <!-- trellis:ignore undefined-key,dead-end -->
+Without rule IDs, every rule is silenced for that node.
+
+Exits with 1 when a finding is at least as severe as --fail-on.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		if !cmd.Flags().Changed("dir") && len(args) > 0 {
+			dir = args[0]
+		}
+		toolsPath, _ := cmd.Flags().GetString("tools")
+		strict, _ := cmd.Flags().GetBool("strict")
+		rev, _ := cmd.Flags().GetString("rev")
+		format, _ := cmd.Flags().GetString("format")
+		disable, _ := cmd.Flags().GetStringSlice("disable")
+		failOn, _ := cmd.Flags().GetString("fail-on")
+
+		minSeverity, err := lint.ParseSeverity(failOn)
+		if err != nil {
+			fmt.Printf("Error: --fail-on: %v\n", err)
+			os.Exit(1)
+		}
+
+		findings, err := cli.Lint(cli.LintOptions{RepoPath: dir, ToolsPath: toolsPath, Strict: strict, Rev: rev, Disable: disable})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := lint.Write(os.Stdout, format, findings); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if lint.HasSeverity(findings, minSeverity) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().String("format", "text", "Output format: text, json or sarif")
+	lintCmd.Flags().StringSlice("disable", nil, "Rule IDs to turn off (comma-separated)")
+	lintCmd.Flags().String("fail-on", "error", "Lowest severity that makes the command fail: error, warning or info")
+}
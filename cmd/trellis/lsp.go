@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aretw0/trellis/internal/cli"
+	"github.com/aretw0/trellis/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp [dir]",
+	Short: "Run the Language Server Protocol (LSP) server for flow files",
+	Long: `Starts a language server over Standard Input/Output for editors.
+
+Features:
+- Diagnostics: the findings of 'trellis lint', refreshed on every save.
+- Completion: node IDs after to/jump_to/on_error/on_denied/on_signal, tool names after
+  name, and context keys in templates, save_to and required_context.
+- Go to definition, find references and rename for node IDs. Renaming a node named by
+  its file renames the file.
+- Hover: the node as loaded, after extends, macros and sugar.
+
+The flow directory is the editor workspace, unless --dir or an argument is given.
+Logs go to Standard Error.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var root string
+		if cmd.Flags().Changed("dir") {
+			root, _ = cmd.Flags().GetString("dir")
+		} else if len(args) > 0 {
+			root = args[0]
+		}
+		toolsPath, _ := cmd.Flags().GetString("tools")
+		strict, _ := cmd.Flags().GetBool("strict")
+
+		// Stdout is the protocol stream
+		logger := logging.New(slog.LevelInfo)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		opts := cli.LSPOptions{RepoPath: root, ToolsPath: toolsPath, Strict: strict}
+		if err := cli.ServeLSP(ctx, opts, os.Stdin, os.Stdout, logger); err != nil && ctx.Err() == nil {
+			logger.Error("LSP server failed", "error", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/aretw0/trellis"
 	"github.com/aretw0/trellis/internal/logging"
 	"github.com/aretw0/trellis/pkg/adapters/mcp"
+	"github.com/aretw0/trellis/pkg/manifest"
 	"github.com/spf13/cobra"
 )
 
@@ -34,9 +35,21 @@ Supported Transports:
 		transport, _ := cmd.Flags().GetString("transport")
 		port, _ := cmd.Flags().GetInt("port")
 
+		// Project manifest (trellis.yaml): flags take precedence
+		m, err := manifest.Find(repoPath)
+		if err != nil {
+			log.Fatalf("Invalid manifest: %v", err)
+		}
+		if !cmd.Flags().Changed("port") && m.Server.Port > 0 {
+			port = m.Server.Port
+		}
+		if strict, _ := cmd.Flags().GetBool("strict"); strict {
+			m.Strict = true
+		}
+
 		// 1. Initialize Engine
 		// Use ReadOnly mode implicitly via trellis.New (which sets Loam to ReadOnly)
-		engine, err := trellis.New(repoPath)
+		engine, err := trellis.New(repoPath, trellis.WithManifest(m))
 		if err != nil {
 			log.Fatalf("Error initializing trellis: %v", err)
 		}
@@ -47,6 +60,7 @@ Supported Transports:
 
 		// 2. Initialize MCP Server Adapter
 		srv := mcp.NewServer(engine, engine.Loader())
+		srv.MaxInputSize = m.Server.MaxInputSize
 
 		// 3. Start Server based on Transport
 		switch transport {
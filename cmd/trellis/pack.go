@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aretw0/trellis/internal/cli"
+	"github.com/aretw0/trellis/pkg/bundle"
+	"github.com/spf13/cobra"
+)
+
+var packCmd = &cobra.Command{
+	Use:   "pack [dir]",
+	Short: "Pack the flow into a single .trellis bundle",
+	Long: `Compiles the flow (templates, _defaults and packages resolved) into a reproducible
+archive with the manifest and tool configuration, identified by a content hash.
+
+With --key, the bundle is signed with an ed25519 private key (PEM), e.g. created with:
+  openssl genpkey -algorithm ed25519 -out trellis.key
+  openssl pkey -in trellis.key -pubout -out trellis.pub
+
+Run it with 'trellis run <name>.trellis'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		if !cmd.Flags().Changed("dir") && len(args) > 0 {
+			dir = args[0]
+		}
+		toolsPath, _ := cmd.Flags().GetString("tools")
+		strict, _ := cmd.Flags().GetBool("strict")
+		keyPath, _ := cmd.Flags().GetString("key")
+		out, _ := cmd.Flags().GetString("output")
+
+		b, err := cli.Pack(cli.PackOptions{RepoPath: dir, ToolsPath: toolsPath, KeyPath: keyPath, Strict: strict})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if out == "" {
+			name := b.Name
+			if name == "" {
+				name = "flow"
+			}
+			out = name + bundle.Extension
+		}
+		if err := writeBundle(b, out); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("packed %d nodes into %s\n", len(b.NodeIDs()), out)
+		fmt.Printf("hash: %s\n", b.Hash)
+		if b.Signature != nil {
+			fmt.Printf("signed by key %s\n", b.Signature.KeyID)
+		}
+	},
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <bundle>",
+	Short: "Check the integrity and signature of a .trellis bundle",
+	Long: `Verifies that the bundle content matches its hash. With --trusted-keys (or
+TRELLIS_TRUSTED_KEYS), also requires a valid signature from one of the trusted keys.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		b, err := cli.OpenBundle(args[0])
+		if err != nil {
+			fmt.Printf("Verification failed: %v\n", err)
+			os.Exit(1)
+		}
+
+		label := b.Name
+		if b.Version != "" {
+			label += "@" + b.Version
+		}
+		fmt.Printf("%s: %d nodes, entry %s\n", strings.TrimPrefix(label, "@"), len(b.NodeIDs()), b.Entry)
+		fmt.Printf("hash: %s\n", b.Hash)
+		if b.Signature == nil {
+			fmt.Println("unsigned")
+		} else {
+			fmt.Printf("signed by key %s\n", b.Signature.KeyID)
+		}
+		fmt.Println("Bundle is intact ✅")
+	},
+}
+
+// writeBundle writes the archive atomically, so a failed pack never leaves a truncated bundle.
+func writeBundle(b *bundle.Bundle, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pack-*")
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+
+	if _, err := b.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func init() {
+	rootCmd.AddCommand(packCmd)
+	rootCmd.AddCommand(verifyCmd)
+	packCmd.Flags().StringP("output", "o", "", "Bundle file (default: <name>.trellis)")
+	packCmd.Flags().String("key", "", "Sign the bundle with this ed25519 private key (PEM)")
+}
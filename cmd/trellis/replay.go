@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aretw0/trellis/internal/cli"
+	"github.com/aretw0/trellis/pkg/transcript"
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <transcript>",
+	Short: "Re-run a recorded session against the current flow and report divergences",
+	Long: `Replays a transcript recorded with 'trellis run --transcript' against the flow in --dir,
+starting from the recorded state and feeding the recorded inputs, signals and tool results
+(tools are never executed).
+
+Every step is compared with the recording: the node reached, its status, the rendered
+content, the prompts, the tool calls and their arguments, engine errors, where the flow
+ends and the final context. Content and argument changes are reported and the replay goes
+on; when the recorded moves no longer fit (another node, a prompt that appeared or went
+away, other tools), the replay stops there.
+
+Exits with 1 when there are divergences.`,
+	Example: `  trellis run ./flow --transcript run.jsonl
+  trellis replay run.jsonl --dir ./flow`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		strict, _ := cmd.Flags().GetBool("strict")
+		format, _ := cmd.Flags().GetString("format")
+
+		report, err := cli.Replay(cmd.Context(), cli.ReplayOptions{
+			RepoPath:   dir,
+			Strict:     strict,
+			Transcript: args[0],
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := transcript.WriteReport(os.Stdout, format, report); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !report.OK() {
+			os.Exit(1)
+		}
+	},
+}
+
+var transcriptCmd = &cobra.Command{
+	Use:   "transcript <transcript>",
+	Short: "Export a recorded session as a readable conversation log",
+	Long: `Turns a transcript recorded with 'trellis run --transcript' into a Markdown or HTML
+conversation log: what each node showed, the answers, signals and tool calls with their
+results and timings, and the final context. For sessions recorded without a transcript,
+see 'trellis session export'.`,
+	Example: `  trellis transcript run.jsonl > run.md
+  trellis transcript run.jsonl --format html --out run.html`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		t, err := transcript.Load(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := writeLog(cmd, t); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// writeLog exports t in the --format of the command, to --out or stdout.
+func writeLog(cmd *cobra.Command, t *transcript.Transcript) error {
+	format, _ := cmd.Flags().GetString("format")
+	out, _ := cmd.Flags().GetString("out")
+	if out == "" {
+		return transcript.Export(os.Stdout, format, t)
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := transcript.Export(f, format, t); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().String("format", "text", "Output format: text or json")
+
+	rootCmd.AddCommand(transcriptCmd)
+	transcriptCmd.Flags().String("format", transcript.FormatMarkdown, "Log format: markdown or html")
+	transcriptCmd.Flags().String("out", "", "Write the log to this file instead of stdout")
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"os"
+
+	"github.com/aretw0/trellis/pkg/bundle"
 	"github.com/spf13/cobra"
 )
 
@@ -9,6 +12,13 @@ var rootCmd = &cobra.Command{
 	Short: "Trellis is a state machine based documentation engine",
 	Long:  `Trellis allows you to build interactive documentation flows using simple Markdown files.`,
 	Args:  cobra.ArbitraryArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// The trust policy is read from the environment by every bundle loader.
+		if keys, _ := cmd.Flags().GetString("trusted-keys"); keys != "" {
+			return os.Setenv(bundle.TrustedKeysEnv, keys)
+		}
+		return nil
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -29,4 +39,14 @@ func init() {
 	rootCmd.PersistentFlags().String("redis-url", "", "Redis connection URL (e.g. redis://localhost:6379) for distributed state & locking")
 	rootCmd.PersistentFlags().String("tools", "tools.yaml", "Path to the tool registry file")
 	rootCmd.PersistentFlags().Bool("unsafe-inline", false, "Allow inline execution of scripts defined in Markdown (Dangerous)")
+	rootCmd.PersistentFlags().Bool("strict", false, "Reject unknown node keys and mistyped values (file:line diagnostics)")
+	rootCmd.PersistentFlags().String("rev", "", "Load the flow directory as of a git revision (commit, tag or branch) without checking it out")
+	rootCmd.PersistentFlags().String("trusted-keys", "", "PEM files with the public keys .trellis bundles must be signed with (default: $TRELLIS_TRUSTED_KEYS)")
+	rootCmd.PersistentFlags().String("budget", "", "Per-session tool budget (e.g. 'cost=1.5,tokens=20000,calls=10')")
+	rootCmd.PersistentFlags().String("transcript", "", "Record the session (content, prompts, inputs, tool calls, timings) as a JSON Lines transcript in this file")
+	rootCmd.PersistentFlags().String("answers", "", "Answer the prompts from this YAML/JSON file (keyed by node ID or save_to key) instead of stdin")
+	rootCmd.PersistentFlags().Bool("fail-unanswered", false, "With --answers or --batch, fail at a prompt nothing answers instead of sending an empty answer")
+	rootCmd.PersistentFlags().String("batch", "", "Run the flow once per record of this CSV or JSONL file (records are merged into the context and answer prompts)")
+	rootCmd.PersistentFlags().String("results", "", "With --batch, write the final status and context of each record to this JSONL file (default: stdout)")
+	rootCmd.PersistentFlags().Int("parallel", 4, "With --batch, number of records run at once")
 }
@@ -14,7 +14,7 @@ import (
 var runCmd = &cobra.Command{
 	Use:   "run",
 	Short: "Run the interactive documentation flow",
-	Long:  `Starts the Trellis engine in interactive mode with the content from the current directory.`,
+	Long:  `Starts the Trellis engine in interactive mode with the content from the current directory, from a single flow file (e.g. trellis run flow.yaml) or from a bundle built with 'trellis pack' (e.g. trellis run support.trellis).`,
 	Run: func(cmd *cobra.Command, args []string) {
 		repoPath, _ := cmd.Flags().GetString("dir")
 		if !cmd.Flags().Changed("dir") && len(args) > 0 {
@@ -30,6 +30,21 @@ var runCmd = &cobra.Command{
 		redisURL, _ := cmd.Flags().GetString("redis-url")
 		toolsPath, _ := cmd.Flags().GetString("tools")
 		unsafeInline, _ := cmd.Flags().GetBool("unsafe-inline")
+		budgetSpec, _ := cmd.Flags().GetString("budget")
+		strict, _ := cmd.Flags().GetBool("strict")
+		rev, _ := cmd.Flags().GetString("rev")
+		transcriptPath, _ := cmd.Flags().GetString("transcript")
+		answersPath, _ := cmd.Flags().GetString("answers")
+		failUnanswered, _ := cmd.Flags().GetBool("fail-unanswered")
+		batchPath, _ := cmd.Flags().GetString("batch")
+		resultsPath, _ := cmd.Flags().GetString("results")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+
+		budget, err := cli.ParseBudget(budgetSpec)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 
 		opts := cli.RunOptions{
 			RepoPath:     repoPath,
@@ -43,6 +58,16 @@ var runCmd = &cobra.Command{
 			RedisURL:     redisURL,
 			ToolsPath:    toolsPath,
 			UnsafeInline: unsafeInline,
+			Budget:       budget,
+			Strict:       strict,
+			Rev:          rev,
+			Transcript:   transcriptPath,
+
+			Answers:        answersPath,
+			FailUnanswered: failUnanswered,
+			Batch:          batchPath,
+			Results:        resultsPath,
+			Parallel:       parallel,
 		}
 
 		var lifecycleOpts []any
@@ -52,7 +77,7 @@ var runCmd = &cobra.Command{
 		// Set ForceExit to 0: We give Trellis (via InteractiveRouter) full control over the exit strategy.
 		lifecycleOpts = append(lifecycleOpts, lifecycle.WithForceExit(0))
 
-		err := lifecycle.Run(lifecycle.Job(func(ctx context.Context) error {
+		err = lifecycle.Run(lifecycle.Job(func(ctx context.Context) error {
 			return cli.Execute(ctx, opts)
 		}), lifecycleOpts...)
 
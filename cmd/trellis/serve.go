@@ -6,15 +6,30 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/aretw0/lifecycle"
 	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/internal/cli"
 	"github.com/aretw0/trellis/internal/logging"
+	"github.com/aretw0/trellis/pkg/adapters/git"
 	httpAdapter "github.com/aretw0/trellis/pkg/adapters/http"
+	"github.com/aretw0/trellis/pkg/adapters/openai"
+	"github.com/aretw0/trellis/pkg/debugger"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/intent"
+	"github.com/aretw0/trellis/pkg/manifest"
+	"github.com/aretw0/trellis/pkg/observability"
+	"github.com/aretw0/trellis/pkg/session"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
 )
 
+// asyncExpiryInterval is how often 'serve' resolves async tool calls past their deadline.
+const asyncExpiryInterval = 10 * time.Second
+
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Start the stateless HTTP server",
@@ -33,12 +48,86 @@ var serveCmd = &cobra.Command{
 			logger := logging.New(level)
 			slog.SetDefault(logger)
 
-			engine, err := trellis.New(dir)
+			// Tool usage metrics (exposed at /metrics)
+			registry := prometheus.NewRegistry()
+			usageMetrics, err := observability.NewUsageMetrics(registry)
+			if err != nil {
+				return fmt.Errorf("error registering metrics: %w", err)
+			}
+
+			// Project manifest (trellis.yaml): flags take precedence.
+			// With --rev, the flow and its manifest are read from that git revision.
+			rev, _ := cmd.Flags().GetString("rev")
+			var snap *git.Snapshot
+			var m *manifest.Manifest
+			if rev != "" {
+				snap, m, err = cli.OpenRevision(ctx, dir, rev)
+				if err != nil {
+					return err
+				}
+			} else if m, err = manifest.Find(dir); err != nil {
+				return fmt.Errorf("invalid manifest: %w", err)
+			}
+			if !cmd.Flags().Changed("port") && m.Server.Port > 0 {
+				port = strconv.Itoa(m.Server.Port)
+			}
+			if strict, _ := cmd.Flags().GetBool("strict"); strict {
+				m.Strict = true
+			}
+
+			engineOpts := []trellis.Option{
+				trellis.WithManifest(m),
+				trellis.WithLifecycleHooks(usageMetrics.Hooks()),
+			}
+			if snap != nil {
+				engineOpts = append(engineOpts, trellis.WithFS(snap), trellis.WithRevision(snap.Commit))
+			}
+			if provider, ok := openai.FromEnv(); ok {
+				engineOpts = append(engineOpts, trellis.WithModelProvider(provider))
+				// Route nodes fall back to the model when the built-in rules are unsure.
+				engineOpts = append(engineOpts, trellis.WithIntentClassifier(
+					intent.Chain(domain.DefaultMinConfidence, intent.Default(), intent.LLM(provider))))
+			}
+
+			budgetSpec, _ := cmd.Flags().GetString("budget")
+			budget, err := cli.ParseBudget(budgetSpec)
+			if err != nil {
+				return err
+			}
+			if !budget.IsZero() {
+				engineOpts = append(engineOpts, trellis.WithBudget(budget))
+			}
+
+			engine, err := trellis.New(dir, engineOpts...)
 			if err != nil {
 				return fmt.Errorf("error initializing trellis: %w", err)
 			}
 
-			handler := httpAdapter.NewHandler(engine)
+			// Durable sessions back async tool completion (POST /sessions/{id}/tool-results),
+			// pinned to the served revision so sessions from another --rev are not resumed here.
+			redisURL, _ := cmd.Flags().GetString("redis-url")
+			sessions := cli.NewDurableSessionManager(cli.StoreConfig(redisURL, m), logger,
+				session.WithEngine(engine), session.WithRevision(engine.Revision()))
+
+			handlerOpts := []httpAdapter.HandlerOption{
+				httpAdapter.WithMetricsHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})),
+				httpAdapter.WithSessionManager(sessions),
+				httpAdapter.WithMaxInputSize(m.Server.MaxInputSize),
+			}
+
+			// Step debugger (/debug routes): sessions run the tools of the registry, like 'run'
+			if enabled, _ := cmd.Flags().GetBool("debugger"); enabled {
+				toolsPath, _ := cmd.Flags().GetString("tools")
+				unsafeInline, _ := cmd.Flags().GetBool("unsafe-inline")
+				tools, err := cli.NewToolRunner(cli.RunOptions{RepoPath: dir, Manifest: m, ToolsPath: toolsPath, UnsafeInline: unsafeInline})
+				if err != nil {
+					return err
+				}
+				handlerOpts = append(handlerOpts, httpAdapter.WithDebugger(debugger.NewManager(engine, debugger.WithSessionOptions(debugger.WithToolRunner(tools)))))
+				logger.Warn("Debugger enabled: /debug routes can edit sessions and run tools; do not expose this server")
+			}
+
+			handler := httpAdapter.NewHandler(engine, handlerOpts...)
 
 			srv := &http.Server{
 				Addr:    ":" + port,
@@ -55,6 +144,24 @@ var serveCmd = &cobra.Command{
 				return nil
 			})
 
+			// Async tool calls past their deadline resume even if the Host never answers.
+			lifecycle.Go(ctx, func(ctx context.Context) error {
+				ticker := time.NewTicker(asyncExpiryInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						return nil
+					case <-ticker.C:
+						if n, err := sessions.ExpireAll(ctx); err != nil {
+							logger.Warn("Async expiry sweep failed", "err", err)
+						} else if n > 0 {
+							logger.Info("Expired async tool calls", "sessions", n)
+						}
+					}
+				}
+			})
+
 			// Blocking wait for shutdown or error.
 			select {
 			case err := <-serverErrors:
@@ -90,4 +197,5 @@ var serveCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(serveCmd)
 	serveCmd.Flags().StringP("port", "p", "8080", "Port to listen on")
+	serveCmd.Flags().Bool("debugger", false, "Enable the step debugger routes (/debug/sessions) for the web inspector (development only)")
 }
@@ -5,8 +5,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/aretw0/trellis"
 	"github.com/aretw0/trellis/pkg/adapters/file"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/manifest"
+	"github.com/aretw0/trellis/pkg/transcript"
 	"github.com/spf13/cobra"
 )
 
@@ -53,6 +60,21 @@ var sessionInspectCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if showUsage, _ := cmd.Flags().GetBool("usage"); showUsage {
+			printUsageReport(state)
+			return
+		}
+		if showHistory, _ := cmd.Flags().GetBool("history"); showHistory {
+			dir, _ := cmd.Flags().GetString("dir")
+			engine, err := trellis.New(dir)
+			if err != nil {
+				fmt.Printf("Error loading flow: %v\n", err)
+				os.Exit(1)
+			}
+			printHistory(engine.Trace(state))
+			return
+		}
+
 		// Pretty print JSON
 		data, err := json.MarshalIndent(state, "", "  ")
 		if err != nil {
@@ -64,6 +86,27 @@ var sessionInspectCmd = &cobra.Command{
 	},
 }
 
+var sessionExportCmd = &cobra.Command{
+	Use:   "export <session-id>",
+	Short: "Export a session as a readable log",
+	Long: `Writes a persisted session as a Markdown or HTML log. Stores keep only the visited nodes
+and the current state; record a transcript with 'trellis run --transcript' for the full
+conversation (content, inputs, tool results, timings) and export it with 'trellis transcript'.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sessionID := args[0]
+		state, err := getStore(cmd).Load(cmd.Context(), sessionID)
+		if err != nil {
+			fmt.Printf("Error loading session '%s': %v\n", sessionID, err)
+			os.Exit(1)
+		}
+		if err := writeLog(cmd, transcript.FromState(state)); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
 var sessionRmCmd = &cobra.Command{
 	Use:   "rm <session-id>...",
 	Short: "Remove one or more sessions",
@@ -94,6 +137,72 @@ func init() {
 	sessionCmd.AddCommand(sessionLsCmd)
 	sessionCmd.AddCommand(sessionInspectCmd)
 	sessionCmd.AddCommand(sessionRmCmd)
+	sessionCmd.AddCommand(sessionExportCmd)
+	sessionExportCmd.Flags().String("format", transcript.FormatMarkdown, "Log format: markdown or html")
+	sessionExportCmd.Flags().String("out", "", "Write the log to this file instead of stdout")
+
+	sessionInspectCmd.Flags().Bool("usage", false, "Print the tool usage/cost report instead of the raw state")
+	sessionInspectCmd.Flags().Bool("history", false, "Print the visited nodes with their source (file or macro line) instead of the raw state")
+}
+
+// printHistory lists the visited nodes with where they were authored, so generated
+// macro steps ("checkout#ask-name") point back to their script line.
+func printHistory(steps []domain.TraceStep) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tNODE\tSOURCE")
+	for i, step := range steps {
+		src := "-"
+		if step.Source != nil {
+			src = step.Source.String()
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", i+1, step.NodeID, src)
+	}
+	_ = w.Flush()
+}
+
+// printUsageReport renders the accumulated tool usage and the remaining flow budget.
+func printUsageReport(state *domain.State) {
+	report := domain.UsageFromState(state)
+
+	fmt.Printf("Session: %s\n\n", state.SessionID)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TOOL\tCALLS\tTOKENS\tCOST\tUNITS")
+	names := make([]string, 0, len(report.Tools))
+	for name := range report.Tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := report.Tools[name]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.4f\t%g\n", name, t.Calls, t.Tokens, t.Cost, t.Units)
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t%d\t%.4f\t%g\n", report.Calls, report.Tokens, report.Cost, report.Units)
+	_ = w.Flush()
+
+	if budget := domain.BudgetFromState(state); !budget.IsZero() {
+		fmt.Printf("\nFlow budget: %s\n", formatBudget(budget))
+		if exhausted, reason := budget.Exhausted(report.UsageTotals); exhausted {
+			fmt.Printf("Status: %s\n", reason)
+		}
+	}
+}
+
+func formatBudget(b domain.Budget) string {
+	parts := []string{}
+	if b.MaxCalls > 0 {
+		parts = append(parts, fmt.Sprintf("calls=%d", b.MaxCalls))
+	}
+	if b.MaxTokens > 0 {
+		parts = append(parts, fmt.Sprintf("tokens=%d", b.MaxTokens))
+	}
+	if b.MaxCost > 0 {
+		parts = append(parts, fmt.Sprintf("cost=%g", b.MaxCost))
+	}
+	if b.MaxUnits > 0 {
+		parts = append(parts, fmt.Sprintf("units=%g", b.MaxUnits))
+	}
+	return strings.Join(parts, ", ")
 }
 
 func getStore(cmd *cobra.Command) *file.Store {
@@ -105,5 +214,8 @@ func getStore(cmd *cobra.Command) *file.Store {
 	// FileStore constructs based on basePath.
 	// We want to target <projectDir>/.trellis/sessions
 	storePath := filepath.Join(projectDir, ".trellis", "sessions")
+	if m, err := manifest.Find(projectDir); err == nil && m.Store.Path != "" {
+		storePath = m.Resolve(m.Store.Path)
+	}
 	return file.New(storePath)
 }
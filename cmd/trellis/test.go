@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aretw0/trellis/internal/cli"
+	"github.com/aretw0/trellis/internal/scenario"
+	"github.com/aretw0/trellis/pkg/coverage"
+	"github.com/spf13/cobra"
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test [dir]",
+	Short: "Run the flow's scenario tests (*.test.yaml)",
+	Long: `Finds the scenario files (*.test.yaml) in the flow directory and runs each scenario
+as a session: it starts with the given context, answers input requests with the listed
+inputs and signals, answers tool calls with the mocked results and then checks the visited
+nodes, rendered content (substring, regex or golden file), context values and tool call
+arguments. Tools are never executed.
+
+With --cover, it also reports which nodes, transitions, error handlers and signal handlers
+the scenarios exercised; --coverprofile writes the report as JSON and --cover-graph writes a
+Mermaid graph coloring covered and uncovered edges.
+
+Exits with 1 when a scenario fails.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		if !cmd.Flags().Changed("dir") && len(args) > 0 {
+			dir = args[0]
+		}
+		strict, _ := cmd.Flags().GetBool("strict")
+		run, _ := cmd.Flags().GetString("run")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		update, _ := cmd.Flags().GetBool("update")
+		format, _ := cmd.Flags().GetString("format")
+		cover, _ := cmd.Flags().GetBool("cover")
+		coverProfile, _ := cmd.Flags().GetString("coverprofile")
+		coverGraph, _ := cmd.Flags().GetString("cover-graph")
+
+		results, report, err := cli.Test(cmd.Context(), cli.TestOptions{
+			RepoPath:     dir,
+			Strict:       strict,
+			Run:          run,
+			Parallel:     parallel,
+			Update:       update,
+			Cover:        cover,
+			CoverProfile: coverProfile,
+			CoverGraph:   coverGraph,
+		})
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := scenario.Write(os.Stdout, format, results); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if cover {
+			// The summary goes to stderr with machine-readable formats, to keep stdout parseable.
+			out := os.Stdout
+			if format != "" && format != "text" {
+				out = os.Stderr
+			}
+			if err := coverage.WriteText(out, report); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if scenario.Failed(results) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(testCmd)
+	testCmd.Flags().String("run", "", "Run only the scenarios whose name matches this regular expression")
+	testCmd.Flags().Int("parallel", 4, "Number of scenarios run at once")
+	testCmd.Flags().Bool("update", false, "Rewrite golden files with the rendered content")
+	testCmd.Flags().String("format", "text", "Output format: text, json or junit")
+	testCmd.Flags().Bool("cover", false, "Print node, transition, error handler and signal handler coverage")
+	testCmd.Flags().String("coverprofile", "", "Write the coverage report as JSON to this file")
+	testCmd.Flags().String("cover-graph", "", "Write a Mermaid graph colored by coverage to this file")
+}
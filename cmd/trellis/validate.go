@@ -13,9 +13,12 @@ import (
 var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Check the graph for consistency",
-	Long:  `Crawls the graph starting from 'start' node and reports dead links or unreachable nodes.`,
+	Long: `Crawls the graph starting from the entry node ('start' or the trellis.yaml entry) and reports dead links or unreachable nodes.
+With --strict (or strict: true in trellis.yaml), unknown keys and mistyped values are reported as file:line diagnostics.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := runValidate(args); err != nil {
+		strict, _ := cmd.Flags().GetBool("strict")
+		rev, _ := cmd.Flags().GetString("rev")
+		if err := runValidate(args, strict, rev); err != nil {
 			fmt.Printf("Validation failed: %v\n", err)
 			os.Exit(1)
 		}
@@ -27,7 +30,7 @@ func init() {
 	rootCmd.AddCommand(validateCmd)
 }
 
-func runValidate(args []string) error {
+func runValidate(args []string, strict bool, rev string) error {
 	var dir string
 	var err error
 
@@ -42,16 +45,29 @@ func runValidate(args []string) error {
 
 	// 1. Init Trellis Engine
 	// We use the Engine to handle Loam initialization (which enforces ReadOnly by default).
-	eng, err := trellis.New(dir)
+	var opts []trellis.Option
+	if strict {
+		opts = append(opts, trellis.WithStrict())
+	}
+	if rev != "" {
+		opts = append(opts, trellis.WithRevision(rev))
+	}
+	eng, err := trellis.New(dir, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to init engine: %w", err)
 	}
 
 	// 2. Run Validation
 	// We instantiate a parser to validate node content during traversal.
-	parser := compiler.NewParser()
+	m := eng.Manifest()
+	parser := compiler.NewParser(compiler.WithStrict(strict || (m != nil && m.Strict)))
+
+	entry := "start"
+	if m != nil && m.Entry != "" {
+		entry = m.Entry
+	}
 
-	if err := validator.ValidateGraph(eng.Loader(), parser, "start"); err != nil {
+	if err := validator.ValidateGraph(eng.Loader(), parser, entry); err != nil {
 		return err
 	}
 
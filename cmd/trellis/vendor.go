@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/aretw0/trellis/pkg/manifest"
+	"github.com/aretw0/trellis/pkg/packages"
+	"github.com/spf13/cobra"
+)
+
+var vendorCmd = &cobra.Command{
+	Use:   "vendor",
+	Short: "Copy flow package dependencies into vendor/",
+	Long: `Copies every dependency declared in trellis.yaml (local paths or archives) into
+vendor/<name> and records their checksums in trellis.lock. Works fully offline.
+
+With --verify, checks that vendored packages still match trellis.lock without changing anything.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		if !cmd.Flags().Changed("dir") && len(args) > 0 {
+			dir = args[0]
+		}
+		verifyOnly, _ := cmd.Flags().GetBool("verify")
+
+		m, err := manifest.Find(dir)
+		if err != nil {
+			fmt.Printf("Error: invalid manifest: %v\n", err)
+			os.Exit(1)
+		}
+		if len(m.Dependencies) == 0 {
+			fmt.Println("No dependencies declared in trellis.yaml.")
+			return
+		}
+
+		if verifyOnly {
+			if _, err := packages.Resolve(m); err != nil {
+				fmt.Printf("Verification failed: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("Packages match trellis.lock ✅")
+			return
+		}
+
+		lock, err := packages.Vendor(m)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		names := make([]string, 0, len(lock.Packages))
+		for name := range lock.Packages {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p := lock.Packages[name]
+			label := name
+			if p.Version != "" {
+				label += "@" + p.Version
+			}
+			fmt.Printf("vendored %s (%s)\n", label, p.Source)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(vendorCmd)
+	vendorCmd.Flags().Bool("verify", false, "Only check vendored packages against trellis.lock")
+}
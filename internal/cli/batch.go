@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/pkg/adapters/process"
+	"github.com/aretw0/trellis/pkg/answers"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/runner"
+)
+
+// BatchResult is the outcome of one batch record, written as a line of the results file.
+type BatchResult struct {
+	Record    int                    `json:"record"` // 1-based position in the records file
+	SessionID string                 `json:"session_id"`
+	Status    domain.ExecutionStatus `json:"status,omitempty"` // Empty when the session did not start
+	Node      string                 `json:"node,omitempty"`
+	Context   map[string]any         `json:"context,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// Failed reports whether the record did not run to the end of the flow.
+func (r BatchResult) Failed() bool {
+	return r.Error != "" || r.Status != domain.StatusTerminated
+}
+
+// ReadRecords reads batch records: a CSV file with a header row (empty cells are left out)
+// or JSON Lines with one object per line.
+func ReadRecords(path string) ([]map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read records: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return readCSV(data)
+	case ".jsonl", ".ndjson", ".json":
+		return readJSONL(data)
+	}
+	return nil, fmt.Errorf("unsupported records file %s (use .csv or .jsonl)", path)
+}
+
+func readCSV(data []byte) ([]map[string]any, error) {
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV records: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	header := rows[0]
+	records := make([]map[string]any, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := make(map[string]any, len(header))
+		for i, value := range row {
+			if value != "" && strings.TrimSpace(header[i]) != "" {
+				record[strings.TrimSpace(header[i])] = value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func readJSONL(data []byte) ([]map[string]any, error) {
+	var records []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			return nil, fmt.Errorf("invalid JSON record at line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// RunBatch runs the flow once per record of opts.Batch, opts.Parallel at a time, and writes
+// one BatchResult per record, in record order, to opts.Results (stdout when empty). Each
+// record is merged over the initial context and answers the prompts keyed by its fields
+// (over the answers file). Sessions are not persisted and tool calls are auto-approved.
+func RunBatch(ctx context.Context, opts RunOptions, initialContext map[string]any) error {
+	records, err := ReadRecords(opts.Batch)
+	if err != nil {
+		return err
+	}
+	base, err := loadAnswers(opts)
+	if err != nil {
+		return err
+	}
+
+	logger := createLogger(opts.Debug)
+	engine, err := createEngine(opts, logger)
+	if err != nil {
+		return err
+	}
+	nodes, err := engine.Inspect()
+	if err != nil {
+		return fmt.Errorf("failed to inspect graph: %w", err)
+	}
+	toolConfig, baseDir, err := loadTools(opts)
+	if err != nil {
+		logger.Warn("Failed to load tools configuration", "path", opts.ToolsPath, "err", err)
+	}
+	procRunner := process.NewRunner(
+		process.WithRegistry(toolConfig),
+		process.WithInlineExecution(opts.UnsafeInline),
+		process.WithBaseDir(baseDir),
+	)
+
+	out := io.Writer(os.Stdout)
+	if opts.Results != "" {
+		f, err := os.Create(opts.Results)
+		if err != nil {
+			return fmt.Errorf("failed to create results file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	results := make([]BatchResult, len(records))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range max(opts.Parallel, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = runRecord(ctx, opts, i, records[i], initialContext, base, nodes, engine, procRunner, logger)
+			}
+		}()
+	}
+	for i := range records {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	enc := json.NewEncoder(out)
+	failed := 0
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to write results: %w", err)
+		}
+		if r.Failed() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d records failed", failed, len(records))
+	}
+	return ctx.Err()
+}
+
+func runRecord(ctx context.Context, opts RunOptions, i int, record, initialContext map[string]any, base *answers.File,
+	nodes []domain.Node, engine *trellis.Engine, tools runner.ToolRunner, logger *slog.Logger) BatchResult {
+	result := BatchResult{Record: i + 1, SessionID: fmt.Sprintf("batch-%d", i+1)}
+	initial := maps.Clone(initialContext)
+	if initial == nil {
+		initial = make(map[string]any, len(record))
+	}
+	maps.Copy(initial, record)
+
+	state, err := engine.Start(ctx, result.SessionID, initial)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	r := runner.NewRunner(
+		runner.WithLogger(logger),
+		runner.WithHeadless(true),
+		runner.WithEngine(engine),
+		runner.WithInitialState(state),
+		runner.WithInputHandler(answers.NewHandler(runner.NewTextHandler(io.Discard), base.With(record), nodes)),
+		runner.WithToolRunner(tools),
+		runner.WithBudget(opts.Budget),
+	)
+	runErr := r.Run(ctx)
+	if final := r.State(); final != nil {
+		state = final
+	}
+	result.Status, result.Node, result.Context = state.Status, state.CurrentNodeID, state.Context
+	if runErr != nil && !errors.Is(runErr, io.EOF) {
+		result.Error = runErr.Error()
+	}
+	return result
+}
+
+// loadAnswers reads --answers (an empty file when unset) and applies --fail-unanswered.
+func loadAnswers(opts RunOptions) (*answers.File, error) {
+	f := &answers.File{}
+	if opts.Answers != "" {
+		var err error
+		if f, err = answers.Load(opts.Answers); err != nil {
+			return nil, err
+		}
+	}
+	f.FailUnanswered = f.FailUnanswered || opts.FailUnanswered
+	return f, nil
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+func TestReadRecords(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "records.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("name,plan\nAda,pro\nBob,\n"), 0644))
+	records, err := ReadRecords(csvPath)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"name": "Ada", "plan": "pro"}, {"name": "Bob"}}, records)
+
+	jsonlPath := filepath.Join(dir, "records.jsonl")
+	require.NoError(t, os.WriteFile(jsonlPath, []byte("{\"name\":\"Ada\",\"seats\":2}\n\n{\"name\":\"Bob\"}\n"), 0644))
+	records, err = ReadRecords(jsonlPath)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"name": "Ada", "seats": 2.0}, {"name": "Bob"}}, records)
+
+	require.NoError(t, os.WriteFile(jsonlPath, []byte("{\"name\":\"Ada\"}\nnot json\n"), 0644))
+	_, err = ReadRecords(jsonlPath)
+	assert.ErrorContains(t, err, "line 2")
+
+	_, err = ReadRecords(filepath.Join(dir, "records.xlsx"))
+	assert.Error(t, err)
+}
+
+func TestRunBatch(t *testing.T) {
+	dir := testutils.WriteFlow(t, map[string]string{
+		"trellis.yaml": "name: signup\nentry: main\n",
+		"main.md":      "---\nwait: true\nsave_to: name\nto: plan\n---\nName?",
+		"plan.md":      "---\ninput_type: choice\ninput_options: [basic, pro]\nsave_to: plan\nto: done\n---\nPlan?",
+		"done.md":      "Bye {{ .name }} ({{ .region }})",
+	})
+	records := filepath.Join(dir, "records.csv")
+	require.NoError(t, os.WriteFile(records, []byte("name,plan\nAda,pro\nBob,\nCy,basic\n"), 0644))
+	answersPath := filepath.Join(dir, "answers.yaml")
+	require.NoError(t, os.WriteFile(answersPath, []byte("answers:\n  plan: basic\n"), 0644))
+	results := filepath.Join(dir, "results.jsonl")
+
+	opts := RunOptions{
+		RepoPath: dir, ToolsPath: defaultToolsPath, Context: `{"region": "eu"}`,
+		Batch: records, Answers: answersPath, Results: results, Parallel: 2,
+	}
+	require.NoError(t, Execute(context.Background(), opts))
+
+	got := readResults(t, results)
+	require.Len(t, got, 3)
+	for i, r := range got {
+		assert.Equal(t, i+1, r.Record)
+		assert.Equal(t, domain.StatusTerminated, r.Status)
+		assert.Equal(t, "done", r.Node)
+		assert.Equal(t, "eu", r.Context["region"])
+	}
+	assert.Equal(t, "pro", got[0].Context["plan"])
+	assert.Equal(t, "basic", got[1].Context["plan"], "the answers file covers fields a record leaves out")
+	assert.Equal(t, "Cy", got[2].Context["name"])
+
+	t.Run("fail unanswered", func(t *testing.T) {
+		opts := opts
+		opts.Answers, opts.FailUnanswered = "", true
+		err := Execute(context.Background(), opts)
+		assert.ErrorContains(t, err, "1 of 3 records failed")
+
+		got := readResults(t, results)
+		require.Len(t, got, 3)
+		assert.False(t, got[0].Failed())
+		assert.True(t, got[1].Failed())
+		assert.Equal(t, "plan", got[1].Node)
+		assert.Contains(t, got[1].Error, `no answer for node "plan"`)
+	})
+
+	t.Run("invalid combinations", func(t *testing.T) {
+		opts := opts
+		opts.SessionID = "s1"
+		assert.Error(t, Execute(context.Background(), opts))
+		opts.SessionID, opts.Watch = "", true
+		assert.Error(t, Execute(context.Background(), opts))
+	})
+}
+
+func readResults(t *testing.T, path string) []BatchResult {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var out []BatchResult
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r BatchResult
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// ParseBudget parses a budget specification like "cost=1.5,tokens=20000,calls=10,units=3".
+// An empty specification yields an unlimited budget.
+func ParseBudget(spec string) (domain.Budget, error) {
+	var b domain.Budget
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return b, nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return b, fmt.Errorf("invalid budget entry '%s' (expected key=value)", part)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "tokens":
+			b.MaxTokens, err = strconv.ParseInt(value, 10, 64)
+		case "calls":
+			b.MaxCalls, err = strconv.Atoi(value)
+		case "cost":
+			b.MaxCost, err = strconv.ParseFloat(value, 64)
+		case "units":
+			b.MaxUnits, err = strconv.ParseFloat(value, 64)
+		default:
+			return b, fmt.Errorf("unknown budget key '%s' (expected tokens, cost, units or calls)", key)
+		}
+		if err != nil {
+			return b, fmt.Errorf("invalid value for budget '%s': %w", key, err)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+func TestParseBudget(t *testing.T) {
+	b, err := ParseBudget("cost=1.5, tokens=2000,calls=3,units=4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := domain.Budget{MaxCost: 1.5, MaxTokens: 2000, MaxCalls: 3, MaxUnits: 4}
+	if b != want {
+		t.Errorf("got %+v, want %+v", b, want)
+	}
+
+	if b, err := ParseBudget(""); err != nil || !b.IsZero() {
+		t.Errorf("expected empty budget, got %+v (%v)", b, err)
+	}
+
+	for _, bad := range []string{"cost", "money=3", "tokens=abc"} {
+		if _, err := ParseBudget(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/adapters/process"
+	"github.com/aretw0/trellis/pkg/debugger"
+	"github.com/aretw0/trellis/pkg/manifest"
+)
+
+// DebugOptions configures 'trellis debug'.
+type DebugOptions struct {
+	RepoPath     string
+	Strict       bool   // Reject unknown node keys and mistyped values
+	Context      string // Raw JSON string
+	ToolsPath    string
+	UnsafeInline bool
+	// Breakpoints are set before the session starts, in the text form of 'break' (e.g. "pay", "tool charge").
+	Breakpoints []string
+}
+
+// Debug runs the step debugger on the flow, loaded with the same conventions as 'run',
+// reading commands from in (see debugger.Help) until it ends or reads 'quit'.
+func Debug(ctx context.Context, opts DebugOptions, in io.Reader, out io.Writer) error {
+	var initialContext map[string]any
+	if opts.Context != "" {
+		if err := json.Unmarshal([]byte(opts.Context), &initialContext); err != nil {
+			return fmt.Errorf("error parsing --context JSON: %w", err)
+		}
+	}
+
+	m, err := manifest.Find(opts.RepoPath)
+	if err != nil {
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+	m.Strict = m.Strict || opts.Strict
+
+	runOpts := RunOptions{RepoPath: opts.RepoPath, Strict: opts.Strict, Manifest: m, ToolsPath: opts.ToolsPath, UnsafeInline: opts.UnsafeInline}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	engine, err := createEngine(runOpts, logger)
+	if err != nil {
+		return err
+	}
+	tools, err := NewToolRunner(runOpts)
+	if err != nil {
+		return err
+	}
+
+	var breakpoints []debugger.Breakpoint
+	for _, spec := range opts.Breakpoints {
+		cmd, err := debugger.Parse("break " + spec)
+		if err != nil || *cmd.Breakpoint == (debugger.Breakpoint{}) {
+			return fmt.Errorf("invalid --break %q (use <node>, 'tool <name>' or 'if <condition>')", spec)
+		}
+		breakpoints = append(breakpoints, *cmd.Breakpoint)
+	}
+	s, err := debugger.Start(ctx, engine, "debug", initialContext,
+		debugger.WithToolRunner(tools), debugger.WithBreakpoints(breakpoints...))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "Trellis debugger. Type 'help' for the commands.")
+	writeStop(out, s.Stop())
+
+	scanner := bufio.NewScanner(in)
+	last := ""
+	for {
+		fmt.Fprint(out, "(trellis) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			line = last // Enter repeats the last move, like gdb
+			if line == "" {
+				continue
+			}
+		}
+		switch line {
+		case "quit", "q", "exit":
+			return nil
+		case "help", "h", "?":
+			fmt.Fprintln(out, debugger.Help)
+			continue
+		}
+
+		cmd, err := debugger.Parse(line)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			continue
+		}
+		last = ""
+		if cmd.Op == debugger.OpStep || cmd.Op == debugger.OpNext || cmd.Op == debugger.OpContinue {
+			last = line
+		}
+		reply, err := s.Exec(ctx, cmd)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			continue
+		}
+		writeReply(out, cmd, reply)
+	}
+}
+
+// NewToolRunner loads the tool registry the way 'run' does (--tools, the manifest `tools`
+// entry, then a tools.yaml next to the flow) and returns a runner executing it.
+func NewToolRunner(opts RunOptions) (*process.Runner, error) {
+	if opts.Manifest == nil {
+		opts.Manifest = &manifest.Manifest{}
+	}
+	if opts.ToolsPath == "" {
+		opts.ToolsPath = defaultToolsPath
+	}
+	config, baseDir, err := loadTools(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tools from %s: %w", opts.ToolsPath, err)
+	}
+	return process.NewRunner(
+		process.WithRegistry(config),
+		process.WithInlineExecution(opts.UnsafeInline),
+		process.WithBaseDir(baseDir),
+	), nil
+}
+
+func writeReply(w io.Writer, cmd debugger.Command, reply *debugger.Reply) {
+	switch {
+	case reply.Stop != nil:
+		writeStop(w, *reply.Stop)
+	case reply.State != nil:
+		writeJSON(w, reply.State)
+	case cmd.Op == debugger.OpTemplate:
+		fmt.Fprintln(w, reply.Value)
+	case reply.Value != nil:
+		writeJSON(w, reply.Value)
+	case cmd.Op == debugger.OpBreak || cmd.Op == debugger.OpDelete || cmd.Op == debugger.OpBreakpoints:
+		if len(reply.Breakpoints) == 0 {
+			fmt.Fprintln(w, "no breakpoints")
+		}
+		for _, bp := range reply.Breakpoints {
+			fmt.Fprintf(w, "%s (hits: %d)\n", bp.String(), bp.Hits)
+		}
+	}
+}
+
+// writeStop shows where the session paused and what the node does.
+func writeStop(w io.Writer, st debugger.Stop) {
+	reason := st.Reason
+	if st.Breakpoint != nil {
+		reason = "breakpoint " + st.Breakpoint.String()
+	}
+	fmt.Fprintf(w, "→ %s [%s] (%s)\n", st.Node, st.Status, reason)
+	for _, text := range st.Content {
+		for _, line := range strings.Split(text, "\n") {
+			fmt.Fprintf(w, "  │ %s\n", line)
+		}
+	}
+	injected := make(map[string]bool)
+	for _, id := range st.Injected {
+		injected[id] = true
+	}
+	for _, call := range st.ToolCalls {
+		args, _ := json.Marshal(call.Args)
+		note := ""
+		if injected[call.ID] {
+			note = " [result injected]"
+		}
+		fmt.Fprintf(w, "  tool call: %s %s (id %s)%s\n", call.Name, args, call.ID, note)
+	}
+	switch {
+	case st.Error != "":
+		fmt.Fprintf(w, "  error: %s\n", st.Error)
+	case st.Ended:
+		fmt.Fprintln(w, "  flow ended ('restart' to run it again)")
+	case st.WaitingInput:
+		fmt.Fprintln(w, "  waiting for input ('input <text>', 'input' alone for empty, or 'signal <name>')")
+	}
+}
+
+func writeJSON(w io.Writer, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(w, "%v\n", v)
+		return
+	}
+	fmt.Fprintln(w, string(data))
+}
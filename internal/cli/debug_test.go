@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/testutils"
+)
+
+func TestDebug(t *testing.T) {
+	dir := testutils.WriteFlow(t, map[string]string{
+		"start.md":   "---\nwait: true\nsave_to: name\nto: greet\n---\nName?",
+		"greet.yaml": "do: { name: greet, args: { name: \"{{ .name }}\" } }\nsave_to: greeting\nto: done\n",
+		"done.md":    "{{ .greeting }}",
+		"tools.yaml": "tools:\n  - name: greet\n    command: echo\n    args: [\"hello\"]\n",
+	})
+
+	commands := strings.Join([]string{
+		"input Ada",
+		"p name",
+		"t Hi {{ .name }}",
+		"eval input == 'ada'",
+		"inject \"faked\"",
+		"c",
+		"",
+		"restart",
+		"c",
+		"i Grace",
+		"c",
+		"bl",
+		"jump",
+		"q",
+	}, "\n")
+	var out bytes.Buffer
+	err := Debug(context.Background(), DebugOptions{RepoPath: dir, Breakpoints: []string{"tool greet"}}, strings.NewReader(commands), &out)
+	require.NoError(t, err)
+
+	got := out.String()
+	assert.Contains(t, got, "→ start [active] (start)\n  │ Name?\n  waiting for input")
+	assert.Contains(t, got, "→ greet [waiting_for_tool] (breakpoint #1 tool greet)\n  tool call: greet {\"name\":\"Ada\"} (id greet)\n")
+	assert.Contains(t, got, "\"Ada\"\n")
+	assert.Contains(t, got, "Hi Ada\n")
+	assert.Contains(t, got, "true\n")
+	assert.Contains(t, got, "(id greet) [result injected]")
+	assert.Contains(t, got, "→ done [terminated] (end)\n  │ faked\n  flow ended")
+	assert.Contains(t, got, "error: session ended", "enter repeats continue")
+	assert.Contains(t, got, "→ done [terminated] (end)\n  │ hello\n", "the tool runs from the registry")
+	assert.Contains(t, got, "#1 tool greet (hits: 2)\n")
+	assert.Contains(t, got, "error: invalid command: unknown command \"jump\"")
+
+	err = Debug(context.Background(), DebugOptions{RepoPath: dir, Breakpoints: []string{"a b"}}, strings.NewReader(""), &out)
+	assert.ErrorContains(t, err, "invalid --break")
+}
@@ -1,42 +1,115 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/pkg/adapters/git"
+	"github.com/aretw0/trellis/pkg/adapters/loam"
+	"github.com/aretw0/trellis/pkg/adapters/openai"
 	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/intent"
+	"github.com/aretw0/trellis/pkg/manifest"
 )
 
+// OpenRevision reads the flow directory at repoPath as of a git revision (--rev),
+// along with the manifest stored in that revision.
+func OpenRevision(ctx context.Context, repoPath, rev string) (*git.Snapshot, *manifest.Manifest, error) {
+	if loam.IsFlowFile(repoPath) {
+		return nil, nil, fmt.Errorf("--rev requires a flow directory, not a single-file flow")
+	}
+	snap, err := git.Open(ctx, repoPath, rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := manifest.FindFS(snap)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest at revision %s: %w", rev, err)
+	}
+	return snap, m, nil
+}
+
 // createEngine initializes a Trellis engine with standard CLI conventions.
 func createEngine(opts RunOptions, logger *slog.Logger) (*trellis.Engine, error) {
 	engineOpts := []trellis.Option{}
 
-	// 1. Logger & Hooks
+	// 1. Logger & Hooks (even in non-debug, use the provided logger)
+	engineOpts = append(engineOpts, trellis.WithLogger(logger))
+	var hooks []domain.LifecycleHooks
 	if opts.Debug {
-		engineOpts = append(engineOpts, trellis.WithLogger(logger))
-		engineOpts = append(engineOpts, trellis.WithLifecycleHooks(createDebugHooks(logger)))
+		hooks = append(hooks, createDebugHooks(logger))
+	}
+	if opts.Hooks != nil {
+		hooks = append(hooks, *opts.Hooks)
+	}
+	if len(hooks) > 0 {
+		engineOpts = append(engineOpts, trellis.WithLifecycleHooks(domain.MergeHooks(hooks...)))
+	}
+
+	// A git revision is read like an embedded flow: conventions and manifest come from it.
+	exists := func(id string) bool { return hasNode(opts.RepoPath, id) }
+	if opts.Rev != "" {
+		snap, m, err := OpenRevision(context.Background(), opts.RepoPath, opts.Rev)
+		if err != nil {
+			return nil, err
+		}
+		m.Strict = m.Strict || opts.Strict
+		opts.Manifest = m
+		exists = func(id string) bool { return hasNodeFile(snap, id) }
+		engineOpts = append(engineOpts, trellis.WithFS(snap), trellis.WithRevision(snap.Commit))
+	}
+
+	// 2. Project Manifest: explicit settings from trellis.yaml (applied by trellis.New)
+	m := opts.Manifest
+	if m == nil {
+		m = &manifest.Manifest{}
 	} else {
-		// Even in non-debug, use the provided logger (standardized)
-		engineOpts = append(engineOpts, trellis.WithLogger(logger))
+		engineOpts = append(engineOpts, trellis.WithManifest(m))
 	}
 
-	// 2. Smart Convention: Default Error Node
-	if hasNode(opts.RepoPath, domain.DefaultErrorNodeID) {
+	// A bundle carries the entry and error node resolved when it was packed.
+	if opts.Bundle != nil {
+		engineOpts = append(engineOpts, trellis.WithBundle(opts.Bundle))
+	}
+
+	// 3. Smart Convention: Default Error Node
+	if opts.Bundle == nil && m.ErrorNode == "" && exists(domain.DefaultErrorNodeID) {
 		engineOpts = append(engineOpts, trellis.WithDefaultErrorNode(domain.DefaultErrorNodeID))
 	}
 
-	// 3. Smart Convention: Entrypoint Fallback
-	entryPoint := determineEntryPoint(opts.RepoPath)
+	// 4. Smart Convention: Entrypoint Fallback
+	if opts.Bundle == nil && m.Entry == "" {
+		entryPoint := determineEntryPoint(opts.RepoPath)
+		if opts.Rev != "" {
+			entryPoint = conventionalEntry(exists, filepath.Base(opts.RepoPath))
+		}
+
+		// Only override if different from default "start" to avoid unnecessary config
+		if entryPoint != "" && entryPoint != "start" {
+			engineOpts = append(engineOpts, trellis.WithEntryNode(entryPoint))
+		}
+	}
+
+	// 5. Model Provider for llm nodes (configured via TRELLIS_LLM_* env vars)
+	if provider, ok := openai.FromEnv(); ok {
+		engineOpts = append(engineOpts, trellis.WithModelProvider(provider))
+		// Route nodes fall back to the model when the built-in rules are unsure.
+		engineOpts = append(engineOpts, trellis.WithIntentClassifier(
+			intent.Chain(domain.DefaultMinConfidence, intent.Default(), intent.LLM(provider))))
+	}
 
-	// Only override if different from default "start" to avoid unnecessary config
-	if entryPoint != "start" {
-		engineOpts = append(engineOpts, trellis.WithEntryNode(entryPoint))
+	// Session budget (--budget), enforced by the engine as well as by the runner
+	if !opts.Budget.IsZero() {
+		engineOpts = append(engineOpts, trellis.WithBudget(opts.Budget))
 	}
 
-	// 4. Initialize
+	// 6. Initialize
 	engine, err := trellis.New(opts.RepoPath, engineOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing engine: %w", err)
@@ -45,12 +118,19 @@ func createEngine(opts RunOptions, logger *slog.Logger) (*trellis.Engine, error)
 	return engine, nil
 }
 
-// hasNode checks if a node exists as a file in the directory.
+// hasNode checks if a node exists as a file in the directory (or is declared in a single-file flow).
 func hasNode(repoPath, nodeID string) bool {
+	if flow := readFlowFile(repoPath); flow != nil {
+		return flow.Has(nodeID)
+	}
+	return hasNodeFile(os.DirFS(repoPath), nodeID)
+}
+
+// hasNodeFile checks if a node exists as a file in fsys.
+func hasNodeFile(fsys fs.FS, nodeID string) bool {
 	extensions := []string{".md", ".yaml", ".json"}
 	for _, ext := range extensions {
-		path := filepath.Join(repoPath, nodeID+ext)
-		if _, err := os.Stat(path); err == nil {
+		if _, err := fs.Stat(fsys, nodeID+ext); err == nil {
 			return true
 		}
 	}
@@ -58,22 +138,50 @@ func hasNode(repoPath, nodeID string) bool {
 }
 
 // determineEntryPoint implements the fallback logic for finding the initial node.
-// Priority: start > main > index > DirectoryName
+// Priority: flow `entry` > start > main > index > DirectoryName (or FileName)
 func determineEntryPoint(repoPath string) string {
-	if hasNode(repoPath, "start") {
-		return "start"
+	flow := readFlowFile(repoPath)
+	if flow != nil && flow.Entry != "" {
+		return flow.Entry
 	}
-	if hasNode(repoPath, "main") {
-		return "main"
+	name := filepath.Base(repoPath)
+	if flow != nil {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
 	}
-	if hasNode(repoPath, "index") {
-		return "index"
+	if id := conventionalEntry(func(id string) bool { return hasNode(repoPath, id) }, name); id != "" {
+		return id
 	}
 
-	dirName := filepath.Base(repoPath)
-	if hasNode(repoPath, dirName) {
-		return dirName
+	// Single-file flows start at their first node
+	if flow != nil {
+		return flow.FirstNode()
 	}
 
 	return "start" // Default
 }
+
+// conventionalEntry returns the first existing node of start, main, index and name ("" if none).
+func conventionalEntry(exists func(string) bool, name string) string {
+	for _, id := range []string{"start", "main", "index", name} {
+		if exists(id) {
+			return id
+		}
+	}
+	return ""
+}
+
+// readFlowFile parses repoPath when it is a single-file flow (nil for directories or invalid files).
+func readFlowFile(repoPath string) *loam.FlowFile {
+	if !loam.IsFlowFile(repoPath) {
+		return nil
+	}
+	data, err := os.ReadFile(repoPath)
+	if err != nil {
+		return nil
+	}
+	flow, err := loam.ParseFlowFile(repoPath, data)
+	if err != nil {
+		return nil
+	}
+	return flow
+}
@@ -52,4 +52,24 @@ func TestDetermineEntryPoint(t *testing.T) {
 		dir := createDir(t, []string{"other.md"})
 		assert.Equal(t, "start", determineEntryPoint(dir))
 	})
+
+	t.Run("Single-file flow", func(t *testing.T) {
+		dir := t.TempDir()
+		write := func(name, content string) string {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+			return path
+		}
+
+		explicit := write("explicit.yaml", "entry: intro\nnodes:\n  main: {}\n  intro: {}\n")
+		assert.Equal(t, "intro", determineEntryPoint(explicit))
+
+		conventional := write("conventional.yaml", "nodes:\n  other: {}\n  main: {}\n")
+		assert.Equal(t, "main", determineEntryPoint(conventional))
+		assert.True(t, hasNode(conventional, "other"))
+		assert.False(t, hasNode(conventional, "missing"))
+
+		first := write("first.yaml", "nodes:\n  hello: {}\n  bye: {}\n")
+		assert.Equal(t, "hello", determineEntryPoint(first))
+	})
 }
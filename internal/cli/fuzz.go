@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/aretw0/trellis/internal/fuzz"
+	"github.com/aretw0/trellis/pkg/manifest"
+)
+
+// FuzzOptions configures 'trellis fuzz'.
+type FuzzOptions struct {
+	RepoPath  string
+	Strict    bool     // Reject unknown node keys and mistyped values
+	Strategy  string   // bfs or dfs
+	MaxDepth  int      // Choices along a path
+	MaxStates int      // Distinct states explored
+	Context   string   // Raw JSON string, merged over the generated initial context
+	Inputs    []string // Extra inputs tried at open-ended prompts
+	Out       string   // Scenario file receiving the reproducers
+}
+
+// Fuzz explores the state space of the flow, loaded with the same conventions as 'run'.
+// Tools are never executed: every call is answered with success, error and denial. With
+// Out set, the reproducers of the findings are written there as a 'trellis test' file.
+func Fuzz(ctx context.Context, opts FuzzOptions) (*fuzz.Report, error) {
+	var initialContext map[string]any
+	if opts.Context != "" {
+		if err := json.Unmarshal([]byte(opts.Context), &initialContext); err != nil {
+			return nil, fmt.Errorf("error parsing --context JSON: %w", err)
+		}
+	}
+
+	m, err := manifest.Find(opts.RepoPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	m.Strict = m.Strict || opts.Strict
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	engine, err := createEngine(RunOptions{RepoPath: opts.RepoPath, Strict: opts.Strict, Manifest: m}, logger)
+	if err != nil {
+		return nil, err
+	}
+	report, err := fuzz.Explore(ctx, engine, fuzz.Options{
+		Strategy:  opts.Strategy,
+		MaxDepth:  opts.MaxDepth,
+		MaxStates: opts.MaxStates,
+		Context:   initialContext,
+		Inputs:    opts.Inputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.Out != "" && len(report.Findings) > 0 {
+		if err := fuzz.WriteScenarios(opts.Out, report.Findings); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/scenario"
+	"github.com/aretw0/trellis/internal/testutils"
+)
+
+func TestFuzz(t *testing.T) {
+	dir := testutils.WriteFlow(t, map[string]string{
+		"trellis.yaml": "name: shop\nentry: main\n",
+		"main.md":      "---\nwait: true\nrequired_context: [plan]\nsave_to: name\nto: pay\n---\nName?",
+		"pay.yaml":     "do: { name: charge, args: { plan: \"{{ .plan }}\" } }\nto: done\n",
+		"done.md":      "Bye {{ .name }}",
+	})
+	out := filepath.Join(dir, "fuzz.test.yaml")
+
+	report, err := Fuzz(context.Background(), FuzzOptions{RepoPath: dir, Context: `{"plan": "pro"}`, Out: out})
+	require.NoError(t, err)
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, "unhandled-tool-error", report.Findings[0].Kind)
+	assert.Equal(t, []string{"main", "pay"}, report.Findings[0].Path)
+
+	// The reproducers run as scenarios of the flow.
+	f, err := scenario.Load(out)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"plan": "pro"}, f.Scenarios[0].Context)
+	results, _, err := Test(context.Background(), TestOptions{RepoPath: dir})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Passed)
+
+	_, err = Fuzz(context.Background(), FuzzOptions{RepoPath: dir, Context: "{"})
+	assert.ErrorContains(t, err, "error parsing --context JSON")
+}
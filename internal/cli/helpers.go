@@ -15,6 +15,7 @@ import (
 	"github.com/aretw0/trellis/pkg/adapters/memory"
 	"github.com/aretw0/trellis/pkg/adapters/redis"
 	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/manifest"
 	"github.com/aretw0/trellis/pkg/ports"
 	"github.com/aretw0/trellis/pkg/runner"
 	"github.com/aretw0/trellis/pkg/session"
@@ -144,30 +145,48 @@ func logCompletion(nodeID string, err error, quiet bool, sig os.Signal) {
 
 // setupPersistence initializes the state store and session manager.
 func setupPersistence(opts RunOptions, logger *slog.Logger) (ports.StateStore, *session.Manager) {
+	// Ephemeral sessions use an In-Memory store to prevent Panics when Session Manager tries to Load/Save
+	return newSessionManager(opts.Store, opts.SessionID != "", logger)
+}
+
+// NewDurableSessionManager creates a session manager backed by the configured store
+// (Redis, or the default file store). Server modes use it to resume sessions out-of-band,
+// e.g. when an async tool result arrives.
+func NewDurableSessionManager(cfg manifest.Store, logger *slog.Logger, opts ...session.Option) *session.Manager {
+	_, manager := newSessionManager(cfg, true, logger, opts...)
+	return manager
+}
+
+func newSessionManager(cfg manifest.Store, durable bool, logger *slog.Logger, opts ...session.Option) (ports.StateStore, *session.Manager) {
 	var store ports.StateStore
 	var locker ports.DistributedLocker
 
-	if opts.RedisURL != "" {
+	switch cfg.Backend {
+	case "redis":
 		// Use Redis Store & Locker
-		storeOpts, err := redis.ParseURL(opts.RedisURL)
+		storeOpts, err := redis.ParseURL(cfg.URL)
 		if err == nil {
 			rStore := redis.New(storeOpts.Addr, storeOpts.Password, storeOpts.DB)
 			// Enable Distributed Locking by default for Redis
 			locker = redis.NewLocker(rStore.Client(), "trellis:lock:")
 			store = rStore
 		} else {
-			fmt.Printf("Warning: Invalid Redis URL %q, falling back to FileStore. Error: %v\n", opts.RedisURL, err)
+			fmt.Printf("Warning: Invalid Redis URL %q, falling back to FileStore. Error: %v\n", cfg.URL, err)
 		}
+	case "memory":
+		store = memory.NewStore()
 	}
 
 	if store == nil {
-		if opts.SessionID != "" {
-			store = file.New("") // Uses default .trellis/sessions
+		if durable {
+			store = file.New(cfg.Path) // Empty path uses default .trellis/sessions
 		} else {
-			// Ephemeral session: Use In-Memory store to prevent Panics when Session Manager tries to Load/Save
 			store = memory.NewStore()
 		}
 	}
+	if cfg.Locker == "none" {
+		locker = nil
+	}
 
 	managerOpts := []session.Option{
 		session.WithLogger(logger),
@@ -175,16 +194,17 @@ func setupPersistence(opts RunOptions, logger *slog.Logger) (ports.StateStore, *
 	if locker != nil {
 		managerOpts = append(managerOpts, session.WithLocker(locker))
 	}
+	managerOpts = append(managerOpts, opts...)
 
 	return store, session.NewManager(store, managerOpts...)
 }
 
-// ResetSession clears the session data for the given ID.
-func ResetSession(sessionID string) {
+// ResetSession clears the session data for the given ID from the file store at storePath (default if empty).
+func ResetSession(sessionID, storePath string) {
 	if sessionID == "" {
 		sessionID = "watch-dev"
 	}
-	store := file.New("")
+	store := file.New(storePath)
 	_ = store.Delete(context.Background(), sessionID)
 }
 
@@ -235,6 +255,8 @@ func hydrateAndValidateState(ctx context.Context, engine *trellis.Engine, sessio
 			fmt.Printf(">>> Resetting status from WaitingForTool to Active (Node type changed or missing)\n")
 			state.Status = domain.StatusActive
 			state.PendingToolCall = ""
+			state.PendingToolCalls = nil
+			state.BatchResults = nil
 		}
 	}
 
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sort"
+
+	"github.com/aretw0/trellis/internal/lint"
+	"github.com/aretw0/trellis/pkg/adapters/git"
+	"github.com/aretw0/trellis/pkg/adapters/loam"
+	"github.com/aretw0/trellis/pkg/bundle"
+	"github.com/aretw0/trellis/pkg/manifest"
+)
+
+// LintOptions configures 'trellis lint'.
+type LintOptions struct {
+	RepoPath  string
+	ToolsPath string   // Tool registry used by the unknown-tool rule (conventions apply to the default)
+	Strict    bool     // Report unknown keys and mistyped values as load errors
+	Rev       string   // Git revision of the flow directory
+	Disable   []string // Rule IDs to turn off
+}
+
+// Lint runs the static analyzer over the flow at RepoPath with the same conventions as 'run':
+// manifest, entry and error node fallbacks, and the tool registry when one is found.
+func Lint(opts LintOptions) ([]lint.Finding, error) {
+	res, err := Analyze(opts)
+	if err != nil {
+		return nil, err
+	}
+	return res.Findings, nil
+}
+
+// Analyze is Lint, keeping the loaded nodes and the options of the run (see 'trellis lsp').
+func Analyze(opts LintOptions) (*lint.Result, error) {
+	for _, id := range opts.Disable {
+		if !knownRule(id) {
+			return nil, fmt.Errorf("unknown rule %q", id)
+		}
+	}
+
+	run := RunOptions{RepoPath: opts.RepoPath, ToolsPath: opts.ToolsPath, Strict: opts.Strict, Rev: opts.Rev}
+	var m *manifest.Manifest
+	var err error
+	if bundle.IsBundle(opts.RepoPath) {
+		if opts.Rev != "" {
+			return nil, fmt.Errorf("--rev cannot be used with a bundle (its content is fixed)")
+		}
+		if run.Bundle, err = OpenBundle(opts.RepoPath); err != nil {
+			return nil, err
+		}
+		if m, err = run.Bundle.Manifest(); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+	} else if m, err = manifest.Find(opts.RepoPath); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	m.Strict = m.Strict || opts.Strict
+	run.Manifest = m
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	engine, err := createEngine(run, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	lintOpts := lint.Options{
+		Entry:     engine.EntryNode(),
+		ErrorNode: engine.ErrorNode(),
+		Disable:   opts.Disable,
+	}
+	if em := engine.Manifest(); em != nil {
+		lintOpts.Legacy = em.Interpolator == "legacy"
+		lintOpts.Context = em.Context
+	}
+	if run.Bundle == nil {
+		lintOpts.Dir = opts.RepoPath
+		if loam.IsFlowFile(opts.RepoPath) {
+			lintOpts.Dir = filepath.Dir(opts.RepoPath)
+		}
+	}
+	// Suppression comments are read from the revision being linted, not the working tree.
+	if opts.Rev != "" {
+		snap, err := git.Open(context.Background(), opts.RepoPath, opts.Rev)
+		if err != nil {
+			return nil, err
+		}
+		lintOpts.ReadFile = snap.ReadFile
+	}
+	run.Manifest = m
+	if lintOpts.Tools, err = lintTools(run); err != nil {
+		return nil, err
+	}
+
+	return lint.Analyze(engine.Loader(), lintOpts)
+}
+
+// lintTools lists the registry tools, or returns nil when the flow has no registry
+// (tool names are not checked then).
+func lintTools(opts RunOptions) ([]string, error) {
+	config, _, err := loadTools(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tools: %w", err)
+	}
+	if config == nil {
+		return nil, nil
+	}
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func knownRule(id string) bool {
+	for _, r := range lint.Rules {
+		if r.ID == id {
+			return true
+		}
+	}
+	return false
+}
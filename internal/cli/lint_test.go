@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/lint"
+	"github.com/aretw0/trellis/internal/testutils"
+)
+
+func TestLint(t *testing.T) {
+	dir := testutils.WriteFlow(t, map[string]string{
+		"trellis.yaml": "name: support\ninterpolator: legacy\ncontext: [tier]\n",
+		"tools.yaml":   "tools:\n  - name: greet\n    command: echo\n",
+		"main.md":      "---\ndo:\n  name: greet\nto: ask\n---\nWelcome",
+		"ask.md":       "---\ndo:\n  name: charge\nrequired_context: [tier]\nto: done\n---\nHi {{ name }}",
+		"done.md":      "<!-- trellis:ignore -->\nBye",
+		"error.md":     "Oops",
+		"orphan.md":    "Lost",
+	})
+
+	findings, err := Lint(LintOptions{RepoPath: dir, ToolsPath: defaultToolsPath})
+	require.NoError(t, err)
+
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.NodeID+":"+f.Rule)
+	}
+	// main is the entry and error the error node by convention; tools.yaml is the registry
+	// and the manifest context provides tier.
+	assert.Equal(t, []string{"ask:undefined-key", "ask:unknown-tool", "orphan:unreachable", "tools:unreachable"}, got)
+	assert.Equal(t, filepath.ToSlash(filepath.Join(dir, "ask.md")), findings[0].File)
+
+	findings, err = Lint(LintOptions{RepoPath: dir, ToolsPath: defaultToolsPath, Disable: []string{lint.RuleUnreachable, lint.RuleUndefinedKey}})
+	require.NoError(t, err)
+	assert.Len(t, findings, 1)
+
+	_, err = Lint(LintOptions{RepoPath: dir, ToolsPath: defaultToolsPath, Disable: []string{"nope"}})
+	assert.ErrorContains(t, err, `unknown rule "nope"`)
+
+	_, err = Lint(LintOptions{RepoPath: dir, ToolsPath: filepath.Join(dir, "missing.yaml")})
+	assert.ErrorContains(t, err, "failed to load tools")
+}
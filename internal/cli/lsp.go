@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/aretw0/trellis/internal/lint"
+	"github.com/aretw0/trellis/internal/lsp"
+)
+
+// LSPOptions configures 'trellis lsp'.
+type LSPOptions struct {
+	RepoPath  string // Flow directory; empty to use the workspace root sent by the editor
+	ToolsPath string
+	Strict    bool
+}
+
+// ServeLSP runs the language server on in/out until the editor exits. Each analysis
+// follows the conventions of 'trellis lint' (manifest, entry, error node, tool registry).
+func ServeLSP(ctx context.Context, opts LSPOptions, in io.Reader, out io.Writer, logger *slog.Logger) error {
+	analyze := func(root string) (*lint.Result, error) {
+		return Analyze(LintOptions{RepoPath: root, ToolsPath: opts.ToolsPath, Strict: opts.Strict})
+	}
+	srv := lsp.NewServer(analyze, lsp.WithRoot(opts.RepoPath), lsp.WithLogger(logger))
+	return srv.Serve(ctx, in, out)
+}
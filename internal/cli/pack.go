@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aretw0/trellis/internal/compiler"
+	"github.com/aretw0/trellis/pkg/adapters/process"
+	"github.com/aretw0/trellis/pkg/bundle"
+	"github.com/aretw0/trellis/pkg/manifest"
+)
+
+// PackOptions configures 'trellis pack'.
+type PackOptions struct {
+	RepoPath  string
+	ToolsPath string // Tool registry to include (conventions apply to the default)
+	KeyPath   string // PEM ed25519 private key; empty leaves the bundle unsigned
+	Strict    bool
+}
+
+// Pack compiles the flow at RepoPath into a bundle, signing it when a key is given.
+// The bundle holds what the engine would load: templates, defaults and packages are
+// already resolved, and every node must parse.
+func Pack(opts PackOptions) (*bundle.Bundle, error) {
+	if bundle.IsBundle(opts.RepoPath) {
+		return nil, fmt.Errorf("%s is already a bundle", opts.RepoPath)
+	}
+	var key ed25519.PrivateKey
+	if opts.KeyPath != "" {
+		var err error
+		if key, err = bundle.LoadPrivateKey(opts.KeyPath); err != nil {
+			return nil, err
+		}
+	}
+
+	m, err := manifest.Find(opts.RepoPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	m.Strict = m.Strict || opts.Strict
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	engine, err := createEngine(RunOptions{RepoPath: opts.RepoPath, Strict: opts.Strict, Manifest: m}, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := engine.Loader().ListNodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	parser := compiler.NewParser(compiler.WithStrict(m.Strict))
+	nodes := make(map[string]json.RawMessage, len(ids))
+	for _, id := range ids {
+		raw, err := engine.Loader().GetNode(id)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", id, err)
+		}
+		if _, err := parser.Parse(raw); err != nil {
+			return nil, fmt.Errorf("node %s: %w", id, err)
+		}
+		nodes[id] = raw
+	}
+
+	contents := bundle.Contents{
+		Name:      engine.Name,
+		Version:   m.Version,
+		Entry:     engine.EntryNode(),
+		ErrorNode: engine.ErrorNode(),
+		Nodes:     nodes,
+	}
+	if m.Path != "" {
+		if contents.Manifest, err = os.ReadFile(m.Path); err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+	}
+	toolsPath := resolveToolsPath(opts.ToolsPath, opts.RepoPath, m)
+	if data, err := os.ReadFile(toolsPath); err == nil {
+		if _, err := process.ParseTools(filepath.Base(toolsPath), data); err != nil {
+			return nil, err
+		}
+		contents.Tools = data
+		contents.ToolsFile = "tools.yaml"
+		if strings.EqualFold(filepath.Ext(toolsPath), ".json") {
+			contents.ToolsFile = "tools.json"
+		}
+	} else if toolsPath != defaultToolsPath {
+		return nil, fmt.Errorf("failed to read tools: %w", err)
+	}
+
+	b, err := bundle.New(contents)
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		b.Sign(key)
+	}
+	return b, nil
+}
+
+// OpenBundle opens and verifies a bundle, enforcing the trust policy of TRELLIS_TRUSTED_KEYS.
+func OpenBundle(path string) (*bundle.Bundle, error) {
+	trusted, err := bundle.TrustedKeysFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return bundle.Open(path, trusted...)
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/bundle"
+)
+
+func TestPack(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "flow")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "config"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "config", "tools.yaml"), []byte("tools:\n  - name: greet\n    command: echo\n"), 0644))
+	testutils.WriteFiles(t, dir, map[string]string{
+		"trellis.yaml":       "name: support\nversion: 2.0.0\ntools: ../config/tools.yaml\n",
+		"main.md":            "---\nto: billing/charge\n---\nWelcome",
+		"billing/charge.md":  "Charging",
+		"error.md":           "Oops",
+		"_defaults.yaml":     "wait: true\n",
+		"billing/notes.yaml": "content: internal\n",
+	})
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "trellis.key")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	b, err := Pack(PackOptions{RepoPath: dir, ToolsPath: defaultToolsPath, KeyPath: keyPath})
+	require.NoError(t, err)
+	assert.Equal(t, "support", b.Name)
+	assert.Equal(t, "2.0.0", b.Version)
+	assert.Equal(t, "main", b.Entry, "conventions are resolved at pack time")
+	assert.Equal(t, "error", b.ErrorNode)
+	assert.Equal(t, []string{"billing/charge", "billing/notes", "error", "main"}, b.NodeIDs())
+	require.NotNil(t, b.Signature)
+	assert.Equal(t, bundle.KeyID(key.Public().(ed25519.PublicKey)), b.Signature.KeyID)
+
+	out := filepath.Join(t.TempDir(), "support.trellis")
+	f, err := os.Create(out)
+	require.NoError(t, err)
+	_, err = b.WriteTo(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	t.Run("Runs from the bundle", func(t *testing.T) {
+		opened, err := OpenBundle(out)
+		require.NoError(t, err)
+		m, err := opened.Manifest()
+		require.NoError(t, err)
+
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		engine, err := createEngine(RunOptions{RepoPath: out, Bundle: opened, Manifest: m}, logger)
+		require.NoError(t, err)
+		assert.Equal(t, "support", engine.Name)
+
+		state, err := engine.Start(context.Background(), "s1", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "main", state.CurrentNodeID)
+		assert.Equal(t, b.Hash, state.BundleHash())
+		state, err = engine.Navigate(context.Background(), state, "")
+		require.NoError(t, err)
+		assert.Equal(t, "billing/charge", state.CurrentNodeID, "defaults were applied when packing")
+
+		tools, baseDir, err := loadTools(RunOptions{RepoPath: out, Bundle: opened, ToolsPath: defaultToolsPath})
+		require.NoError(t, err)
+		assert.Contains(t, tools, "greet")
+		assert.Equal(t, filepath.Dir(out), baseDir)
+	})
+
+	t.Run("Trust policy", func(t *testing.T) {
+		other, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		der, err := x509.MarshalPKIXPublicKey(other)
+		require.NoError(t, err)
+		pubPath := filepath.Join(t.TempDir(), "trusted.pub")
+		require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+
+		t.Setenv(bundle.TrustedKeysEnv, pubPath)
+		_, err = OpenBundle(out)
+		assert.ErrorContains(t, err, "bundle is signed by untrusted key")
+	})
+
+	t.Run("Invalid node", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("do: [{ name: a }]\nbatch_policy: sometimes\n"), 0644))
+		defer os.Remove(filepath.Join(dir, "broken.yaml"))
+		_, err := Pack(PackOptions{RepoPath: dir, ToolsPath: defaultToolsPath})
+		assert.ErrorContains(t, err, "node broken")
+	})
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/aretw0/trellis/pkg/manifest"
+	"github.com/aretw0/trellis/pkg/transcript"
+)
+
+// ReplayOptions configures 'trellis replay'.
+type ReplayOptions struct {
+	RepoPath   string
+	Strict     bool   // Reject unknown node keys and mistyped values
+	Transcript string // Transcript recorded with 'run --transcript'
+}
+
+// Replay runs a recorded transcript again against the flow, loaded with the same conventions
+// as 'run', and reports where the flow now behaves differently. Tools are never executed:
+// calls are answered with the recorded results.
+func Replay(ctx context.Context, opts ReplayOptions) (*transcript.Report, error) {
+	t, err := transcript.Load(opts.Transcript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	m, err := manifest.Find(opts.RepoPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	m.Strict = m.Strict || opts.Strict
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	engine, err := createEngine(RunOptions{RepoPath: opts.RepoPath, Strict: opts.Strict, Manifest: m}, logger)
+	if err != nil {
+		return nil, err
+	}
+	return transcript.Replay(ctx, engine, t)
+}
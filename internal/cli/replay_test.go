@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/transcript"
+)
+
+func TestReplay(t *testing.T) {
+	dir := testutils.WriteFlow(t, map[string]string{
+		"trellis.yaml": "name: hello\nentry: main\n",
+		"main.md":      "---\nwait: true\nsave_to: name\nto: done\n---\nName?",
+		"done.md":      "Bye {{ .name }}",
+	})
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"kind":"start","state":{"session_id":"s","current_node_id":"main","status":"active","context":{},"history":["main"]}}
+{"kind":"render","node":"main","status":"active","content":["Name?"],"prompt":{"type":"text"}}
+{"kind":"input","node":"main","input":"Ada"}
+{"kind":"render","node":"done","status":"active","content":["Bye Ada"]}
+{"kind":"end","node":"done","status":"terminated","state":{"current_node_id":"done","status":"terminated","context":{"name":"Ada"}}}
+`), 0644))
+
+	report, err := Replay(context.Background(), ReplayOptions{RepoPath: dir, Transcript: path})
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "%+v", report.Divergences)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "done.md"), []byte("Goodbye {{ .name }}"), 0644))
+	report, err = Replay(context.Background(), ReplayOptions{RepoPath: dir, Transcript: path})
+	require.NoError(t, err)
+	require.Len(t, report.Divergences, 1)
+	assert.Equal(t, transcript.FieldContent, report.Divergences[0].Field)
+	assert.Equal(t, 4, report.Divergences[0].Line)
+
+	_, err = Replay(context.Background(), ReplayOptions{RepoPath: dir, Transcript: filepath.Join(dir, "missing.jsonl")})
+	assert.Error(t, err)
+}
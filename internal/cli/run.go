@@ -6,6 +6,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/aretw0/trellis/pkg/adapters/loam"
+	"github.com/aretw0/trellis/pkg/adapters/process"
+	"github.com/aretw0/trellis/pkg/bundle"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/manifest"
 )
 
 // RunOptions contains all the configuration for the Run command.
@@ -21,16 +27,60 @@ type RunOptions struct {
 	RedisURL     string
 	ToolsPath    string
 	UnsafeInline bool
+	Budget       domain.Budget // Per-session tool spending limit
+	Strict       bool          // Reject unknown node keys and mistyped values
+	Rev          string        // Git revision of the flow directory (commit, tag or branch)
+	Transcript   string        // JSON Lines transcript file written during the session
+
+	// Non-interactive runs: prompts are answered from a file instead of stdin.
+	Answers        string // Answers file (implies Headless)
+	FailUnanswered bool   // Fail at a prompt the answers do not cover
+	Batch          string // CSV or JSONL records, one session each
+	Results        string // Batch results file (stdout when empty)
+	Parallel       int    // Batch sessions run at once
+
+	// Resolved by Execute from the project manifest (trellis.yaml).
+	Manifest *manifest.Manifest
+	Store    manifest.Store
+	Bundle   *bundle.Bundle // Set when RepoPath is a .trellis bundle
+	// MaxInputSize limits user input in bytes (manifest server.max_input_size; 0 uses the runner default).
+	MaxInputSize int
+	// Hooks, when set, run after the debug hooks (e.g. coverage collection in 'test').
+	Hooks *domain.LifecycleHooks
 }
 
+// defaultToolsPath is the --tools default, which conventions may replace.
+const defaultToolsPath = "tools.yaml"
+
 // Execute handles the 'run' command logic, dispatching to Session or Watch mode.
 func Execute(ctx context.Context, opts RunOptions) error {
-	// Smart Default for Tools: If not explicitly set by user, check local repo
-	if opts.ToolsPath == "tools.yaml" {
-		candidate := filepath.Join(opts.RepoPath, "tools.yaml")
-		if _, err := os.Stat(candidate); err == nil {
-			opts.ToolsPath = candidate
+	var m *manifest.Manifest
+	var err error
+	if bundle.IsBundle(opts.RepoPath) {
+		if opts.Watch || opts.Rev != "" {
+			return fmt.Errorf("--watch and --rev cannot be used with a bundle (its content is fixed)")
 		}
+		if opts.Bundle, err = OpenBundle(opts.RepoPath); err != nil {
+			return err
+		}
+		if m, err = opts.Bundle.Manifest(); err != nil {
+			return fmt.Errorf("invalid manifest: %w", err)
+		}
+		// Relative paths (e.g. the session store) resolve next to the bundle.
+		m.Dir = filepath.Dir(opts.RepoPath)
+	} else if m, err = manifest.Find(opts.RepoPath); err != nil {
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+	if opts.Strict {
+		m.Strict = true
+	}
+	opts.Manifest = m
+	opts.Store = StoreConfig(opts.RedisURL, m)
+	opts.MaxInputSize = m.Server.MaxInputSize
+
+	// Bundled tools are used unless --tools is given
+	if opts.Bundle == nil {
+		opts.ToolsPath = resolveToolsPath(opts.ToolsPath, opts.RepoPath, m)
 	}
 
 	// Parse initial context if provided
@@ -41,10 +91,29 @@ func Execute(ctx context.Context, opts RunOptions) error {
 		}
 	}
 
+	if opts.Answers != "" || opts.Batch != "" {
+		if opts.Watch {
+			return fmt.Errorf("--watch cannot be used with --answers or --batch")
+		}
+		opts.Headless = true
+	}
+	if opts.Batch != "" {
+		if opts.SessionID != "" || opts.Transcript != "" {
+			return fmt.Errorf("--batch cannot be used with --session or --transcript (each record is its own session)")
+		}
+		return RunBatch(ctx, opts, initialContext)
+	}
+
 	if opts.Watch {
 		if opts.Headless {
 			return fmt.Errorf("--watch and --headless cannot be used together")
 		}
+		if opts.Rev != "" {
+			return fmt.Errorf("--watch and --rev cannot be used together (a revision never changes)")
+		}
+		if opts.Transcript != "" {
+			return fmt.Errorf("--watch and --transcript cannot be used together (reloads restart the session)")
+		}
 		RunWatch(ctx, opts)
 		return nil
 	}
@@ -52,8 +121,74 @@ func Execute(ctx context.Context, opts RunOptions) error {
 	// Session Mode
 	// Handle Fresh reset here for Session mode to mirror Watch mode behavior
 	if opts.Fresh {
-		ResetSession(opts.SessionID)
+		ResetSession(opts.SessionID, opts.Store.Path)
 	}
 
 	return RunSession(ctx, opts, initialContext)
 }
+
+// resolveToolsPath applies the tool registry conventions when --tools is left at its default:
+// the manifest `tools` entry, then a tools.yaml next to the flow.
+func resolveToolsPath(toolsPath, repoPath string, m *manifest.Manifest) string {
+	if toolsPath != defaultToolsPath {
+		return toolsPath
+	}
+	// Tools declared in the manifest replace the default registry path
+	if m.Tools != "" {
+		return m.ToolsPath()
+	}
+
+	// Smart Default for Tools: If not explicitly set by user, check local repo
+	baseDir := repoPath
+	if loam.IsFlowFile(baseDir) {
+		baseDir = filepath.Dir(baseDir)
+	}
+	candidate := filepath.Join(baseDir, defaultToolsPath)
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate
+	}
+	return toolsPath
+}
+
+// loadTools reads the tool registry and the directory relative scripts run from.
+// Bundles use their own registry unless --tools points elsewhere; otherwise the registry
+// is found by resolveToolsPath. The registry is nil when the flow has none, and a --tools
+// path that does not exist is an error.
+func loadTools(opts RunOptions) (map[string]process.ProcessConfig, string, error) {
+	if opts.Bundle != nil && opts.ToolsPath == defaultToolsPath {
+		baseDir := filepath.Dir(opts.RepoPath)
+		data, name := opts.Bundle.Tools()
+		if data == nil {
+			return nil, baseDir, nil
+		}
+		config, err := process.ParseTools(name, data)
+		return config, baseDir, err
+	}
+
+	m := opts.Manifest
+	if m == nil {
+		m = &manifest.Manifest{}
+	}
+	path := resolveToolsPath(opts.ToolsPath, opts.RepoPath, m)
+	if _, err := os.Stat(path); err != nil {
+		if path == defaultToolsPath {
+			return nil, filepath.Dir(path), nil // No registry
+		}
+		return nil, filepath.Dir(path), err
+	}
+	config, err := process.LoadTools(path)
+	return config, filepath.Dir(path), err
+}
+
+// StoreConfig resolves the session store settings: --redis-url wins over the manifest `store` section.
+func StoreConfig(redisURL string, m *manifest.Manifest) manifest.Store {
+	if redisURL != "" {
+		return manifest.Store{Backend: "redis", URL: redisURL}
+	}
+	if m == nil {
+		return manifest.Store{}
+	}
+	store := m.Store
+	store.Path = m.Resolve(store.Path)
+	return store
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aretw0/trellis/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreConfig(t *testing.T) {
+	m := &manifest.Manifest{
+		Dir:   "/project",
+		Store: manifest.Store{Backend: "file", Path: "data/sessions", Locker: "none"},
+	}
+
+	t.Run("Manifest store with resolved path", func(t *testing.T) {
+		cfg := StoreConfig("", m)
+		assert.Equal(t, "file", cfg.Backend)
+		assert.Equal(t, filepath.Join("/project", "data", "sessions"), cfg.Path)
+		assert.Equal(t, "none", cfg.Locker)
+	})
+
+	t.Run("Flag wins over manifest", func(t *testing.T) {
+		cfg := StoreConfig("redis://localhost:6379", m)
+		assert.Equal(t, manifest.Store{Backend: "redis", URL: "redis://localhost:6379"}, cfg)
+	})
+
+	t.Run("No manifest", func(t *testing.T) {
+		assert.Equal(t, manifest.Store{}, StoreConfig("", nil))
+	})
+}
+
+func TestLoadTools(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tools.yaml"), []byte("tools:\n  - name: greet\n    command: echo\n"), 0644))
+
+	t.Run("Registry next to the flow", func(t *testing.T) {
+		tools, baseDir, err := loadTools(RunOptions{RepoPath: dir, ToolsPath: defaultToolsPath})
+		require.NoError(t, err)
+		assert.Contains(t, tools, "greet")
+		assert.Equal(t, dir, baseDir)
+	})
+
+	t.Run("No registry", func(t *testing.T) {
+		tools, _, err := loadTools(RunOptions{RepoPath: t.TempDir(), ToolsPath: defaultToolsPath})
+		require.NoError(t, err)
+		assert.Nil(t, tools)
+	})
+
+	t.Run("Missing --tools", func(t *testing.T) {
+		_, _, err := loadTools(RunOptions{RepoPath: dir, ToolsPath: filepath.Join(dir, "missing.yaml")})
+		assert.Error(t, err)
+	})
+}
@@ -3,14 +3,18 @@ package cli
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
-	"path/filepath"
 
 	"github.com/aretw0/lifecycle"
 	"github.com/aretw0/trellis"
 	"github.com/aretw0/trellis/internal/presentation/tui"
+	"github.com/aretw0/trellis/pkg/adapters/git"
 	"github.com/aretw0/trellis/pkg/adapters/process"
+	"github.com/aretw0/trellis/pkg/answers"
+	"github.com/aretw0/trellis/pkg/ports"
 	"github.com/aretw0/trellis/pkg/runner"
+	"github.com/aretw0/trellis/pkg/transcript"
 )
 
 // RunSession executes a single session of Trellis.
@@ -20,6 +24,14 @@ func RunSession(ctx context.Context, opts RunOptions, initialContext map[string]
 	// Unified Logging
 	lifecycle.SetLogger(logger)
 
+	// Setup Persistence
+	store, sessionManager := setupPersistence(opts, logger)
+
+	// Resumed sessions keep the graph revision they started on
+	if err := pinRevision(ctx, &opts, store, logger); err != nil {
+		return err
+	}
+
 	// Initialize Engine
 	engine, err := createEngine(opts, logger)
 	if err != nil {
@@ -32,19 +44,38 @@ func RunSession(ctx context.Context, opts RunOptions, initialContext map[string]
 	var ioHandler runner.IOHandler
 
 	if opts.JSON {
-		ioHandler = runner.NewJSONHandler(os.Stdout)
+		ioHandler = runner.NewJSONHandler(os.Stdout, runner.WithJSONMaxInputSize(opts.MaxInputSize))
 	} else if !opts.Headless {
 		tui.PrintBanner(trellis.Version)
 		ioHandler = runner.NewTextHandler(os.Stdout,
 			runner.WithTextHandlerRenderer(tui.NewRenderer()),
+			runner.WithTextMaxInputSize(opts.MaxInputSize),
 		)
 	}
 
+	if opts.Answers != "" {
+		file, err := loadAnswers(opts)
+		if err != nil {
+			return err
+		}
+		nodes, err := engine.Inspect()
+		if err != nil {
+			return fmt.Errorf("failed to inspect graph: %w", err)
+		}
+		if ioHandler == nil {
+			ioHandler = runner.NewTextHandler(os.Stdout, runner.WithTextMaxInputSize(opts.MaxInputSize))
+		}
+		ioHandler = answers.NewHandler(ioHandler, file, nodes)
+	}
+
 	// ---------------------------------------------------------
 	// 2. Lifecycle Integration (The "Source")
 	// ---------------------------------------------------------
 	var mode string
-	if opts.Headless && !opts.JSON {
+	if opts.Answers != "" {
+		// Prompts are answered from the file: nothing is read from stdin
+		mode = "headless"
+	} else if opts.Headless && !opts.JSON {
 		mode = "headless"
 	} else if opts.JSON {
 		mode = "json"
@@ -66,9 +97,6 @@ func RunSession(ctx context.Context, opts RunOptions, initialContext map[string]
 	// 5. App Initialization
 	// ---------------------------------------------------------
 
-	// Setup Persistence
-	store, sessionManager := setupPersistence(opts, logger)
-
 	// Hydrate State
 	state, loaded, err := hydrateAndValidateState(ctx, engine, opts.SessionID, initialContext, sessionManager)
 	if err != nil {
@@ -78,7 +106,7 @@ func RunSession(ctx context.Context, opts RunOptions, initialContext map[string]
 	logSessionStatus(logger, opts.SessionID, state.CurrentNodeID, loaded, opts.JSON || opts.Headless)
 
 	// Setup Process Runner
-	toolConfig, err := process.LoadTools(opts.ToolsPath)
+	toolConfig, baseDir, err := loadTools(opts)
 	if err != nil {
 		logger.Warn("Failed to load tools configuration", "path", opts.ToolsPath, "err", err)
 	}
@@ -86,15 +114,27 @@ func RunSession(ctx context.Context, opts RunOptions, initialContext map[string]
 	procRunner := process.NewRunner(
 		process.WithRegistry(toolConfig),
 		process.WithInlineExecution(opts.UnsafeInline),
-		process.WithBaseDir(filepath.Dir(opts.ToolsPath)),
+		process.WithBaseDir(baseDir),
 	)
 
 	// Setup Runner
 	runnerOpts := createRunnerOptions(logger, opts.Headless, opts.SessionID, store, ioHandler, interruptSource)
 	runnerOpts = append(runnerOpts, runner.WithToolRunner(procRunner))
+	runnerOpts = append(runnerOpts, runner.WithBudget(opts.Budget))
 	runnerOpts = append(runnerOpts, runner.WithEngine(engine))
 	runnerOpts = append(runnerOpts, runner.WithInitialState(state))
 
+	var recorder *transcript.Recorder
+	if opts.Transcript != "" {
+		f, err := os.Create(opts.Transcript)
+		if err != nil {
+			return fmt.Errorf("failed to create transcript: %w", err)
+		}
+		defer f.Close()
+		recorder = transcript.NewRecorder(f)
+		runnerOpts = append(runnerOpts, runner.WithTranscript(recorder))
+	}
+
 	r := runner.NewRunner(runnerOpts...)
 
 	// Execute as lifecycle.Worker
@@ -114,5 +154,35 @@ func RunSession(ctx context.Context, opts RunOptions, initialContext map[string]
 	sig := lifecycle.Signal(ctx)
 	logCompletion(completionNodeID, runErr, opts.JSON || opts.Headless, sig)
 
+	if recorder != nil && recorder.Err() != nil && runErr == nil {
+		return fmt.Errorf("failed to write transcript: %w", recorder.Err())
+	}
 	return handleExecutionError(runErr)
 }
+
+// pinRevision keeps a resumed session on the revision recorded when it started:
+// without --rev that revision is loaded, and a --rev pointing elsewhere is rejected.
+func pinRevision(ctx context.Context, opts *RunOptions, store ports.StateStore, logger *slog.Logger) error {
+	if opts.SessionID == "" || opts.Bundle != nil {
+		return nil
+	}
+	state, err := store.Load(ctx, opts.SessionID)
+	if err != nil || state.Revision() == "" {
+		return nil // New or unpinned session (load errors surface when hydrating)
+	}
+	pinned := state.Revision()
+
+	if opts.Rev == "" {
+		logger.Info("Resuming session on its pinned revision", "session", opts.SessionID, "revision", pinned)
+		opts.Rev = pinned
+		return nil
+	}
+	commit, err := git.Resolve(ctx, opts.RepoPath, opts.Rev)
+	if err != nil {
+		return err
+	}
+	if commit != pinned {
+		return fmt.Errorf("session %s is pinned to revision %s; use --fresh to restart it on %s", opts.SessionID, pinned, opts.Rev)
+	}
+	return nil
+}
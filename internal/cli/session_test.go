@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/adapters/git"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+func TestRevisionPinning(t *testing.T) {
+	dir := t.TempDir()
+	testutils.GitCommit(t, dir, map[string]string{"start.md": "Version 1", "error.md": "Oops"}, "v1")
+	testutils.GitCommit(t, dir, map[string]string{"main.md": "Version 2"}, "v2")
+
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	engine, err := createEngine(RunOptions{RepoPath: dir, Rev: "v1"}, logger)
+	require.NoError(t, err)
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+	v1, err := git.Resolve(ctx, dir, "v1")
+	require.NoError(t, err)
+	assert.Equal(t, v1, state.Revision())
+	assert.Equal(t, "start", state.CurrentNodeID)
+
+	store := memory.NewStore()
+	require.NoError(t, store.Save(ctx, "s1", state))
+
+	t.Run("Resume uses the pinned revision", func(t *testing.T) {
+		opts := RunOptions{RepoPath: dir, SessionID: "s1"}
+		require.NoError(t, pinRevision(ctx, &opts, store, logger))
+		assert.Equal(t, v1, opts.Rev)
+
+		opts.Rev = "v1"
+		assert.NoError(t, pinRevision(ctx, &opts, store, logger), "same commit, different name")
+	})
+
+	t.Run("Another revision is rejected", func(t *testing.T) {
+		opts := RunOptions{RepoPath: dir, SessionID: "s1", Rev: "v2"}
+		err := pinRevision(ctx, &opts, store, logger)
+		assert.ErrorContains(t, err, "session s1 is pinned to revision "+v1)
+	})
+
+	t.Run("New and unpinned sessions are untouched", func(t *testing.T) {
+		opts := RunOptions{RepoPath: dir, SessionID: "new"}
+		require.NoError(t, pinRevision(ctx, &opts, store, logger))
+		assert.Empty(t, opts.Rev)
+
+		require.NoError(t, store.Save(ctx, "plain", domain.NewState("plain", "start")))
+		opts = RunOptions{RepoPath: dir, SessionID: "plain", Rev: "v2"}
+		require.NoError(t, pinRevision(ctx, &opts, store, logger))
+		assert.Equal(t, "v2", opts.Rev)
+	})
+}
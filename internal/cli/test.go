@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/aretw0/trellis/internal/presentation/graph"
+	"github.com/aretw0/trellis/internal/scenario"
+	"github.com/aretw0/trellis/pkg/adapters/loam"
+	"github.com/aretw0/trellis/pkg/coverage"
+	"github.com/aretw0/trellis/pkg/manifest"
+)
+
+// TestOptions configures 'trellis test'.
+type TestOptions struct {
+	RepoPath string
+	Strict   bool   // Reject unknown node keys and mistyped values
+	Run      string // Regular expression selecting scenarios by name
+	Parallel int    // Scenarios run at once
+	Update   bool   // Rewrite golden files
+
+	Cover        bool   // Collect node and edge coverage
+	CoverProfile string // Write the coverage report as JSON to this file (implies Cover)
+	CoverGraph   string // Write a Mermaid graph colored by coverage to this file (implies Cover)
+}
+
+// Test runs the scenario files (`*.test.yaml`) found in the flow directory against the flow,
+// loaded with the same conventions as 'run'. Tools are never executed: scenarios mock them.
+// With coverage enabled, it also returns what the scenarios exercised (nil otherwise).
+func Test(ctx context.Context, opts TestOptions) ([]scenario.Result, *coverage.Report, error) {
+	var filter *regexp.Regexp
+	if opts.Run != "" {
+		var err error
+		if filter, err = regexp.Compile(opts.Run); err != nil {
+			return nil, nil, fmt.Errorf("invalid --run pattern: %w", err)
+		}
+	}
+
+	m, err := manifest.Find(opts.RepoPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	m.Strict = m.Strict || opts.Strict
+
+	// Scenarios of a single-file flow live next to it.
+	dir := opts.RepoPath
+	if loam.IsFlowFile(opts.RepoPath) {
+		dir = filepath.Dir(opts.RepoPath)
+	}
+	paths, err := scenario.Find(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := make([]*scenario.File, 0, len(paths))
+	for _, path := range paths {
+		f, err := scenario.Load(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, f)
+	}
+
+	runOpts := RunOptions{RepoPath: opts.RepoPath, Strict: opts.Strict, Manifest: m}
+	var collector *coverage.Collector
+	if opts.Cover || opts.CoverProfile != "" || opts.CoverGraph != "" {
+		collector = coverage.NewCollector()
+		hooks := collector.Hooks()
+		runOpts.Hooks = &hooks
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	engine, err := createEngine(runOpts, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	results := scenario.Run(ctx, engine, files, scenario.Options{Run: filter, Parallel: opts.Parallel, Update: opts.Update})
+	if collector == nil {
+		return results, nil, nil
+	}
+
+	nodes, err := engine.Inspect()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to inspect graph: %w", err)
+	}
+	report := collector.Report(nodes, coverage.WithErrorNode(engine.ErrorNode()))
+	if opts.CoverProfile != "" {
+		if err := writeCoverage(opts.CoverProfile, func(w io.Writer) error { return coverage.WriteJSON(w, report) }); err != nil {
+			return nil, nil, err
+		}
+	}
+	if opts.CoverGraph != "" {
+		overlay := &graph.GraphOverlay{VisitedNodes: report.CoveredNodes(), CoveredEdges: report.Edges()}
+		if err := writeCoverage(opts.CoverGraph, func(w io.Writer) error {
+			_, err := io.WriteString(w, graph.GenerateMermaid(nodes, overlay))
+			return err
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+	return results, report, nil
+}
+
+// writeCoverage creates path and writes a coverage output to it.
+func writeCoverage(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write coverage: %w", err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write coverage: %w", err)
+	}
+	return f.Close()
+}
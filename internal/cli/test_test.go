@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/testutils"
+)
+
+func TestTest(t *testing.T) {
+	dir := testutils.WriteFlow(t, map[string]string{
+		"trellis.yaml":    "name: shop\nentry: main\n",
+		"main.md":         "---\nwait: true\nsave_to: name\nto: pay\n---\nName?",
+		"pay.yaml":        "do: { name: charge, args: { who: \"{{ .name }}\" } }\nto: done\n",
+		"done.md":         "Bye {{ .name }}",
+		"main.test.yaml":  "tools:\n  charge: { result: ok }\nscenarios:\n  - name: pays\n    inputs: [Ann]\n    expect:\n      visited: [main, pay, done]\n      tool_calls: [{ name: charge, args: { who: Ann } }]\n",
+		"other.test.yaml": "scenarios:\n  - name: broken\n    inputs: [Bob]\n    expect:\n      ends_at: main\n",
+	})
+
+	results, _, err := Test(context.Background(), TestOptions{RepoPath: dir, Parallel: 2})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	// The manifest entry applies and scenario files are not nodes of the flow.
+	assert.True(t, results[0].Passed, results[0].Failures)
+	assert.Equal(t, filepath.Join(dir, "main.test.yaml"), results[0].File)
+	assert.Equal(t, []string{"tool 'charge' called at node 'pay' has no mock", "expected to end at 'main', ended at 'pay'"}, results[1].Failures)
+
+	results, _, err = Test(context.Background(), TestOptions{RepoPath: dir, Run: "^pays$"})
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	_, _, err = Test(context.Background(), TestOptions{RepoPath: dir, Run: "("})
+	assert.ErrorContains(t, err, "invalid --run pattern")
+}
+
+func TestTest_Coverage(t *testing.T) {
+	dir := testutils.WriteFlow(t, map[string]string{
+		"start.md":        "---\nwait: true\ntransitions:\n  - { condition: input == 'a', to: a }\n  - { to: b }\n---\nA or B?",
+		"a.md":            "A",
+		"b.md":            "B",
+		"start.test.yaml": "scenarios:\n  - name: a\n    inputs: [a]\n",
+	})
+
+	results, report, err := Test(context.Background(), TestOptions{RepoPath: dir})
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Nil(t, report)
+
+	profile := filepath.Join(dir, "cover.json")
+	diagram := filepath.Join(dir, "cover.mmd")
+	_, report, err = Test(context.Background(), TestOptions{RepoPath: dir, CoverProfile: profile, CoverGraph: diagram})
+	require.NoError(t, err)
+	require.NotNil(t, report)
+	assert.Equal(t, 2, report.Nodes.Covered)
+	assert.Equal(t, 1, report.Transitions.Covered)
+	assert.Equal(t, []string{"b"}, []string{report.Uncovered()[0].Node})
+
+	data, err := os.ReadFile(profile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"error_handlers"`)
+	data, err = os.ReadFile(diagram)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "class b uncovered;")
+	assert.Contains(t, string(data), "linkStyle 1 stroke:#c62828")
+}
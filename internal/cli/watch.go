@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/aretw0/lifecycle"
@@ -30,14 +29,14 @@ func RunWatch(ctx context.Context, opts RunOptions) {
 	}
 
 	if opts.Fresh {
-		ResetSession(opts.SessionID)
+		ResetSession(opts.SessionID, opts.Store.Path)
 	}
 
 	logger.Info("Starting Watcher", "path", opts.RepoPath, "session_id", opts.SessionID)
 	printSystemMessage("Watcher at '%s' session.", opts.SessionID)
 
 	// Reuse the same IO handler to avoid multiple Stdin Pumps (ghost readers)
-	ioHandler := runner.NewTextHandler(os.Stdout, runner.WithTextHandlerRenderer(tui.NewRenderer()))
+	ioHandler := runner.NewTextHandler(os.Stdout, runner.WithTextHandlerRenderer(tui.NewRenderer()), runner.WithTextMaxInputSize(opts.MaxInputSize))
 
 	// Setup Lifecycle Router (uses shared factory)
 	interruptSource := make(chan struct{}, 1)
@@ -114,14 +113,14 @@ func runWatchIteration(parentCtx context.Context, opts RunOptions, ioHandler run
 	}
 
 	// Setup Process Runner
-	toolConfig, err := process.LoadTools(opts.ToolsPath)
+	toolConfig, baseDir, err := loadTools(opts)
 	if err != nil {
 		logger.Warn("Failed to load tools configuration", "path", opts.ToolsPath, "err", err)
 	}
 	procRunner := process.NewRunner(
 		process.WithRegistry(toolConfig),
 		process.WithInlineExecution(opts.UnsafeInline),
-		process.WithBaseDir(filepath.Dir(opts.ToolsPath)),
+		process.WithBaseDir(baseDir),
 	)
 
 	rOpts := createRunnerOptions(logger, false, opts.SessionID, store, ioHandler, interruptSource)
@@ -129,6 +128,7 @@ func runWatchIteration(parentCtx context.Context, opts RunOptions, ioHandler run
 	rOpts = append(rOpts,
 		runner.WithInputHandler(ioHandler),
 		runner.WithToolRunner(procRunner),
+		runner.WithBudget(opts.Budget),
 		runner.WithEngine(engine),
 		runner.WithInitialState(state),
 	)
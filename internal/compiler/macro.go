@@ -0,0 +1,256 @@
+package compiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// GeneratedIDSeparator joins the macro node ID and the step key in generated IDs ("checkout#ask-name").
+const GeneratedIDSeparator = "#"
+
+// ExpandFlow lowers a `type: flow` macro node into atomic DFA nodes.
+//
+// The script (the node content) holds one step per line:
+//
+//	say Welcome to ACME!
+//	ask name: What is your name?
+//	do create_account {"name": "{{ .name }}"} as account
+//	branch input == 'pro' -> pro_onboarding
+//	[receipt] say Thanks, {{ .name }}!
+//
+// Steps are chained in order. `branch` adds a conditional transition to the previous
+// step; the last step inherits the flow's own transitions. The first node keeps the
+// flow ID, so existing references still work; the others get IDs derived from the step
+// (its `[name]`, ask variable, tool name or text), so editing other lines keeps them.
+func ExpandFlow(flow *domain.Node) ([]domain.Node, error) {
+	var nodes []domain.Node
+	used := map[string]bool{flow.ID: true}
+
+	for i, raw := range strings.Split(string(flow.Content), "\n") {
+		line := i + 1
+		text := strings.TrimSpace(raw)
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "//") {
+			continue
+		}
+		src := &domain.SourceRef{Macro: flow.ID, Line: line, Step: text}
+		if flow.Source != nil {
+			src.File = flow.Source.File
+		}
+		fail := func(format string, args ...any) ([]domain.Node, error) {
+			return nil, fmt.Errorf("node %s line %d: %s", flow.ID, line, fmt.Sprintf(format, args...))
+		}
+
+		name, step, err := splitName(text)
+		if err != nil {
+			return fail("%v", err)
+		}
+		keyword, rest := splitStep(step)
+		if keyword == "branch" {
+			if name != "" {
+				return fail("'branch' steps cannot be named")
+			}
+			if len(nodes) == 0 {
+				return fail("'branch' must follow a step")
+			}
+			condition, target, ok := strings.Cut(rest, "->")
+			condition, target = strings.TrimSpace(condition), strings.TrimSpace(target)
+			if !ok || condition == "" || target == "" {
+				return fail("expected 'branch <condition> -> <node>'")
+			}
+			prev := &nodes[len(nodes)-1]
+			prev.Transitions = append(prev.Transitions, domain.Transition{Condition: condition, ToNodeID: target})
+			continue
+		}
+
+		node := domain.Node{Source: src, OnSignal: flow.OnSignal}
+		var key string
+		switch keyword {
+		case "say":
+			if rest == "" {
+				return fail("'say' needs a text")
+			}
+			node.Type = domain.NodeTypeText
+			node.Content = []byte(unquote(rest))
+			key = "say-" + slug(rest)
+
+		case "ask":
+			variable, question := "", rest
+			if before, after, ok := strings.Cut(rest, ":"); ok && isIdentifier(strings.TrimSpace(before)) {
+				variable, question = strings.TrimSpace(before), strings.TrimSpace(after)
+			}
+			node.Type = domain.NodeTypeQuestion
+			node.Content = []byte(unquote(question))
+			node.SaveTo = variable
+			key = "ask-" + variable
+			if variable == "" {
+				key = "ask-" + slug(question)
+			}
+
+		case "do":
+			call, saveTo, err := parseDoStep(rest)
+			if err != nil {
+				return fail("%v", err)
+			}
+			node.Type = domain.NodeTypeTool
+			node.Do = call
+			node.SaveTo = saveTo
+			node.OnError = flow.OnError
+			node.OnDenied = flow.OnDenied
+			key = "do-" + call.Name
+
+		default:
+			return fail("unknown step '%s' (expected say, ask, do or branch)", keyword)
+		}
+
+		id := flow.ID + GeneratedIDSeparator + key
+		switch {
+		case name != "":
+			id = flow.ID + GeneratedIDSeparator + name
+			if used[id] {
+				return fail("duplicate step name '%s'", name)
+			}
+		case used[id]:
+			// Repeated steps are numbered by occurrence; name them to pin the ID.
+			base := id
+			for n := 2; used[id]; n++ {
+				id = fmt.Sprintf("%s-%d", base, n)
+			}
+		}
+		if len(nodes) == 0 {
+			id = flow.ID
+		}
+		used[id] = true
+		node.ID = id
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("node %s: flow has no steps", flow.ID)
+	}
+
+	// Chain steps; the unconditional edge is the fallback after any branch.
+	for i := 0; i < len(nodes)-1; i++ {
+		nodes[i].Transitions = append(nodes[i].Transitions, domain.Transition{ToNodeID: nodes[i+1].ID})
+	}
+	last := &nodes[len(nodes)-1]
+	last.Transitions = append(last.Transitions, flow.Transitions...)
+
+	// Flow-level settings belong to the entry step.
+	first := &nodes[0]
+	first.RequiredContext = flow.RequiredContext
+	first.DefaultContext = flow.DefaultContext
+	first.ContextSchema = flow.ContextSchema
+	first.OnSignalDefault = flow.OnSignalDefault
+	first.Budget = flow.Budget
+
+	for i := range nodes {
+		for j := range nodes[i].Transitions {
+			nodes[i].Transitions[j].FromNodeID = nodes[i].ID
+		}
+	}
+	return nodes, nil
+}
+
+// splitName separates an optional leading `[name]` from the step.
+func splitName(text string) (string, string, error) {
+	if !strings.HasPrefix(text, "[") {
+		return "", text, nil
+	}
+	end := strings.Index(text, "]")
+	if end < 0 {
+		return "", "", fmt.Errorf("unterminated step name")
+	}
+	name := strings.TrimSpace(text[1:end])
+	if !isStepName(name) {
+		return "", "", fmt.Errorf("invalid step name '%s' (use letters, digits, '_' or '-')", name)
+	}
+	return name, strings.TrimSpace(text[end+1:]), nil
+}
+
+// slugWords bounds how much of a text goes into a generated ID.
+const slugWords = 4
+
+// slug derives an ID fragment from text: its first words, lowercased ("Order placed." ->
+// "order-placed"). Text without letters or digits falls back to a hash.
+func slug(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		h := fnv.New32a()
+		h.Write([]byte(text))
+		return fmt.Sprintf("%08x", h.Sum32())
+	}
+	return strings.Join(words[:min(len(words), slugWords)], "-")
+}
+
+// splitStep separates the keyword from its arguments ("say: Hi" and "say Hi" are equivalent).
+func splitStep(text string) (string, string) {
+	end := strings.IndexFunc(text, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		return text, ""
+	}
+	rest := strings.TrimSpace(text[end:])
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, ":"))
+	return text[:end], rest
+}
+
+// parseDoStep parses `<tool> [{json args}] [as <var>]`.
+func parseDoStep(rest string) (*domain.ToolCall, string, error) {
+	saveTo := ""
+	if idx := strings.LastIndex(rest, " as "); idx >= 0 && isIdentifier(strings.TrimSpace(rest[idx+4:])) {
+		saveTo = strings.TrimSpace(rest[idx+4:])
+		rest = strings.TrimSpace(rest[:idx])
+	}
+
+	name, argsText, _ := strings.Cut(rest, " ")
+	if name == "" {
+		return nil, "", fmt.Errorf("'do' needs a tool name")
+	}
+	call := &domain.ToolCall{ID: name, Name: name}
+	if argsText = strings.TrimSpace(argsText); argsText != "" {
+		if err := json.Unmarshal([]byte(argsText), &call.Args); err != nil {
+			return nil, "", fmt.Errorf("invalid arguments for '%s' (expected a JSON object): %w", name, err)
+		}
+	}
+	return call, saveTo, nil
+}
+
+func isStepName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package compiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/ports"
+)
+
+// MacroLoader decorates a GraphLoader with the lowering phase: macro nodes (`type: flow`)
+// are expanded on the fly, so the Engine only ever sees atomic DFA nodes.
+// It works with any adapter (Loam, memory, Redis...) since it only sees raw definitions.
+type MacroLoader struct {
+	inner  ports.GraphLoader
+	parser *Parser
+}
+
+// NewMacroLoader wraps the given loader.
+func NewMacroLoader(inner ports.GraphLoader) *MacroLoader {
+	return &MacroLoader{inner: inner, parser: NewParser()}
+}
+
+// GetNode implements ports.GraphLoader.
+// The macro ID resolves to the first generated step; generated IDs resolve to their step.
+func (l *MacroLoader) GetNode(id string) ([]byte, error) {
+	macroID, _, generated := strings.Cut(id, GeneratedIDSeparator)
+
+	raw, err := l.inner.GetNode(macroID)
+	if err != nil {
+		return nil, err
+	}
+	if !generated && !isMacro(raw) {
+		return raw, nil
+	}
+
+	nodes, err := l.expand(raw)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		if n.ID == id {
+			return json.Marshal(n)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s (flow %s has no such step)", domain.ErrNodeNotFound, id, macroID)
+}
+
+// ListNodes implements ports.GraphLoader, listing generated steps after their macro.
+func (l *MacroLoader) ListNodes() ([]string, error) {
+	ids, err := l.inner.ListNodes()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		raw, err := l.inner.GetNode(id)
+		if err != nil || !isMacro(raw) {
+			result = append(result, id)
+			continue
+		}
+		nodes, err := l.expand(raw)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			result = append(result, n.ID)
+		}
+	}
+	return result, nil
+}
+
+func (l *MacroLoader) expand(raw []byte) ([]domain.Node, error) {
+	macro, err := l.parser.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if macro.Type != domain.NodeTypeFlow {
+		return nil, fmt.Errorf("node %s is not a flow", macro.ID)
+	}
+	return ExpandFlow(macro)
+}
+
+// isMacro peeks at the node type without a full parse.
+func isMacro(raw []byte) bool {
+	var probe struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(raw, &probe) == nil && probe.Type == domain.NodeTypeFlow
+}
@@ -0,0 +1,135 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const checkoutScript = `# Checkout
+say Welcome to checkout!
+ask email: "What is your email?"
+branch input == 'skip' -> done
+
+do create_order {"email": "{{ .email }}"} as order
+say Order placed.`
+
+func TestExpandFlow(t *testing.T) {
+	flow := &domain.Node{
+		ID:          "checkout",
+		Type:        domain.NodeTypeFlow,
+		Content:     []byte(checkoutScript),
+		OnError:     "failed",
+		Transitions: []domain.Transition{{ToNodeID: "done"}},
+	}
+
+	nodes, err := ExpandFlow(flow)
+	require.NoError(t, err)
+	require.Len(t, nodes, 4)
+
+	ids := []string{nodes[0].ID, nodes[1].ID, nodes[2].ID, nodes[3].ID}
+	assert.Equal(t, []string{"checkout", "checkout#ask-email", "checkout#do-create_order", "checkout#say-order-placed"}, ids)
+
+	welcome := nodes[0]
+	assert.Equal(t, domain.NodeTypeText, welcome.Type)
+	assert.Equal(t, "Welcome to checkout!", string(welcome.Content))
+	assert.Equal(t, &domain.SourceRef{Macro: "checkout", Line: 2, Step: "say Welcome to checkout!"}, welcome.Source)
+
+	ask := nodes[1]
+	assert.Equal(t, domain.NodeTypeQuestion, ask.Type)
+	assert.Equal(t, "email", ask.SaveTo)
+	assert.Equal(t, "What is your email?", string(ask.Content))
+	assert.Equal(t, []domain.Transition{
+		{FromNodeID: "checkout#ask-email", ToNodeID: "done", Condition: "input == 'skip'"},
+		{FromNodeID: "checkout#ask-email", ToNodeID: "checkout#do-create_order"},
+	}, ask.Transitions)
+
+	order := nodes[2]
+	require.NotNil(t, order.Do)
+	assert.Equal(t, "create_order", order.Do.Name)
+	assert.Equal(t, "{{ .email }}", order.Do.Args["email"])
+	assert.Equal(t, "order", order.SaveTo)
+	assert.Equal(t, "failed", order.OnError, "flow-level on_error applies to tool steps")
+	assert.Equal(t, 6, order.Source.Line)
+
+	assert.Equal(t, "done", nodes[3].Transitions[0].ToNodeID, "the last step exits through the flow transitions")
+}
+
+func TestExpandFlow_StableIDs(t *testing.T) {
+	ids := func(script string) []string {
+		t.Helper()
+		nodes, err := ExpandFlow(&domain.Node{ID: "f", Type: domain.NodeTypeFlow, Content: []byte(script)})
+		require.NoError(t, err)
+		out := make([]string, len(nodes))
+		for i, n := range nodes {
+			out[i] = n.ID
+		}
+		return out
+	}
+
+	before := ids("say Hi\nask: Ready to start the setup now?\nsay Done\nsay Done\n[bye] say Done")
+	assert.Equal(t, []string{"f", "f#ask-ready-to-start-the", "f#say-done", "f#say-done-2", "f#bye"}, before)
+
+	after := ids("say Hi\n\n# a new step\nsay Welcome\nask: Ready to start the setup now?\nsay Done\nsay Done\n[bye] say Done")
+	assert.Equal(t, before[1:], after[2:], "inserting lines does not rename the other steps")
+
+	assert.Len(t, ids("say Hi\nsay 🎉")[1], len("f#say-")+8, "text without words falls back to a hash")
+}
+
+func TestExpandFlow_Errors(t *testing.T) {
+	tests := map[string]struct {
+		script string
+		err    string
+	}{
+		"empty":            {"# nothing\n", "flow has no steps"},
+		"unknown step":     {"say hi\ndance now", "line 2: unknown step 'dance'"},
+		"orphan branch":    {"branch input == 'x' -> y", "line 1: 'branch' must follow a step"},
+		"malformed branch": {"ask q: ?\nbranch input == 'x'", "line 2: expected 'branch"},
+		"bad args":         {"do ping {oops}", "line 1: invalid arguments for 'ping'"},
+		"bad name":         {"say hi\n[a b] say bye", "line 2: invalid step name 'a b'"},
+		"duplicate name":   {"say hi\n[x] say a\n[x] say b", "line 3: duplicate step name 'x'"},
+		"named branch":     {"ask q: ?\n[x] branch input == 'x' -> y", "line 2: 'branch' steps cannot be named"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ExpandFlow(&domain.Node{ID: "f", Type: domain.NodeTypeFlow, Content: []byte(tt.script)})
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestMacroLoader(t *testing.T) {
+	loader := NewMacroLoader(memory.NewLoader(map[string]string{
+		"start": `{"id": "start", "type": "flow", "content": "c2F5IEhpCmFzayBuYW1lOiBOYW1lPw==", "transitions": [{"to_node_id": "end"}]}`,
+		"end":   `{"id": "end", "type": "text"}`,
+	}))
+
+	ids, err := loader.ListNodes()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"start", "start#ask-name", "end"}, ids)
+
+	parser := NewParser()
+	raw, err := loader.GetNode("start")
+	require.NoError(t, err)
+	first, err := parser.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, domain.NodeTypeText, first.Type, "the macro ID resolves to its first step")
+
+	raw, err = loader.GetNode("start#ask-name")
+	require.NoError(t, err)
+	ask, err := parser.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "end", ask.Transitions[0].ToNodeID)
+
+	raw, err = loader.GetNode("end")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": "end", "type": "text"}`, string(raw), "atomic nodes pass through untouched")
+
+	_, err = loader.GetNode("start#missing")
+	assert.ErrorContains(t, err, "no such step")
+	_, err = loader.GetNode("end#step")
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package compiler
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 
@@ -8,23 +9,106 @@ import (
 )
 
 // Parser is responsible for converting raw bytes into a Node.
-type Parser struct{}
+type Parser struct {
+	strict bool
+}
+
+// ParserOption configures a Parser.
+type ParserOption func(*Parser)
+
+// WithStrict rejects node documents with unknown keys, so typos in loaders that
+// bypass the file checks (memory, bundles, custom loaders) fail instead of being dropped.
+func WithStrict(strict bool) ParserOption {
+	return func(p *Parser) {
+		p.strict = strict
+	}
+}
 
 // NewParser creates a new parser instance.
-func NewParser() *Parser {
-	return &Parser{}
+func NewParser(opts ...ParserOption) *Parser {
+	p := &Parser{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // Parse takes the raw content and tries to decode it into a Node.
 // For MVP, we assume the content is JSON.
 func (p *Parser) Parse(data []byte) (*domain.Node, error) {
 	var node domain.Node
-	if err := json.Unmarshal(data, &node); err != nil {
+
+	// Lowering: `do` may be authored as a list (batch).
+	var probe struct {
+		Do json.RawMessage `json:"do"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse node: %w", err)
+	}
+	if trimmed := bytes.TrimSpace(probe.Do); len(trimmed) > 0 && trimmed[0] == '[' {
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse node: %w", err)
+		}
+		raw["batch"] = raw["do"]
+		delete(raw, "do")
+		lowered, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse node: %w", err)
+		}
+		data = lowered
+	}
+
+	if err := p.decode(data, &node); err != nil {
 		return nil, fmt.Errorf("failed to parse node: %w", err)
 	}
 	// Basic validation
 	if node.ID == "" {
 		return nil, fmt.Errorf("node missing ID")
 	}
+	if err := normalizeBatch(&node); err != nil {
+		return nil, err
+	}
 	return &node, nil
 }
+
+// decode unmarshals a node document, rejecting unknown keys in strict mode.
+func (p *Parser) decode(data []byte, node *domain.Node) error {
+	if !p.strict {
+		return json.Unmarshal(data, node)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(node)
+}
+
+// normalizeBatch assigns default call IDs and rejects ambiguous batches.
+func normalizeBatch(node *domain.Node) error {
+	if len(node.Batch) == 0 {
+		return nil
+	}
+	if node.Do != nil {
+		return fmt.Errorf("node %s: 'do' cannot be both a single call and a batch", node.ID)
+	}
+	switch node.BatchPolicy {
+	case "", domain.BatchPolicyAll, domain.BatchPolicyContinue, domain.BatchPolicyRollback:
+	default:
+		return fmt.Errorf("node %s: invalid batch_policy '%s' (expected all, continue or rollback)", node.ID, node.BatchPolicy)
+	}
+
+	seen := make(map[string]bool, len(node.Batch))
+	for i := range node.Batch {
+		call := &node.Batch[i]
+		if call.ID == "" {
+			call.ID = call.Name
+		}
+		if call.ID == "" {
+			return fmt.Errorf("node %s: batch call #%d is missing a name", node.ID, i)
+		}
+		if seen[call.ID] {
+			return fmt.Errorf("node %s: duplicate batch call id '%s' (set an explicit 'id')", node.ID, call.ID)
+		}
+		seen[call.ID] = true
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+func TestParser_BatchDo(t *testing.T) {
+	p := NewParser()
+
+	node, err := p.Parse([]byte(`{
+		"id": "fanout",
+		"do": [
+			{"name": "email", "undo": {"name": "unsend"}},
+			{"id": "sms_1", "name": "sms"}
+		],
+		"batch_policy": "rollback"
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if node.Do != nil {
+		t.Error("expected list 'do' to be lowered into Batch")
+	}
+	if len(node.Batch) != 2 {
+		t.Fatalf("expected 2 batch calls, got %d", len(node.Batch))
+	}
+	if node.Batch[0].ID != "email" || node.Batch[1].ID != "sms_1" {
+		t.Errorf("unexpected call IDs: %s, %s", node.Batch[0].ID, node.Batch[1].ID)
+	}
+	if node.Batch[0].Undo == nil || node.Batch[0].Undo.Name != "unsend" {
+		t.Error("expected per-call undo to be preserved")
+	}
+	if node.BatchPolicy != domain.BatchPolicyRollback {
+		t.Errorf("unexpected policy %q", node.BatchPolicy)
+	}
+}
+
+func TestParser_BatchDo_Invalid(t *testing.T) {
+	p := NewParser()
+	cases := map[string]string{
+		"duplicate ids":  `{"id": "n", "do": [{"name": "a"}, {"name": "a"}]}`,
+		"missing name":   `{"id": "n", "do": [{"args": {}}]}`,
+		"invalid policy": `{"id": "n", "do": [{"name": "a"}], "batch_policy": "maybe"}`,
+	}
+	for name, raw := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := p.Parse([]byte(raw)); err == nil || !strings.Contains(err.Error(), "node n") {
+				t.Errorf("expected validation error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParser_SingleDo(t *testing.T) {
+	node, err := NewParser().Parse([]byte(`{"id": "n", "do": {"id": "x", "name": "a"}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if node.Do == nil || node.Do.Name != "a" || len(node.Batch) != 0 {
+		t.Errorf("single 'do' must stay untouched: %+v", node)
+	}
+}
+
+func TestParser_Strict(t *testing.T) {
+	raw := []byte(`{"id": "n", "type": "text", "to": "end"}`)
+	if _, err := NewParser().Parse(raw); err != nil {
+		t.Fatalf("lenient parser must ignore unknown keys: %v", err)
+	}
+	_, err := NewParser(WithStrict(true)).Parse(raw)
+	if err == nil || !strings.Contains(err.Error(), `unknown field "to"`) {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+	// Batch lowering keeps `do` lists valid in strict mode
+	if _, err := NewParser(WithStrict(true)).Parse([]byte(`{"id": "n", "do": [{"name": "a"}]}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
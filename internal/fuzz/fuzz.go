@@ -0,0 +1,688 @@
+// Package fuzz implements `trellis fuzz`: automatic exploration of a flow's state space.
+//
+// Starting from the entry node, the explorer drives the engine through every input it can
+// derive from the graph (choice options, yes/no for confirms, the literals of `input == '...'`
+// conditions, defaults and handled signals) and every tool outcome (success, error, denial).
+// States are deduplicated by node, status and context, so loops are explored once. Along the
+// way it reports panics, engine errors, tool errors no node handles, states from which the
+// flow can no longer end, and paths that exhaust the flow budget. Each finding carries a
+// reproducer: a `trellis test` scenario replaying the shortest path found to it.
+package fuzz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aretw0/trellis/internal/runtime"
+	"github.com/aretw0/trellis/internal/scenario"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/schema"
+)
+
+// Finding kinds.
+const (
+	KindPanic     = "panic"
+	KindError     = "error"                // Render, Navigate or Signal failed
+	KindToolError = "unhandled-tool-error" // A tool error with no on_error or error node
+	KindStuck     = "stuck"                // The flow can no longer end, or loops without input
+	KindBudget    = "budget"               // A path exhausts the flow budget
+)
+
+// Defaults of Options.
+const (
+	DefaultMaxDepth  = 25
+	DefaultMaxStates = 10000
+)
+
+// Strategies of Options.
+const (
+	BreadthFirst = "bfs"
+	DepthFirst   = "dfs"
+)
+
+// FreeText is the input tried at prompts whose answers are open-ended.
+const FreeText = "fuzz"
+
+// Options configures an exploration.
+type Options struct {
+	// Strategy is BreadthFirst (default: shortest reproducers first) or DepthFirst.
+	Strategy string
+	// MaxDepth bounds the choices (inputs, signals, tool outcomes) along a path.
+	MaxDepth int
+	// MaxStates bounds the distinct states explored.
+	MaxStates int
+	// Context is merged over the initial context generated from the entry node.
+	Context map[string]any
+	// Inputs are tried at open-ended prompts, besides FreeText.
+	Inputs []string
+}
+
+// Finding is a problem found on some path of the flow.
+type Finding struct {
+	Kind    string `json:"kind"`
+	NodeID  string `json:"node"`
+	Message string `json:"message"`
+	// Path lists the nodes visited up to the finding.
+	Path []string `json:"path"`
+	// Scenario replays the path (see WriteScenarios).
+	Scenario scenario.Scenario `json:"-"`
+}
+
+// Report is the outcome of an exploration.
+type Report struct {
+	Findings []Finding `json:"findings"`
+	// States is the number of distinct states explored.
+	States int `json:"states"`
+	// Covered lists the nodes reached; Unreached the other nodes of the graph.
+	Covered   []string `json:"covered"`
+	Unreached []string `json:"unreached,omitempty"`
+	// Truncated is set when MaxDepth or MaxStates cut the exploration.
+	Truncated bool `json:"truncated"`
+}
+
+// item is a state reached by a path of choices.
+type item struct {
+	state   *domain.State
+	key     string
+	steps   []scenario.Step
+	calls   []toolCall
+	visited []string
+	depth   int
+	// auto holds the states since the last choice, to catch loops that never ask for input.
+	auto map[string]bool
+}
+
+// toolCall is the outcome chosen for a tool call.
+type toolCall struct {
+	name string
+	mock scenario.ToolMock
+}
+
+// choice is one way to leave a state.
+type choice struct {
+	auto  bool
+	step  *scenario.Step
+	calls []toolCall
+	input any // What Navigate receives for tool outcomes
+}
+
+type explorer struct {
+	ctx     context.Context
+	engine  scenario.Engine
+	opts    Options
+	nodes   map[string]*domain.Node
+	signals []string // Handled on every node (entry on_signal_default)
+	initial map[string]any
+
+	findings map[string]*Finding
+	order    []string
+	covered  map[string]bool
+
+	edges    map[string][]string
+	ended    map[string]bool  // Terminated, failed, or cut by the limits
+	prompts  map[string]*item // States waiting for input, by key
+	budgeted map[string]bool  // Nodes already reported for an exhausted budget
+}
+
+// endKey is where choices that fail lead, for the stuck analysis.
+const endKey = "<end>"
+
+// Explore walks the state space of the flow behind engine.
+func Explore(ctx context.Context, engine scenario.Engine, opts Options) (*Report, error) {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+	if opts.MaxStates <= 0 {
+		opts.MaxStates = DefaultMaxStates
+	}
+	switch opts.Strategy {
+	case "":
+		opts.Strategy = BreadthFirst
+	case BreadthFirst, DepthFirst:
+	default:
+		return nil, fmt.Errorf("unknown strategy %q (use bfs or dfs)", opts.Strategy)
+	}
+
+	list, err := engine.Inspect()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the graph: %w", err)
+	}
+	x := &explorer{
+		ctx:      ctx,
+		engine:   engine,
+		opts:     opts,
+		nodes:    make(map[string]*domain.Node, len(list)),
+		findings: make(map[string]*Finding),
+		covered:  make(map[string]bool),
+		edges:    make(map[string][]string),
+		ended:    map[string]bool{endKey: true},
+		prompts:  make(map[string]*item),
+		budgeted: make(map[string]bool),
+	}
+	for i := range list {
+		x.nodes[list[i].ID] = &list[i]
+	}
+
+	root, err := x.start()
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{}
+	if root != nil {
+		report.States, report.Truncated = x.walk(root)
+		x.findStuck()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, key := range x.order {
+		report.Findings = append(report.Findings, *x.findings[key])
+	}
+	for id := range x.nodes {
+		if x.covered[id] {
+			report.Covered = append(report.Covered, id)
+		} else {
+			report.Unreached = append(report.Unreached, id)
+		}
+	}
+	sort.Strings(report.Covered)
+	sort.Strings(report.Unreached)
+	return report, nil
+}
+
+// start begins the session with a context generated from the entry node: sample values for
+// its context_schema and required_context, overridden by Options.Context.
+func (x *explorer) start() (*item, error) {
+	var probe *domain.State
+	err := x.guard(func() error {
+		var err error
+		probe, err = x.engine.Start(x.ctx, "fuzz", x.opts.Context)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start a session: %w", err)
+	}
+	x.initial = make(map[string]any)
+	if entry := x.nodes[probe.CurrentNodeID]; entry != nil {
+		for _, key := range entry.RequiredContext {
+			x.initial[key] = FreeText
+		}
+		for key, t := range entry.ContextSchema {
+			x.initial[key] = sample(t)
+		}
+		for name := range entry.OnSignalDefault {
+			x.signals = append(x.signals, name)
+		}
+		sort.Strings(x.signals)
+	}
+	for key, v := range x.opts.Context {
+		x.initial[key] = v
+	}
+
+	root := &item{visited: []string{probe.CurrentNodeID}, auto: map[string]bool{}}
+	var state *domain.State
+	err = x.guard(func() error {
+		var err error
+		state, err = x.engine.Start(x.ctx, "fuzz", x.initial)
+		return err
+	})
+	if err != nil {
+		x.report(root, probe.CurrentNodeID, err)
+		return nil, nil
+	}
+	root.state, root.key = state, stateKey(state)
+	x.covered[state.CurrentNodeID] = true
+	return root, nil
+}
+
+// sample returns a value that satisfies a context_schema type.
+func sample(t schema.Type) any {
+	name := t.Name()
+	switch {
+	case name == "int":
+		return 42
+	case name == "float":
+		return 1.5
+	case name == "bool":
+		return true
+	case strings.HasPrefix(name, "["):
+		return []any{}
+	}
+	return FreeText
+}
+
+// walk explores from root and returns the number of states and whether the limits cut it.
+func (x *explorer) walk(root *item) (int, bool) {
+	queue := []*item{root}
+	seen := map[string]bool{root.key: true}
+	truncated := false
+	for len(queue) > 0 && x.ctx.Err() == nil {
+		var it *item
+		if x.opts.Strategy == DepthFirst {
+			it, queue = queue[len(queue)-1], queue[:len(queue)-1]
+		} else {
+			it, queue = queue[0], queue[1:]
+		}
+		if it.depth >= x.opts.MaxDepth {
+			x.ended[it.key], truncated = true, true
+			continue
+		}
+		children := x.expand(it)
+		if x.opts.Strategy == DepthFirst {
+			slices.Reverse(children) // First choices are explored first
+		}
+		for _, c := range children {
+			x.edges[it.key] = append(x.edges[it.key], c.key)
+			if seen[c.key] || x.ended[c.key] {
+				continue
+			}
+			if len(seen) >= x.opts.MaxStates {
+				x.ended[c.key], truncated = true, true
+				continue
+			}
+			seen[c.key] = true
+			queue = append(queue, c)
+		}
+	}
+	return len(seen), truncated
+}
+
+// expand renders the state and follows each choice it offers, returning the states reached.
+func (x *explorer) expand(it *item) []*item {
+	state := it.state
+	var actions []domain.ActionRequest
+	var isTerminal bool
+	err := x.guard(func() error {
+		var err error
+		actions, isTerminal, err = x.engine.Render(x.ctx, state)
+		return err
+	})
+	if err != nil {
+		x.report(it, state.CurrentNodeID, err)
+		x.ended[it.key] = true
+		return nil
+	}
+
+	view := domain.NewRenderView(actions)
+	var choices []choice
+	switch {
+	case state.Status == domain.StatusWaitingForTool || state.Status == domain.StatusRollingBack:
+		choices = x.toolChoices(it, view.Pending(state))
+	case view.NeedsInput:
+		x.prompts[it.key] = it
+		choices = x.inputChoices(state)
+	default:
+		choices = []choice{{auto: true, input: ""}}
+	}
+
+	var children []*item
+	for _, ch := range choices {
+		child := it.follow(ch)
+		var next *domain.State
+		err := x.guard(func() error {
+			var err error
+			if ch.step != nil && ch.step.Signal != "" {
+				next, err = x.engine.Signal(x.ctx, state, ch.step.Signal)
+			} else if ch.step != nil {
+				next, err = x.engine.Navigate(x.ctx, state, *ch.step.Input)
+			} else {
+				next, err = x.engine.Navigate(x.ctx, state, ch.input)
+			}
+			return err
+		})
+		if err != nil {
+			x.report(child, state.CurrentNodeID, err)
+			x.edges[it.key] = append(x.edges[it.key], endKey)
+			continue
+		}
+
+		child.visited = append(child.visited, domain.Entered(state, next)...)
+		child.state, child.key = next, stateKey(next)
+		for _, id := range child.visited[len(it.visited):] {
+			x.covered[id] = true
+		}
+		if domain.FlowEnded(state, next, isTerminal, view.NeedsInput) {
+			x.ended[child.key] = true
+			x.edges[it.key] = append(x.edges[it.key], child.key)
+			continue
+		}
+		if ch.auto && (child.auto[child.key] || child.key == it.key) {
+			x.add(child, KindStuck, next.CurrentNodeID, fmt.Sprintf("node '%s' loops without asking for input", next.CurrentNodeID))
+			x.edges[it.key] = append(x.edges[it.key], endKey) // Reported: not stuck again below
+			continue
+		}
+		children = append(children, child)
+	}
+	return children
+}
+
+// follow returns the path extended with a choice.
+func (it *item) follow(ch choice) *item {
+	child := &item{
+		steps:   slices.Clone(it.steps),
+		calls:   slices.Clone(it.calls),
+		visited: slices.Clone(it.visited),
+		depth:   it.depth,
+		auto:    map[string]bool{},
+	}
+	if ch.auto {
+		for k := range it.auto {
+			child.auto[k] = true
+		}
+		child.auto[it.key] = true
+	} else {
+		child.depth++
+	}
+	if ch.step != nil {
+		child.steps = append(child.steps, *ch.step)
+	}
+	child.calls = append(child.calls, ch.calls...)
+	return child
+}
+
+// toolChoices lists the outcomes tried for the pending calls: all succeed, or one call fails
+// (error, or denial outside rollbacks) while the others succeed. With the flow budget
+// exhausted, calls are denied as the runner's budget middleware would do.
+func (x *explorer) toolChoices(it *item, calls []domain.ToolCall) []choice {
+	state := it.state
+	if len(calls) == 0 {
+		return nil
+	}
+	if state.Status == domain.StatusWaitingForTool {
+		budget := domain.BudgetFromState(state)
+		if exhausted, reason := budget.Exhausted(domain.UsageFromState(state).UsageTotals); !budget.IsZero() && exhausted {
+			denied := make([]scenario.ToolMock, len(calls))
+			for i := range denied {
+				denied[i] = scenario.ToolMock{Denied: true, Error: reason}
+			}
+			ch := outcome(state, calls, denied)
+			if !x.budgeted[state.CurrentNodeID] {
+				x.budgeted[state.CurrentNodeID] = true
+				x.add(it.follow(ch), KindBudget, state.CurrentNodeID, fmt.Sprintf("%s before calling %s (the call is denied)", reason, callNames(calls)))
+			}
+			return []choice{ch}
+		}
+	}
+
+	// An object, so templates reading fields of the result (`{{ .receipt.id }}`) still render.
+	ok := scenario.ToolMock{Result: map[string]any{"status": "ok"}}
+	failures := []scenario.ToolMock{{Error: "fuzz: simulated failure"}}
+	if state.Status == domain.StatusWaitingForTool {
+		failures = append(failures, scenario.ToolMock{Denied: true})
+	}
+	mocks := make([]scenario.ToolMock, len(calls))
+	for i := range mocks {
+		mocks[i] = ok
+	}
+	choices := []choice{outcome(state, calls, mocks)}
+	for i := range calls {
+		for _, failure := range failures {
+			mocks := slices.Clone(mocks)
+			mocks[i] = failure
+			choices = append(choices, outcome(state, calls, mocks))
+		}
+	}
+	return choices
+}
+
+// outcome answers calls with mocks, as a single result or a batch.
+func outcome(state *domain.State, calls []domain.ToolCall, mocks []scenario.ToolMock) choice {
+	ch := choice{}
+	results := make([]domain.ToolResult, len(calls))
+	for i, call := range calls {
+		m := mocks[i]
+		ch.calls = append(ch.calls, toolCall{name: call.Name, mock: m})
+		results[i] = m.Answer(call.ID)
+	}
+	ch.input = domain.ToolInput(state, results)
+	return ch
+}
+
+func callNames(calls []domain.ToolCall) string {
+	names := make([]string, len(calls))
+	for i, c := range calls {
+		names[i] = "'" + c.Name + "'"
+	}
+	return strings.Join(names, ", ")
+}
+
+// conditionLiteral captures the value of `input == 'value'` conditions.
+var conditionLiteral = regexp.MustCompile(`^\s*input\s*==\s*['"]([^'"]*)['"]\s*$`)
+
+// inputChoices lists the inputs and signals tried at a prompt.
+func (x *explorer) inputChoices(state *domain.State) []choice {
+	var inputs []string
+	signals := x.signals
+	if n := x.nodes[state.CurrentNodeID]; n != nil {
+		switch domain.InputType(n.InputType) {
+		case domain.InputConfirm:
+			inputs = []string{"yes", "no"}
+		case domain.InputChoice:
+			inputs = slices.Clone(n.InputOptions)
+		}
+		for _, t := range n.Transitions {
+			if m := conditionLiteral.FindStringSubmatch(t.Condition); m != nil {
+				inputs = append(inputs, m[1])
+			}
+		}
+		if len(inputs) == 0 || (n.InputType != string(domain.InputConfirm) && n.InputType != string(domain.InputChoice)) {
+			if n.InputDefault != "" {
+				inputs = append(inputs, "")
+			}
+			if n.OnDenied != "" {
+				inputs = append(inputs, "no")
+			}
+			inputs = append(inputs, FreeText)
+			inputs = append(inputs, x.opts.Inputs...)
+		}
+		for name := range n.OnSignal {
+			signals = append(signals, name)
+		}
+	} else {
+		inputs = append([]string{FreeText}, x.opts.Inputs...)
+	}
+
+	var choices []choice
+	seen := make(map[string]bool)
+	for _, in := range inputs {
+		if seen["i:"+in] {
+			continue
+		}
+		seen["i:"+in] = true
+		in := in
+		choices = append(choices, choice{step: &scenario.Step{Input: &in}})
+	}
+	sort.Strings(signals)
+	for _, sig := range signals {
+		if seen["s:"+sig] {
+			continue
+		}
+		seen["s:"+sig] = true
+		choices = append(choices, choice{step: &scenario.Step{Signal: sig}})
+	}
+	return choices
+}
+
+// guard runs an engine call, turning a panic into a *scenario.PanicError.
+func (x *explorer) guard(call func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &scenario.PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return call()
+}
+
+// report records an engine failure on the path.
+func (x *explorer) report(it *item, nodeID string, err error) {
+	kind := KindError
+	var panicErr *scenario.PanicError
+	var toolErr *runtime.UnhandledToolError
+	switch {
+	case errors.As(err, &panicErr):
+		kind = KindPanic
+	case errors.As(err, &toolErr):
+		kind = KindToolError
+	}
+	x.add(it, kind, nodeID, err.Error())
+}
+
+// add records a finding, keeping the first path found for each kind, node and message.
+func (x *explorer) add(it *item, kind, nodeID, message string) {
+	key := kind + "\x00" + nodeID + "\x00" + message
+	if _, ok := x.findings[key]; ok {
+		return
+	}
+	f := &Finding{Kind: kind, NodeID: nodeID, Message: message, Path: it.visited, Scenario: x.scenario(it, kind, nodeID, message)}
+	if kind == KindPanic || kind == KindError || kind == KindToolError {
+		x.minimize(f)
+	}
+	x.findings[key] = f
+	x.order = append(x.order, key)
+}
+
+// scenario builds the reproducer of a finding: the path's inputs, and the tool outcomes in
+// call order, by tool name.
+func (x *explorer) scenario(it *item, kind, nodeID, message string) scenario.Scenario {
+	sc := scenario.Scenario{
+		Name:        fmt.Sprintf("%s at %s", kind, nodeID),
+		Description: message,
+		Inputs:      it.steps,
+	}
+	if len(x.initial) > 0 {
+		sc.Context = x.initial
+	}
+	for _, c := range it.calls {
+		if sc.Tools == nil {
+			sc.Tools = make(map[string]scenario.ToolMocks)
+		}
+		sc.Tools[c.name] = append(sc.Tools[c.name], c.mock)
+	}
+	for name, mocks := range sc.Tools {
+		sc.Tools[name] = compact(mocks)
+	}
+	if kind == KindStuck {
+		terminated := true
+		sc.Expect.Terminated = &terminated
+	}
+	return sc
+}
+
+// compact drops trailing repeats, since the last mock answers the remaining calls.
+func compact(mocks scenario.ToolMocks) scenario.ToolMocks {
+	for len(mocks) > 1 && equalMock(mocks[len(mocks)-1], mocks[len(mocks)-2]) {
+		mocks = mocks[:len(mocks)-1]
+	}
+	return mocks
+}
+
+func equalMock(a, b scenario.ToolMock) bool {
+	return a.Error == b.Error && a.Denied == b.Denied && fmt.Sprint(a.Result) == fmt.Sprint(b.Result)
+}
+
+// minimize drops the inputs the failure does not need, replaying the reproducer each time.
+func (x *explorer) minimize(f *Finding) {
+	sc := f.Scenario
+	for i := 0; i < len(sc.Inputs); {
+		candidate := sc
+		candidate.Inputs = slices.Delete(slices.Clone(sc.Inputs), i, i+1)
+		if x.reproduces(candidate, f) {
+			sc = candidate
+		} else {
+			i++
+		}
+	}
+	f.Scenario = sc
+}
+
+// reproduces reports whether the scenario fails the same way at the same node.
+func (x *explorer) reproduces(sc scenario.Scenario, f *Finding) bool {
+	out := scenario.Play(x.ctx, x.engine, &scenario.File{}, sc, scenario.Options{})
+	if out.Err == nil || out.State == nil || out.State.CurrentNodeID != f.NodeID || len(out.Failures) > 0 {
+		return false
+	}
+	var panicErr *scenario.PanicError
+	var toolErr *runtime.UnhandledToolError
+	switch f.Kind {
+	case KindPanic:
+		return errors.As(out.Err, &panicErr)
+	case KindToolError:
+		return errors.As(out.Err, &toolErr)
+	}
+	return out.Err.Error() == f.Message
+}
+
+// findStuck reports the prompts from which no explored path reaches an end. Paths cut by
+// the limits count as ending, so only fully explored regions are reported.
+func (x *explorer) findStuck() {
+	reverse := make(map[string][]string)
+	for from, tos := range x.edges {
+		for _, to := range tos {
+			reverse[to] = append(reverse[to], from)
+		}
+	}
+	canEnd := make(map[string]bool)
+	var queue []string
+	for key := range x.ended {
+		canEnd[key] = true
+		queue = append(queue, key)
+	}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		for _, from := range reverse[key] {
+			if !canEnd[from] {
+				canEnd[from] = true
+				queue = append(queue, from)
+			}
+		}
+	}
+
+	var stuck []*item
+	for key, it := range x.prompts {
+		if !canEnd[key] {
+			stuck = append(stuck, it)
+		}
+	}
+	// One finding per node, from its shortest path.
+	sort.Slice(stuck, func(i, j int) bool {
+		if stuck[i].depth != stuck[j].depth {
+			return stuck[i].depth < stuck[j].depth
+		}
+		return stuck[i].key < stuck[j].key
+	})
+	reported := make(map[string]bool)
+	for _, it := range stuck {
+		id := it.state.CurrentNodeID
+		if reported[id] {
+			continue
+		}
+		reported[id] = true
+		x.add(it, KindStuck, id, fmt.Sprintf("no input or tool outcome leads from node '%s' to the end of the flow", id))
+	}
+}
+
+// stateKey identifies a state for deduplication: node, status, pending calls and context.
+// The history is left out, so the same situation reached by different paths is explored once.
+func stateKey(s *domain.State) string {
+	data, err := json.Marshal(struct {
+		Node    string
+		Status  domain.ExecutionStatus
+		Pending string
+		Batch   []string
+		Context map[string]any
+		System  map[string]any
+	}{s.CurrentNodeID, s.Status, s.PendingToolCall, s.PendingToolCalls, s.Context, s.SystemContext})
+	if err != nil {
+		return fmt.Sprintf("%s/%s/%v", s.CurrentNodeID, s.Status, s.Context)
+	}
+	return string(data)
+}
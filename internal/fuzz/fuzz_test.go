@@ -0,0 +1,199 @@
+package fuzz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/internal/scenario"
+	"github.com/aretw0/trellis/internal/testutils/flowtest"
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+var shop = map[string]string{
+	"start.md": "---\nwait: true\ninput_type: choice\ninput_options: [buy, browse, help, spin]\ntransitions:\n" +
+		"  - { condition: input == 'buy', to: pay }\n" +
+		"  - { condition: input == 'browse', to: browse }\n" +
+		"  - { condition: input == 'help', to: help }\n" +
+		"  - { condition: input == 'spin', to: spin }\n" +
+		"---\nMenu\n",
+	"pay.yaml":       "do: { name: charge }\non_denied: start\nto: bye\n",
+	"browse.md":      "---\nwait: true\ntransitions:\n  - { condition: input == 'back', to: start }\n---\nBrowsing\n",
+	"help.md":        "---\nwait: true\nto: help\n---\nThere is no way out.\n",
+	"spin.yaml":      "to: spin2\n",
+	"spin2.yaml":     "to: spin\n",
+	"bye.md":         "Bye\n",
+	"orphan.md":      "Nobody links here.\n",
+	"shop.test.yaml": "scenarios: []\n",
+}
+
+func byKind(findings []Finding) map[string]Finding {
+	out := make(map[string]Finding)
+	for _, f := range findings {
+		out[f.Kind+" "+f.NodeID] = f
+	}
+	return out
+}
+
+func TestExplore(t *testing.T) {
+	engine := flowtest.NewEngine(t, shop)
+
+	for _, strategy := range []string{BreadthFirst, DepthFirst} {
+		t.Run(strategy, func(t *testing.T) {
+			report, err := Explore(context.Background(), engine, Options{Strategy: strategy})
+			require.NoError(t, err)
+
+			found := byKind(report.Findings)
+			assert.Len(t, found, 3, report.Findings)
+			assert.Equal(t, []string{"browse", "bye", "help", "pay", "spin", "spin2", "start"}, report.Covered)
+			assert.Equal(t, []string{"orphan"}, report.Unreached)
+			assert.False(t, report.Truncated)
+
+			toolErr := found["unhandled-tool-error pay"]
+			assert.Contains(t, toolErr.Message, "no 'on_error' handler")
+			assert.Equal(t, []string{"start", "pay"}, toolErr.Path)
+			assert.Equal(t, "buy", *toolErr.Scenario.Inputs[0].Input)
+			assert.Equal(t, scenario.ToolMocks{{Error: "fuzz: simulated failure"}}, toolErr.Scenario.Tools["charge"])
+
+			// The loop is caught on whichever of its nodes repeats first.
+			loop, ok := found["stuck spin2"]
+			if !ok {
+				loop = found["stuck spin"]
+			}
+			assert.Contains(t, loop.Message, "loops without asking for input")
+
+			stuck := found["stuck help"]
+			assert.Equal(t, "no input or tool outcome leads from node 'help' to the end of the flow", stuck.Message)
+			require.NotNil(t, stuck.Scenario.Expect.Terminated)
+			assert.True(t, *stuck.Scenario.Expect.Terminated)
+		})
+	}
+
+	_, err := Explore(context.Background(), engine, Options{Strategy: "random"})
+	assert.EqualError(t, err, `unknown strategy "random" (use bfs or dfs)`)
+}
+
+func TestExplore_Limits(t *testing.T) {
+	engine := flowtest.NewEngine(t, shop)
+
+	report, err := Explore(context.Background(), engine, Options{MaxDepth: 1})
+	require.NoError(t, err)
+	assert.True(t, report.Truncated)
+	// Cut paths count as ending: only the loops found within the depth are reported.
+	assert.NotContains(t, byKind(report.Findings), "stuck help")
+
+	report, err = Explore(context.Background(), engine, Options{MaxStates: 2})
+	require.NoError(t, err)
+	assert.True(t, report.Truncated)
+	assert.Equal(t, 2, report.States)
+}
+
+func TestExplore_Budget(t *testing.T) {
+	engine := flowtest.NewEngine(t, map[string]string{
+		"start.yaml":  "budget: { max_calls: 1 }\ndo: { name: lookup }\non_error: notify\nto: notify\n",
+		"notify.yaml": "do: { name: send }\non_error: done\non_denied: done\nto: done\n",
+		"done.md":     "Done\n",
+	})
+
+	report, err := Explore(context.Background(), engine, Options{})
+	require.NoError(t, err)
+	require.Len(t, report.Findings, 1)
+	f := report.Findings[0]
+	assert.Equal(t, KindBudget, f.Kind)
+	assert.Equal(t, "notify", f.NodeID)
+	assert.Equal(t, "tool call budget exhausted (1/1 calls) before calling 'send' (the call is denied)", f.Message)
+
+	// The reproducer answers the call with the denial the budget middleware would give.
+	assert.Equal(t, scenario.ToolMocks{{Denied: true, Error: "tool call budget exhausted (1/1 calls)"}}, f.Scenario.Tools["send"])
+	results := scenario.Run(context.Background(), engine, []*scenario.File{Scenarios(report.Findings)}, scenario.Options{})
+	assert.True(t, results[0].Passed, results[0].Failures)
+}
+
+// panicky panics when rendering a node, as a broken engine extension would.
+type panicky struct {
+	*trellis.Engine
+	node string
+}
+
+func (p panicky) Render(ctx context.Context, state *domain.State) ([]domain.ActionRequest, bool, error) {
+	if state.CurrentNodeID == p.node {
+		panic("boom")
+	}
+	return p.Engine.Render(ctx, state)
+}
+
+func TestExplore_Panic(t *testing.T) {
+	engine := flowtest.NewEngine(t, map[string]string{
+		"start.md": "---\nwait: true\ninput_type: confirm\nrequired_context: [user]\ncontext_schema: { age: int }\n" +
+			"on_signal_default: { interrupt: bye }\ntransitions:\n  - { condition: input == 'yes', to: name }\n  - { to: bye }\n---\nHi {{ .user }}, go on?\n",
+		"name.md":  "---\nwait: true\nsave_to: name\nto: crash\n---\nName?\n",
+		"crash.md": "Crashing\n",
+		"bye.md":   "Bye\n",
+	})
+
+	report, err := Explore(context.Background(), panicky{engine, "crash"}, Options{Context: map[string]any{"age": 7}})
+	require.NoError(t, err)
+	require.Len(t, report.Findings, 1)
+	f := report.Findings[0]
+	assert.Equal(t, KindPanic, f.Kind)
+	assert.Equal(t, "crash", f.NodeID)
+	assert.Equal(t, "panic: boom", f.Message)
+	assert.Equal(t, []string{"start", "name", "crash"}, f.Path)
+	// Required context gets a sample value; Options.Context overrides schema samples.
+	assert.Equal(t, map[string]any{"user": FreeText, "age": 7}, f.Scenario.Context)
+	assert.Len(t, f.Scenario.Inputs, 2)
+	assert.Equal(t, "yes", *f.Scenario.Inputs[0].Input)
+}
+
+func TestMinimize(t *testing.T) {
+	engine := flowtest.NewEngine(t, shop)
+	x := &explorer{ctx: context.Background(), engine: engine}
+
+	buy, extra := "buy", "extra"
+	f := &Finding{Kind: KindToolError, NodeID: "pay", Scenario: scenario.Scenario{
+		Name:   "unhandled-tool-error at pay",
+		Tools:  map[string]scenario.ToolMocks{"charge": {{Error: "down"}}},
+		Inputs: []scenario.Step{{Input: &extra}, {Input: &buy}, {Input: &extra}},
+	}}
+	x.minimize(f)
+	require.Len(t, f.Scenario.Inputs, 1)
+	assert.Equal(t, "buy", *f.Scenario.Inputs[0].Input)
+}
+
+func TestWrite(t *testing.T) {
+	engine := flowtest.NewEngine(t, shop)
+	report, err := Explore(context.Background(), engine, Options{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, "text", report))
+	assert.Contains(t, buf.String(), "--- UNHANDLED-TOOL-ERROR at pay: ")
+	assert.Contains(t, buf.String(), "    path: start → pay\n")
+	assert.Contains(t, buf.String(), "FAIL: 3 findings, ")
+	assert.Contains(t, buf.String(), "7/8 nodes reached\nunreached: orphan\n")
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, "json", report))
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Len(t, decoded["findings"], 3)
+
+	assert.EqualError(t, Write(&buf, "xml", report), `unknown format "xml" (use text or json)`)
+
+	// Reproducers are valid scenario files, and fail while the findings stand.
+	path := filepath.Join(t.TempDir(), "fuzz.test.yaml")
+	require.NoError(t, WriteScenarios(path, append(report.Findings, report.Findings[0])))
+	f, err := scenario.Load(path)
+	require.NoError(t, err)
+	require.Len(t, f.Scenarios, 4)
+	assert.Equal(t, f.Scenarios[0].Name+" (2)", f.Scenarios[3].Name)
+	for _, r := range scenario.Run(context.Background(), engine, []*scenario.File{f}, scenario.Options{MaxSteps: 50}) {
+		assert.False(t, r.Passed, r.Name)
+	}
+}
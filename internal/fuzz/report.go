@@ -0,0 +1,101 @@
+package fuzz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aretw0/trellis/internal/scenario"
+)
+
+// WriteText prints one block per finding, with its path, and a summary.
+func WriteText(w io.Writer, r *Report) error {
+	for _, f := range r.Findings {
+		if _, err := fmt.Fprintf(w, "--- %s at %s: %s\n    path: %s\n", strings.ToUpper(f.Kind), f.NodeID, strings.ReplaceAll(f.Message, "\n", "\n    "), strings.Join(f.Path, " → ")); err != nil {
+			return err
+		}
+	}
+	status := "ok"
+	if len(r.Findings) > 0 {
+		status = "FAIL"
+	}
+	covered := len(r.Covered)
+	total := covered + len(r.Unreached)
+	if _, err := fmt.Fprintf(w, "%s: %d findings, %d states, %d/%d nodes reached\n", status, len(r.Findings), r.States, covered, total); err != nil {
+		return err
+	}
+	if len(r.Unreached) > 0 {
+		if _, err := fmt.Fprintf(w, "unreached: %s\n", strings.Join(r.Unreached, ", ")); err != nil {
+			return err
+		}
+	}
+	if r.Truncated {
+		_, err := fmt.Fprintln(w, "exploration cut by --max-depth or --max-states: some paths were not followed")
+		return err
+	}
+	return nil
+}
+
+// WriteJSON prints the report as indented JSON.
+func WriteJSON(w io.Writer, r *Report) error {
+	out := *r
+	if out.Findings == nil {
+		out.Findings = []Finding{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// Write prints the report in the given format: text or json.
+func Write(w io.Writer, format string, r *Report) error {
+	switch format {
+	case "", "text":
+		return WriteText(w, r)
+	case "json":
+		return WriteJSON(w, r)
+	}
+	return fmt.Errorf("unknown format %q (use text or json)", format)
+}
+
+// Scenarios returns the reproducers of the findings as a scenario file. Names are made
+// unique, as `trellis test` requires.
+func Scenarios(findings []Finding) *scenario.File {
+	f := &scenario.File{}
+	names := make(map[string]int)
+	for _, finding := range findings {
+		sc := finding.Scenario
+		names[sc.Name]++
+		if n := names[sc.Name]; n > 1 {
+			sc.Name = fmt.Sprintf("%s (%d)", sc.Name, n)
+		}
+		f.Scenarios = append(f.Scenarios, sc)
+	}
+	return f
+}
+
+// scenarioHeader explains a generated file to whoever opens it.
+const scenarioHeader = `# Reproducers generated by trellis fuzz. Each scenario replays the shortest path found to
+# a finding and fails while the finding stands; budget findings replay the denied call.
+# Run them with: trellis test
+`
+
+// WriteScenarios writes the reproducers of the findings to path, as a `trellis test` file.
+func WriteScenarios(path string, findings []Finding) error {
+	var buf bytes.Buffer
+	buf.WriteString(scenarioHeader)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(Scenarios(findings)); err != nil {
+		return fmt.Errorf("failed to encode reproducers: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write reproducers: %w", err)
+	}
+	return nil
+}
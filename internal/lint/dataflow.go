@@ -0,0 +1,315 @@
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// Context keys the engine fills after tool calls: the last successful result of a single
+// do, and the outcomes of a batch keyed by call ID.
+const (
+	toolResultKey  = "tool_result"
+	toolResultsKey = "tool_results"
+)
+
+// flowEdge is an edge of the execution graph, with the keys written when it is taken.
+type flowEdge struct {
+	kind   string
+	from   string
+	to     string
+	writes []string
+}
+
+// dataflow holds, for each node reachable from the entry, the context keys that are set
+// when the node renders: on every path (must) or on at least one path (may).
+type dataflow struct {
+	must map[string]map[string]bool
+	may  map[string]map[string]bool
+
+	entry   string
+	initial map[string]bool
+	out     map[string][]flowEdge // outgoing edges by source node
+	byID    map[string]*domain.Node
+}
+
+// initialKeys are set before the entry node renders: the keys declared in the manifest,
+// the entry's defaults, and the entry's own requirements, which the engine checks at
+// session start and which stay in the context afterwards.
+func (g *graph) initialKeys() map[string]bool {
+	keys := make(map[string]bool)
+	for _, k := range g.opts.Context {
+		keys[k] = true
+	}
+	if entry := g.byID[g.opts.Entry]; entry != nil {
+		for k := range entry.DefaultContext {
+			keys[k] = true
+		}
+		for _, k := range entry.RequiredContext {
+			keys[k] = true
+		}
+		for k := range entry.ContextSchema {
+			keys[k] = true
+		}
+	}
+	return keys
+}
+
+// flowEdges lists how the engine can leave n. Only the regular transitions go through
+// the update phase (save_to, tool_result or tool_results); error, denial, signal and unclear routes jump
+// without writing. Tool nodes without on_error fall back to the global error node, and the
+// entry's on_signal_default handlers fire from any node.
+func (g *graph) flowEdges(n *domain.Node, globalSignals map[string]string) []flowEdge {
+	var writes []string
+	if n.SaveTo != "" {
+		writes = append(writes, n.SaveTo)
+	}
+	switch {
+	case len(n.Batch) > 0:
+		writes = append(writes, toolResultsKey)
+	case n.Do != nil:
+		writes = append(writes, toolResultKey)
+	}
+
+	var out []flowEdge
+	add := func(kind, to string, w []string) {
+		if to != "" && !domain.IsRollback(to) {
+			out = append(out, flowEdge{kind: kind, from: n.ID, to: to, writes: w})
+		}
+	}
+	for _, t := range n.Transitions {
+		add("transition", t.ToNodeID, writes)
+	}
+	add("on_error", n.OnError, nil)
+	add("on_denied", n.OnDenied, nil)
+	add("on_unclear", n.OnUnclear, nil)
+	if n.HasTools() && n.OnError == "" {
+		add("error_node", g.opts.ErrorNode, nil)
+	}
+	for _, signal := range sortedKeys(n.OnSignal) {
+		add("on_signal."+signal, n.OnSignal[signal], nil)
+	}
+	for _, signal := range sortedKeys(globalSignals) {
+		if _, local := n.OnSignal[signal]; !local {
+			add("on_signal_default."+signal, globalSignals[signal], nil)
+		}
+	}
+	return out
+}
+
+// analyze computes which context keys are set when each node reachable from the entry renders.
+// Keys written on entering a node (default_context) count for that node.
+func (g *graph) analyze() *dataflow {
+	df := &dataflow{
+		must:    make(map[string]map[string]bool),
+		may:     make(map[string]map[string]bool),
+		entry:   g.opts.Entry,
+		initial: g.initialKeys(),
+		out:     make(map[string][]flowEdge),
+		byID:    g.byID,
+	}
+	entry := g.byID[g.opts.Entry]
+	if entry == nil {
+		return df
+	}
+	for id, n := range g.byID {
+		df.out[id] = g.flowEdges(n, entry.OnSignalDefault)
+	}
+
+	df.must[entry.ID] = copySet(df.initial)
+	df.may[entry.ID] = copySet(df.initial)
+	queue := []string{entry.ID}
+	queued := map[string]bool{entry.ID: true}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		queued[id] = false
+
+		for _, e := range df.out[id] {
+			target := g.byID[e.to]
+			if target == nil {
+				continue // Reported by dangling-ref
+			}
+			must := df.enter(df.must[id], e, target)
+			may := df.enter(df.may[id], e, target)
+
+			changed := false
+			if prev, seen := df.must[e.to]; !seen {
+				df.must[e.to], df.may[e.to] = must, may
+				changed = true
+			} else {
+				changed = intersect(prev, must) || changed
+				changed = union(df.may[e.to], may) || changed
+			}
+			if changed && !queued[e.to] {
+				queue = append(queue, e.to)
+				queued[e.to] = true
+			}
+		}
+	}
+	return df
+}
+
+// enter returns the keys set at target after taking e with keys set at its source.
+func (df *dataflow) enter(keys map[string]bool, e flowEdge, target *domain.Node) map[string]bool {
+	next := copySet(keys)
+	for _, k := range e.writes {
+		next[k] = true
+	}
+	for k := range target.DefaultContext {
+		next[k] = true
+	}
+	return next
+}
+
+// pathWithout returns a path from the entry to the node on which key is never set,
+// or nil when every path sets it.
+func (df *dataflow) pathWithout(nodeID, key string) []flowEdge {
+	if df.initial[key] || df.byID[df.entry] == nil {
+		return nil
+	}
+	if _, ok := df.byID[df.entry].DefaultContext[key]; ok {
+		return nil
+	}
+	parent := map[string]flowEdge{}
+	seen := map[string]bool{df.entry: true}
+	queue := []string{df.entry}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == nodeID {
+			var path []flowEdge
+			for id != df.entry {
+				e := parent[id]
+				path = append([]flowEdge{e}, path...)
+				id = e.from
+			}
+			return path
+		}
+		for _, e := range df.out[id] {
+			target := df.byID[e.to]
+			if target == nil || seen[e.to] || slices.Contains(e.writes, key) {
+				continue
+			}
+			if _, ok := target.DefaultContext[key]; ok {
+				continue
+			}
+			seen[e.to] = true
+			parent[e.to] = e
+			queue = append(queue, e.to)
+		}
+	}
+	return nil
+}
+
+// renderPath prints a path as "start -> ask -[on_error]-> oops".
+func renderPath(entry string, path []flowEdge) string {
+	var b strings.Builder
+	b.WriteString(entry)
+	for _, e := range path {
+		if e.kind == "transition" {
+			b.WriteString(" -> ")
+		} else {
+			fmt.Fprintf(&b, " -[%s]-> ", e.kind)
+		}
+		b.WriteString(e.to)
+	}
+	return b.String()
+}
+
+// pathNodes lists the node IDs of a path, starting at the entry.
+func pathNodes(entry string, path []flowEdge) []string {
+	ids := []string{entry}
+	for _, e := range path {
+		ids = append(ids, e.to)
+	}
+	return ids
+}
+
+func checkMissingContext(g *graph) []Finding {
+	df := g.analyze()
+	var out []Finding
+	for _, n := range g.nodes {
+		must, reached := df.must[n.ID]
+		if !reached || n.ID == g.opts.Entry {
+			continue // Unreachable nodes are reported elsewhere; the entry defines the contract
+		}
+		for _, req := range requirements(n) {
+			if must[req.key] {
+				continue
+			}
+			path := df.pathWithout(n.ID, req.key)
+			if path == nil {
+				continue
+			}
+			how := "is not set on every path"
+			if !df.may[n.ID][req.key] {
+				how = "is never set"
+			}
+			out = append(out, Finding{
+				NodeID:  n.ID,
+				Message: fmt.Sprintf("%s '%s' %s to '%s', e.g. %s", req.field, req.key, how, n.ID, renderPath(g.opts.Entry, path)),
+				Path:    pathNodes(g.opts.Entry, path),
+			})
+		}
+	}
+	return out
+}
+
+// requirement is a key a node checks before rendering.
+type requirement struct {
+	field string
+	key   string
+}
+
+func requirements(n *domain.Node) []requirement {
+	var out []requirement
+	seen := make(map[string]bool)
+	for _, k := range n.RequiredContext {
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, requirement{"required_context", k})
+		}
+	}
+	for _, k := range sortedKeys(n.ContextSchema) {
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, requirement{"context_schema", k})
+		}
+	}
+	return out
+}
+
+func copySet(set map[string]bool) map[string]bool {
+	out := make(map[string]bool, len(set))
+	for k := range set {
+		out[k] = true
+	}
+	return out
+}
+
+// intersect keeps in dst only the keys of src, reporting whether dst changed.
+func intersect(dst, src map[string]bool) bool {
+	changed := false
+	for k := range dst {
+		if !src[k] {
+			delete(dst, k)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// union adds the keys of src to dst, reporting whether dst changed.
+func union(dst, src map[string]bool) bool {
+	changed := false
+	for k := range src {
+		if !dst[k] {
+			dst[k] = true
+			changed = true
+		}
+	}
+	return changed
+}
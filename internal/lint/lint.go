@@ -0,0 +1,260 @@
+// Package lint implements the static analyzer behind `trellis lint`.
+//
+// The analyzer loads every node of a graph and runs a set of rules over it:
+// broken references in any edge kind, unreachable nodes, dead ends, template keys
+// that no node writes, and node definitions the engine would reject at runtime.
+// Findings can be suppressed per node with a `trellis:ignore` comment in the node source.
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aretw0/trellis/internal/compiler"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/ports"
+)
+
+// Severity ranks a finding.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// rank orders severities from least to most severe.
+func (s Severity) rank() int {
+	switch s {
+	case SeverityError:
+		return 2
+	case SeverityWarning:
+		return 1
+	}
+	return 0
+}
+
+// AtLeast reports whether s is as severe as other.
+func (s Severity) AtLeast(other Severity) bool {
+	return s.rank() >= other.rank()
+}
+
+// ParseSeverity accepts "error", "warning" or "info".
+func ParseSeverity(s string) (Severity, error) {
+	switch sev := Severity(strings.ToLower(s)); sev {
+	case SeverityError, SeverityWarning, SeverityInfo:
+		return sev, nil
+	}
+	return "", fmt.Errorf("unknown severity %q (use error, warning or info)", s)
+}
+
+// Finding is a single problem reported by a rule.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	NodeID   string   `json:"node,omitempty"`
+	Message  string   `json:"message"`
+	// File is the node source, joined with Options.Dir (empty for in-memory graphs).
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+	// Path is an example route from the entry node that reproduces the finding, if any.
+	Path []string `json:"path,omitempty"`
+}
+
+// String renders the finding in the compiler-style "file:line: severity: message [rule]" form.
+func (f Finding) String() string {
+	msg := fmt.Sprintf("%s: %s [%s]", f.Severity, f.Message, f.Rule)
+	d := domain.Diagnostic{File: f.File, Line: f.Line, Message: msg}
+	if f.File == "" && f.NodeID != "" {
+		d.File = "node " + f.NodeID
+	}
+	return d.Error()
+}
+
+// Options configures a lint run.
+type Options struct {
+	// Entry is the node the flow starts from (default: "start").
+	Entry string
+	// ErrorNode is the global fallback for tool errors, if any.
+	ErrorNode string
+	// Dir is the project directory node sources are relative to. It prefixes reported
+	// files and is where suppression comments are read from.
+	Dir string
+	// Legacy selects the `{{ key }}` template syntax (manifest `interpolator: legacy`).
+	Legacy bool
+	// Context lists the keys callers provide at session start (manifest `context`).
+	Context []string
+	// Tools lists the tools of the registry. When nil, tool names are not checked.
+	Tools []string
+	// Disable turns rules off by ID.
+	Disable []string
+	// ReadFile reads a node source for suppression comments (default: os.ReadFile under Dir).
+	ReadFile func(name string) ([]byte, error)
+}
+
+// graph is the loaded flow the rules run over.
+type graph struct {
+	opts  Options
+	nodes []*domain.Node // root nodes, sorted by ID
+	byID  map[string]*domain.Node
+	known map[string]bool // every loadable ID, including package nodes
+	// broken holds the nodes that failed to load or parse.
+	broken []Finding
+}
+
+// Result is the outcome of Analyze: the findings and the graph they were computed from.
+type Result struct {
+	Findings []Finding
+	// Nodes holds every node that loaded, including package nodes, by ID.
+	Nodes map[string]*domain.Node
+	// Options are the options of the run, with defaults applied.
+	Options Options
+}
+
+// Run loads every node through the loader and applies all enabled rules.
+// Findings are sorted by file, line and rule. An error is returned only when the
+// graph cannot be listed at all (e.g. ID collisions).
+func Run(loader ports.GraphLoader, opts Options) ([]Finding, error) {
+	res, err := Analyze(loader, opts)
+	if err != nil {
+		return nil, err
+	}
+	return res.Findings, nil
+}
+
+// Analyze is Run for tools that also need the loaded nodes, such as the language server.
+func Analyze(loader ports.GraphLoader, opts Options) (*Result, error) {
+	if opts.Entry == "" {
+		opts.Entry = domain.DefaultStartNodeID
+	}
+	if opts.ReadFile == nil {
+		opts.ReadFile = func(name string) ([]byte, error) {
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(opts.Dir, name)
+			}
+			return os.ReadFile(name)
+		}
+	}
+
+	loader = compiler.NewMacroLoader(loader)
+	ids, err := loader.ListNodes()
+	if err != nil {
+		return nil, fmt.Errorf("structural validation failed: %w", err)
+	}
+	sort.Strings(ids)
+
+	g := &graph{opts: opts, byID: make(map[string]*domain.Node, len(ids)), known: make(map[string]bool, len(ids))}
+	parser := compiler.NewParser()
+	for _, id := range ids {
+		g.known[id] = true
+		raw, err := loader.GetNode(id)
+		if err == nil {
+			var node *domain.Node
+			if node, err = parser.Parse(raw); err == nil {
+				if node.ID == "" {
+					node.ID = id
+				}
+				g.byID[id] = node
+				if !isPackageNode(id) {
+					g.nodes = append(g.nodes, node)
+				}
+				continue
+			}
+		}
+		if isPackageNode(id) {
+			continue // Reported by linting the package itself
+		}
+		g.broken = append(g.broken, loadFindings(id, err)...)
+	}
+
+	disabled := make(map[string]bool, len(opts.Disable))
+	for _, id := range opts.Disable {
+		disabled[id] = true
+	}
+	var findings []Finding
+	for _, rule := range Rules {
+		if disabled[rule.ID] {
+			continue
+		}
+		for _, f := range rule.check(g) {
+			f.Rule, f.Severity = rule.ID, rule.Severity
+			findings = append(findings, f)
+		}
+	}
+
+	findings = newSuppressions(g).filter(g.locate(findings))
+	g.prefixDir(findings)
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.NodeID != b.NodeID {
+			return a.NodeID < b.NodeID
+		}
+		return a.Rule < b.Rule
+	})
+	return &Result{Findings: findings, Nodes: g.byID, Options: opts}, nil
+}
+
+// loadFindings reports a node that cannot be loaded or parsed, keeping strict-mode positions.
+func loadFindings(id string, err error) []Finding {
+	if diags := domain.Diagnostics(err); len(diags) > 0 {
+		out := make([]Finding, 0, len(diags))
+		for _, d := range diags {
+			out = append(out, Finding{Rule: RuleLoadError, Severity: SeverityError, NodeID: id, Message: d.Message, File: d.File, Line: d.Line})
+		}
+		return out
+	}
+	return []Finding{{Rule: RuleLoadError, Severity: SeverityError, NodeID: id, Message: fmt.Sprintf("node '%s' cannot be loaded: %v", id, err)}}
+}
+
+// locate fills in the source of findings that only name a node.
+func (g *graph) locate(findings []Finding) []Finding {
+	for i := range findings {
+		f := &findings[i]
+		if f.File == "" {
+			if node := g.byID[f.NodeID]; node != nil && node.Source != nil {
+				f.File = node.Source.File
+				if node.Source.Macro == "" {
+					f.Line = node.Source.Line // Macro steps only know their line within the script
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// prefixDir makes reported files relative to the working directory rather than the project.
+func (g *graph) prefixDir(findings []Finding) {
+	if g.opts.Dir == "" {
+		return
+	}
+	for i := range findings {
+		if f := &findings[i]; f.File != "" && !filepath.IsAbs(f.File) {
+			f.File = filepath.ToSlash(filepath.Join(g.opts.Dir, f.File))
+		}
+	}
+}
+
+// isPackageNode reports whether id belongs to a mounted package (pkg:<name>/<node>).
+func isPackageNode(id string) bool {
+	return strings.HasPrefix(id, "pkg:")
+}
+
+// HasSeverity reports whether any finding is at least as severe as min.
+func HasSeverity(findings []Finding, min Severity) bool {
+	for _, f := range findings {
+		if f.Severity.AtLeast(min) {
+			return true
+		}
+	}
+	return false
+}
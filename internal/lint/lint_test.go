@@ -0,0 +1,408 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aretw0/trellis/pkg/adapters/loam"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rules returns the "node:rule" pairs of findings, in report order.
+func rules(findings []Finding) []string {
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.NodeID+":"+f.Rule)
+	}
+	return out
+}
+
+func to(id string) []domain.Transition {
+	return []domain.Transition{{ToNodeID: id}}
+}
+
+func TestRun_Rules(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []domain.Node
+		opts  Options
+		want  []string
+	}{
+		{
+			name: "clean graph",
+			nodes: []domain.Node{
+				{ID: "start", Type: domain.NodeTypeQuestion, SaveTo: "name", Transitions: to("end")},
+				{ID: "end", Type: domain.NodeTypeText, Content: []byte("Bye {{ .name }}, {{ .sys.session_id }}")},
+			},
+		},
+		{
+			name: "dangling references in every edge kind",
+			nodes: []domain.Node{
+				{
+					ID: "start", Type: domain.NodeTypeTool, Do: &domain.ToolCall{Name: "t"}, Transitions: to("gone"),
+					OnError: "oops", OnDenied: "denied", OnSignal: map[string]string{"interrupt": "bye"},
+				},
+				{ID: "rb", Type: domain.NodeTypeTool, Do: &domain.ToolCall{Name: "t"}, Transitions: to("rollback"), OnError: "rollback"},
+			},
+			want: []string{
+				"rb:unreachable",
+				"start:dangling-ref", "start:dangling-ref", "start:dangling-ref", "start:dangling-ref",
+			},
+		},
+		{
+			name: "missing entry",
+			nodes: []domain.Node{
+				{ID: "other", Type: domain.NodeTypeText},
+			},
+			opts: Options{Entry: "main"},
+			want: []string{":dangling-ref"},
+		},
+		{
+			name: "every edge kind reaches",
+			nodes: []domain.Node{
+				{ID: "start", Type: domain.NodeTypeTool, Do: &domain.ToolCall{Name: "t"}, Transitions: to("a"), OnError: "failed",
+					OnSignalDefault: map[string]string{"interrupt": "bye"}},
+				{ID: "a", Type: domain.NodeTypeText, OnSignal: map[string]string{"timeout": "slow"}},
+				{ID: "failed", Type: domain.NodeTypeText},
+				{ID: "bye", Type: domain.NodeTypeText},
+				{ID: "slow", Type: domain.NodeTypeText},
+				{ID: "error", Type: domain.NodeTypeText},
+				{ID: "orphan", Type: domain.NodeTypeText},
+			},
+			opts: Options{ErrorNode: "error"},
+			want: []string{"orphan:unreachable"},
+		},
+		{
+			name: "dead ends",
+			nodes: []domain.Node{
+				{ID: "start", Type: domain.NodeTypeQuestion, Transitions: to("ask")},
+				{ID: "ask", Type: domain.NodeTypeText, SaveTo: "x", Transitions: to("tool")},
+				{ID: "tool", Type: domain.NodeTypeTool, Do: &domain.ToolCall{Name: "t"}, Transitions: to("pause")},
+				{ID: "pause", Type: domain.NodeTypeText, Wait: true, Transitions: to("q")},
+				{ID: "q", Type: domain.NodeTypeQuestion},
+			},
+			want: []string{"q:dead-end"},
+		},
+		{
+			name: "templates",
+			nodes: []domain.Node{
+				{ID: "start", Type: domain.NodeTypeText, Content: []byte("{{ .missing }} {{ range .items }}{{ .inner }}{{ end }} {{ default \"x\" .opt }}"),
+					DefaultContext: map[string]any{"items": []any{}}, Transitions: to("bad")},
+				{ID: "bad", Type: domain.NodeTypeText, Content: []byte("{{ .x "), Transitions: to("llm")},
+				{ID: "llm", Type: domain.NodeTypeLLM, Prompt: "{{ .input }} {{ .topic }}", Transitions: to("call")},
+				{ID: "call", Type: domain.NodeTypeTool, Do: &domain.ToolCall{Name: "t", Args: map[string]any{"q": "{{ $.query }}"}},
+					RequiredContext: []string{"topic"}, Transitions: to("end")},
+				{ID: "end", Type: domain.NodeTypeText},
+			},
+			want: []string{"bad:invalid-template", "call:missing-context", "call:undefined-key", "start:undefined-key"},
+		},
+		{
+			name: "legacy templates",
+			nodes: []domain.Node{
+				{ID: "start", Type: domain.NodeTypeText, Content: []byte("Hi {{ name }} {{ .x ")},
+			},
+			opts: Options{Legacy: true},
+			want: []string{"start:undefined-key"},
+		},
+		{
+			name: "node definitions",
+			nodes: []domain.Node{
+				{ID: "start", Type: domain.NodeTypeQuestion, SaveTo: "sys.user", Timeout: "soon", Transitions: to("tool")},
+				{ID: "tool", Type: domain.NodeTypeTool, Transitions: to("both")},
+				{ID: "both", Type: domain.NodeTypeText, Do: &domain.ToolCall{Name: "t"}, Wait: true, Transitions: to("end")},
+				{ID: "end", Type: domain.NodeTypeText, Timeout: "-5s"},
+			},
+			want: []string{"both:do-wait-conflict", "end:invalid-timeout", "start:invalid-timeout", "start:reserved-save-to", "tool:tool-without-do"},
+		},
+		{
+			name: "unknown tools",
+			nodes: []domain.Node{
+				{ID: "start", Type: domain.NodeTypeTool, Do: &domain.ToolCall{Name: "known"}, Undo: &domain.ToolCall{Name: "unknown"}, Transitions: to("inline")},
+				{ID: "inline", Type: domain.NodeTypeTool, Do: &domain.ToolCall{Name: "script", Metadata: map[string]string{"x-exec-command": "echo"}}},
+			},
+			opts: Options{Tools: []string{"known"}},
+			want: []string{"inline:dead-end", "start:unknown-tool"},
+		},
+		{
+			name: "disabled rules",
+			nodes: []domain.Node{
+				{ID: "start", Type: domain.NodeTypeText},
+				{ID: "orphan", Type: domain.NodeTypeText, SaveTo: "sys", Transitions: to("start")},
+			},
+			opts: Options{Disable: []string{RuleUnreachable}},
+			want: []string{"orphan:reserved-save-to"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader, err := memory.NewFromNodes(tt.nodes...)
+			require.NoError(t, err)
+
+			findings, err := Run(loader, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rules(findings))
+		})
+	}
+}
+
+func TestRun_MissingContext(t *testing.T) {
+	loader, err := memory.NewFromNodes(
+		domain.Node{ID: "start", Type: domain.NodeTypeQuestion, SaveTo: "name", RequiredContext: []string{"tenant"},
+			Transitions: []domain.Transition{{ToNodeID: "ask", Condition: "input == 'yes'"}, {ToNodeID: "skip"}}},
+		domain.Node{ID: "ask", Type: domain.NodeTypeQuestion, SaveTo: "email", Transitions: to("charge")},
+		domain.Node{ID: "skip", Type: domain.NodeTypeText, Transitions: to("charge")},
+		domain.Node{ID: "charge", Type: domain.NodeTypeTool, Do: &domain.ToolCall{Name: "pay"}, SaveTo: "receipt",
+			RequiredContext: []string{"name", "tenant", "plan"}, Transitions: to("confirm"), OnError: "confirm"},
+		domain.Node{ID: "confirm", Type: domain.NodeTypeText, RequiredContext: []string{"email", "receipt", "theme", "region"},
+			DefaultContext: map[string]any{"theme": "dark"}},
+	)
+	require.NoError(t, err)
+
+	findings, err := Run(loader, Options{Context: []string{"plan"}})
+	require.NoError(t, err)
+
+	var got []string
+	for _, f := range findings {
+		require.Equal(t, RuleMissingContext, f.Rule)
+		got = append(got, f.Message)
+	}
+	assert.Equal(t, []string{
+		"required_context 'email' is not set on every path to 'confirm', e.g. start -> skip -> charge -> confirm",
+		"required_context 'receipt' is not set on every path to 'confirm', e.g. start -> ask -> charge -[on_error]-> confirm",
+		"required_context 'region' is never set to 'confirm', e.g. start -> ask -> charge -> confirm",
+	}, got)
+	assert.Equal(t, []string{"start", "skip", "charge", "confirm"}, findings[0].Path)
+}
+
+func TestRun_MissingContext_ToolResults(t *testing.T) {
+	loader, err := memory.NewFromNodes(
+		domain.Node{ID: "start", Type: domain.NodeTypeTool, Do: &domain.ToolCall{Name: "lookup"}, Transitions: to("fanout"), OnError: "fanout"},
+		domain.Node{ID: "fanout", Type: domain.NodeTypeTool, RequiredContext: []string{"tool_result"},
+			Batch:       []domain.BatchCall{{ToolCall: domain.ToolCall{ID: "a", Name: "a"}}, {ToolCall: domain.ToolCall{ID: "b", Name: "b"}}},
+			Transitions: to("summary")},
+		domain.Node{ID: "summary", Type: domain.NodeTypeText, RequiredContext: []string{"tool_results", "tool_result"}},
+	)
+	require.NoError(t, err)
+
+	findings, err := Run(loader, Options{})
+	require.NoError(t, err)
+
+	var got []string
+	for _, f := range findings {
+		require.Equal(t, RuleMissingContext, f.Rule)
+		got = append(got, f.Message)
+	}
+	assert.Equal(t, []string{
+		"required_context 'tool_result' is not set on every path to 'fanout', e.g. start -[on_error]-> fanout",
+		"required_context 'tool_result' is not set on every path to 'summary', e.g. start -[on_error]-> fanout -> summary",
+	}, got)
+}
+
+func TestRun_Transitions(t *testing.T) {
+	when := func(label, id string) domain.Transition {
+		return domain.Transition{ToNodeID: id, Condition: "input == '" + label + "'"}
+	}
+	choice := func(options ...string) domain.Node {
+		return domain.Node{ID: "start", Type: domain.NodeTypeQuestion, InputType: string(domain.InputChoice), InputOptions: options}
+	}
+
+	tests := []struct {
+		name  string
+		node  domain.Node
+		want  []string
+		first string
+	}{
+		{
+			name: "every option handled",
+			node: func() domain.Node {
+				n := choice("Sales", "Support")
+				n.Transitions = []domain.Transition{when("sales", "end"), when("Support", "end")}
+				return n
+			}(),
+		},
+		{
+			name: "unhandled option",
+			node: func() domain.Node {
+				n := choice("Sales", "Support")
+				n.Transitions = []domain.Transition{when("Sales", "end")}
+				return n
+			}(),
+			want:  []string{"start:unhandled-option"},
+			first: "option 'Support' is not handled by any transition, so the session silently stays on the node",
+		},
+		{
+			name: "shadowed and unmatched conditions",
+			node: func() domain.Node {
+				n := choice("Sales", "Support")
+				n.Transitions = []domain.Transition{when("Sales", "end"), when("Support", "end"), when("SALES", "end"), when("Billing", "end")}
+				return n
+			}(),
+			want:  []string{"start:unreachable-transition", "start:unreachable-transition"},
+			first: "transition 3 to 'end' (input == 'SALES') is shadowed by transition 1 to 'end', which matches first",
+		},
+		{
+			name:  "duplicate fallback",
+			node:  domain.Node{ID: "start", Type: domain.NodeTypeQuestion, Transitions: []domain.Transition{when("x", "end"), {ToNodeID: "end"}, {ToNodeID: "end"}}},
+			want:  []string{"start:unreachable-transition"},
+			first: "transition 3 to 'end' is never taken: transition 2 to 'end' is already unconditional",
+		},
+		{
+			name: "confirm refusal goes to on_denied",
+			node: domain.Node{ID: "start", Type: domain.NodeTypeQuestion, InputType: string(domain.InputConfirm), OnDenied: "end",
+				Transitions: []domain.Transition{when("yes", "end")}},
+		},
+		{
+			name: "open input without fallback",
+			node: domain.Node{ID: "start", Type: domain.NodeTypeQuestion, Transitions: []domain.Transition{when("x", "end")}},
+			want: []string{"start:missing-fallback"},
+		},
+		{
+			name: "strict transitions need no fallback",
+			node: domain.Node{ID: "start", Type: domain.NodeTypeQuestion, StrictTransitions: true, Transitions: []domain.Transition{when("x", "end")}},
+		},
+		{
+			name: "custom conditions are not analyzed",
+			node: func() domain.Node {
+				n := choice("Sales", "Support")
+				n.Transitions = []domain.Transition{{ToNodeID: "end", Condition: "len(input) > 3"}}
+				return n
+			}(),
+			want: []string{"start:missing-fallback"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader, err := memory.NewFromNodes(tt.node, domain.Node{ID: "end", Type: domain.NodeTypeText})
+			require.NoError(t, err)
+
+			findings, err := Run(loader, Options{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rules(findings))
+			if tt.first != "" {
+				assert.Equal(t, tt.first, findings[0].Message)
+			}
+		})
+	}
+}
+
+func TestRun_LoadErrors(t *testing.T) {
+	loader := memory.NewLoader(map[string]string{
+		"start":  `{"id": "start", "type": "text", "transitions": [{"to_node_id": "broken"}]}`,
+		"broken": `{"id": "broken", "type": `,
+	})
+
+	findings, err := Run(loader, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"broken:load-error"}, rules(findings))
+	assert.Equal(t, SeverityError, findings[0].Severity)
+	assert.True(t, HasSeverity(findings, SeverityError))
+}
+
+func TestRun_Suppressions(t *testing.T) {
+	dir := t.TempDir()
+	flow := `nodes:
+  start:
+    type: question
+    to: done
+  # trellis:ignore unreachable
+  orphan:
+    content: "{{ .nobody }}"
+  loose:
+    # trellis:ignore
+    content: "{{ .nobody }}"
+  other:
+    content: "{{ .nobody }}" # trellis:ignore dead-end
+  done:
+    content: end
+`
+	path := filepath.Join(dir, "flow.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(flow), 0o644))
+	loader, _, err := loam.NewFileLoader(path)
+	require.NoError(t, err)
+
+	findings, err := Run(loader, Options{Dir: dir})
+	require.NoError(t, err)
+
+	// The comment above orphan covers only unreachable; the one inside loose covers everything.
+	assert.Equal(t, []string{"orphan:undefined-key", "other:undefined-key", "other:unreachable"}, rules(findings))
+	assert.Equal(t, filepath.ToSlash(path), findings[0].File)
+	assert.Equal(t, 6, findings[0].Line)
+	assert.Equal(t, filepath.ToSlash(path)+":6: warning: content reads '.nobody', but no node writes it (save_to, default_context) or declares it (required_context, context_schema) [undefined-key]", findings[0].String())
+}
+
+func TestParseIgnores(t *testing.T) {
+	got := parseIgnores("a\n# trellis:ignore\n<!-- trellis:ignore dead-end, unreachable -->\nx: 1 # trellis:ignore undefined-key\n# trellis:ignored\n")
+	assert.Equal(t, map[int][]string{
+		2: {"*"},
+		3: {"dead-end", "unreachable"},
+		4: {"undefined-key"},
+	}, got)
+}
+
+func TestWrite(t *testing.T) {
+	findings := []Finding{
+		{Rule: RuleDeadEnd, Severity: SeverityWarning, NodeID: "ask", Message: "dead", File: "flow/ask.md", Line: 1},
+		{Rule: RuleDanglingRef, Severity: SeverityError, Message: "entry node 'start' does not exist"},
+	}
+
+	var text bytes.Buffer
+	require.NoError(t, Write(&text, "text", findings))
+	assert.Equal(t, "flow/ask.md:1: warning: dead [dead-end]\n"+
+		"error: entry node 'start' does not exist [dangling-ref]\n"+
+		"2 findings (1 errors, 1 warnings, 0 info)\n", text.String())
+
+	var empty bytes.Buffer
+	require.NoError(t, Write(&empty, "json", nil))
+	assert.Equal(t, "[]\n", empty.String())
+
+	var sarif bytes.Buffer
+	require.NoError(t, Write(&sarif, "sarif", findings))
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(sarif.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, "trellis", run.Tool.Driver.Name)
+	assert.Len(t, run.Tool.Driver.Rules, len(Rules))
+	require.Len(t, run.Results, 2)
+	assert.Equal(t, "warning", run.Results[0].Level)
+	assert.Equal(t, "flow/ask.md", run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 1, run.Results[0].Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Empty(t, run.Results[1].Locations)
+
+	assert.Error(t, Write(&text, "xml", findings))
+}
@@ -0,0 +1,281 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// Rule IDs, as used in reports, `--disable` and suppression comments.
+const (
+	RuleLoadError      = "load-error"
+	RuleDanglingRef    = "dangling-ref"
+	RuleUnreachable    = "unreachable"
+	RuleDeadEnd        = "dead-end"
+	RuleUndefinedKey   = "undefined-key"
+	RuleInvalidTmpl    = "invalid-template"
+	RuleReservedSaveTo = "reserved-save-to"
+	RuleToolWithoutDo  = "tool-without-do"
+	RuleDoWait         = "do-wait-conflict"
+	RuleInvalidTimeout = "invalid-timeout"
+	RuleUnknownTool    = "unknown-tool"
+	RuleMissingContext = "missing-context"
+	RuleUnhandledOpt   = "unhandled-option"
+	RuleUnreachableTr  = "unreachable-transition"
+	RuleNoFallback     = "missing-fallback"
+)
+
+// Rule is a single check with its default severity.
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+	check       func(g *graph) []Finding
+}
+
+// Rules is the rule set, in report order.
+var Rules = []Rule{
+	{RuleLoadError, SeverityError, "Node cannot be loaded or parsed.", checkLoadErrors},
+	{RuleDanglingRef, SeverityError, "A transition, on_error, on_denied, on_signal, on_signal_default or on_unclear target does not exist.", checkDanglingRefs},
+	{RuleUnreachable, SeverityWarning, "Node cannot be reached from the entry node through any edge.", checkUnreachable},
+	{RuleDeadEnd, SeverityWarning, "Node collects input, runs a tool or calls a model but has no transition to continue.", checkDeadEnds},
+	{RuleUndefinedKey, SeverityWarning, "Template references a context key that no node writes (save_to, default_context) or declares (required_context, context_schema).", checkUndefinedKeys},
+	{RuleInvalidTmpl, SeverityError, "Template does not parse.", checkInvalidTemplates},
+	{RuleReservedSaveTo, SeverityError, "save_to writes into the reserved sys namespace.", checkReservedSaveTo},
+	{RuleToolWithoutDo, SeverityError, "type: tool node without a do call.", checkToolWithoutDo},
+	{RuleDoWait, SeverityError, "Node both runs a tool (do) and waits for input (wait).", checkDoWait},
+	{RuleInvalidTimeout, SeverityError, "timeout is not a positive duration (e.g. 30s, 5m).", checkTimeouts},
+	{RuleUnknownTool, SeverityWarning, "do or undo calls a tool missing from the tool registry.", checkUnknownTools},
+	{RuleUnhandledOpt, SeverityWarning, "A choice or confirm option that no transition handles.", checkUnhandledOptions},
+	{RuleUnreachableTr, SeverityWarning, "A transition that is never taken: shadowed by an earlier match, matching no option, or after another unconditional transition.", checkUnreachableTransitions},
+	{RuleNoFallback, SeverityInfo, "Node with conditional transitions only, so unmatched input leaves the session on the node.", checkMissingFallbacks},
+	{RuleMissingContext, SeverityError, "A required_context or context_schema key can be missing when the node is reached.", checkMissingContext},
+}
+
+func checkLoadErrors(g *graph) []Finding {
+	return g.broken
+}
+
+// edge is a reference from a node to another node.
+type edge struct {
+	kind   string
+	target string
+}
+
+// edges lists every node reference of n, in declaration order.
+func edges(n *domain.Node) []edge {
+	var out []edge
+	for _, t := range n.Transitions {
+		if t.ToNodeID != "" && !domain.IsRollback(t.ToNodeID) {
+			out = append(out, edge{"transition", t.ToNodeID})
+		}
+	}
+	if n.OnError != "" && !domain.IsRollback(n.OnError) {
+		out = append(out, edge{"on_error", n.OnError})
+	}
+	if n.OnDenied != "" {
+		out = append(out, edge{"on_denied", n.OnDenied})
+	}
+	for _, signal := range sortedKeys(n.OnSignal) {
+		out = append(out, edge{"on_signal." + signal, n.OnSignal[signal]})
+	}
+	for _, signal := range sortedKeys(n.OnSignalDefault) {
+		out = append(out, edge{"on_signal_default." + signal, n.OnSignalDefault[signal]})
+	}
+	if n.OnUnclear != "" {
+		out = append(out, edge{"on_unclear", n.OnUnclear})
+	}
+	return out
+}
+
+func checkDanglingRefs(g *graph) []Finding {
+	var out []Finding
+	for _, n := range g.nodes {
+		for _, e := range edges(n) {
+			if !g.known[e.target] {
+				out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("%s target '%s' does not exist", e.kind, e.target)})
+			}
+		}
+	}
+	if g.known[g.opts.Entry] || len(g.byID) == 0 {
+		return out
+	}
+	return append(out, Finding{Message: fmt.Sprintf("entry node '%s' does not exist", g.opts.Entry)})
+}
+
+// reachable walks every edge kind from the entry node. The entry's global signal
+// handlers (on_signal_default) and the error node can fire from anywhere, so they are roots too.
+func (g *graph) reachable() map[string]bool {
+	seen := make(map[string]bool, len(g.byID))
+	queue := []string{g.opts.Entry}
+	if g.opts.ErrorNode != "" {
+		queue = append(queue, g.opts.ErrorNode)
+	}
+	if entry := g.byID[g.opts.Entry]; entry != nil {
+		for _, target := range entry.OnSignalDefault {
+			queue = append(queue, target)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if n := g.byID[id]; n != nil {
+			for _, e := range edges(n) {
+				queue = append(queue, e.target)
+			}
+		}
+	}
+	return seen
+}
+
+func checkUnreachable(g *graph) []Finding {
+	if _, ok := g.byID[g.opts.Entry]; !ok {
+		return nil // Reported as a dangling entry
+	}
+	seen := g.reachable()
+	var out []Finding
+	for _, n := range g.nodes {
+		if !seen[n.ID] {
+			out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("node '%s' is unreachable from '%s'", n.ID, g.opts.Entry)})
+		}
+	}
+	return out
+}
+
+// expectsMore reports whether the flow is not meant to end at n: it collects input,
+// runs a tool or a model, or routes intents. A bare wait is a final pause, not a dead end.
+func expectsMore(n *domain.Node) bool {
+	switch n.Type {
+	case domain.NodeTypeQuestion, domain.NodeTypeTool, domain.NodeTypeLLM, domain.NodeTypeRoute:
+		return true
+	}
+	return n.InputType != "" || n.SaveTo != "" || n.HasTools()
+}
+
+func checkDeadEnds(g *graph) []Finding {
+	var out []Finding
+	for _, n := range g.nodes {
+		if len(n.Transitions) == 0 && expectsMore(n) {
+			out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("node '%s' %s but has no transitions, so the flow ends there", n.ID, describeWork(n))})
+		}
+	}
+	return out
+}
+
+func describeWork(n *domain.Node) string {
+	switch {
+	case n.HasTools():
+		return "runs a tool"
+	case n.Type == domain.NodeTypeLLM:
+		return "calls a model"
+	case n.Type == domain.NodeTypeRoute:
+		return "routes input"
+	}
+	return "collects input"
+}
+
+func checkReservedSaveTo(g *graph) []Finding {
+	var out []Finding
+	for _, n := range g.nodes {
+		if n.SaveTo == "sys" || strings.HasPrefix(n.SaveTo, "sys.") {
+			out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("save_to '%s' writes into the reserved sys namespace", n.SaveTo)})
+		}
+	}
+	return out
+}
+
+func checkToolWithoutDo(g *graph) []Finding {
+	var out []Finding
+	for _, n := range g.nodes {
+		if n.Type == domain.NodeTypeTool && !n.HasTools() {
+			out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("node '%s' is type: tool but declares no do call", n.ID)})
+		}
+	}
+	return out
+}
+
+func checkDoWait(g *graph) []Finding {
+	var out []Finding
+	for _, n := range g.nodes {
+		if n.HasTools() && n.Wait {
+			out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("node '%s' runs a tool and waits for input; split it into a question and a tool node", n.ID)})
+		}
+	}
+	return out
+}
+
+func checkTimeouts(g *graph) []Finding {
+	var out []Finding
+	for _, n := range g.nodes {
+		if n.Timeout == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(n.Timeout); err != nil || d <= 0 {
+			out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("timeout '%s' is not a positive duration (e.g. 30s, 5m)", n.Timeout)})
+		}
+	}
+	return out
+}
+
+func checkUnknownTools(g *graph) []Finding {
+	if g.opts.Tools == nil {
+		return nil
+	}
+	registry := make(map[string]bool, len(g.opts.Tools))
+	for _, name := range g.opts.Tools {
+		registry[name] = true
+	}
+	var out []Finding
+	for _, n := range g.nodes {
+		for _, call := range calls(n) {
+			// Inline tools (x-exec-command) carry their own command.
+			if call.call.Name != "" && !registry[call.call.Name] && call.call.Metadata[inlineCommand] == "" {
+				out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("%s calls tool '%s', which is not in the tool registry", call.field, call.call.Name)})
+			}
+		}
+	}
+	return out
+}
+
+// inlineCommand is the metadata key of tools declared inline in a node.
+const inlineCommand = "x-exec-command"
+
+// namedCall is a tool call with the field that declares it.
+type namedCall struct {
+	field string
+	call  *domain.ToolCall
+}
+
+// calls lists the tool calls of n: do (or each batch entry), and the compensations.
+func calls(n *domain.Node) []namedCall {
+	var out []namedCall
+	if n.Do != nil {
+		out = append(out, namedCall{"do", n.Do})
+	}
+	for i := range n.Batch {
+		out = append(out, namedCall{fmt.Sprintf("do[%d]", i), &n.Batch[i].ToolCall})
+		if n.Batch[i].Undo != nil {
+			out = append(out, namedCall{fmt.Sprintf("do[%d].undo", i), n.Batch[i].Undo})
+		}
+	}
+	if n.Undo != nil {
+		out = append(out, namedCall{"undo", n.Undo})
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
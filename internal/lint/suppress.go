@@ -0,0 +1,126 @@
+package lint
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// ignoreComment matches `# trellis:ignore [rule,...]` and `<!-- trellis:ignore [rule,...] -->`.
+var ignoreComment = regexp.MustCompile(`(?:#|<!--)\s*trellis:ignore\b([^>]*?)\s*(?:-->)?\s*$`)
+
+// span is the line range of a node within its file.
+type span struct {
+	from, to int // inclusive; to == 0 means end of file
+}
+
+// suppressions maps node IDs to the rules silenced for them ("*" for all rules).
+type suppressions map[string]map[string]bool
+
+// newSuppressions reads the `trellis:ignore` comments of every node source.
+//
+// A comment applies to the node it sits in, or to the node right below it: the range of
+// a node starts one line above its declaration and ends before the next node of the same file.
+// Nodes expanded from a `type: flow` script have no line of their own, so they are covered by
+// comments anywhere in their file when the file declares nothing else.
+func newSuppressions(g *graph) suppressions {
+	byFile := make(map[string][]*domain.Node)
+	for _, n := range g.nodes {
+		if n.Source != nil && n.Source.File != "" {
+			byFile[n.Source.File] = append(byFile[n.Source.File], n)
+		}
+	}
+
+	s := make(suppressions)
+	for file, nodes := range byFile {
+		data, err := g.opts.ReadFile(file)
+		if err != nil {
+			continue // Sources we cannot read carry no suppressions
+		}
+		comments := parseIgnores(string(data))
+		if len(comments) == 0 {
+			continue
+		}
+		for id, sp := range spans(nodes) {
+			for line, rules := range comments {
+				if line >= sp.from && (sp.to == 0 || line <= sp.to) {
+					s.add(id, rules)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// spans computes the line range of each node of a single file.
+func spans(nodes []*domain.Node) map[string]span {
+	out := make(map[string]span, len(nodes))
+	var lined []*domain.Node
+	macros := make(map[string]bool)
+	for _, n := range nodes {
+		if n.Source.Macro != "" {
+			macros[n.Source.Macro] = true
+			continue
+		}
+		lined = append(lined, n)
+	}
+	if len(lined) == 0 && len(macros) == 1 {
+		for _, n := range nodes {
+			out[n.ID] = span{}
+		}
+		return out
+	}
+
+	sort.Slice(lined, func(i, j int) bool { return lined[i].Source.Line < lined[j].Source.Line })
+	for i, n := range lined {
+		sp := span{from: n.Source.Line - 1}
+		if i+1 < len(lined) {
+			sp.to = lined[i+1].Source.Line - 2
+		}
+		out[n.ID] = sp
+	}
+	return out
+}
+
+// parseIgnores returns the rules each `trellis:ignore` line silences, keyed by 1-based line.
+func parseIgnores(text string) map[int][]string {
+	if !strings.Contains(text, "trellis:ignore") {
+		return nil
+	}
+	out := make(map[int][]string)
+	for i, line := range strings.Split(text, "\n") {
+		m := ignoreComment.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		rules := strings.FieldsFunc(m[1], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(rules) == 0 {
+			rules = []string{"*"}
+		}
+		out[i+1] = rules
+	}
+	return out
+}
+
+func (s suppressions) add(id string, rules []string) {
+	if s[id] == nil {
+		s[id] = make(map[string]bool)
+	}
+	for _, r := range rules {
+		s[id][r] = true
+	}
+}
+
+// filter drops the suppressed findings. Findings without a node cannot be suppressed.
+func (s suppressions) filter(findings []Finding) []Finding {
+	out := findings[:0]
+	for _, f := range findings {
+		if rules := s[f.NodeID]; rules != nil && (rules["*"] || rules[f.Rule]) {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
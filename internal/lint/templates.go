@@ -0,0 +1,195 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template/parse"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// builtinKeys are set by the engine rather than by nodes.
+var builtinKeys = map[string]bool{
+	"sys":          true,
+	"tool_result":  true,
+	"tool_results": true,
+}
+
+// fallbackFuncs take keys that may be missing (see docs/reference/interpolation.md).
+var fallbackFuncs = map[string]bool{"default": true, "coalesce": true}
+
+// templateField is a templated string of a node.
+type templateField struct {
+	field string
+	text  string
+	// llm prompts also see the turn input as `.input`.
+	llm bool
+}
+
+// templates lists the strings the engine interpolates: content, localized messages,
+// model prompts and string arguments of tool calls.
+func templates(n *domain.Node) []templateField {
+	out := []templateField{{field: "content", text: string(n.Content)}}
+	for _, locale := range sortedKeys(n.Messages) {
+		for i, item := range n.Messages[locale] {
+			out = append(out, templateField{field: fmt.Sprintf("messages.%s[%d]", locale, i), text: item.Text})
+		}
+	}
+	out = append(out,
+		templateField{field: "prompt", text: n.Prompt, llm: true},
+		templateField{field: "system", text: n.System, llm: true},
+	)
+	for _, c := range calls(n) {
+		for _, arg := range sortedKeys(c.call.Args) {
+			if s, ok := c.call.Args[arg].(string); ok {
+				out = append(out, templateField{field: c.field + ".args." + arg, text: s})
+			}
+		}
+	}
+	return out
+}
+
+// legacyRef matches the `{{ key }}` placeholders of the legacy interpolator.
+var legacyRef = regexp.MustCompile(`\{\{ ([A-Za-z_][A-Za-z0-9_]*) \}\}`)
+
+// templateRefs returns the top-level context keys a template reads (`.key`, `.key.sub`, `$.key`).
+func templateRefs(text string, legacy bool) ([]string, error) {
+	if !strings.Contains(text, "{{") {
+		return nil, nil
+	}
+	if legacy {
+		var refs []string
+		for _, m := range legacyRef.FindAllStringSubmatch(text, -1) {
+			refs = append(refs, m[1])
+		}
+		return refs, nil
+	}
+
+	tree := parse.New("node")
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(text, "", "", map[string]*parse.Tree{}); err != nil {
+		return nil, err
+	}
+	var refs []string
+	collectRefs(tree.Root, true, &refs)
+	return refs, nil
+}
+
+// collectRefs walks a template tree. rootDot is false inside range/with bodies,
+// where `.` no longer is the context.
+func collectRefs(node parse.Node, rootDot bool, refs *[]string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectRefs(child, rootDot, refs)
+		}
+	case *parse.ActionNode:
+		collectRefs(n.Pipe, rootDot, refs)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectRefs(cmd, rootDot, refs)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) > 0 {
+			// Fallback helpers handle missing keys on purpose.
+			if fn, ok := n.Args[0].(*parse.IdentifierNode); ok && fallbackFuncs[fn.Ident] {
+				return
+			}
+		}
+		for _, arg := range n.Args {
+			collectRefs(arg, rootDot, refs)
+		}
+	case *parse.FieldNode:
+		if rootDot {
+			*refs = append(*refs, n.Ident[0])
+		}
+	case *parse.ChainNode:
+		collectRefs(n.Node, rootDot, refs)
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			*refs = append(*refs, n.Ident[1])
+		}
+	case *parse.IfNode:
+		collectRefs(n.Pipe, rootDot, refs)
+		collectRefs(n.List, rootDot, refs)
+		collectRefs(n.ElseList, rootDot, refs)
+	case *parse.RangeNode:
+		collectRefs(n.Pipe, rootDot, refs)
+		collectRefs(n.List, false, refs)
+		collectRefs(n.ElseList, rootDot, refs)
+	case *parse.WithNode:
+		collectRefs(n.Pipe, rootDot, refs)
+		collectRefs(n.List, false, refs)
+		collectRefs(n.ElseList, rootDot, refs)
+	case *parse.TemplateNode:
+		collectRefs(n.Pipe, rootDot, refs)
+	}
+}
+
+// writtenKeys collects the context keys some node provides.
+func (g *graph) writtenKeys() map[string]bool {
+	keys := make(map[string]bool)
+	for k := range builtinKeys {
+		keys[k] = true
+	}
+	for _, k := range g.opts.Context {
+		keys[k] = true
+	}
+	for _, n := range g.byID {
+		if n.SaveTo != "" {
+			keys[n.SaveTo] = true
+		}
+		for k := range n.DefaultContext {
+			keys[k] = true
+		}
+		for _, k := range n.RequiredContext {
+			keys[k] = true
+		}
+		for k := range n.ContextSchema {
+			keys[k] = true
+		}
+	}
+	return keys
+}
+
+func checkUndefinedKeys(g *graph) []Finding {
+	keys := g.writtenKeys()
+	var out []Finding
+	for _, n := range g.nodes {
+		reported := make(map[string]bool)
+		for _, t := range templates(n) {
+			refs, err := templateRefs(t.text, g.opts.Legacy)
+			if err != nil {
+				continue // Reported by invalid-template
+			}
+			for _, ref := range refs {
+				if keys[ref] || reported[ref] || (t.llm && ref == "input") {
+					continue
+				}
+				reported[ref] = true
+				out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("%s reads '.%s', but no node writes it (save_to, default_context) or declares it (required_context, context_schema)", t.field, ref)})
+			}
+		}
+	}
+	return out
+}
+
+func checkInvalidTemplates(g *graph) []Finding {
+	var out []Finding
+	for _, n := range g.nodes {
+		for _, t := range templates(n) {
+			if _, err := templateRefs(t.text, g.opts.Legacy); err != nil {
+				msg := strings.TrimPrefix(err.Error(), "template: node:")
+				out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("%s is not a valid template: %s", t.field, msg)})
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,200 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// matcher reports whether a condition holds for an input, as the default evaluator decides it.
+type matcher func(input string) bool
+
+// parseCondition understands the `input == 'value'` conditions of the default evaluator
+// (case-insensitive, as generated from option labels). Other conditions need a custom
+// evaluator, so their outcome is unknown.
+func parseCondition(cond string) (matcher, bool) {
+	parts := strings.Split(cond, "==")
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) != "input" {
+		return nil, false
+	}
+	expected := strings.Trim(strings.TrimSpace(parts[1]), "'\"")
+	return func(input string) bool {
+		return strings.EqualFold(strings.TrimSpace(input), expected)
+	}, true
+}
+
+// knownInputs returns the values a node can receive, when its input is closed: the options
+// of a choice, or yes/no for a confirm (the engine normalizes y/true/1 and friends).
+func knownInputs(n *domain.Node) []string {
+	switch domain.InputType(n.InputType) {
+	case domain.InputConfirm:
+		return []string{"yes", "no"}
+	case domain.InputChoice:
+		return n.InputOptions
+	}
+	return nil
+}
+
+// isRefusal mirrors the inputs the engine sends to on_denied.
+func isRefusal(input string) bool {
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "n", "no", "false", "deny":
+		return true
+	}
+	return false
+}
+
+// usesTransitions reports whether the engine resolves n's next node from its transitions
+// (route nodes classify input instead).
+func usesTransitions(n *domain.Node) bool {
+	return n.Type != domain.NodeTypeRoute && len(n.Transitions) > 0
+}
+
+func checkUnhandledOptions(g *graph) []Finding {
+	var out []Finding
+	for _, n := range g.nodes {
+		inputs := knownInputs(n)
+		if !usesTransitions(n) || len(inputs) == 0 || hasFallback(n) {
+			continue
+		}
+		matchers, ok := conditionMatchers(n)
+		if !ok {
+			continue
+		}
+		for _, input := range inputs {
+			if isRefusal(input) && n.OnDenied != "" {
+				continue
+			}
+			handled := false
+			for _, m := range matchers {
+				if m.match(input) {
+					handled = true
+					break
+				}
+			}
+			if !handled {
+				out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("option '%s' is not handled by any transition, so %s", input, noMatchOutcome(n))})
+			}
+		}
+	}
+	return out
+}
+
+func checkUnreachableTransitions(g *graph) []Finding {
+	var out []Finding
+	for _, n := range g.nodes {
+		if !usesTransitions(n) {
+			continue
+		}
+		// Conditions are tried before fallbacks, and only the first fallback is ever taken.
+		fallback := -1
+		for i, t := range n.Transitions {
+			if t.Condition != "" {
+				continue
+			}
+			if fallback >= 0 {
+				out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("transition %d to '%s' is never taken: transition %d to '%s' is already unconditional", i+1, t.ToNodeID, fallback+1, n.Transitions[fallback].ToNodeID)})
+				continue
+			}
+			fallback = i
+		}
+
+		inputs := knownInputs(n)
+		matchers, ok := conditionMatchers(n)
+		if len(inputs) == 0 || !ok {
+			continue
+		}
+		claimed := make(map[string]int) // input -> transition that takes it
+		for _, m := range matchers {
+			var own []string
+			for _, input := range inputs {
+				if !m.match(input) {
+					continue
+				}
+				if _, taken := claimed[input]; !taken {
+					claimed[input] = m.index
+					own = append(own, input)
+				}
+			}
+			if len(own) > 0 {
+				continue
+			}
+			t := n.Transitions[m.index]
+			msg := fmt.Sprintf("transition %d to '%s' (%s) matches none of the options %s", m.index+1, t.ToNodeID, t.Condition, quoteList(inputs))
+			for _, input := range inputs {
+				if prev, taken := claimed[input]; taken && m.match(input) {
+					msg = fmt.Sprintf("transition %d to '%s' (%s) is shadowed by transition %d to '%s', which matches first", m.index+1, t.ToNodeID, t.Condition, prev+1, n.Transitions[prev].ToNodeID)
+					break
+				}
+			}
+			out = append(out, Finding{NodeID: n.ID, Message: msg})
+		}
+	}
+	return out
+}
+
+func checkMissingFallbacks(g *graph) []Finding {
+	var out []Finding
+	for _, n := range g.nodes {
+		if !usesTransitions(n) || hasFallback(n) || n.StrictTransitions {
+			continue
+		}
+		// Closed inputs that are all handled need no fallback (reported by unhandled-option otherwise).
+		if len(knownInputs(n)) > 0 {
+			if _, ok := conditionMatchers(n); ok {
+				continue
+			}
+		}
+		out = append(out, Finding{NodeID: n.ID, Message: fmt.Sprintf("node '%s' has only conditional transitions, so input that matches none leaves the session on the node (add an unconditional transition or strict_transitions: true)", n.ID)})
+	}
+	return out
+}
+
+// indexedMatcher is a parsed condition with the index of its transition.
+type indexedMatcher struct {
+	index int
+	match matcher
+}
+
+// conditionMatchers parses the conditional transitions of n in order. ok is false when
+// some condition needs a custom evaluator, as the analysis would be guesswork.
+func conditionMatchers(n *domain.Node) ([]indexedMatcher, bool) {
+	var out []indexedMatcher
+	for i, t := range n.Transitions {
+		if t.Condition == "" {
+			continue
+		}
+		m, ok := parseCondition(t.Condition)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, indexedMatcher{i, m})
+	}
+	return out, true
+}
+
+func hasFallback(n *domain.Node) bool {
+	for _, t := range n.Transitions {
+		if t.Condition == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// noMatchOutcome describes what the engine does when no transition matches.
+func noMatchOutcome(n *domain.Node) string {
+	if n.StrictTransitions {
+		return "navigation fails (strict_transitions)"
+	}
+	return "the session silently stays on the node"
+}
+
+func quoteList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, s := range items {
+		quoted[i] = "'" + s + "'"
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,267 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+var (
+	// templateKey is a template expression being typed: `{{ .na`, `{{ $.na`.
+	templateKey = regexp.MustCompile(`\{\{-?\s*\$?\.([\w]*)$`)
+	// legacyKey is a legacy placeholder being typed: `{{ na`.
+	legacyKey = regexp.MustCompile(`\{\{\s*([\w]*)$`)
+	// toolName is the `name:` of a tool call being typed.
+	toolName = regexp.MustCompile(`^\s*(?:-\s+)?name\s*:\s*["']?([\w.-]*)$`)
+	// keyValue is a `save_to:` or `required_context: [..` value being typed.
+	keyValue = regexp.MustCompile(`(?:save_to\s*:\s*["']?|required_context\s*:\s*\[[^\]]*?)([\w]*)$`)
+	// validID is a node ID a rename can produce.
+	validID = regexp.MustCompile(`^[\w][\w./-]*$`)
+)
+
+func (s *Server) completion(p TextDocumentPositionParams) []CompletionItem {
+	content, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+	lines := splitLines(content)
+	if p.Position.Line >= len(lines) {
+		return nil
+	}
+	line := lines[p.Position.Line]
+	cursor := byteOffset(line, p.Position.Character)
+	prefix := line[:cursor]
+	path := uriToPath(p.TextDocument.URI)
+	meta := metadataLines(path, lines)[p.Position.Line] != ""
+
+	// Replace the word being typed, as node IDs contain characters editors split words on.
+	replace := func(start int) Range {
+		return lineRange(line, p.Position.Line, start, cursor)
+	}
+
+	if meta {
+		current := slices.Clone(lines[:p.Position.Line+1])
+		current[p.Position.Line] = prefix
+		if r, ok := refsInLines(metadataLines(path, current))[p.Position.Line]; ok && r.start+len(r.value) == cursor {
+			return s.nodeItems(replace(r.start))
+		}
+		if m := toolName.FindStringSubmatchIndex(prefix); m != nil {
+			return s.toolItems(replace(m[2]))
+		}
+		if m := keyValue.FindStringSubmatchIndex(prefix); m != nil {
+			return s.keyItems(replace(m[2]))
+		}
+	}
+	keyPattern := templateKey
+	if s.idx.result != nil && s.idx.result.Options.Legacy {
+		keyPattern = legacyKey
+	}
+	if m := keyPattern.FindStringSubmatchIndex(prefix); m != nil {
+		return s.keyItems(replace(m[2]))
+	}
+	return nil
+}
+
+func (s *Server) nodeItems(rng Range) []CompletionItem {
+	ids := make([]string, 0, len(s.idx.defs))
+	for id := range s.idx.defs {
+		ids = append(ids, id)
+	}
+	if s.idx.result != nil {
+		for id := range s.idx.result.Nodes {
+			if _, ok := s.idx.defs[id]; !ok {
+				ids = append(ids, id) // Package nodes and nodes without a source
+			}
+		}
+	}
+	sort.Strings(ids)
+	items := make([]CompletionItem, 0, len(ids))
+	for _, id := range ids {
+		item := CompletionItem{Label: id, Kind: CompletionKindReference, TextEdit: &TextEdit{Range: rng, NewText: id}}
+		if n := s.node(id); n != nil {
+			item.Detail = n.Type
+			item.Documentation = &MarkupContent{Kind: "markdown", Value: describeNode(n)}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func (s *Server) toolItems(rng Range) []CompletionItem {
+	if s.idx.result == nil {
+		return nil
+	}
+	var items []CompletionItem
+	for _, name := range s.idx.result.Options.Tools {
+		items = append(items, CompletionItem{Label: name, Kind: CompletionKindFunction, Detail: "tool", TextEdit: &TextEdit{Range: rng, NewText: name}})
+	}
+	return items
+}
+
+func (s *Server) keyItems(rng Range) []CompletionItem {
+	items := make([]CompletionItem, 0, len(s.idx.keys))
+	for _, key := range s.idx.keys {
+		items = append(items, CompletionItem{Label: key, Kind: CompletionKindVariable, Detail: "context key", TextEdit: &TextEdit{Range: rng, NewText: key}})
+	}
+	return items
+}
+
+func (s *Server) definition(p TextDocumentPositionParams) []Location {
+	sp, ok := s.idx.spanAt(p.TextDocument.URI, p.Position)
+	if !ok {
+		return nil
+	}
+	def := s.idx.defs[sp.id]
+	if def == nil {
+		return nil
+	}
+	return []Location{def.location()}
+}
+
+func (s *Server) references(p ReferenceParams) []Location {
+	id, ok := s.idx.nodeAt(p.TextDocument.URI, p.Position)
+	if !ok {
+		return nil
+	}
+	var out []Location
+	if def := s.idx.defs[id]; def != nil && p.Context.IncludeDeclaration && def.node.Source.Macro == "" {
+		out = append(out, def.location())
+	}
+	for _, r := range s.idx.refs {
+		if r.id == id {
+			out = append(out, r.location())
+		}
+	}
+	return out
+}
+
+// rename edits every reference and the declaration: the `id:` or single-file key when the
+// node has one, or else the node file itself, which the client is asked to rename.
+func (s *Server) rename(p RenameParams) (*WorkspaceEdit, error) {
+	id, ok := s.idx.nodeAt(p.TextDocument.URI, p.Position)
+	if !ok {
+		return nil, fmt.Errorf("no node at this position")
+	}
+	def := s.idx.defs[id]
+	switch {
+	case def == nil:
+		return nil, fmt.Errorf("node '%s' is not declared in this flow", id)
+	case def.node.Source.Macro != "":
+		return nil, fmt.Errorf("node '%s' is generated by flow '%s': rename the step there", id, def.node.Source.Macro)
+	case !validID.MatchString(p.NewName) || strings.HasPrefix(p.NewName, "pkg:") || domain.IsRollback(p.NewName):
+		return nil, fmt.Errorf("invalid node ID %q", p.NewName)
+	case p.NewName == id:
+		return &WorkspaceEdit{}, nil
+	case s.node(p.NewName) != nil:
+		return nil, fmt.Errorf("node '%s' already exists", p.NewName)
+	case !def.explicit && !s.renameFiles:
+		return nil, fmt.Errorf("node '%s' is named by its file, and the editor cannot rename files", id)
+	}
+
+	edits := make(map[string][]TextEdit)
+	var uris []string
+	add := func(uri string, rng Range) {
+		if _, ok := edits[uri]; !ok {
+			uris = append(uris, uri)
+		}
+		edits[uri] = append(edits[uri], TextEdit{Range: rng, NewText: p.NewName})
+	}
+	if def.explicit {
+		add(def.uri, def.rng)
+	}
+	for _, r := range s.idx.refs {
+		if r.id == id {
+			add(r.uri, r.rng)
+		}
+	}
+
+	edit := &WorkspaceEdit{}
+	for _, uri := range uris {
+		edit.DocumentChanges = append(edit.DocumentChanges, TextDocumentEdit{TextDocument: VersionedTextDocumentIdentifier{URI: uri}, Edits: edits[uri]})
+	}
+	// Text edits address the old file, so the file is renamed last.
+	if !def.explicit {
+		dir := strings.TrimSuffix(def.file, filepath.FromSlash(id)+filepath.Ext(def.file))
+		target := filepath.Join(dir, filepath.FromSlash(p.NewName)+filepath.Ext(def.file))
+		edit.DocumentChanges = append(edit.DocumentChanges, RenameFile{Kind: "rename", OldURI: def.uri, NewURI: pathToURI(target)})
+	}
+	return edit, nil
+}
+
+func (s *Server) hover(p TextDocumentPositionParams) *Hover {
+	sp, ok := s.idx.spanAt(p.TextDocument.URI, p.Position)
+	if !ok {
+		return nil
+	}
+	n := s.node(sp.id)
+	if n == nil {
+		return &Hover{Contents: MarkupContent{Kind: "markdown", Value: fmt.Sprintf("Node `%s` does not exist.", sp.id)}, Range: &sp.rng}
+	}
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: describeNode(n)}, Range: &sp.rng}
+}
+
+func (s *Server) node(id string) *domain.Node {
+	if s.idx.result == nil {
+		return nil
+	}
+	return s.idx.result.Nodes[id]
+}
+
+// describeNode renders the node as loaded (after extends, macros and sugar) in Markdown.
+func describeNode(n *domain.Node) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** `%s`", n.ID, n.Type)
+	if n.Source != nil {
+		fmt.Fprintf(&b, " — %s", n.Source)
+	}
+	b.WriteString("\n\n")
+
+	if n.InputType != "" {
+		fmt.Fprintf(&b, "- input: `%s`", n.InputType)
+		if len(n.InputOptions) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(n.InputOptions, ", "))
+		}
+		b.WriteString("\n")
+	}
+	if n.SaveTo != "" {
+		fmt.Fprintf(&b, "- save_to: `%s`\n", n.SaveTo)
+	}
+	if n.Do != nil {
+		fmt.Fprintf(&b, "- do: `%s`\n", n.Do.Name)
+	}
+	for _, bc := range n.Batch {
+		fmt.Fprintf(&b, "- do: `%s`\n", bc.Name)
+	}
+	for _, t := range n.Transitions {
+		if t.Condition != "" {
+			fmt.Fprintf(&b, "- `%s` → `%s`\n", t.Condition, t.ToNodeID)
+		} else {
+			fmt.Fprintf(&b, "- → `%s`\n", t.ToNodeID)
+		}
+	}
+	if n.StrictTransitions {
+		b.WriteString("- strict_transitions\n")
+	}
+	for _, edge := range []struct{ name, to string }{{"on_error", n.OnError}, {"on_denied", n.OnDenied}, {"on_unclear", n.OnUnclear}} {
+		if edge.to != "" {
+			fmt.Fprintf(&b, "- %s → `%s`\n", edge.name, edge.to)
+		}
+	}
+	if len(n.RequiredContext) > 0 {
+		fmt.Fprintf(&b, "- required_context: %s\n", strings.Join(n.RequiredContext, ", "))
+	}
+
+	if content := strings.TrimSpace(string(n.Content)); content != "" {
+		lines := strings.Split(content, "\n")
+		if len(lines) > 5 {
+			lines = append(lines[:5], "…")
+		}
+		fmt.Fprintf(&b, "\n```text\n%s\n```\n", strings.Join(lines, "\n"))
+	}
+	return b.String()
+}
@@ -0,0 +1,303 @@
+// Package lsp implements `trellis lsp`, a Language Server Protocol server for flow files.
+//
+// The server speaks JSON-RPC over stdio. It reuses the Loam loader and the static analyzer
+// (internal/lint): every save re-runs the analysis, publishes its findings as diagnostics and
+// refreshes the index of node declarations and references behind completion, go-to-definition,
+// find-references, rename and hover. References are read from the open buffers, so navigation
+// follows unsaved edits; diagnostics follow the files on disk.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aretw0/trellis/internal/lint"
+)
+
+// Analyzer runs the static analysis of the flow at root.
+type Analyzer func(root string) (*lint.Result, error)
+
+// Server is a language server for one flow directory.
+type Server struct {
+	analyze Analyzer
+	root    string
+	logger  *slog.Logger
+
+	conn        *conn
+	docs        map[string]string // Open documents by URI
+	idx         *index
+	published   map[string]bool // URIs with diagnostics, to clear them when fixed
+	renameFiles bool            // The client applies RenameFile operations
+	shutdown    bool
+}
+
+// Option configures the server.
+type Option func(*Server)
+
+// WithRoot fixes the flow directory, instead of the workspace root sent by the client.
+func WithRoot(dir string) Option {
+	return func(s *Server) {
+		s.root = dir
+	}
+}
+
+// WithLogger sets the logger for server errors (stdout is the protocol stream, so it must not write there).
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// NewServer creates a server that analyzes flows with analyze.
+func NewServer(analyze Analyzer, opts ...Option) *Server {
+	s := &Server{
+		analyze:   analyze,
+		logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		docs:      make(map[string]string),
+		idx:       &index{defs: map[string]*definition{}},
+		published: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Serve handles messages from in until the client sends `exit`, closes the stream or ctx is done.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.conn = newConn(in, out)
+	for ctx.Err() == nil {
+		msg, err := s.conn.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var rerr *rpcError
+			if errors.As(err, &rerr) {
+				if err := s.conn.reply(nil, nil, rerr); err != nil {
+					return err
+				}
+				continue
+			}
+			return fmt.Errorf("failed to read message: %w", err)
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if msg.ID == nil {
+			s.handleNotification(msg)
+			continue
+		}
+		result, err := s.handleRequest(msg)
+		if err := s.conn.reply(msg.ID, result, err); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func (s *Server) handleRequest(msg *message) (any, error) {
+	if s.shutdown {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "server is shutting down"}
+	}
+	switch msg.Method {
+	case "initialize":
+		var p InitializeParams
+		if err := decode(msg.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.initialize(p), nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/completion":
+		var p TextDocumentPositionParams
+		if err := decode(msg.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.completion(p), nil
+	case "textDocument/definition":
+		var p TextDocumentPositionParams
+		if err := decode(msg.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.definition(p), nil
+	case "textDocument/references":
+		var p ReferenceParams
+		if err := decode(msg.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.references(p), nil
+	case "textDocument/rename":
+		var p RenameParams
+		if err := decode(msg.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.rename(p)
+	case "textDocument/hover":
+		var p TextDocumentPositionParams
+		if err := decode(msg.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.hover(p), nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+}
+
+func (s *Server) handleNotification(msg *message) {
+	switch msg.Method {
+	case "initialized":
+		s.refresh()
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		if decode(msg.Params, &p) == nil {
+			s.docs[p.TextDocument.URI] = p.TextDocument.Text
+			s.reindex()
+		}
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		if decode(msg.Params, &p) == nil && len(p.ContentChanges) > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[len(p.ContentChanges)-1].Text
+			s.reindex()
+		}
+	case "textDocument/didSave", "workspace/didChangeWatchedFiles":
+		s.refresh()
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		if decode(msg.Params, &p) == nil {
+			delete(s.docs, p.TextDocument.URI)
+			s.reindex()
+		}
+	}
+}
+
+func (s *Server) initialize(p InitializeParams) any {
+	if s.root == "" {
+		switch {
+		case len(p.WorkspaceFolders) > 0:
+			s.root = uriToPath(p.WorkspaceFolders[0].URI)
+		case p.RootURI != "":
+			s.root = uriToPath(p.RootURI)
+		default:
+			s.root = p.RootPath
+		}
+	}
+	if s.root == "" {
+		s.root = "."
+	}
+	if abs, err := filepath.Abs(s.root); err == nil {
+		s.root = abs
+	}
+	edit := p.Capabilities.Workspace.WorkspaceEdit
+	s.renameFiles = edit.DocumentChanges && slices.Contains(edit.ResourceOperations, "rename")
+
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync": map[string]any{
+				"openClose": true,
+				"change":    1, // Full
+				"save":      map[string]any{"includeText": false},
+			},
+			"completionProvider": map[string]any{"triggerCharacters": []string{".", ":", " "}},
+			"definitionProvider": true,
+			"referencesProvider": true,
+			"renameProvider":     true,
+			"hoverProvider":      true,
+		},
+		"serverInfo": map[string]any{"name": "trellis"},
+	}
+}
+
+// refresh re-runs the analysis and publishes its findings. A flow that cannot be analyzed
+// (e.g. an invalid manifest) keeps the previous index and is reported to the user.
+func (s *Server) refresh() {
+	res, err := s.analyze(s.root)
+	if err != nil {
+		s.logger.Error("analysis failed", "root", s.root, "error", err)
+		s.notify("window/showMessage", LogMessageParams{Type: MessageError, Message: "trellis: " + err.Error()})
+		return
+	}
+	s.idx = buildIndex(res, s.text)
+	s.publish(res.Findings)
+}
+
+// reindex rescans references after an edit, keeping the last analysis.
+func (s *Server) reindex() {
+	s.idx = buildIndex(s.idx.result, s.text)
+}
+
+// text returns the content of a file, from the editor buffer when it is open.
+func (s *Server) text(path string) (string, bool) {
+	if doc, ok := s.docs[pathToURI(path)]; ok {
+		return doc, true
+	}
+	return readFile(path)
+}
+
+// publish sends the findings as diagnostics, grouped by file, and clears the files that no longer have any.
+func (s *Server) publish(findings []lint.Finding) {
+	byURI := make(map[string][]Diagnostic)
+	for _, f := range findings {
+		if f.File == "" {
+			// No position (e.g. a missing entry node): the editor can only show it as a message.
+			s.notify("window/logMessage", LogMessageParams{Type: MessageInfo, Message: f.String()})
+			continue
+		}
+		path := absPath(s.root, f.File)
+		uri := pathToURI(path)
+		byURI[uri] = append(byURI[uri], s.diagnostic(path, f))
+	}
+	for uri := range s.published {
+		if _, ok := byURI[uri]; !ok {
+			byURI[uri] = []Diagnostic{}
+		}
+	}
+	s.published = make(map[string]bool)
+	for uri, diags := range byURI {
+		if len(diags) > 0 {
+			s.published[uri] = true
+		}
+		s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diags})
+	}
+}
+
+// diagnostic covers the whole line of the finding (the first line when it has none).
+func (s *Server) diagnostic(path string, f lint.Finding) Diagnostic {
+	line := max(f.Line-1, 0)
+	var rng Range
+	if content, ok := s.text(path); ok {
+		if lines := splitLines(content); line < len(lines) {
+			rng = lineRange(lines[line], line, 0, len(lines[line]))
+		}
+	}
+	rng.Start.Line, rng.End.Line = line, line
+
+	severity := SeverityInformation
+	switch f.Severity {
+	case lint.SeverityError:
+		severity = SeverityError
+	case lint.SeverityWarning:
+		severity = SeverityWarning
+	}
+	return Diagnostic{Range: rng, Severity: severity, Code: f.Rule, Source: "trellis", Message: f.Message}
+}
+
+func (s *Server) notify(method string, params any) {
+	if err := s.conn.notify(method, params); err != nil {
+		s.logger.Error("failed to notify client", "method", method, "error", err)
+	}
+}
+
+func decode(raw json.RawMessage, v any) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: strings.TrimPrefix(err.Error(), "json: ")}
+	}
+	return nil
+}
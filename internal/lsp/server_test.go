@@ -0,0 +1,290 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/lint"
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/adapters/loam"
+)
+
+// client drives a Server over pipes, as an editor would.
+type client struct {
+	t      *testing.T
+	conn   *conn
+	nextID int
+	// notifications received while waiting for responses, in order.
+	notifications []*message
+	messages      chan *message // Read ahead, as an OS pipe would buffer them
+	done          chan error
+}
+
+func newClient(t *testing.T, srv *Server) *client {
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	c := &client{t: t, conn: newConn(clientIn, clientOut), messages: make(chan *message, 64), done: make(chan error, 1)}
+	go func() {
+		for {
+			msg, err := c.conn.read()
+			if err != nil {
+				close(c.messages)
+				return
+			}
+			c.messages <- msg
+		}
+	}()
+	go func() {
+		err := srv.Serve(context.Background(), serverIn, serverOut)
+		serverOut.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() { clientOut.Close() })
+	return c
+}
+
+func (c *client) notify(method string, params any) {
+	require.NoError(c.t, c.conn.notify(method, params))
+}
+
+// call sends a request and returns its response, keeping the notifications sent meanwhile.
+func (c *client) call(method string, params any, result any) *rpcError {
+	c.nextID++
+	id := json.RawMessage(fmt.Sprint(c.nextID))
+	raw, err := json.Marshal(params)
+	require.NoError(c.t, err)
+	require.NoError(c.t, c.conn.write(&message{ID: &id, Method: method, Params: raw}))
+	for {
+		msg := c.read()
+		if msg.ID == nil {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+		require.Equal(c.t, string(id), string(*msg.ID))
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil {
+			data, err := json.Marshal(msg.Result)
+			require.NoError(c.t, err)
+			require.NoError(c.t, json.Unmarshal(data, result))
+		}
+		return nil
+	}
+}
+
+func (c *client) read() *message {
+	select {
+	case msg, ok := <-c.messages:
+		require.True(c.t, ok, "server closed the stream")
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timeout waiting for the server")
+		return nil
+	}
+}
+
+// diagnostics returns the last diagnostics published for uri.
+func (c *client) diagnostics(uri string) []Diagnostic {
+	var out []Diagnostic
+	for _, n := range c.notifications {
+		if n.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var p PublishDiagnosticsParams
+		require.NoError(c.t, json.Unmarshal(n.Params, &p))
+		if p.URI == uri {
+			out = p.Diagnostics
+		}
+	}
+	return out
+}
+
+func analyzer(root string) (*lint.Result, error) {
+	return lint.Analyze(loam.NewFSLoader(os.DirFS(root)), lint.Options{Dir: root, Tools: []string{"greet"}})
+}
+
+func position(uri string, line, char int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{line, char}}
+}
+
+func TestServer(t *testing.T) {
+	start := "---\nsave_to: name\nwait: true\nto: sales\non_signal:\n  timeout: sales\n---\nWho are you?\n"
+	sales := "---\ndo:\n  name: greet\n  args:\n    to: start\non_error: start\nto: missing\n---\nHello {{ .name }}\n"
+	dir := testutils.WriteFlow(t, map[string]string{"start.md": start, "sales.md": sales})
+	startURI, salesURI := pathToURI(filepath.Join(dir, "start.md")), pathToURI(filepath.Join(dir, "sales.md"))
+
+	c := newClient(t, NewServer(analyzer))
+	var init InitializeParams
+	init.RootURI = pathToURI(dir)
+	init.Capabilities.Workspace.WorkspaceEdit.DocumentChanges = true
+	init.Capabilities.Workspace.WorkspaceEdit.ResourceOperations = []string{"create", "rename"}
+	var caps map[string]any
+	require.Nil(t, c.call("initialize", init, &caps))
+	assert.Contains(t, caps, "capabilities")
+	c.notify("initialized", struct{}{})
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: startURI, Text: start}})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		var locs []Location
+		require.Nil(t, c.call("textDocument/definition", position(startURI, 3, 5), &locs)) // Flushes notifications
+		diags := c.diagnostics(salesURI)
+		require.Len(t, diags, 1)
+		assert.Equal(t, lint.RuleDanglingRef, diags[0].Code)
+		assert.Equal(t, SeverityError, diags[0].Severity)
+		assert.Equal(t, 0, diags[0].Range.Start.Line)
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		var locs []Location
+		require.Nil(t, c.call("textDocument/definition", position(startURI, 3, 6), &locs))
+		require.Len(t, locs, 1)
+		assert.Equal(t, salesURI, locs[0].URI)
+
+		require.Nil(t, c.call("textDocument/definition", position(startURI, 7, 2), &locs))
+		assert.Empty(t, locs)
+	})
+
+	t.Run("references", func(t *testing.T) {
+		var locs []Location
+		params := ReferenceParams{TextDocumentPositionParams: position(startURI, 5, 12)}
+		require.Nil(t, c.call("textDocument/references", params, &locs))
+		require.Len(t, locs, 2)
+		assert.Equal(t, Range{Start: Position{3, 4}, End: Position{3, 9}}, locs[0].Range)
+		assert.Equal(t, Range{Start: Position{5, 11}, End: Position{5, 16}}, locs[1].Range)
+
+		// Anywhere in a node file means that node; tool arguments are not references.
+		params = ReferenceParams{TextDocumentPositionParams: position(startURI, 7, 0)}
+		require.Nil(t, c.call("textDocument/references", params, &locs))
+		require.Len(t, locs, 1)
+		assert.Equal(t, salesURI, locs[0].URI)
+		assert.Equal(t, 5, locs[0].Range.Start.Line)
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		edited := "---\nsave_to: name\nwait: true\nto: sa\non_signal:\n  timeout: sales\n---\nWho are you, {{ .na\n"
+		c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+			TextDocument: TextDocumentIdentifier{URI: startURI},
+			ContentChanges: []struct {
+				Text string `json:"text"`
+			}{{Text: edited}},
+		})
+
+		var items []CompletionItem
+		require.Nil(t, c.call("textDocument/completion", position(startURI, 3, 6), &items))
+		require.Len(t, items, 2)
+		assert.Equal(t, "sales", items[0].Label)
+		assert.Equal(t, Range{Start: Position{3, 4}, End: Position{3, 6}}, items[0].TextEdit.Range)
+
+		require.Nil(t, c.call("textDocument/completion", position(startURI, 7, 19), &items))
+		var keys []string
+		for _, item := range items {
+			keys = append(keys, item.Label)
+		}
+		assert.Contains(t, keys, "name")
+
+		c.notify("textDocument/didChange", DidChangeTextDocumentParams{
+			TextDocument: TextDocumentIdentifier{URI: startURI},
+			ContentChanges: []struct {
+				Text string `json:"text"`
+			}{{Text: start}},
+		})
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		var hover Hover
+		require.Nil(t, c.call("textDocument/hover", position(startURI, 3, 5), &hover))
+		assert.Contains(t, hover.Contents.Value, "**sales** `text`")
+		assert.Contains(t, hover.Contents.Value, "- do: `greet`")
+		assert.Contains(t, hover.Contents.Value, "- on_error → `start`")
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		var edit struct {
+			DocumentChanges []map[string]any `json:"documentChanges"`
+		}
+		require.Nil(t, c.call("textDocument/rename", RenameParams{TextDocumentPositionParams: position(startURI, 3, 5), NewName: "shop"}, &edit))
+		require.Len(t, edit.DocumentChanges, 2)
+		assert.Len(t, edit.DocumentChanges[0]["edits"], 2)
+		assert.Equal(t, "rename", edit.DocumentChanges[1]["kind"])
+		assert.Equal(t, pathToURI(filepath.Join(dir, "shop.md")), edit.DocumentChanges[1]["newUri"])
+
+		err := c.call("textDocument/rename", RenameParams{TextDocumentPositionParams: position(startURI, 3, 5), NewName: "start"}, nil)
+		require.NotNil(t, err)
+		assert.Equal(t, "node 'start' already exists", err.Message)
+	})
+
+	require.Nil(t, c.call("shutdown", nil, nil))
+	c.notify("exit", nil)
+	assert.NoError(t, <-c.done)
+}
+
+func TestServer_SingleFileRename(t *testing.T) {
+	flow := "nodes:\n  start:\n    to: next\n  next:\n    content: Bye\n"
+	dir := testutils.WriteFlow(t, map[string]string{"flow.yaml": flow})
+	uri := pathToURI(filepath.Join(dir, "flow.yaml"))
+
+	c := newClient(t, NewServer(func(root string) (*lint.Result, error) {
+		loader, _, err := loam.NewFileLoader(filepath.Join(root, "flow.yaml"))
+		if err != nil {
+			return nil, err
+		}
+		return lint.Analyze(loader, lint.Options{Dir: root})
+	}, WithRoot(dir)))
+	require.Nil(t, c.call("initialize", InitializeParams{}, nil))
+	c.notify("initialized", struct{}{})
+
+	var edit struct {
+		DocumentChanges []TextDocumentEdit `json:"documentChanges"`
+	}
+	// Renaming from the key, without file operations: both the key and the reference change.
+	require.Nil(t, c.call("textDocument/rename", RenameParams{TextDocumentPositionParams: position(uri, 3, 3), NewName: "bye"}, &edit))
+	require.Len(t, edit.DocumentChanges, 1)
+	assert.Equal(t, []TextEdit{
+		{Range: Range{Start: Position{3, 2}, End: Position{3, 6}}, NewText: "bye"},
+		{Range: Range{Start: Position{2, 8}, End: Position{2, 12}}, NewText: "bye"},
+	}, edit.DocumentChanges[0].Edits)
+
+	// Between nodes, the cursor names no node.
+	err := c.call("textDocument/rename", RenameParams{TextDocumentPositionParams: position(uri, 0, 0), NewName: "x"}, nil)
+	require.NotNil(t, err)
+	assert.Equal(t, "no node at this position", err.Message)
+}
+
+func TestRefsInLines(t *testing.T) {
+	lines := splitLines(`{
+  "to": "next",
+  "on_signal": {
+    "timeout": "late"
+  },
+  "do": {"name": "mail", "args": {"to": "bob"}},
+  "transitions": [
+    {"jump_to": "pkg:auth/start"}
+  ]
+}`)
+	got := make(map[int]string)
+	for i, r := range refsInLines(lines) {
+		got[i] = r.value
+	}
+	assert.Equal(t, map[int]string{1: "next", 3: "late", 7: "pkg:auth/start"}, got)
+}
+
+func TestConn(t *testing.T) {
+	in := "Content-Length: 40\r\nContent-Type: application/vscode-jsonrpc\r\n\r\n" + `{"jsonrpc":"2.0","id":1,"method":"test"}`
+	c := newConn(strings.NewReader(in), io.Discard)
+	msg, err := c.read()
+	require.NoError(t, err)
+	assert.Equal(t, "test", msg.Method)
+	_, err = c.read()
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,365 @@
+package lsp
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/aretw0/trellis/internal/lint"
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// refKeys are the metadata keys whose value is a node ID.
+var refKeys = []string{"to", "to_node_id", "jump_to", "on_error", "on_denied", "on_unclear", "on_timeout", "on_interrupt", "extends"}
+
+// signalMaps are the metadata keys holding a signal -> node ID map.
+var signalMaps = []string{"on_signal", "on_signal_default"}
+
+// opaqueKeys hold free-form data, where a `to:` is not a node reference (e.g. a tool argument).
+var opaqueKeys = []string{"args", "metadata", "default_context", "context_schema", "output_schema", "tools", "budget", "messages"}
+
+var (
+	// refLine matches `to: target`, `- jump_to: "target"` or `{"on_error": "target",` up to the value.
+	refLine = regexp.MustCompile(`^(\s*)(?:-\s+|\{\s*)?["']?(` + strings.Join(refKeys, "|") + `)["']?\s*:\s*["']?`)
+	// signalLine opens a signal map block: `on_signal:` (YAML) or `"on_signal": {` (JSON).
+	signalLine = regexp.MustCompile(`^(\s*)["']?(` + strings.Join(signalMaps, "|") + `)["']?\s*:\s*\{?\s*$`)
+	// opaqueLine opens a block of free-form data.
+	opaqueLine = regexp.MustCompile(`^(\s*)(?:-\s+|\{\s*)?["']?(` + strings.Join(opaqueKeys, "|") + `)["']?\s*:`)
+	// entryLine is a `key: value` entry of a map, up to the value.
+	entryLine = regexp.MustCompile(`^(\s*)["']?[\w.-]+["']?\s*:\s*["']?`)
+	// idLine is the explicit `id:` of a node file.
+	idLine = regexp.MustCompile(`^["']?id["']?\s*:\s*["']?`)
+	// keyLine is the node key of a single-file flow (`  start:`).
+	keyLine = regexp.MustCompile(`^(\s*)["']?([^\s:"'#]+)["']?\s*:`)
+	// idValue is the node ID at the start of a value.
+	idValue = regexp.MustCompile(`^[^\s"',#{}\[\]]*`)
+)
+
+// span is a range of a file naming a node.
+type span struct {
+	uri string
+	rng Range
+	id  string
+}
+
+func (s span) location() Location {
+	return Location{URI: s.uri, Range: s.rng}
+}
+
+// definition is where a node is declared.
+type definition struct {
+	span
+	node *domain.Node
+	// explicit is set when the span is an `id:` value or a single-file key, so renaming
+	// edits it. Otherwise the node is named by its file, which must be renamed instead.
+	explicit bool
+	file     string // Absolute path of the node file
+}
+
+// index is what the server knows about the workspace: the last analysis and the node
+// references found in the flow files, with open documents taking precedence over disk.
+type index struct {
+	result *lint.Result
+	defs   map[string]*definition
+	refs   []span
+	keys   []string // Context keys, sorted
+}
+
+// buildIndex scans the files of the analyzed nodes. text returns the current content of a file.
+func buildIndex(res *lint.Result, text func(path string) (string, bool)) *index {
+	idx := &index{result: res, defs: make(map[string]*definition)}
+	if res == nil {
+		return idx
+	}
+
+	// Nodes per file: more than one means a single-file flow (or a macro and its steps).
+	files := make(map[string][]*domain.Node)
+	for _, n := range res.Nodes {
+		if n.Source != nil && n.Source.File != "" {
+			path := absPath(res.Options.Dir, n.Source.File)
+			files[path] = append(files[path], n)
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		content, ok := text(path)
+		if !ok {
+			continue
+		}
+		lines := splitLines(content)
+		uri := pathToURI(path)
+		idx.refs = append(idx.refs, scanRefs(uri, path, lines)...)
+		for _, n := range files[path] {
+			if n.Source.Macro != "" {
+				continue // Located at their macro, below
+			}
+			idx.defs[n.ID] = declare(uri, path, lines, n, len(files[path]) > 1)
+		}
+	}
+	for id, n := range res.Nodes {
+		if _, ok := idx.defs[id]; ok || n.Source == nil || n.Source.Macro == "" {
+			continue
+		}
+		if macro := idx.defs[n.Source.Macro]; macro != nil {
+			idx.defs[id] = &definition{span: span{uri: macro.uri, rng: macro.rng, id: id}, node: n, file: macro.file}
+		}
+	}
+	idx.keys = contextKeys(res)
+	return idx
+}
+
+// declare locates the declaration of n in its file.
+func declare(uri, path string, lines []string, n *domain.Node, shared bool) *definition {
+	def := &definition{span: span{uri: uri, id: n.ID}, node: n, file: path}
+	line := n.Source.Line - 1
+	if shared && line >= 0 && line < len(lines) {
+		if m := keyLine.FindStringSubmatchIndex(lines[line]); m != nil {
+			def.rng = lineRange(lines[line], line, m[4], m[5])
+			def.explicit = true
+		}
+		return def
+	}
+	for i, l := range metadataLines(path, lines) {
+		if l == "" {
+			continue
+		}
+		if m := idLine.FindStringIndex(l); m != nil {
+			end := m[1] + len(idValue.FindString(l[m[1]:]))
+			def.rng = lineRange(l, i, m[1], end)
+			def.explicit = true
+			return def
+		}
+	}
+	if line > 0 && line < len(lines) {
+		def.rng = lineRange(lines[line], line, 0, 0)
+	}
+	return def
+}
+
+// scanRefs finds the node IDs in the metadata of a file: reference keys and signal maps.
+func scanRefs(uri, path string, lines []string) []span {
+	var out []span
+	for i, r := range refsInLines(metadataLines(path, lines)) {
+		if r.value != "" {
+			out = append(out, span{uri: uri, rng: lineRange(lines[i], i, r.start, r.start+len(r.value)), id: r.value})
+		}
+	}
+	sort.Slice(out, func(a, b int) bool {
+		if out[a].rng.Start.Line != out[b].rng.Start.Line {
+			return out[a].rng.Start.Line < out[b].rng.Start.Line
+		}
+		return out[a].rng.Start.Character < out[b].rng.Start.Character
+	})
+	return out
+}
+
+// lineRef is the value of a reference key on a line, possibly empty (while typing).
+type lineRef struct {
+	start int // Byte offset of the value
+	value string
+}
+
+// refsInLines returns the reference on each line that has one, by line number.
+func refsInLines(lines []string) map[int]lineRef {
+	out := make(map[int]lineRef)
+	signalIndent, opaqueIndent := -1, -1
+	for i, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		indent := len(l) - len(strings.TrimLeft(l, " \t"))
+		if signalIndent >= 0 && indent <= signalIndent {
+			signalIndent = -1
+		}
+		if opaqueIndent >= 0 {
+			if indent > opaqueIndent {
+				continue
+			}
+			opaqueIndent = -1
+		}
+		if m := opaqueLine.FindStringSubmatchIndex(l); m != nil {
+			opaqueIndent = m[3] - m[2]
+			continue
+		}
+		if m := signalLine.FindStringSubmatchIndex(l); m != nil {
+			signalIndent = m[3] - m[2]
+			continue
+		}
+		var m []int
+		if signalIndent >= 0 {
+			m = entryLine.FindStringIndex(l)
+		} else {
+			m = refLine.FindStringIndex(l)
+		}
+		if m != nil {
+			out[i] = lineRef{start: m[1], value: idValue.FindString(l[m[1]:])}
+		}
+	}
+	return out
+}
+
+// metadataLines blanks out the lines that cannot hold metadata: the body of a Markdown
+// node, after its frontmatter. Line numbers are kept.
+func metadataLines(path string, lines []string) []string {
+	if !strings.EqualFold(filepath.Ext(path), ".md") {
+		return lines
+	}
+	out := make([]string, len(lines))
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return out
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			break
+		}
+		out[i] = lines[i]
+	}
+	return out
+}
+
+// contextKeys lists the keys a template can read: declared in the manifest, written or
+// declared by nodes, and provided by the engine.
+func contextKeys(res *lint.Result) []string {
+	set := map[string]bool{"sys": true, "tool_result": true, "tool_results": true}
+	for _, k := range res.Options.Context {
+		set[k] = true
+	}
+	for _, n := range res.Nodes {
+		if n.SaveTo != "" {
+			set[strings.SplitN(n.SaveTo, ".", 2)[0]] = true
+		}
+		for k := range n.DefaultContext {
+			set[k] = true
+		}
+		for _, k := range n.RequiredContext {
+			set[k] = true
+		}
+		for k := range n.ContextSchema {
+			set[k] = true
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// spanAt returns the reference or declaration under the cursor.
+func (idx *index) spanAt(uri string, pos Position) (span, bool) {
+	for _, r := range idx.refs {
+		if r.uri == uri && r.rng.contains(pos) {
+			return r, true
+		}
+	}
+	for _, d := range idx.defs {
+		if d.explicit && d.uri == uri && d.node.Source.Macro == "" && d.rng.contains(pos) {
+			return d.span, true
+		}
+	}
+	return span{}, false
+}
+
+// nodeAt resolves the node ID under the cursor. Anywhere else in a directory node file,
+// the file's own node is meant.
+func (idx *index) nodeAt(uri string, pos Position) (string, bool) {
+	if s, ok := idx.spanAt(uri, pos); ok {
+		return s.id, true
+	}
+	var found string
+	for id, d := range idx.defs {
+		if d.uri != uri || d.node.Source.Macro != "" {
+			continue
+		}
+		if found != "" {
+			return "", false // A single-file flow: the cursor must be on a name
+		}
+		found = id
+	}
+	return found, found != ""
+}
+
+func splitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	return lines
+}
+
+// lineRange builds the range between two byte offsets of a line.
+func lineRange(line string, n, start, end int) Range {
+	return Range{Start: Position{n, utf16Column(line, start)}, End: Position{n, utf16Column(line, end)}}
+}
+
+// utf16Column converts a byte offset of line into a UTF-16 column.
+func utf16Column(line string, offset int) int {
+	col := 0
+	for _, r := range line[:min(offset, len(line))] {
+		col += utf16.RuneLen(r)
+	}
+	return col
+}
+
+// byteOffset converts a UTF-16 column of line into a byte offset.
+func byteOffset(line string, col int) int {
+	n := 0
+	for i, r := range line {
+		if n >= col {
+			return i
+		}
+		n += utf16.RuneLen(r)
+	}
+	return len(line)
+}
+
+func absPath(dir, file string) string {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		return abs
+	}
+	return file
+}
+
+func pathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows drive letters
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// uriToPath returns the local path of a file:// URI, or "" for other schemes.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.Clean(filepath.FromSlash(path))
+}
+
+// readFile is the default source of files that are not open in the editor.
+func readFile(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
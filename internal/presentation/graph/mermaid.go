@@ -2,7 +2,9 @@ package graph
 
 import (
 	"fmt"
+	"maps"
 	"path"
+	"slices"
 	"strings"
 
 	"github.com/aretw0/trellis/pkg/domain"
@@ -12,6 +14,10 @@ import (
 type GraphOverlay struct {
 	VisitedNodes []string
 	CurrentNode  string
+	// CoveredEdges, when set, turns the overlay into a coverage map: it tells whether each
+	// edge (by domain.EdgeKey) was taken. Taken edges are drawn green, the others red, and
+	// nodes missing from VisitedNodes are marked as uncovered.
+	CoveredEdges map[string]bool
 }
 
 // GenerateMermaid produces a Mermaid flowchart syntax string from a list of nodes.
@@ -25,6 +31,20 @@ func GenerateMermaid(nodes []domain.Node, overlay *GraphOverlay) string {
 	var sb strings.Builder
 	sb.WriteString("graph TD\n")
 
+	// Mermaid styles links by their position: track it for the coverage overlay.
+	link := 0
+	var taken, missed []string
+	addLink := func(key string) {
+		if overlay != nil && overlay.CoveredEdges != nil {
+			if overlay.CoveredEdges[key] {
+				taken = append(taken, fmt.Sprint(link))
+			} else {
+				missed = append(missed, fmt.Sprint(link))
+			}
+		}
+		link++
+	}
+
 	for _, node := range nodes {
 		// Sanitize ID for Mermaid
 		safeID := sanitizeMermaidID(node.ID)
@@ -41,15 +61,21 @@ func GenerateMermaid(nodes []domain.Node, overlay *GraphOverlay) string {
 			opener, closer = "[/", "/]" // Parallelogram (Input)
 		}
 
-		label := fmt.Sprintf("    %s%s\"%s\"%s\n", safeID, opener, node.ID, closer)
+		text := node.ID
+		if node.Source != nil && node.Source.Macro != "" {
+			// Generated by a macro: point back to the script line
+			text = fmt.Sprintf("%s:%d <br/> %s", node.Source.Macro, node.Source.Line, sanitizeMermaidLabel(node.Source.Step))
+		}
+		label := fmt.Sprintf("    %s%s\"%s\"%s\n", safeID, opener, text, closer)
 		if node.Timeout != "" {
 			// Annotate node with Timeout clock icon or text
-			label = fmt.Sprintf("    %s%s\"%s <br/> ⏱️ %s\"%s\n", safeID, opener, node.ID, node.Timeout, closer)
+			label = fmt.Sprintf("    %s%s\"%s <br/> ⏱️ %s\"%s\n", safeID, opener, text, node.Timeout, closer)
 		}
 		sb.WriteString(label)
 
 		// Transitions
-		for _, t := range node.Transitions {
+		for i, t := range node.Transitions {
+			addLink(domain.EdgeKey(node.ID, domain.TransitionNext, i, ""))
 			safeTo := sanitizeMermaidID(t.ToNodeID)
 
 			// Determine if it's a cross-module transition (Jump)
@@ -73,8 +99,9 @@ func GenerateMermaid(nodes []domain.Node, overlay *GraphOverlay) string {
 		}
 
 		// Signal Transitions (Intervention)
-		for signalName, targetID := range node.OnSignal {
-			safeTo := sanitizeMermaidID(targetID)
+		for _, signalName := range slices.Sorted(maps.Keys(node.OnSignal)) {
+			addLink(domain.EdgeKey(node.ID, domain.TransitionSignal, -1, signalName))
+			safeTo := sanitizeMermaidID(node.OnSignal[signalName])
 			// Use dotted line with lightning bolt/signal icon
 			arrow := fmt.Sprintf("-. ⚡ %s .->", signalName)
 			sb.WriteString(fmt.Sprintf("    %s %s %s\n", safeID, arrow, safeTo))
@@ -103,6 +130,21 @@ func GenerateMermaid(nodes []domain.Node, overlay *GraphOverlay) string {
 			safeCurrent := sanitizeMermaidID(overlay.CurrentNode)
 			sb.WriteString(fmt.Sprintf("    class %s current;\n", safeCurrent))
 		}
+
+		if overlay.CoveredEdges != nil {
+			sb.WriteString("    classDef uncovered fill:#ffebee,stroke:#c62828,stroke-dasharray:4 2,color:#000;\n")
+			for _, node := range nodes {
+				if safeID := sanitizeMermaidID(node.ID); !visitedSet[safeID] {
+					sb.WriteString(fmt.Sprintf("    class %s uncovered;\n", safeID))
+				}
+			}
+			if len(taken) > 0 {
+				sb.WriteString(fmt.Sprintf("    linkStyle %s stroke:#2e7d32,stroke-width:2px;\n", strings.Join(taken, ",")))
+			}
+			if len(missed) > 0 {
+				sb.WriteString(fmt.Sprintf("    linkStyle %s stroke:#c62828,stroke-dasharray:4 2;\n", strings.Join(missed, ",")))
+			}
+		}
 	}
 
 	return sb.String()
@@ -113,5 +155,17 @@ func sanitizeMermaidID(id string) string {
 	s = strings.ReplaceAll(s, "-", "_")
 	s = strings.ReplaceAll(s, "/", "_")
 	s = strings.ReplaceAll(s, "\\", "_")
+	s = strings.ReplaceAll(s, "#", "__")
+	s = strings.ReplaceAll(s, ":", "__")
 	return s
 }
+
+// sanitizeMermaidLabel makes free text safe inside a quoted Mermaid label.
+func sanitizeMermaidLabel(text string) string {
+	const maxLen = 40
+	if r := []rune(text); len(r) > maxLen {
+		text = string(r[:maxLen-1]) + "…"
+	}
+	text = strings.ReplaceAll(text, "\"", "'")
+	return strings.ReplaceAll(text, "#", "#35;")
+}
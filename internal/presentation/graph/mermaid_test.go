@@ -54,6 +54,31 @@ func TestGenerateMermaid(t *testing.T) {
 				"hyphen_ated[\"hyphen-ated\"]",
 			},
 		},
+		{
+			name: "Macro Source Map",
+			nodes: []domain.Node{
+				{
+					ID:     "checkout#ask-email",
+					Type:   domain.NodeTypeQuestion,
+					Source: &domain.SourceRef{Macro: "checkout", Line: 3, Step: `ask email: "Your #1 email?"`},
+				},
+			},
+			contains: []string{
+				`checkout__ask_email[/"checkout:3 <br/> ask email: 'Your #35;1 email?'"/]`,
+			},
+		},
+		{
+			name: "Package Reference",
+			nodes: []domain.Node{
+				{
+					ID:          "start",
+					Transitions: []domain.Transition{{ToNodeID: "pkg:auth/start"}},
+				},
+			},
+			contains: []string{
+				`start -.-> pkg__auth_start`,
+			},
+		},
 		{
 			name: "Transition Escaping",
 			nodes: []domain.Node{
@@ -81,3 +106,30 @@ func TestGenerateMermaid(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateMermaid_CoverageOverlay(t *testing.T) {
+	nodes := []domain.Node{
+		{ID: "start", Transitions: []domain.Transition{{ToNodeID: "a", Condition: "input == 'a'"}, {ToNodeID: "b"}}, OnSignal: map[string]string{"timeout": "b", "interrupt": "a"}},
+		{ID: "a"},
+		{ID: "b"},
+	}
+	out := graph.GenerateMermaid(nodes, &graph.GraphOverlay{
+		VisitedNodes: []string{"start", "a"},
+		CoveredEdges: map[string]bool{"start#0": true, "start!timeout": true},
+	})
+
+	// Links are numbered in declaration order: transitions, then signals sorted by name.
+	for _, want := range []string{
+		"start -. ⚡ interrupt .-> a\n    start -. ⚡ timeout .-> b",
+		"class b uncovered;",
+		"linkStyle 0,3 stroke:#2e7d32",
+		"linkStyle 1,2 stroke:#c62828",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "class a uncovered;") {
+		t.Errorf("Visited node marked as uncovered:\n%s", out)
+	}
+}
@@ -0,0 +1,69 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// GenerateResolved renders the effective definition of each node as a YAML document,
+// as the engine sees it: after inheritance (`extends`, `_defaults`) and macro expansion.
+// When ids are given, only those nodes are rendered, in that order.
+func GenerateResolved(nodes []domain.Node, ids ...string) (string, error) {
+	selected := nodes
+	if len(ids) > 0 {
+		byID := make(map[string]domain.Node, len(nodes))
+		for _, node := range nodes {
+			byID[node.ID] = node
+		}
+		selected = make([]domain.Node, 0, len(ids))
+		for _, id := range ids {
+			node, ok := byID[id]
+			if !ok {
+				return "", fmt.Errorf("node %s not found", id)
+			}
+			selected = append(selected, node)
+		}
+	}
+
+	var sb strings.Builder
+	for i, node := range selected {
+		doc, err := resolvedDocument(node)
+		if err != nil {
+			return "", fmt.Errorf("node %s: %w", node.ID, err)
+		}
+		out, err := yaml.Marshal(doc)
+		if err != nil {
+			return "", fmt.Errorf("node %s: %w", node.ID, err)
+		}
+		if i > 0 {
+			sb.WriteString("---\n")
+		}
+		sb.Write(out)
+	}
+	return sb.String(), nil
+}
+
+// resolvedDocument encodes a node, showing its content as text instead of bytes.
+func resolvedDocument(node domain.Node) (*yaml.Node, error) {
+	content := string(node.Content)
+	node.Content = nil
+
+	var doc yaml.Node
+	if err := doc.Encode(node); err != nil {
+		return nil, err
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value != "content" {
+			continue
+		}
+		value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: content}
+		if strings.Contains(content, "\n") {
+			value.Style = yaml.LiteralStyle
+		}
+		doc.Content[i+1] = value
+	}
+	return &doc, nil
+}
@@ -0,0 +1,39 @@
+package graph_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aretw0/trellis/internal/presentation/graph"
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+func TestGenerateResolved(t *testing.T) {
+	nodes := []domain.Node{
+		{ID: "start", Type: domain.NodeTypeText, Content: []byte("Hello\nWorld"), OnError: "oops",
+			Transitions: []domain.Transition{{ToNodeID: "done"}}},
+		{ID: "done", Type: domain.NodeTypeText, Content: []byte("Bye")},
+	}
+
+	all, err := graph.GenerateResolved(nodes)
+	if err != nil {
+		t.Fatalf("GenerateResolved failed: %v", err)
+	}
+	for _, want := range []string{"id: start", "content: |-\n    Hello\n    World", "on_error: oops", "- to: done", "---\nid: done", "content: Bye"} {
+		if !strings.Contains(all, want) {
+			t.Errorf("Expected %q in output:\n%s", want, all)
+		}
+	}
+
+	one, err := graph.GenerateResolved(nodes, "done")
+	if err != nil {
+		t.Fatalf("GenerateResolved failed: %v", err)
+	}
+	if strings.Contains(one, "start") || strings.Contains(one, "---") {
+		t.Errorf("Expected only the selected node, got:\n%s", one)
+	}
+
+	if _, err := graph.GenerateResolved(nodes, "ghost"); err == nil {
+		t.Error("Expected an error for an unknown node")
+	}
+}
@@ -0,0 +1,98 @@
+package runtime
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// trackAsync records results that only acknowledge a background execution (Accepted)
+// and clears the async bookkeeping of calls that have now completed.
+// It returns the updated state and the results that still need to be processed.
+func (e *Engine) trackAsync(state *domain.State, results []domain.ToolResult) (*domain.State, []domain.ToolResult, error) {
+	var (
+		next      = state
+		remaining = make([]domain.ToolResult, 0, len(results))
+		node      *domain.Node
+	)
+	mutable := func() {
+		if next == state {
+			next = e.cloneState(state)
+		}
+	}
+
+	for _, result := range results {
+		_, accepted := state.AsyncCall(result.ID)
+
+		if !result.Accepted {
+			if accepted {
+				mutable()
+				next.AsyncCalls = removeAsyncCall(next.AsyncCalls, result.ID)
+			}
+			remaining = append(remaining, result)
+			continue
+		}
+
+		if !state.IsPending(result.ID) {
+			return nil, nil, fmt.Errorf("accepted tool call %s is not pending", result.ID)
+		}
+		if accepted {
+			return nil, nil, fmt.Errorf("tool call %s was already accepted", result.ID)
+		}
+		if node == nil {
+			var err error
+			if node, err = e.loadNode(state.CurrentNodeID); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		mutable()
+		call := domain.AsyncCall{
+			ID:             result.ID,
+			IdempotencyKey: e.generateIdempotencyKey(state, node.ID, asyncKeyDiscriminator(state, node, result.ID)),
+			Handle:         result.Handle,
+			AcceptedAt:     time.Now().UTC(),
+		}
+		if node.Timeout != "" {
+			if d, err := time.ParseDuration(node.Timeout); err == nil {
+				deadline := call.AcceptedAt.Add(d)
+				call.Deadline = &deadline
+			} else {
+				e.logger.Warn("Failed to parse node timeout", "node_id", node.ID, "timeout", node.Timeout, "error", err)
+			}
+		}
+		next.AsyncCalls = append(next.AsyncCalls, call)
+		e.logger.Debug("tool call accepted for async completion", "node", node.ID, "call", result.ID, "handle", result.Handle)
+	}
+
+	return next, remaining, nil
+}
+
+// asyncKeyDiscriminator mirrors the discriminator used when rendering the call,
+// so the recorded idempotency key matches the one the Host received.
+func asyncKeyDiscriminator(state *domain.State, node *domain.Node, callID string) string {
+	if len(state.PendingToolCalls) > 0 {
+		return callID
+	}
+	if state.Status == domain.StatusRollingBack && node.Undo != nil {
+		return node.Undo.Name
+	}
+	if node.Do != nil {
+		return node.Do.Name
+	}
+	return callID
+}
+
+func removeAsyncCall(calls []domain.AsyncCall, callID string) []domain.AsyncCall {
+	kept := make([]domain.AsyncCall, 0, len(calls))
+	for _, call := range calls {
+		if call.ID != callID {
+			kept = append(kept, call)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
@@ -0,0 +1,77 @@
+package runtime_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aretw0/trellis/internal/runtime"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_AsyncAcceptAndComplete(t *testing.T) {
+	loader := memory.NewLoader(map[string]string{
+		"start": `{"id": "start", "do": {"id": "build", "name": "ci_build"}, "timeout": "30m", "save_to": "build", "transitions": [{"to_node_id": "done"}]}`,
+		"done":  `{"id": "done", "type": "text"}`,
+	})
+	engine := runtime.NewEngine(loader, nil, nil)
+	ctx := context.Background()
+
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+	actions, _, err := engine.Render(ctx, state)
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	call := actions[0].Payload.(domain.ToolCall)
+
+	accepted, err := engine.Navigate(ctx, state, domain.ToolResult{ID: "build", Accepted: true, Handle: "job-42"})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusWaitingForTool, accepted.Status)
+	assert.True(t, accepted.AwaitingAsync())
+	assert.Empty(t, state.AsyncCalls, "previous state must be untouched")
+
+	async, ok := accepted.AsyncCall("build")
+	require.True(t, ok)
+	assert.Equal(t, call.IdempotencyKey, async.IdempotencyKey)
+	assert.Equal(t, "job-42", async.Handle)
+	require.NotNil(t, async.Deadline)
+
+	// Accepted calls are not fired again.
+	actions, _, err = engine.Render(ctx, accepted)
+	require.NoError(t, err)
+	assert.Empty(t, actions)
+
+	_, err = engine.Navigate(ctx, accepted, domain.ToolResult{ID: "build", Accepted: true})
+	assert.ErrorContains(t, err, "already accepted")
+
+	done, err := engine.Navigate(ctx, accepted, domain.ToolResult{ID: "build", Result: "green"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", done.CurrentNodeID)
+	assert.Empty(t, done.AsyncCalls)
+	assert.Equal(t, "green", done.Context["build"])
+}
+
+func TestEngine_AsyncBatchMixed(t *testing.T) {
+	engine := batchLoader(t, "")
+	ctx := context.Background()
+	state := enterBatch(t, engine)
+
+	state, err := engine.Navigate(ctx, state, []domain.ToolResult{
+		{ID: "email", Accepted: true},
+		{ID: "sms", Result: "queued"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"email"}, state.PendingToolCalls)
+	assert.True(t, state.AwaitingAsync())
+
+	actions, _, err := engine.Render(ctx, state)
+	require.NoError(t, err)
+	assert.Empty(t, actions)
+
+	next, err := engine.Navigate(ctx, state, domain.ToolResult{ID: "email", Result: "sent"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", next.CurrentNodeID)
+	assert.Empty(t, next.AsyncCalls)
+}
@@ -0,0 +1,207 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// awaitTools puts the state in WaitingForTool if the node fires side-effects.
+// Single calls use PendingToolCall; batches track the set of pending IDs.
+func awaitTools(state *domain.State, node *domain.Node) {
+	state.PendingToolCall = ""
+	state.PendingToolCalls = nil
+	state.BatchResults = nil
+	state.AsyncCalls = nil
+
+	switch {
+	case node.Do != nil:
+		state.Status = domain.StatusWaitingForTool
+		state.PendingToolCall = node.Do.ID
+	case len(node.Batch) > 0:
+		state.Status = domain.StatusWaitingForTool
+		ids := make([]string, 0, len(node.Batch))
+		for _, call := range node.Batch {
+			ids = append(ids, call.ID)
+		}
+		state.PendingToolCalls = ids
+	}
+}
+
+// toToolResults normalizes Navigate input into a list of results.
+func toToolResults(input any) ([]domain.ToolResult, bool) {
+	switch v := input.(type) {
+	case domain.ToolResult:
+		return []domain.ToolResult{v}, true
+	case []domain.ToolResult:
+		return v, true
+	}
+	return nil, false
+}
+
+// navigateBatch collects results for a pending batch (Do list or batch Undo list).
+// Results may arrive all at once or one at a time; the node only resolves once every
+// pending call has reported back.
+func (e *Engine) navigateBatch(ctx context.Context, currentState *domain.State, input any) (*domain.State, error) {
+	results, ok := toToolResults(input)
+	if !ok || len(results) == 0 {
+		return nil, fmt.Errorf("expected ToolResult or []ToolResult input when in WaitingForTool/RollingBack status")
+	}
+
+	nextState := e.cloneState(currentState)
+	for _, result := range results {
+		idx := indexOf(nextState.PendingToolCalls, result.ID)
+		if idx < 0 {
+			for _, received := range nextState.BatchResults {
+				if received.ID == result.ID {
+					return nil, fmt.Errorf("duplicate tool result for call %s", result.ID)
+				}
+			}
+			return nil, fmt.Errorf("tool result ID %s does not match any pending call %v", result.ID, currentState.PendingToolCalls)
+		}
+		nextState.PendingToolCalls = append(nextState.PendingToolCalls[:idx], nextState.PendingToolCalls[idx+1:]...)
+		nextState.BatchResults = append(nextState.BatchResults, result)
+	}
+
+	// Partial: keep waiting for the remaining calls.
+	if len(nextState.PendingToolCalls) > 0 {
+		return nextState, nil
+	}
+	nextState.PendingToolCalls = nil
+
+	// Batch compensation completed: keep unwinding.
+	if nextState.Status == domain.StatusRollingBack {
+		node, err := e.loadNode(nextState.CurrentNodeID)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range nextState.BatchResults {
+			e.recordUsage(nextState, undoNameFor(node, result.ID), result)
+		}
+		nextState.BatchResults = nil
+		return e.continueRollback(ctx, nextState, true)
+	}
+
+	node, err := e.loadNode(nextState.CurrentNodeID)
+	if err != nil {
+		return nil, err
+	}
+	return e.handleBatchResults(ctx, nextState, node)
+}
+
+// handleBatchResults resolves a completed batch according to the node's BatchPolicy.
+func (e *Engine) handleBatchResults(ctx context.Context, state *domain.State, node *domain.Node) (*domain.State, error) {
+	results := state.BatchResults
+	state.BatchResults = nil
+
+	names := make(map[string]string, len(node.Batch))
+	for _, call := range node.Batch {
+		names[call.ID] = call.Name
+	}
+
+	succeeded := make(map[string]bool, len(results))
+	exposed := make(map[string]any, len(results))
+	var failures []string
+	denied := 0
+
+	for _, result := range results {
+		// Usage is recorded as the loop goes, so each call sees the calls before it.
+		if !result.IsDenied {
+			result, _ = e.meterResult(state, names[result.ID], result)
+		}
+		entry := flattenToolResult(result)
+
+		if result.IsDenied {
+			denied++
+			entry["_denied"] = true
+			entry["_error"] = result.Error
+			exposed[result.ID] = entry
+			continue
+		}
+
+		e.emitToolReturn(ctx, state.CurrentNodeID, names[result.ID], result.Result, result.IsError, result.Usage)
+
+		if result.IsError {
+			cause := result.Error
+			if cause == "" {
+				cause = fmt.Sprintf("%v", result.Result)
+			}
+			failures = append(failures, fmt.Sprintf("%s: %s", result.ID, cause))
+			entry["_error"] = cause
+		} else {
+			succeeded[result.ID] = true
+		}
+		exposed[result.ID] = entry
+	}
+
+	// Expose every outcome, keyed by call ID: {{ .tool_results.<id>.field }}
+	state.Context["tool_results"] = exposed
+
+	policy := node.BatchPolicy
+	if policy == "" {
+		policy = domain.BatchPolicyAll
+	}
+
+	if (len(failures) == 0 && denied == 0) || policy == domain.BatchPolicyContinue {
+		state.Status = domain.StatusActive
+		return e.navigateInternal(ctx, state, exposed)
+	}
+
+	rollback := func(s *domain.State) (*domain.State, error) {
+		return e.startBatchRollback(ctx, s, node, succeeded)
+	}
+
+	if policy == domain.BatchPolicyRollback {
+		e.emitNodeLeave(ctx, node)
+		return rollback(state)
+	}
+
+	// Policy "all": the node fails as a whole.
+	if len(failures) == 0 {
+		e.logger.Debug("batch execution denied", "node", node.ID, "denied", denied)
+		return e.routeDenial(ctx, state, node)
+	}
+	return e.routeToolError(ctx, state, node, "batch", fmt.Sprintf("%v", failures), rollback)
+}
+
+// startBatchRollback compensates the calls of the current batch that succeeded,
+// then continues the regular SAGA unwinding through history.
+func (e *Engine) startBatchRollback(ctx context.Context, state *domain.State, node *domain.Node, succeeded map[string]bool) (*domain.State, error) {
+	undos := node.BatchUndoCalls(func(id string) bool { return succeeded[id] })
+	if len(undos) == 0 {
+		return e.startRollback(ctx, state)
+	}
+
+	nextState := e.cloneState(state)
+	nextState.Status = domain.StatusRollingBack
+	nextState.PendingToolCall = ""
+	nextState.BatchResults = nil
+	nextState.PendingToolCalls = make([]string, 0, len(undos))
+	for _, undo := range undos {
+		nextState.PendingToolCalls = append(nextState.PendingToolCalls, undo.ID)
+	}
+	return nextState, nil
+}
+
+// loadNode fetches and parses a node by ID.
+func (e *Engine) loadNode(nodeID string) (*domain.Node, error) {
+	raw, err := e.loader.GetNode(nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load node %s: %w", nodeID, err)
+	}
+	node, err := e.parser.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse node %s: %w", nodeID, err)
+	}
+	return node, nil
+}
+
+func indexOf(list []string, value string) int {
+	for i, v := range list {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
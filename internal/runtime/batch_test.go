@@ -0,0 +1,172 @@
+package runtime_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aretw0/trellis/internal/runtime"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batchLoader(t *testing.T, policy string) *runtime.Engine {
+	t.Helper()
+	loader := memory.NewLoader(map[string]string{
+		"start": `{"id": "start", "type": "text", "transitions": [{"to_node_id": "fanout"}]}`,
+		"fanout": `{
+			"id": "fanout",
+			"do": [
+				{"name": "email", "args": {"to": "{{ .user }}"}, "undo": {"name": "unsend"}},
+				{"name": "sms", "undo": {"name": "unsms"}}
+			],
+			"batch_policy": "` + policy + `",
+			"on_error": "failed",
+			"on_denied": "denied",
+			"save_to": "outcomes",
+			"transitions": [{"to_node_id": "done"}]
+		}`,
+		"done":   `{"id": "done", "type": "text"}`,
+		"failed": `{"id": "failed", "type": "text"}`,
+		"denied": `{"id": "denied", "type": "text"}`,
+	})
+	return runtime.NewEngine(loader, nil, nil)
+}
+
+func enterBatch(t *testing.T, engine *runtime.Engine) *domain.State {
+	t.Helper()
+	ctx := context.Background()
+	state, err := engine.Start(ctx, "s1", map[string]any{"user": "alice"})
+	require.NoError(t, err)
+	state, err = engine.Navigate(ctx, state, "")
+	require.NoError(t, err)
+	require.Equal(t, "fanout", state.CurrentNodeID)
+	return state
+}
+
+func TestEngine_Batch_RenderAndCollect(t *testing.T) {
+	engine := batchLoader(t, "")
+	ctx := context.Background()
+	state := enterBatch(t, engine)
+
+	assert.Equal(t, domain.StatusWaitingForTool, state.Status)
+	assert.Equal(t, []string{"email", "sms"}, state.PendingToolCalls)
+
+	actions, _, err := engine.Render(ctx, state)
+	require.NoError(t, err)
+	require.Len(t, actions, 2)
+	email := actions[0].Payload.(domain.ToolCall)
+	sms := actions[1].Payload.(domain.ToolCall)
+	assert.Equal(t, "alice", email.Args["to"])
+	assert.NotEqual(t, email.IdempotencyKey, sms.IdempotencyKey)
+
+	// One at a time: partial results keep the node waiting.
+	partial, err := engine.Navigate(ctx, state, domain.ToolResult{ID: "sms", Result: "queued"})
+	require.NoError(t, err)
+	assert.Equal(t, "fanout", partial.CurrentNodeID)
+	assert.Equal(t, []string{"email"}, partial.PendingToolCalls)
+	assert.Equal(t, []string{"email", "sms"}, state.PendingToolCalls, "previous state must be untouched")
+
+	actions, _, err = engine.Render(ctx, partial)
+	require.NoError(t, err)
+	require.Len(t, actions, 1, "only pending calls are rendered")
+
+	// Duplicates are rejected.
+	_, err = engine.Navigate(ctx, partial, domain.ToolResult{ID: "sms"})
+	assert.ErrorContains(t, err, "duplicate")
+
+	next, err := engine.Navigate(ctx, partial, domain.ToolResult{ID: "email", Result: map[string]any{"status": "sent"}})
+	require.NoError(t, err)
+	assert.Equal(t, "done", next.CurrentNodeID)
+	assert.Empty(t, next.PendingToolCalls)
+	assert.Empty(t, next.BatchResults)
+
+	outcomes := next.Context["outcomes"].(map[string]any)
+	assert.Equal(t, "sent", outcomes["email"].(map[string]any)["status"])
+	assert.Equal(t, "queued", outcomes["sms"].(map[string]any)["result"])
+	assert.Equal(t, 2, domain.UsageFromState(next).Calls)
+}
+
+func TestEngine_Batch_AllAtOnce(t *testing.T) {
+	engine := batchLoader(t, "")
+	state := enterBatch(t, engine)
+
+	next, err := engine.Navigate(context.Background(), state, []domain.ToolResult{{ID: "email"}, {ID: "sms"}})
+	require.NoError(t, err)
+	assert.Equal(t, "done", next.CurrentNodeID)
+
+	_, err = engine.Navigate(context.Background(), state, []domain.ToolResult{{ID: "email"}, {ID: "push"}})
+	assert.ErrorContains(t, err, "does not match")
+}
+
+func TestEngine_Batch_Policies(t *testing.T) {
+	ctx := context.Background()
+	failing := []domain.ToolResult{{ID: "email"}, {ID: "sms", IsError: true, Error: "carrier down"}}
+
+	t.Run("all routes to on_error", func(t *testing.T) {
+		engine := batchLoader(t, domain.BatchPolicyAll)
+		next, err := engine.Navigate(ctx, enterBatch(t, engine), failing)
+		require.NoError(t, err)
+		assert.Equal(t, "failed", next.CurrentNodeID)
+	})
+
+	t.Run("all routes denials to on_denied", func(t *testing.T) {
+		engine := batchLoader(t, "")
+		next, err := engine.Navigate(ctx, enterBatch(t, engine), []domain.ToolResult{{ID: "email"}, {ID: "sms", IsDenied: true}})
+		require.NoError(t, err)
+		assert.Equal(t, "denied", next.CurrentNodeID)
+	})
+
+	t.Run("continue proceeds with outcomes", func(t *testing.T) {
+		engine := batchLoader(t, domain.BatchPolicyContinue)
+		next, err := engine.Navigate(ctx, enterBatch(t, engine), failing)
+		require.NoError(t, err)
+		assert.Equal(t, "done", next.CurrentNodeID)
+		sms := next.Context["tool_results"].(map[string]any)["sms"].(map[string]any)
+		assert.Equal(t, "carrier down", sms["_error"])
+	})
+
+	t.Run("rollback compensates succeeded calls only", func(t *testing.T) {
+		engine := batchLoader(t, domain.BatchPolicyRollback)
+		next, err := engine.Navigate(ctx, enterBatch(t, engine), failing)
+		require.NoError(t, err)
+		assert.Equal(t, domain.StatusRollingBack, next.Status)
+		assert.Equal(t, "fanout", next.CurrentNodeID)
+		assert.Equal(t, []string{"email_undo"}, next.PendingToolCalls)
+
+		actions, _, err := engine.Render(ctx, next)
+		require.NoError(t, err)
+		require.Len(t, actions, 1)
+		assert.Equal(t, "unsend", actions[0].Payload.(domain.ToolCall).Name)
+
+		final, err := engine.Navigate(ctx, next, domain.ToolResult{ID: "email_undo"})
+		require.NoError(t, err)
+		assert.Equal(t, domain.StatusTerminated, final.Status)
+	})
+}
+
+func TestEngine_Batch_HistoryRollbackRunsAllUndos(t *testing.T) {
+	loader := memory.NewLoader(map[string]string{
+		"start":  `{"id": "start", "do": [{"name": "a", "undo": {"name": "undo_a"}}, {"name": "b", "undo": {"name": "undo_b"}}], "transitions": [{"to_node_id": "charge"}]}`,
+		"charge": `{"id": "charge", "do": {"id": "charge", "name": "charge"}, "on_error": "rollback"}`,
+	})
+	engine := runtime.NewEngine(loader, nil, nil)
+	ctx := context.Background()
+
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+	state, err = engine.Navigate(ctx, state, []domain.ToolResult{{ID: "a"}, {ID: "b"}})
+	require.NoError(t, err)
+	require.Equal(t, "charge", state.CurrentNodeID)
+
+	state, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "charge", IsError: true})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusRollingBack, state.Status)
+	assert.Equal(t, "start", state.CurrentNodeID)
+	assert.ElementsMatch(t, []string{"a_undo", "b_undo"}, state.PendingToolCalls)
+
+	state, err = engine.Navigate(ctx, state, []domain.ToolResult{{ID: "a_undo"}, {ID: "b_undo"}})
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusTerminated, state.Status)
+}
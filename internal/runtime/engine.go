@@ -16,6 +16,7 @@ import (
 
 	"github.com/aretw0/trellis/internal/compiler"
 	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/intent"
 	"github.com/aretw0/trellis/pkg/ports"
 )
 
@@ -32,6 +33,12 @@ type Engine struct {
 	hooks              domain.LifecycleHooks
 	entryNodeID        string
 	defaultErrorNodeID string
+	modelProvider      ports.ModelProvider
+	intentClassifier   ports.IntentClassifier
+	defaultLocale      string
+	revision           string
+	bundleHash         string
+	budget             domain.Budget
 	logger             *slog.Logger
 }
 
@@ -74,6 +81,57 @@ func WithContentConverter(converter ports.ContentConverter) EngineOption {
 	}
 }
 
+// WithModelProvider configures the provider used to execute llm nodes.
+func WithModelProvider(provider ports.ModelProvider) EngineOption {
+	return func(e *Engine) {
+		e.modelProvider = provider
+	}
+}
+
+// WithIntentClassifier replaces the classifier used by route nodes (default: intent.Default()).
+func WithIntentClassifier(classifier ports.IntentClassifier) EngineOption {
+	return func(e *Engine) {
+		e.intentClassifier = classifier
+	}
+}
+
+// WithDefaultLocale sets the locale of new sessions (e.g. "pt-BR").
+func WithDefaultLocale(locale string) EngineOption {
+	return func(e *Engine) {
+		e.defaultLocale = locale
+	}
+}
+
+// WithRevision records the graph revision (e.g. a git commit) in new sessions,
+// so hosts can keep resuming them against the same version of the flow.
+func WithRevision(revision string) EngineOption {
+	return func(e *Engine) {
+		e.revision = revision
+	}
+}
+
+// WithBundleHash records the hash of the bundle the graph was loaded from in new sessions.
+func WithBundleHash(hash string) EngineOption {
+	return func(e *Engine) {
+		e.bundleHash = hash
+	}
+}
+
+// WithStrict makes the engine reject node documents with unknown keys.
+func WithStrict(strict bool) EngineOption {
+	return func(e *Engine) {
+		e.parser = compiler.NewParser(compiler.WithStrict(strict))
+	}
+}
+
+// WithBudget sets a spending limit applied to every session, merged with the flow-level
+// budget of the entry node (the tightest limit wins).
+func WithBudget(budget domain.Budget) EngineOption {
+	return func(e *Engine) {
+		e.budget = budget
+	}
+}
+
 // DefaultEvaluator implements the basic "condition: input == 'value'" logic.
 func DefaultEvaluator(ctx context.Context, condition string, input any) (bool, error) {
 	// For backward compatibility and simplicity in string matching,
@@ -234,12 +292,13 @@ func NewEngine(loader ports.GraphLoader, evaluator ConditionEvaluator, interpola
 		interpolator = DefaultInterpolator
 	}
 	e := &Engine{
-		loader:       loader,
-		parser:       compiler.NewParser(),
-		evaluator:    evaluator,
-		interpolator: interpolator,
-		entryNodeID:  "start",                                        // Default convention
-		logger:       slog.New(slog.NewJSONHandler(io.Discard, nil)), // Default No-Op
+		loader:           compiler.NewMacroLoader(loader), // Lowering phase: macro nodes become atomic nodes
+		parser:           compiler.NewParser(),
+		evaluator:        evaluator,
+		interpolator:     interpolator,
+		entryNodeID:      "start", // Default convention
+		intentClassifier: intent.Default(),
+		logger:           slog.New(slog.NewJSONHandler(io.Discard, nil)), // Default No-Op
 	}
 	for _, opt := range opts {
 		opt(e)
@@ -247,6 +306,11 @@ func NewEngine(loader ports.GraphLoader, evaluator ConditionEvaluator, interpola
 	return e
 }
 
+// EntryNode returns the ID of the node new sessions start at.
+func (e *Engine) EntryNode() string {
+	return e.entryNodeID
+}
+
 func (e *Engine) generateIdempotencyKey(state *domain.State, nodeID string, toolName string) string {
 	// Key = SessionID + NodeID + HistoryLength (Step Index) + ToolName
 	stepIndex := len(state.History)
@@ -258,6 +322,7 @@ func (e *Engine) generateIdempotencyKey(state *domain.State, nodeID string, tool
 // Start creates the initial state and triggers the OnNodeEnter hook.
 func (e *Engine) Start(ctx context.Context, sessionID string, initialContext map[string]any) (*domain.State, error) {
 	state := domain.NewState(sessionID, e.entryNodeID)
+	state.Locale = e.defaultLocale
 	// Load start node to get defaults and metadata
 	var startNode *domain.Node
 	raw, err := e.loader.GetNode(e.entryNodeID)
@@ -276,10 +341,21 @@ func (e *Engine) Start(ctx context.Context, sessionID string, initialContext map
 		state.Context[k] = v
 	}
 
+	if e.revision != "" {
+		state.SystemContext[domain.SysKeyRevision] = e.revision
+	}
+	if e.bundleHash != "" {
+		state.SystemContext[domain.SysKeyBundle] = e.bundleHash
+	}
+
+	// Flow-level budget (declared on the entry node) travels with the session.
+	if startNode != nil && startNode.Budget != nil && !startNode.Budget.IsZero() {
+		state.SystemContext[domain.SysKeyBudget] = startNode.Budget.ToMap()
+	}
+
 	// Determine initial status based on Entry Node
-	if startNode != nil && startNode.Do != nil {
-		state.Status = domain.StatusWaitingForTool
-		state.PendingToolCall = startNode.Do.ID
+	if startNode != nil {
+		awaitTools(state, startNode)
 	}
 
 	// Trigger OnNodeEnter for the start node
@@ -294,6 +370,11 @@ func (e *Engine) Start(ctx context.Context, sessionID string, initialContext map
 // It loads the node and generates actions (e.g. print text) but does NOT change state.
 // It returns actions, isTerminal (true if no transitions), and error.
 func (e *Engine) Render(ctx context.Context, currentState *domain.State) ([]domain.ActionRequest, bool, error) {
+	actions, isTerminal, err := e.render(ctx, currentState)
+	return actions, isTerminal, e.withSource(currentState, err)
+}
+
+func (e *Engine) render(ctx context.Context, currentState *domain.State) ([]domain.ActionRequest, bool, error) {
 	if currentState == nil {
 		return nil, false, fmt.Errorf("cannot render nil state")
 	}
@@ -321,7 +402,9 @@ func (e *Engine) Render(ctx context.Context, currentState *domain.State) ([]doma
 	actions := []domain.ActionRequest{}
 
 	// 1. Render Content (Text/Markdown)
-	if node.Type == domain.NodeTypeText || node.Type == domain.NodeTypeQuestion || len(node.Content) > 0 {
+	// LLM nodes without an explicit prompt use their body as the prompt: it is never displayed.
+	promptOnly := node.Type == domain.NodeTypeLLM && node.Prompt == ""
+	if !promptOnly && (node.Type == domain.NodeTypeText || node.Type == domain.NodeTypeQuestion || len(node.Content) > 0) {
 		text, err := e.renderContent(ctx, node, currentState)
 		if err != nil {
 			return nil, false, err
@@ -351,6 +434,16 @@ func (e *Engine) Render(ctx context.Context, currentState *domain.State) ([]doma
 		e.emitToolCall(ctx, currentState.CurrentNodeID, toolCall.Payload.(domain.ToolCall))
 	}
 
+	// 3b. Render Batch Calls (pending subset only)
+	batchCalls, err := e.renderBatchCalls(ctx, node, currentState)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, action := range batchCalls {
+		actions = append(actions, action)
+		e.emitToolCall(ctx, currentState.CurrentNodeID, action.Payload.(domain.ToolCall))
+	}
+
 	// 4. Terminal Logic
 	hasStandardTransitions := len(node.Transitions) > 0
 	hasSignalTransitions := len(node.OnSignal) > 0
@@ -361,13 +454,38 @@ func (e *Engine) Render(ctx context.Context, currentState *domain.State) ([]doma
 }
 
 func (e *Engine) Navigate(ctx context.Context, currentState *domain.State, input any) (*domain.State, error) {
+	nextState, err := e.navigate(ctx, currentState, input)
+	return nextState, e.withSource(currentState, err)
+}
+
+func (e *Engine) navigate(ctx context.Context, currentState *domain.State, input any) (*domain.State, error) {
 	if currentState == nil {
 		return nil, fmt.Errorf("cannot navigate nil state")
 	}
 
 	// 1. Handle State: WaitingForTool (or RollingBack which works similarly for Undo)
 	if currentState.Status == domain.StatusWaitingForTool || currentState.Status == domain.StatusRollingBack {
+		// Async: acknowledgements park the call until its result is delivered later.
+		if results, ok := toToolResults(input); ok {
+			tracked, remaining, err := e.trackAsync(currentState, results)
+			if err != nil {
+				return nil, err
+			}
+			if len(remaining) == 0 {
+				return tracked, nil
+			}
+			currentState, input = tracked, remaining
+		}
+
+		// Batch: a set of pending calls (results may arrive together or one at a time)
+		if len(currentState.PendingToolCalls) > 0 {
+			return e.navigateBatch(ctx, currentState, input)
+		}
+
 		result, ok := input.(domain.ToolResult)
+		if results, isList := input.([]domain.ToolResult); isList && len(results) == 1 {
+			result, ok = results[0], true
+		}
 		if !ok {
 			return nil, fmt.Errorf("expected ToolResult input when in WaitingForTool/RollingBack status")
 		}
@@ -377,7 +495,13 @@ func (e *Engine) Navigate(ctx context.Context, currentState *domain.State, input
 
 		// Handle State: RollingBack (Undo Tool Completed)
 		if currentState.Status == domain.StatusRollingBack {
-			return e.continueRollback(ctx, currentState, true)
+			node, err := e.loadNode(currentState.CurrentNodeID)
+			if err != nil {
+				return nil, err
+			}
+			accounted := e.cloneState(currentState)
+			e.recordUsage(accounted, undoNameFor(node, result.ID), result)
+			return e.continueRollback(ctx, accounted, true)
 		}
 
 		// Handle Tool Result (Success/Error/Denied)
@@ -412,8 +536,10 @@ func (e *Engine) Signal(ctx context.Context, currentState *domain.State, signalN
 		return nil, fmt.Errorf("failed to parse node %s: %w", currentState.CurrentNodeID, err)
 	}
 
+	next := edge{kind: domain.TransitionSignal, index: -1, signal: signalName}
 	targetNodeID, ok := node.OnSignal[signalName]
 	if !ok {
+		next.kind = domain.TransitionSignalDefault
 		// FALLBACK: Check OnSignalDefault on the entry node (usually "start")
 		// This provides a centralized way to handle signals like "quit" or "cancel"
 		entryRaw, err := e.loader.GetNode(e.entryNodeID)
@@ -436,6 +562,8 @@ func (e *Engine) Signal(ctx context.Context, currentState *domain.State, signalN
 	nextState.Status = domain.StatusActive
 	nextState.PendingToolCall = ""
 
+	next.to = targetNodeID
+	e.emitTransition(ctx, node.ID, next)
 	return e.transitionTo(nextState, targetNodeID)
 }
 
@@ -466,6 +594,16 @@ func (e *Engine) navigateInternal(ctx context.Context, currentState *domain.Stat
 		return nil, err
 	}
 
+	// LLM nodes delegate the decision to the model provider.
+	if node.Type == domain.NodeTypeLLM {
+		return e.navigateLLM(ctx, currentState, node, effectiveInput)
+	}
+
+	// Route nodes classify free text onto their transitions.
+	if node.Type == domain.NodeTypeRoute {
+		return e.navigateRoute(ctx, currentState, node, effectiveInput)
+	}
+
 	// 1. Update Context (SaveTo)
 	nextState, err := e.applyInput(currentState, node, effectiveInput)
 	if err == nil && node.SaveTo != "" {
@@ -476,13 +614,19 @@ func (e *Engine) navigateInternal(ctx context.Context, currentState *domain.Stat
 	}
 
 	// 2. Resolve Next Node (Priority Logic: Conditional > Denial > Fallback)
-	nextNodeID, err := e.resolveNextNodeID(ctx, node, effectiveInput)
+	next, err := e.resolveNextNodeID(ctx, node, effectiveInput)
 	if err != nil {
 		return nil, err
 	}
 
 	// 3. Process Resulting State
-	if strings.EqualFold(nextNodeID, "rollback") {
+	return e.advance(ctx, nextState, node, next)
+}
+
+// advance leaves the node along next (rollback, termination or transition).
+func (e *Engine) advance(ctx context.Context, nextState *domain.State, node *domain.Node, next edge) (*domain.State, error) {
+	nextNodeID := next.to
+	if domain.IsRollback(nextNodeID) {
 		e.emitNodeLeave(ctx, node)
 		return e.startRollback(ctx, nextState)
 	}
@@ -495,12 +639,52 @@ func (e *Engine) navigateInternal(ctx context.Context, currentState *domain.Stat
 
 	if nextNodeID != "" {
 		e.emitNodeLeave(ctx, node)
+		e.emitTransition(ctx, node.ID, next)
 		return e.transitionTo(nextState, nextNodeID)
 	}
 
 	return nextState, nil
 }
 
+// withSource points errors back to where the current node was authored:
+// its file position, or the macro line that generated it.
+func (e *Engine) withSource(state *domain.State, err error) error {
+	if err == nil || state == nil {
+		return err
+	}
+	src, srcErr := e.Source(state.CurrentNodeID)
+	if srcErr != nil || src == nil {
+		return err
+	}
+	return fmt.Errorf("%w [%s]", err, src)
+}
+
+// Source returns where a node was authored: its file position or, for a node generated
+// by a macro, the script line that produced it (nil when the loader does not know).
+func (e *Engine) Source(nodeID string) (*domain.SourceRef, error) {
+	node, err := e.loadNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return node.Source, nil
+}
+
+// Trace maps the visited nodes of state (State.History) to their sources. Nodes that
+// are no longer in the graph keep a nil Source.
+func (e *Engine) Trace(state *domain.State) []domain.TraceStep {
+	steps := make([]domain.TraceStep, 0, len(state.History))
+	sources := make(map[string]*domain.SourceRef)
+	for _, id := range state.History {
+		src, seen := sources[id]
+		if !seen {
+			src, _ = e.Source(id)
+			sources[id] = src
+		}
+		steps = append(steps, domain.TraceStep{NodeID: id, Source: src})
+	}
+	return steps
+}
+
 // transitionTo handles the mechanics of moving the state to a new node ID.
 func (e *Engine) transitionTo(nextState *domain.State, nextNodeID string) (*domain.State, error) {
 	// 1. Transition State
@@ -530,10 +714,7 @@ func (e *Engine) transitionTo(nextState *domain.State, nextNodeID string) (*doma
 	}
 
 	// 4. Set Status based on node behavior
-	if nextNode.Do != nil {
-		nextState.Status = domain.StatusWaitingForTool
-		nextState.PendingToolCall = nextNode.Do.ID
-	}
+	awaitTools(nextState, nextNode)
 
 	// 5. Emit Enter Event
 	e.emitNodeEnter(context.Background(), nextNode, nextNodeID)
@@ -606,6 +787,22 @@ func (e *Engine) emitNodeEnter(ctx context.Context, node *domain.Node, nodeID st
 	}
 }
 
+func (e *Engine) emitTransition(ctx context.Context, from string, next edge) {
+	if e.hooks.OnTransition != nil {
+		e.hooks.OnTransition(ctx, &domain.TransitionEvent{
+			EventBase: domain.EventBase{
+				Timestamp: time.Now(),
+				Type:      domain.EventTransition,
+			},
+			From:   from,
+			To:     next.to,
+			Kind:   next.kind,
+			Index:  next.index,
+			Signal: next.signal,
+		})
+	}
+}
+
 func (e *Engine) emitToolCall(ctx context.Context, nodeID string, call domain.ToolCall) {
 	if e.hooks.OnToolCall != nil {
 		e.hooks.OnToolCall(ctx, &domain.ToolEvent{
@@ -620,7 +817,7 @@ func (e *Engine) emitToolCall(ctx context.Context, nodeID string, call domain.To
 	}
 }
 
-func (e *Engine) emitToolReturn(ctx context.Context, nodeID string, toolName string, output any, isError bool) {
+func (e *Engine) emitToolReturn(ctx context.Context, nodeID string, toolName string, output any, isError bool, usage *domain.ToolUsage) {
 	if e.hooks.OnToolReturn != nil {
 		e.hooks.OnToolReturn(ctx, &domain.ToolEvent{
 			EventBase: domain.EventBase{
@@ -631,6 +828,7 @@ func (e *Engine) emitToolReturn(ctx context.Context, nodeID string, toolName str
 			ToolName: toolName,
 			Output:   output,
 			IsError:  isError,
+			Usage:    usage,
 		})
 	}
 }
@@ -0,0 +1,112 @@
+package runtime_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/aretw0/trellis/internal/runtime"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func flowEngine(t *testing.T, script string) *runtime.Engine {
+	t.Helper()
+	content := base64.StdEncoding.EncodeToString([]byte(script))
+	loader := memory.NewLoader(map[string]string{
+		"start": `{"id": "start", "type": "flow", "content": "` + content + `", "transitions": [{"to_node_id": "done"}]}`,
+		"done":  `{"id": "done", "type": "text"}`,
+		"vip":   `{"id": "vip", "type": "text"}`,
+	})
+	return runtime.NewEngine(loader, nil, nil)
+}
+
+func TestEngine_Flow_RunsAsAtomicNodes(t *testing.T) {
+	engine := flowEngine(t, "say Hello\nask plan: Which plan?\nbranch input == 'vip' -> vip\ndo register {\"plan\": \"{{ .plan }}\"}")
+	ctx := context.Background()
+
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "start", state.CurrentNodeID)
+
+	state, err = engine.Navigate(ctx, state, "")
+	require.NoError(t, err)
+	assert.Equal(t, "start#ask-plan", state.CurrentNodeID)
+
+	vip, err := engine.Navigate(ctx, state, "vip")
+	require.NoError(t, err)
+	assert.Equal(t, "vip", vip.CurrentNodeID)
+
+	state, err = engine.Navigate(ctx, state, "basic")
+	require.NoError(t, err)
+	assert.Equal(t, "start#do-register", state.CurrentNodeID)
+	assert.Equal(t, domain.StatusWaitingForTool, state.Status)
+
+	actions, _, err := engine.Render(ctx, state)
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, "basic", actions[0].Payload.(domain.ToolCall).Args["plan"])
+
+	state, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "register", Result: "ok"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", state.CurrentNodeID)
+	assert.Equal(t, []string{"start", "start#ask-plan", "start#do-register", "done"}, state.History)
+}
+
+func TestEngine_Flow_ErrorsPointToMacroLine(t *testing.T) {
+	engine := flowEngine(t, "# setup\ndo charge")
+	ctx := context.Background()
+
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+
+	_, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "charge", IsError: true, Error: "card declined"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "flow start line 2 (do charge)")
+
+	var unhandled *runtime.UnhandledToolError
+	assert.True(t, errors.As(err, &unhandled), "the original error is preserved")
+}
+
+func TestEngine_Flow_Inspect(t *testing.T) {
+	engine := flowEngine(t, "say Hi\nsay Bye")
+
+	nodes, err := engine.Inspect()
+	require.NoError(t, err)
+
+	sources := map[string]*domain.SourceRef{}
+	for _, n := range nodes {
+		sources[n.ID] = n.Source
+	}
+	require.Contains(t, sources, "start#say-bye")
+	assert.Equal(t, 2, sources["start#say-bye"].Line)
+	assert.Nil(t, sources["done"])
+}
+
+func TestEngine_Flow_Trace(t *testing.T) {
+	engine := flowEngine(t, "say Hi\n\nask plan: Which plan?")
+	ctx := context.Background()
+
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+	state, err = engine.Navigate(ctx, state, "")
+	require.NoError(t, err)
+	state, err = engine.Navigate(ctx, state, "pro")
+	require.NoError(t, err)
+	state.History = append(state.History, "removed")
+
+	src, err := engine.Source("start#ask-plan")
+	require.NoError(t, err)
+	assert.Equal(t, &domain.SourceRef{Macro: "start", Line: 3, Step: "ask plan: Which plan?"}, src)
+
+	trace := engine.Trace(state)
+	require.Len(t, trace, 4)
+	assert.Equal(t, "start#ask-plan", trace[1].NodeID)
+	assert.Equal(t, src, trace[1].Source)
+	assert.Equal(t, 1, trace[0].Source.Line)
+	assert.Nil(t, trace[2].Source, "done is not generated by a macro")
+	assert.Nil(t, trace[3].Source, "nodes missing from the graph have no source")
+}
@@ -90,3 +90,74 @@ func TestEngine_LifecycleHooks(t *testing.T) {
 		t.Errorf("Expected enter [start, step_2], got: %v", entered)
 	}
 }
+
+func TestEngine_TransitionHook(t *testing.T) {
+	loader, _ := memory.NewFromNodes(
+		domain.Node{
+			ID: "start", Type: domain.NodeTypeQuestion, OnDenied: "bye",
+			OnSignalDefault: map[string]string{"interrupt": "bye"},
+			Transitions: []domain.Transition{
+				{ToNodeID: "pay", Condition: "input == 'pay'"},
+				{ToNodeID: "start"},
+			},
+		},
+		domain.Node{
+			ID: "pay", Type: domain.NodeTypeTool, OnError: "failed",
+			OnSignal: map[string]string{"timeout": "bye"},
+			Do:       &domain.ToolCall{ID: "charge", Name: "charge"},
+		},
+		domain.Node{ID: "failed", Type: domain.NodeTypeText},
+		domain.Node{ID: "bye", Type: domain.NodeTypeText},
+	)
+
+	var events []domain.TransitionEvent
+	hooks := domain.LifecycleHooks{
+		OnTransition: func(ctx context.Context, e *domain.TransitionEvent) {
+			events = append(events, *e)
+		},
+	}
+	engine := runtime.NewEngine(loader, nil, nil, runtime.WithDefaultErrorNode("bye"), runtime.WithLifecycleHooks(hooks))
+	ctx := context.Background()
+
+	state, err := engine.Start(ctx, "s", nil)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	paying, err := engine.Navigate(ctx, state, "pay")
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	if _, err := engine.Navigate(ctx, paying, domain.ToolResult{ID: "charge", IsError: true, Error: "declined"}); err != nil {
+		t.Fatalf("Navigate with tool error failed: %v", err)
+	}
+	if _, err := engine.Signal(ctx, paying, "timeout"); err != nil {
+		t.Fatalf("Signal failed: %v", err)
+	}
+	if _, err := engine.Signal(ctx, paying, "interrupt"); err != nil {
+		t.Fatalf("Signal failed: %v", err)
+	}
+	if _, err := engine.Navigate(ctx, state, "no"); err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	if _, err := engine.Navigate(ctx, state, "again"); err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+
+	want := []string{"start#0", "pay@on_error", "pay!timeout", "*!interrupt", "start@on_denied", "start#1"}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Key())
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected edges %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected edges %v, got %v", want, got)
+			break
+		}
+	}
+	if e := events[1]; e.From != "pay" || e.To != "failed" || e.Index != -1 || e.Type != domain.EventTransition {
+		t.Errorf("Unexpected on_error event: %+v", e)
+	}
+}
@@ -0,0 +1,156 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/schema"
+)
+
+// llmToolName is the name under which model completions are metered and observed.
+const llmToolName = "llm"
+
+// navigateLLM executes an llm node: it renders the prompts, asks the model provider,
+// stores the (optionally structured) answer and follows the route, which is always
+// constrained to the node's declared transitions.
+func (e *Engine) navigateLLM(ctx context.Context, currentState *domain.State, node *domain.Node, input any) (*domain.State, error) {
+	if e.modelProvider == nil {
+		return nil, fmt.Errorf("node %s is type 'llm' but no model provider is configured (use WithModelProvider)", node.ID)
+	}
+
+	state := e.cloneState(currentState)
+	fail := func(cause string) (*domain.State, error) {
+		return e.routeToolError(ctx, state, node, llmToolName, cause, func(s *domain.State) (*domain.State, error) {
+			return e.startRollback(ctx, s)
+		})
+	}
+
+	// Completions are metered like tools, so the flow budget applies to them too.
+	if budget := e.budgetFor(state); !budget.IsZero() {
+		if exhausted, reason := budget.Exhausted(domain.UsageFromState(state).UsageTotals); exhausted {
+			e.logger.Debug("llm call denied", "node", node.ID, "reason", reason)
+			return e.routeDenial(ctx, state, node)
+		}
+	}
+
+	req, err := e.buildModelRequest(ctx, state, node, input)
+	if err != nil {
+		return nil, err
+	}
+
+	e.emitToolCall(ctx, node.ID, domain.ToolCall{ID: node.ID, Name: llmToolName, Metadata: map[string]string{"model": req.Model}})
+	resp, err := e.modelProvider.Complete(ctx, req)
+	if err != nil {
+		e.emitToolReturn(ctx, node.ID, llmToolName, err.Error(), true, nil)
+		return fail(fmt.Sprintf("model provider error: %v", err))
+	}
+	e.recordUsage(state, llmToolName, domain.ToolResult{ID: node.ID, Usage: resp.Usage})
+	e.emitToolReturn(ctx, node.ID, llmToolName, resp.Content, false, resp.Usage)
+
+	state.SystemContext[domain.SysKeyLLM] = map[string]any{
+		"content":    resp.Content,
+		"route":      resp.Route,
+		"tool_calls": resp.ToolCalls,
+	}
+
+	// Structured output: decode and validate before anything reaches the context.
+	var output any = resp.Content
+	if len(node.OutputSchema) > 0 {
+		obj := resp.Output
+		if obj == nil {
+			if obj, err = decodeModelObject(resp.Content); err != nil {
+				return fail(fmt.Sprintf("model output is not a JSON object: %v", err))
+			}
+		}
+		if err := schema.Validate(node.OutputSchema, obj); err != nil {
+			return fail(fmt.Sprintf("model output does not match output_schema: %v", err))
+		}
+		output = obj
+	}
+
+	nextState, err := e.applyInput(state, node, output)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Route != "" {
+		if indexOf(req.Routes, resp.Route) < 0 {
+			return fail(fmt.Sprintf("model chose undeclared route '%s' (allowed: %v)", resp.Route, req.Routes))
+		}
+		return e.advance(ctx, nextState, node, transitionEdge(node, resp.Route))
+	}
+
+	next, err := e.resolveNextNodeID(ctx, node, output)
+	if err != nil {
+		return nil, err
+	}
+	return e.advance(ctx, nextState, node, next)
+}
+
+// buildModelRequest interpolates the prompts and collects tools, schema and routes.
+func (e *Engine) buildModelRequest(ctx context.Context, state *domain.State, node *domain.Node, input any) (domain.ModelRequest, error) {
+	data := make(map[string]any, len(state.Context)+2)
+	for k, v := range state.Context {
+		data[k] = v
+	}
+	data["sys"] = state.SystemContext
+	data["input"] = input
+
+	render := func(field, tmpl string) (string, error) {
+		if e.interpolator == nil || tmpl == "" {
+			return tmpl, nil
+		}
+		out, err := e.interpolator(ctx, tmpl, data)
+		if err != nil {
+			return "", fmt.Errorf("node %s: failed to render %s: %w", node.ID, field, err)
+		}
+		return out, nil
+	}
+
+	prompt := node.Prompt
+	if prompt == "" {
+		prompt = string(node.Content)
+	}
+	prompt, err := render("prompt", prompt)
+	if err != nil {
+		return domain.ModelRequest{}, err
+	}
+	system, err := render("system", node.System)
+	if err != nil {
+		return domain.ModelRequest{}, err
+	}
+
+	var routes []string
+	for _, t := range node.Transitions {
+		if t.ToNodeID != "" && indexOf(routes, t.ToNodeID) < 0 {
+			routes = append(routes, t.ToNodeID)
+		}
+	}
+
+	return domain.ModelRequest{
+		Model:  node.Model,
+		System: system,
+		Prompt: strings.TrimSpace(prompt),
+		Tools:  node.Tools,
+		Schema: node.OutputSchema,
+		Routes: routes,
+	}, nil
+}
+
+// decodeModelObject parses a JSON object from model text, tolerating Markdown code fences.
+func decodeModelObject(content string) (map[string]any, error) {
+	text := strings.TrimSpace(content)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(text), &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
@@ -0,0 +1,126 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aretw0/trellis/internal/runtime"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func llmEngine(t *testing.T, provider *memory.ModelProvider) *runtime.Engine {
+	t.Helper()
+	loader := memory.NewLoader(map[string]string{
+		"start": `{
+			"id": "start",
+			"type": "llm",
+			"model": "tiny",
+			"system": "You triage tickets for {{ .team }}.",
+			"content": "VGlja2V0OiB7eyAudGlja2V0IH19",
+			"output_schema": {"category": "string", "confidence": "float"},
+			"tools": [{"name": "lookup_customer", "description": "Find a customer"}],
+			"save_to": "triage",
+			"on_error": "human",
+			"transitions": [
+				{"to_node_id": "billing", "condition": "input == 'never'"},
+				{"to_node_id": "support"}
+			]
+		}`,
+		"billing": `{"id": "billing", "type": "text", "content": "QmlsbGluZw=="}`,
+		"support": `{"id": "support", "type": "text"}`,
+		"human":   `{"id": "human", "type": "text"}`,
+	})
+	return runtime.NewEngine(loader, nil, nil, runtime.WithModelProvider(provider))
+}
+
+func startLLM(t *testing.T, engine *runtime.Engine) *domain.State {
+	t.Helper()
+	state, err := engine.Start(context.Background(), "s1", map[string]any{"team": "ACME", "ticket": "refund please"})
+	require.NoError(t, err)
+	return state
+}
+
+func TestEngine_LLM_StructuredOutputAndRoute(t *testing.T) {
+	provider := memory.NewModelProvider(domain.ModelResponse{
+		Content: `{"category": "billing", "confidence": 0.93}`,
+		Route:   "billing",
+		Usage:   &domain.ToolUsage{Tokens: 120},
+	})
+	engine := llmEngine(t, provider)
+	ctx := context.Background()
+	state := startLLM(t, engine)
+
+	// The body is the prompt: it is never displayed.
+	actions, _, err := engine.Render(ctx, state)
+	require.NoError(t, err)
+	for _, a := range actions {
+		assert.NotEqual(t, domain.ActionRenderContent, a.Type)
+	}
+
+	next, err := engine.Navigate(ctx, state, "")
+	require.NoError(t, err)
+	assert.Equal(t, "billing", next.CurrentNodeID)
+	assert.Equal(t, map[string]any{"category": "billing", "confidence": 0.93}, next.Context["triage"])
+	assert.Equal(t, int64(120), domain.UsageFromState(next).Tokens)
+
+	reqs := provider.Requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "tiny", reqs[0].Model)
+	assert.Equal(t, "You triage tickets for ACME.", reqs[0].System)
+	assert.Equal(t, "Ticket: refund please", reqs[0].Prompt)
+	assert.Equal(t, []string{"billing", "support"}, reqs[0].Routes)
+	require.Len(t, reqs[0].Tools, 1)
+	assert.Contains(t, reqs[0].Schema, "category")
+}
+
+func TestEngine_LLM_FallsBackToTransitions(t *testing.T) {
+	provider := memory.NewModelProvider(domain.ModelResponse{Content: "```json\n{\"category\": \"other\", \"confidence\": 0.5}\n```"})
+	engine := llmEngine(t, provider)
+
+	next, err := engine.Navigate(context.Background(), startLLM(t, engine), "")
+	require.NoError(t, err)
+	assert.Equal(t, "support", next.CurrentNodeID)
+}
+
+func TestEngine_LLM_Failures(t *testing.T) {
+	tests := []struct {
+		name string
+		resp domain.ModelResponse
+		err  error
+	}{
+		{name: "undeclared route", resp: domain.ModelResponse{Content: `{"category": "x", "confidence": 1}`, Route: "admin"}},
+		{name: "schema mismatch", resp: domain.ModelResponse{Content: `{"category": 42}`}},
+		{name: "not json", resp: domain.ModelResponse{Content: "I think billing"}},
+		{name: "provider error", err: errors.New("rate limited")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &memory.ModelProvider{Func: func(domain.ModelRequest) (domain.ModelResponse, error) {
+				return tt.resp, tt.err
+			}}
+			engine := llmEngine(t, provider)
+
+			next, err := engine.Navigate(context.Background(), startLLM(t, engine), "")
+			require.NoError(t, err)
+			assert.Equal(t, "human", next.CurrentNodeID, "failures follow on_error")
+			assert.Nil(t, next.Context["triage"])
+		})
+	}
+}
+
+func TestEngine_LLM_RequiresProvider(t *testing.T) {
+	loader := memory.NewLoader(map[string]string{
+		"start": `{"id": "start", "type": "llm", "prompt": "hi", "transitions": [{"to_node_id": "end"}]}`,
+		"end":   `{"id": "end", "type": "text"}`,
+	})
+	engine := runtime.NewEngine(loader, nil, nil)
+	state, err := engine.Start(context.Background(), "s1", nil)
+	require.NoError(t, err)
+
+	_, err = engine.Navigate(context.Background(), state, "")
+	assert.ErrorContains(t, err, "no model provider")
+}
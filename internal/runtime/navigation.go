@@ -13,52 +13,20 @@ func (e *Engine) handleToolResult(ctx context.Context, currentState *domain.Stat
 	// 1. Policy Denial Handling
 	if result.IsDenied {
 		e.logger.Debug("tool execution denied", "tool", result.ID, "node", currentState.CurrentNodeID)
+		return e.routeDenial(ctx, currentState, node)
+	}
 
-		target := node.OnDenied
-		if target == "" {
-			target = node.OnError
-		}
-		if target == "" {
-			target = e.defaultErrorNodeID
-		}
-
-		if target != "" {
-			nextState := e.cloneState(currentState)
-			nextState.Status = domain.StatusActive
-			nextState.PendingToolCall = ""
-			return e.transitionTo(nextState, target)
-		}
-
-		// Unhandled Denial: Graceful termination
-		nextState := e.cloneState(currentState)
-		nextState.Status = domain.StatusTerminated
-		return nextState, nil
+	// Executed calls (success or failure) are metered; denied ones never ran.
+	// A call that ran after the budget was exhausted is metered, then denied.
+	toolName := toolNameFor(node, result)
+	currentState = e.cloneState(currentState)
+	if _, over := e.meterResult(currentState, toolName, result); over {
+		return e.routeDenial(ctx, currentState, node)
 	}
 
 	// 2. Runtime Error Handling
 	if result.IsError {
-		e.emitToolReturn(ctx, currentState.CurrentNodeID, result.ID, result.Result, true)
-
-		if node.OnError != "" {
-			if node.OnError == "rollback" {
-				e.emitNodeLeave(ctx, node)
-				return e.startRollback(ctx, currentState)
-			}
-
-			nextState := e.cloneState(currentState)
-			nextState.Status = domain.StatusActive
-			nextState.PendingToolCall = ""
-			return e.transitionTo(nextState, node.OnError)
-		}
-
-		// Global Fallback
-		if e.defaultErrorNodeID != "" {
-			e.emitNodeLeave(ctx, node)
-			nextState := e.cloneState(currentState)
-			nextState.Status = domain.StatusActive
-			nextState.PendingToolCall = ""
-			return e.transitionTo(nextState, e.defaultErrorNodeID)
-		}
+		e.emitToolReturn(ctx, currentState.CurrentNodeID, toolName, result.Result, true, result.Usage)
 
 		// Prepare Error Cause for reporting
 		cause := result.Error
@@ -66,15 +34,13 @@ func (e *Engine) handleToolResult(ctx context.Context, currentState *domain.Stat
 			cause = fmt.Sprintf("%v", result.Result)
 		}
 
-		return nil, &UnhandledToolError{
-			NodeID:   node.ID,
-			ToolName: result.ID,
-			Cause:    cause,
-		}
+		return e.routeToolError(ctx, currentState, node, result.ID, cause, func(s *domain.State) (*domain.State, error) {
+			return e.startRollback(ctx, s)
+		})
 	}
 
 	// 3. Success: Resume execution
-	e.emitToolReturn(ctx, currentState.CurrentNodeID, result.ID, result.Result, false)
+	e.emitToolReturn(ctx, currentState.CurrentNodeID, toolName, result.Result, false, result.Usage)
 
 	resumedState := e.cloneState(currentState)
 	resumedState.Status = domain.StatusActive
@@ -83,6 +49,84 @@ func (e *Engine) handleToolResult(ctx context.Context, currentState *domain.Stat
 	// Flatten: Expose the last tool result as an accessible map in user context.
 	// This allows {{ .tool_result.field }}, {{ .tool_result._id }}, etc. in templates.
 	// Policy: "last-result" — only the most recent successful tool result is kept.
+	resumedState.Context["tool_result"] = flattenToolResult(result)
+
+	return e.navigateInternal(ctx, resumedState, result.Result)
+}
+
+// routeDenial moves the flow to on_denied (or on_error / the global error node).
+// Without any handler the session terminates gracefully.
+func (e *Engine) routeDenial(ctx context.Context, currentState *domain.State, node *domain.Node) (*domain.State, error) {
+	next := edge{to: node.OnDenied, kind: domain.TransitionDenied, index: -1}
+	if next.to == "" {
+		next = edge{to: node.OnError, kind: domain.TransitionError, index: -1}
+	}
+	if next.to == "" {
+		next = edge{to: e.defaultErrorNodeID, kind: domain.TransitionErrorNode, index: -1}
+	}
+	target := next.to
+
+	nextState := e.cloneState(currentState)
+	nextState.PendingToolCall = ""
+	nextState.PendingToolCalls = nil
+	nextState.BatchResults = nil
+
+	if target != "" {
+		nextState.Status = domain.StatusActive
+		e.emitTransition(ctx, node.ID, next)
+		return e.transitionTo(nextState, target)
+	}
+
+	// Unhandled Denial: Graceful termination
+	nextState.Status = domain.StatusTerminated
+	return nextState, nil
+}
+
+// routeToolError moves the flow to on_error (or rollback / the global error node).
+func (e *Engine) routeToolError(
+	ctx context.Context,
+	currentState *domain.State,
+	node *domain.Node,
+	toolName string,
+	cause string,
+	rollback func(*domain.State) (*domain.State, error),
+) (*domain.State, error) {
+	if node.OnError != "" {
+		if domain.IsRollback(node.OnError) {
+			e.emitNodeLeave(ctx, node)
+			return rollback(currentState)
+		}
+
+		nextState := e.cloneState(currentState)
+		nextState.Status = domain.StatusActive
+		nextState.PendingToolCall = ""
+		nextState.PendingToolCalls = nil
+		nextState.BatchResults = nil
+		e.emitTransition(ctx, node.ID, edge{to: node.OnError, kind: domain.TransitionError, index: -1})
+		return e.transitionTo(nextState, node.OnError)
+	}
+
+	// Global Fallback
+	if e.defaultErrorNodeID != "" {
+		e.emitNodeLeave(ctx, node)
+		nextState := e.cloneState(currentState)
+		nextState.Status = domain.StatusActive
+		nextState.PendingToolCall = ""
+		nextState.PendingToolCalls = nil
+		nextState.BatchResults = nil
+		e.emitTransition(ctx, node.ID, edge{to: e.defaultErrorNodeID, kind: domain.TransitionErrorNode, index: -1})
+		return e.transitionTo(nextState, e.defaultErrorNodeID)
+	}
+
+	return nil, &UnhandledToolError{
+		NodeID:   node.ID,
+		ToolName: toolName,
+		Cause:    cause,
+	}
+}
+
+// flattenToolResult exposes a tool result as a map for templates ({{ .tool_result.field }}).
+func flattenToolResult(result domain.ToolResult) map[string]any {
 	trContext := make(map[string]any)
 	trContext["_id"] = result.ID
 
@@ -93,10 +137,7 @@ func (e *Engine) handleToolResult(ctx context.Context, currentState *domain.Stat
 	} else {
 		trContext["result"] = result.Result
 	}
-
-	resumedState.Context["tool_result"] = trContext
-
-	return e.navigateInternal(ctx, resumedState, result.Result)
+	return trContext
 }
 
 // resolveEffectiveInput applies defaults and validations based on node configuration.
@@ -142,8 +183,26 @@ func (e *Engine) resolveEffectiveInput(node *domain.Node, input any) (any, error
 	return effectiveInput, nil
 }
 
+// edge is the rule chosen to leave a node. An empty target means the node stays put.
+type edge struct {
+	to     string
+	kind   domain.TransitionKind
+	index  int // Position in the node's transitions (-1 for other kinds)
+	signal string
+}
+
+// transitionEdge returns the edge of the first transition of node leading to target.
+func transitionEdge(node *domain.Node, target string) edge {
+	for i, t := range node.Transitions {
+		if t.ToNodeID == target {
+			return edge{to: target, kind: domain.TransitionNext, index: i}
+		}
+	}
+	return edge{to: target, kind: domain.TransitionNext, index: -1}
+}
+
 // resolveNextNodeID evaluates the priority-based transition rules.
-func (e *Engine) resolveNextNodeID(ctx context.Context, node *domain.Node, input any) (string, error) {
+func (e *Engine) resolveNextNodeID(ctx context.Context, node *domain.Node, input any) (edge, error) {
 	// Check for refusal (for on_denied handler synergy)
 	isRefusal := false
 	switch v := input.(type) {
@@ -155,28 +214,32 @@ func (e *Engine) resolveNextNodeID(ctx context.Context, node *domain.Node, input
 	}
 
 	// Priority 1: Conditional Transitions
-	for _, t := range node.Transitions {
+	for i, t := range node.Transitions {
 		if t.Condition != "" && e.evaluator != nil {
 			ok, err := e.evaluator(ctx, t.Condition, input)
 			if err == nil && ok {
-				return t.ToNodeID, nil
+				return edge{to: t.ToNodeID, kind: domain.TransitionNext, index: i}, nil
 			}
 		}
 	}
 
 	// Priority 2: Policy Handler (on_denied)
 	if isRefusal && node.OnDenied != "" {
-		return node.OnDenied, nil
+		return edge{to: node.OnDenied, kind: domain.TransitionDenied, index: -1}, nil
 	}
 
 	// Priority 3: Unconditional Transitions
-	for _, t := range node.Transitions {
+	for i, t := range node.Transitions {
 		if t.Condition == "" {
-			return t.ToNodeID, nil
+			return edge{to: t.ToNodeID, kind: domain.TransitionNext, index: i}, nil
 		}
 	}
 
-	return "", nil
+	// Without a match the node stays put, unless the author asked for strictness.
+	if node.StrictTransitions && len(node.Transitions) > 0 {
+		return edge{}, fmt.Errorf("node %s: %w (input: %v)", node.ID, domain.ErrNoTransition, input)
+	}
+	return edge{}, nil
 }
 
 // cloneState creates a shallow copy of the state with deep-copied contexts for safe mutation.
@@ -193,5 +256,14 @@ func (e *Engine) cloneState(src *domain.State) *domain.State {
 	for k, v := range src.SystemContext {
 		next.SystemContext[k] = v
 	}
+	if src.PendingToolCalls != nil {
+		next.PendingToolCalls = append([]string(nil), src.PendingToolCalls...)
+	}
+	if src.BatchResults != nil {
+		next.BatchResults = append([]domain.ToolResult(nil), src.BatchResults...)
+	}
+	if src.AsyncCalls != nil {
+		next.AsyncCalls = append([]domain.AsyncCall(nil), src.AsyncCalls...)
+	}
 	return &next
 }
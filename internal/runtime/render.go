@@ -92,7 +92,7 @@ func (e *Engine) applyContentConversion(ctx context.Context, text string) (strin
 
 // renderInputRequest calculates the action for user input based on node config.
 func (e *Engine) renderInputRequest(node *domain.Node) (*domain.ActionRequest, error) {
-	needsInput := node.Wait || node.Type == domain.NodeTypeQuestion || node.InputType != ""
+	needsInput := node.Wait || node.Type == domain.NodeTypeQuestion || node.Type == domain.NodeTypeRoute || node.InputType != ""
 	if !needsInput {
 		return nil, nil
 	}
@@ -137,15 +137,68 @@ func (e *Engine) renderToolCall(ctx context.Context, node *domain.Node, state *d
 
 	if toolCallToRender == nil {
 		// Strict check for tool-type nodes
-		if node.Type == domain.NodeTypeTool && state.Status != domain.StatusRollingBack {
+		if node.Type == domain.NodeTypeTool && state.Status != domain.StatusRollingBack && len(node.Batch) == 0 {
 			return nil, fmt.Errorf("node %s is type 'tool' but missing tool_call definition", node.ID)
 		}
 		return nil, nil
 	}
 
+	// Accepted calls are running in the background: do not fire them again.
+	if _, accepted := state.AsyncCall(toolCallToRender.ID); accepted {
+		return nil, nil
+	}
+
+	call, err := e.prepareCall(ctx, node, state, *toolCallToRender, toolCallToRender.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ActionRequest{
+		Type:    domain.ActionCallTool,
+		Payload: call,
+	}, nil
+}
+
+// renderBatchCalls calculates one action per pending call of a batch (Do list or per-call Undo).
+func (e *Engine) renderBatchCalls(ctx context.Context, node *domain.Node, state *domain.State) ([]domain.ActionRequest, error) {
+	if len(state.PendingToolCalls) == 0 {
+		return nil, nil
+	}
+
+	var candidates []domain.ToolCall
+	if state.Status == domain.StatusRollingBack {
+		candidates = node.BatchUndoCalls(nil)
+	} else {
+		for _, bc := range node.Batch {
+			candidates = append(candidates, bc.ToolCall)
+		}
+	}
+
+	actions := make([]domain.ActionRequest, 0, len(state.PendingToolCalls))
+	for _, candidate := range candidates {
+		if indexOf(state.PendingToolCalls, candidate.ID) < 0 {
+			continue
+		}
+		// Accepted calls are running in the background: do not fire them again.
+		if _, accepted := state.AsyncCall(candidate.ID); accepted {
+			continue
+		}
+		// Batch entries may share a tool name, so the call ID keeps keys distinct.
+		call, err := e.prepareCall(ctx, node, state, candidate, candidate.ID)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, domain.ActionRequest{
+			Type:    domain.ActionCallTool,
+			Payload: call,
+		})
+	}
+	return actions, nil
+}
+
+// prepareCall clones a tool call and enriches it with metadata, idempotency key and interpolated args.
+func (e *Engine) prepareCall(ctx context.Context, node *domain.Node, state *domain.State, call domain.ToolCall, keyDiscriminator string) (domain.ToolCall, error) {
 	// Clone and enrich call metadata.
 	// We must preserve existing metadata from the tool definition (e.g. x-exec).
-	call := *toolCallToRender
 	if call.Metadata == nil {
 		call.Metadata = make(map[string]string)
 	} else {
@@ -165,7 +218,7 @@ func (e *Engine) renderToolCall(ctx context.Context, node *domain.Node, state *d
 	}
 
 	// Idempotency
-	key := e.generateIdempotencyKey(state, node.ID, call.Name)
+	key := e.generateIdempotencyKey(state, node.ID, keyDiscriminator)
 	call.IdempotencyKey = key
 	call.Metadata[domain.KeyIdempotency] = key
 
@@ -182,7 +235,7 @@ func (e *Engine) renderToolCall(ctx context.Context, node *domain.Node, state *d
 			if strVal, ok := v.(string); ok && strings.Contains(strVal, "{{") {
 				val, err := e.interpolator(ctx, strVal, data)
 				if err != nil {
-					return nil, fmt.Errorf("failed to interpolate tool arg '%s': %w", k, err)
+					return call, fmt.Errorf("failed to interpolate tool arg '%s': %w", k, err)
 				}
 				interpolatedArgs[k] = val
 			} else {
@@ -192,8 +245,20 @@ func (e *Engine) renderToolCall(ctx context.Context, node *domain.Node, state *d
 		call.Args = interpolatedArgs
 	}
 
-	return &domain.ActionRequest{
-		Type:    domain.ActionCallTool,
-		Payload: call,
-	}, nil
+	return call, nil
+}
+
+// Evaluate runs the engine's condition evaluator, as a transition condition sees input.
+func (e *Engine) Evaluate(ctx context.Context, condition string, input any) (bool, error) {
+	return e.evaluator(ctx, condition, input)
+}
+
+// Interpolate renders a template with the data node content sees: the context plus `sys`.
+func (e *Engine) Interpolate(ctx context.Context, templateStr string, state *domain.State) (string, error) {
+	data := make(map[string]any, len(state.Context)+1)
+	for k, v := range state.Context {
+		data[k] = v
+	}
+	data["sys"] = state.SystemContext
+	return e.interpolator(ctx, templateStr, data)
 }
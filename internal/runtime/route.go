@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// labelCondition extracts the option label from the implicit `input == 'Label'` conditions.
+var labelCondition = regexp.MustCompile(`^\s*input\s*==\s*'((?:[^'\\]|\\.)*)'\s*$`)
+
+// navigateRoute classifies free-text input onto one of the node's transitions.
+// Confident matches store the canonical label (save_to) and follow the transition;
+// unclear input goes to on_unclear, or keeps the node active so it can ask again.
+func (e *Engine) navigateRoute(ctx context.Context, currentState *domain.State, node *domain.Node, input any) (*domain.State, error) {
+	candidates := routeCandidates(node)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("node %s is type 'route' but declares no labeled transitions", node.ID)
+	}
+
+	text := strings.TrimSpace(fmt.Sprintf("%v", input))
+	if input == nil {
+		text = ""
+	}
+
+	var match domain.IntentMatch
+	if text != "" {
+		var err error
+		match, err = e.intentClassifier.Classify(ctx, text, candidates)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: intent classification failed: %w", node.ID, err)
+		}
+	}
+
+	label := ""
+	for _, c := range candidates {
+		if c.Target == match.Target {
+			label = c.Label
+			break
+		}
+	}
+
+	state := e.cloneState(currentState)
+	state.SystemContext[domain.SysKeyIntent] = match.ToMap(text, label)
+
+	minConfidence := node.MinConfidence
+	if minConfidence <= 0 {
+		minConfidence = domain.DefaultMinConfidence
+	}
+	if match.Target == "" || match.Confidence < minConfidence {
+		e.logger.Debug("intent unclear", "node", node.ID, "input", text, "confidence", match.Confidence)
+		if node.OnUnclear != "" {
+			e.emitNodeLeave(ctx, node)
+			e.emitTransition(ctx, node.ID, edge{to: node.OnUnclear, kind: domain.TransitionUnclear, index: -1})
+			return e.transitionTo(state, node.OnUnclear)
+		}
+		return state, nil
+	}
+
+	canonical := label
+	if canonical == "" {
+		canonical = match.Target
+	}
+	nextState, err := e.applyInput(state, node, canonical)
+	if err != nil {
+		return nil, err
+	}
+	return e.advance(ctx, nextState, node, transitionEdge(node, match.Target))
+}
+
+// routeCandidates derives the intents of a route node from its transitions, in order.
+// The label comes from the transition itself or from an implicit `input == 'Label'` condition.
+func routeCandidates(node *domain.Node) []domain.Intent {
+	var candidates []domain.Intent
+	for _, t := range node.Transitions {
+		label := t.Label
+		if label == "" {
+			if m := labelCondition.FindStringSubmatch(t.Condition); m != nil {
+				label = strings.ReplaceAll(m[1], `\'`, `'`)
+			}
+		}
+		if t.ToNodeID == "" || (label == "" && len(t.Synonyms) == 0 && len(t.Keywords) == 0) {
+			continue
+		}
+		candidates = append(candidates, domain.Intent{
+			Target:   t.ToNodeID,
+			Label:    label,
+			Index:    len(candidates) + 1,
+			Synonyms: t.Synonyms,
+			Keywords: t.Keywords,
+		})
+	}
+	return candidates
+}
@@ -0,0 +1,109 @@
+package runtime_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aretw0/trellis/internal/runtime"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/intent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func routeEngine(t *testing.T, onUnclear string, opts ...runtime.EngineOption) *runtime.Engine {
+	t.Helper()
+	loader := memory.NewLoader(map[string]string{
+		"start": `{
+			"id": "start",
+			"type": "route",
+			"save_to": "answer",
+			"on_unclear": "` + onUnclear + `",
+			"input_options": ["Yes", "No"],
+			"transitions": [
+				{"to_node_id": "confirmed", "condition": "input == 'Yes'"},
+				{"to_node_id": "cancelled", "label": "No", "synonyms": ["forget it"]}
+			]
+		}`,
+		"confirmed": `{"id": "confirmed", "type": "text"}`,
+		"cancelled": `{"id": "cancelled", "type": "text"}`,
+		"clarify":   `{"id": "clarify", "type": "text"}`,
+	})
+	return runtime.NewEngine(loader, nil, nil, opts...)
+}
+
+func TestEngine_Route_ClassifiesFreeText(t *testing.T) {
+	engine := routeEngine(t, "clarify")
+	ctx := context.Background()
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+
+	actions, _, err := engine.Render(ctx, state)
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	req := actions[0].Payload.(domain.InputRequest)
+	assert.Equal(t, domain.InputText, req.Type, "route nodes accept free text")
+	assert.Equal(t, []string{"Yes", "No"}, req.Options)
+
+	next, err := engine.Navigate(ctx, state, "yeah sure")
+	require.NoError(t, err)
+	assert.Equal(t, "confirmed", next.CurrentNodeID)
+	assert.Equal(t, "Yes", next.Context["answer"], "the canonical label is saved")
+
+	recorded := next.SystemContext[domain.SysKeyIntent].(map[string]any)
+	assert.Equal(t, "yeah sure", recorded["input"])
+	assert.Equal(t, "synonym", recorded["strategy"])
+	assert.GreaterOrEqual(t, recorded["confidence"].(float64), domain.DefaultMinConfidence)
+
+	next, err = engine.Navigate(ctx, state, "forget it")
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", next.CurrentNodeID)
+
+	next, err = engine.Navigate(ctx, state, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "confirmed", next.CurrentNodeID)
+}
+
+func TestEngine_Route_Unclear(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("routes to on_unclear", func(t *testing.T) {
+		engine := routeEngine(t, "clarify")
+		state, err := engine.Start(ctx, "s1", nil)
+		require.NoError(t, err)
+
+		next, err := engine.Navigate(ctx, state, "what time is it?")
+		require.NoError(t, err)
+		assert.Equal(t, "clarify", next.CurrentNodeID)
+		assert.NotContains(t, next.Context, "answer")
+		assert.Less(t, next.SystemContext[domain.SysKeyIntent].(map[string]any)["confidence"].(float64), domain.DefaultMinConfidence)
+	})
+
+	t.Run("stays to ask again without on_unclear", func(t *testing.T) {
+		engine := routeEngine(t, "")
+		state, err := engine.Start(ctx, "s1", nil)
+		require.NoError(t, err)
+
+		next, err := engine.Navigate(ctx, state, "hmm")
+		require.NoError(t, err)
+		assert.Equal(t, "start", next.CurrentNodeID)
+		assert.Equal(t, domain.StatusActive, next.Status)
+	})
+}
+
+func TestEngine_Route_CustomClassifier(t *testing.T) {
+	classifier := intent.Func(func(_ context.Context, input string, candidates []domain.Intent) (domain.IntentMatch, error) {
+		require.Len(t, candidates, 2)
+		assert.Equal(t, domain.Intent{Target: "cancelled", Label: "No", Index: 2, Synonyms: []string{"forget it"}}, candidates[1])
+		return domain.IntentMatch{Target: "cancelled", Confidence: 0.7, Strategy: "custom"}, nil
+	})
+	engine := routeEngine(t, "clarify", runtime.WithIntentClassifier(classifier))
+
+	state, err := engine.Start(context.Background(), "s1", nil)
+	require.NoError(t, err)
+	next, err := engine.Navigate(context.Background(), state, "anything")
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", next.CurrentNodeID)
+	assert.Equal(t, "No", next.Context["answer"])
+}
@@ -23,6 +23,8 @@ func (e *Engine) continueRollback(ctx context.Context, state *domain.State, popC
 	nextState := e.cloneState(state)
 	nextState.Status = domain.StatusRollingBack
 	nextState.PendingToolCall = ""
+	nextState.PendingToolCalls = nil
+	nextState.BatchResults = nil
 
 	if popCurrent && len(nextState.History) > 0 {
 		nextState.History = nextState.History[:len(nextState.History)-1]
@@ -51,6 +53,14 @@ func (e *Engine) continueRollback(ctx context.Context, state *domain.State, popC
 			return nextState, nil
 		}
 
+		// Batch compensation: every per-call undo of the node.
+		if undos := node.BatchUndoCalls(nil); len(undos) > 0 {
+			for _, undo := range undos {
+				nextState.PendingToolCalls = append(nextState.PendingToolCalls, undo.ID)
+			}
+			return nextState, nil
+		}
+
 		// Read-only step or no compensation defined: pop and continue unwinding.
 		nextState.History = nextState.History[:len(nextState.History)-1]
 	}
@@ -0,0 +1,61 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictTransitions(t *testing.T) {
+	menu := func(strict bool) domain.Node {
+		return domain.Node{
+			ID:                "menu",
+			Type:              domain.NodeTypeQuestion,
+			InputType:         "choice",
+			InputOptions:      []string{"Tea", "Coffee"},
+			StrictTransitions: strict,
+			Transitions: []domain.Transition{
+				{Condition: "input == 'Tea'", ToNodeID: "tea"},
+			},
+		}
+	}
+	tea := domain.Node{ID: "tea", Type: domain.NodeTypeText}
+
+	t.Run("lenient node stays put", func(t *testing.T) {
+		loader, _ := memory.NewFromNodes(menu(false), tea)
+		engine := NewEngine(loader, nil, nil)
+
+		next, err := engine.Navigate(context.Background(), domain.NewState("s", "menu"), "Coffee")
+		require.NoError(t, err)
+		assert.Equal(t, "menu", next.CurrentNodeID)
+	})
+
+	t.Run("strict node fails", func(t *testing.T) {
+		loader, _ := memory.NewFromNodes(menu(true), tea)
+		engine := NewEngine(loader, nil, nil)
+
+		_, err := engine.Navigate(context.Background(), domain.NewState("s", "menu"), "Coffee")
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, domain.ErrNoTransition))
+		assert.Contains(t, err.Error(), "node menu")
+		assert.Contains(t, err.Error(), "Coffee")
+
+		next, err := engine.Navigate(context.Background(), domain.NewState("s", "menu"), "tea")
+		require.NoError(t, err)
+		assert.Equal(t, "tea", next.CurrentNodeID)
+	})
+
+	t.Run("terminal strict node ends", func(t *testing.T) {
+		loader, _ := memory.NewFromNodes(domain.Node{ID: "end", Type: domain.NodeTypeText, StrictTransitions: true})
+		engine := NewEngine(loader, nil, nil)
+
+		next, err := engine.Navigate(context.Background(), domain.NewState("s", "end"), "")
+		require.NoError(t, err)
+		assert.True(t, next.Terminated)
+	})
+}
@@ -0,0 +1,63 @@
+package runtime
+
+import "github.com/aretw0/trellis/pkg/domain"
+
+// recordUsage accounts a completed tool call into the session usage ledger (SystemContext["usage"]).
+// Every executed call is counted, even if the tool did not report any usage metadata.
+func (e *Engine) recordUsage(state *domain.State, toolName string, result domain.ToolResult) {
+	if toolName == "" {
+		toolName = result.ID
+	}
+	report := domain.UsageFromState(state).Record(toolName, result.Usage)
+	state.SystemContext[domain.SysKeyUsage] = report.ToMap()
+}
+
+// toolNameFor resolves the tool name used for accounting the pending call of a node.
+func toolNameFor(node *domain.Node, result domain.ToolResult) string {
+	if node != nil && node.Do != nil && node.Do.Name != "" {
+		return node.Do.Name
+	}
+	return result.ID
+}
+
+// budgetFor returns the budget in force for a session: the engine budget merged with the
+// flow-level budget recorded at start.
+func (e *Engine) budgetFor(state *domain.State) domain.Budget {
+	return e.budget.Merge(domain.BudgetFromState(state))
+}
+
+// undoNameFor resolves the tool name used for accounting a compensation of a node,
+// single (undo) or per batch call.
+func undoNameFor(node *domain.Node, callID string) string {
+	if node == nil {
+		return callID
+	}
+	if node.Undo != nil && (node.Undo.ID == callID || (node.Undo.ID == "" && node.Undo.Name == callID)) {
+		return node.Undo.Name
+	}
+	for _, undo := range node.BatchUndoCalls(nil) {
+		if undo.ID == callID {
+			return undo.Name
+		}
+	}
+	return callID
+}
+
+// meterResult accounts a call that ran and reports whether the budget had already run out
+// before it, in which case the result must be routed as a denial. The usage is recorded
+// either way, so overspend stays visible in the ledger. Hosts are expected to deny such
+// calls before running them (runner.BudgetMiddleware); checking again here covers every
+// entry point, including stateless HTTP clients.
+func (e *Engine) meterResult(state *domain.State, toolName string, result domain.ToolResult) (domain.ToolResult, bool) {
+	budget := e.budgetFor(state)
+	exhausted, reason := false, ""
+	if !budget.IsZero() {
+		exhausted, reason = budget.Exhausted(domain.UsageFromState(state).UsageTotals)
+	}
+	e.recordUsage(state, toolName, result)
+	if !exhausted {
+		return result, false
+	}
+	e.logger.Debug("tool result denied", "tool", toolName, "node", state.CurrentNodeID, "reason", reason)
+	return domain.ToolResult{ID: result.ID, IsDenied: true, Error: reason}, true
+}
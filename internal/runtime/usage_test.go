@@ -0,0 +1,197 @@
+package runtime_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aretw0/trellis/internal/runtime"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_UsageAccounting(t *testing.T) {
+	loader, _ := memory.NewFromNodes(
+		domain.Node{
+			ID:          "start",
+			Type:        domain.NodeTypeTool,
+			Do:          &domain.ToolCall{ID: "ask", Name: "llm"},
+			Budget:      &domain.Budget{MaxCost: 2},
+			Transitions: []domain.Transition{{ToNodeID: "again"}},
+		},
+		domain.Node{
+			ID:          "again",
+			Type:        domain.NodeTypeTool,
+			Do:          &domain.ToolCall{ID: "ask2", Name: "llm"},
+			OnError:     "end",
+			Transitions: []domain.Transition{{ToNodeID: "end"}},
+		},
+		domain.Node{ID: "end", Type: domain.NodeTypeText},
+	)
+
+	var returned []*domain.ToolEvent
+	engine := runtime.NewEngine(loader, nil, nil, runtime.WithLifecycleHooks(domain.LifecycleHooks{
+		OnToolReturn: func(_ context.Context, e *domain.ToolEvent) { returned = append(returned, e) },
+	}))
+	ctx := context.Background()
+
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, domain.Budget{MaxCost: 2}, domain.BudgetFromState(state), "flow budget must be stamped at start")
+
+	state, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "ask", Usage: &domain.ToolUsage{Tokens: 100, Cost: 0.5}})
+	require.NoError(t, err)
+	assert.Equal(t, "again", state.CurrentNodeID)
+
+	// Failed calls are still metered.
+	next, err := engine.Navigate(ctx, state, domain.ToolResult{ID: "ask2", IsError: true, Usage: &domain.ToolUsage{Tokens: 20}})
+	require.NoError(t, err)
+	assert.Equal(t, "end", next.CurrentNodeID)
+
+	report := domain.UsageFromState(next)
+	assert.Equal(t, 2, report.Calls)
+	assert.Equal(t, int64(120), report.Tokens)
+	assert.Equal(t, 0.5, report.Cost)
+	assert.Equal(t, 2, report.Tools["llm"].Calls)
+
+	// Previous state must remain untouched.
+	assert.Equal(t, 1, domain.UsageFromState(state).Calls)
+
+	require.Len(t, returned, 2)
+	assert.Equal(t, "llm", returned[0].ToolName)
+	assert.Equal(t, int64(100), returned[0].Usage.Tokens)
+}
+
+func TestEngine_UsageAccounting_DeniedIsNotMetered(t *testing.T) {
+	loader, _ := memory.NewFromNodes(
+		domain.Node{
+			ID:       "start",
+			Type:     domain.NodeTypeTool,
+			Do:       &domain.ToolCall{ID: "ask", Name: "llm"},
+			OnDenied: "denied",
+		},
+		domain.Node{ID: "denied", Type: domain.NodeTypeText},
+	)
+	engine := runtime.NewEngine(loader, nil, nil)
+	ctx := context.Background()
+
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+
+	next, err := engine.Navigate(ctx, state, domain.ToolResult{ID: "ask", IsDenied: true})
+	require.NoError(t, err)
+	assert.Equal(t, "denied", next.CurrentNodeID)
+	assert.Equal(t, 0, domain.UsageFromState(next).Calls)
+}
+
+func TestEngine_EnforcesBudget(t *testing.T) {
+	loader, _ := memory.NewFromNodes(
+		domain.Node{
+			ID:          "start",
+			Type:        domain.NodeTypeTool,
+			Do:          &domain.ToolCall{ID: "ask", Name: "llm"},
+			Transitions: []domain.Transition{{ToNodeID: "fanout"}},
+		},
+		domain.Node{
+			ID:   "fanout",
+			Type: domain.NodeTypeTool,
+			Batch: []domain.BatchCall{
+				{ToolCall: domain.ToolCall{ID: "a", Name: "llm"}},
+				{ToolCall: domain.ToolCall{ID: "b", Name: "llm"}},
+				{ToolCall: domain.ToolCall{ID: "c", Name: "llm"}},
+			},
+			BatchPolicy: domain.BatchPolicyContinue,
+			Transitions: []domain.Transition{{ToNodeID: "again"}},
+		},
+		domain.Node{
+			ID:          "again",
+			Type:        domain.NodeTypeTool,
+			Do:          &domain.ToolCall{ID: "ask2", Name: "llm"},
+			OnDenied:    "over_budget",
+			Transitions: []domain.Transition{{ToNodeID: "end"}},
+		},
+		domain.Node{ID: "over_budget", Type: domain.NodeTypeText},
+		domain.Node{ID: "end", Type: domain.NodeTypeText},
+	)
+	engine := runtime.NewEngine(loader, nil, nil, runtime.WithBudget(domain.Budget{MaxCalls: 3}))
+	ctx := context.Background()
+
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+	state, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "ask"})
+	require.NoError(t, err)
+	require.Equal(t, "fanout", state.CurrentNodeID)
+
+	// The host ran the whole batch: the call past the budget is denied, not applied,
+	// but its usage stays in the ledger.
+	state, err = engine.Navigate(ctx, state, []domain.ToolResult{{ID: "a"}, {ID: "b"}, {ID: "c", Result: "ran anyway"}})
+	require.NoError(t, err)
+	assert.Equal(t, "again", state.CurrentNodeID)
+	assert.Equal(t, 4, domain.UsageFromState(state).Calls)
+	results := state.Context["tool_results"].(map[string]any)
+	assert.Equal(t, true, results["c"].(map[string]any)["_denied"])
+	assert.Nil(t, results["b"].(map[string]any)["_denied"])
+
+	// A stateless client that ignores the budget gets its result denied too.
+	state, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "ask2", Result: "ran anyway"})
+	require.NoError(t, err)
+	assert.Equal(t, "over_budget", state.CurrentNodeID)
+	assert.Equal(t, 5, domain.UsageFromState(state).Calls)
+	assert.Equal(t, 5, domain.UsageFromState(state).Tools["llm"].Calls)
+
+	// Results that come in denied never ran and are not metered.
+	state, err = engine.Start(ctx, "s2", nil)
+	require.NoError(t, err)
+	state, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "ask"})
+	require.NoError(t, err)
+	state, err = engine.Navigate(ctx, state, []domain.ToolResult{{ID: "a"}, {ID: "b", IsDenied: true}, {ID: "c", IsDenied: true}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, domain.UsageFromState(state).Calls)
+}
+
+func TestEngine_RecordsUndoUsageByToolName(t *testing.T) {
+	loader, _ := memory.NewFromNodes(
+		domain.Node{
+			ID:          "start",
+			Type:        domain.NodeTypeTool,
+			Do:          &domain.ToolCall{ID: "charge-1", Name: "charge"},
+			Undo:        &domain.ToolCall{ID: "refund-1", Name: "refund"},
+			Transitions: []domain.Transition{{ToNodeID: "ship"}},
+		},
+		domain.Node{
+			ID:   "ship",
+			Type: domain.NodeTypeTool,
+			Batch: []domain.BatchCall{
+				{ToolCall: domain.ToolCall{ID: "label", Name: "print"}, Undo: &domain.ToolCall{Name: "void"}},
+				{ToolCall: domain.ToolCall{ID: "pickup", Name: "courier"}},
+			},
+			BatchPolicy: domain.BatchPolicyContinue,
+			Transitions: []domain.Transition{{ToNodeID: "notify"}},
+		},
+		domain.Node{ID: "notify", Type: domain.NodeTypeTool, Do: &domain.ToolCall{ID: "mail", Name: "mail"}, OnError: "rollback"},
+	)
+	engine := runtime.NewEngine(loader, nil, nil)
+	ctx := context.Background()
+
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+	state, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "charge-1"})
+	require.NoError(t, err)
+	state, err = engine.Navigate(ctx, state, []domain.ToolResult{{ID: "label"}, {ID: "pickup"}})
+	require.NoError(t, err)
+	state, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "mail", IsError: true})
+	require.NoError(t, err)
+	require.Equal(t, domain.StatusRollingBack, state.Status)
+
+	state, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "label_undo"})
+	require.NoError(t, err)
+	state, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "refund-1"})
+	require.NoError(t, err)
+
+	usage := domain.UsageFromState(state)
+	assert.Equal(t, 1, usage.Tools["void"].Calls)
+	assert.Equal(t, 1, usage.Tools["refund"].Calls)
+	assert.NotContains(t, usage.Tools, "label_undo")
+	assert.NotContains(t, usage.Tools, "refund-1")
+}
@@ -13,13 +13,18 @@ func (e *Engine) validateExecution(node *domain.Node) error {
 	}
 
 	// Forbidden: Concurrent side-effect (Do) and UI pause (Wait/Input)
-	hasTool := node.Do != nil
-	hasInput := node.Wait || node.InputType != "" || node.Type == domain.NodeTypeQuestion
+	hasTool := node.HasTools()
+	hasInput := node.Wait || node.InputType != "" || node.Type == domain.NodeTypeQuestion || node.Type == domain.NodeTypeRoute
 
 	if hasTool && hasInput {
 		return fmt.Errorf("node %s violation: cannot have both 'do' (tool) and 'wait/input' in the same node", node.ID)
 	}
 
+	// Forbidden: LLM nodes decide through the model provider, not through a side-effect
+	if node.Type == domain.NodeTypeLLM && hasTool {
+		return fmt.Errorf("node %s violation: 'llm' nodes cannot declare 'do' (expose functions via 'tools' instead)", node.ID)
+	}
+
 	// Warning: on_signal_default is only valid on the root/entry node
 	if node.ID != e.entryNodeID && len(node.OnSignalDefault) > 0 {
 		e.logger.Warn("on_signal_default configuration ignored: this property is only supported on the entry node",
@@ -0,0 +1,139 @@
+package scenario
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteText prints one line per scenario, the failures under it, and a summary.
+func WriteText(w io.Writer, results []Result) error {
+	failed := 0
+	for _, r := range results {
+		status := "PASS"
+		if !r.Passed {
+			status = "FAIL"
+			failed++
+		}
+		if _, err := fmt.Fprintf(w, "--- %s: %s: %s (%.2fs)\n", status, r.File, r.Name, r.Duration.Seconds()); err != nil {
+			return err
+		}
+		for _, f := range r.Failures {
+			if _, err := fmt.Fprintf(w, "    %s\n", strings.ReplaceAll(f, "\n", "\n    ")); err != nil {
+				return err
+			}
+		}
+	}
+	if len(results) == 0 {
+		_, err := fmt.Fprintln(w, "no scenarios")
+		return err
+	}
+	status := "ok"
+	if failed > 0 {
+		status = "FAIL"
+	}
+	_, err := fmt.Fprintf(w, "%s: %d scenarios, %d passed, %d failed\n", status, len(results), len(results)-failed, failed)
+	return err
+}
+
+// WriteJSON prints the results as an indented JSON array.
+func WriteJSON(w io.Writer, results []Result) error {
+	if results == nil {
+		results = []Result{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// JUnit XML subset, as read by CI test reporters. Each scenario file is a test suite.
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteJUnit prints the results as a JUnit XML report.
+func WriteJUnit(w io.Writer, results []Result) error {
+	var report junitSuites
+	var total time.Duration
+	index := make(map[string]int)
+	durations := make(map[string]time.Duration)
+	for _, r := range results {
+		i, ok := index[r.File]
+		if !ok {
+			i = len(report.Suites)
+			index[r.File] = i
+			report.Suites = append(report.Suites, junitSuite{Name: r.File})
+		}
+		suite := &report.Suites[i]
+		tc := junitCase{Name: r.Name, ClassName: r.File, Time: seconds(r.Duration)}
+		if !r.Passed {
+			tc.Failure = &junitFailure{Message: r.Failures[0], Text: strings.Join(r.Failures, "\n")}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.Cases = append(suite.Cases, tc)
+		suite.Tests++
+		report.Tests++
+		durations[r.File] += r.Duration
+		total += r.Duration
+	}
+	for i := range report.Suites {
+		report.Suites[i].Time = seconds(durations[report.Suites[i].Name])
+	}
+	report.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Write prints the results in the given format: text, json or junit.
+func Write(w io.Writer, format string, results []Result) error {
+	switch format {
+	case "", "text":
+		return WriteText(w, results)
+	case "json":
+		return WriteJSON(w, results)
+	case "junit":
+		return WriteJUnit(w, results)
+	}
+	return fmt.Errorf("unknown format %q (use text, json or junit)", format)
+}
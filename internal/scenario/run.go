@@ -0,0 +1,497 @@
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/ports"
+)
+
+// DefaultMaxSteps bounds a scenario, so a flow that loops without asking for input fails
+// instead of hanging the run.
+const DefaultMaxSteps = 1000
+
+// Engine is what a scenario drives: a stateless engine that can start sessions.
+type Engine interface {
+	ports.StatelessEngine
+	Start(ctx context.Context, sessionID string, initialContext map[string]any) (*domain.State, error)
+}
+
+// Options configures a run.
+type Options struct {
+	// Run selects the scenarios whose name matches (all when nil).
+	Run *regexp.Regexp
+	// Parallel is the number of scenarios run at once (default 1).
+	Parallel int
+	// Update rewrites golden files with the rendered content instead of comparing them.
+	Update bool
+	// MaxSteps bounds the engine steps of a scenario (default DefaultMaxSteps).
+	MaxSteps int
+}
+
+// Result is the outcome of a scenario.
+type Result struct {
+	File     string        `json:"file"`
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Failures []string      `json:"failures,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+	// Visited lists the nodes entered, in order.
+	Visited []string `json:"visited"`
+}
+
+// Failed reports whether any result failed.
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes the selected scenarios of files and returns their results in file order.
+func Run(ctx context.Context, engine Engine, files []*File, opts Options) []Result {
+	type job struct {
+		file *File
+		sc   Scenario
+	}
+	var jobs []job
+	for _, f := range files {
+		for _, sc := range f.Scenarios {
+			if opts.Run == nil || opts.Run.MatchString(sc.Name) {
+				jobs = append(jobs, job{f, sc})
+			}
+		}
+	}
+
+	results := make([]Result, len(jobs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range max(opts.Parallel, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = runScenario(ctx, engine, jobs[i].file, jobs[i].sc, opts)
+			}
+		}()
+	}
+	for i := range jobs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+// session is what a scenario observed while driving the engine.
+type session struct {
+	state    *domain.State
+	visited  []string
+	content  map[string]string // Last render of each node
+	rendered []string          // Every render, in order
+	calls    []domain.ToolCall
+	err      error
+}
+
+// PanicError is a panic of the engine, recovered while driving a scenario.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Outcome is how a scenario's session went, before its expectations are checked.
+type Outcome struct {
+	// State is the last state (nil when the session did not start).
+	State   *domain.State
+	Visited []string
+	// Err is the error that stopped the session (a *PanicError for panics).
+	Err error
+	// Failures are problems driving the session: an unmocked tool, inputs left over...
+	Failures []string
+}
+
+// Play drives a scenario without checking its expectations.
+func Play(ctx context.Context, engine Engine, f *File, sc Scenario, opts Options) Outcome {
+	s, failures := drive(ctx, engine, f, sc, opts)
+	return Outcome{State: s.state, Visited: s.visited, Err: s.err, Failures: failures}
+}
+
+func runScenario(ctx context.Context, engine Engine, f *File, sc Scenario, opts Options) Result {
+	started := time.Now()
+	s, failures := drive(ctx, engine, f, sc, opts)
+	failures = append(failures, check(f, sc.Expect, s, opts.Update)...)
+	return Result{
+		File:     f.Path,
+		Name:     sc.Name,
+		Passed:   len(failures) == 0,
+		Failures: failures,
+		Duration: time.Since(started),
+		Visited:  s.visited,
+	}
+}
+
+// drive runs the session the way the CLI runner does: tool calls get their mocks, input
+// requests consume the next step and everything else auto-transitions. It stops when the
+// flow terminates, fails, or asks for input with no steps left.
+func drive(ctx context.Context, engine Engine, f *File, sc Scenario, opts Options) (s *session, failures []string) {
+	initial := maps.Clone(f.Context)
+	if initial == nil {
+		initial = make(map[string]any)
+	}
+	maps.Copy(initial, sc.Context)
+	mocks := maps.Clone(f.Tools)
+	if mocks == nil {
+		mocks = make(map[string]ToolMocks)
+	}
+	maps.Copy(mocks, sc.Tools)
+	maxSteps := opts.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxSteps
+	}
+
+	s = &session{content: make(map[string]string)}
+	defer func() {
+		if r := recover(); r != nil {
+			s.err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	state, err := engine.Start(ctx, "test:"+sc.Name, initial)
+	if err != nil {
+		s.err = err
+		return s, failures
+	}
+	s.state = state
+	s.visited = append(s.visited, state.CurrentNodeID)
+	steps := sc.Inputs
+	toolCalls := make(map[string]int)
+	renderedVisits := 0
+
+	for range maxSteps {
+		actions, isTerminal, err := engine.Render(ctx, state)
+		if err != nil {
+			s.err = err
+			return s, failures
+		}
+		view := domain.NewRenderView(actions)
+		var text []string
+		for _, msg := range view.Content {
+			if msg = strings.TrimSpace(msg); msg != "" {
+				text = append(text, msg)
+			}
+		}
+		// A node renders again while it waits for tools: keep the content of its first render.
+		if len(s.visited) > renderedVisits {
+			renderedVisits = len(s.visited)
+			if len(text) > 0 {
+				s.content[state.CurrentNodeID] = strings.Join(text, "\n")
+				s.rendered = append(s.rendered, text...)
+			}
+		}
+
+		var next *domain.State
+		switch {
+		case state.Status == domain.StatusWaitingForTool || state.Status == domain.StatusRollingBack:
+			input, err := s.mockTools(state, view.Pending(state), mocks, toolCalls)
+			if err != nil {
+				failures = append(failures, err.Error())
+				return s, failures
+			}
+			next, err = engine.Navigate(ctx, state, input)
+			if err != nil {
+				s.err = err
+				return s, failures
+			}
+		case view.NeedsInput:
+			if len(steps) == 0 {
+				return s, failures // Waiting for input the scenario does not give
+			}
+			step := steps[0]
+			steps = steps[1:]
+			if step.At != "" && step.At != state.CurrentNodeID {
+				failures = append(failures, fmt.Sprintf("step %d: expected input at '%s', got '%s'", len(sc.Inputs)-len(steps), step.At, state.CurrentNodeID))
+				return s, failures
+			}
+			if step.Signal != "" {
+				next, err = engine.Signal(ctx, state, step.Signal)
+			} else {
+				next, err = engine.Navigate(ctx, state, *step.Input)
+			}
+			if err != nil {
+				s.err = err
+				return s, failures
+			}
+		default:
+			next, err = engine.Navigate(ctx, state, "")
+			if err != nil {
+				s.err = err
+				return s, failures
+			}
+		}
+
+		s.visited = append(s.visited, domain.Entered(state, next)...)
+		if domain.FlowEnded(state, next, isTerminal, view.NeedsInput) {
+			s.state = next
+			if len(steps) > 0 {
+				failures = append(failures, fmt.Sprintf("flow ended with %d input(s) left", len(steps)))
+			}
+			return s, failures
+		}
+		state = next
+		s.state = state
+	}
+	failures = append(failures, fmt.Sprintf("no end after %d steps (the flow may loop without asking for input)", maxSteps))
+	return s, failures
+}
+
+// mockTools answers the pending calls with their mocks: a single result, or the results of a batch.
+func (s *session) mockTools(state *domain.State, calls []domain.ToolCall, mocks map[string]ToolMocks, counts map[string]int) (any, error) {
+	var results []domain.ToolResult
+	for _, call := range calls {
+		s.calls = append(s.calls, call)
+		m, ok := mocks[call.Name]
+		if !ok {
+			return nil, fmt.Errorf("tool '%s' called at node '%s' has no mock", call.Name, state.CurrentNodeID)
+		}
+		mock := m[min(counts[call.Name], len(m)-1)]
+		counts[call.Name]++
+		results = append(results, mock.Answer(call.ID))
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("node '%s' is waiting for tools but requested no call", state.CurrentNodeID)
+	}
+	return domain.ToolInput(state, results), nil
+}
+
+// check compares what the session did with the expectations.
+func check(f *File, exp Expect, s *session, update bool) []string {
+	var failures []string
+	fail := func(format string, args ...any) {
+		failures = append(failures, fmt.Sprintf(format, args...))
+	}
+
+	switch {
+	case s.err != nil && exp.Error == "":
+		fail("session failed: %v", s.err)
+	case s.err == nil && exp.Error != "":
+		fail("expected error containing %q, session did not fail", exp.Error)
+	case s.err != nil && !strings.Contains(s.err.Error(), exp.Error):
+		fail("expected error containing %q, got: %v", exp.Error, s.err)
+	}
+
+	if missing, ok := subsequence(s.visited, exp.Visited); !ok {
+		fail("expected to visit '%s' (in order), visited %s", missing, strings.Join(s.visited, " → "))
+	}
+	for _, id := range exp.NotVisited {
+		for _, v := range s.visited {
+			if v == id {
+				fail("expected not to visit '%s'", id)
+				break
+			}
+		}
+	}
+	if s.state != nil {
+		if exp.EndsAt != "" && s.state.CurrentNodeID != exp.EndsAt {
+			fail("expected to end at '%s', ended at '%s'", exp.EndsAt, s.state.CurrentNodeID)
+		}
+		terminated := s.state.Terminated || s.state.Status == domain.StatusTerminated
+		if exp.Terminated != nil && *exp.Terminated != terminated {
+			fail("expected terminated=%t, got %t (at '%s')", *exp.Terminated, terminated, s.state.CurrentNodeID)
+		}
+		for key, want := range exp.Context {
+			got, ok := lookup(s.state.Context, key)
+			if !ok {
+				fail("context: expected %s=%s, key not set", key, show(want))
+			} else if !matches(got, want) {
+				fail("context: expected %s=%s, got %s", key, show(want), show(got))
+			}
+		}
+	}
+
+	for _, c := range exp.Content {
+		failures = append(failures, checkContent(f, c, s, update)...)
+	}
+
+	next := 0
+	for _, want := range exp.ToolCalls {
+		found := false
+		for ; next < len(s.calls); next++ {
+			call := s.calls[next]
+			if call.Name == want.Name && (want.Args == nil || matches(call.Args, want.Args)) {
+				found, next = true, next+1
+				break
+			}
+		}
+		if !found {
+			fail("expected a call to '%s' with args %s (in order), calls: %s", want.Name, show(want.Args), showCalls(s.calls))
+			break
+		}
+	}
+	return failures
+}
+
+func checkContent(f *File, c ContentCheck, s *session, update bool) []string {
+	var failures []string
+	label := "transcript"
+	text := strings.Join(s.rendered, "\n")
+	if c.Node != "" {
+		label = fmt.Sprintf("content of '%s'", c.Node)
+		var ok bool
+		if text, ok = s.content[c.Node]; !ok {
+			return []string{fmt.Sprintf("%s: node rendered no content", label)}
+		}
+	}
+	if c.Contains != "" && !strings.Contains(text, c.Contains) {
+		failures = append(failures, fmt.Sprintf("%s: expected to contain %q, got %q", label, c.Contains, text))
+	}
+	if c.Matches != "" {
+		re, err := regexp.Compile(c.Matches)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: invalid pattern: %v", label, err))
+		} else if !re.MatchString(text) {
+			failures = append(failures, fmt.Sprintf("%s: expected to match %q, got %q", label, c.Matches, text))
+		}
+	}
+	if c.Golden != "" {
+		path := filepath.Join(filepath.Dir(f.Path), filepath.FromSlash(c.Golden))
+		if update {
+			if err := os.WriteFile(path, []byte(text+"\n"), 0644); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: failed to update golden file: %v", label, err))
+			}
+			return failures
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: failed to read golden file (run with --update to create it): %v", label, err))
+		} else if strings.TrimRight(string(want), "\n") != text {
+			failures = append(failures, fmt.Sprintf("%s: differs from %s (run with --update to accept it)\n%s", label, c.Golden, diff(strings.TrimRight(string(want), "\n"), text)))
+		}
+	}
+	return failures
+}
+
+// subsequence reports whether want appears in got in order, returning the first missing item.
+func subsequence(got, want []string) (string, bool) {
+	i := 0
+	for _, w := range want {
+		for i < len(got) && got[i] != w {
+			i++
+		}
+		if i == len(got) {
+			return w, false
+		}
+		i++
+	}
+	return "", true
+}
+
+// lookup reads key from the context, following dots into nested maps when key is not set as is.
+func lookup(ctx map[string]any, key string) (any, bool) {
+	if v, ok := ctx[key]; ok {
+		return v, true
+	}
+	var cur any = ctx
+	for _, part := range strings.Split(key, ".") {
+		m, ok := normalize(cur).(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// matches compares values as JSON. Maps match when they hold the expected keys (extra keys are allowed).
+func matches(got, want any) bool {
+	return subset(normalize(got), normalize(want))
+}
+
+func subset(got, want any) bool {
+	wm, ok := want.(map[string]any)
+	if !ok {
+		return reflect.DeepEqual(got, want)
+	}
+	gm, ok := got.(map[string]any)
+	if !ok {
+		return false
+	}
+	for k, wv := range wm {
+		gv, ok := gm[k]
+		if !ok || !subset(gv, wv) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize round-trips v through JSON, so YAML and engine values compare alike
+// (numbers are kept as json.Number, to compare large integers exactly).
+func normalize(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out any
+	if err := dec.Decode(&out); err != nil {
+		return v
+	}
+	return out
+}
+
+func show(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func showCalls(calls []domain.ToolCall) string {
+	if len(calls) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(calls))
+	for i, c := range calls {
+		parts[i] = c.Name + show(c.Args)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// diff shows the first differing line of two texts.
+func diff(want, got string) string {
+	wl, gl := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < max(len(wl), len(gl)); i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if w != g {
+			return fmt.Sprintf("  line %d:\n    want: %q\n    got:  %q", i+1, w, g)
+		}
+	}
+	return ""
+}
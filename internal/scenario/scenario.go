@@ -0,0 +1,259 @@
+// Package scenario implements `trellis test`: declarative flow tests kept next to the flow.
+//
+// A scenario file (`*.test.yaml`) lists scenarios. Each one starts a session with an initial
+// context, answers the input requests with a sequence of inputs and signals, answers tool
+// calls with mocked results (by tool name) and then checks what happened: the nodes visited,
+// the content rendered, the final context and the arguments of the tool calls.
+//
+//	context: { plan: pro }
+//	tools:
+//	  charge: { result: { status: paid } }
+//	scenarios:
+//	  - name: happy path
+//	    inputs: [Alice, yes]
+//	    expect:
+//	      visited: [start, confirm, billing/charge, done]
+//	      content:
+//	        - { node: done, contains: "Bye Alice" }
+//	      context: { name: Alice }
+//	      tool_calls:
+//	        - { name: charge, args: { plan: pro } }
+package scenario
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aretw0/trellis/pkg/adapters/loam"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/manifest"
+)
+
+// Extensions of scenario files. The loader leaves them out of the graph (see loam.IsScenario).
+var Extensions = loam.ScenarioExtensions
+
+// File is a scenario file. Its context and tool mocks apply to every scenario in it.
+type File struct {
+	// Path is where the file was read from.
+	Path      string               `yaml:"-"`
+	Context   map[string]any       `yaml:"context,omitempty"`
+	Tools     map[string]ToolMocks `yaml:"tools,omitempty"`
+	Scenarios []Scenario           `yaml:"scenarios,omitempty"`
+}
+
+// Scenario is a single session driven from start to finish.
+type Scenario struct {
+	Name string `yaml:"name,omitempty"`
+	// Description explains the scenario (e.g. the finding a `trellis fuzz` reproducer shows).
+	Description string `yaml:"description,omitempty"`
+	// Context is merged over the file context, as the initial context of the session.
+	Context map[string]any `yaml:"context,omitempty"`
+	// Tools is merged over the file mocks, by tool name.
+	Tools map[string]ToolMocks `yaml:"tools,omitempty"`
+	// Inputs answer the input requests, in order.
+	Inputs []Step `yaml:"inputs,omitempty"`
+	Expect Expect `yaml:"expect,omitempty"`
+}
+
+// Step answers one input request with an input or a signal. A plain string is an input.
+type Step struct {
+	Input  *string `yaml:"input,omitempty"`
+	Signal string  `yaml:"signal,omitempty"`
+	// At, when set, is the node that must be asking for input.
+	At string `yaml:"at,omitempty"`
+}
+
+// UnmarshalYAML accepts a scalar as the input of the step.
+func (s *Step) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		value := node.Value
+		s.Input = &value
+		return nil
+	}
+	type plain Step
+	if err := decodeStrict(node, (*plain)(s)); err != nil {
+		return err
+	}
+	if (s.Input == nil) == (s.Signal == "") {
+		return fmt.Errorf("line %d: a step needs either input or signal", node.Line)
+	}
+	return nil
+}
+
+// MarshalYAML writes a plain input as a scalar.
+func (s Step) MarshalYAML() (any, error) {
+	if s.Input != nil && s.At == "" {
+		return *s.Input, nil
+	}
+	type plain Step
+	return plain(s), nil
+}
+
+// ToolMock is the result of a mocked tool call: a value, an error when Error is set, or a
+// denial (as a policy or an exhausted budget would answer) when Denied is set.
+type ToolMock struct {
+	Result any    `yaml:"result,omitempty"`
+	Error  string `yaml:"error,omitempty"`
+	Denied bool   `yaml:"denied,omitempty"`
+}
+
+// Answer returns the result the mock gives to the call with the given ID.
+func (m ToolMock) Answer(callID string) domain.ToolResult {
+	switch {
+	case m.Denied:
+		return domain.ToolResult{ID: callID, IsDenied: true, Error: m.Error}
+	case m.Error != "":
+		return domain.ToolResult{ID: callID, IsError: true, Error: m.Error}
+	default:
+		return domain.ToolResult{ID: callID, Result: m.Result}
+	}
+}
+
+// ToolMocks answers the successive calls of a tool; the last mock answers the remaining calls.
+type ToolMocks []ToolMock
+
+// UnmarshalYAML accepts a single mock or a list of mocks.
+func (m *ToolMocks) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		var mocks []ToolMock
+		if err := decodeStrict(node, &mocks); err != nil {
+			return err
+		}
+		if len(mocks) == 0 {
+			return fmt.Errorf("line %d: empty list of tool mocks", node.Line)
+		}
+		*m = mocks
+		return nil
+	}
+	var mock ToolMock
+	if err := decodeStrict(node, &mock); err != nil {
+		return err
+	}
+	*m = ToolMocks{mock}
+	return nil
+}
+
+// MarshalYAML writes a single mock as a mapping.
+func (m ToolMocks) MarshalYAML() (any, error) {
+	if len(m) == 1 {
+		return m[0], nil
+	}
+	return []ToolMock(m), nil
+}
+
+// Expect lists the assertions checked once the session stops.
+type Expect struct {
+	// Visited nodes, in order; other nodes may be visited in between.
+	Visited []string `yaml:"visited,omitempty"`
+	// NotVisited nodes must not be entered at all.
+	NotVisited []string `yaml:"not_visited,omitempty"`
+	// EndsAt is the node the session stops on (terminated, or waiting for more input).
+	EndsAt string `yaml:"ends_at,omitempty"`
+	// Terminated checks whether the flow reached its end.
+	Terminated *bool `yaml:"terminated,omitempty"`
+	// Error is a substring of the error that stops the session. Without it, any error fails.
+	Error     string          `yaml:"error,omitempty"`
+	Content   []ContentCheck  `yaml:"content,omitempty"`
+	Context   map[string]any  `yaml:"context,omitempty"`
+	ToolCalls []ToolCallCheck `yaml:"tool_calls,omitempty"`
+}
+
+// ContentCheck asserts on rendered content: the last render of Node, or the whole
+// transcript when Node is empty.
+type ContentCheck struct {
+	Node     string `yaml:"node,omitempty"`
+	Contains string `yaml:"contains,omitempty"`
+	Matches  string `yaml:"matches,omitempty"` // Regular expression
+	// Golden is a file, relative to the scenario file, holding the exact content.
+	Golden string `yaml:"golden,omitempty"`
+}
+
+// ToolCallCheck asserts that a tool was called with at least the given arguments.
+// Checks are matched in order against the calls, like Visited.
+type ToolCallCheck struct {
+	Name string         `yaml:"name,omitempty"`
+	Args map[string]any `yaml:"args,omitempty"`
+}
+
+// Parse decodes a scenario file, rejecting unknown keys.
+func Parse(path string, data []byte) (*File, error) {
+	f := &File{Path: path}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	names := make(map[string]bool)
+	for i, sc := range f.Scenarios {
+		if sc.Name == "" {
+			return nil, fmt.Errorf("%s: scenario %d has no name", path, i+1)
+		}
+		if names[sc.Name] {
+			return nil, fmt.Errorf("%s: duplicate scenario %q", path, sc.Name)
+		}
+		names[sc.Name] = true
+	}
+	return f, nil
+}
+
+// Load reads and parses the scenario file at path.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+	return Parse(path, data)
+}
+
+// Find returns the scenario files under dir, sorted. Hidden and vendored directories are skipped.
+func Find(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == manifest.VendorDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if IsFile(p) {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find scenario files: %w", err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// IsFile reports whether path names a scenario file.
+func IsFile(path string) bool {
+	return loam.IsScenario(path)
+}
+
+// decodeStrict decodes node rejecting unknown keys, which yaml.Node.Decode does not.
+func decodeStrict(node *yaml.Node, v any) error {
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package scenario
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/internal/testutils/flowtest"
+)
+
+var flow = map[string]string{
+	"start.md":   "---\nwait: true\nsave_to: name\nto: confirm\non_timeout: late\n---\nWho are you?\n",
+	"confirm.md": "---\nwait: true\ntransitions:\n  - condition: input == 'yes'\n    to: charge\n  - to: start\n---\nCharge {{ .name }}?\n",
+	"charge.yaml": "do: { name: charge, args: { customer: \"{{ .name }}\", amount: 10 } }\n" +
+		"save_to: receipt\non_error: failed\nto: done\n",
+	"done.md":   "Paid, {{ .name }}.\n",
+	"failed.md": "Payment failed.\n",
+	"late.md":   "Too slow.\n",
+}
+
+func newEngine(t *testing.T) (*trellis.Engine, string) {
+	dir := testutils.WriteFlow(t, flow)
+	return flowtest.Open(t, dir), dir
+}
+
+func parse(t *testing.T, dir, src string) *File {
+	f, err := Parse(filepath.Join(dir, "flow.test.yaml"), []byte(src))
+	require.NoError(t, err)
+	return f
+}
+
+func TestRun(t *testing.T) {
+	engine, dir := newEngine(t)
+	f := parse(t, dir, `
+tools:
+  charge: { result: { id: r1 } }
+scenarios:
+  - name: pays
+    inputs: [Alice, yes]
+    expect:
+      visited: [start, charge, done]
+      not_visited: [failed]
+      terminated: true
+      ends_at: done
+      content:
+        - { node: done, contains: "Paid, Alice." }
+        - { matches: "(?s)Who are you.*Charge Alice" }
+      context: { name: Alice, receipt.id: r1 }
+      tool_calls:
+        - { name: charge, args: { amount: 10 } }
+  - name: retries and fails
+    tools:
+      charge: { error: card declined }
+    inputs: [Bob, "no", Bob, { input: yes, at: confirm }]
+    expect:
+      visited: [start, confirm, start, confirm, charge, failed]
+  - name: waits
+    inputs: [Carol]
+    expect:
+      ends_at: confirm
+      terminated: false
+  - name: times out
+    inputs: [{ signal: timeout }]
+    expect:
+      visited: [late]
+  - name: wrong
+    inputs: [Dave, yes, extra]
+    expect:
+      visited: [done, start]
+      context: { name: Eve }
+      tool_calls:
+        - { name: charge, args: { amount: 20 } }
+`)
+
+	results := Run(context.Background(), engine, []*File{f}, Options{Parallel: 3})
+	require.Len(t, results, 5)
+	for _, r := range results[:4] {
+		assert.True(t, r.Passed, "%s: %v", r.Name, r.Failures)
+	}
+
+	wrong := results[4]
+	assert.False(t, wrong.Passed)
+	assert.Equal(t, []string{
+		"flow ended with 1 input(s) left",
+		"expected to visit 'start' (in order), visited start → confirm → charge → done",
+		`context: expected name="Eve", got "Dave"`,
+		`expected a call to 'charge' with args {"amount":20} (in order), calls: charge{"amount":10,"customer":"Dave"}`,
+	}, wrong.Failures)
+
+	t.Run("Filter", func(t *testing.T) {
+		results := Run(context.Background(), engine, []*File{f}, Options{Run: regexp.MustCompile("^wa")})
+		require.Len(t, results, 1)
+		assert.Equal(t, "waits", results[0].Name)
+	})
+}
+
+func TestRun_Failures(t *testing.T) {
+	engine, dir := newEngine(t)
+	f := parse(t, dir, `
+scenarios:
+  - name: unmocked tool
+    inputs: [Alice, yes]
+  - name: wrong step node
+    inputs: [{ input: Alice, at: confirm }]
+  - name: expected error
+    expect:
+      error: boom
+`)
+	results := Run(context.Background(), engine, []*File{f}, Options{})
+	require.Len(t, results, 3)
+	assert.Equal(t, []string{"tool 'charge' called at node 'charge' has no mock"}, results[0].Failures)
+	assert.Equal(t, []string{"step 1: expected input at 'confirm', got 'start'"}, results[1].Failures)
+	assert.Equal(t, []string{`expected error containing "boom", session did not fail`}, results[2].Failures)
+}
+
+func TestRun_Golden(t *testing.T) {
+	engine, dir := newEngine(t)
+	f := parse(t, dir, `
+tools:
+  charge: { result: ok }
+scenarios:
+  - name: golden
+    inputs: [Alice, yes]
+    expect:
+      content:
+        - { golden: testdata/transcript.txt }
+`)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "testdata"), 0755))
+
+	results := Run(context.Background(), engine, []*File{f}, Options{})
+	require.Len(t, results[0].Failures, 1)
+	assert.Contains(t, results[0].Failures[0], "run with --update to create it")
+
+	results = Run(context.Background(), engine, []*File{f}, Options{Update: true})
+	assert.True(t, results[0].Passed, results[0].Failures)
+	golden, err := os.ReadFile(filepath.Join(dir, "testdata", "transcript.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "Who are you?\nCharge Alice?\nPaid, Alice.\n", string(golden))
+
+	results = Run(context.Background(), engine, []*File{f}, Options{})
+	assert.True(t, results[0].Passed, results[0].Failures)
+}
+
+func TestParse(t *testing.T) {
+	_, err := Parse("a.test.yaml", []byte("scenarios:\n  - name: x\n    inputz: []\n"))
+	assert.ErrorContains(t, err, "field inputz not found")
+
+	_, err = Parse("a.test.yaml", []byte("scenarios:\n  - name: x\n    inputs: [{ at: start }]\n"))
+	assert.ErrorContains(t, err, "a step needs either input or signal")
+
+	_, err = Parse("a.test.yaml", []byte("scenarios:\n  - name: x\n  - name: x\n"))
+	assert.EqualError(t, err, `a.test.yaml: duplicate scenario "x"`)
+
+	f, err := Parse("a.test.yaml", []byte("tools:\n  t: [{ error: e }, { result: 1 }]\n  u: { result: 2 }\n"))
+	require.NoError(t, err)
+	assert.Equal(t, ToolMocks{{Error: "e"}, {Result: 1}}, f.Tools["t"])
+	assert.Equal(t, ToolMocks{{Result: 2}}, f.Tools["u"])
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.test.yaml", "sub/b.test.yml", "start.md", ".hidden/c.test.yaml", "vendor/d.test.yaml"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+	}
+	paths, err := Find(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.test.yaml"), filepath.Join(dir, "sub", "b.test.yml")}, paths)
+}
+
+func TestWrite(t *testing.T) {
+	results := []Result{
+		{File: "a.test.yaml", Name: "ok", Passed: true},
+		{File: "a.test.yaml", Name: "bad", Failures: []string{"expected x", "expected y"}},
+	}
+
+	var text bytes.Buffer
+	require.NoError(t, Write(&text, "text", results))
+	assert.Equal(t, "--- PASS: a.test.yaml: ok (0.00s)\n--- FAIL: a.test.yaml: bad (0.00s)\n    expected x\n    expected y\nFAIL: 2 scenarios, 1 passed, 1 failed\n", text.String())
+
+	var js bytes.Buffer
+	require.NoError(t, Write(&js, "json", results))
+	var decoded []Result
+	require.NoError(t, json.Unmarshal(js.Bytes(), &decoded))
+	assert.Equal(t, results[1].Failures, decoded[1].Failures)
+
+	var junit bytes.Buffer
+	require.NoError(t, Write(&junit, "junit", results))
+	var report junitSuites
+	require.NoError(t, xml.Unmarshal(junit.Bytes(), &report))
+	assert.Equal(t, 2, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+	require.Len(t, report.Suites, 1)
+	assert.Equal(t, "expected x", report.Suites[0].Cases[1].Failure.Message)
+
+	assert.EqualError(t, Write(&text, "xml", results), `unknown format "xml" (use text, json or junit)`)
+}
@@ -0,0 +1,41 @@
+// Package flowtest builds engines over flow fixtures for tests. It is kept apart from
+// testutils because it imports the trellis facade, which the Loam adapter tests (users
+// of testutils) cannot import without a cycle.
+package flowtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/internal/testutils"
+)
+
+// Checkout is a small payment flow exercising every kind of edge: start asks whether
+// to pay (transitions, a timeout and an interrupt handler), pay charges
+// `{{ .amount }}` into `receipt` (on_error, on_denied) and done shows the receipt.
+var Checkout = map[string]string{
+	"start.md": "---\nwait: true\non_timeout: late\non_signal_default: { interrupt: bye }\ntransitions:\n" +
+		"  - { condition: input == 'pay', to: pay }\n  - { to: bye }\n---\nHi {{ .name }}\n",
+	"pay.yaml":  "do: { name: charge, args: { amount: '{{ .amount }}' } }\nsave_to: receipt\non_error: failed\non_denied: bye\nto: done\n",
+	"done.md":   "Paid: {{ .receipt.status }}\n",
+	"failed.md": "Failed\n",
+	"late.md":   "Late\n",
+	"bye.md":    "Bye\n",
+}
+
+// NewEngine writes files (slash-separated paths) into a new temporary directory and
+// loads it as a flow. It fails the test immediately on error.
+func NewEngine(t *testing.T, files map[string]string, opts ...trellis.Option) *trellis.Engine {
+	t.Helper()
+	return Open(t, testutils.WriteFlow(t, files), opts...)
+}
+
+// Open loads the flow in dir. It fails the test immediately on error.
+func Open(t *testing.T, dir string, opts ...trellis.Option) *trellis.Engine {
+	t.Helper()
+	engine, err := trellis.New(dir, opts...)
+	require.NoError(t, err)
+	return engine
+}
@@ -1,6 +1,8 @@
 package testutils
 
 import (
+	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 
@@ -29,3 +31,50 @@ func SetupTestRepo(t *testing.T, opts ...loam.Option) (string, core.Repository)
 
 	return absPath, repo
 }
+
+// WriteFlow writes files (slash-separated paths) into a new temporary directory and returns
+// it, ready for trellis.New. Building the engine is left to the caller so that packages the
+// facade depends on (such as the Loam adapter) can use this helper without an import cycle.
+func WriteFlow(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	WriteFiles(t, dir, files)
+	return dir
+}
+
+// WriteFiles writes files (slash-separated paths) under dir, creating parent directories.
+func WriteFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+// GitCommit writes files (slash-separated paths) under dir and commits them, creating the
+// repository on first use. A non-empty tag is attached to the new commit.
+// The test is skipped when the git binary is not available.
+func GitCommit(t *testing.T, dir string, files map[string]string, tag string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not available")
+	}
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=trellis", "-c", "user.email=trellis@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "git %v: %s", args, out)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		git("init", "-q")
+	}
+	WriteFiles(t, dir, files)
+	git("add", "-A")
+	git("commit", "-q", "--allow-empty", "-m", "commit "+tag)
+	if tag != "" {
+		git("tag", tag)
+	}
+}
@@ -0,0 +1,24 @@
+package textdist
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevenshtein(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"on_eror", "on_error", 1},
+		{"supprot", "support", 2},
+		{"café", "cafe", 1}, // runes, not bytes
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, Levenshtein(c.a, c.b), "%q -> %q", c.a, c.b)
+		assert.Equal(t, c.want, Levenshtein(c.b, c.a), "%q -> %q", c.b, c.a)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/aretw0/trellis/internal/compiler"
+	"github.com/aretw0/trellis/pkg/domain"
 	"github.com/aretw0/trellis/pkg/ports"
 )
 
@@ -18,6 +19,9 @@ func ValidateGraph(loader ports.GraphLoader, parser *compiler.Parser, startNodeI
 
 	// 1. Get raw start node to verify existence and ID
 	startNodeRaw, err := loader.GetNode(startNodeID)
+	if diags := domain.Diagnostics(err); len(diags) > 0 {
+		return report(diagnosticLines(diags))
+	}
 	if err != nil {
 		return fmt.Errorf("start node '%s' not found: %w", startNodeID, err)
 	}
@@ -39,6 +43,9 @@ func ValidateGraph(loader ports.GraphLoader, parser *compiler.Parser, startNodeI
 
 	visited[actualStartID] = true
 
+	// Where each node was first linked from (for broken link positions)
+	linkedFrom := make(map[string]*domain.SourceRef)
+
 	var errors []string
 
 	for len(queue) > 0 {
@@ -48,7 +55,16 @@ func ValidateGraph(loader ports.GraphLoader, parser *compiler.Parser, startNodeI
 		// Load Node
 		raw, err := loader.GetNode(currentID)
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("Missing node or load error: '%s'", currentID))
+			// Strict-mode diagnostics already carry file:line; report them as-is.
+			if diags := domain.Diagnostics(err); len(diags) > 0 {
+				errors = append(errors, diagnosticLines(diags)...)
+				continue
+			}
+			msg := fmt.Sprintf("Missing node or load error: '%s'", currentID)
+			if src := linkedFrom[currentID]; src != nil {
+				msg += fmt.Sprintf(" (linked from %s)", src)
+			}
+			errors = append(errors, msg)
 			continue
 		}
 
@@ -68,14 +84,27 @@ func ValidateGraph(loader ports.GraphLoader, parser *compiler.Parser, startNodeI
 
 			if !visited[target] {
 				visited[target] = true
+				linkedFrom[target] = node.Source
 				queue = append(queue, target)
 			}
 		}
 	}
 
 	if len(errors) > 0 {
-		return fmt.Errorf("found %d errors:\n- %s", len(errors), strings.Join(errors, "\n- "))
+		return report(errors)
 	}
 
 	return nil
 }
+
+func report(errors []string) error {
+	return fmt.Errorf("found %d errors:\n- %s", len(errors), strings.Join(errors, "\n- "))
+}
+
+func diagnosticLines(diags []*domain.Diagnostic) []string {
+	lines := make([]string, 0, len(diags))
+	for _, d := range diags {
+		lines = append(lines, d.Error())
+	}
+	return lines
+}
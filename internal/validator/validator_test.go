@@ -1,11 +1,13 @@
 package validator
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
 	"github.com/aretw0/trellis/internal/compiler"
 	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
 )
 
 func TestValidateGraph(t *testing.T) {
@@ -57,3 +59,46 @@ func TestValidateGraph(t *testing.T) {
 		}
 	}
 }
+
+// diagnosticLoader fails to load one node with authoring diagnostics (as strict loaders do).
+type diagnosticLoader struct {
+	*memory.Loader
+	failing string
+}
+
+func (l diagnosticLoader) GetNode(id string) ([]byte, error) {
+	if id == l.failing {
+		return nil, errors.Join(
+			&domain.Diagnostic{File: id + ".md", Line: 3, Message: `unknown field "on_eror"; did you mean "on_error"?`},
+			&domain.Diagnostic{File: id + ".md", Line: 4, Message: `field "wait": expected boolean, got string "yes"`},
+		)
+	}
+	return l.Loader.GetNode(id)
+}
+
+func TestValidateGraph_Positions(t *testing.T) {
+	parser := compiler.NewParser()
+	loader := memory.NewLoader(map[string]string{
+		"start": `{
+			"id": "start",
+			"type": "text",
+			"source": {"file": "start.md", "line": 1},
+			"transitions": [{"to_node_id": "ghost"}, {"to_node_id": "typo"}]
+		}`,
+		"typo": `{"id": "typo", "type": "text"}`,
+	})
+
+	err := ValidateGraph(diagnosticLoader{Loader: loader, failing: "typo"}, parser, "start")
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{
+		"Missing node or load error: 'ghost' (linked from start.md:1)",
+		`typo.md:3: unknown field "on_eror"; did you mean "on_error"?`,
+		`typo.md:4: field "wait": expected boolean, got string "yes"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package git reads flows from a local git repository as of a given revision
+// (commit, tag or branch), without checking it out.
+//
+// A Snapshot is a read-only fs.FS, so it plugs into loam.NewFSLoader and
+// trellis.WithFS like an embedded flow:
+//
+//	snap, err := git.Open(ctx, "./flows/support", "v1.2.0")
+//	loader := loam.NewFSLoader(snap)
+//
+// It shells out to the `git` binary, which must be in PATH.
+package git
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot holds the files of a directory at a given commit.
+type Snapshot struct {
+	// Commit is the full hash the revision resolved to.
+	Commit string
+
+	files map[string][]byte
+	// dirs maps each directory ("." is the root) to its children (name -> is directory).
+	dirs map[string]map[string]bool
+}
+
+// Open resolves rev in the repository containing dir and reads the files under dir
+// as of that commit. Paths in the snapshot are relative to dir.
+func Open(ctx context.Context, dir, rev string) (*Snapshot, error) {
+	commit, err := Resolve(ctx, dir, rev)
+	if err != nil {
+		return nil, err
+	}
+	out, err := run(ctx, dir, "rev-parse", "--show-toplevel", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	toplevel, prefix, _ := strings.Cut(strings.TrimSuffix(out, "\n"), "\n")
+
+	// `<commit>:<prefix>` archives the subtree with paths relative to dir.
+	archive, err := run(ctx, toplevel, "archive", "--format=tar", commit+":"+prefix)
+	if err != nil {
+		return nil, fmt.Errorf("directory %q not found at revision %s: %w", strings.TrimSuffix(prefix, "/"), rev, err)
+	}
+
+	s := &Snapshot{Commit: commit, files: make(map[string][]byte), dirs: map[string]map[string]bool{".": {}}}
+	tr := tar.NewReader(strings.NewReader(archive))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read revision %s: %w", rev, err)
+		}
+		// Symlinks and submodules are not followed.
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s at revision %s: %w", hdr.Name, rev, err)
+		}
+		s.add(path.Clean(hdr.Name), data)
+	}
+	return s, nil
+}
+
+// Resolve returns the commit hash rev points to in the repository containing dir.
+func Resolve(ctx context.Context, dir, rev string) (string, error) {
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return "", fmt.Errorf("invalid revision %q", rev)
+	}
+	// Fails with git's own message outside a repository.
+	if _, err := run(ctx, dir, "rev-parse", "--git-dir"); err != nil {
+		return "", err
+	}
+	out, err := run(ctx, dir, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q", rev)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (s *Snapshot) add(name string, data []byte) {
+	s.files[name] = data
+	for child, isDir := name, false; child != "."; child, isDir = path.Dir(child), true {
+		parent := path.Dir(child)
+		if s.dirs[parent] == nil {
+			s.dirs[parent] = make(map[string]bool)
+		}
+		s.dirs[parent][path.Base(child)] = isDir
+	}
+}
+
+// Open implements fs.FS.
+func (s *Snapshot) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if data, ok := s.files[name]; ok {
+		info := fileInfo{name: path.Base(name), size: int64(len(data))}
+		return &file{info: info, Reader: bytes.NewReader(data)}, nil
+	}
+	children, ok := s.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	names := make([]string, 0, len(children))
+	for child := range children {
+		names = append(names, child)
+	}
+	sort.Strings(names)
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, child := range names {
+		info := fileInfo{name: child, dir: children[child]}
+		if !info.dir {
+			info.size = int64(len(s.files[path.Join(name, child)]))
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	return &dir{info: fileInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (s *Snapshot) ReadFile(name string) ([]byte, error) {
+	data, ok := s.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(data), nil
+}
+
+func run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
+
+type fileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) ModTime() time.Time { return time.Time{} }
+func (i fileInfo) IsDir() bool        { return i.dir }
+func (i fileInfo) Sys() any           { return nil }
+func (i fileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type file struct {
+	info fileInfo
+	*bytes.Reader
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return nil }
+
+type dir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dir) Close() error               { return nil }
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.offset += n
+	return rest[:n], nil
+}
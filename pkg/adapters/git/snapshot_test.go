@@ -0,0 +1,77 @@
+package git_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/adapters/git"
+)
+
+// initRepo creates a repository with two versions of flows/support, tagged v1 and v2,
+// plus an uncommitted change.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	testutils.GitCommit(t, root, map[string]string{
+		"flows/support/start.md":            "---\nto: billing/charge\n---\nHello v1",
+		"flows/support/billing/charge.yaml": "content: Charging\n",
+		"README.md":                         "not part of the flow",
+	}, "v1")
+	testutils.GitCommit(t, root, map[string]string{"flows/support/start.md": "Hello v2"}, "v2")
+	require.NoError(t, os.WriteFile(filepath.Join(root, "flows", "support", "start.md"), []byte("Uncommitted"), 0644))
+	return root
+}
+
+func TestOpen(t *testing.T) {
+	root := initRepo(t)
+	dir := filepath.Join(root, "flows", "support")
+	ctx := context.Background()
+
+	v1, err := git.Open(ctx, dir, "v1")
+	require.NoError(t, err)
+	require.NoError(t, fstest.TestFS(v1, "start.md", "billing/charge.yaml"))
+
+	data, err := v1.ReadFile("start.md")
+	require.NoError(t, err)
+	assert.Equal(t, "---\nto: billing/charge\n---\nHello v1", string(data))
+	_, err = v1.ReadFile("README.md")
+	assert.Error(t, err, "files outside the directory are not included")
+
+	v2, err := git.Open(ctx, dir, "v2")
+	require.NoError(t, err)
+	data, err = v2.ReadFile("start.md")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello v2", string(data), "the working tree is ignored")
+	assert.NotEqual(t, v1.Commit, v2.Commit)
+
+	head, err := git.Resolve(ctx, dir, "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, v2.Commit, head)
+	assert.Len(t, head, 40)
+
+	rootSnap, err := git.Open(ctx, root, "v1")
+	require.NoError(t, err)
+	_, err = rootSnap.ReadFile("flows/support/start.md")
+	assert.NoError(t, err, "the repository root works too")
+}
+
+func TestOpen_Errors(t *testing.T) {
+	root := initRepo(t)
+	ctx := context.Background()
+
+	_, err := git.Open(ctx, root, "v3")
+	assert.EqualError(t, err, `unknown revision "v3"`)
+
+	_, err = git.Open(ctx, root, "--output=x")
+	assert.EqualError(t, err, `invalid revision "--output=x"`)
+
+	_, err = git.Open(ctx, t.TempDir(), "v1")
+	assert.ErrorContains(t, err, "not a git repository")
+}
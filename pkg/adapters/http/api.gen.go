@@ -13,12 +13,45 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi/v5"
 	"github.com/oapi-codegen/runtime"
 )
 
+// Defines values for DebugCommandOp.
+const (
+	DebugCommandOpBreak       DebugCommandOp = "break"
+	DebugCommandOpBreakpoints DebugCommandOp = "breakpoints"
+	DebugCommandOpContinue    DebugCommandOp = "continue"
+	DebugCommandOpDelete      DebugCommandOp = "delete"
+	DebugCommandOpEval        DebugCommandOp = "eval"
+	DebugCommandOpGet         DebugCommandOp = "get"
+	DebugCommandOpInject      DebugCommandOp = "inject"
+	DebugCommandOpInput       DebugCommandOp = "input"
+	DebugCommandOpNext        DebugCommandOp = "next"
+	DebugCommandOpRestart     DebugCommandOp = "restart"
+	DebugCommandOpSet         DebugCommandOp = "set"
+	DebugCommandOpSignal      DebugCommandOp = "signal"
+	DebugCommandOpState       DebugCommandOp = "state"
+	DebugCommandOpStatus      DebugCommandOp = "status"
+	DebugCommandOpStep        DebugCommandOp = "step"
+	DebugCommandOpTemplate    DebugCommandOp = "template"
+	DebugCommandOpUnset       DebugCommandOp = "unset"
+)
+
+// Defines values for DebugStopReason.
+const (
+	DebugStopReasonBreakpoint DebugStopReason = "breakpoint"
+	DebugStopReasonEnd        DebugStopReason = "end"
+	DebugStopReasonError      DebugStopReason = "error"
+	DebugStopReasonInput      DebugStopReason = "input"
+	DebugStopReasonLimit      DebugStopReason = "limit"
+	DebugStopReasonStart      DebugStopReason = "start"
+	DebugStopReasonStep       DebugStopReason = "step"
+)
+
 // ActionRequest defines model for ActionRequest.
 type ActionRequest struct {
 	// Payload Dynamic payload depending on the action type.
@@ -28,9 +61,124 @@ type ActionRequest struct {
 	Type string `json:"type"`
 }
 
+// AsyncCall defines model for AsyncCall.
+type AsyncCall struct {
+	AcceptedAt     time.Time  `json:"accepted_at"`
+	Deadline       *time.Time `json:"deadline,omitempty"`
+	Handle         *string    `json:"handle,omitempty"`
+	Id             string     `json:"id"`
+	IdempotencyKey string     `json:"idempotency_key"`
+}
+
+// DebugBreakpoint Every field set must match for the breakpoint to stop the session.
+type DebugBreakpoint struct {
+	// Condition Stop when the condition holds for the last input (alone, when it becomes true).
+	Condition *string `json:"condition,omitempty"`
+	Hits      *int    `json:"hits,omitempty"`
+	Id        *int    `json:"id,omitempty"`
+
+	// Node Stop when the session enters the node.
+	Node *string `json:"node,omitempty"`
+
+	// Tool Stop when a call to the tool is pending, before it runs.
+	Tool *string `json:"tool,omitempty"`
+}
+
+// DebugCommand defines model for DebugCommand.
+type DebugCommand struct {
+	// Breakpoint Every field set must match for the breakpoint to stop the session.
+	Breakpoint *DebugBreakpoint `json:"breakpoint,omitempty"`
+
+	// Expr Condition (eval) or template (template).
+	Expr *string `json:"expr,omitempty"`
+
+	// Id Breakpoint to delete.
+	Id *int `json:"id,omitempty"`
+
+	// Input input: the value submitted. eval: the input the condition sees (default: the last input).
+	Input interface{} `json:"input"`
+
+	// Key Context key (`a.b` for nested keys, `sys.x` for the system context).
+	Key *string        `json:"key,omitempty"`
+	Op  DebugCommandOp `json:"op"`
+
+	// Result A fake tool result. The ID defaults to the call it answers.
+	Result *DebugToolResult `json:"result,omitempty"`
+	Signal *string          `json:"signal,omitempty"`
+
+	// Tool inject: the pending call to answer, by tool name (default: the first one without a result).
+	Tool *string `json:"tool,omitempty"`
+
+	// Value Value to set.
+	Value interface{} `json:"value"`
+}
+
+// DebugCommandOp defines model for DebugCommand.Op.
+type DebugCommandOp string
+
+// DebugReply defines model for DebugReply.
+type DebugReply struct {
+	Breakpoints *[]DebugBreakpoint `json:"breakpoints,omitempty"`
+	State       *State             `json:"state,omitempty"`
+	Stop        *DebugStop         `json:"stop,omitempty"`
+
+	// Value Result of get, eval and template.
+	Value interface{} `json:"value"`
+}
+
+// DebugSession defines model for DebugSession.
+type DebugSession struct {
+	SessionId string    `json:"session_id"`
+	Stop      DebugStop `json:"stop"`
+}
+
+// DebugSessionRequest defines model for DebugSessionRequest.
+type DebugSessionRequest struct {
+	// Context Initial context.
+	Context *map[string]interface{} `json:"context,omitempty"`
+
+	// SessionId ID of the session (generated when empty).
+	SessionId *string `json:"session_id,omitempty"`
+}
+
+// DebugStop defines model for DebugStop.
+type DebugStop struct {
+	// Breakpoint Every field set must match for the breakpoint to stop the session.
+	Breakpoint *DebugBreakpoint `json:"breakpoint,omitempty"`
+
+	// Content What the node renders.
+	Content *[]string `json:"content,omitempty"`
+	Ended   *bool     `json:"ended,omitempty"`
+	Error   *string   `json:"error,omitempty"`
+
+	// Injected IDs of the pending calls that have a fake result.
+	Injected *[]string       `json:"injected,omitempty"`
+	Node     string          `json:"node"`
+	Reason   DebugStopReason `json:"reason"`
+	Status   string          `json:"status"`
+
+	// ToolCalls Pending calls, run (or answered with the injected results) by the next move.
+	ToolCalls *[]map[string]interface{} `json:"tool_calls,omitempty"`
+
+	// WaitingInput The next move needs an input or a signal.
+	WaitingInput *bool `json:"waiting_input,omitempty"`
+}
+
+// DebugStopReason defines model for DebugStop.Reason.
+type DebugStopReason string
+
+// DebugToolResult A fake tool result. The ID defaults to the call it answers.
+type DebugToolResult struct {
+	Error    *string     `json:"error,omitempty"`
+	Id       *string     `json:"id,omitempty"`
+	IsDenied *bool       `json:"is_denied,omitempty"`
+	IsError  *bool       `json:"is_error,omitempty"`
+	Result   interface{} `json:"result"`
+}
+
 // NavigateRequest defines model for NavigateRequest.
 type NavigateRequest struct {
-	// Input The user input or tool result.
+	// Input The user input, a tool result, or a list of tool results (batch).
 	Input *NavigateRequest_Input `json:"input,omitempty"`
 	State State                  `json:"state"`
 }
@@ -38,7 +186,10 @@ type NavigateRequest struct {
 // NavigateRequestInput0 defines model for .
 type NavigateRequestInput0 = string
 
-// NavigateRequest_Input The user input or tool result.
+// NavigateRequestInput2 defines model for .
+type NavigateRequestInput2 = []ToolResult
+
+// NavigateRequest_Input The user input, a tool result, or a list of tool results (batch).
 type NavigateRequest_Input struct {
 	union json.RawMessage
 }
@@ -57,6 +208,12 @@ type State struct {
 	// Actions List of actions to be performed (e.g., render content).
 	Actions *[]ActionRequest `json:"actions,omitempty"`
 
+	// AsyncCalls Pending calls accepted by the Host for background completion.
+	AsyncCalls *[]AsyncCall `json:"async_calls,omitempty"`
+
+	// BatchResults Results already received for the current batch.
+	BatchResults *[]ToolResult `json:"batch_results,omitempty"`
+
 	// CurrentNodeId The identifier of the active node.
 	CurrentNodeId string `json:"current_node_id"`
 
@@ -71,30 +228,65 @@ type State struct {
 
 	// PendingToolCall ID of a tool call being waited on.
 	PendingToolCall *string `json:"pending_tool_call,omitempty"`
-	SessionId       *string `json:"session_id,omitempty"`
+
+	// PendingToolCalls IDs of batch tool calls still being waited on.
+	PendingToolCalls *[]string `json:"pending_tool_calls,omitempty"`
+	SessionId        *string   `json:"session_id,omitempty"`
 
 	// Status Current lifecycle status of the State.
 	Status *string `json:"status,omitempty"`
 
+	// SystemContext System-level state (e.g. usage ledger and budget). Round-trip it unchanged.
+	SystemContext *map[string]interface{} `json:"system_context,omitempty"`
+
 	// Terminated Indicates if the execution has reached a sink state.
 	Terminated *bool `json:"terminated,omitempty"`
 }
 
+// ToolCancellation defines model for ToolCancellation.
+type ToolCancellation struct {
+	// Reason Why the call was cancelled; appended to the error the node receives.
+	Reason *string `json:"reason,omitempty"`
+}
+
+// ToolCompletion defines model for ToolCompletion.
+type ToolCompletion struct {
+	// IdempotencyKey The idempotency key of the original ToolCall.
+	IdempotencyKey string     `json:"idempotency_key"`
+	Result         ToolResult `json:"result"`
+}
+
 // ToolResult defines model for ToolResult.
 type ToolResult struct {
-	Error *string `json:"error,omitempty"`
+	// Accepted The call was started in the background; its result will be delivered later.
+	Accepted *bool   `json:"accepted,omitempty"`
+	Error    *string `json:"error,omitempty"`
+
+	// Handle Optional Host-side reference for an accepted call (job ID, ticket, ...).
+	Handle *string `json:"handle,omitempty"`
 
 	// Id Corresponds to the ToolCall ID.
-	Id      string `json:"id"`
-	IsError *bool  `json:"is_error,omitempty"`
+	Id       string `json:"id"`
+	IsDenied *bool  `json:"is_denied,omitempty"`
+	IsError  *bool  `json:"is_error,omitempty"`
 
 	// Result The output of the tool execution.
 	Result interface{} `json:"result"`
+
+	// Usage Optional metering metadata reported by the tool.
+	Usage *ToolUsage `json:"usage,omitempty"`
+}
+
+// ToolUsage Optional metering metadata reported by the tool.
+type ToolUsage struct {
+	Cost   *float64 `json:"cost,omitempty"`
+	Tokens *int64   `json:"tokens,omitempty"`
+	Units  *float64 `json:"units,omitempty"`
 }
 
 // SubscribeEventsParams defines parameters for SubscribeEvents.
 type SubscribeEventsParams struct {
-	// SessionId Session ID to subscribe to for state updates
+	// SessionId Session ID to subscribe to for state updates (`debug:<id>` for a debug session)
 	SessionId *string `form:"session_id,omitempty" json:"session_id,omitempty"`
 
 	// Watch Comma-separated list of fields to watch (e.g. "context,history")
@@ -108,12 +300,24 @@ type SignalJSONBody struct {
 	State  State  `json:"state"`
 }
 
+// CreateDebugSessionJSONRequestBody defines body for CreateDebugSession for application/json ContentType.
+type CreateDebugSessionJSONRequestBody = DebugSessionRequest
+
+// ExecDebugCommandJSONRequestBody defines body for ExecDebugCommand for application/json ContentType.
+type ExecDebugCommandJSONRequestBody = DebugCommand
+
 // NavigateJSONRequestBody defines body for Navigate for application/json ContentType.
 type NavigateJSONRequestBody = NavigateRequest
 
 // RenderJSONRequestBody defines body for Render for application/json ContentType.
 type RenderJSONRequestBody = State
 
+// CancelToolJSONRequestBody defines body for CancelTool for application/json ContentType.
+type CancelToolJSONRequestBody = ToolCancellation
+
+// CompleteToolJSONRequestBody defines body for CompleteTool for application/json ContentType.
+type CompleteToolJSONRequestBody = ToolCompletion
+
 // SignalJSONRequestBody defines body for Signal for application/json ContentType.
 type SignalJSONRequestBody SignalJSONBody
 
@@ -169,6 +373,32 @@ func (t *NavigateRequest_Input) MergeToolResult(v ToolResult) error {
 	return err
 }
 
+// AsNavigateRequestInput2 returns the union data inside the NavigateRequest_Input as a NavigateRequestInput2
+func (t NavigateRequest_Input) AsNavigateRequestInput2() (NavigateRequestInput2, error) {
+	var body NavigateRequestInput2
+	err := json.Unmarshal(t.union, &body)
+	return body, err
+}
+
+// FromNavigateRequestInput2 overwrites any union data inside the NavigateRequest_Input as the provided NavigateRequestInput2
+func (t *NavigateRequest_Input) FromNavigateRequestInput2(v NavigateRequestInput2) error {
+	b, err := json.Marshal(v)
+	t.union = b
+	return err
+}
+
+// MergeNavigateRequestInput2 performs a merge with any union data inside the NavigateRequest_Input, using the provided NavigateRequestInput2
+func (t *NavigateRequest_Input) MergeNavigateRequestInput2(v NavigateRequestInput2) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	merged, err := runtime.JSONMerge(t.union, b)
+	t.union = merged
+	return err
+}
+
 func (t NavigateRequest_Input) MarshalJSON() ([]byte, error) {
 	b, err := t.union.MarshalJSON()
 	return b, err
@@ -181,6 +411,18 @@ func (t *NavigateRequest_Input) UnmarshalJSON(b []byte) error {
 
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Start a debug session
+	// (POST /debug/sessions)
+	CreateDebugSession(w http.ResponseWriter, r *http.Request)
+	// End a debug session
+	// (DELETE /debug/sessions/{sessionId})
+	DeleteDebugSession(w http.ResponseWriter, r *http.Request, sessionId string)
+	// Show where a debug session is paused
+	// (GET /debug/sessions/{sessionId})
+	GetDebugSession(w http.ResponseWriter, r *http.Request, sessionId string)
+	// Run a debugger command
+	// (POST /debug/sessions/{sessionId})
+	ExecDebugCommand(w http.ResponseWriter, r *http.Request, sessionId string)
 	// Subscribe to server-sent events for graph changes
 	// (GET /events)
 	SubscribeEvents(w http.ResponseWriter, r *http.Request, params SubscribeEventsParams)
@@ -199,6 +441,12 @@ type ServerInterface interface {
 	// Get the current view (actions) for a given state
 	// (POST /render)
 	Render(w http.ResponseWriter, r *http.Request)
+	// Cancel a tool call awaiting its async result
+	// (POST /sessions/{sessionId}/tool-calls/{callId}/cancel)
+	CancelTool(w http.ResponseWriter, r *http.Request, sessionId string, callId string)
+	// Deliver the result of a tool call accepted for async completion
+	// (POST /sessions/{sessionId}/tool-results)
+	CompleteTool(w http.ResponseWriter, r *http.Request, sessionId string)
 	// Send a global signal to the state machine
 	// (POST /signal)
 	Signal(w http.ResponseWriter, r *http.Request)
@@ -208,6 +456,30 @@ type ServerInterface interface {
 
 type Unimplemented struct{}
 
+// Start a debug session
+// (POST /debug/sessions)
+func (_ Unimplemented) CreateDebugSession(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// End a debug session
+// (DELETE /debug/sessions/{sessionId})
+func (_ Unimplemented) DeleteDebugSession(w http.ResponseWriter, r *http.Request, sessionId string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Show where a debug session is paused
+// (GET /debug/sessions/{sessionId})
+func (_ Unimplemented) GetDebugSession(w http.ResponseWriter, r *http.Request, sessionId string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Run a debugger command
+// (POST /debug/sessions/{sessionId})
+func (_ Unimplemented) ExecDebugCommand(w http.ResponseWriter, r *http.Request, sessionId string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Subscribe to server-sent events for graph changes
 // (GET /events)
 func (_ Unimplemented) SubscribeEvents(w http.ResponseWriter, r *http.Request, params SubscribeEventsParams) {
@@ -244,6 +516,18 @@ func (_ Unimplemented) Render(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Cancel a tool call awaiting its async result
+// (POST /sessions/{sessionId}/tool-calls/{callId}/cancel)
+func (_ Unimplemented) CancelTool(w http.ResponseWriter, r *http.Request, sessionId string, callId string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Deliver the result of a tool call accepted for async completion
+// (POST /sessions/{sessionId}/tool-results)
+func (_ Unimplemented) CompleteTool(w http.ResponseWriter, r *http.Request, sessionId string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Send a global signal to the state machine
 // (POST /signal)
 func (_ Unimplemented) Signal(w http.ResponseWriter, r *http.Request) {
@@ -259,6 +543,95 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.Handler) http.Handler
 
+// CreateDebugSession operation middleware
+func (siw *ServerInterfaceWrapper) CreateDebugSession(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateDebugSession(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// DeleteDebugSession operation middleware
+func (siw *ServerInterfaceWrapper) DeleteDebugSession(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "sessionId" -------------
+	var sessionId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "sessionId", chi.URLParam(r, "sessionId"), &sessionId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sessionId", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteDebugSession(w, r, sessionId)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// GetDebugSession operation middleware
+func (siw *ServerInterfaceWrapper) GetDebugSession(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "sessionId" -------------
+	var sessionId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "sessionId", chi.URLParam(r, "sessionId"), &sessionId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sessionId", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetDebugSession(w, r, sessionId)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ExecDebugCommand operation middleware
+func (siw *ServerInterfaceWrapper) ExecDebugCommand(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "sessionId" -------------
+	var sessionId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "sessionId", chi.URLParam(r, "sessionId"), &sessionId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sessionId", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ExecDebugCommand(w, r, sessionId)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // SubscribeEvents operation middleware
 func (siw *ServerInterfaceWrapper) SubscribeEvents(w http.ResponseWriter, r *http.Request) {
 
@@ -364,6 +737,65 @@ func (siw *ServerInterfaceWrapper) Render(w http.ResponseWriter, r *http.Request
 	handler.ServeHTTP(w, r)
 }
 
+// CancelTool operation middleware
+func (siw *ServerInterfaceWrapper) CancelTool(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "sessionId" -------------
+	var sessionId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "sessionId", chi.URLParam(r, "sessionId"), &sessionId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sessionId", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "callId" -------------
+	var callId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "callId", chi.URLParam(r, "callId"), &callId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "callId", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CancelTool(w, r, sessionId, callId)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// CompleteTool operation middleware
+func (siw *ServerInterfaceWrapper) CompleteTool(w http.ResponseWriter, r *http.Request) {
+
+	var err error
+
+	// ------------- Path parameter "sessionId" -------------
+	var sessionId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "sessionId", chi.URLParam(r, "sessionId"), &sessionId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "sessionId", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CompleteTool(w, r, sessionId)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // Signal operation middleware
 func (siw *ServerInterfaceWrapper) Signal(w http.ResponseWriter, r *http.Request) {
 
@@ -491,6 +923,18 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/debug/sessions", wrapper.CreateDebugSession)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/debug/sessions/{sessionId}", wrapper.DeleteDebugSession)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/debug/sessions/{sessionId}", wrapper.GetDebugSession)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/debug/sessions/{sessionId}", wrapper.ExecDebugCommand)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/events", wrapper.SubscribeEvents)
 	})
@@ -509,6 +953,12 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/render", wrapper.Render)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/sessions/{sessionId}/tool-calls/{callId}/cancel", wrapper.CancelTool)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/sessions/{sessionId}/tool-results", wrapper.CompleteTool)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/signal", wrapper.Signal)
 	})
@@ -519,33 +969,69 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/8xYW28bvRH9KwTbBxuQJTVJgUBvqW0kQlPHtZyn2BCo3ZGWCZfckLOyBUP/vZjhri4r",
-	"ymnifECebIm3mXPO3PQkM1dWzoLFIEdPMmQFlIr/fZehdvYGvtcQkL6ovKvAowZertTKOJXTvzmEzOuK",
-	"tsuRvFhZVepMNBtEDhXYXNuFcFZgAULxxQJXFfRlT9raGDUzIEfoa1j3JC0cXntbAB8Rbt7ecAL9Rb8n",
-	"bi6vLi5vpuefrm4vr27p838/X05up+Or68+3p/REvFEG9Nou5Hrdkx6+19pDLkdf4ur9ZpebfYUM5bon",
-	"r9RSLxTCUQy0rWpMm1oH8ILXhfMCnTPCQ6gNkj3Owqe5HH156lrWe5J/9zCXI/m3wZaYQcPK4NY5c8O3",
-	"yPX9uicDKmSonjs04U1dp+PRlNc3YHPwNxAqZwMcOh3Bj/4jlOFH7+/raMOvVN6rlfxJL3oSwZfaKpOA",
-	"3dcg9Jw1ltXeg0VhXQ6iUEFYJ1yNC0c6RK9s0OzFjjpmzhlQlpE6AGXS2ngUi31bPuqAW6UGgU7MQFTg",
-	"586XkLfS9Yy1yJxFsMha/T2YNv5Pyf+pztMa1TlY1HMNnkxtQ3MJjFoibnqy0AGdX6WwVxmH5lIHjZDz",
-	"FWHPn4PLujYblymTiPxrD3PwHnJhlF3UagFiTjFVgAgQgnY2aWsJZWOqynNmW5nrHfYo2/Q6T/0bVmdL",
-	"ZWoQ5Gd8p3lDLJXXlKd2NbMVSJPjphTp00yZhD7HF6yImAxoi5gByfFBMWJH3Gieb0g8XEaFdUKA500A",
-	"GD2HbJUZ8oh2tkyzoJMPNgGGkFDN2OY6UwihjTN4hKzmZExB5kFlBeRCiaDtN34RjkTYbi7qijWVlXZS",
-	"30EUgvfOJ9FJKf/cec/JLeewJC/o8nMiZHyRhESHafeNjS/kSmvWYYi5GrkERLSY+Q1kieLXASaJBW3S",
-	"du4OH3x3PWbFaovgKZRJXBqL6KMHY3SIxIv/qKzQFsSlXdAfbYkzWjEQgiiVteD7d5bA0Ejmyb3zvGsC",
-	"fgle9uQSfIgWDPuv+kPCxFVgVaXlSL7uD/uvZU9WCgtmawDLtttYAMNGXCryYZzLkZzUM3JqBpdxHx31",
-	"qgQEH7hm7js9acJzfEFshvYwfeDoZXfrKifVUjqiM99r8CtCX5VM9DbEek0HlFATFeeukspSnQUg+yiC",
-	"TZP05xpMFNeDwqyIyV7cSU7zj9hrsuidPD1iEB971pZ7lh1XaEby1XBIf5pCwifgESPUZwE9qHLb3nHM",
-	"PKqyYmJzhWokPFCzdmcj553X1t08ydSIeK+AgGpmdCgg59AOdVkqyrxbKpkalstZoJNRAkzQwquqEFmh",
-	"7AICnx/wV0f18R7wPW/4IQSqqgylK+3s4Gsgw3cR2BSm58rDQR7aL1qHyFDQz2tjGr9ymGvLt3egGVv0",
-	"LlSQIUcnH4laLZvQjCgwIAUog88i8iHueCEk+2l1W1q2YnHfku10IkV1o5TIFzoIZfQSOmB81EuwlFIq",
-	"72ZxcdCmuGMej2n9t/qrKj3dpLJdp4f9f/SHqbqgqmp/J8YkeVYgVqkDR65/zRny12EtARWFcTf89leF",
-	"srloLBAEry/VRpkD2ww7jIsLCdDbcUjGIgUB/+Xy1U8B/lxP25221vvVsBkOX8T3c893xp4E2FfwEEOU",
-	"mHwTn+62R0tldB7nPtr1z/QuBG+VaTKiiK3FPnW3mxGl7VEsPGKTIWYqcL/YvkP0xUniOHnRvb+IunbE",
-	"/MMIm9RZBiHMa9MMWk0UPstdhPjlDL4H3JtElxoexEkzD55y6VNiQYmvFRXRGPSimW3TNE7i+q/T2Enx",
-	"m+cOqxj1Im3jGve1U+ud5A7T1xXeSfqIugRXY2xokiPKi36l6LV2ppvhPzJHiJPtrwyQn/7/KePN8M3h",
-	"rki7sA5FoWxuIBcn1u38kHH6Iq1OwNLQtjBuRhvjY03e2WtKosvxplQz/pFGeHEBy+1wUHsjR5IK4mgw",
-	"4BG/cAFHT5XzuKbhoR2ro+h9M0jNFc9U8u3wLZXeZNnj3etUY97E3AcXUJzcgFGol3C6sWYg1/fr/wUA",
-	"AP//P14xFvsUAAA=",
+	"H4sIAAAAAAAC/8xbX3PbNhL/KhjePUgztOw2uZtWfUpjT+q5NslZzvWhzlgQsZJQkwALgLI1GX33m12A",
+	"lEhCspzEbV8SmwTxZ//8dve38Kck00WpFShnk/GnxGZLKDj9+CpzUqsr+KMC6/BBaXQJxkmg1yVf55oL",
+	"/FGAzYwscXgyTs7XihcyY2EAE1CCElItmFbMLYFxmpi5dQmjJE1Uled8lkMydqaCTZrgi/6010ugT5ie",
+	"1zMMYLQYpezq4u35xdXt63dvry/eXuPv//1wMbm+vXz7/sP1EJfwMybWGakWyWaTJgb+qKQBkYx/828/",
+	"NqP07HfIXLJJk1d2rbLXPM/7p+dZBqUDcctJNHNtCvwpEdzBiZMF9FdNEwFc5FLB8V8suRI5je+9kmLP",
+	"YyhK7UBl69s7WEfGdE4vRdL/Km0dMCabc5hVix8N8LtSS+X6+rpYgVmzuYRcMAuOFZV1rOAuW7K5NmQI",
+	"s+Zz5jSzTpf02IK1UitUXFvomVZC+um7q03w4/sleAtrBrKlzoVtFsy5dUyqsnJswHOtIPXfSMdmkOkC",
+	"LEMbjNlMmiyld5HwQioHCzA9Vew8V1rAY1sNh2WgHBhLj/Cz6A6c1vmh+TjLeJ6jLHEaHM2kZcH7UjaD",
+	"uTaAhzWVsnG/iOv5tS4KrkTfDWYtA/ingXkyTv5xusWU0wAop1172aQJPJSmf5zXje4GsOL5kKHuoChz",
+	"7oAN6p/iKpIROPqxZWQCcnC70t1VIxpGfwJ6PCaRrnheAbPVrJDOgRgx3KF/5a2qbXwWwLKBgDmvcjfu",
+	"WOAwin3BZXsicfDg2B2s2WDKR7MpWbQC60DgU5uyqV3b0cO0MXW7tg4KlvlP4+LSJa4FqioQCKyDErcE",
+	"Dy5J0decVBUktVzSxMqF4nmSJgas44YeOe4qG37AsWQQSZp4OdcPSPw4bAH0Ff1bKf8/yhB3FzRLC5L1",
+	"fYxs2YCt8uOM7Vrr/MoP3zSbj0Fm3K38JrzW6gBWuxdX9h5MymZr72WKF9BR9Fwa65hWwO6lW+rKMc78",
+	"5uO6INPq7+J/+BiXtOAiBtPBcl3ux+orKPP1IQ+mX6WDwn6GL4c1uTF8nWxqe3hkngkNotG6fGwwLYpY",
+	"d0BaXt2YISzApeSdjCvR4EdcgnF5TTww9yUWEPt2TwB+4lk6GtyZPEz18ZEN7k3Qguvjj1x4ROL5+50h",
+	"eP60I8FLJZ3keY0boySyelsAne/PUfq7gW2wAAWGI1JRkIKidOvhU6LPJEj0K4YeOl4sa/l1yV0ThJkB",
+	"JcBQqGwcow8fHdPHb3ZNY6Z1DlzRK2O0iU7i0QaiErW1SHdhCFMF7tiSr4BxNud3EODlaZutU5QI0HKr",
+	"VTs+1JhPcWJH/tsQAUok9THTJJeFjMN4iBv70PiWTtiXxftdAaSYxrCBNgGO0cCkW4Zo7MUZZGKHBNWo",
+	"VoyjhV5BS0yH/KNnk10R3nPppFrc7kkfrndXZQpAWMZVyBdw88yHph2PaCymAw5BJ0FrjRT3IsROBOxt",
+	"65W3GQpfwXAYbvXynIU4ZutEkqKedEHMtp+XHzDrPUWKvRWg5D43kfa2O+XO220KcASUv+UrueAO9qLk",
+	"Aa1VFozXU8r4rqRSr7dcWoo1O28sG8ywxCF80wrezZPxb30BHMarVuJyZEhufdMx0Y9PjMfdkERPY0Z2",
+	"RfB4BbbUykKsSEZpHp9XtBmHL84qHJhChqSvo11TAZMeVLPKGFDOA/6SW6Y005VbaAQaZ7iyBA12j3/2",
+	"hDKp97hXFu29/BysKAxAp5sBK8EgQwCiJjl8KGIhcA1b8PVFMuVIchwFuKzmBGow/UlbRwXHjGd3C6Mr",
+	"JRhuIQcX6vfjttjQLJHtkTvdBu/al/FZxnMDXKyZgQzkCkRTB9XqpXmO3tIhd0qTMOctmkw0C0L0kAKU",
+	"k3MJpg7fqOHVgfJ+Ka3TZh0zV54R77WSVqL8cYonZiW5zngeSZnfG5iDweCZc7Wo+AK2JeSWh+lNX0AR",
+	"tnp8bvkfWJ+ECtpp49cJa7AVNxKh3EYzzpD43Da5wb7EM8A0DmEzQMvF6AyC7TlGb2K7NwEjA9pOb5l1",
+	"Mr7K8Up5tJSos6QOHxBsOpdzyNZZDsyPrA2NICh6Xs8K3H5WaTChb09yWEFOC4LHJlZZtJocxAIMlVuz",
+	"SizADUfsCiHhxBlZYv5QqWzJ1QJEVMcBreM5sBIy4w5sDdrwAFnlCT5umQGeLUFQKqXu/N6OSKe6bhwL",
+	"cQgEr7nKIM+5i5aD20S5W0mst9nTPbcs89OA+IHxsqQqoc6wKNvZLTsIxI6l6GiPDez2dxihhKNwVQ8i",
+	"nimYkjZygTGUeUHkedSsjmNldkG1R0J3+ecw5T6dbNPaOC8fP2SjC6plQDDpSdht/PqBSWdDMsfuvYMj",
+	"aShX4DHSgYmZ1qHabkvit3f0rvSOR3H0xErS/BwMqMyDI1fbkEtbH/yuZ+zyPGVOZnfgUjYajY5nQl9r",
+	"YyhXE01qX2uVXZ7Hp/k6iXpfE7pyVP/Mt1R149NRYpRA5hgL+0AD+wa215Q+1DPv0U4BDlAe+AMX3HFm",
+	"oNRmJw/C7ce6FbbTG9IVnqjZhqqKmWeenb4DZVuDpXL/fhmlqSslXXvsvon7YIGPpJrrSD34/pJsDhcy",
+	"mKmoxbacvjaQ59L6wMJ+4dlSKmAXaoH/SWw8EObmYC0ruFJgRjcK9yMdqjBpfU+jJmBWYJI0WYHxNFty",
+	"Nvp2dOZpaVC8lMk4eTE6G71AwXK3pCOfCqxrT0PcpEdlEHO3KcINpoVNglFR+oyHsQ5KRvNgvBpMXdic",
+	"xS2xk5P61XSYspJXFgMLcUI3CpQza5/AsUuHzRVh5ArUVlIWmeDMt0ss45Y109O0Uy8XNBMKJ5cC/dIA",
+	"d9AiHb3xgnU/arFuyDxPWPGyzDEaSq1Ofw+hxzvAcbRjmzbcbLyr+BqOJPrt2TfPsqQ3v46egnoCIKP6",
+	"X56dxTKAFc+lYEEsbIZyocHfx7gNEnaj+6AdaZHdqCsFeJDWWZzjX/EFHRh0fzIL40O0H/1NxNz8IKVd",
+	"E1oak2hMDRQimiC/tFVRcLOuLZXx9pZpTMfYTz+Fny7Fxu+AWizjTx1zOqfnPXNqKfhl7Ah1I1LUeoiM",
+	"Om8JFs87x7D5HIK5UKIvFt9C6p35DbjDBz770yy6Ts5taFX81WKcLPU9Mu8GutKk5jABHK5bcsMp1Fmi",
+	"rCQui7ibpIniBaUFtfklu7HVlwpb2XUT1o/pHoj+Ra/AtlsF1kGZEluasroBmdYUnKdJUxa6j8MUQw1f",
+	"gN25R2DTG4XubakOASGdXyEUPL4lVFEl0bRpbatFZInc8/yxDcz6jWrRpCHuzbnMKwOW3S9lDkjvYszk",
+	"Brb5QUgv6WbDwBcKbEpIMh3GIsHFA2SthvszxoF6ic1m01Xn5rndx/ciY87jNxVE/Wg0yOozPN3NooED",
+	"M9N5ru89CLJBMLWWkTpNfQfkwxdcquFz+OxVpRjfDmqOiUEBVvVdrSgSTqoZbmIGF35cz7HjqH95jgez",
+	"9cf4C9EzlPBVpSCXGUxpS+Ob6uzsRSYF/Q/+zkEHW4bUFErGyR8VmHUPQ0J/cy9opJ9ihnFiAQ+Dkqy5",
+	"d7pfROXMPfEznpG4qZufaeDUbpJ9G6LPkkcA7BFvwJW8Xk6sM8CLtjvAA8fi3N/14mNmAO/F3SifIndW",
+	"67kE6ZH5eRna4yyXdtmH+V3V+ZzlxOKX3l5IRwvDyyXzJIz11kSP9hrTG3BvaMAXAsLXaLT1JUPeWuV5",
+	"OJeAuVQ0e0c0l8oZbUvIvCPTJ96wi1DJeCmQQJbAc3dQIj/5EV8oks6dgobp2xqLvjuG/oml1IQ7Eklx",
+	"uYKOMH7GegWsZaXRM//ytK4I9534Et9/1fPyUt42ld/uoc9G34zOYkwEL8v2yFBXnSydK2Mf7Jn+xehF",
+	"f/QTxFqTAF33a7+llCLsgEnlK/Umr1ehKbm/fK3bGui1nd4LPqKezU6rJWQdoedNUebl2fd0EWnNFkj1",
+	"GF0tljdq+v7d5JpFS4pTzHFOQqNlihcjZYZgAdmdz6G6DCEekari+lrriP26vdVI4ljy3RKcWP+U3iu4",
+	"Dy6IhTdfeSpUulhSVLdwnykZ6naI/+R8qNNDjRje21pWj6ZDvpe9L7kJl6N8sCY6kYerCz17empV3HKF",
+	"66ZnWrOMdP3Bq3vGLfVJ6r2iO/jW5q4ztA3Ai+iZ1F/3vP9mSp9UWQbWzqs8dH4Dqh3Uf/Co2go+W4Nv",
+	"wLV6pysJ92wQGtTDYEELIr5sc2XgAKgQgp1+wv/wmW+E7Ae/VzPtuTsyUlffyKJuM8/ulL6nXlMAummN",
+	"jGO6uz0dsSaLRzArgGg4Ob9RDdO85IKqNxAps5pNtfI09pRuE01evXnFjM5z7AoMGWkZbJS4o4Mgh5w8",
+	"X/2cRufysnxqIf4cDtTrkEUZxbPncNh4KeO1/jlU4gFW7PHa8a0O7aUW2YgIa5urv1+fbcy0mstF1Vx+",
+	"60TbjmN7PbW65E0MoH1SHDDbHt3jmcJ+Nw4tSfhCT67PQ38/MoMb5Vkf0RQ5UuDd68pgIe1PjZ3n+l6I",
+	"Af9HJ75bk/leF+UunXzmhxtVKdySSpmovHUCckH0hwdmZ75eohXFhnD6Z0aH53TqnXTgzw2Pf1cXDxlU",
+	"o/9BMBi0kq3JoI2llCEX0pLtpaxOkRk8ZAACxPCvhoJz39MmfzTNtfkWMhyqOAI6NH9UEc/cJtu/GPk8",
+	"G+1Uyc1ykSu22Hirr57TuPrm3A01T42pSneT4K9OFqAr5zmh6KWbL7op2fytycdoVfm3LC3YYHvTMRjn",
+	"sZVGzKO8/NEo/d0HwQZK71ymHH5RejoB6gwtcj3DgX6xUGq0eB1/ZD9TjPz8Ge/EsXNYbdvRlcmTcYKc",
+	"wvj0lO7MLbV140+lNm6D7er6npo3ehPiHl2YTsbJd2ffIXsR9VEavYlxmyHNprA4uALMo1YwbHZzild4",
+	"/z8AATVdxqk7AAA=",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/aretw0/trellis/pkg/debugger"
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// debugSession is the DebugSession response.
+type debugSession struct {
+	SessionID string        `json:"session_id"`
+	Stop      debugger.Stop `json:"stop"`
+}
+
+// writeDebugError maps debugger errors to status codes.
+func writeDebugError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, debugger.ErrSessionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, debugger.ErrSessionExists), errors.Is(err, debugger.ErrEnded):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, debugger.ErrInvalidCommand):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeEngineError(w, "Debugger error", err)
+		slog.Error(op+" failed", "error", err)
+	}
+}
+
+func (s *Server) debuggerEnabled(w http.ResponseWriter) bool {
+	if s.Debugger == nil {
+		http.Error(w, "The debugger is not enabled on this server (trellis serve --debugger)", http.StatusNotImplemented)
+		return false
+	}
+	return true
+}
+
+// CreateDebugSession handles the POST /debug/sessions request.
+func (s *Server) CreateDebugSession(w http.ResponseWriter, r *http.Request) {
+	if !s.debuggerEnabled(w) {
+		return
+	}
+	var body CreateDebugSessionJSONRequestBody
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			slog.Warn("CreateDebugSession: Invalid request body", "error", err)
+			return
+		}
+	}
+	var sessionID string
+	if body.SessionId != nil {
+		sessionID = *body.SessionId
+	}
+	var initialContext map[string]any
+	if body.Context != nil {
+		initialContext = *body.Context
+	}
+
+	sess, err := s.Debugger.Create(r.Context(), sessionID, initialContext)
+	if err != nil {
+		writeDebugError(w, "CreateDebugSession", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(debugSession{SessionID: sess.ID(), Stop: sess.Stop()}); err != nil {
+		slog.Error("CreateDebugSession response encode failed", "error", err)
+	}
+}
+
+// GetDebugSession handles the GET /debug/sessions/{sessionId} request.
+func (s *Server) GetDebugSession(w http.ResponseWriter, r *http.Request, sessionId string) {
+	if !s.debuggerEnabled(w) {
+		return
+	}
+	sess, err := s.Debugger.Get(sessionId)
+	if err != nil {
+		writeDebugError(w, "GetDebugSession", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(debugSession{SessionID: sess.ID(), Stop: sess.Stop()}); err != nil {
+		slog.Error("GetDebugSession response encode failed", "error", err)
+	}
+}
+
+// DebugStreamPrefix namespaces the event streams of debug sessions: their IDs are chosen by
+// clients like session IDs, so a debug session must not publish on a real session's stream.
+const DebugStreamPrefix = "debug:"
+
+// ExecDebugCommand handles the POST /debug/sessions/{sessionId} request.
+// Moves are broadcast to the event stream DebugStreamPrefix + sessionId, like Navigate.
+func (s *Server) ExecDebugCommand(w http.ResponseWriter, r *http.Request, sessionId string) {
+	if !s.debuggerEnabled(w) {
+		return
+	}
+	var cmd debugger.Command
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		slog.Warn("ExecDebugCommand: Invalid request body", "error", err)
+		return
+	}
+	sess, err := s.Debugger.Get(sessionId)
+	if err != nil {
+		writeDebugError(w, "ExecDebugCommand", err)
+		return
+	}
+
+	previous := sess.State()
+	reply, err := sess.Exec(r.Context(), cmd)
+	if err != nil {
+		writeDebugError(w, "ExecDebugCommand", err)
+		return
+	}
+	if reply.Stop != nil {
+		if diff := domain.Diff(previous, sess.State()); diff != nil {
+			if bytes, err := json.Marshal(diff); err == nil {
+				s.Streams.Broadcast(DebugStreamPrefix+sessionId, string(bytes))
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reply); err != nil {
+		slog.Error("ExecDebugCommand response encode failed", "error", err)
+	}
+}
+
+// DeleteDebugSession handles the DELETE /debug/sessions/{sessionId} request.
+func (s *Server) DeleteDebugSession(w http.ResponseWriter, r *http.Request, sessionId string) {
+	if !s.debuggerEnabled(w) {
+		return
+	}
+	if err := s.Debugger.Delete(sessionId); err != nil {
+		writeDebugError(w, "DeleteDebugSession", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
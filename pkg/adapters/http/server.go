@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -12,9 +13,11 @@ import (
 
 	"github.com/aretw0/trellis"
 	"github.com/aretw0/trellis/pkg/adapters/http/ui"
+	"github.com/aretw0/trellis/pkg/debugger"
 	"github.com/aretw0/trellis/pkg/domain"
 	"github.com/aretw0/trellis/pkg/ports"
 	"github.com/aretw0/trellis/pkg/runner"
+	"github.com/aretw0/trellis/pkg/session"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -30,19 +33,79 @@ type Engine interface {
 type Server struct {
 	Engine  Engine
 	Streams *StreamManager
+	// Sessions enables server-side session operations (async tool completion).
+	Sessions *session.Manager
+	// Debugger enables the /debug routes driving step-debug sessions.
+	Debugger *debugger.Manager
+	// MaxInputSize limits text input in bytes (0 uses the runner default).
+	MaxInputSize int
 }
 
 // Ensure Server implements ServerInterface
 var _ ServerInterface = (*Server)(nil)
 
+// HandlerOption configures optional routes of the HTTP handler.
+type HandlerOption func(*handlerConfig)
+
+type handlerConfig struct {
+	metrics  http.Handler
+	sessions *session.Manager
+	debugger *debugger.Manager
+	maxInput int
+}
+
+// WithMetricsHandler exposes the given handler (e.g. promhttp) at GET /metrics.
+func WithMetricsHandler(h http.Handler) HandlerOption {
+	return func(c *handlerConfig) {
+		c.metrics = h
+	}
+}
+
+// WithSessionManager enables routes that operate on persisted sessions,
+// such as POST /sessions/{id}/tool-results. The manager must be configured
+// with the engine (session.WithEngine).
+func WithSessionManager(m *session.Manager) HandlerOption {
+	return func(c *handlerConfig) {
+		c.sessions = m
+	}
+}
+
+// WithDebugger enables the /debug routes, through which clients such as the web
+// inspector drive step-debug sessions with the same commands as `trellis debug`.
+func WithDebugger(m *debugger.Manager) HandlerOption {
+	return func(c *handlerConfig) {
+		c.debugger = m
+	}
+}
+
+// WithMaxInputSize limits text input in bytes, such as the manifest server.max_input_size.
+func WithMaxInputSize(size int) HandlerOption {
+	return func(c *handlerConfig) {
+		c.maxInput = size
+	}
+}
+
 // NewHandler creates a new HTTP handler for the engine.
-func NewHandler(engine Engine) http.Handler {
+func NewHandler(engine Engine, opts ...HandlerOption) http.Handler {
+	cfg := &handlerConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
 	server := &Server{
-		Engine:  engine,
-		Streams: NewStreamManager(),
+		Engine:       engine,
+		Streams:      NewStreamManager(),
+		Sessions:     cfg.sessions,
+		Debugger:     cfg.debugger,
+		MaxInputSize: cfg.maxInput,
 	}
 	r := chi.NewRouter()
 
+	// Metrics (optional)
+	if cfg.metrics != nil {
+		r.Handle("/metrics", cfg.metrics)
+	}
+
 	// Swagger UI
 	r.Get("/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/yaml")
@@ -107,6 +170,30 @@ const swaggerHTML = `
 </html>
 `
 
+// diagnosticsResponse is the error body when the graph has authoring errors (strict mode):
+// {"error": "...", "diagnostics": [{"file": "start.md", "line": 12, "message": "..."}]}.
+type diagnosticsResponse struct {
+	Error       string               `json:"error"`
+	Diagnostics []*domain.Diagnostic `json:"diagnostics"`
+}
+
+// writeEngineError reports an engine failure as plain text, or as JSON with the
+// file:line diagnostics when the failure comes from invalid node files.
+func writeEngineError(w http.ResponseWriter, prefix string, err error) {
+	diags := domain.Diagnostics(err)
+	if len(diags) == 0 {
+		http.Error(w, fmt.Sprintf("%s: %v", prefix, err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusInternalServerError)
+	resp := diagnosticsResponse{Error: fmt.Sprintf("%s: %v", prefix, err), Diagnostics: diags}
+	if encErr := json.NewEncoder(w).Encode(resp); encErr != nil {
+		slog.Error("Error response encode failed", "error", encErr)
+	}
+}
+
 // Render handles the POST /render request.
 func (s *Server) Render(w http.ResponseWriter, r *http.Request) {
 	var body RenderJSONRequestBody
@@ -119,7 +206,7 @@ func (s *Server) Render(w http.ResponseWriter, r *http.Request) {
 	domainState := mapStateToDomain(body)
 	actions, terminal, err := s.Engine.Render(r.Context(), &domainState)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Render error: %v", err), http.StatusInternalServerError)
+		writeEngineError(w, "Render error", err)
 		slog.Error("Render failed", "error", err)
 		return
 	}
@@ -148,24 +235,18 @@ func (s *Server) Navigate(w http.ResponseWriter, r *http.Request) {
 	domainState := mapStateToDomain(body.State)
 	var input any = ""
 	if body.Input != nil {
-		if tr, err := body.Input.AsToolResult(); err == nil && tr.Id != "" {
-			res := domain.ToolResult{
-				ID: tr.Id,
-			}
-			if tr.Result != nil {
-				res.Result = tr.Result
-			}
-			if tr.IsError != nil {
-				res.IsError = *tr.IsError
+		if trs, err := body.Input.AsNavigateRequestInput2(); err == nil && len(trs) > 0 {
+			results := make([]domain.ToolResult, 0, len(trs))
+			for _, tr := range trs {
+				results = append(results, mapToolResultToDomain(tr))
 			}
-			if tr.Error != nil {
-				res.Error = *tr.Error
-			}
-			input = res
+			input = results
+		} else if tr, err := body.Input.AsToolResult(); err == nil && tr.Id != "" {
+			input = mapToolResultToDomain(tr)
 		} else {
 			str, err := body.Input.AsNavigateRequestInput0()
 			if err != nil {
-				http.Error(w, "Invalid input format: expected string or tool result", http.StatusBadRequest)
+				http.Error(w, "Invalid input format: expected string, tool result or list of tool results", http.StatusBadRequest)
 				slog.Warn("Navigate: Invalid input format", "error", err)
 				return
 			}
@@ -175,7 +256,7 @@ func (s *Server) Navigate(w http.ResponseWriter, r *http.Request) {
 
 	// Sanitize Input (Global Policy)
 	if strInput, ok := input.(string); ok && strInput != "" {
-		clean, err := runner.SanitizeInput(strInput)
+		clean, err := runner.SanitizeInputLimit(strInput, s.MaxInputSize)
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Invalid input: %v", err), http.StatusBadRequest)
 			slog.Warn("Navigate: Input rejected", "error", err, "size", len(strInput))
@@ -184,20 +265,50 @@ func (s *Server) Navigate(w http.ResponseWriter, r *http.Request) {
 		input = clean
 	}
 
-	rich, err := runner.NavigateAndRender(r.Context(), s.Engine, &domainState, input)
-	if err != nil {
+	// Results for calls accepted for async completion go through /sessions/{id}/tool-results.
+	// With a session store, navigation runs under the session lock from the stored state
+	// (the client's state only starts new sessions) and its result is saved, so completion,
+	// cancellation and expiry work on what /navigate produced.
+	var rich *runner.RichResponse
+	var renderErr error
+	from := &domainState
+	navigate := func(ctx context.Context, base *domain.State) (*domain.State, error) {
+		from = base
+		rich, renderErr = runner.NavigateAndRender(ctx, s.Engine, base, input)
 		if rich == nil {
-			http.Error(w, fmt.Sprintf("Navigate error: %v", err), http.StatusInternalServerError)
-			slog.Error("Navigate failed", "error", err)
+			return nil, renderErr
+		}
+		return rich.State, nil
+	}
+	var err error
+	if s.Sessions != nil && domainState.SessionID != "" {
+		_, err = s.Sessions.Advance(r.Context(), &domainState, input, navigate)
+	} else if err = session.CheckResults(&domainState, input); err == nil {
+		_, err = navigate(r.Context(), &domainState)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrAsyncCompletionRequired) {
+			http.Error(w, fmt.Sprintf("Tool result rejected: %v", err), http.StatusConflict)
+			slog.Warn("Navigate: Result rejected", "session_id", domainState.SessionID, "error", err)
+			return
+		}
+		if errors.Is(err, domain.ErrRevisionMismatch) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			slog.Warn("Navigate: Session rejected", "session_id", domainState.SessionID, "error", err)
 			return
 		}
-		slog.Error("Navigate: Render failed", "error", err)
+		writeEngineError(w, "Navigate error", err)
+		slog.Error("Navigate failed", "error", err)
+		return
+	}
+	if renderErr != nil {
+		slog.Error("Navigate: Render failed", "error", renderErr)
 	}
 
 	newState := rich.State
 
 	// Calculate and Broadcast Diff
-	diff := domain.Diff(&domainState, newState)
+	diff := domain.Diff(from, newState)
 	if diff != nil {
 		slog.Debug("Navigate: Diff calculated", "diff", diff, "session_id", domainState.SessionID)
 		if bytes, err := json.Marshal(diff); err == nil {
@@ -237,7 +348,7 @@ func (s *Server) Signal(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, fmt.Sprintf("Signal unhandled: %v", err), http.StatusNotFound)
 				return
 			}
-			http.Error(w, fmt.Sprintf("Signal error: %v", err), http.StatusInternalServerError)
+			writeEngineError(w, "Signal error", err)
 			slog.Error("Signal failed", "error", err)
 			return
 		}
@@ -269,6 +380,88 @@ func (s *Server) Signal(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// CompleteTool handles the POST /sessions/{sessionId}/tool-results request.
+func (s *Server) CompleteTool(w http.ResponseWriter, r *http.Request, sessionId string) {
+	if s.Sessions == nil {
+		http.Error(w, "Async tool completion requires a session store", http.StatusNotImplemented)
+		return
+	}
+
+	var body CompleteToolJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Result.Id == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		slog.Warn("CompleteTool: Invalid request body", "error", err)
+		return
+	}
+
+	previous, _ := s.Sessions.Load(r.Context(), sessionId)
+
+	newState, err := s.Sessions.CompleteTool(r.Context(), sessionId, body.IdempotencyKey, mapToolResultToDomain(body.Result))
+	s.writeAsyncResolution(w, "CompleteTool", sessionId, previous, newState, err)
+}
+
+// CancelTool handles the POST /sessions/{sessionId}/tool-calls/{callId}/cancel request.
+func (s *Server) CancelTool(w http.ResponseWriter, r *http.Request, sessionId string, callId string) {
+	if s.Sessions == nil {
+		http.Error(w, "Async tool cancellation requires a session store", http.StatusNotImplemented)
+		return
+	}
+
+	var body CancelToolJSONRequestBody
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			slog.Warn("CancelTool: Invalid request body", "error", err)
+			return
+		}
+	}
+	reason := ""
+	if body.Reason != nil {
+		reason = *body.Reason
+	}
+
+	previous, _ := s.Sessions.Load(r.Context(), sessionId)
+
+	newState, err := s.Sessions.CancelTool(r.Context(), sessionId, callId, reason)
+	s.writeAsyncResolution(w, "CancelTool", sessionId, previous, newState, err)
+}
+
+// writeAsyncResolution answers a completion or cancellation: the resumed state, or the
+// status matching the rejection. The diff is broadcast to the session's subscribers.
+func (s *Server) writeAsyncResolution(w http.ResponseWriter, op, sessionID string, previous, newState *domain.State, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrSessionNotFound):
+			http.Error(w, fmt.Sprintf("Session not found: %s", sessionID), http.StatusNotFound)
+		case errors.Is(err, domain.ErrAsyncCallNotFound),
+			errors.Is(err, domain.ErrIdempotencyMismatch),
+			errors.Is(err, domain.ErrAsyncDeadlineExceeded):
+			http.Error(w, fmt.Sprintf("Tool result rejected: %v", err), http.StatusConflict)
+			slog.Warn(op+": Result rejected", "session_id", sessionID, "error", err)
+		case errors.Is(err, domain.ErrRevisionMismatch):
+			http.Error(w, err.Error(), http.StatusConflict)
+			slog.Warn(op+": Session rejected", "session_id", sessionID, "error", err)
+		default:
+			http.Error(w, fmt.Sprintf("%s error: %v", op, err), http.StatusInternalServerError)
+			slog.Error(op+" failed", "session_id", sessionID, "error", err)
+		}
+		return
+	}
+
+	if previous != nil {
+		if diff := domain.Diff(previous, newState); diff != nil {
+			if bytes, err := json.Marshal(diff); err == nil {
+				s.Streams.Broadcast(sessionID, string(bytes))
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(mapStateFromDomain(*newState)); err != nil {
+		slog.Error(op+" response encode failed", "error", err)
+	}
+}
+
 // GetGraph handles the GET /graph request.
 func (s *Server) GetGraph(w http.ResponseWriter, r *http.Request) {
 	nodes, err := s.Engine.Inspect()
@@ -491,9 +684,34 @@ func mapStateToDomain(s State) domain.State {
 	if s.PendingToolCall != nil {
 		d.PendingToolCall = *s.PendingToolCall
 	}
+	if s.PendingToolCalls != nil {
+		d.PendingToolCalls = *s.PendingToolCalls
+	}
+	if s.BatchResults != nil {
+		for _, tr := range *s.BatchResults {
+			d.BatchResults = append(d.BatchResults, mapToolResultToDomain(tr))
+		}
+	}
+	if s.AsyncCalls != nil {
+		for _, c := range *s.AsyncCalls {
+			call := domain.AsyncCall{
+				ID:             c.Id,
+				IdempotencyKey: c.IdempotencyKey,
+				AcceptedAt:     c.AcceptedAt,
+				Deadline:       c.Deadline,
+			}
+			if c.Handle != nil {
+				call.Handle = *c.Handle
+			}
+			d.AsyncCalls = append(d.AsyncCalls, call)
+		}
+	}
 	if s.Memory != nil {
 		d.Context = *s.Memory
 	}
+	if s.SystemContext != nil {
+		d.SystemContext = *s.SystemContext
+	}
 	if s.History != nil {
 		d.History = *s.History
 	}
@@ -519,9 +737,106 @@ func mapStateFromDomain(d domain.State) State {
 	if d.History != nil {
 		s.History = &d.History
 	}
+	if len(d.SystemContext) > 0 {
+		s.SystemContext = &d.SystemContext
+	}
+	if len(d.PendingToolCalls) > 0 {
+		s.PendingToolCalls = &d.PendingToolCalls
+	}
+	if len(d.BatchResults) > 0 {
+		results := make([]ToolResult, 0, len(d.BatchResults))
+		for _, r := range d.BatchResults {
+			results = append(results, mapToolResultFromDomain(r))
+		}
+		s.BatchResults = &results
+	}
+	if len(d.AsyncCalls) > 0 {
+		calls := make([]AsyncCall, 0, len(d.AsyncCalls))
+		for _, c := range d.AsyncCalls {
+			call := AsyncCall{
+				Id:             c.ID,
+				IdempotencyKey: c.IdempotencyKey,
+				AcceptedAt:     c.AcceptedAt,
+				Deadline:       c.Deadline,
+			}
+			if c.Handle != "" {
+				call.Handle = ptr(c.Handle)
+			}
+			calls = append(calls, call)
+		}
+		s.AsyncCalls = &calls
+	}
 	return s
 }
 
+func mapToolResultToDomain(tr ToolResult) domain.ToolResult {
+	res := domain.ToolResult{
+		ID: tr.Id,
+	}
+	if tr.Result != nil {
+		res.Result = tr.Result
+	}
+	if tr.IsError != nil {
+		res.IsError = *tr.IsError
+	}
+	if tr.IsDenied != nil {
+		res.IsDenied = *tr.IsDenied
+	}
+	if tr.Error != nil {
+		res.Error = *tr.Error
+	}
+	if tr.Usage != nil {
+		res.Usage = mapUsageToDomain(*tr.Usage)
+	}
+	if tr.Accepted != nil {
+		res.Accepted = *tr.Accepted
+	}
+	if tr.Handle != nil {
+		res.Handle = *tr.Handle
+	}
+	return res
+}
+
+func mapToolResultFromDomain(d domain.ToolResult) ToolResult {
+	tr := ToolResult{
+		Id:     d.ID,
+		Result: &d.Result,
+	}
+	if d.IsError {
+		tr.IsError = ptr(true)
+	}
+	if d.IsDenied {
+		tr.IsDenied = ptr(true)
+	}
+	if d.Error != "" {
+		tr.Error = ptr(d.Error)
+	}
+	if d.Usage != nil {
+		tr.Usage = &ToolUsage{Tokens: ptr(d.Usage.Tokens), Cost: ptr(d.Usage.Cost), Units: ptr(d.Usage.Units)}
+	}
+	if d.Accepted {
+		tr.Accepted = ptr(true)
+	}
+	if d.Handle != "" {
+		tr.Handle = ptr(d.Handle)
+	}
+	return tr
+}
+
+func mapUsageToDomain(u ToolUsage) *domain.ToolUsage {
+	usage := &domain.ToolUsage{}
+	if u.Tokens != nil {
+		usage.Tokens = *u.Tokens
+	}
+	if u.Cost != nil {
+		usage.Cost = *u.Cost
+	}
+	if u.Units != nil {
+		usage.Units = *u.Units
+	}
+	return usage
+}
+
 func mapActionsFromDomain(actions []domain.ActionRequest) []ActionRequest {
 	res := make([]ActionRequest, len(actions))
 	for i, a := range actions {
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -10,7 +11,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/debugger"
 	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/session"
 )
 
 // MockEngine for testing
@@ -232,3 +237,259 @@ func TestSubscribeEvents_Stress(t *testing.T) {
 
 	cancel() // Close listeners
 }
+
+func TestServer_MetricsRoute(t *testing.T) {
+	// Without option: not exposed
+	handler := NewHandler(&MockEngine{})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code == http.StatusOK {
+		t.Errorf("Expected /metrics to be disabled by default, got %d", w.Code)
+	}
+
+	// With option
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("trellis_tool_calls_total 1"))
+	})
+	handler = NewHandler(&MockEngine{}, WithMetricsHandler(metrics))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "trellis_tool_calls_total") {
+		t.Errorf("Expected metrics output, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestMapState_SystemContextRoundTrip(t *testing.T) {
+	d := domain.NewState("s1", "start")
+	d.SystemContext[domain.SysKeyUsage] = domain.UsageReport{}.Record("llm", &domain.ToolUsage{Cost: 0.5}).ToMap()
+
+	back := mapStateToDomain(mapStateFromDomain(*d))
+	if got := domain.UsageFromState(&back).Cost; got != 0.5 {
+		t.Errorf("Expected usage to survive mapping, got cost %v", got)
+	}
+}
+
+func TestServer_CompleteTool(t *testing.T) {
+	ctx := context.Background()
+	engine, err := trellis.New("", trellis.WithLoader(memory.NewLoader(map[string]string{
+		"start": `{"id": "start", "do": {"id": "build", "name": "ci"}, "transitions": [{"to_node_id": "done"}]}`,
+		"done":  `{"id": "done", "type": "text"}`,
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a session manager the route is disabled.
+	w := httptest.NewRecorder()
+	NewHandler(engine).ServeHTTP(w, httptest.NewRequest("POST", "/sessions/s1/tool-results", strings.NewReader(`{}`)))
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("Expected 501 without session manager, got %d", w.Code)
+	}
+
+	store := memory.NewStore()
+	state, _ := engine.Start(ctx, "s1", nil)
+	state, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "build", Accepted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Save(ctx, "s1", state)
+	call, _ := state.AsyncCall("build")
+
+	handler := NewHandler(engine, WithSessionManager(session.NewManager(store, session.WithEngine(engine))))
+	post := func(sessionID, key string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(ToolCompletion{IdempotencyKey: key, Result: ToolResult{Id: "build", Result: ptr[interface{}]("ok")}})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/sessions/"+sessionID+"/tool-results", bytes.NewReader(body)))
+		return w
+	}
+
+	if w := post("missing", call.IdempotencyKey); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown session, got %d", w.Code)
+	}
+	if w := post("s1", "forged"); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for wrong idempotency key, got %d", w.Code)
+	}
+
+	w = post("s1", call.IdempotencyKey)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resumed State
+	if err := json.NewDecoder(w.Body).Decode(&resumed); err != nil {
+		t.Fatal(err)
+	}
+	if resumed.CurrentNodeId != "done" {
+		t.Errorf("Expected session to resume at 'done', got %s", resumed.CurrentNodeId)
+	}
+
+	if w := post("s1", call.IdempotencyKey); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for duplicate result, got %d", w.Code)
+	}
+}
+
+func TestServer_AsyncNavigateAndCancel(t *testing.T) {
+	engine, err := trellis.New("", trellis.WithLoader(memory.NewLoader(map[string]string{
+		"start":  `{"id": "start", "do": {"id": "build", "name": "ci"}, "on_error": "failed", "transitions": [{"to_node_id": "done"}]}`,
+		"done":   `{"id": "done", "type": "text"}`,
+		"failed": `{"id": "failed", "type": "text"}`,
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := memory.NewStore()
+	handler := NewHandler(engine, WithSessionManager(session.NewManager(store, session.WithEngine(engine))))
+
+	navigate := func(state State, result ToolResult) *httptest.ResponseRecorder {
+		var input NavigateRequest_Input
+		_ = input.FromToolResult(result)
+		body, _ := json.Marshal(NavigateRequest{State: state, Input: &input})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/navigate", bytes.NewReader(body)))
+		return w
+	}
+
+	start, _ := engine.Start(context.Background(), "s1", nil)
+	w := navigate(mapStateFromDomain(*start), ToolResult{Id: "build", Accepted: ptr(true)})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 accepting the call, got %d: %s", w.Code, w.Body.String())
+	}
+	var accepted RenderResponse
+	if err := json.NewDecoder(w.Body).Decode(&accepted); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(context.Background(), "s1"); err != nil {
+		t.Fatalf("Expected /navigate to save the session: %v", err)
+	}
+
+	// The result of an accepted call cannot skip the idempotency and deadline checks.
+	if w := navigate(*accepted.State, ToolResult{Id: "build", Result: ptr[interface{}]("ok")}); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for an async result sent to /navigate, got %d", w.Code)
+	}
+	if w := navigate(mapStateFromDomain(*start), ToolResult{Id: "build", Result: ptr[interface{}]("ok")}); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 when the client state omits the stored async call, got %d", w.Code)
+	}
+
+	cancel := func(callID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/sessions/s1/tool-calls/"+callID+"/cancel", strings.NewReader(`{"reason": "superseded"}`)))
+		return w
+	}
+	w = cancel("build")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 cancelling the call, got %d: %s", w.Code, w.Body.String())
+	}
+	var cancelled State
+	if err := json.NewDecoder(w.Body).Decode(&cancelled); err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.CurrentNodeId != "failed" {
+		t.Errorf("Expected cancellation to follow on_error, got %s", cancelled.CurrentNodeId)
+	}
+	if w := cancel("build"); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 cancelling a resolved call, got %d", w.Code)
+	}
+}
+
+func TestServer_Debugger(t *testing.T) {
+	engine, err := trellis.New("", trellis.WithLoader(memory.NewLoader(map[string]string{
+		"start": `{"id": "start", "do": {"id": "build", "name": "ci"}, "save_to": "build", "transitions": [{"to_node_id": "done"}]}`,
+		"done":  `{"id": "done", "type": "text"}`,
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a debugger the routes are disabled.
+	w := httptest.NewRecorder()
+	NewHandler(engine).ServeHTTP(w, httptest.NewRequest("POST", "/debug/sessions", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("Expected 501 without debugger, got %d", w.Code)
+	}
+
+	handler := NewHandler(engine, WithDebugger(debugger.NewManager(engine)))
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w = do("POST", "/debug/sessions", `{"session_id": "d1"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		SessionID string        `json:"session_id"`
+		Stop      debugger.Stop `json:"stop"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.SessionID != "d1" || created.Stop.Node != "start" || len(created.Stop.ToolCalls) != 1 {
+		t.Errorf("Unexpected session: %+v", created)
+	}
+	if w := do("POST", "/debug/sessions", `{"session_id": "d1"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for duplicate session, got %d", w.Code)
+	}
+
+	if w := do("POST", "/debug/sessions/d1", `{"op": "inject", "result": {"result": "ok"}}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for inject, got %d: %s", w.Code, w.Body.String())
+	}
+	// Moves are published on the debug stream, apart from a real session named d1.
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/events?session_id="+DebugStreamPrefix+"d1", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	events := bufio.NewReader(res.Body)
+	readData := func() string {
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Reading the debug stream: %v", err)
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				return strings.TrimSpace(data)
+			}
+		}
+	}
+	if data := readData(); data != "connected" {
+		t.Fatalf("Expected the stream to connect, got %q", data)
+	}
+
+	w = do("POST", "/debug/sessions/d1", `{"op": "continue"}`)
+	if data := readData(); !strings.Contains(data, `"done"`) {
+		t.Errorf("Expected the move to 'done' on the debug stream, got %s", data)
+	}
+	var reply debugger.Reply
+	if err := json.NewDecoder(w.Body).Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Stop == nil || reply.Stop.Reason != debugger.ReasonEnd || reply.Stop.Node != "done" {
+		t.Fatalf("Expected the flow to end at 'done', got %+v", reply.Stop)
+	}
+	w = do("POST", "/debug/sessions/d1", `{"op": "get", "key": "build"}`)
+	if !strings.Contains(w.Body.String(), `"value":"ok"`) {
+		t.Errorf("Expected the injected result in the context, got %s", w.Body.String())
+	}
+
+	if w := do("POST", "/debug/sessions/d1", `{"op": "step"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 after the end, got %d", w.Code)
+	}
+	if w := do("POST", "/debug/sessions/d1", `{"op": "jump"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown command, got %d", w.Code)
+	}
+	if w := do("GET", "/debug/sessions/d1", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", w.Code)
+	}
+	if w := do("DELETE", "/debug/sessions/d1", ""); w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", w.Code)
+	}
+	if w := do("GET", "/debug/sessions/d1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", w.Code)
+	}
+}
@@ -0,0 +1,740 @@
+package loam
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/manifest"
+	"github.com/aretw0/trellis/pkg/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// ExportOption configures ExportDir and ExportFile.
+type ExportOption func(*exportConfig)
+
+type exportConfig struct {
+	name  string
+	entry string
+}
+
+// WithExportName sets the flow name (`name:` of single-file flows, `name` in trellis.yaml).
+func WithExportName(name string) ExportOption {
+	return func(c *exportConfig) {
+		c.name = name
+	}
+}
+
+// WithExportEntry sets the entry node. When it is not "start", it is declared
+// in the flow file (`entry:`) or in trellis.yaml.
+func WithExportEntry(id string) ExportOption {
+	return func(c *exportConfig) {
+		c.entry = id
+	}
+}
+
+// ExportDir writes nodes (e.g. from Engine.Inspect) as one file per node under dir:
+// a Markdown file when the content can be the body, YAML otherwise. Namespaced IDs
+// become folders, and tool sets shared by several nodes are moved to a tool library
+// (`_tools.yaml`) that the nodes import. Existing files are never overwritten.
+//
+// Loading the directory yields the same nodes, with the loader defaults applied
+// (e.g. `type: text`, call IDs defaulting to the tool name).
+func ExportDir(dir string, nodes []domain.Node, opts ...ExportOption) error {
+	cfg := newExportConfig(opts)
+	docs, err := exportDocuments(nodes)
+	if err != nil {
+		return err
+	}
+
+	files := make(map[string][]byte, len(docs)+1)
+	for _, doc := range docs {
+		name, data, err := doc.file()
+		if err != nil {
+			return fmt.Errorf("node %s: %w", doc.id, err)
+		}
+		files[name] = data
+	}
+	if cfg.name != "" || (cfg.entry != "" && cfg.entry != domain.DefaultStartNodeID) {
+		m := manifest.Manifest{Name: cfg.name}
+		if cfg.entry != domain.DefaultStartNodeID {
+			m.Entry = cfg.entry
+		}
+		data, err := marshalYAML(m)
+		if err != nil {
+			return err
+		}
+		files[manifest.FileNames[0]] = data
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return fmt.Errorf("%s already exists", filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := writeNew(target, files[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportFile writes nodes as a single-file flow. The format follows the extension:
+// `.yaml`/`.yml`, `.json` or `.md` (a fenced definition block per node followed by its body).
+// The file must not exist yet.
+func ExportFile(path string, nodes []domain.Node, opts ...ExportOption) error {
+	cfg := newExportConfig(opts)
+	docs, err := exportDocuments(nodes)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		data, err = marshalYAML(cfg.flowDocument(docs))
+	case ".json":
+		data, err = json.MarshalIndent(cfg.flowDocument(docs), "", "  ")
+		data = append(data, '\n')
+	case ".md":
+		data, err = cfg.markdownFlow(docs)
+	default:
+		return fmt.Errorf("unsupported flow file extension %q (use .yaml, .yml, .json or .md)", filepath.Ext(path))
+	}
+	if err != nil {
+		return err
+	}
+	return writeNew(path, data)
+}
+
+func newExportConfig(opts []ExportOption) *exportConfig {
+	cfg := &exportConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+func writeNew(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s already exists", path)
+		}
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exportDoc is a node (or tool library) in authoring form.
+type exportDoc struct {
+	id      string
+	meta    document
+	content string
+}
+
+// toolLibraryID names the tool libraries extracted from shared tool sets.
+const toolLibraryID = "_tools"
+
+// exportDocuments converts nodes to authoring form, in ID order, followed by the tool libraries.
+func exportDocuments(nodes []domain.Node) ([]exportDoc, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes to export")
+	}
+	sorted := slices.Clone(nodes)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	libraries, err := toolLibraries(sorted)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]exportDoc, 0, len(sorted)+len(libraries.order))
+	seen := make(map[string]bool, len(sorted))
+	for _, node := range sorted {
+		if err := checkExportID(node.ID); err != nil {
+			return nil, fmt.Errorf("node %s cannot be exported: %w", node.ID, err)
+		}
+		if seen[node.ID] {
+			return nil, fmt.Errorf("duplicate node id '%s'", node.ID)
+		}
+		seen[node.ID] = true
+
+		meta, err := nodeDocument(node, libraries)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", node.ID, err)
+		}
+		docs = append(docs, exportDoc{id: node.ID, meta: meta, content: string(node.Content)})
+	}
+	for _, lib := range libraries.order {
+		tools := make([]any, 0, len(libraries.tools[lib]))
+		for _, tool := range libraries.tools[lib] {
+			tools = append(tools, toolDocument(tool))
+		}
+		docs = append(docs, exportDoc{id: lib, meta: document{{"tools", tools}}})
+	}
+	return docs, nil
+}
+
+// checkExportID rejects IDs that would not load back as the same node from a file.
+func checkExportID(id string) error {
+	switch {
+	case strings.HasPrefix(id, "pkg:"):
+		return fmt.Errorf("package nodes belong to their package (export the package itself)")
+	case !fs.ValidPath(id) || id == "." || strings.ContainsAny(id, `\:`):
+		return fmt.Errorf("not a valid file path")
+	case path.Ext(id) != "":
+		return fmt.Errorf("IDs with a file extension are not supported")
+	case IsPartial(id):
+		return fmt.Errorf("IDs starting with '_' are reserved for templates and defaults")
+	case id == manifest.DocumentID:
+		return fmt.Errorf("'%s' is reserved for the project manifest", id)
+	case strings.HasPrefix(id, manifest.VendorDir+"/"):
+		return fmt.Errorf("the %s/ folder is reserved for packages", manifest.VendorDir)
+	}
+	return nil
+}
+
+// libraryIndex maps shared tool sets to the libraries that hold them.
+type libraryIndex struct {
+	order []string
+	tools map[string][]domain.Tool
+	byKey map[string]string
+}
+
+// toolLibraries extracts every tool set used (identically) by more than one node.
+// Imports bring in the whole library, so only exact sets are shared.
+func toolLibraries(nodes []domain.Node) (*libraryIndex, error) {
+	count := make(map[string]int)
+	var keys []string
+	for _, node := range nodes {
+		if len(node.Tools) == 0 {
+			continue
+		}
+		key, err := json.Marshal(node.Tools)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", node.ID, err)
+		}
+		if count[string(key)] == 0 {
+			keys = append(keys, string(key))
+		}
+		count[string(key)]++
+	}
+
+	idx := &libraryIndex{tools: map[string][]domain.Tool{}, byKey: map[string]string{}}
+	for _, key := range keys {
+		if count[key] < 2 {
+			continue
+		}
+		id := toolLibraryID
+		if len(idx.order) > 0 {
+			id = fmt.Sprintf("%s_%d", toolLibraryID, len(idx.order)+1)
+		}
+		var tools []domain.Tool
+		if err := json.Unmarshal([]byte(key), &tools); err != nil {
+			return nil, err
+		}
+		idx.order = append(idx.order, id)
+		idx.tools[id] = tools
+		idx.byKey[key] = id
+	}
+	return idx, nil
+}
+
+// nodeDocument is the inverse of the Loader: the node metadata as it would be authored.
+func nodeDocument(node domain.Node, libraries *libraryIndex) (document, error) {
+	var d document
+	add := func(key string, value any) { d = append(d, field{key, value}) }
+
+	if node.Type != "" && node.Type != domain.NodeTypeText {
+		add("type", node.Type)
+	}
+	if node.Wait {
+		add("wait", true)
+	}
+	if node.SaveTo != "" {
+		add("save_to", node.SaveTo)
+	}
+
+	options, transitions, to := splitTransitions(node)
+	d = append(d, inputFields(node, options)...)
+
+	if len(node.RequiredContext) > 0 {
+		add("required_context", node.RequiredContext)
+	}
+	if len(node.DefaultContext) > 0 {
+		add("default_context", node.DefaultContext)
+	}
+	if len(node.ContextSchema) > 0 {
+		add("context_schema", schemaDocument(node.ContextSchema))
+	}
+	if node.Prompt != "" {
+		add("prompt", node.Prompt)
+	}
+	if node.System != "" {
+		add("system", node.System)
+	}
+	if node.Model != "" {
+		add("model", node.Model)
+	}
+	if len(node.OutputSchema) > 0 {
+		add("output_schema", schemaDocument(node.OutputSchema))
+	}
+
+	if node.Do != nil {
+		add("do", callDocument(*node.Do))
+	} else if len(node.Batch) > 0 {
+		batch := make([]any, 0, len(node.Batch))
+		for _, call := range node.Batch {
+			entry := callDocument(call.ToolCall)
+			if call.Undo != nil {
+				entry = append(entry, field{"undo", callDocument(*call.Undo)})
+			}
+			batch = append(batch, entry)
+		}
+		add("do", batch)
+	}
+	if node.BatchPolicy != "" {
+		add("batch_policy", node.BatchPolicy)
+	}
+	if node.Undo != nil {
+		add("undo", callDocument(*node.Undo))
+	}
+	if len(node.Tools) > 0 {
+		key, err := json.Marshal(node.Tools)
+		if err != nil {
+			return nil, err
+		}
+		if lib, ok := libraries.byKey[string(key)]; ok {
+			add("tools", []any{lib})
+		} else {
+			tools := make([]any, 0, len(node.Tools))
+			for _, tool := range node.Tools {
+				tools = append(tools, toolDocument(tool))
+			}
+			add("tools", tools)
+		}
+	}
+
+	if len(options) > 0 {
+		add("options", options)
+	}
+	if len(transitions) > 0 {
+		add("transitions", transitions)
+	}
+	if to != "" {
+		add("to", to)
+	}
+	if node.StrictTransitions {
+		add("strict_transitions", true)
+	}
+	if node.OnError != "" {
+		add("on_error", node.OnError)
+	}
+	if node.OnDenied != "" {
+		add("on_denied", node.OnDenied)
+	}
+	if len(node.OnSignal) > 0 {
+		add("on_signal", node.OnSignal)
+	}
+	if len(node.OnSignalDefault) > 0 {
+		add("on_signal_default", node.OnSignalDefault)
+	}
+	if node.OnUnclear != "" {
+		add("on_unclear", node.OnUnclear)
+	}
+	if node.MinConfidence > 0 {
+		add("min_confidence", node.MinConfidence)
+	}
+	if node.Timeout != "" {
+		add("timeout", node.Timeout)
+	}
+	if node.Budget != nil && !node.Budget.IsZero() {
+		add("budget", node.Budget)
+	}
+	if len(node.Metadata) > 0 {
+		add("metadata", node.Metadata)
+	}
+	if len(node.Messages) > 0 {
+		add("messages", node.Messages)
+	}
+	return d, nil
+}
+
+// splitTransitions maps transitions back to `options`, `transitions` and `to`, which
+// the Loader concatenates in that order. `options` are only used where the Loader's input
+// inference (choice input from option labels) matches the node.
+func splitTransitions(node domain.Node) (options, transitions []any, to string) {
+	rest := node.Transitions
+	if n := len(rest); n > 0 && isPlainTransition(rest[n-1]) {
+		to = rest[n-1].ToNodeID
+		rest = rest[:n-1]
+	}
+
+	labelled := 0
+	for labelled < len(rest) && isOption(rest[labelled]) {
+		labelled++
+	}
+	useOptions := labelled > 0 && (node.Type == domain.NodeTypeRoute || node.InputType == string(domain.InputChoice))
+	for _, t := range rest[labelled:] {
+		if isOption(t) {
+			useOptions = false
+		}
+	}
+	if !useOptions {
+		labelled = 0
+	}
+
+	for i, t := range rest {
+		if i < labelled {
+			options = append(options, transitionDocument(t))
+		} else {
+			transitions = append(transitions, transitionDocument(t))
+		}
+	}
+	return options, transitions, to
+}
+
+// isOption reports whether a transition reads as an option: a label with a condition
+// (usually the one the label implies).
+func isOption(t domain.Transition) bool {
+	return t.Label != "" && t.Condition != ""
+}
+
+func isPlainTransition(t domain.Transition) bool {
+	return t.ToNodeID != "" && t.FromNodeID == "" && t.Condition == "" && t.Label == "" &&
+		len(t.Synonyms) == 0 && len(t.Keywords) == 0
+}
+
+func transitionDocument(t domain.Transition) document {
+	var d document
+	switch {
+	case t.Label != "" && t.Condition == "":
+		// `text` would imply `input == '<label>'`.
+		d = append(d, field{"label", t.Label})
+	case t.Label != "":
+		d = append(d, field{"text", t.Label})
+	}
+	if t.FromNodeID != "" {
+		d = append(d, field{"from", t.FromNodeID})
+	}
+	if t.ToNodeID != "" {
+		d = append(d, field{"to", t.ToNodeID})
+	}
+	// A label implies `input == '<label>'` unless another condition is given.
+	if t.Condition != "" && (t.Label == "" || t.Condition != labelCondition(t.Label)) {
+		d = append(d, field{"condition", t.Condition})
+	}
+	if len(t.Synonyms) > 0 {
+		d = append(d, field{"synonyms", t.Synonyms})
+	}
+	if len(t.Keywords) > 0 {
+		d = append(d, field{"keywords", t.Keywords})
+	}
+	return d
+}
+
+func labelCondition(label string) string {
+	return fmt.Sprintf("input == '%s'", strings.ReplaceAll(label, "'", "\\'"))
+}
+
+// inputFields returns the input keys that differ from what the Loader infers from options.
+func inputFields(node domain.Node, options []any) document {
+	var labels []string
+	for _, t := range node.Transitions[:len(options)] {
+		labels = append(labels, t.Label)
+	}
+
+	inferredType := ""
+	if len(labels) > 0 && node.Type != domain.NodeTypeRoute {
+		inferredType = string(domain.InputChoice)
+	}
+	inferredOptions := labels
+	if node.InputType == string(domain.InputConfirm) && len(labels) == 0 {
+		inferredOptions = []string{"yes", "no"}
+	}
+
+	var fields document
+	if node.InputType != inferredType {
+		fields = append(fields, field{"input_type", node.InputType})
+	}
+	if len(node.InputOptions) > 0 && !slices.Equal(node.InputOptions, inferredOptions) {
+		fields = append(fields, field{"input_options", node.InputOptions})
+	}
+	if node.InputDefault != "" {
+		fields = append(fields, field{"input_default", node.InputDefault})
+	}
+	return fields
+}
+
+// schemaDocument writes a schema back as its type names (e.g. `int`, `[string]`).
+func schemaDocument(s schema.Schema) map[string]any {
+	out := make(map[string]any, len(s))
+	for key, typ := range s {
+		out[key] = typ.Name()
+	}
+	return out
+}
+
+func callDocument(call domain.ToolCall) document {
+	var d document
+	if call.ID != "" && call.ID != call.Name {
+		d = append(d, field{"id", call.ID})
+	}
+	d = append(d, field{"name", call.Name})
+	if len(call.Args) > 0 {
+		d = append(d, field{"args", call.Args})
+	}
+	if len(call.Metadata) > 0 {
+		d = append(d, field{"metadata", call.Metadata})
+	}
+	if call.IdempotencyKey != "" {
+		d = append(d, field{"idempotency_key", call.IdempotencyKey})
+	}
+	return d
+}
+
+func toolDocument(tool domain.Tool) document {
+	d := document{{"name", tool.Name}}
+	if tool.Description != "" {
+		d = append(d, field{"description", tool.Description})
+	}
+	if len(tool.Parameters) > 0 {
+		d = append(d, field{"parameters", tool.Parameters})
+	}
+	return d
+}
+
+// file renders a node file: Markdown with the content as body, unless a body-only file
+// would be mistaken for frontmatter; YAML with an explicit `content` key otherwise.
+func (doc exportDoc) file() (string, []byte, error) {
+	if doc.content != "" && (len(doc.meta) > 0 || !strings.HasPrefix(doc.content, "---")) {
+		var buf bytes.Buffer
+		if len(doc.meta) > 0 {
+			front, err := marshalYAML(doc.meta)
+			if err != nil {
+				return "", nil, err
+			}
+			buf.WriteString("---\n")
+			buf.Write(front)
+			buf.WriteString("---\n")
+		}
+		buf.WriteString(doc.content)
+		return doc.id + ".md", buf.Bytes(), nil
+	}
+
+	meta := doc.meta
+	if doc.content != "" {
+		meta = append(slices.Clone(meta), field{"content", doc.content})
+	}
+	if len(meta) == 0 {
+		// An empty node still needs a document.
+		meta = document{{"type", domain.NodeTypeText}}
+	}
+	data, err := marshalYAML(meta)
+	return doc.id + ".yaml", data, err
+}
+
+// flowDocument is the YAML/JSON single-file form.
+func (c *exportConfig) flowDocument(docs []exportDoc) document {
+	var d document
+	if c.name != "" {
+		d = append(d, field{"name", c.name})
+	}
+	if c.entry != "" {
+		d = append(d, field{"entry", c.entry})
+	}
+	nodes := make(document, 0, len(docs))
+	for _, doc := range docs {
+		meta := doc.meta
+		if doc.content != "" {
+			meta = append(slices.Clone(meta), field{"content", doc.content})
+		}
+		nodes = append(nodes, field{doc.id, meta})
+	}
+	return append(d, field{"nodes", nodes})
+}
+
+// markdownFlow renders a Markdown single-file flow. Bodies are trimmed by the parser,
+// so content with surrounding whitespace or its own fences goes in the definition block.
+func (c *exportConfig) markdownFlow(docs []exportDoc) ([]byte, error) {
+	var buf bytes.Buffer
+	var header document
+	if c.name != "" {
+		header = append(header, field{"name", c.name})
+	}
+	if c.entry != "" {
+		header = append(header, field{"entry", c.entry})
+	}
+	if len(header) > 0 {
+		front, err := marshalYAML(header)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("---\n")
+		buf.Write(front)
+		buf.WriteString("---\n\n")
+	}
+
+	for i, doc := range docs {
+		meta, body := doc.meta, doc.content
+		if body != "" && (strings.TrimSpace(body) != body || strings.Contains(body, "```")) {
+			meta = append(slices.Clone(meta), field{"content", quoted(body)})
+			body = ""
+		}
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("```yaml " + doc.id + "\n")
+		if len(meta) > 0 {
+			def, err := marshalYAML(meta)
+			if err != nil {
+				return nil, fmt.Errorf("node %s: %w", doc.id, err)
+			}
+			buf.Write(def)
+		}
+		buf.WriteString("```\n")
+		if body != "" {
+			buf.WriteString("\n" + body + "\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// quoted is a string written on a single line (escaped), e.g. inside a Markdown fence.
+type quoted string
+
+// field and document keep keys in authoring order in both YAML and JSON.
+type field struct {
+	key   string
+	value any
+}
+
+type document []field
+
+// MarshalYAML implements yaml.Marshaler.
+func (d document) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range d {
+		value, err := yamlValue(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.key, err)
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: f.key}, value)
+	}
+	return node, nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d document) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range d {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.key, err)
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalYAML encodes with the two-space indentation used in node files.
+func marshalYAML(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlValue encodes a value, keeping json.Number exact (large integers stay integers)
+// and writing multi-line strings as literal blocks.
+func yamlValue(value any) (*yaml.Node, error) {
+	switch v := value.(type) {
+	case document:
+		out, err := v.MarshalYAML()
+		if err != nil {
+			return nil, err
+		}
+		return out.(*yaml.Node), nil
+	case json.Number:
+		tag := "!!float"
+		if _, err := strconv.ParseInt(string(v), 10, 64); err == nil || !strings.ContainsAny(string(v), ".eE") {
+			tag = "!!int"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: string(v)}, nil
+	case quoted:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: string(v), Style: yaml.DoubleQuotedStyle}, nil
+	case string:
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+		if strings.Contains(v, "\n") {
+			// Literal blocks cannot start with a line break or indentation.
+			node.Style = yaml.LiteralStyle
+			if strings.TrimLeft(v, " \t\n") != v {
+				node.Style = yaml.DoubleQuotedStyle
+			}
+		}
+		return node, nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		d := make(document, 0, len(keys))
+		for _, key := range keys {
+			d = append(d, field{key, v[key]})
+		}
+		return yamlValue(d)
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range v {
+			child, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
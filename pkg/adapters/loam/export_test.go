@@ -0,0 +1,264 @@
+package loam_test
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/adapters/loam"
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// exportFixture covers the authoring features an export has to reproduce.
+var exportFixture = map[string]string{
+	"trellis.yaml": "name: support\nentry: main\n",
+	"main.md": `---
+wait: true
+save_to: topic
+default_context:
+  retries: 3
+  big: 9007199254740993
+context_schema:
+  retries: int
+options:
+  - text: "It's billing"
+    to: billing/charge
+  - text: Other
+    to: triage
+strict_transitions: true
+on_signal:
+  interrupt: bye
+on_signal_default:
+  timeout: bye
+timeout: 30s
+budget:
+  max_calls: 5
+metadata:
+  owner: team
+  tags: [a, b]
+---
+# Welcome
+
+Pick a topic.
+`,
+	"triage.yaml": `
+type: route
+content: "  What do you need?"
+options:
+  - text: Refund
+    to: billing/charge
+    synonyms: [money back]
+  - text: Other
+    to: bye
+    keywords: [else]
+on_unclear: triage
+min_confidence: 0.7
+`,
+	"billing/charge.yaml": `
+type: tool
+do:
+  - name: charge
+    args: { amount: 12.5, currency: BRL }
+    undo: { name: refund }
+  - id: notify_1
+    name: notify
+    metadata: { confirm_msg: "Notify?" }
+batch_policy: rollback
+tools: [_lib]
+on_error: error
+transitions:
+  - condition: "tool_result.ok"
+    to: billing/done
+  - to: error
+`,
+	"billing/done.md": `---
+tools: [_lib]
+input_type: confirm
+input_default: "yes"
+to: bye
+---
+Done.
+`,
+	"_lib.yaml": "tools:\n  - name: charge\n    description: Charges the customer\n    parameters:\n      type: object\n  - name: notify\n",
+	"ask.yaml": `
+type: llm
+prompt: "Summarize {{ .topic }}"
+system: "Be brief"
+model: small
+output_schema:
+  summary: string
+to: bye
+`,
+	"greet.yaml": `
+type: format
+messages:
+  en: [{ text: Hello }]
+  pt: [{ text: Olá, condition: "formal" }]
+to: bye
+`,
+	"bye.md":   "Bye!\n\n",
+	"error.md": "---\nundo: { name: cleanup }\n---\n---\nSomething went wrong",
+}
+
+func inspect(t *testing.T, path string) []domain.Node {
+	t.Helper()
+	engine, err := trellis.New(path)
+	require.NoError(t, err)
+	nodes, err := engine.Inspect()
+	require.NoError(t, err)
+	for i := range nodes {
+		nodes[i].Source = nil
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+func TestExport_RoundTrip(t *testing.T) {
+	src := testutils.WriteFlow(t, exportFixture)
+	graph := inspect(t, src)
+	require.Len(t, graph, 8)
+	opts := []loam.ExportOption{loam.WithExportName("support"), loam.WithExportEntry("main")}
+
+	t.Run("Directory", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, loam.ExportDir(dir, graph, opts...))
+		assert.Equal(t, graph, inspect(t, dir))
+
+		engine, err := trellis.New(dir)
+		require.NoError(t, err)
+		assert.Equal(t, "support", engine.Name)
+		assert.Equal(t, "main", engine.EntryNode())
+
+		lib, err := os.ReadFile(filepath.Join(dir, "_tools.yaml"))
+		require.NoError(t, err, "shared tool sets become a library")
+		assert.Contains(t, string(lib), "Charges the customer")
+		charge, err := os.ReadFile(filepath.Join(dir, "billing", "charge.yaml"))
+		require.NoError(t, err)
+		assert.Contains(t, string(charge), "tools:\n  - _tools\n")
+		bye, err := os.ReadFile(filepath.Join(dir, "bye.md"))
+		require.NoError(t, err)
+		assert.Equal(t, "Bye!\n\n", string(bye), "content is the Markdown body")
+
+		again := t.TempDir()
+		require.NoError(t, loam.ExportDir(again, inspect(t, dir), opts...))
+		for _, name := range []string{"main.md", "triage.md", "billing/charge.yaml", "error.md", "_tools.yaml", "trellis.yaml"} {
+			first, err := os.ReadFile(filepath.Join(dir, name))
+			require.NoError(t, err)
+			second, err := os.ReadFile(filepath.Join(again, name))
+			require.NoError(t, err)
+			assert.Equal(t, string(first), string(second), "%s is stable", name)
+		}
+
+		assert.ErrorContains(t, loam.ExportDir(dir, graph), "already exists")
+	})
+
+	for _, name := range []string{"flow.yaml", "flow.json", "flow.md"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, loam.ExportFile(path, graph, opts...))
+			assert.Equal(t, graph, inspect(t, path))
+
+			engine, err := trellis.New(path)
+			require.NoError(t, err)
+			assert.Equal(t, "support", engine.Name)
+			assert.Equal(t, "main", engine.EntryNode())
+		})
+	}
+}
+
+func TestExport_LabelledTransitions(t *testing.T) {
+	transitions := []domain.Transition{
+		{ToNodeID: "sales", Condition: "input == 'Sales'", Label: "Sales"},
+		{ToNodeID: "vip", Condition: "context.vip", Label: "VIP"},
+		{ToNodeID: "support", Label: "Talk to us"},
+	}
+	graph := []domain.Node{
+		{ID: "start", Type: domain.NodeTypeQuestion, InputType: string(domain.InputChoice), Transitions: transitions},
+		{ID: "sales", Type: domain.NodeTypeText},
+		{ID: "support", Type: domain.NodeTypeText},
+		{ID: "vip", Type: domain.NodeTypeText},
+	}
+
+	for _, name := range []string{"dir", "flow.yaml", "flow.md"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if name == "dir" {
+				require.NoError(t, loam.ExportDir(path, graph))
+			} else {
+				require.NoError(t, loam.ExportFile(path, graph))
+			}
+			var start domain.Node
+			for _, n := range inspect(t, path) {
+				if n.ID == "start" {
+					start = n
+				}
+			}
+			for i := range start.Transitions {
+				start.Transitions[i].Synonyms, start.Transitions[i].Keywords = nil, nil
+			}
+			assert.Equal(t, transitions, start.Transitions, "a label without a condition stays unconditional")
+		})
+	}
+}
+
+func TestExport_Errors(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"pkg:billing/start": "export the package itself",
+		"../escape":         "not a valid file path",
+		"notes.v2":          "file extension",
+		"_base":             "reserved for templates",
+		"trellis":           "reserved for the project manifest",
+		"vendor/pkg/start":  "reserved for packages",
+	}
+	for id, msg := range cases {
+		err := loam.ExportDir(dir, []domain.Node{{ID: id}})
+		assert.ErrorContains(t, err, msg, id)
+	}
+
+	assert.EqualError(t, loam.ExportDir(dir, nil), "no nodes to export")
+	err := loam.ExportFile(filepath.Join(dir, "flow.toml"), []domain.Node{{ID: "start"}})
+	assert.ErrorContains(t, err, "unsupported flow file extension")
+}
+
+// TestExport_Examples round-trips every example flow through each export format.
+func TestExport_Examples(t *testing.T) {
+	examplesDir := filepath.Join("..", "..", "..", "examples")
+	entries, err := os.ReadDir(examplesDir)
+	if err != nil {
+		t.Skip("examples not found (possibly running in different context)")
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		src := filepath.Join(examplesDir, entry.Name())
+		engine, err := trellis.New(src)
+		if err != nil {
+			continue // Go-only examples have no flow files
+		}
+		if nodes, err := engine.Inspect(); err != nil || len(nodes) == 0 {
+			continue
+		}
+
+		t.Run(entry.Name(), func(t *testing.T) {
+			graph := inspect(t, src)
+			for _, name := range []string{"flow", "flow.yaml", "flow.json", "flow.md"} {
+				out := filepath.Join(t.TempDir(), name)
+				if name == "flow" {
+					require.NoError(t, loam.ExportDir(out, graph))
+				} else {
+					require.NoError(t, loam.ExportFile(out, graph))
+				}
+				assert.Equal(t, graph, inspect(t, out), name)
+			}
+		})
+	}
+}
@@ -0,0 +1,539 @@
+package loam
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aretw0/loam"
+	"github.com/aretw0/loam/pkg/core"
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// FlowFile is the parsed form of a single-file flow.
+//
+// YAML/JSON:
+//
+//	name: onboarding
+//	entry: start
+//	nodes:
+//	  start: { wait: true, content: "Name?", save_to: name, to: done }
+//	  done:  { content: "Hi {{ .name }}" }
+//
+// Markdown: flow settings go in the frontmatter and each node is a fenced block
+// (```yaml <id>, ```json <id> or ```trellis <id>) followed by its body.
+type FlowFile struct {
+	// Name is a descriptive label for the flow (defaults to the file name).
+	Name string
+	// Entry is the initial node ID (empty means the CLI conventions apply).
+	Entry string
+	// Budget is applied to the entry node unless it declares its own.
+	Budget map[string]any
+	// Nodes are the documents in declaration order.
+	Nodes []core.Document
+
+	// positions maps node IDs to where they are declared in the file.
+	positions map[string]nodePosition
+}
+
+// nodePosition is the declaration of a node inside a flow file.
+type nodePosition struct {
+	line int
+	meta *yaml.Node
+}
+
+// flowHeader holds the flow-level settings of a single-file flow.
+type flowHeader struct {
+	Name   string         `yaml:"name"`
+	Entry  string         `yaml:"entry"`
+	Budget map[string]any `yaml:"budget"`
+	Nodes  yaml.Node      `yaml:"nodes"`
+}
+
+// IsFlowFile reports whether path points to a single-file flow (as opposed to a directory).
+func IsFlowFile(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json", ".md":
+		return true
+	}
+	return false
+}
+
+// ParseFlowFile decodes a single-file flow. The format is chosen by extension.
+func ParseFlowFile(path string, data []byte) (*FlowFile, error) {
+	var (
+		flow *FlowFile
+		err  error
+	)
+	if strings.EqualFold(filepath.Ext(path), ".md") {
+		flow, err = parseMarkdownFlow(data)
+	} else {
+		flow, err = parseStructuredFlow(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid flow file %s: %w", path, err)
+	}
+	if flow.Name == "" {
+		flow.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if len(flow.Nodes) == 0 {
+		return nil, fmt.Errorf("invalid flow file %s: no nodes declared", path)
+	}
+	if err := flow.applyFlowSettings(); err != nil {
+		return nil, fmt.Errorf("invalid flow file %s: %w", path, err)
+	}
+	return flow, nil
+}
+
+// FirstNode returns the first declared node that is not a partial (`_defaults`, templates).
+func (f *FlowFile) FirstNode() string {
+	for _, doc := range f.Nodes {
+		if !IsPartial(doc.ID) {
+			return doc.ID
+		}
+	}
+	return f.Nodes[0].ID
+}
+
+// Has reports whether the flow declares the given node ID.
+func (f *FlowFile) Has(id string) bool {
+	_, ok := f.node(id)
+	return ok
+}
+
+func (f *FlowFile) node(id string) (core.Document, bool) {
+	id = trimExtension(strings.TrimPrefix(id, "./"))
+	for _, doc := range f.Nodes {
+		if doc.ID == id {
+			return doc, true
+		}
+	}
+	return core.Document{}, false
+}
+
+// locate records where a node is declared (meta may be nil).
+func (f *FlowFile) locate(id string, line int, meta *yaml.Node) {
+	if f.positions == nil {
+		f.positions = make(map[string]nodePosition)
+	}
+	f.positions[id] = nodePosition{line: line, meta: meta}
+}
+
+func (f *FlowFile) add(id string, meta map[string]any, content string) error {
+	if id == "" {
+		return fmt.Errorf("node without id")
+	}
+	if meta == nil {
+		meta = make(map[string]any)
+	}
+	if declared, ok := meta["id"].(string); ok && declared != "" && declared != id {
+		return fmt.Errorf("node '%s' declares a different id '%s'", id, declared)
+	}
+	if f.Has(id) {
+		return fmt.Errorf("duplicate node id '%s'", id)
+	}
+	// Mirror the loam JSON/YAML convention: the body lives in "content".
+	if c, ok := meta["content"].(string); ok {
+		if content == "" {
+			content = c
+		}
+		delete(meta, "content")
+	}
+	f.Nodes = append(f.Nodes, core.Document{ID: id, Content: content, Metadata: meta})
+	return nil
+}
+
+// applyFlowSettings pushes flow-level settings onto the entry node.
+func (f *FlowFile) applyFlowSettings() error {
+	if f.Entry != "" && !f.Has(f.Entry) {
+		return fmt.Errorf("entry node '%s' is not declared", f.Entry)
+	}
+	if len(f.Budget) == 0 {
+		return nil
+	}
+	entry := f.Entry
+	if entry == "" {
+		entry = f.FirstNode()
+	}
+	doc, _ := f.node(entry)
+	if _, ok := doc.Metadata["budget"]; !ok {
+		doc.Metadata["budget"] = f.Budget
+	}
+	return nil
+}
+
+func parseStructuredFlow(data []byte) (*FlowFile, error) {
+	var header flowHeader
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	flow := &FlowFile{Name: header.Name, Entry: header.Entry, Budget: header.Budget}
+	if header.Nodes.Kind == 0 {
+		return flow, nil
+	}
+	if header.Nodes.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("'nodes' must be a map of node ID to definition")
+	}
+	// Walk the mapping manually to keep declaration order.
+	for i := 0; i+1 < len(header.Nodes.Content); i += 2 {
+		key, value := header.Nodes.Content[i], header.Nodes.Content[i+1]
+		id := key.Value
+		var meta map[string]any
+		if err := value.Decode(&meta); err != nil {
+			return nil, fmt.Errorf("line %d: node '%s': %w", key.Line, id, err)
+		}
+		if err := flow.add(id, meta, ""); err != nil {
+			return nil, err
+		}
+		if value.Kind == yaml.MappingNode {
+			flow.locate(id, key.Line, value)
+		} else {
+			flow.locate(id, key.Line, nil)
+		}
+	}
+	return flow, nil
+}
+
+// nodeFenceLangs are the info strings that mark a fenced block as a node definition.
+var nodeFenceLangs = map[string]bool{"yaml": true, "yml": true, "json": true, "trellis": true}
+
+func parseMarkdownFlow(data []byte) (*FlowFile, error) {
+	body := data
+	flow := &FlowFile{}
+	offset := 0 // lines before the body, so positions refer to the whole file
+
+	// Frontmatter: flow-level settings.
+	if rest, ok := bytes.CutPrefix(bytes.TrimPrefix(data, []byte("\ufeff")), []byte("---\n")); ok {
+		front, after, found := bytes.Cut(rest, []byte("\n---"))
+		if !found {
+			return nil, fmt.Errorf("unterminated frontmatter")
+		}
+		var header flowHeader
+		if err := yaml.Unmarshal(front, &header); err != nil {
+			return nil, fmt.Errorf("frontmatter: %w", err)
+		}
+		flow.Name, flow.Entry, flow.Budget = header.Name, header.Entry, header.Budget
+		body = after
+		offset = bytes.Count(front, []byte("\n")) + 2
+	}
+
+	var (
+		id      string
+		meta    map[string]any
+		fence   int // body line of the current node fence
+		def     strings.Builder
+		content strings.Builder
+		inNode  bool   // inside a node definition fence
+		inProse string // fence marker of a regular code block inside a node body
+		started bool
+	)
+	flush := func() error {
+		if !started {
+			return nil
+		}
+		if err := flow.add(id, meta, strings.TrimSpace(content.String())); err != nil {
+			return err
+		}
+		flow.locate(id, offset+fence, definitionNode(def.String(), offset+fence))
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+
+		switch {
+		case inNode:
+			if trimmed == "```" {
+				inNode = false
+				meta = nil
+				if err := yaml.Unmarshal([]byte(def.String()), &meta); err != nil {
+					return nil, fmt.Errorf("line %d: node '%s': %w", line, id, err)
+				}
+				if id == "" {
+					id, _ = meta["id"].(string)
+				}
+				if id == "" {
+					return nil, fmt.Errorf("line %d: node block without id", line)
+				}
+				continue
+			}
+			def.WriteString(text + "\n")
+			continue
+
+		case inProse != "":
+			if trimmed == inProse {
+				inProse = ""
+			}
+
+		case strings.HasPrefix(trimmed, "```"):
+			info := strings.Fields(strings.TrimPrefix(trimmed, "```"))
+			if len(info) > 0 && nodeFenceLangs[info[0]] && (len(info) > 1 || info[0] == "trellis") {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+				started, inNode = true, true
+				fence = line
+				id = ""
+				if len(info) > 1 {
+					id = info[1]
+				}
+				def.Reset()
+				content.Reset()
+				continue
+			}
+			inProse = "```"
+		}
+
+		if started {
+			content.WriteString(text + "\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if inNode {
+		return nil, fmt.Errorf("node '%s': unterminated block", id)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return flow, nil
+}
+
+// definitionNode parses a fenced node definition for diagnostics, shifted to its file position.
+func definitionNode(def string, fenceLine int) *yaml.Node {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(def), &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	meta := doc.Content[0]
+	if meta.Kind != yaml.MappingNode {
+		return nil
+	}
+	shiftLines(meta, fenceLine)
+	return meta
+}
+
+// FileRepository is a read-only loam repository backed by a single-file flow.
+// Plugging it into loam.NewTypedRepository lets the regular Loader build the nodes,
+// so IDs, jump_to and tool imports behave exactly as in a directory.
+// Imports not declared in the file are resolved against the file's directory.
+type FileRepository struct {
+	path string
+
+	mu   sync.RWMutex
+	flow *FlowFile
+
+	dirOnce sync.Once
+	dir     core.Repository
+	dirErr  error
+}
+
+// OpenFile parses a single-file flow into a repository.
+func OpenFile(path string) (*FileRepository, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+	r := &FileRepository{path: absPath}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewFileLoader returns a GraphLoader for a single-file flow.
+// Nodes carry their position in the file; opts may enable strict checks.
+func NewFileLoader(path string, opts ...Option) (*Loader, *FileRepository, error) {
+	repo, err := OpenFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts = append([]Option{withSources(repo)}, opts...)
+	return New(loam.NewTypedRepository[NodeMetadata](NewInheritRepository(repo)), opts...), repo, nil
+}
+
+func withSources(locator sourceLocator) Option {
+	return func(l *Loader) {
+		l.sources = locator
+	}
+}
+
+// Flow returns the parsed flow (name, entry and nodes).
+func (r *FileRepository) Flow() *FlowFile {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.flow
+}
+
+func (r *FileRepository) reload() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read flow file: %w", err)
+	}
+	flow, err := ParseFlowFile(r.path, data)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.flow = flow
+	r.mu.Unlock()
+	return nil
+}
+
+// Get implements core.Repository.
+func (r *FileRepository) Get(ctx context.Context, id string) (core.Document, error) {
+	if doc, ok := r.Flow().node(id); ok {
+		return cloneDocument(doc), nil
+	}
+	dir, err := r.sibling()
+	if err != nil {
+		return core.Document{}, fmt.Errorf("node '%s' not found in %s: %w", id, filepath.Base(r.path), os.ErrNotExist)
+	}
+	return dir.Get(ctx, id)
+}
+
+// List implements core.Repository. Only the nodes declared in the file are listed.
+func (r *FileRepository) List(ctx context.Context) ([]core.Document, error) {
+	flow := r.Flow()
+	docs := make([]core.Document, 0, len(flow.Nodes))
+	for _, doc := range flow.Nodes {
+		docs = append(docs, cloneDocument(doc))
+	}
+	return docs, nil
+}
+
+// Save implements core.Repository. Single-file flows are read-only.
+func (r *FileRepository) Save(ctx context.Context, doc core.Document) error {
+	return fmt.Errorf("flow file %s is read-only", filepath.Base(r.path))
+}
+
+// Delete implements core.Repository. Single-file flows are read-only.
+func (r *FileRepository) Delete(ctx context.Context, id string) error {
+	return fmt.Errorf("flow file %s is read-only", filepath.Base(r.path))
+}
+
+// Initialize implements core.Repository.
+func (r *FileRepository) Initialize(ctx context.Context) error {
+	return nil
+}
+
+// Watch implements core.Watchable. It reloads the file on change and emits a MODIFY event.
+// The parent directory is watched so editors that replace the file on save are handled.
+func (r *FileRepository) Watch(ctx context.Context, pattern string) (<-chan core.Event, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(r.path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	events := make(chan core.Event, 1)
+	go func() {
+		defer close(events)
+		defer watcher.Close()
+
+		// Debounce bursts of writes (save = truncate + write on most editors).
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(evt.Name) == r.path && !evt.Has(fsnotify.Chmod) {
+					debounce = time.After(100 * time.Millisecond)
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			case <-debounce:
+				debounce = nil
+				// A broken save keeps the previous flow; the consumer reloads and reports the error.
+				_ = r.reload()
+				select {
+				case events <- core.Event{Type: core.EventModify, ID: filepath.Base(r.path), Timestamp: time.Now().Unix()}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// locate implements sourceLocator: declared nodes point into the flow file,
+// external imports into the sibling directory.
+func (r *FileRepository) locate(id string, parse bool) *nodeSource {
+	flow := r.Flow()
+	if pos, ok := flow.positions[trimExtension(strings.TrimPrefix(id, "./"))]; ok {
+		return &nodeSource{File: filepath.Base(r.path), Line: pos.line, Meta: pos.meta}
+	}
+	return dirSource{fsys: os.DirFS(filepath.Dir(r.path))}.locate(id, parse)
+}
+
+// sibling lazily opens the directory holding the flow file (for external imports).
+func (r *FileRepository) sibling() (core.Repository, error) {
+	r.dirOnce.Do(func() {
+		r.dir, r.dirErr = loam.Init(filepath.Dir(r.path), loam.WithStrict(true), loam.WithReadOnly(true))
+	})
+	return r.dir, r.dirErr
+}
+
+// cloneDocument deep-copies metadata so callers cannot mutate the parsed flow.
+// Values keep their decoded types (json.Number, int, time.Time...).
+func cloneDocument(doc core.Document) core.Document {
+	meta, _ := cloneValue(map[string]any(doc.Metadata)).(map[string]any)
+	return core.Document{ID: doc.ID, Content: doc.Content, Metadata: meta}
+}
+
+// cloneValue copies maps and slices recursively; other values are immutable.
+func cloneValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = cloneValue(item)
+		}
+		return out
+	case core.Metadata:
+		return core.Metadata(cloneValue(map[string]any(v)).(map[string]any))
+	case []any:
+		if v == nil {
+			return v
+		}
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = cloneValue(item)
+		}
+		return out
+	case []string:
+		return slices.Clone(v)
+	}
+	return v
+}
@@ -0,0 +1,193 @@
+package loam
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadFlowNode(t *testing.T, loader *Loader, id string) domain.Node {
+	t.Helper()
+	data, err := loader.GetNode(id)
+	require.NoError(t, err)
+	var node domain.Node
+	require.NoError(t, json.Unmarshal(data, &node))
+	return node
+}
+
+func TestFileLoader_YAML(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "shared.yaml", `
+tools:
+  - name: shared_tool
+    description: Imported from a sibling file
+`)
+	writeFile(t, dir, "flow.yaml", `
+name: onboarding
+entry: start
+budget:
+  max_calls: 3
+nodes:
+  start:
+    wait: true
+    content: "What is your name?"
+    save_to: name
+    options:
+      - text: "Skip"
+        jump_to: done
+    to: greet
+  greet:
+    type: tool
+    do: { name: greet, args: { who: "{{ .name }}" } }
+    tools: [lib, shared.yaml]
+    to: done
+  lib:
+    tools:
+      - name: lib_tool
+  done:
+    content: "Bye {{ .name }}"
+`)
+
+	loader, repo, err := NewFileLoader(filepath.Join(dir, "flow.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "onboarding", repo.Flow().Name)
+	assert.Equal(t, "start", repo.Flow().Entry)
+
+	ids, err := loader.ListNodes()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"start", "greet", "lib", "done"}, ids, "declaration order, no sibling files")
+
+	start := loadFlowNode(t, loader, "start")
+	assert.Equal(t, "What is your name?", string(start.Content))
+	assert.Equal(t, "choice", start.InputType)
+	require.Len(t, start.Transitions, 2)
+	assert.Equal(t, "done", start.Transitions[0].ToNodeID, "jump_to behaves as in a directory")
+	assert.Equal(t, "greet", start.Transitions[1].ToNodeID)
+	require.NotNil(t, start.Budget, "flow-level budget lands on the entry node")
+	assert.Equal(t, 3, start.Budget.MaxCalls)
+
+	greet := loadFlowNode(t, loader, "greet")
+	require.NotNil(t, greet.Do)
+	assert.Equal(t, "greet", greet.Do.ID)
+	require.Len(t, greet.Tools, 2)
+	assert.Equal(t, "lib_tool", greet.Tools[0].Name)
+	assert.Equal(t, "shared_tool", greet.Tools[1].Name)
+
+	_, err = loader.GetNode("missing")
+	assert.Error(t, err)
+}
+
+func TestFileLoader_Markdown(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "flow.md", "---\n"+
+		"name: quiz\n"+
+		"---\n"+
+		"# Quiz\n\n"+
+		"Free text before the first node is documentation.\n\n"+
+		"```yaml start\n"+
+		"wait: true\n"+
+		"save_to: answer\n"+
+		"to: result\n"+
+		"```\n"+
+		"How much is 2 + 2?\n\n"+
+		"```bash\n"+
+		"echo 'regular code blocks stay in the body'\n"+
+		"```\n\n"+
+		"```trellis\n"+
+		"id: result\n"+
+		"```\n"+
+		"You said {{ .answer }}.\n")
+
+	loader, repo, err := NewFileLoader(filepath.Join(dir, "flow.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "quiz", repo.Flow().Name)
+
+	ids, err := loader.ListNodes()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"start", "result"}, ids)
+
+	start := loadFlowNode(t, loader, "start")
+	assert.True(t, start.Wait)
+	assert.Contains(t, string(start.Content), "How much is 2 + 2?")
+	assert.Contains(t, string(start.Content), "echo 'regular code blocks stay in the body'")
+
+	result := loadFlowNode(t, loader, "result")
+	assert.Equal(t, "You said {{ .answer }}.", string(result.Content))
+}
+
+func TestFileRepository_CloneKeepsValues(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "flow.yaml", `
+nodes:
+  start:
+    do: { name: charge, args: { amount: 9007199254740993, tags: [a, { b: 1 }] } }
+`)
+	repo, err := OpenFile(filepath.Join(dir, "flow.yaml"))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	doc, err := repo.Get(ctx, "start")
+	require.NoError(t, err)
+	args := doc.Metadata["do"].(map[string]any)["args"].(map[string]any)
+	assert.Equal(t, 9007199254740993, args["amount"], "values keep their decoded type (no float64 round-trip)")
+	args["tags"].([]any)[1].(map[string]any)["b"] = 2
+
+	again, err := repo.Get(ctx, "start")
+	require.NoError(t, err)
+	tags := again.Metadata["do"].(map[string]any)["args"].(map[string]any)["tags"].([]any)
+	assert.Equal(t, 1, tags[1].(map[string]any)["b"], "callers cannot mutate the parsed flow")
+}
+
+func TestParseFlowFile_Errors(t *testing.T) {
+	tests := map[string]struct {
+		name string
+		data string
+		err  string
+	}{
+		"no nodes":      {"a.yaml", "name: x\n", "no nodes"},
+		"bad entry":     {"a.yaml", "entry: nope\nnodes:\n  start: {}\n", "entry node 'nope'"},
+		"id mismatch":   {"a.json", `{"nodes": {"start": {"id": "other"}}}`, "different id"},
+		"duplicate":     {"a.md", "```yaml a\n```\n```yaml a\n```\n", "duplicate node id 'a'"},
+		"unterminated":  {"a.md", "```yaml a\nwait: true\n", "unterminated"},
+		"nodes as list": {"a.yaml", "nodes: [a, b]\n", "must be a map"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseFlowFile(tt.name, []byte(tt.data))
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestFileLoader_Watch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "flow.yaml")
+	writeFile(t, dir, "flow.yaml", "nodes:\n  start: { content: v1 }\n")
+
+	loader, _, err := NewFileLoader(path)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := loader.Watch(ctx)
+	require.NoError(t, err)
+
+	// Unrelated files in the same directory are ignored.
+	writeFile(t, dir, "notes.txt", "ignored")
+	require.NoError(t, os.WriteFile(path, []byte("nodes:\n  start: { content: v2 }\n"), 0644))
+
+	select {
+	case id := <-events:
+		assert.Equal(t, "flow.yaml", id)
+	case <-time.After(3 * time.Second):
+		t.Fatal("no reload event")
+	}
+	assert.Equal(t, "v2", string(loadFlowNode(t, loader, "start").Content))
+}
@@ -0,0 +1,143 @@
+package loam
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/aretw0/loam"
+	loamfs "github.com/aretw0/loam/pkg/adapters/fs"
+	"github.com/aretw0/loam/pkg/core"
+)
+
+// FSRepository is a read-only loam repository over an fs.FS, so flows can be shipped
+// inside the binary with `embed`:
+//
+//	//go:embed flows/support
+//	var flows embed.FS
+//
+//	sub, _ := fs.Sub(flows, "flows/support")
+//	loader := loam.NewFSLoader(sub)
+//
+// Documents are parsed with the same serializers (and lookup priority) as a Loam
+// directory. Embedded files never change, so the repository is not watchable.
+type FSRepository struct {
+	fsys        fs.FS
+	serializers map[string]loamfs.Serializer
+}
+
+// NewFSRepository creates a repository reading node files from fsys.
+func NewFSRepository(fsys fs.FS) *FSRepository {
+	// Strict serializers keep numbers as json.Number, as in openLoam.
+	return &FSRepository{fsys: fsys, serializers: loamfs.DefaultSerializers(true)}
+}
+
+// NewFSLoader returns a GraphLoader for the node files in fsys (templates and
+// `_defaults` included). Nodes carry their file position; opts may enable strict checks.
+func NewFSLoader(fsys fs.FS, opts ...Option) *Loader {
+	opts = append([]Option{withSources(dirSource{fsys: fsys})}, opts...)
+	return New(loam.NewTypedRepository[NodeMetadata](NewInheritRepository(NewFSRepository(fsys))), opts...)
+}
+
+// Get implements core.Repository.
+func (r *FSRepository) Get(ctx context.Context, id string) (core.Document, error) {
+	name := path.Clean(strings.TrimPrefix(id, "./"))
+	ext := path.Ext(name)
+	if ext == "" {
+		name = r.resolve(name)
+		ext = path.Ext(name)
+	}
+
+	serializer, ok := r.serializers[ext]
+	if !ok {
+		return core.Document{}, fmt.Errorf("no serializer registered for extension %s", ext)
+	}
+
+	f, err := r.fsys.Open(name)
+	if err != nil {
+		return core.Document{}, err
+	}
+	defer f.Close()
+
+	doc, err := serializer.Parse(f, "", true, "body")
+	if err != nil {
+		return core.Document{}, fmt.Errorf("failed to parse document %s: %w", id, err)
+	}
+	doc.ID = id
+	return *doc, nil
+}
+
+// resolve returns the file of an extensionless ID, with the same priority as the loam
+// filesystem adapter; missing nodes report the .md lookup.
+func (r *FSRepository) resolve(name string) string {
+	for _, candidate := range sourceExtensions {
+		if info, err := fs.Stat(r.fsys, name+candidate); err == nil && !info.IsDir() {
+			return name + candidate
+		}
+	}
+	return name + sourceExtensions[0]
+}
+
+// List implements core.Repository. Hidden files, directories and `trellis test` scenario
+// files (`*.test.yaml`) are skipped, and documents that fail to parse are left out
+// (GetNode reports the error). When several
+// files share an ID (start.md and start.yaml), only the one Get resolves is listed.
+func (r *FSRepository) List(ctx context.Context) ([]core.Document, error) {
+	var docs []core.Document
+	err := fs.WalkDir(r.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && p != "." {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isSourceFile(p) || IsScenario(p) {
+			return nil
+		}
+
+		id := strings.TrimSuffix(p, path.Ext(p))
+		if r.resolve(id) != p {
+			return nil // shadowed by a file with higher priority
+		}
+		doc, err := r.Get(ctx, p)
+		if err != nil {
+			return nil
+		}
+		docs = append(docs, core.Document{ID: id, Metadata: doc.Metadata})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	return docs, nil
+}
+
+// Save implements core.Repository. fs.FS flows are read-only.
+func (r *FSRepository) Save(ctx context.Context, doc core.Document) error {
+	return fmt.Errorf("fs repository is read-only")
+}
+
+// Delete implements core.Repository. fs.FS flows are read-only.
+func (r *FSRepository) Delete(ctx context.Context, id string) error {
+	return fmt.Errorf("fs repository is read-only")
+}
+
+// Initialize implements core.Repository.
+func (r *FSRepository) Initialize(ctx context.Context) error {
+	return nil
+}
+
+func isSourceFile(name string) bool {
+	ext := path.Ext(name)
+	for _, candidate := range sourceExtensions {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package loam
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/aretw0/loam"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+var fsFlow = map[string]string{
+	"start.md":            "---\nwait: true\nsave_to: name\nto: billing/charge\n---\nName?",
+	"_defaults.yaml":      "on_error: oops\n",
+	"billing/charge.yaml": "type: tool\ndo: { name: charge, args: { amount: 9007199254740993 } }\nto: done\n",
+	"done.json":           `{"content": "Bye {{ .name }}"}`,
+	"oops.md":             "Something went wrong",
+	".git/config.md":      "ignored",
+	"notes.txt":           "not a node",
+	"start.test.yaml":     "scenarios:\n  - name: greets\n    tools: { charge: { result: ok } }\n",
+}
+
+func TestFSLoader(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for name, content := range fsFlow {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	loader := NewFSLoader(fsys)
+
+	t.Run("Matches the directory loader", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, content := range fsFlow {
+			require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+			writeFile(t, dir, name, content)
+		}
+		repo, err := loam.Init(dir, loam.WithStrict(true), loam.WithReadOnly(true))
+		require.NoError(t, err)
+		disk := New(loam.NewTypedRepository[NodeMetadata](NewInheritRepository(repo)), WithSourceDir(dir))
+
+		for _, id := range []string{"start", "billing/charge", "done", "oops"} {
+			want, err := disk.GetNode(id)
+			require.NoError(t, err, id)
+			got, err := loader.GetNode(id)
+			require.NoError(t, err, id)
+			assert.JSONEq(t, string(want), string(got), id)
+		}
+
+		wantIDs, err := disk.ListNodes()
+		require.NoError(t, err)
+		ids, err := loader.ListNodes()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, wantIDs, ids)
+		assert.ElementsMatch(t, []string{"start", "billing/charge", "done", "oops"}, ids)
+	})
+
+	t.Run("Sources and defaults", func(t *testing.T) {
+		node := loadFlowNode(t, loader, "billing/charge")
+		assert.Equal(t, "oops", node.OnError)
+		assert.Equal(t, &domain.SourceRef{File: "billing/charge.yaml", Line: 1}, node.Source)
+	})
+
+	t.Run("Shadowed files are listed once", func(t *testing.T) {
+		shadowed := fstest.MapFS{
+			"start.md":   &fstest.MapFile{Data: []byte("Hi")},
+			"start.yaml": &fstest.MapFile{Data: []byte("content: Other\n")},
+		}
+		ids, err := NewFSLoader(shadowed).ListNodes()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"start"}, ids)
+	})
+
+	t.Run("Missing node", func(t *testing.T) {
+		_, err := loader.GetNode("ghost")
+		assert.ErrorIs(t, err, domain.ErrNodeNotFound)
+	})
+
+	t.Run("Strict", func(t *testing.T) {
+		strictFS := fstest.MapFS{"start.yaml": &fstest.MapFile{Data: []byte("content: Hi\ntoo: done\n")}}
+		_, err := NewFSLoader(strictFS, WithStrict(true)).GetNode("start")
+		assert.EqualError(t, err, `start.yaml:2: unknown field "too"; did you mean "to"?`)
+	})
+
+	t.Run("Static", func(t *testing.T) {
+		_, err := loader.Watch(context.Background())
+		assert.ErrorIs(t, err, domain.ErrWatchUnsupported)
+	})
+}
@@ -0,0 +1,283 @@
+package loam
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aretw0/loam/pkg/core"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/manifest"
+)
+
+// DefaultsID is the name of folder-level default documents (`_defaults.md`, `_defaults.yaml`...).
+const DefaultsID = "_defaults"
+
+// Inheritance rules for `extends` and `_defaults`: the child wins on conflicts,
+// except for the keys below. A `null` value removes an inherited key.
+var (
+	// appendKeys accumulate: inherited entries come first.
+	appendKeys = map[string]bool{"tools": true, "required_context": true}
+	// replaceKeys are taken as a whole, even when both sides are maps.
+	replaceKeys = map[string]bool{"do": true, "tool_call": true, "undo": true, "transitions": true, "options": true}
+	// ownKeys are never inherited.
+	ownKeys = map[string]bool{"id": true, "extends": true}
+)
+
+// IsPartial reports whether a document is a building block for other nodes
+// (`_defaults` or a template such as `_base_tool`) rather than a graph node.
+func IsPartial(id string) bool {
+	return strings.HasPrefix(path.Base(trimExtension(id)), "_")
+}
+
+// ScenarioExtensions are the file extensions of `trellis test` scenario files.
+var ScenarioExtensions = []string{".test.yaml", ".test.yml"}
+
+// IsScenario reports whether a file name is a `trellis test` scenario file (`*.test.yaml`),
+// kept next to the nodes it tests but not part of the graph. The Loam directory adapter
+// does not list these files; FSRepository skips them.
+func IsScenario(name string) bool {
+	for _, ext := range ScenarioExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// InheritRepository resolves node inheritance on top of a document repository,
+// before the metadata is decoded:
+//
+//   - `extends: <id>` merges the (resolved) definition of another node;
+//   - `_defaults` documents apply to every node in their folder and subfolders.
+//
+// Precedence, lowest first: the defaults of folders only the base is in, the node's
+// folder defaults (outer, then inner), the base node, the node itself.
+type InheritRepository struct {
+	core.Repository
+}
+
+// NewInheritRepository wraps repo with inheritance resolution.
+func NewInheritRepository(repo core.Repository) *InheritRepository {
+	return &InheritRepository{Repository: repo}
+}
+
+// Get implements core.Repository, returning the effective document.
+func (r *InheritRepository) Get(ctx context.Context, id string) (core.Document, error) {
+	doc, levels, own, err := r.resolve(ctx, id, nil)
+	if err != nil || own == nil {
+		return doc, err
+	}
+	defaults, err := r.defaults(ctx, levels)
+	if err != nil {
+		return core.Document{}, err
+	}
+	doc.Metadata = mergeMetadata(defaults, own)
+	delete(doc.Metadata, "extends")
+	return doc, nil
+}
+
+// List implements core.Repository. The project manifest and vendored packages are left
+// out, since their documents do not decode as nodes.
+func (r *InheritRepository) List(ctx context.Context) ([]core.Document, error) {
+	docs, err := r.Repository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes := docs[:0]
+	for _, doc := range docs {
+		if doc.ID == manifest.DocumentID || strings.HasPrefix(filepath.ToSlash(doc.ID), manifest.VendorDir+"/") {
+			continue
+		}
+		nodes = append(nodes, doc)
+	}
+	return nodes, nil
+}
+
+// Watch implements core.Watchable by delegating to the wrapped repository.
+func (r *InheritRepository) Watch(ctx context.Context, pattern string) (<-chan core.Event, error) {
+	if w, ok := r.Repository.(core.Watchable); ok {
+		return w.Watch(ctx, pattern)
+	}
+	return nil, fmt.Errorf("repository: %w", domain.ErrWatchUnsupported)
+}
+
+// resolve returns the document with its inheritance layers kept apart: the folders whose
+// defaults apply (the base's, then the node's) and the definitions (the base's, then the
+// node's). Layering them only at the end keeps every default below every definition,
+// whichever folder the base lives in. own is nil for `_defaults` documents.
+func (r *InheritRepository) resolve(ctx context.Context, id string, chain []string) (doc core.Document, levels []string, own core.Metadata, err error) {
+	doc, err = r.Repository.Get(ctx, id)
+	if err != nil || path.Base(trimExtension(id)) == DefaultsID {
+		return doc, nil, nil, err
+	}
+
+	key := trimExtension(strings.TrimPrefix(id, "./"))
+	for i, seen := range chain {
+		if seen == key {
+			// DFS Cycle Detection (same approach as tool imports)
+			cycle := append(append([]string{}, chain[i:]...), key)
+			return core.Document{}, nil, nil, fmt.Errorf("cycle detected in extends: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	chain = append(chain, key)
+
+	levels = folderLevels(key)
+	own = core.Metadata{}
+
+	if base, ok := doc.Metadata["extends"].(string); ok && base != "" {
+		parent, parentLevels, parentOwn, err := r.resolve(ctx, base, chain)
+		if errors.Is(err, fs.ErrNotExist) {
+			return core.Document{}, nil, nil, fmt.Errorf("node %s: extends unknown node '%s'", key, base)
+		}
+		if err != nil {
+			return core.Document{}, nil, nil, err
+		}
+		if parentOwn == nil { // Extending a `_defaults` document
+			parentOwn = parent.Metadata
+		}
+		levels = layerLevels(parentLevels, levels)
+		own = parentOwn
+		if strings.TrimSpace(doc.Content) == "" {
+			doc.Content = parent.Content
+		}
+	}
+
+	// Nulls stay in the definitions, so they still remove inherited defaults.
+	return doc, levels, layerMetadata(own, doc.Metadata), nil
+}
+
+// folderLevels lists the folders whose `_defaults` apply to a node, from the root down.
+func folderLevels(key string) []string {
+	levels := []string{""}
+	if dir := path.Dir(key); dir != "." {
+		parts := strings.Split(dir, "/")
+		for i := range parts {
+			levels = append(levels, strings.Join(parts[:i+1], "/"))
+		}
+	}
+	return levels
+}
+
+// layerLevels puts the base's folders under the node's own: folders they share apply
+// once, in the node's order, after the ones only the base has.
+func layerLevels(base, own []string) []string {
+	out := make([]string, 0, len(base)+len(own))
+	for _, level := range base {
+		if !slices.Contains(own, level) {
+			out = append(out, level)
+		}
+	}
+	return append(out, own...)
+}
+
+// defaults merges the `_defaults` documents of the given folders, in order.
+func (r *InheritRepository) defaults(ctx context.Context, levels []string) (core.Metadata, error) {
+	meta := core.Metadata{}
+	for _, level := range levels {
+		id := path.Join(level, DefaultsID)
+		doc, err := r.Repository.Get(ctx, id)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", id, err)
+		}
+		meta = mergeMetadata(meta, doc.Metadata)
+	}
+	return meta, nil
+}
+
+// mergeMetadata layers child node metadata over base without mutating either.
+func mergeMetadata(base, child map[string]any) core.Metadata {
+	return core.Metadata(mergeMaps(inheritable(base), child, true, false))
+}
+
+// layerMetadata is mergeMetadata for definitions still to be layered over defaults:
+// `null` values are kept instead of applied.
+func layerMetadata(base, child map[string]any) core.Metadata {
+	return core.Metadata(mergeMaps(inheritable(base), child, true, true))
+}
+
+func inheritable(base map[string]any) map[string]any {
+	inherited := make(map[string]any, len(base))
+	for k, v := range base {
+		if !ownKeys[k] {
+			inherited[k] = v
+		}
+	}
+	return inherited
+}
+
+// mergeMaps applies the inheritance rules; the key rules only hold at the top level.
+func mergeMaps(base, child map[string]any, top, keepNulls bool) map[string]any {
+	out := make(map[string]any, len(base)+len(child))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range child {
+		switch {
+		case v == nil && keepNulls:
+			out[k] = nil
+		case v == nil:
+			delete(out, k)
+		case top && appendKeys[k]:
+			out[k] = appendList(out[k], v, k == "required_context")
+		case top && replaceKeys[k]:
+			out[k] = v
+		default:
+			baseMap, baseOK := asMap(out[k])
+			childMap, childOK := asMap(v)
+			if baseOK && childOK {
+				out[k] = mergeMaps(baseMap, childMap, false, keepNulls)
+			} else {
+				out[k] = v
+			}
+		}
+	}
+	return out
+}
+
+func appendList(base, child any, unique bool) any {
+	baseList, ok := base.([]any)
+	childList, childOK := child.([]any)
+	if !ok || !childOK {
+		return child
+	}
+	out := append([]any{}, baseList...)
+	for _, item := range childList {
+		if unique && containsString(out, item) {
+			continue
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func containsString(list []any, value any) bool {
+	s, ok := value.(string)
+	if !ok {
+		return false
+	}
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func asMap(v any) (map[string]any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		return m, true
+	case core.Metadata:
+		return m, true
+	}
+	return nil, false
+}
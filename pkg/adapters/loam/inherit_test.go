@@ -0,0 +1,182 @@
+package loam
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aretw0/loam"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+func TestLoader_Inheritance(t *testing.T) {
+	tmpDir, repo := testutils.SetupTestRepo(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "billing"), 0755))
+
+	files := map[string]string{
+		"_defaults.yaml": `
+on_error: oops
+timeout: 30s
+on_signal: { interrupt: bye }
+metadata: { team: core }
+tools: [{ name: log }]
+`,
+		"billing/_defaults.md": "---\nmetadata:\n  owner: billing\n---\n",
+		"_tool.md": `---
+type: tool
+on_denied: denied
+required_context: [user_id]
+tools: [{ name: audit }]
+---
+Working on it...`,
+		"billing/charge.md": `---
+extends: _tool
+do: { name: charge }
+timeout: null
+required_context: [user_id, amount]
+to: done
+---
+`,
+		"billing/refund.md": `---
+extends: _tool
+on_error: refund_failed
+metadata: { owner: support }
+do: { name: refund }
+---
+Refunding.`,
+		"done.md":   "Done.",
+		"loop_a.md": "---\nextends: loop_b\n---\nA",
+		"loop_b.md": "---\nextends: loop_a\n---\nB",
+		"orphan.md": "---\nextends: ghost\n---\nO",
+	}
+	for name, content := range files {
+		writeFile(t, tmpDir, name, content)
+	}
+
+	loader := New(loam.NewTypedRepository[NodeMetadata](NewInheritRepository(repo)))
+
+	t.Run("Defaults, template and overrides", func(t *testing.T) {
+		node := loadFlowNode(t, loader, "billing/charge")
+		assert.Equal(t, "billing/charge", node.ID)
+		assert.Equal(t, domain.NodeTypeTool, node.Type)
+		assert.Equal(t, "Working on it...", string(node.Content), "content comes from the template")
+		assert.Equal(t, "oops", node.OnError)
+		assert.Equal(t, "denied", node.OnDenied)
+		assert.Empty(t, node.Timeout, "null removes an inherited key")
+		assert.Equal(t, map[string]string{"interrupt": "bye"}, node.OnSignal)
+		assert.Equal(t, map[string]string{"team": "core", "owner": "billing"}, node.Metadata)
+		assert.Equal(t, []string{"user_id", "amount"}, node.RequiredContext)
+		require.Len(t, node.Tools, 2)
+		assert.Equal(t, "log", node.Tools[0].Name)
+		assert.Equal(t, "audit", node.Tools[1].Name)
+		require.NotNil(t, node.Do)
+		assert.Equal(t, "charge", node.Do.Name)
+	})
+
+	t.Run("Node wins over inherited values", func(t *testing.T) {
+		node := loadFlowNode(t, loader, "billing/refund")
+		assert.Equal(t, "Refunding.", string(node.Content))
+		assert.Equal(t, "refund_failed", node.OnError)
+		assert.Equal(t, "30s", node.Timeout)
+		assert.Equal(t, map[string]string{"team": "core", "owner": "support"}, node.Metadata)
+	})
+
+	t.Run("Cycle detection", func(t *testing.T) {
+		_, err := loader.GetNode("loop_a")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cycle detected in extends: loop_a -> loop_b -> loop_a")
+	})
+
+	t.Run("Unknown base", func(t *testing.T) {
+		_, err := loader.GetNode("orphan")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "node orphan: extends unknown node 'ghost'")
+	})
+
+	t.Run("Partials are not listed", func(t *testing.T) {
+		ids, err := loader.ListNodes()
+		require.NoError(t, err)
+		assert.NotContains(t, ids, "_defaults")
+		assert.NotContains(t, ids, "billing/_defaults")
+		assert.NotContains(t, ids, "_tool")
+		assert.Contains(t, ids, "billing/charge")
+	})
+}
+
+func TestLoader_NestedDefaults(t *testing.T) {
+	tmpDir, repo := testutils.SetupTestRepo(t)
+	for _, dir := range []string{"billing/eu", "templates"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, dir), 0755))
+	}
+
+	files := map[string]string{
+		"_defaults.yaml":           "on_error: oops\ntimeout: 30s\nmetadata: { team: core, tier: 1 }\ntools: [{ name: log }]\n",
+		"billing/_defaults.yaml":   "on_error: billing_failed\nmetadata: { team: billing }\n",
+		"billing/eu/_defaults.yml": "timeout: 10s\n",
+		"templates/_defaults.yaml": "on_denied: denied\nmetadata: { tier: 2 }\ntools: [{ name: trace }]\n",
+		"_tool.md":                 "---\ntype: tool\n---\nWorking on it...",
+		"templates/_remote.md":     "---\ntype: tool\ntimeout: 5m\n---\nCalling...",
+		"billing/eu/charge.md":     "---\nextends: _tool\ndo: { name: charge }\n---\n",
+		"billing/eu/refund.md":     "---\nextends: templates/_remote\ndo: { name: refund }\n---\n",
+	}
+	for name, content := range files {
+		writeFile(t, tmpDir, name, content)
+	}
+	loader := New(loam.NewTypedRepository[NodeMetadata](NewInheritRepository(repo)))
+
+	t.Run("Inner defaults win over the template's outer defaults", func(t *testing.T) {
+		node := loadFlowNode(t, loader, "billing/eu/charge")
+		assert.Equal(t, "billing_failed", node.OnError)
+		assert.Equal(t, "10s", node.Timeout)
+		assert.Equal(t, map[string]string{"team": "billing", "tier": "1"}, node.Metadata)
+		require.Len(t, node.Tools, 1, "shared folders apply once")
+		assert.Equal(t, "log", node.Tools[0].Name)
+	})
+
+	t.Run("Template definitions win over defaults", func(t *testing.T) {
+		node := loadFlowNode(t, loader, "billing/eu/refund")
+		assert.Equal(t, "5m", node.Timeout)
+		assert.Equal(t, "billing_failed", node.OnError)
+		assert.Equal(t, "denied", node.OnDenied, "the template's folder defaults still apply")
+		assert.Equal(t, map[string]string{"team": "billing", "tier": "1"}, node.Metadata, "below the node's folder defaults")
+		require.Len(t, node.Tools, 2)
+		assert.Equal(t, "trace", node.Tools[0].Name)
+		assert.Equal(t, "log", node.Tools[1].Name)
+	})
+}
+
+func TestFileLoader_Inheritance(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "flow.yaml", `
+nodes:
+  _defaults:
+    on_error: oops
+  start:
+    content: Hi
+    to: oops
+  oops:
+    content: Something went wrong
+    on_error: null
+`)
+	loader, _, err := NewFileLoader(filepath.Join(dir, "flow.yaml"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "oops", loadFlowNode(t, loader, "start").OnError)
+	assert.Empty(t, loadFlowNode(t, loader, "oops").OnError)
+
+	ids, err := loader.ListNodes()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"start", "oops"}, ids)
+}
+
+func TestIsScenario(t *testing.T) {
+	assert.True(t, IsScenario("start.test.yaml"))
+	assert.True(t, IsScenario("billing/charge.test.yml"))
+	assert.False(t, IsScenario("start.test"), "node IDs are not scenario files")
+	assert.False(t, IsScenario("release.test.md"))
+	assert.False(t, IsScenario("start.yaml"))
+}
@@ -3,25 +3,56 @@ package loam
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/aretw0/loam"
 	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/manifest"
 	"github.com/mitchellh/mapstructure"
 )
 
 // Loader adapts the Loam library to the Trellis GraphLoader interface.
 type Loader struct {
 	Repo *loam.TypedRepository[NodeMetadata]
+
+	strict  bool
+	sources sourceLocator
+}
+
+// Option configures the Loam adapter.
+type Option func(*Loader)
+
+// WithSourceDir points the adapter at the directory holding the node files,
+// so nodes carry their file position (`source`) and errors can cite it.
+func WithSourceDir(dir string) Option {
+	return func(l *Loader) {
+		l.sources = dirSource{fsys: os.DirFS(dir)}
+	}
+}
+
+// WithStrict rejects unknown keys and values of the wrong type in node metadata,
+// reporting each problem as a file:line diagnostic. It needs a source (WithSourceDir
+// or a single-file flow); nodes without one are loaded as usual.
+func WithStrict(strict bool) Option {
+	return func(l *Loader) {
+		l.strict = strict
+	}
 }
 
 // New creates a new Loam adapter.
-func New(repo *loam.TypedRepository[NodeMetadata]) *Loader {
-	return &Loader{
+func New(repo *loam.TypedRepository[NodeMetadata], opts ...Option) *Loader {
+	l := &Loader{
 		Repo: repo,
 	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
 }
 
 // GetNode retrieves a node from the Loam repository using the direct Service API.
@@ -29,11 +60,28 @@ func New(repo *loam.TypedRepository[NodeMetadata]) *Loader {
 func (l *Loader) GetNode(id string) ([]byte, error) {
 	ctx := context.Background()
 
+	var src *nodeSource
+	if l.sources != nil {
+		src = l.sources.locate(id, l.strict)
+	}
+	// Strict checks run on the authored YAML, before loam decodes (and loses) positions.
+	if l.strict && src != nil && src.Meta != nil {
+		if err := checkMetadata(src); err != nil {
+			return nil, err
+		}
+	}
+
 	// Loam Normalized Retrieval.
 	// We trust Loam to find the file (e.g. start.md) even if we ask for "start",
 	// or we assume the seeding created "start" (which maps to start.md).
 	doc, err := l.Repo.Get(ctx, id)
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = fmt.Errorf("%w: %w", domain.ErrNodeNotFound, err)
+		}
+		if src != nil {
+			return nil, fmt.Errorf("%s: loam get failed for %s: %w", src.File, id, err)
+		}
 		return nil, fmt.Errorf("loam get failed for %s: %w", id, err)
 	}
 
@@ -49,6 +97,9 @@ func (l *Loader) GetNode(id string) ([]byte, error) {
 	}
 
 	l.applyMetadataAndTimeout(doc.Data, data)
+	if src != nil {
+		data["source"] = domain.SourceRef{File: src.File, Line: src.Line}
+	}
 
 	bytes, err := json.Marshal(data)
 	if err != nil {
@@ -79,11 +130,18 @@ func (l *Loader) buildTransitions(meta NodeMetadata) []domain.Transition {
 		if condition == "" && lt.Text != "" {
 			condition = fmt.Sprintf("input == '%s'", strings.ReplaceAll(lt.Text, "'", "\\'"))
 		}
+		label := lt.Text
+		if label == "" {
+			label = lt.Label
+		}
 
 		return domain.Transition{
 			FromNodeID: from,
 			ToNodeID:   to,
 			Condition:  condition,
+			Label:      label,
+			Synonyms:   lt.Synonyms,
+			Keywords:   lt.Keywords,
 		}
 	}
 
@@ -128,6 +186,9 @@ func (l *Loader) buildBaseNodeData(docID string, meta NodeMetadata, content stri
 	}
 
 	l.applySignalSugar(meta, data)
+	if len(meta.OnSignalDefault) > 0 {
+		data["on_signal_default"] = meta.OnSignalDefault
+	}
 
 	if meta.SaveTo != "" {
 		data["save_to"] = meta.SaveTo
@@ -139,14 +200,39 @@ func (l *Loader) buildBaseNodeData(docID string, meta NodeMetadata, content stri
 		data["default_context"] = meta.DefaultContext
 	}
 	if len(meta.ContextSchema) > 0 {
-		normalized, err := normalizeContextSchema(meta.ContextSchema)
+		normalized, err := normalizeSchema("context_schema", meta.ContextSchema)
 		if err != nil {
 			return nil, err
 		}
 		data["context_schema"] = normalized
 	}
+	if len(meta.OutputSchema) > 0 {
+		normalized, err := normalizeSchema("output_schema", meta.OutputSchema)
+		if err != nil {
+			return nil, err
+		}
+		data["output_schema"] = normalized
+	}
+	if meta.Prompt != "" {
+		data["prompt"] = meta.Prompt
+	}
+	if meta.System != "" {
+		data["system"] = meta.System
+	}
+	if meta.Model != "" {
+		data["model"] = meta.Model
+	}
+	if meta.OnUnclear != "" {
+		data["on_unclear"] = meta.OnUnclear
+	}
+	if meta.MinConfidence > 0 {
+		data["min_confidence"] = meta.MinConfidence
+	}
 
 	data["transitions"] = transitions
+	if meta.StrictTransitions {
+		data["strict_transitions"] = true
+	}
 	data["content"] = []byte(content)
 
 	if len(meta.Messages) > 0 {
@@ -156,7 +242,7 @@ func (l *Loader) buildBaseNodeData(docID string, meta NodeMetadata, content stri
 	return data, nil
 }
 
-func normalizeContextSchema(raw map[string]any) (map[string]string, error) {
+func normalizeSchema(field string, raw map[string]any) (map[string]string, error) {
 	if len(raw) == 0 {
 		return nil, nil
 	}
@@ -165,7 +251,7 @@ func normalizeContextSchema(raw map[string]any) (map[string]string, error) {
 	for key, value := range raw {
 		typeStr, err := formatSchemaType(value)
 		if err != nil {
-			return nil, fmt.Errorf("context_schema.%s: %w", key, err)
+			return nil, fmt.Errorf("%s.%s: %w", field, key, err)
 		}
 		normalized[key] = typeStr
 	}
@@ -231,7 +317,8 @@ func (l *Loader) applyInputConfig(meta NodeMetadata, data map[string]any) {
 		if len(inputOptions) == 0 {
 			inputOptions = inferredOptions
 		}
-		if inputType == "" {
+		// Route nodes accept free text: options are only hints.
+		if inputType == "" && meta.Type != domain.NodeTypeRoute {
 			inputType = string(domain.InputChoice)
 		}
 	}
@@ -245,12 +332,28 @@ func (l *Loader) applyInputConfig(meta NodeMetadata, data map[string]any) {
 		data["input_options"] = inputOptions
 		data["input_default"] = meta.InputDefault
 	}
+	if meta.Type == domain.NodeTypeRoute && len(inputOptions) > 0 {
+		data["input_options"] = inputOptions
+	}
 }
 
 func (l *Loader) applyToolConfig(ctx context.Context, nodeID string, meta NodeMetadata, data map[string]any) error {
 	var toolCall *LoaderToolCall
-	if meta.Do != nil {
-		toolCall = meta.Do
+	if meta.Do.IsBatch() {
+		batch := make([]domain.BatchCall, 0, len(meta.Do.Batch))
+		for _, entry := range meta.Do.Batch {
+			call := convertToolCall(&entry.LoaderToolCall)
+			if call.ID == "" {
+				call.ID = call.Name
+			}
+			batch = append(batch, domain.BatchCall{ToolCall: *call, Undo: convertToolCall(entry.Undo)})
+		}
+		data["batch"] = batch
+		if meta.BatchPolicy != "" {
+			data["batch_policy"] = meta.BatchPolicy
+		}
+	} else if meta.Do != nil {
+		toolCall = &meta.Do.LoaderToolCall
 	} else if meta.ToolCall != nil {
 		toolCall = meta.ToolCall
 	}
@@ -289,6 +392,9 @@ func (l *Loader) applyMetadataAndTimeout(meta NodeMetadata, data map[string]any)
 	if meta.Timeout != "" {
 		data["timeout"] = meta.Timeout
 	}
+	if meta.Budget != nil {
+		data["budget"] = meta.Budget
+	}
 }
 
 // resolveTools recursively resolves polymorphic tool definitions (inline maps or import strings).
@@ -380,6 +486,12 @@ func (l *Loader) ListNodes() ([]string, error) {
 	ids := make([]string, 0, len(docs))
 
 	for _, doc := range docs {
+		// The project manifest, vendored packages and partials (_defaults, templates)
+		// live next to the nodes but are not part of the graph.
+		if doc.ID == manifest.DocumentID || strings.HasPrefix(filepath.ToSlash(doc.ID), manifest.VendorDir+"/") || IsPartial(doc.ID) {
+			continue
+		}
+
 		// Use the ID from metadata if available, otherwise filename ID
 		rawID := doc.Data.ID
 		if rawID == "" {
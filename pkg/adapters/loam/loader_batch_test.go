@@ -0,0 +1,78 @@
+package loam
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aretw0/loam"
+
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_BatchDo(t *testing.T) {
+	tmpDir, repo := testutils.SetupTestRepo(t)
+
+	content := `---
+id: notify
+batch_policy: rollback
+do:
+  - name: email
+    args:
+      to: "{{ .user }}"
+    undo:
+      name: unsend
+  - id: sms_primary
+    name: sms
+budget:
+  max_cost: 1.5
+to: done
+---
+Notifying...`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "notify.md"), []byte(content), 0644))
+
+	loader := New(loam.NewTypedRepository[NodeMetadata](repo))
+
+	data, err := loader.GetNode("notify")
+	require.NoError(t, err)
+
+	var node domain.Node
+	require.NoError(t, json.Unmarshal(data, &node))
+
+	assert.Nil(t, node.Do)
+	require.Len(t, node.Batch, 2)
+	assert.Equal(t, "email", node.Batch[0].ID)
+	assert.Equal(t, "{{ .user }}", node.Batch[0].Args["to"])
+	require.NotNil(t, node.Batch[0].Undo)
+	assert.Equal(t, "unsend", node.Batch[0].Undo.Name)
+	assert.Equal(t, "sms_primary", node.Batch[1].ID)
+	assert.Equal(t, domain.BatchPolicyRollback, node.BatchPolicy)
+	require.NotNil(t, node.Budget)
+	assert.Equal(t, 1.5, node.Budget.MaxCost)
+}
+
+func TestLoader_SingleDoStillWorks(t *testing.T) {
+	tmpDir, repo := testutils.SetupTestRepo(t)
+
+	content := `---
+id: single
+do:
+  name: ping
+---
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "single.md"), []byte(content), 0644))
+
+	loader := New(loam.NewTypedRepository[NodeMetadata](repo))
+	data, err := loader.GetNode("single")
+	require.NoError(t, err)
+
+	var node domain.Node
+	require.NoError(t, json.Unmarshal(data, &node))
+	require.NotNil(t, node.Do)
+	assert.Equal(t, "ping", node.Do.ID)
+	assert.Empty(t, node.Batch)
+}
@@ -0,0 +1,48 @@
+package loam
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aretw0/loam"
+
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_LLMNode(t *testing.T) {
+	tmpDir, repo := testutils.SetupTestRepo(t)
+
+	content := `---
+id: triage
+type: llm
+model: gpt-4o-mini
+system: You are a triage bot.
+output_schema:
+  category: string
+  tags: [string]
+save_to: triage
+transitions:
+  - to: billing
+  - to: support
+---
+Classify: {{ .ticket }}`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "triage.md"), []byte(content), 0644))
+
+	loader := New(loam.NewTypedRepository[NodeMetadata](repo))
+	data, err := loader.GetNode("triage")
+	require.NoError(t, err)
+
+	var node domain.Node
+	require.NoError(t, json.Unmarshal(data, &node))
+	assert.Equal(t, domain.NodeTypeLLM, node.Type)
+	assert.Equal(t, "gpt-4o-mini", node.Model)
+	assert.Equal(t, "You are a triage bot.", node.System)
+	require.Len(t, node.OutputSchema, 2)
+	assert.Equal(t, "[string]", node.OutputSchema["tags"].Name())
+	assert.Contains(t, string(node.Content), "Classify")
+}
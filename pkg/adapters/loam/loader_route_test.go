@@ -0,0 +1,52 @@
+package loam
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aretw0/loam"
+
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_RouteNode(t *testing.T) {
+	tmpDir, repo := testutils.SetupTestRepo(t)
+
+	content := `---
+id: confirm
+type: route
+on_unclear: clarify
+min_confidence: 0.8
+options:
+  - text: "Yes"
+    synonyms: [absolutely]
+    to: confirmed
+  - text: "No"
+    keywords: [cancel]
+    to: cancelled
+---
+Shall we proceed?`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "confirm.md"), []byte(content), 0644))
+
+	loader := New(loam.NewTypedRepository[NodeMetadata](repo))
+	data, err := loader.GetNode("confirm")
+	require.NoError(t, err)
+
+	var node domain.Node
+	require.NoError(t, json.Unmarshal(data, &node))
+	assert.Equal(t, domain.NodeTypeRoute, node.Type)
+	assert.Equal(t, "clarify", node.OnUnclear)
+	assert.Equal(t, 0.8, node.MinConfidence)
+	assert.Empty(t, node.InputType, "route nodes are not restricted to choices")
+	assert.Equal(t, []string{"Yes", "No"}, node.InputOptions)
+
+	require.Len(t, node.Transitions, 2)
+	assert.Equal(t, "Yes", node.Transitions[0].Label)
+	assert.Equal(t, []string{"absolutely"}, node.Transitions[0].Synonyms)
+	assert.Equal(t, []string{"cancel"}, node.Transitions[1].Keywords)
+}
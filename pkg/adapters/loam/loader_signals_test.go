@@ -34,6 +34,12 @@ on_signal:
   timeout: explicit_handler
 ---
 Conflict Test (Sugar should typically win or merge)`,
+		"global.md": `---
+type: text
+on_signal_default:
+  interrupt: goodbye
+---
+Global Handlers`,
 	}
 
 	for filename, content := range files {
@@ -51,6 +57,12 @@ Conflict Test (Sugar should typically win or merge)`,
 		assert.Contains(t, jsonStr, `"`+domain.SignalTimeout+`":"my_timeout_handler"`)
 	})
 
+	t.Run("on_signal_default mapping", func(t *testing.T) {
+		data, err := loader.GetNode("global")
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"on_signal_default":{"`+domain.SignalInterrupt+`":"goodbye"}`)
+	})
+
 	t.Run("on_interrupt mapping", func(t *testing.T) {
 		data, err := loader.GetNode("interrupt")
 		require.NoError(t, err)
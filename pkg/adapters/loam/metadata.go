@@ -1,11 +1,18 @@
 package loam
 
-import "github.com/aretw0/trellis/pkg/domain"
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
 
 // NodeMetadata represents the header/metadata of a Trellis Node.
 // It uses "mapstructure" tags to match standard Frontmatter/YAML keys (to, from).
 type NodeMetadata struct {
-	ID          string             `json:"id" mapstructure:"id"`
+	ID string `json:"id" mapstructure:"id"`
+	// Extends names a base node whose definition is merged into this one (see InheritRepository)
+	Extends     string             `json:"extends,omitempty" mapstructure:"extends"`
 	Type        string             `json:"type" mapstructure:"type"`
 	Transitions []LoaderTransition `json:"transitions" mapstructure:"transitions"`
 	Options     []LoaderTransition `json:"options" mapstructure:"options"`
@@ -18,10 +25,15 @@ type NodeMetadata struct {
 	OnTimeout string `json:"on_timeout" mapstructure:"on_timeout"`
 	// OnInterrupt is syntactic sugar for on_signal["interrupt"]
 	OnInterrupt string `json:"on_interrupt" mapstructure:"on_interrupt"`
-	Wait        bool   `json:"wait" mapstructure:"wait"`
+	// OnSignalDefault declares global signal handlers (entry node only)
+	OnSignalDefault map[string]string `json:"on_signal_default,omitempty" mapstructure:"on_signal_default"`
+	Wait            bool              `json:"wait" mapstructure:"wait"`
 	// SaveTo captures the input into a variable in the context
 	SaveTo string `json:"save_to" mapstructure:"save_to"`
 
+	// StrictTransitions fails navigation when no transition matches the input
+	StrictTransitions bool `json:"strict_transitions,omitempty" mapstructure:"strict_transitions"`
+
 	// RequiredContext lists keys that MUST exist in the context
 	RequiredContext []string `json:"required_context" mapstructure:"required_context"`
 
@@ -41,9 +53,24 @@ type NodeMetadata struct {
 
 	// Tool Config
 	ToolCall *LoaderToolCall `json:"tool_call" mapstructure:"tool_call"`
-	Do       *LoaderToolCall `json:"do" mapstructure:"do"`
+	Do       *LoaderDo       `json:"do" mapstructure:"do"`
 	Tools    []any           `json:"tools" mapstructure:"tools"`
 	Undo     *LoaderToolCall `json:"undo,omitempty" mapstructure:"undo"`
+	// BatchPolicy controls partial failure when `do` is a list (all, continue, rollback)
+	BatchPolicy string `json:"batch_policy,omitempty" mapstructure:"batch_policy"`
+
+	// LLM Config (type: llm)
+	Prompt       string         `json:"prompt,omitempty" mapstructure:"prompt"`
+	System       string         `json:"system,omitempty" mapstructure:"system"`
+	Model        string         `json:"model,omitempty" mapstructure:"model"`
+	OutputSchema map[string]any `json:"output_schema,omitempty" mapstructure:"output_schema"`
+
+	// Route Config (type: route)
+	OnUnclear     string  `json:"on_unclear,omitempty" mapstructure:"on_unclear"`
+	MinConfidence float64 `json:"min_confidence,omitempty" mapstructure:"min_confidence"`
+
+	// Budget declares flow-level tool spending limits (entry node only)
+	Budget *domain.Budget `json:"budget,omitempty" mapstructure:"budget"`
 
 	// General Metadata
 	Metadata map[string]any `json:"metadata" mapstructure:"metadata"`
@@ -62,6 +89,12 @@ type LoaderTransition struct {
 	// Text is the display label for options/buttons.
 	// It is also used as the implicit match condition (Condition="input == Text") if Condition is empty.
 	Text string `json:"text" mapstructure:"text"`
+	// Label names the transition without implying a condition (graph exports use it
+	// for labelled fallbacks). Text takes precedence when both are set.
+	Label string `json:"label,omitempty" mapstructure:"label"`
+	// Synonyms and Keywords help route nodes classify free-text input onto this option.
+	Synonyms []string `json:"synonyms,omitempty" mapstructure:"synonyms"`
+	Keywords []string `json:"keywords,omitempty" mapstructure:"keywords"`
 }
 
 // LoaderToolCall is a permissive version of domain.ToolCall for YAML decoding.
@@ -72,3 +105,38 @@ type LoaderToolCall struct {
 	Metadata       map[string]any `json:"metadata" mapstructure:"metadata"`
 	IdempotencyKey string         `json:"idempotency_key" mapstructure:"idempotency_key"`
 }
+
+// LoaderDo is the polymorphic `do:` key: a single call (object) or a batch (list).
+type LoaderDo struct {
+	LoaderToolCall
+	Batch []LoaderBatchCall
+}
+
+// LoaderBatchCall is one entry of a `do:` list, with its own optional compensation.
+type LoaderBatchCall struct {
+	LoaderToolCall
+	Undo *LoaderToolCall `json:"undo,omitempty" mapstructure:"undo"`
+}
+
+// IsBatch reports whether `do:` was authored as a list.
+func (d *LoaderDo) IsBatch() bool {
+	return d != nil && d.Batch != nil
+}
+
+// UnmarshalJSON accepts either an object or a list of objects.
+func (d *LoaderDo) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		d.Batch = []LoaderBatchCall{}
+		return json.Unmarshal(trimmed, &d.Batch)
+	}
+	return json.Unmarshal(trimmed, &d.LoaderToolCall)
+}
+
+// MarshalJSON preserves the authored shape.
+func (d LoaderDo) MarshalJSON() ([]byte, error) {
+	if d.Batch != nil {
+		return json.Marshal(d.Batch)
+	}
+	return json.Marshal(d.LoaderToolCall)
+}
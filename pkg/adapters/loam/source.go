@@ -0,0 +1,93 @@
+package loam
+
+import (
+	"bytes"
+	"io/fs"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// nodeSource is where a node was authored.
+type nodeSource struct {
+	// File is relative to the source root (or the flow file name).
+	File string
+	// Line is the 1-based line where the node starts.
+	Line int
+	// Meta is the authored metadata with file-accurate line numbers (nil when unavailable).
+	Meta *yaml.Node
+}
+
+// sourceLocator finds the file behind a node ID. Metadata is only parsed when asked for,
+// since positions are cheap but strict checks need the full YAML tree.
+type sourceLocator interface {
+	locate(id string, parse bool) *nodeSource
+}
+
+// sourceExtensions mirrors the lookup priority of the loam filesystem adapter.
+var sourceExtensions = []string{".md", ".json", ".yaml", ".yml"}
+
+// dirSource locates nodes stored as one file per node (on disk or embedded).
+type dirSource struct {
+	fsys fs.FS
+}
+
+func (d dirSource) locate(id string, parse bool) *nodeSource {
+	id = trimExtension(strings.TrimPrefix(id, "./"))
+	for _, ext := range sourceExtensions {
+		rel := id + ext
+		info, err := fs.Stat(d.fsys, rel)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		src := &nodeSource{File: rel, Line: 1}
+		if parse {
+			if data, err := fs.ReadFile(d.fsys, rel); err == nil {
+				src.Meta = parseSourceMeta(ext, data)
+			}
+		}
+		return src
+	}
+	return nil
+}
+
+// parseSourceMeta returns the metadata mapping of a node file: the frontmatter of
+// Markdown files, or the whole document of YAML/JSON files.
+// Unparseable files yield nil; loam reports the syntax error itself.
+func parseSourceMeta(ext string, data []byte) *yaml.Node {
+	offset := 0
+	if ext == ".md" {
+		data = bytes.ReplaceAll(bytes.TrimPrefix(data, []byte("\ufeff")), []byte("\r\n"), []byte("\n"))
+		rest, ok := bytes.CutPrefix(data, []byte("---\n"))
+		if !ok {
+			return nil
+		}
+		front, _, found := bytes.Cut(rest, []byte("\n---"))
+		if !found {
+			return nil
+		}
+		data, offset = front, 1
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	meta := doc.Content[0]
+	if meta.Kind != yaml.MappingNode {
+		return nil
+	}
+	shiftLines(meta, offset)
+	return meta
+}
+
+// shiftLines moves a parsed fragment to its position in the enclosing file.
+func shiftLines(n *yaml.Node, offset int) {
+	if n == nil || offset == 0 {
+		return
+	}
+	n.Line += offset
+	for _, child := range n.Content {
+		shiftLines(child, offset)
+	}
+}
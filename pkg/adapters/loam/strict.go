@@ -0,0 +1,214 @@
+package loam
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aretw0/trellis/internal/textdist"
+	"github.com/aretw0/trellis/pkg/domain"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	loaderDoType   = reflect.TypeOf(LoaderDo{})
+	toolCallType   = reflect.TypeOf(LoaderToolCall{})
+	batchCallsType = reflect.TypeOf([]LoaderBatchCall{})
+)
+
+// nodeFields are the keys accepted at the top level of a node: NodeMetadata plus the body.
+var nodeFields = func() map[string]reflect.Type {
+	fields := jsonFields(reflect.TypeOf(NodeMetadata{}))
+	fields["content"] = reflect.TypeOf("")
+	return fields
+}()
+
+// checkMetadata validates authored metadata in strict mode. Unknown keys and values of
+// the wrong type become diagnostics pinned to their line in src.File.
+func checkMetadata(src *nodeSource) error {
+	c := &metaChecker{file: src.File}
+	c.checkFields(src.Meta, nodeFields, "")
+	return errors.Join(c.diags...)
+}
+
+type metaChecker struct {
+	file  string
+	diags []error
+}
+
+func (c *metaChecker) report(n *yaml.Node, format string, args ...any) {
+	c.diags = append(c.diags, &domain.Diagnostic{File: c.file, Line: n.Line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *metaChecker) checkFields(n *yaml.Node, fields map[string]reflect.Type, path string) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Value == "<<" {
+			continue // YAML merge key
+		}
+		ft, ok := fields[key.Value]
+		if !ok {
+			msg := fmt.Sprintf("unknown field %q", key.Value)
+			if path != "" {
+				msg += " in " + path
+			}
+			if hint := suggest(key.Value, fields); hint != "" {
+				msg += fmt.Sprintf("; did you mean %q?", hint)
+			}
+			c.report(key, "%s", msg)
+			continue
+		}
+		c.check(value, ft, joinPath(path, key.Value))
+	}
+}
+
+func (c *metaChecker) check(n *yaml.Node, t reflect.Type, path string) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if n.ShortTag() == "!!null" || t.Kind() == reflect.Interface {
+		return
+	}
+
+	switch {
+	case t == loaderDoType:
+		// `do` is polymorphic: a single call or a batch.
+		if n.Kind == yaml.SequenceNode {
+			c.check(n, batchCallsType, path)
+		} else {
+			c.check(n, toolCallType, path)
+		}
+
+	case t.Kind() == reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			c.mismatch(n, t, path)
+			return
+		}
+		c.checkFields(n, jsonFields(t), path)
+
+	case t.Kind() == reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			c.mismatch(n, t, path)
+			return
+		}
+		for i, item := range n.Content {
+			c.check(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+
+	case t.Kind() == reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			c.mismatch(n, t, path)
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			c.check(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value))
+		}
+
+	default:
+		// Scalars follow the same JSON round-trip loam uses to fill NodeMetadata.
+		var value any
+		if n.Kind != yaml.ScalarNode || n.Decode(&value) != nil {
+			c.mismatch(n, t, path)
+			return
+		}
+		raw, err := json.Marshal(value)
+		if err != nil || json.Unmarshal(raw, reflect.New(t).Interface()) != nil {
+			c.mismatch(n, t, path)
+		}
+	}
+}
+
+func (c *metaChecker) mismatch(n *yaml.Node, t reflect.Type, path string) {
+	c.report(n, "field %q: expected %s, got %s", path, describeType(t), describeNode(n))
+}
+
+// jsonFields maps the JSON keys of a struct (including embedded structs) to their types.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			for name, ft := range jsonFields(f.Type) {
+				fields[name] = ft
+			}
+			continue
+		}
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "map"
+	}
+	return t.String()
+}
+
+func describeNode(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "map"
+	case yaml.SequenceNode:
+		return "list"
+	}
+	switch n.ShortTag() {
+	case "!!str":
+		return fmt.Sprintf("string %q", n.Value)
+	case "!!int", "!!float":
+		return "number " + n.Value
+	case "!!bool":
+		return "boolean " + n.Value
+	}
+	return n.Value
+}
+
+// suggest returns the known key closest to name (at most two edits away), if any.
+func suggest(name string, fields map[string]reflect.Type) string {
+	candidates := make([]string, 0, len(fields))
+	for candidate := range fields {
+		candidates = append(candidates, candidate)
+	}
+	sort.Strings(candidates)
+
+	best, bestDist := "", 3
+	for _, candidate := range candidates {
+		if d := textdist.Levenshtein(name, candidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
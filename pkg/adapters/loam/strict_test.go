@@ -0,0 +1,118 @@
+package loam
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aretw0/loam"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+const typoNode = `---
+type: question
+wait: "yes"
+on_eror: error
+transitions:
+  - too: done
+    condition: input == 'ok'
+---
+Ready?`
+
+func TestLoader_Strict(t *testing.T) {
+	tmpDir, repo := testutils.SetupTestRepo(t)
+	writeFile(t, tmpDir, "start.md", typoNode)
+	writeFile(t, tmpDir, "done.md", "---\ntype: text\n---\nBye")
+
+	typed := loam.NewTypedRepository[NodeMetadata](repo)
+
+	t.Run("Reports every problem with its position", func(t *testing.T) {
+		loader := New(typed, WithSourceDir(tmpDir), WithStrict(true))
+		_, err := loader.GetNode("start")
+		require.Error(t, err)
+
+		diags := domain.Diagnostics(err)
+		require.Len(t, diags, 3)
+		assert.Equal(t, `start.md:3: field "wait": expected boolean, got string "yes"`, diags[0].Error())
+		assert.Equal(t, `start.md:4: unknown field "on_eror"; did you mean "on_error"?`, diags[1].Error())
+		assert.Equal(t, `start.md:6: unknown field "too" in transitions[0]; did you mean "to"?`, diags[2].Error())
+	})
+
+	t.Run("Valid nodes carry their source", func(t *testing.T) {
+		loader := New(typed, WithSourceDir(tmpDir), WithStrict(true))
+		node := loadFlowNode(t, loader, "done")
+		require.NotNil(t, node.Source)
+		assert.Equal(t, "done.md:1", node.Source.String())
+	})
+
+	t.Run("Lenient by default", func(t *testing.T) {
+		loader := New(typed, WithSourceDir(tmpDir))
+		_, err := loader.GetNode("start")
+		require.Error(t, err, "loam still rejects the mistyped value")
+		assert.Contains(t, err.Error(), "start.md: loam get failed for start")
+		assert.Empty(t, domain.Diagnostics(err))
+	})
+}
+
+func TestLoader_Strict_Polymorphic(t *testing.T) {
+	tmpDir, repo := testutils.SetupTestRepo(t)
+	writeFile(t, tmpDir, "start.yaml", `type: tool
+do:
+  - name: charge
+    undo: { name: refund, arg: 1 }
+  - name: notify
+budget:
+  max_calls: many
+metadata:
+  anything: goes
+`)
+	loader := New(loam.NewTypedRepository[NodeMetadata](repo), WithSourceDir(tmpDir), WithStrict(true))
+
+	_, err := loader.GetNode("start")
+	diags := domain.Diagnostics(err)
+	require.Len(t, diags, 2)
+	assert.Equal(t, `start.yaml:4: unknown field "arg" in do[0].undo; did you mean "args"?`, diags[0].Error())
+	assert.Equal(t, `start.yaml:7: field "budget.max_calls": expected integer, got string "many"`, diags[1].Error())
+}
+
+func TestFileLoader_Strict(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("YAML", func(t *testing.T) {
+		writeFile(t, dir, "flow.yaml", `name: demo
+nodes:
+  start:
+    content: Hi
+    to: done
+  done:
+    contnt: Bye
+`)
+		path := filepath.Join(dir, "flow.yaml")
+
+		loader, _, err := NewFileLoader(path)
+		require.NoError(t, err)
+		node := loadFlowNode(t, loader, "done")
+		assert.Equal(t, &domain.SourceRef{File: "flow.yaml", Line: 6}, node.Source)
+
+		strict, _, err := NewFileLoader(path, WithStrict(true))
+		require.NoError(t, err)
+		_, err = strict.GetNode("done")
+		assert.EqualError(t, err, `flow.yaml:7: unknown field "contnt"; did you mean "content"?`)
+	})
+
+	t.Run("Markdown", func(t *testing.T) {
+		writeFile(t, dir, "flow.md", "---\nname: demo\nentry: start\n---\n\n# Demo\n\n```yaml start\nwaitt: true\nto: done\n```\nHi\n\n```yaml done\n```\nBye\n")
+		path := filepath.Join(dir, "flow.md")
+
+		loader, _, err := NewFileLoader(path, WithStrict(true))
+		require.NoError(t, err)
+		_, err = loader.GetNode("start")
+		assert.EqualError(t, err, `flow.md:9: unknown field "waitt"; did you mean "wait"?`)
+
+		node := loadFlowNode(t, loader, "done")
+		assert.Equal(t, &domain.SourceRef{File: "flow.md", Line: 14}, node.Source)
+	})
+}
@@ -35,6 +35,9 @@ type Server struct {
 	engine    Engine
 	loader    ports.GraphLoader
 	mcpServer *server.MCPServer
+
+	// MaxInputSize limits navigation input in bytes (0 uses the runner default).
+	MaxInputSize int
 }
 
 // NewServer creates a new MCP Server instance.
@@ -219,7 +222,7 @@ func (s *Server) handleNavigate(ctx context.Context, request mcp.CallToolRequest
 	}
 
 	// Sanitize Input
-	clean, err := runner.SanitizeInput(input)
+	clean, err := runner.SanitizeInputLimit(input, s.MaxInputSize)
 	if err != nil {
 		slog.Warn("MCP Navigate: Input rejected", "error", err, "size", len(input))
 		return RenderResponse{}, fmt.Errorf("input rejected: %w", err)
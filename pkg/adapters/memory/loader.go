@@ -45,7 +45,7 @@ func NewFromNodes(nodes ...domain.Node) (*Loader, error) {
 func (l *Loader) GetNode(id string) ([]byte, error) {
 	content, ok := l.nodes[id]
 	if !ok {
-		return nil, fmt.Errorf("node not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", domain.ErrNodeNotFound, id)
 	}
 	return content, nil
 }
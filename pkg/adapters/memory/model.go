@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// ModelProvider is a deterministic ports.ModelProvider for tests and offline runs.
+// It replays scripted responses in order, or delegates to Func when set.
+// Safe for concurrent use.
+type ModelProvider struct {
+	// Func, if set, computes the response for each request (takes precedence over scripted responses).
+	Func func(req domain.ModelRequest) (domain.ModelResponse, error)
+
+	mu        sync.Mutex
+	responses []domain.ModelResponse
+	requests  []domain.ModelRequest
+}
+
+// NewModelProvider creates a fake provider that returns the given responses in order.
+func NewModelProvider(responses ...domain.ModelResponse) *ModelProvider {
+	return &ModelProvider{responses: responses}
+}
+
+// Complete implements ports.ModelProvider.
+func (p *ModelProvider) Complete(ctx context.Context, req domain.ModelRequest) (domain.ModelResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.requests = append(p.requests, req)
+	if p.Func != nil {
+		return p.Func(req)
+	}
+	if len(p.responses) == 0 {
+		return domain.ModelResponse{}, fmt.Errorf("memory model provider: no scripted response left (request #%d)", len(p.requests))
+	}
+	resp := p.responses[0]
+	p.responses = p.responses[1:]
+	return resp, nil
+}
+
+// Requests returns the requests received so far.
+func (p *ModelProvider) Requests() []domain.ModelRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]domain.ModelRequest(nil), p.requests...)
+}
@@ -0,0 +1,27 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelProvider_Scripted(t *testing.T) {
+	p := memory.NewModelProvider(domain.ModelResponse{Content: "one"}, domain.ModelResponse{Content: "two"})
+	ctx := context.Background()
+
+	r1, err := p.Complete(ctx, domain.ModelRequest{Prompt: "a"})
+	require.NoError(t, err)
+	r2, err := p.Complete(ctx, domain.ModelRequest{Prompt: "b"})
+	require.NoError(t, err)
+	assert.Equal(t, "one", r1.Content)
+	assert.Equal(t, "two", r2.Content)
+
+	_, err = p.Complete(ctx, domain.ModelRequest{Prompt: "c"})
+	assert.Error(t, err)
+	assert.Len(t, p.Requests(), 3)
+}
@@ -0,0 +1,291 @@
+// Package openai implements ports.ModelProvider for OpenAI-compatible Chat Completions APIs
+// (OpenAI, Azure OpenAI, Ollama, vLLM, LM Studio, ...).
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/schema"
+)
+
+// DefaultBaseURL is the public OpenAI endpoint.
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+// DefaultModel is used when neither the node nor the provider sets a model.
+const DefaultModel = "gpt-4o-mini"
+
+// Environment variables read by FromEnv.
+const (
+	EnvBaseURL = "TRELLIS_LLM_BASE_URL"
+	EnvAPIKey  = "TRELLIS_LLM_API_KEY"
+	EnvModel   = "TRELLIS_LLM_MODEL"
+	// EnvOpenAIKey is the conventional OpenAI key, used when EnvAPIKey is not set.
+	EnvOpenAIKey = "OPENAI_API_KEY"
+)
+
+// routeField is the reserved output field carrying the chosen transition.
+const routeField = "route"
+
+// Provider calls an OpenAI-compatible /chat/completions endpoint.
+type Provider struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+// Option configures the Provider.
+type Option func(*Provider)
+
+// WithModel sets the default model (overridden by the node's `model`).
+func WithModel(model string) Option {
+	return func(p *Provider) {
+		p.model = model
+	}
+}
+
+// WithHTTPClient sets a custom HTTP client (timeouts, proxies, tests).
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// New creates a provider. An empty baseURL defaults to DefaultBaseURL.
+func New(baseURL, apiKey string, opts ...Option) *Provider {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	p := &Provider{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   DefaultModel,
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// FromEnv creates a provider from the TRELLIS_LLM_* environment variables.
+// It reports false when no endpoint or key is configured.
+func FromEnv() (*Provider, bool) {
+	baseURL := os.Getenv(EnvBaseURL)
+	apiKey := os.Getenv(EnvAPIKey)
+	if apiKey == "" {
+		apiKey = os.Getenv(EnvOpenAIKey)
+	}
+	if baseURL == "" && apiKey == "" {
+		return nil, false
+	}
+
+	var opts []Option
+	if model := os.Getenv(EnvModel); model != "" {
+		opts = append(opts, WithModel(model))
+	}
+	return New(baseURL, apiKey, opts...), true
+}
+
+type chatMessage struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+}
+
+type toolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatRequest struct {
+	Model          string         `json:"model"`
+	Messages       []chatMessage  `json:"messages"`
+	Tools          []any          `json:"tools,omitempty"`
+	ResponseFormat map[string]any `json:"response_format,omitempty"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		TotalTokens int64 `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Complete implements ports.ModelProvider.
+func (p *Provider) Complete(ctx context.Context, req domain.ModelRequest) (domain.ModelResponse, error) {
+	body, err := json.Marshal(p.buildRequest(req))
+	if err != nil {
+		return domain.ModelResponse{}, fmt.Errorf("openai: failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return domain.ModelResponse{}, fmt.Errorf("openai: failed to build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	httpResp, err := p.client.Do(httpReq)
+	if err != nil {
+		return domain.ModelResponse{}, fmt.Errorf("openai: request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	raw, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return domain.ModelResponse{}, fmt.Errorf("openai: failed to read response: %w", err)
+	}
+
+	var resp chatResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return domain.ModelResponse{}, fmt.Errorf("openai: invalid response (status %d): %w", httpResp.StatusCode, err)
+	}
+	if httpResp.StatusCode >= 300 || resp.Error != nil {
+		msg := http.StatusText(httpResp.StatusCode)
+		if resp.Error != nil {
+			msg = resp.Error.Message
+		}
+		return domain.ModelResponse{}, fmt.Errorf("openai: API error (status %d): %s", httpResp.StatusCode, msg)
+	}
+	if len(resp.Choices) == 0 {
+		return domain.ModelResponse{}, fmt.Errorf("openai: response has no choices")
+	}
+
+	return parseMessage(req, resp.Choices[0].Message, resp.Usage.TotalTokens)
+}
+
+func (p *Provider) buildRequest(req domain.ModelRequest) chatRequest {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+
+	out := chatRequest{Model: model}
+	if req.System != "" {
+		out.Messages = append(out.Messages, chatMessage{Role: "system", Content: req.System})
+	}
+	out.Messages = append(out.Messages, chatMessage{Role: "user", Content: req.Prompt})
+
+	for _, tool := range req.Tools {
+		params := tool.Parameters
+		if params == nil {
+			params = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		out.Tools = append(out.Tools, map[string]any{
+			"type": "function",
+			"function": map[string]any{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  params,
+			},
+		})
+	}
+
+	if len(req.Schema) > 0 || len(req.Routes) > 0 {
+		out.ResponseFormat = map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   "trellis_output",
+				"strict": true,
+				"schema": jsonSchema(req.Schema, req.Routes),
+			},
+		}
+	}
+	return out
+}
+
+// jsonSchema converts a Trellis schema (plus the allowed routes) into a JSON Schema object.
+func jsonSchema(s schema.Schema, routes []string) map[string]any {
+	properties := make(map[string]any, len(s)+1)
+	required := make([]string, 0, len(s)+1)
+	for field, typ := range s {
+		properties[field] = jsonType(typ.Name())
+		required = append(required, field)
+	}
+	if len(routes) > 0 {
+		properties[routeField] = map[string]any{"type": "string", "enum": routes}
+		required = append(required, routeField)
+	}
+	if len(s) == 0 {
+		// Route-only: keep the free-text answer alongside the decision.
+		properties["answer"] = map[string]any{"type": "string"}
+		required = append(required, "answer")
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func jsonType(name string) map[string]any {
+	if strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]") {
+		return map[string]any{"type": "array", "items": jsonType(name[1 : len(name)-1])}
+	}
+	switch name {
+	case "int":
+		return map[string]any{"type": "integer"}
+	case "float":
+		return map[string]any{"type": "number"}
+	case "bool":
+		return map[string]any{"type": "boolean"}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+func parseMessage(req domain.ModelRequest, msg chatMessage, tokens int64) (domain.ModelResponse, error) {
+	resp := domain.ModelResponse{Content: msg.Content}
+	if tokens > 0 {
+		resp.Usage = &domain.ToolUsage{Tokens: tokens}
+	}
+
+	for _, tc := range msg.ToolCalls {
+		var args map[string]any
+		if tc.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+				return resp, fmt.Errorf("openai: invalid arguments for tool call %s: %w", tc.Function.Name, err)
+			}
+		}
+		resp.ToolCalls = append(resp.ToolCalls, domain.ToolCall{ID: tc.ID, Name: tc.Function.Name, Args: args})
+	}
+
+	if (len(req.Schema) > 0 || len(req.Routes) > 0) && msg.Content != "" {
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(msg.Content), &obj); err != nil {
+			return resp, fmt.Errorf("openai: structured output is not a JSON object: %w", err)
+		}
+		if route, ok := obj[routeField].(string); ok && len(req.Routes) > 0 {
+			resp.Route = route
+			delete(obj, routeField)
+		}
+		if answer, ok := obj["answer"].(string); ok && len(req.Schema) == 0 {
+			resp.Content = answer
+		} else {
+			resp.Output = obj
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,78 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_StructuredRoute(t *testing.T) {
+	var got chatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+		w.Write([]byte(`{
+			"choices": [{"message": {"role": "assistant", "content": "{\"category\": \"billing\", \"route\": \"billing\"}"}}],
+			"usage": {"total_tokens": 42}
+		}`))
+	}))
+	defer srv.Close()
+
+	p := New(srv.URL, "sk-test", WithModel("small"))
+	resp, err := p.Complete(context.Background(), domain.ModelRequest{
+		System: "sys",
+		Prompt: "classify",
+		Schema: schema.Schema{"category": schema.String()},
+		Routes: []string{"billing", "support"},
+		Tools:  []domain.Tool{{Name: "lookup"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "small", got.Model)
+	require.Len(t, got.Messages, 2)
+	assert.Equal(t, "system", got.Messages[0].Role)
+	require.Len(t, got.Tools, 1)
+	require.NotNil(t, got.ResponseFormat)
+	schemaDef := got.ResponseFormat["json_schema"].(map[string]any)["schema"].(map[string]any)
+	props := schemaDef["properties"].(map[string]any)
+	assert.Equal(t, []any{"billing", "support"}, props["route"].(map[string]any)["enum"])
+
+	assert.Equal(t, "billing", resp.Route)
+	assert.Equal(t, map[string]any{"category": "billing"}, resp.Output)
+	assert.Equal(t, int64(42), resp.Usage.Tokens)
+}
+
+func TestProvider_ToolCallsAndErrors(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			w.Write([]byte(`{"error": {"message": "quota exceeded"}}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "", "tool_calls": [
+			{"id": "call_1", "type": "function", "function": {"name": "lookup", "arguments": "{\"id\": 7}"}}
+		]}}]}`))
+	}))
+	defer srv.Close()
+
+	p := New(srv.URL, "")
+	resp, err := p.Complete(context.Background(), domain.ModelRequest{Prompt: "hi"})
+	require.NoError(t, err)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "lookup", resp.ToolCalls[0].Name)
+	assert.Equal(t, float64(7), resp.ToolCalls[0].Args["id"])
+
+	status = http.StatusTooManyRequests
+	_, err = p.Complete(context.Background(), domain.ModelRequest{Prompt: "hi"})
+	assert.ErrorContains(t, err, "quota exceeded")
+}
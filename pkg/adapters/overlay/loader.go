@@ -0,0 +1,128 @@
+// Package overlay composes several graph loaders into one.
+//
+// Layers are stacked over a base graph and later layers win: a node defined in an
+// overlay replaces the node with the same ID below it, as a whole (definitions are
+// not merged). Typical stacks are Go-defined nodes (memory.Loader) over Markdown
+// files, or a tenant's customizations over a shared flow:
+//
+//	loader := overlay.NewLoader(base, tenant)
+//
+// Collision rules for ListNodes: the same ID in different layers is an override and
+// is listed once; duplicates inside a single layer are still reported by that layer.
+package overlay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/ports"
+)
+
+// Loader serves the union of its layers, the topmost definition of each node winning.
+type Loader struct {
+	// layers are ordered from the base (lowest precedence) to the top.
+	layers []ports.GraphLoader
+}
+
+// NewLoader stacks overlays over base, in increasing order of precedence.
+func NewLoader(base ports.GraphLoader, overlays ...ports.GraphLoader) *Loader {
+	return &Loader{layers: append([]ports.GraphLoader{base}, overlays...)}
+}
+
+// GetNode implements ports.GraphLoader.
+// Layers are searched from the top; a layer is skipped only when the node is missing
+// from it (domain.ErrNodeNotFound), so a broken override is reported, not masked.
+func (l *Loader) GetNode(id string) ([]byte, error) {
+	for i := len(l.layers) - 1; i >= 0; i-- {
+		raw, err := l.layers[i].GetNode(id)
+		if err == nil {
+			return raw, nil
+		}
+		if !errors.Is(err, domain.ErrNodeNotFound) {
+			return nil, fmt.Errorf("overlay layer %d: %w", i, err)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", domain.ErrNodeNotFound, id)
+}
+
+// ListNodes implements ports.GraphLoader.
+// IDs keep the order of the lowest layer defining them; overridden IDs are listed once.
+func (l *Loader) ListNodes() ([]string, error) {
+	seen := make(map[string]bool)
+	var ids []string
+	for i, layer := range l.layers {
+		layerIDs, err := layer.ListNodes()
+		if err != nil {
+			return nil, fmt.Errorf("overlay layer %d: %w", i, err)
+		}
+		for _, id := range layerIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// Watch implements ports.Watchable by merging the changes of every watchable layer.
+// Static layers (not Watchable, or reporting domain.ErrWatchUnsupported) are skipped;
+// any other failure stops the watchers already started. The channel closes when all
+// layer channels have closed or ctx is done.
+func (l *Loader) Watch(ctx context.Context) (<-chan string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var sources []<-chan string
+	for i, layer := range l.layers {
+		w, ok := layer.(ports.Watchable)
+		if !ok {
+			continue
+		}
+		ch, err := w.Watch(ctx)
+		if errors.Is(err, domain.ErrWatchUnsupported) {
+			continue
+		}
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("overlay layer %d: %w", i, err)
+		}
+		sources = append(sources, ch)
+	}
+	if len(sources) == 0 {
+		cancel()
+		return nil, fmt.Errorf("no overlay layer is watchable: %w", domain.ErrWatchUnsupported)
+	}
+
+	out := make(chan string, 1)
+	var wg sync.WaitGroup
+	for _, src := range sources {
+		wg.Add(1)
+		go func(src <-chan string) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case evt, ok := <-src:
+					if !ok {
+						return
+					}
+					select {
+					case out <- evt:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}(src)
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(out)
+	}()
+	return out, nil
+}
@@ -0,0 +1,120 @@
+package overlay_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/adapters/overlay"
+	"github.com/aretw0/trellis/pkg/domain"
+	contract "github.com/aretw0/trellis/pkg/ports/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_Contract(t *testing.T) {
+	base := memory.NewLoader(map[string]string{"start": "base start", "end": "Goodbye"})
+	top := memory.NewLoader(map[string]string{"start": "Hello World"})
+
+	contract.GraphLoaderContractTest(t, overlay.NewLoader(base, top), map[string][]byte{
+		"start": []byte("Hello World"),
+		"end":   []byte("Goodbye"),
+	})
+}
+
+func TestLoader_Precedence(t *testing.T) {
+	base := memory.NewLoader(map[string]string{"start": "base", "help": "base help", "end": "base end"})
+	tenant := memory.NewLoader(map[string]string{"start": "tenant", "promo": "tenant promo"})
+	code := memory.NewLoader(map[string]string{"start": "code", "help": "code help"})
+	loader := overlay.NewLoader(base, tenant, code)
+
+	for id, want := range map[string]string{
+		"start": "code",
+		"help":  "code help",
+		"promo": "tenant promo",
+		"end":   "base end",
+	} {
+		raw, err := loader.GetNode(id)
+		require.NoError(t, err, id)
+		assert.Equal(t, want, string(raw), id)
+	}
+
+	ids, err := loader.ListNodes()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"end", "help", "start", "promo"}, ids, "overrides are listed once")
+}
+
+type brokenLoader struct{ err error }
+
+func (b brokenLoader) GetNode(id string) ([]byte, error) { return nil, b.err }
+func (b brokenLoader) ListNodes() ([]string, error)      { return nil, b.err }
+
+func TestLoader_Errors(t *testing.T) {
+	base := memory.NewLoader(map[string]string{"start": "base"})
+	loader := overlay.NewLoader(base, brokenLoader{err: errors.New("syntax error")})
+
+	_, err := loader.GetNode("start")
+	assert.EqualError(t, err, "overlay layer 1: syntax error", "a broken override does not fall back")
+
+	_, err = loader.ListNodes()
+	assert.EqualError(t, err, "overlay layer 1: syntax error")
+}
+
+type watchLoader struct {
+	*memory.Loader
+	events chan string
+	err    error
+}
+
+func (w *watchLoader) Watch(ctx context.Context) (<-chan string, error) {
+	return w.events, w.err
+}
+
+func TestLoader_Watch(t *testing.T) {
+	static := memory.NewLoader(map[string]string{"start": "base"})
+
+	t.Run("Merges watchable layers", func(t *testing.T) {
+		a := &watchLoader{Loader: static, events: make(chan string, 1)}
+		b := &watchLoader{Loader: static, events: make(chan string, 1)}
+		unsupported := &watchLoader{Loader: static, err: domain.ErrWatchUnsupported}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ch, err := overlay.NewLoader(static, a, unsupported, b).Watch(ctx)
+		require.NoError(t, err)
+
+		a.events <- "a.md"
+		b.events <- "b.md"
+		got := []string{receive(t, ch), receive(t, ch)}
+		assert.ElementsMatch(t, []string{"a.md", "b.md"}, got)
+
+		close(a.events)
+		close(b.events)
+		_, ok := <-ch
+		assert.False(t, ok, "closes once every layer is done")
+	})
+
+	t.Run("Static graph", func(t *testing.T) {
+		_, err := overlay.NewLoader(static, static).Watch(context.Background())
+		assert.ErrorIs(t, err, domain.ErrWatchUnsupported)
+	})
+
+	t.Run("Watcher failure", func(t *testing.T) {
+		failing := &watchLoader{Loader: static, err: errors.New("too many open files")}
+		_, err := overlay.NewLoader(static, failing).Watch(context.Background())
+		assert.EqualError(t, err, "overlay layer 1: too many open files")
+	})
+}
+
+func receive(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case evt := <-ch:
+		return evt
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a change")
+		return ""
+	}
+}
@@ -37,8 +37,13 @@ func LoadTools(path string) (map[string]ProcessConfig, error) {
 		return nil, fmt.Errorf("failed to read tools config: %w", err)
 	}
 
+	return ParseTools(path, data)
+}
+
+// ParseTools decodes tool configuration data; name selects JSON (.json) or YAML.
+func ParseTools(name string, data []byte) (map[string]ProcessConfig, error) {
 	var cfg ConfigFile
-	ext := strings.ToLower(filepath.Ext(path))
+	ext := strings.ToLower(filepath.Ext(name))
 
 	if ext == ".json" {
 		if err := json.Unmarshal(data, &cfg); err != nil {
@@ -0,0 +1,133 @@
+// Package answers answers the prompts of a flow from a file, so a session runs with nobody
+// at the keyboard (CI scripts, batch runs).
+//
+// Answers are keyed by node ID or by the `save_to` key of the node, so they keep working
+// when nodes are added or reordered. A list answers the successive visits of a node:
+//
+//	answers:
+//	  name: Ada          # the node with save_to: name
+//	  confirm: "yes"     # the node with ID confirm
+//	  retry: [no, yes]   # first visit, second visit
+//	default: ""          # prompts nothing else answers
+//	fail_unanswered: true
+//
+// A prompt is answered by, in order: its node ID, its save_to key, the node's input_default,
+// the file default. Otherwise the session fails (fail_unanswered) or gets an empty answer.
+package answers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is an answers file (YAML or JSON).
+type File struct {
+	// Answers maps node IDs and save_to keys to the answers of their prompts.
+	Answers map[string]Answer `yaml:"answers,omitempty" json:"answers,omitempty"`
+	// Default answers the prompts nothing else answers.
+	Default *string `yaml:"default,omitempty" json:"default,omitempty"`
+	// FailUnanswered stops the session at a prompt nothing answers.
+	FailUnanswered bool `yaml:"fail_unanswered,omitempty" json:"fail_unanswered,omitempty"`
+}
+
+// Answer is the answers of a prompt, one per visit of its node. A scalar is a single answer.
+type Answer []string
+
+// UnmarshalYAML accepts a scalar or a list of scalars.
+func (a *Answer) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*a = Answer{node.Value}
+		return nil
+	case yaml.SequenceNode:
+		values := make(Answer, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: an answer must be a scalar", item.Line)
+			}
+			values = append(values, item.Value)
+		}
+		*a = values
+		return nil
+	}
+	return fmt.Errorf("line %d: an answer must be a scalar or a list of scalars", node.Line)
+}
+
+// Load reads an answers file.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read answers: %w", err)
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid answers file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Parse decodes an answers file. JSON is accepted as YAML.
+func Parse(data []byte) (*File, error) {
+	var f File
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// With returns a copy of f whose answers are overridden by values, e.g. the fields of a
+// batch record. Lists answer successive visits; other values are formatted as text.
+func (f *File) With(values map[string]any) *File {
+	out := &File{FailUnanswered: f.FailUnanswered, Default: f.Default, Answers: maps.Clone(f.Answers)}
+	if out.Answers == nil {
+		out.Answers = make(map[string]Answer, len(values))
+	}
+	for key, value := range values {
+		if list, ok := value.([]any); ok {
+			answer := make(Answer, 0, len(list))
+			for _, item := range list {
+				answer = append(answer, text(item))
+			}
+			out.Answers[key] = answer
+			continue
+		}
+		out.Answers[key] = Answer{text(value)}
+	}
+	return out
+}
+
+// text formats a decoded value as the user would type it.
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]any, []any:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
+
+// UnansweredError is returned for a prompt nothing answers when FailUnanswered is set.
+type UnansweredError struct {
+	Node   string
+	SaveTo string
+}
+
+func (e *UnansweredError) Error() string {
+	if e.SaveTo != "" {
+		return fmt.Sprintf("no answer for node %q (save_to: %s)", e.Node, e.SaveTo)
+	}
+	return fmt.Sprintf("no answer for node %q", e.Node)
+}
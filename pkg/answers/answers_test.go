@@ -0,0 +1,108 @@
+package answers_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/internal/testutils/flowtest"
+	"github.com/aretw0/trellis/pkg/answers"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/runner"
+)
+
+var flow = map[string]string{
+	"start.md": "---\nwait: true\nsave_to: name\nto: plan\n---\nName?\n",
+	"plan.md": "---\ninput_type: choice\ninput_options: [basic, pro]\nsave_to: plan\n" +
+		"transitions:\n  - { condition: input == 'retry', to: plan }\n  - { to: seats }\n---\nPlan?\n",
+	"seats.md": "---\ninput_type: text\ninput_default: '1'\nsave_to: seats\nto: done\n---\nSeats?\n",
+	"done.md":  "Bye {{ .name }}\n",
+}
+
+// run answers the flow from f and returns the final state.
+func run(t *testing.T, f *answers.File) (*domain.State, error) {
+	t.Helper()
+	engine := flowtest.NewEngine(t, flow)
+	nodes, err := engine.Inspect()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+	r := runner.NewRunner(
+		runner.WithEngine(engine),
+		runner.WithInitialState(state),
+		runner.WithHeadless(true),
+		runner.WithInputHandler(answers.NewHandler(runner.NewTextHandler(io.Discard), f, nodes)),
+	)
+	err = r.Run(ctx)
+	return r.State(), err
+}
+
+func TestHandler(t *testing.T) {
+	f, err := answers.Parse([]byte("answers:\n  name: Ada\n  plan: [retry, pro]\n"))
+	require.NoError(t, err)
+
+	state, err := run(t, f)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusTerminated, state.Status)
+	assert.Equal(t, "Ada", state.Context["name"])
+	assert.Equal(t, "pro", state.Context["plan"], "a list answers successive visits")
+	assert.Equal(t, "1", state.Context["seats"], "input_default answers unanswered prompts")
+
+	t.Run("by node ID", func(t *testing.T) {
+		f, err := answers.Parse([]byte(`{"answers": {"start": "Bob", "plan": "basic", "seats": 3}}`))
+		require.NoError(t, err)
+		state, err := run(t, f)
+		require.NoError(t, err)
+		assert.Equal(t, "Bob", state.Context["name"])
+		assert.Equal(t, "3", state.Context["seats"])
+	})
+
+	t.Run("default", func(t *testing.T) {
+		f, err := answers.Parse([]byte("answers: { name: Ada }\ndefault: basic\n"))
+		require.NoError(t, err)
+		state, err := run(t, f)
+		require.NoError(t, err)
+		assert.Equal(t, "basic", state.Context["plan"])
+	})
+
+	t.Run("fail unanswered", func(t *testing.T) {
+		f, err := answers.Parse([]byte("answers: { name: Ada }\nfail_unanswered: true\n"))
+		require.NoError(t, err)
+		state, err := run(t, f)
+		var unanswered *answers.UnansweredError
+		require.ErrorAs(t, err, &unanswered)
+		assert.Equal(t, "plan", unanswered.Node)
+		assert.Equal(t, "plan", unanswered.SaveTo)
+		assert.Equal(t, "plan", state.CurrentNodeID)
+	})
+}
+
+func TestParse(t *testing.T) {
+	f, err := answers.Parse(nil)
+	require.NoError(t, err)
+	assert.Empty(t, f.Answers)
+
+	_, err = answers.Parse([]byte("answers:\n  name: { first: Ada }\n"))
+	assert.ErrorContains(t, err, "line 2")
+
+	_, err = answers.Parse([]byte("answer: { name: Ada }\n"))
+	assert.Error(t, err, "unknown keys are rejected")
+}
+
+func TestWith(t *testing.T) {
+	f := &answers.File{Answers: map[string]answers.Answer{"name": {"Ada"}, "plan": {"basic"}}, FailUnanswered: true}
+	out := f.With(map[string]any{"plan": "pro", "seats": 3.0, "tags": []any{"a", "b"}, "address": map[string]any{"city": "Lisbon"}})
+
+	assert.Equal(t, answers.Answer{"Ada"}, out.Answers["name"])
+	assert.Equal(t, answers.Answer{"pro"}, out.Answers["plan"])
+	assert.Equal(t, answers.Answer{"3"}, out.Answers["seats"])
+	assert.Equal(t, answers.Answer{"a", "b"}, out.Answers["tags"])
+	assert.Equal(t, answers.Answer{`{"city":"Lisbon"}`}, out.Answers["address"])
+	assert.True(t, out.FailUnanswered)
+	assert.Equal(t, answers.Answer{"basic"}, f.Answers["plan"], "the original file is left untouched")
+}
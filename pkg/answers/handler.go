@@ -0,0 +1,88 @@
+package answers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/runner"
+)
+
+// DefaultMaxPrompts bounds the prompts a Handler answers, so a flow that keeps asking
+// (e.g. a retry loop fed empty answers) fails instead of running forever.
+const DefaultMaxPrompts = 1000
+
+// Handler is a runner.IOHandler that answers prompts from a File. Output, signals and tools
+// go to the wrapped handler. It needs the runner to attach the session state to the input
+// context (runner.ContextWithState), which the Runner does.
+type Handler struct {
+	runner.IOHandler
+
+	file   *File
+	saveTo map[string]string // Node ID → save_to key
+
+	mu       sync.Mutex
+	prompt   *domain.InputRequest // Last prompt shown
+	visits   map[string]int       // Answers consumed per key
+	answered int
+}
+
+// NewHandler creates a Handler answering from f. The nodes of the graph (engine.Inspect)
+// resolve save_to keys; inner receives everything else.
+func NewHandler(inner runner.IOHandler, f *File, nodes []domain.Node) *Handler {
+	saveTo := make(map[string]string)
+	for _, n := range nodes {
+		if n.SaveTo != "" {
+			saveTo[n.ID] = n.SaveTo
+		}
+	}
+	return &Handler{IOHandler: inner, file: f, saveTo: saveTo, visits: make(map[string]int)}
+}
+
+// Output keeps the prompt, so its input_default is known when answering, and passes the
+// actions on.
+func (h *Handler) Output(ctx context.Context, actions []domain.ActionRequest) (bool, error) {
+	h.mu.Lock()
+	h.prompt = nil
+	for _, act := range actions {
+		if req, ok := act.Payload.(domain.InputRequest); ok && act.Type == domain.ActionRequestInput {
+			h.prompt = &req
+		}
+	}
+	h.mu.Unlock()
+	return h.IOHandler.Output(ctx, actions)
+}
+
+// Input answers the prompt of the current node without reading anything.
+func (h *Handler) Input(ctx context.Context) (string, error) {
+	state := runner.StateFromContext(ctx)
+	if state == nil {
+		return "", fmt.Errorf("answers: no session state in the input context")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.answered++
+	if h.answered > DefaultMaxPrompts {
+		return "", fmt.Errorf("answers: more than %d prompts answered (does the flow loop?)", DefaultMaxPrompts)
+	}
+
+	node := state.CurrentNodeID
+	saveTo := h.saveTo[node]
+	for _, key := range []string{node, saveTo} {
+		if answer, ok := h.file.Answers[key]; ok && key != "" && h.visits[key] < len(answer) {
+			h.visits[key]++
+			return runner.SanitizeInput(answer[h.visits[key]-1])
+		}
+	}
+	switch {
+	case h.prompt != nil && h.prompt.Default != "":
+		return "", nil // The engine applies input_default
+	case h.file.Default != nil:
+		return runner.SanitizeInput(*h.file.Default)
+	case h.file.FailUnanswered:
+		return "", &UnansweredError{Node: node, SaveTo: saveTo}
+	}
+	return "", nil
+}
@@ -0,0 +1,411 @@
+// Package bundle packs a flow into a single verifiable archive.
+//
+// A bundle (`<name>.trellis`) is a gzipped tar holding the normalized node definitions
+// (after templates, `_defaults`, tool imports and packages), the project manifest and
+// the tool configuration. Its header lists the SHA-256 digest of every file, and the
+// bundle hash covers the whole header, so any change to the content is detected.
+//
+// Bundles can be signed with an ed25519 key. When a trust policy is configured
+// (a set of trusted public keys), unsigned bundles and bundles signed by other keys
+// are refused:
+//
+//	b, err := bundle.Open("support.trellis", trusted...)
+//	eng, err := trellis.New("", trellis.WithBundle(b, trusted...))
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/manifest"
+)
+
+// Extension identifies bundle files.
+const Extension = ".trellis"
+
+// Format is the bundle layout version written in the header.
+const Format = "trellis-bundle/v1"
+
+// Archive entries.
+const (
+	headerFile    = "bundle.json"
+	signatureFile = "signature.json"
+	nodesFile     = "nodes.json"
+)
+
+// Header describes the bundle content (bundle.json).
+type Header struct {
+	Format    string `json:"format"`
+	Name      string `json:"name,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Entry     string `json:"entry"`
+	ErrorNode string `json:"error_node,omitempty"`
+	// Files maps each archive entry to its SHA-256 digest (hex).
+	Files map[string]string `json:"files"`
+	// Hash ("sha256:<hex>") covers every other header field, so it identifies the whole bundle.
+	Hash string `json:"hash"`
+}
+
+// Signature is an ed25519 signature of the bundle hash (signature.json).
+type Signature struct {
+	KeyID     string `json:"key_id"`
+	PublicKey []byte `json:"public_key"`
+	Value     []byte `json:"value"`
+}
+
+// Contents are the parts of a flow that go into a bundle.
+type Contents struct {
+	Name      string
+	Version   string
+	Entry     string
+	ErrorNode string
+	// Nodes are the raw definitions served by the flow's GraphLoader, by node ID.
+	Nodes map[string]json.RawMessage
+	// Manifest is the trellis.yaml content (optional).
+	Manifest []byte
+	// Tools is the tool configuration, named ToolsFile ("tools.yaml" or "tools.json").
+	Tools     []byte
+	ToolsFile string
+}
+
+// Bundle is a verified flow archive.
+type Bundle struct {
+	Header
+	// Signature is nil for unsigned bundles.
+	Signature *Signature
+
+	files map[string][]byte
+	nodes map[string]json.RawMessage
+}
+
+// New builds an unsigned bundle from the flow contents.
+func New(c Contents) (*Bundle, error) {
+	if len(c.Nodes) == 0 {
+		return nil, fmt.Errorf("bundle has no nodes")
+	}
+	if c.Entry == "" {
+		return nil, fmt.Errorf("bundle has no entry node")
+	}
+	if _, ok := c.Nodes[c.Entry]; !ok {
+		return nil, fmt.Errorf("entry node '%s' is not in the graph", c.Entry)
+	}
+
+	nodes := make(map[string]json.RawMessage, len(c.Nodes))
+	for id, raw := range c.Nodes {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, raw); err != nil {
+			return nil, fmt.Errorf("node %s: invalid definition: %w", id, err)
+		}
+		nodes[id] = buf.Bytes()
+	}
+	graph, err := json.Marshal(nodes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode nodes: %w", err)
+	}
+
+	files := map[string][]byte{nodesFile: graph}
+	if len(c.Manifest) > 0 {
+		files[manifest.FileNames[0]] = c.Manifest
+	}
+	if len(c.Tools) > 0 {
+		name := c.ToolsFile
+		if name != "tools.json" {
+			name = "tools.yaml"
+		}
+		files[name] = c.Tools
+	}
+
+	b := &Bundle{
+		Header: Header{
+			Format:    Format,
+			Name:      c.Name,
+			Version:   c.Version,
+			Entry:     c.Entry,
+			ErrorNode: c.ErrorNode,
+			Files:     make(map[string]string, len(files)),
+		},
+		files: files,
+		nodes: nodes,
+	}
+	for name, data := range files {
+		b.Files[name] = digest(data)
+	}
+	b.Hash, err = b.Header.hash()
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Sign signs the bundle hash with key, replacing any previous signature.
+func (b *Bundle) Sign(key ed25519.PrivateKey) {
+	pub := key.Public().(ed25519.PublicKey)
+	b.Signature = &Signature{
+		KeyID:     KeyID(pub),
+		PublicKey: pub,
+		Value:     ed25519.Sign(key, []byte(b.Hash)),
+	}
+}
+
+// WriteTo writes the bundle archive. The output is reproducible: the same content
+// always yields the same bytes.
+func (b *Bundle) WriteTo(w io.Writer) (int64, error) {
+	entries := map[string][]byte{}
+	for name, data := range b.files {
+		entries[name] = data
+	}
+	header, err := json.MarshalIndent(b.Header, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	entries[headerFile] = header
+	if b.Signature != nil {
+		sig, err := json.MarshalIndent(b.Signature, "", "  ")
+		if err != nil {
+			return 0, err
+		}
+		entries[signatureFile] = sig
+	}
+
+	// The header goes first so readers can identify the archive early.
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		if name != headerFile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	names = append([]string{headerFile}, names...)
+
+	cw := &countingWriter{w: w}
+	gz := gzip.NewWriter(cw)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		data := entries[name]
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg, Format: tar.FormatPAX}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return cw.n, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return cw.n, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return cw.n, err
+	}
+	if err := gz.Close(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+// Read reads and verifies a bundle archive: every file must match the header and the
+// header must match the bundle hash. With trusted keys, the bundle must also carry a
+// valid signature from one of them.
+func Read(r io.Reader, trusted ...ed25519.PublicKey) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle: %w", err)
+	}
+	defer gz.Close()
+
+	entries := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("not a bundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("bundle: unexpected entry %s", hdr.Name)
+		}
+		if _, dup := entries[hdr.Name]; dup {
+			return nil, fmt.Errorf("bundle: duplicate entry %s", hdr.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("bundle: failed to read %s: %w", hdr.Name, err)
+		}
+		entries[hdr.Name] = data
+	}
+
+	b := &Bundle{files: map[string][]byte{}}
+	raw, ok := entries[headerFile]
+	if !ok {
+		return nil, fmt.Errorf("not a bundle: missing %s", headerFile)
+	}
+	if err := json.Unmarshal(raw, &b.Header); err != nil {
+		return nil, fmt.Errorf("bundle: invalid %s: %w", headerFile, err)
+	}
+	if b.Format != Format {
+		return nil, fmt.Errorf("bundle: unsupported format %q (expected %q)", b.Format, Format)
+	}
+	if raw, ok := entries[signatureFile]; ok {
+		b.Signature = &Signature{}
+		if err := json.Unmarshal(raw, b.Signature); err != nil {
+			return nil, fmt.Errorf("bundle: invalid %s: %w", signatureFile, err)
+		}
+	}
+
+	// Integrity: the header lists exactly the content files, and covers itself.
+	for name, data := range entries {
+		if name == headerFile || name == signatureFile {
+			continue
+		}
+		if want, ok := b.Files[name]; !ok || want != digest(data) {
+			return nil, fmt.Errorf("bundle is corrupted or tampered: %s does not match the header", name)
+		}
+		b.files[name] = data
+	}
+	for name := range b.Files {
+		if _, ok := b.files[name]; !ok {
+			return nil, fmt.Errorf("bundle is corrupted or tampered: %s is missing", name)
+		}
+	}
+	hash, err := b.Header.hash()
+	if err != nil {
+		return nil, err
+	}
+	if hash != b.Hash {
+		return nil, fmt.Errorf("bundle is corrupted or tampered: header does not match the bundle hash")
+	}
+
+	if err := b.verify(trusted); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b.files[nodesFile], &b.nodes); err != nil {
+		return nil, fmt.Errorf("bundle: invalid %s: %w", nodesFile, err)
+	}
+	return b, nil
+}
+
+// Open reads and verifies the bundle at path (see Read).
+func Open(path string, trusted ...ed25519.PublicKey) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer f.Close()
+	b, err := Read(f, trusted...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return b, nil
+}
+
+// IsBundle reports whether path names a bundle file.
+func IsBundle(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), Extension)
+}
+
+// Verify applies a trust policy to a bundle that is already open (or built with New):
+// with trusted keys, it must be signed by one of them; without, any signature must still be valid.
+func (b *Bundle) Verify(trusted ...ed25519.PublicKey) error {
+	return b.verify(trusted)
+}
+
+// verify checks the signature. Without trusted keys, any present signature must still be valid.
+func (b *Bundle) verify(trusted []ed25519.PublicKey) error {
+	sig := b.Signature
+	if sig == nil {
+		if len(trusted) > 0 {
+			return fmt.Errorf("bundle is not signed (the trust policy requires a signature)")
+		}
+		return nil
+	}
+	if len(sig.PublicKey) != ed25519.PublicKeySize || !ed25519.Verify(sig.PublicKey, []byte(b.Hash), sig.Value) {
+		return fmt.Errorf("bundle signature is invalid")
+	}
+	if len(trusted) == 0 {
+		return nil
+	}
+	for _, key := range trusted {
+		if key.Equal(ed25519.PublicKey(sig.PublicKey)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("bundle is signed by untrusted key %s", KeyID(sig.PublicKey))
+}
+
+// Loader returns a GraphLoader serving the bundled nodes.
+func (b *Bundle) Loader() *memory.Loader {
+	data := make(map[string]string, len(b.nodes))
+	for id, raw := range b.nodes {
+		data[id] = string(raw)
+	}
+	return memory.NewLoader(data)
+}
+
+// Manifest returns the bundled project manifest (with environment overrides applied),
+// or an empty manifest when the flow had none.
+func (b *Bundle) Manifest() (*manifest.Manifest, error) {
+	name := manifest.FileNames[0]
+	data, ok := b.files[name]
+	if !ok {
+		return manifest.Decode(name, []byte("{}"))
+	}
+	return manifest.Decode(name, data)
+}
+
+// Tools returns the bundled tool configuration and its file name ("tools.yaml" or
+// "tools.json"), or nil when the flow had none.
+func (b *Bundle) Tools() ([]byte, string) {
+	for _, name := range []string{"tools.yaml", "tools.json"} {
+		if data, ok := b.files[name]; ok {
+			return data, name
+		}
+	}
+	return nil, ""
+}
+
+// NodeIDs returns the bundled node IDs, sorted.
+func (b *Bundle) NodeIDs() []string {
+	ids := make([]string, 0, len(b.nodes))
+	for id := range b.nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (h Header) hash() (string, error) {
+	h.Hash = ""
+	data, err := json.Marshal(h)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode bundle header: %w", err)
+	}
+	return "sha256:" + digest(data), nil
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
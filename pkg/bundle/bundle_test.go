@@ -0,0 +1,253 @@
+package bundle_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis/pkg/bundle"
+)
+
+func newBundle(t *testing.T) *bundle.Bundle {
+	t.Helper()
+	b, err := bundle.New(bundle.Contents{
+		Name:      "support",
+		Version:   "1.2.0",
+		Entry:     "start",
+		ErrorNode: "error",
+		Nodes: map[string]json.RawMessage{
+			"start": json.RawMessage(`{ "content": "Hello", "to": "end" }`),
+			"end":   json.RawMessage(`{"content": "Bye"}`),
+			"error": json.RawMessage(`{"content": "Oops"}`),
+		},
+		Manifest:  []byte("name: support\nversion: 1.2.0\nlocale: pt-BR\n"),
+		Tools:     []byte("tools:\n  - name: echo\n    command: echo\n"),
+		ToolsFile: "tools.yaml",
+	})
+	require.NoError(t, err)
+	return b
+}
+
+func encode(t *testing.T, b *bundle.Bundle) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	_, err := b.WriteTo(&buf)
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func generateKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func TestRoundTrip(t *testing.T) {
+	data := encode(t, newBundle(t))
+	assert.Equal(t, data, encode(t, newBundle(t)), "packing is reproducible")
+
+	b, err := bundle.Read(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "support", b.Name)
+	assert.Equal(t, "start", b.Entry)
+	assert.Equal(t, "error", b.ErrorNode)
+	assert.Regexp(t, `^sha256:[0-9a-f]{64}$`, b.Hash)
+	assert.Nil(t, b.Signature)
+	assert.Equal(t, []string{"end", "error", "start"}, b.NodeIDs())
+
+	raw, err := b.Loader().GetNode("start")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"content": "Hello", "to": "end"}`, string(raw))
+
+	m, err := b.Manifest()
+	require.NoError(t, err)
+	assert.Equal(t, "pt-BR", m.Locale)
+
+	tools, name := b.Tools()
+	assert.Equal(t, "tools.yaml", name)
+	assert.Contains(t, string(tools), "name: echo")
+}
+
+func TestNew_Errors(t *testing.T) {
+	_, err := bundle.New(bundle.Contents{Entry: "start"})
+	assert.EqualError(t, err, "bundle has no nodes")
+
+	_, err = bundle.New(bundle.Contents{Entry: "main", Nodes: map[string]json.RawMessage{"start": json.RawMessage(`{}`)}})
+	assert.EqualError(t, err, "entry node 'main' is not in the graph")
+}
+
+// rewrite copies the archive, letting edit change entries (returning nil drops them).
+func rewrite(t *testing.T, data []byte, edit func(name string, content []byte) []byte) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		if content = edit(hdr.Name, content); content == nil {
+			continue
+		}
+		hdr.Size = int64(len(content))
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err = tw.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func TestRead_Tampered(t *testing.T) {
+	data := encode(t, newBundle(t))
+
+	t.Run("Modified node", func(t *testing.T) {
+		bad := rewrite(t, data, func(name string, content []byte) []byte {
+			if name == "nodes.json" {
+				return bytes.Replace(content, []byte("Bye"), []byte("Pwn"), 1)
+			}
+			return content
+		})
+		_, err := bundle.Read(bytes.NewReader(bad))
+		assert.EqualError(t, err, "bundle is corrupted or tampered: nodes.json does not match the header")
+	})
+
+	t.Run("Missing tools", func(t *testing.T) {
+		bad := rewrite(t, data, func(name string, content []byte) []byte {
+			if name == "tools.yaml" {
+				return nil
+			}
+			return content
+		})
+		_, err := bundle.Read(bytes.NewReader(bad))
+		assert.EqualError(t, err, "bundle is corrupted or tampered: tools.yaml is missing")
+	})
+
+	t.Run("Modified header", func(t *testing.T) {
+		bad := rewrite(t, data, func(name string, content []byte) []byte {
+			if name == "bundle.json" {
+				return bytes.Replace(content, []byte(`"entry": "start"`), []byte(`"entry": "end"`), 1)
+			}
+			return content
+		})
+		_, err := bundle.Read(bytes.NewReader(bad))
+		assert.EqualError(t, err, "bundle is corrupted or tampered: header does not match the bundle hash")
+	})
+
+	t.Run("Not a bundle", func(t *testing.T) {
+		_, err := bundle.Read(bytes.NewReader([]byte("start: hello")))
+		assert.ErrorContains(t, err, "not a bundle")
+	})
+}
+
+func TestSignature(t *testing.T) {
+	key := generateKey(t)
+	pub := key.Public().(ed25519.PublicKey)
+	other := generateKey(t).Public().(ed25519.PublicKey)
+
+	signed := newBundle(t)
+	signed.Sign(key)
+	data := encode(t, signed)
+	unsigned := encode(t, newBundle(t))
+
+	t.Run("Trusted", func(t *testing.T) {
+		b, err := bundle.Read(bytes.NewReader(data), other, pub)
+		require.NoError(t, err)
+		assert.Equal(t, bundle.KeyID(pub), b.Signature.KeyID)
+		assert.Equal(t, newBundle(t).Hash, b.Hash, "the signature is not part of the hash")
+	})
+
+	t.Run("No policy", func(t *testing.T) {
+		_, err := bundle.Read(bytes.NewReader(data))
+		assert.NoError(t, err)
+		_, err = bundle.Read(bytes.NewReader(unsigned))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		_, err := bundle.Read(bytes.NewReader(unsigned), pub)
+		assert.EqualError(t, err, "bundle is not signed (the trust policy requires a signature)")
+	})
+
+	t.Run("Untrusted key", func(t *testing.T) {
+		_, err := bundle.Read(bytes.NewReader(data), other)
+		assert.EqualError(t, err, "bundle is signed by untrusted key "+bundle.KeyID(pub))
+	})
+
+	t.Run("Forged signature", func(t *testing.T) {
+		bad := rewrite(t, data, func(name string, content []byte) []byte {
+			if name != "signature.json" {
+				return content
+			}
+			var sig bundle.Signature
+			require.NoError(t, json.Unmarshal(content, &sig))
+			sig.Value[0] ^= 0xff
+			forged, err := json.Marshal(sig)
+			require.NoError(t, err)
+			return forged
+		})
+		_, err := bundle.Read(bytes.NewReader(bad))
+		assert.EqualError(t, err, "bundle signature is invalid", "checked even without a policy")
+	})
+}
+
+func TestKeys(t *testing.T) {
+	dir := t.TempDir()
+	key := generateKey(t)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, "trellis.key")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	var pubs []byte
+	for _, k := range []ed25519.PrivateKey{generateKey(t), key} {
+		der, err := x509.MarshalPKIXPublicKey(k.Public())
+		require.NoError(t, err)
+		pubs = append(pubs, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)
+	}
+	pubPath := filepath.Join(dir, "trusted.pub")
+	require.NoError(t, os.WriteFile(pubPath, pubs, 0644))
+
+	loaded, err := bundle.LoadPrivateKey(keyPath)
+	require.NoError(t, err)
+	assert.True(t, key.Equal(loaded))
+
+	t.Setenv(bundle.TrustedKeysEnv, pubPath)
+	trusted, err := bundle.TrustedKeysFromEnv()
+	require.NoError(t, err)
+	require.Len(t, trusted, 2)
+	assert.True(t, trusted[1].Equal(key.Public()))
+
+	t.Setenv(bundle.TrustedKeysEnv, "")
+	trusted, err = bundle.TrustedKeysFromEnv()
+	require.NoError(t, err)
+	assert.Empty(t, trusted, "no policy")
+
+	_, err = bundle.LoadPrivateKey(pubPath)
+	assert.ErrorContains(t, err, `expected a PEM "PRIVATE KEY" block`)
+	_, err = bundle.LoadTrustedKeys(keyPath)
+	assert.ErrorContains(t, err, `no PEM "PUBLIC KEY" block found`)
+}
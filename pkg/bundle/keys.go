@@ -0,0 +1,110 @@
+package bundle
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TrustedKeysEnv names the environment variable holding the trust policy: a
+// list of PEM files with trusted public keys, separated by the OS path list separator.
+const TrustedKeysEnv = "TRELLIS_TRUSTED_KEYS"
+
+// KeyID is a short fingerprint of a public key (first 8 bytes of its SHA-256, hex).
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParsePrivateKey decodes a PEM "PRIVATE KEY" (PKCS #8) ed25519 key, as produced by
+// `openssl genpkey -algorithm ed25519`.
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("expected a PEM \"PRIVATE KEY\" block")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	ed, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is %T, expected ed25519", key)
+	}
+	return ed, nil
+}
+
+// ParsePublicKeys decodes every PEM "PUBLIC KEY" (PKIX) ed25519 key in data, as
+// produced by `openssl pkey -pubout`. Other PEM blocks are ignored.
+func ParsePublicKeys(data []byte) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %w", err)
+		}
+		ed, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key is %T, expected ed25519", key)
+		}
+		keys = append(keys, ed)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no PEM \"PUBLIC KEY\" block found")
+	}
+	return keys, nil
+}
+
+// LoadPrivateKey reads a PEM private key file.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	key, err := ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// LoadTrustedKeys reads the public keys of every PEM file in the path list
+// (separated by the OS path list separator). An empty list yields no keys.
+func LoadTrustedKeys(list string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, path := range filepath.SplitList(list) {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read trusted keys: %w", err)
+		}
+		parsed, err := ParsePublicKeys(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, parsed...)
+	}
+	return keys, nil
+}
+
+// TrustedKeysFromEnv loads the trust policy from TRELLIS_TRUSTED_KEYS.
+// No keys means no policy: unsigned bundles are accepted.
+func TrustedKeysFromEnv() ([]ed25519.PublicKey, error) {
+	return LoadTrustedKeys(os.Getenv(TrustedKeysEnv))
+}
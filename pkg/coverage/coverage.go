@@ -0,0 +1,254 @@
+// Package coverage measures which parts of a flow a set of sessions exercised: nodes,
+// transitions, error handlers (on_error, on_denied) and signal handlers (on_signal,
+// on_timeout, on_signal_default).
+//
+// A Collector is fed by the engine's lifecycle hooks, so it works with `trellis test` and
+// with any Go test driving an engine:
+//
+//	cov := coverage.NewCollector()
+//	engine, _ := trellis.New("./flow", trellis.WithLifecycleHooks(cov.Hooks()))
+//	// ... drive sessions ...
+//	nodes, _ := engine.Inspect()
+//	coverage.WriteText(os.Stdout, cov.Report(nodes, coverage.WithErrorNode(engine.ErrorNode())))
+package coverage
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// Categories of coverage items.
+const (
+	CategoryNode          = "node"
+	CategoryTransition    = "transition"
+	CategoryErrorHandler  = "error_handler"
+	CategorySignalHandler = "signal_handler"
+)
+
+// Collector counts the nodes entered and the edges taken. It is safe for concurrent use,
+// so one collector can observe sessions running in parallel.
+type Collector struct {
+	mu    sync.Mutex
+	nodes map[string]int
+	edges map[string]int
+}
+
+// NewCollector creates an empty collector.
+func NewCollector() *Collector {
+	return &Collector{nodes: make(map[string]int), edges: make(map[string]int)}
+}
+
+// Hooks returns the hooks that count node entries and transitions for the collector.
+// Install them on every engine whose sessions should count towards the report.
+func (c *Collector) Hooks() domain.LifecycleHooks {
+	return domain.LifecycleHooks{
+		OnNodeEnter: func(_ context.Context, evt *domain.NodeEvent) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.nodes[evt.NodeID]++
+		},
+		OnTransition: func(_ context.Context, evt *domain.TransitionEvent) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.edges[evt.Key()]++
+		},
+	}
+}
+
+// Counts is the coverage of one category.
+type Counts struct {
+	Covered int `json:"covered"`
+	Total   int `json:"total"`
+}
+
+// Percent returns the covered share, or 100 when there is nothing to cover.
+func (c Counts) Percent() float64 {
+	if c.Total == 0 {
+		return 100
+	}
+	return 100 * float64(c.Covered) / float64(c.Total)
+}
+
+func (c *Counts) add(hit bool) {
+	c.Total++
+	if hit {
+		c.Covered++
+	}
+}
+
+// Summary is the coverage of each category.
+type Summary struct {
+	Nodes          Counts `json:"nodes"`
+	Transitions    Counts `json:"transitions"`
+	ErrorHandlers  Counts `json:"error_handlers"`
+	SignalHandlers Counts `json:"signal_handlers"`
+}
+
+func (s *Summary) add(category string, hit bool) {
+	switch category {
+	case CategoryNode:
+		s.Nodes.add(hit)
+	case CategoryTransition:
+		s.Transitions.add(hit)
+	case CategoryErrorHandler:
+		s.ErrorHandlers.add(hit)
+	case CategorySignalHandler:
+		s.SignalHandlers.add(hit)
+	}
+}
+
+// Item is a part of the graph that can be covered: a node, or an edge leaving it.
+type Item struct {
+	Category string `json:"category"`
+	// Key is the node ID, or the domain.EdgeKey of the edge.
+	Key  string `json:"key"`
+	Node string `json:"node"`
+	To   string `json:"to,omitempty"`
+	// Label describes the edge: its condition, handler or signal.
+	Label string `json:"label,omitempty"`
+	File  string `json:"file"`
+	Hits  int    `json:"hits"`
+}
+
+// FileReport is the coverage of the nodes declared in one file.
+type FileReport struct {
+	File string `json:"file"`
+	Summary
+}
+
+// Report is the coverage of a graph.
+type Report struct {
+	Summary
+	Files []FileReport `json:"files"`
+	Items []Item       `json:"items"`
+}
+
+// ReportOption configures a Report.
+type ReportOption func(*reportConfig)
+
+type reportConfig struct {
+	errorNode string
+}
+
+// WithErrorNode sets the global error node (Engine.ErrorNode), so the report lists the
+// failures of tool and llm nodes without on_error, which the engine routes to it.
+func WithErrorNode(nodeID string) ReportOption {
+	return func(cfg *reportConfig) {
+		cfg.errorNode = nodeID
+	}
+}
+
+// Report measures the coverage of the graph (as returned by Engine.Inspect).
+func (c *Collector) Report(nodes []domain.Node, opts ...ReportOption) *Report {
+	var cfg reportConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sorted := append([]domain.Node(nil), nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	r := &Report{}
+	files := make(map[string]*FileReport)
+	var order []string
+	add := func(item Item) {
+		hit := item.Hits > 0
+		r.Items = append(r.Items, item)
+		r.add(item.Category, hit)
+		f, ok := files[item.File]
+		if !ok {
+			f = &FileReport{File: item.File}
+			files[item.File] = f
+			order = append(order, item.File)
+		}
+		f.add(item.Category, hit)
+	}
+
+	for _, n := range sorted {
+		file := n.ID
+		if n.Source != nil && n.Source.File != "" {
+			file = n.Source.File
+		}
+		add(Item{Category: CategoryNode, Key: n.ID, Node: n.ID, File: file, Hits: c.nodes[n.ID]})
+
+		edge := func(category string, kind domain.TransitionKind, index int, signal, to, label string) {
+			key := domain.EdgeKey(n.ID, kind, index, signal)
+			add(Item{Category: category, Key: key, Node: n.ID, To: to, Label: label, File: file, Hits: c.edges[key]})
+		}
+		for i, t := range n.Transitions {
+			edge(CategoryTransition, domain.TransitionNext, i, "", t.ToNodeID, t.Condition)
+		}
+		if n.OnUnclear != "" {
+			edge(CategoryTransition, domain.TransitionUnclear, -1, "", n.OnUnclear, "on_unclear")
+		}
+		if n.OnError != "" && !domain.IsRollback(n.OnError) {
+			edge(CategoryErrorHandler, domain.TransitionError, -1, "", n.OnError, "on_error")
+		}
+		if n.OnDenied != "" {
+			edge(CategoryErrorHandler, domain.TransitionDenied, -1, "", n.OnDenied, "on_denied")
+		}
+		if cfg.errorNode != "" && n.OnError == "" && (n.HasTools() || n.Type == domain.NodeTypeLLM) {
+			edge(CategoryErrorHandler, domain.TransitionErrorNode, -1, "", cfg.errorNode, "error node")
+		}
+		for _, sig := range sortedKeys(n.OnSignal) {
+			edge(CategorySignalHandler, domain.TransitionSignal, -1, sig, n.OnSignal[sig], "on_signal: "+sig)
+		}
+		for _, sig := range sortedKeys(n.OnSignalDefault) {
+			edge(CategorySignalHandler, domain.TransitionSignalDefault, -1, sig, n.OnSignalDefault[sig], "on_signal_default: "+sig)
+		}
+	}
+
+	sort.Strings(order)
+	for _, name := range order {
+		r.Files = append(r.Files, *files[name])
+	}
+	return r
+}
+
+// Uncovered returns the items no session reached.
+func (r *Report) Uncovered() []Item {
+	var out []Item
+	for _, item := range r.Items {
+		if item.Hits == 0 {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// CoveredNodes returns the IDs of the nodes entered.
+func (r *Report) CoveredNodes() []string {
+	var out []string
+	for _, item := range r.Items {
+		if item.Category == CategoryNode && item.Hits > 0 {
+			out = append(out, item.Key)
+		}
+	}
+	return out
+}
+
+// Edges returns whether each edge of the graph was taken, by domain.EdgeKey.
+func (r *Report) Edges() map[string]bool {
+	out := make(map[string]bool)
+	for _, item := range r.Items {
+		if item.Category != CategoryNode {
+			out[item.Key] = item.Hits > 0
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
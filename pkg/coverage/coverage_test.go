@@ -0,0 +1,107 @@
+package coverage_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/internal/testutils/flowtest"
+	"github.com/aretw0/trellis/pkg/coverage"
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+func newEngine(t *testing.T, cov *coverage.Collector) *trellis.Engine {
+	return flowtest.NewEngine(t, flowtest.Checkout, trellis.WithLifecycleHooks(cov.Hooks()))
+}
+
+func TestCollector(t *testing.T) {
+	cov := coverage.NewCollector()
+	engine := newEngine(t, cov)
+	ctx := context.Background()
+
+	state, err := engine.Start(ctx, "s", nil)
+	require.NoError(t, err)
+	paying, err := engine.Navigate(ctx, state, "pay")
+	require.NoError(t, err)
+	_, err = engine.Navigate(ctx, paying, domain.ToolResult{ID: paying.PendingToolCall, IsError: true, Error: "declined"})
+	require.NoError(t, err)
+	_, err = engine.Signal(ctx, state, "timeout")
+	require.NoError(t, err)
+
+	nodes, err := engine.Inspect()
+	require.NoError(t, err)
+	r := cov.Report(nodes)
+
+	assert.Equal(t, coverage.Counts{Covered: 4, Total: 6}, r.Nodes)
+	assert.Equal(t, coverage.Counts{Covered: 1, Total: 3}, r.Transitions)
+	assert.Equal(t, coverage.Counts{Covered: 1, Total: 2}, r.ErrorHandlers)
+	assert.Equal(t, coverage.Counts{Covered: 1, Total: 2}, r.SignalHandlers)
+	assert.InDelta(t, 66.67, r.Nodes.Percent(), 0.01)
+	assert.Equal(t, 100.0, coverage.Counts{}.Percent())
+
+	require.Len(t, r.Files, 6)
+	assert.Equal(t, "pay.yaml", r.Files[4].File)
+	assert.Equal(t, coverage.Counts{Covered: 1, Total: 2}, r.Files[4].ErrorHandlers)
+
+	var uncovered []string
+	for _, item := range r.Uncovered() {
+		uncovered = append(uncovered, item.Category+" "+item.Key)
+	}
+	assert.Equal(t, []string{"node bye", "node done", "transition pay#0", "error_handler pay@on_denied", "transition start#1", "signal_handler *!interrupt"}, uncovered)
+	assert.Equal(t, []string{"failed", "late", "pay", "start"}, r.CoveredNodes())
+	assert.True(t, r.Edges()["start!timeout"])
+	assert.False(t, r.Edges()["start#1"])
+}
+
+func TestCollector_ErrorNode(t *testing.T) {
+	cov := coverage.NewCollector()
+	engine := flowtest.NewEngine(t, map[string]string{
+		"start.yaml": "do: { name: charge }\nto: done\n",
+		"done.md":    "Paid\n",
+		"oops.md":    "Something went wrong\n",
+	}, trellis.WithDefaultErrorNode("oops"), trellis.WithLifecycleHooks(cov.Hooks()))
+	ctx := context.Background()
+
+	state, err := engine.Start(ctx, "s", nil)
+	require.NoError(t, err)
+	_, err = engine.Navigate(ctx, state, domain.ToolResult{ID: state.PendingToolCall, IsError: true, Error: "declined"})
+	require.NoError(t, err)
+
+	nodes, err := engine.Inspect()
+	require.NoError(t, err)
+	r := cov.Report(nodes, coverage.WithErrorNode(engine.ErrorNode()))
+	assert.Equal(t, coverage.Counts{Covered: 1, Total: 1}, r.ErrorHandlers)
+	assert.True(t, r.Edges()[domain.EdgeKey("start", domain.TransitionErrorNode, -1, "")])
+
+	assert.Equal(t, coverage.Counts{}, cov.Report(nodes).ErrorHandlers, "without the error node, the edge is unknown")
+}
+
+func TestWrite(t *testing.T) {
+	cov := coverage.NewCollector()
+	engine := newEngine(t, cov)
+	_, err := engine.Start(context.Background(), "s", nil)
+	require.NoError(t, err)
+	nodes, err := engine.Inspect()
+	require.NoError(t, err)
+	r := cov.Report(nodes)
+
+	var buf bytes.Buffer
+	require.NoError(t, coverage.WriteText(&buf, r))
+	out := buf.String()
+	assert.Contains(t, out, "coverage: nodes 1/6 (16.7%), transitions 0/3 (0.0%), error handlers 0/2 (0.0%), signal handlers 0/2 (0.0%)\n")
+	assert.Contains(t, out, "file       nodes")
+	assert.Contains(t, out, "start.md   1/1 (100.0%)")
+	assert.Contains(t, out, "transition      start → pay   [input == 'pay']")
+	assert.Contains(t, out, "signal handler  start → late  [on_signal: timeout]")
+
+	buf.Reset()
+	require.NoError(t, coverage.WriteJSON(&buf, r))
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, map[string]any{"covered": 1.0, "total": 6.0}, decoded["nodes"])
+}
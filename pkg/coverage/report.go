@@ -0,0 +1,76 @@
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+func (c Counts) String() string {
+	return fmt.Sprintf("%d/%d (%.1f%%)", c.Covered, c.Total, c.Percent())
+}
+
+// WriteText prints the overall coverage, a table per file and the uncovered items.
+func WriteText(w io.Writer, r *Report) error {
+	if _, err := fmt.Fprintf(w, "coverage: nodes %s, transitions %s, error handlers %s, signal handlers %s\n",
+		r.Nodes, r.Transitions, r.ErrorHandlers, r.SignalHandlers); err != nil {
+		return err
+	}
+	if len(r.Files) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nfile\tnodes\ttransitions\terror handlers\tsignal handlers")
+	for _, f := range r.Files {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", f.File, cell(f.Nodes), cell(f.Transitions), cell(f.ErrorHandlers), cell(f.SignalHandlers))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	uncovered := r.Uncovered()
+	if len(uncovered) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "\nuncovered:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, item := range uncovered {
+		if item.Category == CategoryNode {
+			fmt.Fprintf(tw, "  node\t%s\t\t(%s)\n", item.Node, item.File)
+			continue
+		}
+		label := ""
+		if item.Label != "" {
+			label = "[" + item.Label + "]"
+		}
+		fmt.Fprintf(tw, "  %s\t%s → %s\t%s\t(%s)\n", categoryName(item.Category), item.Node, item.To, label, item.File)
+	}
+	return tw.Flush()
+}
+
+// cell leaves out categories a file has nothing of.
+func cell(c Counts) string {
+	if c.Total == 0 {
+		return "-"
+	}
+	return c.String()
+}
+
+func categoryName(category string) string {
+	switch category {
+	case CategoryErrorHandler:
+		return "error handler"
+	case CategorySignalHandler:
+		return "signal handler"
+	}
+	return category
+}
+
+// WriteJSON prints the report as indented JSON.
+func WriteJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,278 @@
+package debugger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// Command operations.
+const (
+	OpStep        = "step"        // Advance one engine step (a tool call round trip is two)
+	OpNext        = "next"        // Advance to the next node that does not call tools, stepping over tool calls
+	OpContinue    = "continue"    // Advance until a breakpoint, an input prompt or the end
+	OpInput       = "input"       // Submit Input at the prompt and stop after the step
+	OpSignal      = "signal"      // Send Signal (e.g. interrupt, timeout) and stop after the step
+	OpRestart     = "restart"     // Start over from the entry node, keeping the breakpoints
+	OpStatus      = "status"      // Return the current stop
+	OpState       = "state"       // Return the full state
+	OpBreak       = "break"       // Add Breakpoint
+	OpDelete      = "delete"      // Remove the breakpoint ID
+	OpBreakpoints = "breakpoints" // List the breakpoints
+	OpGet         = "get"         // Read Key from the context ("sys.x" reads SystemContext, "" all of it)
+	OpSet         = "set"         // Write Value at Key
+	OpUnset       = "unset"       // Remove Key
+	OpEval        = "eval"        // Evaluate the condition Expr against Input (default: the last input)
+	OpTemplate    = "template"    // Render the template Expr against the current state
+	OpInject      = "inject"      // Answer a pending tool call with Result instead of running the tool
+)
+
+// Command is a request to a session, shared by the CLI and the HTTP server.
+type Command struct {
+	Op         string      `json:"op"`
+	Input      any         `json:"input,omitempty"`
+	Signal     string      `json:"signal,omitempty"`
+	Breakpoint *Breakpoint `json:"breakpoint,omitempty"`
+	ID         int         `json:"id,omitempty"`
+	Key        string      `json:"key,omitempty"`
+	Value      any         `json:"value,omitempty"`
+	Expr       string      `json:"expr,omitempty"`
+	// Tool picks the pending call Result answers (default: the first one without a result).
+	Tool   string             `json:"tool,omitempty"`
+	Result *domain.ToolResult `json:"result,omitempty"`
+}
+
+// Reply is the outcome of a command.
+type Reply struct {
+	// Stop is set by the commands that move or change the view of the session.
+	Stop        *Stop         `json:"stop,omitempty"`
+	Value       any           `json:"value,omitempty"`
+	Breakpoints []Breakpoint  `json:"breakpoints,omitempty"`
+	State       *domain.State `json:"state,omitempty"`
+}
+
+// Exec runs a command. Engine failures while moving are reported in the stop (reason
+// "error"); the error return is for commands that cannot run (see ErrInvalidCommand).
+func (s *Session) Exec(ctx context.Context, cmd Command) (*Reply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch cmd.Op {
+	case OpStep, OpNext, OpContinue, OpInput, OpSignal:
+		return s.move(ctx, cmd)
+	case OpRestart:
+		if err := s.start(ctx); err != nil {
+			return nil, err
+		}
+		return s.reply(), nil
+	case OpStatus:
+		return s.reply(), nil
+	case OpState:
+		return &Reply{State: s.state.Snapshot()}, nil
+	case OpBreak:
+		return s.addBreakpoint(cmd.Breakpoint)
+	case OpDelete:
+		for i, bp := range s.breakpoints {
+			if bp.ID == cmd.ID {
+				s.breakpoints = append(s.breakpoints[:i], s.breakpoints[i+1:]...)
+				return &Reply{Breakpoints: s.listBreakpoints()}, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: no breakpoint #%d", ErrInvalidCommand, cmd.ID)
+	case OpBreakpoints:
+		return &Reply{Breakpoints: s.listBreakpoints()}, nil
+	case OpGet:
+		root, path := s.scope(cmd.Key)
+		v, ok := lookup(root, path)
+		if !ok {
+			return nil, fmt.Errorf("%w: '%s' is not set", ErrInvalidCommand, cmd.Key)
+		}
+		return &Reply{Value: v}, nil
+	case OpSet, OpUnset:
+		return s.edit(ctx, cmd)
+	case OpEval:
+		input := cmd.Input
+		if input == nil {
+			input = s.input
+		}
+		ok, err := s.engine.Evaluate(ctx, cmd.Expr, input)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCommand, err)
+		}
+		return &Reply{Value: ok}, nil
+	case OpTemplate:
+		out, err := s.engine.Interpolate(ctx, cmd.Expr, s.state)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCommand, err)
+		}
+		return &Reply{Value: out}, nil
+	case OpInject:
+		return s.inject(cmd)
+	}
+	return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidCommand, cmd.Op)
+}
+
+func (s *Session) reply() *Reply {
+	st := s.last
+	return &Reply{Stop: &st}
+}
+
+func (s *Session) move(ctx context.Context, cmd Command) (*Reply, error) {
+	if s.ended {
+		return nil, ErrEnded
+	}
+	var m move
+	until := func(bool) bool { return true }
+	switch cmd.Op {
+	case OpInput:
+		if cmd.Input == nil {
+			return nil, fmt.Errorf("%w: input needs a value", ErrInvalidCommand)
+		}
+		if len(s.view.calls) > 0 {
+			return nil, fmt.Errorf("%w: node '%s' is waiting for tools, not input", ErrInvalidCommand, s.state.CurrentNodeID)
+		}
+		m.input = cmd.Input
+	case OpSignal:
+		if cmd.Signal == "" {
+			return nil, fmt.Errorf("%w: signal needs a name", ErrInvalidCommand)
+		}
+		m.signal = cmd.Signal
+	case OpNext:
+		until = func(entered bool) bool { return entered && len(s.view.calls) == 0 }
+	case OpContinue:
+		until = func(bool) bool { return false }
+	}
+	s.last = s.run(ctx, m, until)
+	return s.reply(), nil
+}
+
+func (s *Session) addBreakpoint(bp *Breakpoint) (*Reply, error) {
+	if bp == nil || (bp.Node == "" && bp.Tool == "" && bp.Condition == "") {
+		return nil, fmt.Errorf("%w: a breakpoint needs a node, a tool or a condition", ErrInvalidCommand)
+	}
+	s.add(*bp)
+	return &Reply{Breakpoints: s.listBreakpoints()}, nil
+}
+
+func (s *Session) add(bp Breakpoint) {
+	s.nextID++
+	s.breakpoints = append(s.breakpoints, &Breakpoint{ID: s.nextID, Node: bp.Node, Tool: bp.Tool, Condition: bp.Condition})
+}
+
+func (s *Session) listBreakpoints() []Breakpoint {
+	out := make([]Breakpoint, len(s.breakpoints))
+	for i, bp := range s.breakpoints {
+		out[i] = *bp
+	}
+	return out
+}
+
+// scope splits a key into the map it reads ("sys." keys read SystemContext) and the path in it.
+func (s *Session) scope(key string) (map[string]any, []string) {
+	root := s.state.Context
+	if key == "sys" || strings.HasPrefix(key, "sys.") {
+		root, key = s.state.SystemContext, strings.TrimPrefix(strings.TrimPrefix(key, "sys"), ".")
+	}
+	if key == "" {
+		return root, nil
+	}
+	return root, strings.Split(key, ".")
+}
+
+// edit sets or removes a key, copying the maps along its path so earlier states are left
+// untouched, and renders the node again with the new context.
+func (s *Session) edit(ctx context.Context, cmd Command) (*Reply, error) {
+	if cmd.Key == "" || cmd.Key == "sys" {
+		return nil, fmt.Errorf("%w: %s needs a key", ErrInvalidCommand, cmd.Op)
+	}
+	state := s.state.Snapshot()
+	s.state = state
+	root, path := s.scope(cmd.Key)
+	root = cloneMap(root)
+	if strings.HasPrefix(cmd.Key, "sys.") {
+		state.SystemContext = root
+	} else {
+		state.Context = root
+	}
+	m := root
+	for _, part := range path[:len(path)-1] {
+		child, _ := m[part].(map[string]any)
+		child = cloneMap(child)
+		m[part] = child
+		m = child
+	}
+	leaf := path[len(path)-1]
+	if cmd.Op == OpUnset {
+		delete(m, leaf)
+	} else {
+		m[leaf] = cmd.Value
+	}
+
+	s.render(ctx)
+	s.refresh()
+	return s.reply(), nil
+}
+
+// inject stores a fake result for a pending call, used by the next move instead of running the tool.
+func (s *Session) inject(cmd Command) (*Reply, error) {
+	if cmd.Result == nil {
+		return nil, fmt.Errorf("%w: inject needs a result", ErrInvalidCommand)
+	}
+	if len(s.view.calls) == 0 {
+		return nil, fmt.Errorf("%w: node '%s' has no pending tool call", ErrInvalidCommand, s.state.CurrentNodeID)
+	}
+	for _, call := range s.view.calls {
+		switch {
+		case cmd.Result.ID != "" && call.ID != cmd.Result.ID,
+			cmd.Tool != "" && call.Name != cmd.Tool:
+			continue
+		}
+		if _, done := s.injected[call.ID]; done && cmd.Result.ID == "" {
+			continue
+		}
+		result := *cmd.Result
+		result.ID = call.ID
+		s.injected[call.ID] = result
+		s.refresh()
+		return s.reply(), nil
+	}
+	return nil, fmt.Errorf("%w: no pending call matches (calls: %s)", ErrInvalidCommand, callNames(s.view.calls))
+}
+
+// refresh describes the current view again, keeping why the session stopped.
+func (s *Session) refresh() {
+	reason := s.last.Reason
+	if reason == ReasonError {
+		reason = ReasonStep // The view is rendered again: a render error shows up anew
+	}
+	st := s.stop(reason, nil)
+	if st.Reason == ReasonBreakpoint {
+		st.Breakpoint = s.last.Breakpoint
+	}
+	s.last = st
+}
+
+func callNames(calls []domain.ToolCall) string {
+	names := make([]string, len(calls))
+	for i, c := range calls {
+		names[i] = c.Name + " (" + c.ID + ")"
+	}
+	return strings.Join(names, ", ")
+}
+
+// lookup follows path into nested maps.
+func lookup(root map[string]any, path []string) (any, bool) {
+	var cur any = root
+	for _, part := range path {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
@@ -0,0 +1,410 @@
+// Package debugger steps through a flow session: breakpoints on nodes, tools and
+// conditions; step, next and continue; inspection and editing of the context at a stop;
+// ad-hoc conditions and templates evaluated against the current state; and fake tool
+// results injected instead of running the tool.
+//
+// A Session is driven by Commands, the protocol shared by `trellis debug` (see Parse for
+// its text form) and the HTTP server's /debug routes (see Manager):
+//
+//	s, _ := debugger.Start(ctx, engine, "debug", nil, debugger.WithToolRunner(tools))
+//	s.Exec(ctx, debugger.Command{Op: debugger.OpBreak, Breakpoint: &debugger.Breakpoint{Tool: "charge"}})
+//	reply, _ := s.Exec(ctx, debugger.Command{Op: debugger.OpContinue})
+//	fmt.Println(reply.Stop.Reason, reply.Stop.ToolCalls)
+package debugger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/ports"
+	"github.com/aretw0/trellis/pkg/runner"
+)
+
+// DefaultMaxSteps bounds next and continue, so a flow that loops without asking for
+// input stops instead of hanging the session.
+const DefaultMaxSteps = 1000
+
+// Reasons a session stops.
+const (
+	ReasonStart      = "start"      // The session (re)started at the entry node
+	ReasonStep       = "step"       // A step or next completed
+	ReasonBreakpoint = "breakpoint" // A breakpoint was hit
+	ReasonInput      = "input"      // The node waits for input (or a signal)
+	ReasonEnd        = "end"        // The flow terminated
+	ReasonError      = "error"      // The engine failed; the state is left as it was
+	ReasonLimit      = "limit"      // MaxSteps ran out
+)
+
+var (
+	// ErrInvalidCommand reports a command that is unknown, malformed or not valid at the current stop.
+	ErrInvalidCommand = errors.New("invalid command")
+	// ErrEnded reports a move after the flow terminated.
+	ErrEnded = errors.New("session ended (restart it to run the flow again)")
+)
+
+// Engine is what a debug session drives.
+type Engine interface {
+	ports.StatelessEngine
+	Start(ctx context.Context, sessionID string, initialContext map[string]any) (*domain.State, error)
+	Evaluate(ctx context.Context, condition string, input any) (bool, error)
+	Interpolate(ctx context.Context, templateStr string, state *domain.State) (string, error)
+}
+
+// Breakpoint pauses the session. Every field set must match:
+//   - Node: the session enters the node (or, with Tool, is calling the tool there);
+//   - Tool: a call to the tool is pending, before it runs;
+//   - Condition: the condition holds for the last input. On its own, it stops when
+//     the condition becomes true, not at every step while it stays true.
+type Breakpoint struct {
+	ID        int    `json:"id"`
+	Node      string `json:"node,omitempty"`
+	Tool      string `json:"tool,omitempty"`
+	Condition string `json:"condition,omitempty"`
+	Hits      int    `json:"hits"`
+
+	met bool // Condition result at the last check (conditions on their own)
+}
+
+func (b *Breakpoint) String() string {
+	var parts []string
+	if b.Node != "" {
+		parts = append(parts, "node "+b.Node)
+	}
+	if b.Tool != "" {
+		parts = append(parts, "tool "+b.Tool)
+	}
+	if b.Condition != "" {
+		parts = append(parts, "if "+b.Condition)
+	}
+	return fmt.Sprintf("#%d %s", b.ID, strings.Join(parts, " "))
+}
+
+// Stop is where the session paused and what the current node does.
+type Stop struct {
+	Reason string                 `json:"reason"`
+	Node   string                 `json:"node"`
+	Status domain.ExecutionStatus `json:"status"`
+	// Content is what the node renders.
+	Content []string `json:"content,omitempty"`
+	// WaitingInput is set when the next move needs an input or a signal.
+	WaitingInput bool `json:"waiting_input,omitempty"`
+	// ToolCalls are the pending calls, run (or answered with the injected results) by the next move.
+	ToolCalls []domain.ToolCall `json:"tool_calls,omitempty"`
+	// Injected lists the IDs of the pending calls that have a fake result.
+	Injected   []string    `json:"injected,omitempty"`
+	Breakpoint *Breakpoint `json:"breakpoint,omitempty"`
+	Ended      bool        `json:"ended,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Option configures a Session.
+type Option func(*Session)
+
+// WithToolRunner executes the tool calls no result was injected for.
+// Without it, every call needs an injected result.
+func WithToolRunner(tr runner.ToolRunner) Option {
+	return func(s *Session) {
+		s.tools = tr
+	}
+}
+
+// WithMaxSteps bounds next and continue (default DefaultMaxSteps).
+func WithMaxSteps(n int) Option {
+	return func(s *Session) {
+		s.maxSteps = n
+	}
+}
+
+// WithBreakpoints sets breakpoints before the session starts, so they apply to the entry node too.
+func WithBreakpoints(bps ...Breakpoint) Option {
+	return func(s *Session) {
+		for _, bp := range bps {
+			s.add(bp)
+		}
+	}
+}
+
+// Session is a flow session under the debugger. It is safe for concurrent use:
+// commands run one at a time.
+type Session struct {
+	mu       sync.Mutex
+	id       string
+	engine   Engine
+	tools    runner.ToolRunner
+	maxSteps int
+	initial  map[string]any
+
+	state       *domain.State
+	view        view
+	input       any // Last input given
+	ended       bool
+	breakpoints []*Breakpoint
+	nextID      int
+	injected    map[string]domain.ToolResult // Fake results, by call ID
+	last        Stop
+}
+
+// view is what the engine renders for the current state.
+type view struct {
+	content    []string
+	needsInput bool
+	terminal   bool
+	calls      []domain.ToolCall // Pending calls only
+	err        error
+}
+
+// Start begins a session at the flow's entry node and stops there.
+func Start(ctx context.Context, engine Engine, sessionID string, initialContext map[string]any, opts ...Option) (*Session, error) {
+	s := &Session{
+		id:       sessionID,
+		engine:   engine,
+		maxSteps: DefaultMaxSteps,
+		initial:  initialContext,
+		injected: make(map[string]domain.ToolResult),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.start(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ID returns the session ID.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Stop returns where the session is paused.
+func (s *Session) Stop() Stop {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// State returns a copy of the current state.
+func (s *Session) State() *domain.State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Snapshot()
+}
+
+func (s *Session) start(ctx context.Context) error {
+	state, err := s.engine.Start(ctx, s.id, cloneMap(s.initial))
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	s.state, s.input, s.ended = state, nil, false
+	clear(s.injected)
+	for _, bp := range s.breakpoints {
+		bp.met = false
+	}
+	s.render(ctx)
+	s.last = s.stop(ReasonStart, s.hit(ctx, true))
+	return nil
+}
+
+// render computes the view of the current state.
+func (s *Session) render(ctx context.Context) {
+	s.view = view{}
+	actions, terminal, err := s.engine.Render(ctx, s.state)
+	if err != nil {
+		s.view.err = err
+		return
+	}
+	rendered := domain.NewRenderView(actions)
+	s.view.terminal = terminal
+	s.view.needsInput = rendered.NeedsInput
+	for _, msg := range rendered.Content {
+		if msg = strings.TrimSpace(msg); msg != "" {
+			s.view.content = append(s.view.content, msg)
+		}
+	}
+	if s.state.Status == domain.StatusWaitingForTool || s.state.Status == domain.StatusRollingBack {
+		s.view.calls = rendered.Pending(s.state)
+	}
+}
+
+// stop describes the current state.
+func (s *Session) stop(reason string, bp *Breakpoint) Stop {
+	st := Stop{
+		Reason:       reason,
+		Node:         s.state.CurrentNodeID,
+		Status:       s.state.Status,
+		Content:      s.view.content,
+		WaitingInput: s.view.needsInput && !s.ended,
+		ToolCalls:    s.view.calls,
+		Ended:        s.ended,
+	}
+	for _, call := range s.view.calls {
+		if _, ok := s.injected[call.ID]; ok {
+			st.Injected = append(st.Injected, call.ID)
+		}
+	}
+	if bp != nil {
+		st.Reason = ReasonBreakpoint
+		copied := *bp
+		st.Breakpoint = &copied
+	}
+	if s.ended {
+		st.Reason = ReasonEnd
+	}
+	if s.view.err != nil {
+		st.Reason, st.Error = ReasonError, s.view.err.Error()
+	}
+	return st
+}
+
+// move is one engine step: the input or signal given, the pending tool calls, or an auto transition.
+type move struct {
+	input  any
+	signal string
+}
+
+// step advances the session once. It reports whether a node was entered, and returns
+// engine errors without changing the state.
+func (s *Session) step(ctx context.Context, m move) (entered bool, err error) {
+	if s.view.err != nil {
+		return false, s.view.err
+	}
+	prev := s.state
+	var next *domain.State
+	switch {
+	case m.signal != "":
+		next, err = s.engine.Signal(ctx, prev, m.signal)
+	case len(s.view.calls) > 0:
+		var results any
+		if results, err = s.runTools(ctx); err == nil {
+			next, err = s.engine.Navigate(ctx, prev, results)
+		}
+	case m.input != nil:
+		s.input = m.input
+		next, err = s.engine.Navigate(ctx, prev, m.input)
+	default:
+		next, err = s.engine.Navigate(ctx, prev, "")
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if domain.FlowEnded(prev, next, s.view.terminal, s.view.needsInput) {
+		s.ended = true
+	}
+	s.state = next
+	s.render(ctx)
+	return len(next.History) > len(prev.History) || next.CurrentNodeID != prev.CurrentNodeID, nil
+}
+
+// runTools answers the pending calls with their injected results, running the others.
+// A single call gets a single result, a batch gets all of them.
+func (s *Session) runTools(ctx context.Context) (any, error) {
+	results := make([]domain.ToolResult, 0, len(s.view.calls))
+	for _, call := range s.view.calls {
+		result, ok := s.injected[call.ID]
+		if ok {
+			delete(s.injected, call.ID)
+		} else {
+			if s.tools == nil {
+				return nil, fmt.Errorf("no tool runner to execute '%s': inject a result", call.Name)
+			}
+			var err error
+			if result, err = s.tools.Execute(ctx, call); err != nil {
+				result = domain.ToolResult{IsError: true, Error: err.Error()}
+			}
+		}
+		result.ID = call.ID
+		results = append(results, result)
+	}
+	return domain.ToolInput(s.state, results), nil
+}
+
+// run moves the session until it should stop. The first move uses m; the following ones
+// are auto transitions and tool calls. until reports, after each step, whether to stop.
+func (s *Session) run(ctx context.Context, m move, until func(entered bool) bool) Stop {
+	for i := range s.maxSteps {
+		if s.ended {
+			break
+		}
+		if i > 0 || (m.input == nil && m.signal == "") {
+			if s.view.needsInput && len(s.view.calls) == 0 {
+				return s.stop(ReasonInput, nil)
+			}
+		}
+		entered, err := s.step(ctx, m)
+		if err != nil {
+			st := s.stop(ReasonError, nil)
+			st.Error = err.Error()
+			return st
+		}
+		m = move{}
+		if bp := s.hit(ctx, entered); bp != nil || s.ended || s.view.err != nil {
+			return s.stop(ReasonStep, bp)
+		}
+		if until(entered) {
+			return s.stop(ReasonStep, nil)
+		}
+	}
+	if s.ended {
+		return s.stop(ReasonEnd, nil)
+	}
+	return s.stop(ReasonLimit, nil)
+}
+
+// hit checks the breakpoints against the current state, returning the first one hit.
+// Conditions on their own are all evaluated, to track when they become true.
+func (s *Session) hit(ctx context.Context, entered bool) *Breakpoint {
+	var first *Breakpoint
+	for _, bp := range s.breakpoints {
+		if s.matches(ctx, bp, entered) && first == nil {
+			first = bp
+		}
+	}
+	if first != nil {
+		first.Hits++
+	}
+	return first
+}
+
+func (s *Session) matches(ctx context.Context, bp *Breakpoint, entered bool) bool {
+	if bp.Node != "" && (s.state.CurrentNodeID != bp.Node || (bp.Tool == "" && !entered)) {
+		return false
+	}
+	if bp.Tool != "" && !s.calling(bp.Tool) {
+		return false
+	}
+	if bp.Condition == "" {
+		return true
+	}
+	ok, err := s.engine.Evaluate(ctx, bp.Condition, s.input)
+	if err != nil {
+		ok = true // Stop on broken conditions instead of skipping them silently
+	}
+	if bp.Node != "" || bp.Tool != "" {
+		return ok
+	}
+	was := bp.met
+	bp.met = ok
+	return ok && !was
+}
+
+func (s *Session) calling(tool string) bool {
+	for _, call := range s.view.calls {
+		if call.Name == tool {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneMap(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
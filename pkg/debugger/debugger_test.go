@@ -0,0 +1,287 @@
+package debugger_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/internal/testutils/flowtest"
+	"github.com/aretw0/trellis/pkg/debugger"
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+type fakeTools struct{ calls []string }
+
+func (f *fakeTools) Execute(_ context.Context, call domain.ToolCall) (domain.ToolResult, error) {
+	f.calls = append(f.calls, call.Name)
+	return domain.ToolResult{ID: call.ID, Result: map[string]any{"status": "paid"}}, nil
+}
+
+func newEngine(t *testing.T) *trellis.Engine {
+	return flowtest.NewEngine(t, flowtest.Checkout)
+}
+
+func exec(t *testing.T, s *debugger.Session, cmd debugger.Command) *debugger.Reply {
+	t.Helper()
+	reply, err := s.Exec(context.Background(), cmd)
+	require.NoError(t, err)
+	return reply
+}
+
+func TestSession_Stepping(t *testing.T) {
+	tools := &fakeTools{}
+	s, err := debugger.Start(context.Background(), newEngine(t), "dbg", map[string]any{"name": "Ada", "amount": 10}, debugger.WithToolRunner(tools))
+	require.NoError(t, err)
+
+	stop := s.Stop()
+	assert.Equal(t, debugger.ReasonStart, stop.Reason)
+	assert.Equal(t, "start", stop.Node)
+	assert.Equal(t, []string{"Hi Ada"}, stop.Content)
+	assert.True(t, stop.WaitingInput)
+
+	// Moving without input stops at the prompt.
+	stop = *exec(t, s, debugger.Command{Op: debugger.OpContinue}).Stop
+	assert.Equal(t, debugger.ReasonInput, stop.Reason)
+	assert.Equal(t, "start", stop.Node)
+
+	// Entering a tool node dispatches the call: the stop shows it before it runs.
+	stop = *exec(t, s, debugger.Command{Op: debugger.OpInput, Input: "pay"}).Stop
+	assert.Equal(t, debugger.ReasonStep, stop.Reason)
+	assert.Equal(t, "pay", stop.Node)
+	assert.Equal(t, domain.StatusWaitingForTool, stop.Status)
+	require.Len(t, stop.ToolCalls, 1)
+	assert.Equal(t, "charge", stop.ToolCalls[0].Name)
+	assert.Equal(t, "10", stop.ToolCalls[0].Args["amount"])
+	assert.Empty(t, tools.calls)
+
+	stop = *exec(t, s, debugger.Command{Op: debugger.OpNext}).Stop
+	assert.Equal(t, "done", stop.Node)
+	assert.Equal(t, []string{"Paid: paid"}, stop.Content)
+	assert.Equal(t, []string{"charge"}, tools.calls)
+
+	stop = *exec(t, s, debugger.Command{Op: debugger.OpContinue}).Stop
+	assert.Equal(t, debugger.ReasonEnd, stop.Reason)
+	assert.True(t, stop.Ended)
+	_, err = s.Exec(context.Background(), debugger.Command{Op: debugger.OpStep})
+	assert.ErrorIs(t, err, debugger.ErrEnded)
+
+	stop = *exec(t, s, debugger.Command{Op: debugger.OpRestart}).Stop
+	assert.Equal(t, debugger.ReasonStart, stop.Reason)
+	assert.Equal(t, "start", stop.Node)
+}
+
+func TestSession_Next(t *testing.T) {
+	engine := flowtest.NewEngine(t, map[string]string{
+		"start.md":   "---\nto: fetch\n---\nLoading\n",
+		"fetch.yaml": "do: { name: fetch }\nto: check\n",
+		"check.yaml": "do: { name: check }\nto: done\n",
+		"done.md":    "---\nwait: true\n---\nReady\n",
+	})
+
+	s, err := debugger.Start(context.Background(), engine, "dbg", nil, debugger.WithToolRunner(&fakeTools{}))
+	require.NoError(t, err)
+	assert.Equal(t, "fetch", exec(t, s, debugger.Command{Op: debugger.OpStep}).Stop.Node)
+	assert.Equal(t, "check", exec(t, s, debugger.Command{Op: debugger.OpStep}).Stop.Node)
+
+	exec(t, s, debugger.Command{Op: debugger.OpRestart})
+	stop := *exec(t, s, debugger.Command{Op: debugger.OpNext}).Stop
+	assert.Equal(t, "done", stop.Node)
+	assert.True(t, stop.WaitingInput)
+}
+
+func TestSession_Breakpoints(t *testing.T) {
+	tools := &fakeTools{}
+	s, err := debugger.Start(context.Background(), newEngine(t), "dbg", nil, debugger.WithToolRunner(tools))
+	require.NoError(t, err)
+
+	exec(t, s, debugger.Command{Op: debugger.OpBreak, Breakpoint: &debugger.Breakpoint{Tool: "charge"}})
+	exec(t, s, debugger.Command{Op: debugger.OpBreak, Breakpoint: &debugger.Breakpoint{Node: "done"}})
+	reply := exec(t, s, debugger.Command{Op: debugger.OpBreak, Breakpoint: &debugger.Breakpoint{Condition: "input == 'pay'"}})
+	require.Len(t, reply.Breakpoints, 3)
+
+	stop := *exec(t, s, debugger.Command{Op: debugger.OpInput, Input: "pay"}).Stop
+	assert.Equal(t, debugger.ReasonBreakpoint, stop.Reason)
+	assert.Equal(t, 1, stop.Breakpoint.ID)
+	assert.Empty(t, tools.calls, "the tool breakpoint stops before the call")
+
+	stop = *exec(t, s, debugger.Command{Op: debugger.OpContinue}).Stop
+	assert.Equal(t, debugger.ReasonBreakpoint, stop.Reason)
+	assert.Equal(t, 2, stop.Breakpoint.ID)
+	assert.Equal(t, []string{"Paid: paid"}, stop.Content)
+	assert.Equal(t, debugger.ReasonEnd, exec(t, s, debugger.Command{Op: debugger.OpContinue}).Stop.Reason)
+
+	// The condition stops once when it becomes true, not at every step while it holds.
+	exec(t, s, debugger.Command{Op: debugger.OpRestart})
+	exec(t, s, debugger.Command{Op: debugger.OpDelete, ID: 1})
+	stop = *exec(t, s, debugger.Command{Op: debugger.OpInput, Input: "pay"}).Stop
+	assert.Equal(t, 3, stop.Breakpoint.ID)
+	assert.Equal(t, 2, exec(t, s, debugger.Command{Op: debugger.OpContinue}).Stop.Breakpoint.ID)
+
+	// A fake result replaces the tool.
+	exec(t, s, debugger.Command{Op: debugger.OpRestart})
+	exec(t, s, debugger.Command{Op: debugger.OpDelete, ID: 3})
+	exec(t, s, debugger.Command{Op: debugger.OpBreak, Breakpoint: &debugger.Breakpoint{Node: "pay", Tool: "charge"}})
+	stop = *exec(t, s, debugger.Command{Op: debugger.OpInput, Input: "pay"}).Stop
+	assert.Equal(t, 4, stop.Breakpoint.ID)
+	stop = *exec(t, s, debugger.Command{Op: debugger.OpInject, Result: &domain.ToolResult{IsError: true, Error: "declined"}}).Stop
+	assert.Equal(t, debugger.ReasonBreakpoint, stop.Reason)
+	assert.Equal(t, []string{stop.ToolCalls[0].ID}, stop.Injected)
+	stop = *exec(t, s, debugger.Command{Op: debugger.OpContinue}).Stop
+	assert.Equal(t, debugger.ReasonEnd, stop.Reason)
+	assert.Equal(t, "failed", stop.Node)
+	assert.Equal(t, []string{"charge", "charge"}, tools.calls)
+
+	reply = exec(t, s, debugger.Command{Op: debugger.OpBreakpoints})
+	require.Len(t, reply.Breakpoints, 2)
+	assert.Equal(t, 2, reply.Breakpoints[0].Hits)
+	assert.Equal(t, "#4 node pay tool charge", reply.Breakpoints[1].String())
+	assert.Equal(t, 1, reply.Breakpoints[1].Hits)
+
+	_, err = s.Exec(context.Background(), debugger.Command{Op: debugger.OpDelete, ID: 9})
+	assert.ErrorIs(t, err, debugger.ErrInvalidCommand)
+	_, err = s.Exec(context.Background(), debugger.Command{Op: debugger.OpBreak, Breakpoint: &debugger.Breakpoint{}})
+	assert.ErrorIs(t, err, debugger.ErrInvalidCommand)
+}
+
+func TestSession_Inspection(t *testing.T) {
+	s, err := debugger.Start(context.Background(), newEngine(t), "dbg", map[string]any{"name": "Ada", "user": map[string]any{"plan": "free"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "free", exec(t, s, debugger.Command{Op: debugger.OpGet, Key: "user.plan"}).Value)
+	_, err = s.Exec(context.Background(), debugger.Command{Op: debugger.OpGet, Key: "missing"})
+	assert.ErrorIs(t, err, debugger.ErrInvalidCommand)
+
+	// Edits render the node again and leave earlier states untouched.
+	before := s.State()
+	stop := *exec(t, s, debugger.Command{Op: debugger.OpSet, Key: "name", Value: "Grace"}).Stop
+	assert.Equal(t, []string{"Hi Grace"}, stop.Content)
+	exec(t, s, debugger.Command{Op: debugger.OpSet, Key: "user.plan", Value: "pro"})
+	exec(t, s, debugger.Command{Op: debugger.OpSet, Key: "sys.debug", Value: true})
+	assert.Equal(t, "free", before.Context["user"].(map[string]any)["plan"])
+	state := exec(t, s, debugger.Command{Op: debugger.OpState}).State
+	assert.Equal(t, "pro", state.Context["user"].(map[string]any)["plan"])
+	assert.Equal(t, true, state.SystemContext["debug"])
+	exec(t, s, debugger.Command{Op: debugger.OpUnset, Key: "name"})
+	_, err = s.Exec(context.Background(), debugger.Command{Op: debugger.OpGet, Key: "name"})
+	assert.Error(t, err)
+
+	assert.Equal(t, true, exec(t, s, debugger.Command{Op: debugger.OpEval, Expr: "input == 'pay'", Input: "PAY"}).Value)
+	assert.Equal(t, false, exec(t, s, debugger.Command{Op: debugger.OpEval, Expr: "input == 'pay'"}).Value)
+	assert.Equal(t, "plan=pro debug=true", exec(t, s, debugger.Command{Op: debugger.OpTemplate, Expr: "plan={{ .user.plan }} debug={{ .sys.debug }}"}).Value)
+	_, err = s.Exec(context.Background(), debugger.Command{Op: debugger.OpTemplate, Expr: "{{ .broken"})
+	assert.ErrorIs(t, err, debugger.ErrInvalidCommand)
+
+	// Without a tool runner, calls need an injected result.
+	_, err = s.Exec(context.Background(), debugger.Command{Op: debugger.OpInject, Result: &domain.ToolResult{}})
+	assert.ErrorIs(t, err, debugger.ErrInvalidCommand, "no pending call yet")
+	exec(t, s, debugger.Command{Op: debugger.OpInput, Input: "pay"})
+	stop = *exec(t, s, debugger.Command{Op: debugger.OpStep}).Stop
+	assert.Equal(t, debugger.ReasonError, stop.Reason)
+	assert.Contains(t, stop.Error, "inject a result")
+	_, err = s.Exec(context.Background(), debugger.Command{Op: debugger.OpInject, Tool: "other", Result: &domain.ToolResult{}})
+	assert.ErrorIs(t, err, debugger.ErrInvalidCommand)
+	exec(t, s, debugger.Command{Op: debugger.OpInject, Tool: "charge", Result: &domain.ToolResult{Result: map[string]any{"status": "faked"}}})
+	stop = *exec(t, s, debugger.Command{Op: debugger.OpNext}).Stop
+	assert.Equal(t, []string{"Paid: faked"}, stop.Content)
+
+	_, err = s.Exec(context.Background(), debugger.Command{Op: "jump"})
+	assert.ErrorIs(t, err, debugger.ErrInvalidCommand)
+}
+
+func TestManager(t *testing.T) {
+	m := debugger.NewManager(newEngine(t))
+	ctx := context.Background()
+
+	s, err := m.Create(ctx, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "debug-1", s.ID())
+	_, err = m.Create(ctx, "debug-1", nil)
+	assert.ErrorIs(t, err, debugger.ErrSessionExists)
+
+	reply, err := m.Exec(ctx, "debug-1", debugger.Command{Op: debugger.OpInput, Input: "no"})
+	require.NoError(t, err)
+	assert.Equal(t, "bye", reply.Stop.Node)
+
+	require.NoError(t, m.Delete("debug-1"))
+	_, err = m.Exec(ctx, "debug-1", debugger.Command{Op: debugger.OpStatus})
+	assert.ErrorIs(t, err, debugger.ErrSessionNotFound)
+	assert.ErrorIs(t, m.Delete("debug-1"), debugger.ErrSessionNotFound)
+}
+
+func TestManager_Eviction(t *testing.T) {
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	m := debugger.NewManager(newEngine(t), debugger.WithSessionTTL(time.Minute), debugger.WithMaxSessions(2),
+		debugger.WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b"} {
+		_, err := m.Create(ctx, id, nil)
+		require.NoError(t, err)
+		now = now.Add(10 * time.Second)
+	}
+	_, err := m.Get("a")
+	require.NoError(t, err)
+
+	// At the limit, the least recently used session makes room.
+	_, err = m.Create(ctx, "c", nil)
+	require.NoError(t, err)
+	_, err = m.Get("b")
+	assert.ErrorIs(t, err, debugger.ErrSessionNotFound)
+
+	// A duplicate is refused before making room: nobody is evicted.
+	now = now.Add(time.Second)
+	_, err = m.Get("c")
+	require.NoError(t, err)
+	_, err = m.Create(ctx, "c", nil)
+	assert.ErrorIs(t, err, debugger.ErrSessionExists)
+	_, err = m.Get("a")
+	require.NoError(t, err)
+
+	// Idle sessions expire; used ones stay.
+	now = now.Add(50 * time.Second)
+	_, err = m.Get("c")
+	require.NoError(t, err)
+	now = now.Add(30 * time.Second)
+	_, err = m.Get("a")
+	assert.ErrorIs(t, err, debugger.ErrSessionNotFound)
+	_, err = m.Get("c")
+	assert.NoError(t, err)
+}
+
+func TestParse(t *testing.T) {
+	for line, want := range map[string]debugger.Command{
+		"s":                               {Op: debugger.OpStep},
+		"continue":                        {Op: debugger.OpContinue},
+		"i  yes please ":                  {Op: debugger.OpInput, Input: "yes please"},
+		"signal interrupt":                {Op: debugger.OpSignal, Signal: "interrupt"},
+		"b pay":                           {Op: debugger.OpBreak, Breakpoint: &debugger.Breakpoint{Node: "pay"}},
+		"b notify":                        {Op: debugger.OpBreak, Breakpoint: &debugger.Breakpoint{Node: "notify"}},
+		"break pay if input == 'x'":       {Op: debugger.OpBreak, Breakpoint: &debugger.Breakpoint{Node: "pay", Condition: "input == 'x'"}},
+		"break tool charge":               {Op: debugger.OpBreak, Breakpoint: &debugger.Breakpoint{Tool: "charge"}},
+		"break if input == 'x'":           {Op: debugger.OpBreak, Breakpoint: &debugger.Breakpoint{Condition: "input == 'x'"}},
+		"delete #2":                       {Op: debugger.OpDelete, ID: 2},
+		"p user.plan":                     {Op: debugger.OpGet, Key: "user.plan"},
+		"set count 3":                     {Op: debugger.OpSet, Key: "count", Value: 3.0},
+		"set name Ada Lovelace":           {Op: debugger.OpSet, Key: "name", Value: "Ada Lovelace"},
+		"set user {\"plan\": \"pro\"}":    {Op: debugger.OpSet, Key: "user", Value: map[string]any{"plan": "pro"}},
+		"eval input == 'x'":               {Op: debugger.OpEval, Expr: "input == 'x'"},
+		"t {{ .name }}":                   {Op: debugger.OpTemplate, Expr: "{{ .name }}"},
+		"inject {\"id\": 1}":              {Op: debugger.OpInject, Result: &domain.ToolResult{Result: map[string]any{"id": 1.0}}},
+		"inject tool charge error nope":   {Op: debugger.OpInject, Tool: "charge", Result: &domain.ToolResult{IsError: true, Error: "nope"}},
+		"inject denied":                   {Op: debugger.OpInject, Result: &domain.ToolResult{IsDenied: true}},
+		"inject tool charge \"declined\"": {Op: debugger.OpInject, Tool: "charge", Result: &domain.ToolResult{Result: "declined"}},
+	} {
+		got, err := debugger.Parse(line)
+		require.NoError(t, err, line)
+		assert.Equal(t, want, got, line)
+	}
+
+	for _, line := range []string{"", "jump", "delete x", "set name", "break a b", "inject"} {
+		_, err := debugger.Parse(line)
+		assert.ErrorIs(t, err, debugger.ErrInvalidCommand, line)
+	}
+}
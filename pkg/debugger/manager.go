@@ -0,0 +1,183 @@
+package debugger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSessionNotFound reports an unknown debug session ID.
+	ErrSessionNotFound = errors.New("debug session not found")
+	// ErrSessionExists reports a debug session ID already in use.
+	ErrSessionExists = errors.New("debug session already exists")
+)
+
+// Manager defaults: debug sessions are interactive, so an idle one is most likely abandoned.
+const (
+	DefaultSessionTTL  = 30 * time.Minute
+	DefaultMaxSessions = 64
+)
+
+// Manager keeps the debug sessions of a server, so clients such as the web inspector
+// can drive them by ID. Sessions idle for longer than the TTL are dropped, and creating
+// a session beyond the limit evicts the least recently used one.
+type Manager struct {
+	engine      Engine
+	opts        []Option
+	ttl         time.Duration
+	maxSessions int
+	now         func() time.Time
+	mu          sync.Mutex
+	sessions    map[string]*managed
+	seq         int
+}
+
+// managed is a session with the time it was last used. A nil session is reserved while starting.
+type managed struct {
+	session *Session
+	used    time.Time
+}
+
+// ManagerOption configures a Manager.
+type ManagerOption func(*Manager)
+
+// WithSessionOptions sets the options of the sessions the manager creates.
+func WithSessionOptions(opts ...Option) ManagerOption {
+	return func(m *Manager) {
+		m.opts = append(m.opts, opts...)
+	}
+}
+
+// WithSessionTTL drops sessions idle for longer than ttl (default DefaultSessionTTL).
+// Zero or less keeps idle sessions until they are evicted by the limit.
+func WithSessionTTL(ttl time.Duration) ManagerOption {
+	return func(m *Manager) {
+		m.ttl = ttl
+	}
+}
+
+// WithMaxSessions caps the number of live sessions (default DefaultMaxSessions).
+// Zero or less removes the cap.
+func WithMaxSessions(n int) ManagerOption {
+	return func(m *Manager) {
+		m.maxSessions = n
+	}
+}
+
+// WithClock sets the clock used for session expiry (default time.Now).
+func WithClock(now func() time.Time) ManagerOption {
+	return func(m *Manager) {
+		m.now = now
+	}
+}
+
+// NewManager creates a manager whose sessions drive engine.
+func NewManager(engine Engine, opts ...ManagerOption) *Manager {
+	m := &Manager{
+		engine:      engine,
+		ttl:         DefaultSessionTTL,
+		maxSessions: DefaultMaxSessions,
+		now:         time.Now,
+		sessions:    make(map[string]*managed),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Create starts a debug session. An empty sessionID gets a generated one ("debug-1", ...).
+func (m *Manager) Create(ctx context.Context, sessionID string, initialContext map[string]any) (*Session, error) {
+	m.mu.Lock()
+	m.expire()
+	if sessionID == "" {
+		m.seq++
+		sessionID = fmt.Sprintf("debug-%d", m.seq)
+	}
+	if _, ok := m.sessions[sessionID]; ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrSessionExists, sessionID)
+	}
+	m.makeRoom()
+	m.sessions[sessionID] = &managed{used: m.now()} // Reserved while starting
+	m.mu.Unlock()
+
+	s, err := Start(ctx, m.engine, sessionID, initialContext, m.opts...)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		delete(m.sessions, sessionID)
+		return nil, err
+	}
+	m.sessions[sessionID] = &managed{session: s, used: m.now()}
+	return s, nil
+}
+
+// Get returns a session by ID and marks it as used.
+func (m *Manager) Get(sessionID string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	entry := m.sessions[sessionID]
+	if entry == nil || entry.session == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	entry.used = m.now()
+	return entry.session, nil
+}
+
+// Exec runs a command on a session.
+func (m *Manager) Exec(ctx context.Context, sessionID string, cmd Command) (*Reply, error) {
+	s, err := m.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Exec(ctx, cmd)
+}
+
+// Delete ends a session.
+func (m *Manager) Delete(sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry := m.sessions[sessionID]; entry == nil || entry.session == nil {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	delete(m.sessions, sessionID)
+	return nil
+}
+
+// expire drops the sessions idle for longer than the TTL. Callers hold m.mu.
+func (m *Manager) expire() {
+	if m.ttl <= 0 {
+		return
+	}
+	now := m.now()
+	for id, entry := range m.sessions {
+		if entry.session != nil && now.Sub(entry.used) > m.ttl {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+// makeRoom drops the least recently used sessions until one more fits under the limit.
+// Sessions still starting are kept. Callers hold m.mu.
+func (m *Manager) makeRoom() {
+	if m.maxSessions <= 0 {
+		return
+	}
+	for len(m.sessions) >= m.maxSessions {
+		oldest := ""
+		for id, entry := range m.sessions {
+			if entry.session != nil && (oldest == "" || entry.used.Before(m.sessions[oldest].used)) {
+				oldest = id
+			}
+		}
+		if oldest == "" {
+			return
+		}
+		delete(m.sessions, oldest)
+	}
+}
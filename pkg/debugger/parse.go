@@ -0,0 +1,146 @@
+package debugger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// Help describes the text form of the commands, as read by Parse.
+const Help = `Moving:
+  step, s                         advance one engine step (a tool call is a step of its own)
+  next, n                         advance to the next node that calls no tools, stepping over tool calls
+  continue, c                     run until a breakpoint, an input prompt or the end
+  input, i <text>                 answer the prompt and stop after the step
+  signal <name>                   send a signal (interrupt, timeout...) and stop after the step
+  restart                         start over from the entry node, keeping the breakpoints
+Breakpoints:
+  break, b <node> [if <cond>]     stop when the session enters the node
+  break tool <name> [if <cond>]   stop before the tool runs
+  break if <cond>                 stop when the condition becomes true (for the last input)
+  delete, d <id>                  remove a breakpoint
+  breakpoints, bl                 list the breakpoints
+Inspecting:
+  where, w                        show the current stop
+  state                           show the full state as JSON
+  get, p [key]                    show the context, or a key (a.b for nested keys, sys.x for the system context)
+  set <key> <value>               set a key (the value is JSON, or text when it is not valid JSON)
+  unset <key>                     remove a key
+  eval <condition>                evaluate a condition against the last input
+  template, t <text>              render a template against the current state
+Tools:
+  inject [tool <name>] <json>     answer the pending call with a result instead of running the tool
+  inject [tool <name>] error <message>
+  inject [tool <name>] denied [message]
+Other:
+  help, h                         show this help
+  quit, q                         leave the debugger`
+
+// Parse reads the text form of a command (see Help). Blank lines and "help"/"quit"
+// are the caller's to handle; they are reported as invalid commands.
+func Parse(line string) (Command, error) {
+	word, rest := cut(line)
+	switch word {
+	case "step", "s":
+		return Command{Op: OpStep}, nil
+	case "next", "n":
+		return Command{Op: OpNext}, nil
+	case "continue", "c":
+		return Command{Op: OpContinue}, nil
+	case "input", "i":
+		return Command{Op: OpInput, Input: rest}, nil
+	case "signal":
+		return Command{Op: OpSignal, Signal: rest}, nil
+	case "restart":
+		return Command{Op: OpRestart}, nil
+	case "where", "w":
+		return Command{Op: OpStatus}, nil
+	case "state":
+		return Command{Op: OpState}, nil
+	case "break", "b":
+		return parseBreak(rest)
+	case "delete", "d":
+		id, err := strconv.Atoi(strings.TrimPrefix(rest, "#"))
+		if err != nil {
+			return Command{}, fmt.Errorf("%w: delete needs a breakpoint number", ErrInvalidCommand)
+		}
+		return Command{Op: OpDelete, ID: id}, nil
+	case "breakpoints", "bl":
+		return Command{Op: OpBreakpoints}, nil
+	case "get", "p":
+		return Command{Op: OpGet, Key: rest}, nil
+	case "set":
+		key, value := cut(rest)
+		if key == "" || value == "" {
+			return Command{}, fmt.Errorf("%w: usage: set <key> <value>", ErrInvalidCommand)
+		}
+		return Command{Op: OpSet, Key: key, Value: parseValue(value)}, nil
+	case "unset":
+		return Command{Op: OpUnset, Key: rest}, nil
+	case "eval":
+		return Command{Op: OpEval, Expr: rest}, nil
+	case "template", "t":
+		return Command{Op: OpTemplate, Expr: rest}, nil
+	case "inject":
+		return parseInject(rest)
+	}
+	return Command{}, fmt.Errorf("%w: unknown command %q (type 'help')", ErrInvalidCommand, word)
+}
+
+func parseBreak(args string) (Command, error) {
+	bp := &Breakpoint{}
+	where, cond, hasCond := strings.Cut(args, "if ")
+	if hasCond && strings.TrimSpace(where) != "" && !strings.HasSuffix(where, " ") {
+		where, cond, hasCond = args, "", false // "if " inside a node name
+	}
+	if hasCond {
+		bp.Condition = strings.TrimSpace(cond)
+	}
+	word, rest := cut(where)
+	switch {
+	case word == "tool":
+		bp.Tool = rest
+	case rest != "":
+		return Command{}, fmt.Errorf("%w: usage: break <node> | break tool <name> | break if <condition>", ErrInvalidCommand)
+	default:
+		bp.Node = word
+	}
+	return Command{Op: OpBreak, Breakpoint: bp}, nil
+}
+
+func parseInject(args string) (Command, error) {
+	cmd := Command{Op: OpInject}
+	if word, rest := cut(args); word == "tool" {
+		cmd.Tool, args = cut(rest)
+	}
+	word, rest := cut(args)
+	switch word {
+	case "":
+		return Command{}, fmt.Errorf("%w: usage: inject [tool <name>] <json> | error <message> | denied [message]", ErrInvalidCommand)
+	case "error":
+		cmd.Result = &domain.ToolResult{IsError: true, Error: rest}
+	case "denied":
+		cmd.Result = &domain.ToolResult{IsDenied: true, Error: rest}
+	default:
+		cmd.Result = &domain.ToolResult{Result: parseValue(args)}
+	}
+	return cmd, nil
+}
+
+// cut splits the first word from the rest of the line.
+func cut(line string) (string, string) {
+	word, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+	return word, strings.TrimSpace(rest)
+}
+
+// parseValue reads JSON, falling back to the text itself.
+func parseValue(text string) any {
+	var v any
+	if err := json.Unmarshal([]byte(text), &v); err != nil {
+		return text
+	}
+	return v
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// AsyncCall records a tool call that the Host accepted for background execution.
+// The session stays in WaitingForTool until the result is delivered through a
+// completion API (e.g. session.Manager.CompleteTool), matched by ID and IdempotencyKey.
+type AsyncCall struct {
+	ID             string     `json:"id"`
+	IdempotencyKey string     `json:"idempotency_key"`
+	Handle         string     `json:"handle,omitempty"` // Host-side reference (job ID, ticket, ...)
+	AcceptedAt     time.Time  `json:"accepted_at"`
+	Deadline       *time.Time `json:"deadline,omitempty"` // Derived from the node timeout, if any
+}
+
+// Expired reports whether the call's deadline has passed at the given time.
+func (c AsyncCall) Expired(now time.Time) bool {
+	return c.Deadline != nil && now.After(*c.Deadline)
+}
+
+// AsyncCall returns the accepted call with the given ID, if any.
+func (s *State) AsyncCall(callID string) (AsyncCall, bool) {
+	for _, call := range s.AsyncCalls {
+		if call.ID == callID {
+			return call, true
+		}
+	}
+	return AsyncCall{}, false
+}
+
+// AwaitingAsync reports whether every pending call has been accepted for background
+// execution, meaning there is nothing left for the Host to run right now.
+func (s *State) AwaitingAsync() bool {
+	if s.Status != StatusWaitingForTool && s.Status != StatusRollingBack {
+		return false
+	}
+	if len(s.AsyncCalls) == 0 {
+		return false
+	}
+	if s.PendingToolCall != "" {
+		_, ok := s.AsyncCall(s.PendingToolCall)
+		return ok
+	}
+	for _, id := range s.PendingToolCalls {
+		if _, ok := s.AsyncCall(id); !ok {
+			return false
+		}
+	}
+	return len(s.PendingToolCalls) > 0
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "fmt"
+
+// Diagnostic is a problem found in an authored file, pinned to its position
+// (e.g. `start.md:12: unknown field "on_eror"; did you mean "on_error"?`).
+// Several diagnostics are reported together with errors.Join.
+type Diagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface using the compiler-style "file:line: message" form.
+func (d *Diagnostic) Error() string {
+	switch {
+	case d.File == "":
+		return d.Message
+	case d.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+	default:
+		return fmt.Sprintf("%s: %s", d.File, d.Message)
+	}
+}
+
+// Diagnostics collects every Diagnostic wrapped or joined in err (nil when there are none).
+func Diagnostics(err error) []*Diagnostic {
+	switch e := err.(type) {
+	case *Diagnostic:
+		return []*Diagnostic{e}
+	case interface{ Unwrap() []error }:
+		var out []*Diagnostic
+		for _, inner := range e.Unwrap() {
+			out = append(out, Diagnostics(inner)...)
+		}
+		return out
+	case interface{ Unwrap() error }:
+		return Diagnostics(e.Unwrap())
+	}
+	return nil
+}
@@ -5,5 +5,36 @@ import "errors"
 // ErrUnhandledSignal is returned when a signal is received but no handler is defined for it.
 var ErrUnhandledSignal = errors.New("unhandled signal")
 
+// ErrNodeNotFound is returned (wrapped) by graph loaders when a node ID does not exist,
+// so composite loaders can tell a missing node from a broken one.
+var ErrNodeNotFound = errors.New("node not found")
+
+// ErrWatchUnsupported is returned (wrapped) by Watch when the graph source is static
+// (e.g. an embedded flow), as opposed to a watcher that failed to start.
+var ErrWatchUnsupported = errors.New("loader does not support watching")
+
 // ErrSessionNotFound is returned when a session ID cannot be found in the store.
 var ErrSessionNotFound = errors.New("session not found")
+
+// ErrAsyncCallNotFound is returned when a completion targets a call that is not awaiting
+// a background result (unknown, already completed or cancelled).
+var ErrAsyncCallNotFound = errors.New("no async tool call awaiting this result")
+
+// ErrIdempotencyMismatch is returned when a completion carries the wrong idempotency key.
+var ErrIdempotencyMismatch = errors.New("idempotency key does not match the pending call")
+
+// ErrAsyncDeadlineExceeded is returned when a completion arrives after the call's deadline.
+var ErrAsyncDeadlineExceeded = errors.New("async tool call deadline exceeded")
+
+// ErrAsyncCompletionRequired is returned when a result for a call accepted for background
+// execution arrives as plain input instead of through async completion (idempotency key and
+// deadline checks).
+var ErrAsyncCompletionRequired = errors.New("result must be delivered through async completion")
+
+// ErrRevisionMismatch is returned when a session pinned to a graph revision is resumed
+// by a host serving another revision.
+var ErrRevisionMismatch = errors.New("session is pinned to another revision")
+
+// ErrNoTransition is returned when a node with strict_transitions gets input that
+// none of its transitions handles.
+var ErrNoTransition = errors.New("no transition matches the input")
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -13,6 +14,7 @@ const (
 	EventNodeLeave  EventType = "node_leave"
 	EventToolCall   EventType = "tool_call"
 	EventToolReturn EventType = "tool_return"
+	EventTransition EventType = "transition"
 )
 
 // EventBase contains common fields for all events.
@@ -37,6 +39,54 @@ type ToolEvent struct {
 	Input    any    `json:"input,omitempty"`
 	Output   any    `json:"output,omitempty"`
 	IsError  bool   `json:"is_error,omitempty"`
+	// Usage is the metering metadata reported by the tool (tool_return only).
+	Usage *ToolUsage `json:"usage,omitempty"`
+}
+
+// TransitionKind tells which rule of a node moved the flow to the next one.
+type TransitionKind string
+
+const (
+	TransitionNext          TransitionKind = "transition"        // An entry of `transitions` (see Index)
+	TransitionDenied        TransitionKind = "on_denied"         // A refusal or a denied tool call
+	TransitionError         TransitionKind = "on_error"          // A failed tool call
+	TransitionErrorNode     TransitionKind = "error_node"        // A failure handled by the global error node
+	TransitionSignal        TransitionKind = "on_signal"         // A signal handled by the node
+	TransitionSignalDefault TransitionKind = "on_signal_default" // A signal handled by the entry node's defaults
+	TransitionUnclear       TransitionKind = "on_unclear"        // A route node that could not classify the input
+)
+
+// TransitionEvent represents the flow moving along an edge of the graph.
+// It is emitted between the OnNodeLeave of From and the OnNodeEnter of To.
+type TransitionEvent struct {
+	EventBase
+	From string         `json:"from"`
+	To   string         `json:"to"`
+	Kind TransitionKind `json:"kind"`
+	// Index is the position of the transition in the node's `transitions` (-1 for other kinds).
+	Index int `json:"index"`
+	// Signal is the signal name, for signal transitions.
+	Signal string `json:"signal,omitempty"`
+}
+
+// EdgeKey identifies an edge of the graph: a transition by its position, a signal handler
+// by its signal, any other handler by its kind. Handlers of on_signal_default have no
+// origin, since they apply to every node.
+func EdgeKey(from string, kind TransitionKind, index int, signal string) string {
+	switch kind {
+	case TransitionNext:
+		return fmt.Sprintf("%s#%d", from, index)
+	case TransitionSignal:
+		return fmt.Sprintf("%s!%s", from, signal)
+	case TransitionSignalDefault:
+		return "*!" + signal
+	}
+	return fmt.Sprintf("%s@%s", from, kind)
+}
+
+// Key returns the EdgeKey of the transition.
+func (e *TransitionEvent) Key() string {
+	return EdgeKey(e.From, e.Kind, e.Index, e.Signal)
 }
 
 // LifecycleHooks defines callbacks for engine observability.
@@ -45,4 +95,37 @@ type LifecycleHooks struct {
 	OnNodeLeave  func(context.Context, *NodeEvent)
 	OnToolCall   func(context.Context, *ToolEvent)
 	OnToolReturn func(context.Context, *ToolEvent)
+	OnTransition func(context.Context, *TransitionEvent)
+}
+
+// MergeHooks combines several hook sets into one. Each callback runs the non-nil callbacks
+// of the sets in order; a callback is left nil when no set defines it.
+func MergeHooks(sets ...LifecycleHooks) LifecycleHooks {
+	var merged LifecycleHooks
+	merged.OnNodeEnter = mergeHook(sets, func(h LifecycleHooks) func(context.Context, *NodeEvent) { return h.OnNodeEnter })
+	merged.OnNodeLeave = mergeHook(sets, func(h LifecycleHooks) func(context.Context, *NodeEvent) { return h.OnNodeLeave })
+	merged.OnToolCall = mergeHook(sets, func(h LifecycleHooks) func(context.Context, *ToolEvent) { return h.OnToolCall })
+	merged.OnToolReturn = mergeHook(sets, func(h LifecycleHooks) func(context.Context, *ToolEvent) { return h.OnToolReturn })
+	merged.OnTransition = mergeHook(sets, func(h LifecycleHooks) func(context.Context, *TransitionEvent) { return h.OnTransition })
+	return merged
+}
+
+func mergeHook[E any](sets []LifecycleHooks, pick func(LifecycleHooks) func(context.Context, *E)) func(context.Context, *E) {
+	var fns []func(context.Context, *E)
+	for _, h := range sets {
+		if fn := pick(h); fn != nil {
+			fns = append(fns, fn)
+		}
+	}
+	switch len(fns) {
+	case 0:
+		return nil
+	case 1:
+		return fns[0]
+	}
+	return func(ctx context.Context, evt *E) {
+		for _, fn := range fns {
+			fn(ctx, evt)
+		}
+	}
 }
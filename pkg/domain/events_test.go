@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestMergeHooks(t *testing.T) {
+	var calls []string
+	first := LifecycleHooks{
+		OnNodeEnter: func(_ context.Context, evt *NodeEvent) { calls = append(calls, "first:"+evt.NodeID) },
+	}
+	second := LifecycleHooks{
+		OnNodeEnter:  func(_ context.Context, evt *NodeEvent) { calls = append(calls, "second:"+evt.NodeID) },
+		OnToolReturn: func(_ context.Context, evt *ToolEvent) { calls = append(calls, "tool:"+evt.ToolName) },
+	}
+
+	merged := MergeHooks(first, LifecycleHooks{}, second)
+	merged.OnNodeEnter(context.Background(), &NodeEvent{NodeID: "start"})
+	merged.OnToolReturn(context.Background(), &ToolEvent{ToolName: "charge"})
+
+	if want := []string{"first:start", "second:start", "tool:charge"}; !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if merged.OnNodeLeave != nil || merged.OnToolCall != nil || merged.OnTransition != nil {
+		t.Error("callbacks no set defines must stay nil")
+	}
+}
@@ -0,0 +1,45 @@
+package domain
+
+// NodeTypeRoute classifies free-text input onto the node's transitions (see ports.IntentClassifier).
+const NodeTypeRoute = "route"
+
+// SysKeyIntent holds the last classification result: {{ .sys.intent.confidence }}.
+const SysKeyIntent = "intent"
+
+// DefaultMinConfidence is the confidence below which a route node treats input as unclear.
+const DefaultMinConfidence = 0.6
+
+// Intent is a candidate outcome of a route node, derived from one of its transitions.
+type Intent struct {
+	// Target is the node ID the transition leads to.
+	Target string `json:"target"`
+	// Label is the canonical answer (the option text).
+	Label string `json:"label,omitempty"`
+	// Index is the 1-based position of the option, as presented to the user.
+	Index int `json:"index"`
+	// Synonyms are alternative answers with the same meaning.
+	Synonyms []string `json:"synonyms,omitempty"`
+	// Keywords are words that, when present in the input, hint at this intent.
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// IntentMatch is the result of classifying an input.
+type IntentMatch struct {
+	// Target is the chosen node ID (empty if nothing matched).
+	Target string `json:"target,omitempty"`
+	// Confidence ranges from 0 (no idea) to 1 (certain).
+	Confidence float64 `json:"confidence"`
+	// Strategy names the classifier that produced the match (e.g. "index", "fuzzy", "llm").
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// ToMap converts the match into a template-friendly map for SystemContext.
+func (m IntentMatch) ToMap(input, label string) map[string]any {
+	return map[string]any{
+		"input":      input,
+		"target":     m.Target,
+		"label":      label,
+		"confidence": m.Confidence,
+		"strategy":   m.Strategy,
+	}
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "fmt"
+
+// NodeTypeFlow is a macro node: a compact script that the compiler lowers into atomic nodes.
+const NodeTypeFlow = "flow"
+
+// SourceRef points back to where a node was authored: a file position and, for
+// generated nodes, the macro line that produced them (a source map entry).
+type SourceRef struct {
+	// File is the file declaring the node, relative to the project (empty for in-memory graphs).
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// Macro is the ID of the `type: flow` node that declared the step.
+	Macro string `json:"macro,omitempty" yaml:"macro,omitempty"`
+	// Line is 1-based: the line within the file, or the line of the step within the script.
+	Line int `json:"line,omitempty" yaml:"line,omitempty"`
+	// Step is the original text of the step.
+	Step string `json:"step,omitempty" yaml:"step,omitempty"`
+}
+
+// String renders the reference for error messages,
+// e.g. "start.md:1" or "checkout.md: flow checkout line 3 (ask name: Who?)".
+func (s SourceRef) String() string {
+	if s.Macro == "" {
+		if s.Line > 0 {
+			return fmt.Sprintf("%s:%d", s.File, s.Line)
+		}
+		return s.File
+	}
+	ref := fmt.Sprintf("flow %s line %d (%s)", s.Macro, s.Line, s.Step)
+	if s.File != "" {
+		ref = s.File + ": " + ref
+	}
+	return ref
+}
+
+// TraceStep is an entry of State.History with the source that declared the node,
+// so generated IDs ("checkout#ask-name") map back to their macro line.
+type TraceStep struct {
+	NodeID string     `json:"node_id"`
+	Source *SourceRef `json:"source,omitempty"`
+}
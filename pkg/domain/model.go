@@ -0,0 +1,40 @@
+package domain
+
+import "github.com/aretw0/trellis/pkg/schema"
+
+// NodeTypeLLM delegates the node's decision to a language model (see ports.ModelProvider).
+const NodeTypeLLM = "llm"
+
+// SysKeyLLM holds the last model answer (content, route, tool_calls): {{ .sys.llm.content }}.
+const SysKeyLLM = "llm"
+
+// ModelRequest is what the Engine sends to a ModelProvider when executing an llm node.
+type ModelRequest struct {
+	// Model is the provider-specific model name (empty means provider default).
+	Model string `json:"model,omitempty"`
+	// System is the (interpolated) system prompt.
+	System string `json:"system,omitempty"`
+	// Prompt is the (interpolated) user prompt.
+	Prompt string `json:"prompt"`
+	// Tools are exposed to the model as function definitions.
+	Tools []Tool `json:"tools,omitempty"`
+	// Schema, when set, asks for a JSON object with these fields (structured output).
+	Schema schema.Schema `json:"schema,omitempty"`
+	// Routes lists the node IDs the model may choose from (the node's declared transitions).
+	Routes []string `json:"routes,omitempty"`
+}
+
+// ModelResponse is the answer of a ModelProvider.
+type ModelResponse struct {
+	// Content is the raw text answer.
+	Content string `json:"content"`
+	// Output is the structured answer, decoded by the provider when a Schema was requested.
+	// If nil, the Engine decodes Content as a JSON object.
+	Output map[string]any `json:"output,omitempty"`
+	// Route is the next node chosen by the model, if any. It must be one of the request Routes.
+	Route string `json:"route,omitempty"`
+	// ToolCalls are the functions the model asked to invoke.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// Usage reports what the completion consumed (recorded like any tool call).
+	Usage *ToolUsage `json:"usage,omitempty"`
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "github.com/aretw0/trellis/pkg/schema"
+import (
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/schema"
+)
 
 // NodeType constants define the control flow behavior.
 const (
@@ -26,8 +30,16 @@ const (
 	DefaultStartNodeID = "start"
 	// DefaultErrorNodeID is the standard fallback for tool errors and denials.
 	DefaultErrorNodeID = "error"
+	// RollbackTarget is the reserved on_error and transition value that starts SAGA
+	// compensation instead of jumping to a node.
+	RollbackTarget = "rollback"
 )
 
+// IsRollback reports whether target is the rollback keyword (case-insensitive).
+func IsRollback(target string) bool {
+	return strings.EqualFold(target, RollbackTarget)
+}
+
 // Node represents a logical unit in the graph.
 // It can contain text content (for Wiki-style) or logic instructions (for Logic-style).
 type Node struct {
@@ -55,9 +67,17 @@ type Node struct {
 	// Metadata allows for extensible key-value pairs.
 	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
 
+	// Source points to the line that declared the node: the node file for loaders that know
+	// it, or the originating step for nodes generated from a macro (`type: flow`).
+	Source *SourceRef `json:"source,omitempty" yaml:"source,omitempty"`
+
 	// Transitions defines the possible paths from this node.
 	Transitions []Transition `json:"transitions" yaml:"transitions"`
 
+	// StrictTransitions makes navigation fail with ErrNoTransition when no transition
+	// matches the input, instead of staying on the node.
+	StrictTransitions bool `json:"strict_transitions,omitempty" yaml:"strict_transitions,omitempty"`
+
 	// OnError defines the node ID to transition to if a Tool returns an error.
 	OnError string `json:"on_error,omitempty" yaml:"on_error,omitempty"`
 
@@ -79,6 +99,13 @@ type Node struct {
 	// Do defines the primary action to execute.
 	Do *ToolCall `json:"do,omitempty" yaml:"do,omitempty"`
 
+	// Batch defines multiple tool calls fired together (authored as a `do:` list).
+	// It is mutually exclusive with Do. Results are collected before the node resolves.
+	Batch []BatchCall `json:"batch,omitempty" yaml:"batch,omitempty"`
+
+	// BatchPolicy controls partial failure handling for Batch (see BatchPolicy* constants).
+	BatchPolicy string `json:"batch_policy,omitempty" yaml:"batch_policy,omitempty"`
+
 	// Tools defined within this node (e.g. for LLM context)
 	Tools []Tool `json:"tools,omitempty" yaml:"tools,omitempty"`
 
@@ -92,6 +119,66 @@ type Node struct {
 
 	// Timeout defines the maximum duration (e.g. "30s") to wait for input.
 	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// Route Configuration (Optional, used if Type == "route")
+	// OnUnclear is the clarification node used when no intent reaches MinConfidence.
+	// Without it, the node stays put and asks again.
+	OnUnclear string `json:"on_unclear,omitempty" yaml:"on_unclear,omitempty"`
+	// MinConfidence overrides DefaultMinConfidence.
+	MinConfidence float64 `json:"min_confidence,omitempty" yaml:"min_confidence,omitempty"`
+
+	// LLM Configuration (Optional, used if Type == "llm")
+	// Prompt is the user prompt template. If empty, Content is used as the prompt (and not displayed).
+	Prompt string `json:"prompt,omitempty" yaml:"prompt,omitempty"`
+	// System is the system prompt template.
+	System string `json:"system,omitempty" yaml:"system,omitempty"`
+	// Model selects the provider model (empty means provider default).
+	Model string `json:"model,omitempty" yaml:"model,omitempty"`
+	// OutputSchema requests structured output; the decoded object is validated before SaveTo.
+	OutputSchema schema.Schema `json:"output_schema,omitempty" yaml:"output_schema,omitempty"`
+
+	// Budget declares flow-level spending limits for tool calls.
+	// Only honored on the entry node, where it applies to the whole session.
+	Budget *Budget `json:"budget,omitempty" yaml:"budget,omitempty"`
+}
+
+// Batch failure policies.
+const (
+	// BatchPolicyAll fails the node if any call fails (default). Routing follows on_denied/on_error.
+	BatchPolicyAll = "all"
+	// BatchPolicyContinue tolerates partial failure and proceeds through the transitions.
+	BatchPolicyContinue = "continue"
+	// BatchPolicyRollback compensates the calls that succeeded (per-call undo) and rolls the flow back.
+	BatchPolicyRollback = "rollback"
+)
+
+// BatchCall is one entry of a batch `do:` list, with its own optional compensation.
+type BatchCall struct {
+	ToolCall `yaml:",inline" mapstructure:",squash"`
+	// Undo compensates this specific call during a SAGA rollback.
+	Undo *ToolCall `json:"undo,omitempty" yaml:"undo,omitempty" mapstructure:"undo"`
+}
+
+// HasTools reports whether the node fires any side-effect (single or batch).
+func (n *Node) HasTools() bool {
+	return n.Do != nil || len(n.Batch) > 0
+}
+
+// BatchUndoCalls returns the compensations of the batch entries, optionally filtered by call ID.
+// Undo IDs default to "<call_id>_undo" so they stay unique within the node.
+func (n *Node) BatchUndoCalls(include func(callID string) bool) []ToolCall {
+	undos := make([]ToolCall, 0, len(n.Batch))
+	for _, bc := range n.Batch {
+		if bc.Undo == nil || (include != nil && !include(bc.ID)) {
+			continue
+		}
+		undo := *bc.Undo
+		if undo.ID == "" {
+			undo.ID = bc.ID + "_undo"
+		}
+		undos = append(undos, undo)
+	}
+	return undos
 }
 
 // FormatItem represents a single piece of content within a "format" node.
@@ -0,0 +1,18 @@
+package domain
+
+import "testing"
+
+func TestIsRollback(t *testing.T) {
+	for target, want := range map[string]bool{
+		"rollback":      true,
+		"Rollback":      true,
+		"ROLLBACK":      true,
+		"":              false,
+		"error":         false,
+		"rollback_step": false,
+	} {
+		if got := IsRollback(target); got != want {
+			t.Errorf("IsRollback(%q) = %v, want %v", target, got, want)
+		}
+	}
+}
@@ -1,5 +1,12 @@
 package domain
 
+// SysKeyRevision holds the graph revision a session started on (e.g. a git commit).
+// Sessions pinned this way keep executing that revision: {{ .sys.revision }}.
+const SysKeyRevision = "revision"
+
+// SysKeyBundle holds the hash of the bundle a session started from, for auditing: {{ .sys.bundle }}.
+const SysKeyBundle = "bundle"
+
 // ExecutionStatus defines the current mode of the engine mechanics.
 type ExecutionStatus string
 
@@ -24,6 +31,15 @@ type State struct {
 	// PendingToolCall holds the ID of the tool call we are waiting for (if Status == WaitingForTool).
 	PendingToolCall string `json:"pending_tool_call,omitempty"`
 
+	// PendingToolCalls holds the IDs of batch calls still awaiting results.
+	PendingToolCalls []string `json:"pending_tool_calls,omitempty"`
+
+	// BatchResults collects the results already received for the current batch.
+	BatchResults []ToolResult `json:"batch_results,omitempty"`
+
+	// AsyncCalls lists pending calls accepted by the Host for background completion.
+	AsyncCalls []AsyncCall `json:"async_calls,omitempty"`
+
 	// Context holds variable state for the session (User space).
 	Context map[string]any `json:"context"`
 
@@ -64,16 +80,61 @@ func (s *State) Snapshot() *State {
 	histCopy := make([]string, len(s.History))
 	copy(histCopy, s.History)
 
+	var pendingCopy []string
+	if len(s.PendingToolCalls) > 0 {
+		pendingCopy = make([]string, len(s.PendingToolCalls))
+		copy(pendingCopy, s.PendingToolCalls)
+	}
+	var resultsCopy []ToolResult
+	if len(s.BatchResults) > 0 {
+		resultsCopy = make([]ToolResult, len(s.BatchResults))
+		copy(resultsCopy, s.BatchResults)
+	}
+
+	var asyncCopy []AsyncCall
+	if len(s.AsyncCalls) > 0 {
+		asyncCopy = make([]AsyncCall, len(s.AsyncCalls))
+		copy(asyncCopy, s.AsyncCalls)
+	}
+
 	return &State{
-		SessionID:       s.SessionID,
-		CurrentNodeID:   s.CurrentNodeID,
-		Status:          s.Status,
-		PendingToolCall: s.PendingToolCall,
-		Context:         ctxCopy,
-		SystemContext:   sysCtxCopy,
-		History:         histCopy,
-		Terminated:      s.Terminated,
+		SessionID:        s.SessionID,
+		CurrentNodeID:    s.CurrentNodeID,
+		Status:           s.Status,
+		PendingToolCall:  s.PendingToolCall,
+		PendingToolCalls: pendingCopy,
+		BatchResults:     resultsCopy,
+		AsyncCalls:       asyncCopy,
+		Context:          ctxCopy,
+		SystemContext:    sysCtxCopy,
+		History:          histCopy,
+		Terminated:       s.Terminated,
+	}
+}
+
+// Revision returns the graph revision the session is pinned to ("" when unpinned).
+func (s *State) Revision() string {
+	revision, _ := s.SystemContext[SysKeyRevision].(string)
+	return revision
+}
+
+// BundleHash returns the hash of the bundle the session started from ("" when not bundled).
+func (s *State) BundleHash() string {
+	hash, _ := s.SystemContext[SysKeyBundle].(string)
+	return hash
+}
+
+// IsPending reports whether the given call ID is awaited (single or batch).
+func (s *State) IsPending(callID string) bool {
+	if s.PendingToolCall != "" && s.PendingToolCall == callID {
+		return true
+	}
+	for _, id := range s.PendingToolCalls {
+		if id == callID {
+			return true
+		}
 	}
+	return false
 }
 
 // NewState creates a clean state starting at a specific node.
@@ -17,6 +17,12 @@ type ToolResult struct {
 	IsError  bool   `json:"is_error,omitempty"`
 	IsDenied bool   `json:"is_denied,omitempty"`
 	Error    string `json:"error,omitempty"`
+	// Usage optionally reports what the call consumed (tokens, cost, units).
+	Usage *ToolUsage `json:"usage,omitempty"`
+	// Accepted marks a call the Host has started in the background: the real result
+	// will be delivered later. Handle is an optional Host-side reference for it.
+	Accepted bool   `json:"accepted,omitempty"`
+	Handle   string `json:"handle,omitempty"`
 }
 
 // Tool defines metadata about a tool available to the engine.
@@ -13,4 +13,11 @@ type Transition struct {
 	// for this transition to be valid. e.g., "user_age >= 18"
 	// If empty, it's considered an "always" transition (default).
 	Condition string `json:"condition,omitempty" yaml:"condition,omitempty"`
+
+	// Label is the display text of the option (also the canonical intent for route nodes).
+	Label string `json:"label,omitempty" yaml:"label,omitempty"`
+
+	// Synonyms and Keywords help route nodes classify free-text input onto this transition.
+	Synonyms []string `json:"synonyms,omitempty" yaml:"synonyms,omitempty"`
+	Keywords []string `json:"keywords,omitempty" yaml:"keywords,omitempty"`
 }
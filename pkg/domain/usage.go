@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// System context keys used by usage accounting.
+const (
+	// SysKeyUsage holds the accumulated UsageReport of a session.
+	SysKeyUsage = "usage"
+	// SysKeyBudget holds the flow-level Budget declared on the entry node.
+	SysKeyBudget = "budget"
+)
+
+// ToolUsage is optional metering metadata reported by the Host alongside a ToolResult.
+// It allows LLM/API-backed tools to declare what a call consumed.
+type ToolUsage struct {
+	Tokens int64   `json:"tokens,omitempty" yaml:"tokens,omitempty" mapstructure:"tokens"`
+	Cost   float64 `json:"cost,omitempty" yaml:"cost,omitempty" mapstructure:"cost"`
+	Units  float64 `json:"units,omitempty" yaml:"units,omitempty" mapstructure:"units"`
+}
+
+// UsageTotals accumulates usage over a number of calls.
+type UsageTotals struct {
+	Calls  int     `json:"calls"`
+	Tokens int64   `json:"tokens"`
+	Cost   float64 `json:"cost"`
+	Units  float64 `json:"units"`
+}
+
+// Add records a single call (with optional usage) into the totals.
+func (t UsageTotals) Add(u *ToolUsage) UsageTotals {
+	t.Calls++
+	if u != nil {
+		t.Tokens += u.Tokens
+		t.Cost += u.Cost
+		t.Units += u.Units
+	}
+	return t
+}
+
+// UsageReport is the per-session usage ledger stored in SystemContext["usage"].
+type UsageReport struct {
+	UsageTotals
+	// Tools breaks down the totals by tool name.
+	Tools map[string]UsageTotals `json:"tools,omitempty"`
+}
+
+// Record returns a new report with the call accounted for both globally and per tool.
+// The receiver is never mutated, keeping previous state snapshots intact.
+func (r UsageReport) Record(toolName string, u *ToolUsage) UsageReport {
+	next := UsageReport{
+		UsageTotals: r.UsageTotals.Add(u),
+		Tools:       make(map[string]UsageTotals, len(r.Tools)+1),
+	}
+	for k, v := range r.Tools {
+		next.Tools[k] = v
+	}
+	next.Tools[toolName] = r.Tools[toolName].Add(u)
+	return next
+}
+
+// ToMap converts the report into its generic representation, matching what
+// a persisted state looks like after a JSON round-trip (so templates see the same shape).
+func (r UsageReport) ToMap() map[string]any {
+	var out map[string]any
+	_ = decodeVia(r, &out)
+	return out
+}
+
+// UsageFromState reads the usage ledger from the state's SystemContext.
+// It accepts both the in-memory and the persisted (JSON-decoded) representations.
+func UsageFromState(s *State) UsageReport {
+	var report UsageReport
+	if s == nil || s.SystemContext == nil {
+		return report
+	}
+	if raw, ok := s.SystemContext[SysKeyUsage]; ok && raw != nil {
+		_ = decodeVia(raw, &report)
+	}
+	return report
+}
+
+// Budget declares spending limits. Zero fields mean "unlimited".
+type Budget struct {
+	MaxTokens int64   `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty" mapstructure:"max_tokens"`
+	MaxCost   float64 `json:"max_cost,omitempty" yaml:"max_cost,omitempty" mapstructure:"max_cost"`
+	MaxUnits  float64 `json:"max_units,omitempty" yaml:"max_units,omitempty" mapstructure:"max_units"`
+	MaxCalls  int     `json:"max_calls,omitempty" yaml:"max_calls,omitempty" mapstructure:"max_calls"`
+}
+
+// IsZero reports whether the budget imposes no limits.
+func (b Budget) IsZero() bool {
+	return b == Budget{}
+}
+
+// Exhausted checks whether the consumed totals have reached any limit.
+// It returns a human-readable reason when the budget is exhausted.
+func (b Budget) Exhausted(t UsageTotals) (bool, string) {
+	switch {
+	case b.MaxCalls > 0 && t.Calls >= b.MaxCalls:
+		return true, fmt.Sprintf("tool call budget exhausted (%d/%d calls)", t.Calls, b.MaxCalls)
+	case b.MaxTokens > 0 && t.Tokens >= b.MaxTokens:
+		return true, fmt.Sprintf("token budget exhausted (%d/%d tokens)", t.Tokens, b.MaxTokens)
+	case b.MaxCost > 0 && t.Cost >= b.MaxCost:
+		return true, fmt.Sprintf("cost budget exhausted (%.4f/%.4f)", t.Cost, b.MaxCost)
+	case b.MaxUnits > 0 && t.Units >= b.MaxUnits:
+		return true, fmt.Sprintf("unit budget exhausted (%g/%g units)", t.Units, b.MaxUnits)
+	}
+	return false, ""
+}
+
+// Merge combines two budgets, keeping the tightest non-zero limit of each dimension.
+func (b Budget) Merge(o Budget) Budget {
+	return Budget{
+		MaxTokens: minLimit(b.MaxTokens, o.MaxTokens),
+		MaxCost:   minLimit(b.MaxCost, o.MaxCost),
+		MaxUnits:  minLimit(b.MaxUnits, o.MaxUnits),
+		MaxCalls:  minLimit(b.MaxCalls, o.MaxCalls),
+	}
+}
+
+func minLimit[T int | int64 | float64](a, b T) T {
+	if a <= 0 {
+		return b
+	}
+	if b <= 0 || a < b {
+		return a
+	}
+	return b
+}
+
+// ToMap converts the budget into its generic (JSON-compatible) representation.
+func (b Budget) ToMap() map[string]any {
+	var out map[string]any
+	_ = decodeVia(b, &out)
+	return out
+}
+
+// BudgetFromState reads the flow-level budget from the state's SystemContext.
+func BudgetFromState(s *State) Budget {
+	var b Budget
+	if s == nil || s.SystemContext == nil {
+		return b
+	}
+	if raw, ok := s.SystemContext[SysKeyBudget]; ok && raw != nil {
+		_ = decodeVia(raw, &b)
+	}
+	return b
+}
+
+// decodeVia converts between representations using JSON as the common format.
+func decodeVia(src any, dst any) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUsageReport_Record(t *testing.T) {
+	var r UsageReport
+	r = r.Record("llm", &ToolUsage{Tokens: 10, Cost: 0.5})
+	r2 := r.Record("llm", &ToolUsage{Tokens: 5, Cost: 0.25})
+	r2 = r2.Record("search", nil)
+
+	if r.Calls != 1 || r.Tools["llm"].Tokens != 10 {
+		t.Errorf("Record must not mutate the receiver: %+v", r)
+	}
+	if r2.Calls != 3 || r2.Tokens != 15 || r2.Cost != 0.75 {
+		t.Errorf("unexpected totals: %+v", r2.UsageTotals)
+	}
+	if r2.Tools["llm"].Calls != 2 || r2.Tools["search"].Calls != 1 {
+		t.Errorf("unexpected per-tool totals: %+v", r2.Tools)
+	}
+}
+
+func TestUsageFromState_RoundTrip(t *testing.T) {
+	s := NewState("s1", "start")
+	s.SystemContext[SysKeyUsage] = UsageReport{}.Record("llm", &ToolUsage{Tokens: 42, Cost: 1.5}).ToMap()
+
+	// Simulate persistence (JSON round-trip)
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded State
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	report := UsageFromState(&loaded)
+	if report.Tokens != 42 || report.Cost != 1.5 || report.Tools["llm"].Calls != 1 {
+		t.Errorf("unexpected report after round-trip: %+v", report)
+	}
+}
+
+func TestBudget_Exhausted(t *testing.T) {
+	b := Budget{MaxCost: 1, MaxCalls: 3}
+	if exhausted, _ := b.Exhausted(UsageTotals{Calls: 2, Cost: 0.9}); exhausted {
+		t.Error("budget should not be exhausted yet")
+	}
+	if exhausted, reason := b.Exhausted(UsageTotals{Calls: 2, Cost: 1}); !exhausted || reason == "" {
+		t.Error("cost budget should be exhausted")
+	}
+	if exhausted, _ := b.Exhausted(UsageTotals{Calls: 3}); !exhausted {
+		t.Error("call budget should be exhausted")
+	}
+	if exhausted, _ := (Budget{}).Exhausted(UsageTotals{Calls: 1000, Cost: 1e6}); exhausted {
+		t.Error("zero budget means unlimited")
+	}
+}
+
+func TestBudget_Merge(t *testing.T) {
+	got := Budget{MaxCost: 2, MaxTokens: 100}.Merge(Budget{MaxCost: 1, MaxCalls: 5})
+	want := Budget{MaxCost: 1, MaxTokens: 100, MaxCalls: 5}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+package domain
+
+// RenderView is what a render asks of the host, read from the actions Render returned.
+// Hosts driving a session (runner, scenarios, fuzzer, debugger, replay) share it with
+// FlowEnded and Entered so they agree on how a flow moves and ends.
+type RenderView struct {
+	Content    []string      // RENDER_CONTENT payloads, in order
+	Prompt     *InputRequest // REQUEST_INPUT payload, when typed
+	NeedsInput bool          // Whether the render requests input
+	ToolCalls  []ToolCall    // CALL_TOOL payloads, pending or not
+}
+
+// NewRenderView sorts the actions of a render.
+func NewRenderView(actions []ActionRequest) RenderView {
+	var v RenderView
+	for _, act := range actions {
+		switch act.Type {
+		case ActionRenderContent:
+			if text, ok := act.Payload.(string); ok {
+				v.Content = append(v.Content, text)
+			}
+		case ActionRequestInput:
+			v.NeedsInput = true
+			if req, ok := act.Payload.(InputRequest); ok {
+				v.Prompt = &req
+			}
+		case ActionCallTool:
+			if call, ok := act.Payload.(ToolCall); ok {
+				v.ToolCalls = append(v.ToolCalls, call)
+			}
+		}
+	}
+	return v
+}
+
+// Pending returns the tool calls of the view that state still awaits.
+func (v RenderView) Pending(state *State) []ToolCall {
+	var calls []ToolCall
+	for _, call := range v.ToolCalls {
+		if state.IsPending(call.ID) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// FlowEnded applies the runner's end-of-flow rule to next, the state navigated to from prev:
+// the engine terminated the flow, or a terminal node that asks nothing stayed put.
+// An ended state is marked terminated.
+func FlowEnded(prev, next *State, terminal, needsInput bool) bool {
+	ended := next.Terminated || next.Status == StatusTerminated ||
+		(terminal && !needsInput && next.CurrentNodeID == prev.CurrentNodeID)
+	if ended && !next.Terminated {
+		next.Terminated = true
+		next.Status = StatusTerminated
+	}
+	return ended
+}
+
+// Entered returns the nodes entered between two states: the history entries a transition
+// added, or the new current node (e.g. a rollback step, which pops the history).
+func Entered(prev, next *State) []string {
+	if n := len(next.History) - len(prev.History); n > 0 {
+		return next.History[len(next.History)-n:]
+	}
+	if next.CurrentNodeID != prev.CurrentNodeID {
+		return []string{next.CurrentNodeID}
+	}
+	return nil
+}
+
+// ToolInput returns the results to navigate with: the whole batch when state awaits
+// several calls, the single result otherwise.
+func ToolInput(state *State, results []ToolResult) any {
+	if len(state.PendingToolCalls) > 0 {
+		return results
+	}
+	return results[0]
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewRenderView(t *testing.T) {
+	v := NewRenderView([]ActionRequest{
+		{Type: ActionRenderContent, Payload: "Hi"},
+		{Type: ActionCallTool, Payload: ToolCall{ID: "a", Name: "charge"}},
+		{Type: ActionCallTool, Payload: ToolCall{ID: "b", Name: "notify"}},
+		{Type: ActionRequestInput, Payload: InputRequest{Type: InputText}},
+	})
+	if !slices.Equal(v.Content, []string{"Hi"}) || !v.NeedsInput || v.Prompt == nil || len(v.ToolCalls) != 2 {
+		t.Fatalf("unexpected view: %+v", v)
+	}
+	state := &State{PendingToolCalls: []string{"b"}}
+	if got := v.Pending(state); len(got) != 1 || got[0].ID != "b" {
+		t.Errorf("Pending() = %+v, want the call b", got)
+	}
+}
+
+func TestFlowEnded(t *testing.T) {
+	tests := []struct {
+		name       string
+		next       State
+		terminal   bool
+		needsInput bool
+		want       bool
+	}{
+		{"moved on", State{CurrentNodeID: "next"}, false, false, false},
+		{"terminated by the engine", State{CurrentNodeID: "next", Status: StatusTerminated, Terminated: true}, false, false, true},
+		{"terminal node stays put", State{CurrentNodeID: "end"}, true, false, true},
+		{"terminal node asks for input", State{CurrentNodeID: "end"}, true, true, false},
+		{"terminal node moves on", State{CurrentNodeID: "next"}, true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := tt.next
+			if got := FlowEnded(&State{CurrentNodeID: "end"}, &next, tt.terminal, tt.needsInput); got != tt.want {
+				t.Fatalf("FlowEnded() = %v, want %v", got, tt.want)
+			}
+			if tt.want && (!next.Terminated || next.Status != StatusTerminated) {
+				t.Errorf("ended state not marked terminated: %+v", next)
+			}
+		})
+	}
+}
+
+func TestEntered(t *testing.T) {
+	prev := &State{CurrentNodeID: "a", History: []string{"a"}}
+	if got := Entered(prev, &State{CurrentNodeID: "c", History: []string{"a", "b", "c"}}); !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("transition: got %v", got)
+	}
+	if got := Entered(prev, &State{CurrentNodeID: "z"}); !slices.Equal(got, []string{"z"}) {
+		t.Errorf("rollback: got %v", got)
+	}
+	if got := Entered(prev, &State{CurrentNodeID: "a", History: []string{"a"}}); got != nil {
+		t.Errorf("stay: got %v", got)
+	}
+}
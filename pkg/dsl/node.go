@@ -89,6 +89,12 @@ func (n *NodeBuilder) Branch(condition string, target string) *NodeBuilder {
 	return n
 }
 
+// Strict makes navigation fail when no transition matches the input (strict_transitions).
+func (n *NodeBuilder) Strict() *NodeBuilder {
+	n.node.StrictTransitions = true
+	return n
+}
+
 // Error sets the target node for error handling.
 func (n *NodeBuilder) Error(target string) *NodeBuilder {
 	n.node.OnError = target
@@ -0,0 +1,44 @@
+package intent
+
+import (
+	"context"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/ports"
+)
+
+// Chain tries classifiers in order and returns the first match reaching threshold.
+// If none does, the most confident match seen is returned.
+func Chain(threshold float64, classifiers ...ports.IntentClassifier) Func {
+	return func(ctx context.Context, input string, candidates []domain.Intent) (domain.IntentMatch, error) {
+		var best domain.IntentMatch
+		for _, c := range classifiers {
+			match, err := c.Classify(ctx, input, candidates)
+			if err != nil {
+				return domain.IntentMatch{}, err
+			}
+			if match.Target == "" {
+				continue
+			}
+			if match.Confidence >= threshold {
+				return match, nil
+			}
+			if match.Confidence > best.Confidence {
+				best = match
+			}
+		}
+		return best, nil
+	}
+}
+
+// Default is the built-in rule pipeline: index, exact, synonyms, prefix, keywords, fuzzy.
+func Default() Func {
+	return Chain(domain.DefaultMinConfidence,
+		Index(),
+		Exact(),
+		Synonyms(BuiltinSynonyms),
+		Prefix(),
+		Keywords(),
+		Fuzzy(),
+	)
+}
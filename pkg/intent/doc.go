@@ -0,0 +1,18 @@
+/*
+Package intent provides IntentClassifier strategies for route nodes.
+
+A route node turns its transitions into candidate intents (target node, label,
+synonyms, keywords) and asks a classifier which one the free-text input means.
+The built-in strategies are deterministic and cheap:
+
+  - Index: "2" picks the second option.
+  - Exact: case/punctuation-insensitive match on the label or target.
+  - Synonyms: per-option synonyms plus a small yes/no lexicon ("yeah sure" -> "Yes").
+  - Prefix: "bil" -> "Billing" when unambiguous.
+  - Keywords: rule words found in the input.
+  - Fuzzy: edit distance, tolerant to typos ("supprot" -> "Support").
+
+Default chains them, stopping at the first confident answer. LLM delegates to a
+ports.ModelProvider and is typically chained after Default as a fallback.
+*/
+package intent
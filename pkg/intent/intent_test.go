@@ -0,0 +1,120 @@
+package intent_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/intent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var yesNo = []domain.Intent{
+	{Target: "confirmed", Label: "Yes", Index: 1},
+	{Target: "cancelled", Label: "No", Index: 2},
+}
+
+var departments = []domain.Intent{
+	{Target: "billing", Label: "Billing", Index: 1, Keywords: []string{"invoice", "refund", "charge"}},
+	{Target: "support", Label: "Technical Support", Index: 2, Synonyms: []string{"help desk"}, Keywords: []string{"error", "crash"}},
+	{Target: "sales", Label: "Sales", Index: 3},
+}
+
+func TestDefault(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		input      string
+		candidates []domain.Intent
+		target     string
+		strategy   string
+	}{
+		{"index", "2", departments, "support", "index"},
+		{"exact ignores case and punctuation", "billing!", departments, "billing", "exact"},
+		{"lexicon", "yeah sure", yesNo, "confirmed", "synonym"},
+		{"lexicon negative", "nope", yesNo, "cancelled", "synonym"},
+		{"declared synonym", "help desk", departments, "support", "synonym"},
+		{"prefix", "tech", departments, "support", "prefix"},
+		{"keywords", "I want a refund for this invoice", departments, "billing", "keyword"},
+		{"typo", "saels", departments, "sales", "fuzzy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := intent.Default().Classify(ctx, tt.input, tt.candidates)
+			require.NoError(t, err)
+			assert.Equal(t, tt.target, match.Target)
+			assert.Equal(t, tt.strategy, match.Strategy)
+			assert.GreaterOrEqual(t, match.Confidence, domain.DefaultMinConfidence)
+		})
+	}
+}
+
+func TestDefault_Unclear(t *testing.T) {
+	ctx := context.Background()
+	for _, input := range []string{"what is the weather like", "9", "yes and no"} {
+		match, err := intent.Default().Classify(ctx, input, departments)
+		require.NoError(t, err)
+		assert.Less(t, match.Confidence, domain.DefaultMinConfidence, input)
+	}
+
+	match, err := intent.Default().Classify(ctx, "yes and no", yesNo)
+	require.NoError(t, err)
+	assert.Less(t, match.Confidence, domain.DefaultMinConfidence, "conflicting answers are ambiguous")
+}
+
+func TestPrefix(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		input      string
+		target     string
+		confidence float64
+	}{
+		{"bil", "billing", 0.8},
+		{"sales please", "sales", 0.75},
+		{"i guess sales then", "sales", 0.7},
+	}
+	for _, tt := range tests {
+		match, err := intent.Prefix().Classify(ctx, tt.input, departments)
+		require.NoError(t, err)
+		assert.Equal(t, tt.target, match.Target, tt.input)
+		assert.Equal(t, tt.confidence, match.Confidence, tt.input)
+	}
+}
+
+func TestChain_ReturnsBestWhenNoneIsConfident(t *testing.T) {
+	low := intent.Func(func(context.Context, string, []domain.Intent) (domain.IntentMatch, error) {
+		return domain.IntentMatch{Target: "a", Confidence: 0.3, Strategy: "low"}, nil
+	})
+	mid := intent.Func(func(context.Context, string, []domain.Intent) (domain.IntentMatch, error) {
+		return domain.IntentMatch{Target: "b", Confidence: 0.5, Strategy: "mid"}, nil
+	})
+
+	match, err := intent.Chain(0.9, low, mid).Classify(context.Background(), "x", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "mid", match.Strategy)
+}
+
+func TestLLM(t *testing.T) {
+	provider := memory.NewModelProvider(
+		domain.ModelResponse{Route: "support", Output: map[string]any{"confidence": 0.92}},
+		domain.ModelResponse{Route: "elsewhere"},
+	)
+	classifier := intent.LLM(provider)
+	ctx := context.Background()
+
+	match, err := classifier.Classify(ctx, "my app keeps freezing", departments)
+	require.NoError(t, err)
+	assert.Equal(t, domain.IntentMatch{Target: "support", Confidence: 0.92, Strategy: "llm"}, match)
+
+	reqs := provider.Requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, []string{"billing", "support", "sales"}, reqs[0].Routes)
+	assert.Contains(t, reqs[0].Prompt, "my app keeps freezing")
+
+	// Undeclared routes are never accepted.
+	match, err = classifier.Classify(ctx, "hm", departments)
+	require.NoError(t, err)
+	assert.Empty(t, match.Target)
+}
@@ -0,0 +1,72 @@
+package intent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/ports"
+	"github.com/aretw0/trellis/pkg/schema"
+)
+
+// DefaultLLMConfidence is assumed when the model picks a route without reporting confidence.
+const DefaultLLMConfidence = 0.8
+
+// LLM classifies intents with a language model. The model may only pick among the
+// candidate targets (ModelRequest.Routes) and reports its confidence.
+func LLM(provider ports.ModelProvider) Func {
+	return func(ctx context.Context, input string, candidates []domain.Intent) (domain.IntentMatch, error) {
+		var (
+			routes []string
+			sb     strings.Builder
+		)
+		for _, c := range candidates {
+			routes = append(routes, c.Target)
+			label := c.Label
+			if label == "" {
+				label = c.Target
+			}
+			fmt.Fprintf(&sb, "- %s: %s", c.Target, label)
+			if len(c.Synonyms) > 0 {
+				fmt.Fprintf(&sb, " (also: %s)", strings.Join(c.Synonyms, ", "))
+			}
+			sb.WriteString("\n")
+		}
+
+		resp, err := provider.Complete(ctx, domain.ModelRequest{
+			System: "You map a user's answer to exactly one of the available options. " +
+				"Pick the route of the matching option and report your confidence between 0 and 1. " +
+				"If the answer matches none of them, report a confidence of 0.",
+			Prompt: fmt.Sprintf("Options:\n%s\nAnswer: %q", sb.String(), input),
+			Schema: schema.Schema{"confidence": schema.Float()},
+			Routes: routes,
+		})
+		if err != nil {
+			return domain.IntentMatch{}, fmt.Errorf("llm intent classifier: %w", err)
+		}
+
+		output := resp.Output
+		if output == nil {
+			_ = json.Unmarshal([]byte(strings.TrimSpace(resp.Content)), &output)
+		}
+		confidence := DefaultLLMConfidence
+		if v, ok := output["confidence"].(float64); ok {
+			confidence = v
+		}
+		if resp.Route == "" {
+			if v, ok := output["route"].(string); ok {
+				resp.Route = v
+			}
+		}
+
+		// The decision is still constrained to the declared options.
+		for _, r := range routes {
+			if r == resp.Route {
+				return domain.IntentMatch{Target: r, Confidence: confidence, Strategy: "llm"}, nil
+			}
+		}
+		return domain.IntentMatch{}, nil
+	}
+}
@@ -0,0 +1,239 @@
+package intent
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aretw0/trellis/internal/textdist"
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// Func adapts a function to the ports.IntentClassifier interface.
+type Func func(ctx context.Context, input string, candidates []domain.Intent) (domain.IntentMatch, error)
+
+// Classify implements ports.IntentClassifier.
+func (f Func) Classify(ctx context.Context, input string, candidates []domain.Intent) (domain.IntentMatch, error) {
+	return f(ctx, input, candidates)
+}
+
+// BuiltinSynonyms maps common canonical answers to their colloquial forms.
+var BuiltinSynonyms = map[string][]string{
+	"yes": {"y", "yeah", "yep", "yup", "sure", "ok", "okay", "of course", "certainly", "affirmative", "absolutely", "sim", "s"},
+	"no":  {"n", "nope", "nah", "never", "negative", "no way", "não", "nao"},
+}
+
+// Index matches option numbers ("2" picks the second option).
+func Index() Func {
+	return func(_ context.Context, input string, candidates []domain.Intent) (domain.IntentMatch, error) {
+		n, err := strconv.Atoi(strings.TrimSuffix(normalize(input), "."))
+		if err != nil {
+			return domain.IntentMatch{}, nil
+		}
+		for _, c := range candidates {
+			if c.Index == n {
+				return domain.IntentMatch{Target: c.Target, Confidence: 1, Strategy: "index"}, nil
+			}
+		}
+		return domain.IntentMatch{}, nil
+	}
+}
+
+// Exact matches the label (or target ID) ignoring case, spacing and punctuation.
+func Exact() Func {
+	return func(_ context.Context, input string, candidates []domain.Intent) (domain.IntentMatch, error) {
+		in := normalize(input)
+		for _, c := range candidates {
+			if in != "" && (in == normalize(c.Label) || in == normalize(c.Target)) {
+				return domain.IntentMatch{Target: c.Target, Confidence: 1, Strategy: "exact"}, nil
+			}
+		}
+		return domain.IntentMatch{}, nil
+	}
+}
+
+// Synonyms matches per-option synonyms plus the given lexicon (keyed by canonical label).
+// A whole-input match is near certain; otherwise every word that is a synonym must
+// point to the same option.
+func Synonyms(lexicon map[string][]string) Func {
+	return func(_ context.Context, input string, candidates []domain.Intent) (domain.IntentMatch, error) {
+		in := normalize(input)
+		if in == "" {
+			return domain.IntentMatch{}, nil
+		}
+
+		hits := make(map[string]int)
+		tokens := strings.Fields(in)
+		for _, c := range candidates {
+			words := append([]string{c.Label}, c.Synonyms...)
+			words = append(words, lexicon[normalize(c.Label)]...)
+			for _, w := range words {
+				syn := normalize(w)
+				if syn == "" {
+					continue
+				}
+				if syn == in {
+					return domain.IntentMatch{Target: c.Target, Confidence: 0.95, Strategy: "synonym"}, nil
+				}
+				if strings.Contains(syn, " ") {
+					if strings.Contains(" "+in+" ", " "+syn+" ") {
+						hits[c.Target] += len(strings.Fields(syn))
+					}
+					continue
+				}
+				for _, tok := range tokens {
+					if tok == syn {
+						hits[c.Target]++
+					}
+				}
+			}
+		}
+		target, count, unique := best(hits)
+		if !unique {
+			return domain.IntentMatch{}, nil
+		}
+		ratio := float64(count) / float64(len(tokens))
+		if ratio > 1 {
+			ratio = 1
+		}
+		return domain.IntentMatch{Target: target, Confidence: 0.7 + 0.25*ratio, Strategy: "synonym"}, nil
+	}
+}
+
+// Prefix matches abbreviations ("bil" -> "Billing"), labels followed by extra words
+// ("yes please" -> "Yes") and labels mentioned mid-sentence ("I guess sales" -> "Sales"),
+// as long as only one option qualifies.
+func Prefix() Func {
+	return func(_ context.Context, input string, candidates []domain.Intent) (domain.IntentMatch, error) {
+		in := normalize(input)
+		if in == "" {
+			return domain.IntentMatch{}, nil
+		}
+		var (
+			matches    []string
+			confidence float64
+		)
+		padded := " " + in + " "
+		for _, c := range candidates {
+			label := normalize(c.Label)
+			if label == "" {
+				continue
+			}
+			switch {
+			case strings.HasPrefix(label, in):
+				matches = append(matches, c.Target)
+				confidence = 0.8
+			case strings.HasPrefix(in, label+" "):
+				matches = append(matches, c.Target)
+				confidence = 0.75
+			case strings.Contains(padded, " "+label+" "):
+				// Another option mentioned later ("yes and no") makes the answer ambiguous.
+				matches = append(matches, c.Target)
+				confidence = 0.7
+			}
+		}
+		if len(matches) != 1 {
+			return domain.IntentMatch{}, nil
+		}
+		return domain.IntentMatch{Target: matches[0], Confidence: confidence, Strategy: "prefix"}, nil
+	}
+}
+
+// Keywords matches rule words found anywhere in the input.
+func Keywords() Func {
+	return func(_ context.Context, input string, candidates []domain.Intent) (domain.IntentMatch, error) {
+		in := " " + normalize(input) + " "
+		hits := make(map[string]int)
+		for _, c := range candidates {
+			for _, kw := range c.Keywords {
+				if k := normalize(kw); k != "" && strings.Contains(in, " "+k+" ") {
+					hits[c.Target]++
+				}
+			}
+		}
+		target, count, unique := best(hits)
+		if !unique {
+			return domain.IntentMatch{}, nil
+		}
+		confidence := 0.6 + 0.1*float64(count)
+		if confidence > 0.9 {
+			confidence = 0.9
+		}
+		return domain.IntentMatch{Target: target, Confidence: confidence, Strategy: "keyword"}, nil
+	}
+}
+
+// Fuzzy tolerates typos using edit distance against labels and synonyms.
+func Fuzzy() Func {
+	return func(_ context.Context, input string, candidates []domain.Intent) (domain.IntentMatch, error) {
+		in := normalize(input)
+		if in == "" {
+			return domain.IntentMatch{}, nil
+		}
+		scores := make(map[string]float64)
+		for _, c := range candidates {
+			for _, w := range append([]string{c.Label}, c.Synonyms...) {
+				if ref := normalize(w); ref != "" {
+					if s := similarity(in, ref); s > scores[c.Target] {
+						scores[c.Target] = s
+					}
+				}
+			}
+		}
+
+		var first, second float64
+		target := ""
+		for t, s := range scores {
+			switch {
+			case s > first:
+				first, second, target = s, first, t
+			case s > second:
+				second = s
+			}
+		}
+		if target == "" {
+			return domain.IntentMatch{}, nil
+		}
+		// Near ties are ambiguous: halve the confidence.
+		if first-second < 0.15 {
+			first /= 2
+		}
+		return domain.IntentMatch{Target: target, Confidence: first, Strategy: "fuzzy"}, nil
+	}
+}
+
+// best returns the key with the highest count and whether it is the only one with hits.
+func best(hits map[string]int) (string, int, bool) {
+	if len(hits) != 1 {
+		return "", 0, false
+	}
+	for k, v := range hits {
+		return k, v, true
+	}
+	return "", 0, false
+}
+
+// normalize lowercases, drops punctuation and collapses whitespace.
+func normalize(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			sb.WriteRune(r)
+		case unicode.IsSpace(r), r == '-', r == '_':
+			sb.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// similarity is 1 - normalized Levenshtein distance.
+func similarity(a, b string) float64 {
+	maxLen := max(utf8.RuneCountInString(a), utf8.RuneCountInString(b))
+	if maxLen == 0 {
+		return 1
+	}
+	return 1 - float64(textdist.Levenshtein(a, b))/float64(maxLen)
+}
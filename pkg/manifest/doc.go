@@ -0,0 +1,19 @@
+// Package manifest loads the project definition file (`trellis.yaml`).
+//
+// The manifest centralizes settings that were otherwise spread across CLI flags,
+// environment variables and file-name conventions:
+//
+//	name: checkout
+//	entry: welcome
+//	error_node: oops
+//	tools: tools.yaml
+//	locale: pt-BR
+//	store:
+//	  backend: redis
+//	  url: redis://localhost:6379
+//	server:
+//	  port: 8080
+//
+// Precedence, from highest to lowest: CLI flags and Go options, environment
+// variables (see Env*), the manifest, and the built-in conventions.
+package manifest
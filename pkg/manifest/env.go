@@ -0,0 +1,66 @@
+package manifest
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+// Environment variables that override manifest values.
+const (
+	EnvEntry        = "TRELLIS_ENTRY"
+	EnvErrorNode    = "TRELLIS_ERROR_NODE"
+	EnvTools        = "TRELLIS_TOOLS"
+	EnvInterpolator = "TRELLIS_INTERPOLATOR"
+	EnvLocale       = "TRELLIS_LOCALE"
+	EnvStore        = "TRELLIS_STORE"
+	EnvStoreURL     = "TRELLIS_STORE_URL"
+	EnvStorePath    = "TRELLIS_STORE_PATH"
+	EnvPort         = "TRELLIS_PORT"
+	// EnvMaxInputSize matches runner.EnvMaxInputSize.
+	EnvMaxInputSize = "TRELLIS_MAX_INPUT_SIZE"
+)
+
+// applyEnv overrides fields with the environment variables that are set, then revalidates.
+func (m *Manifest) applyEnv(lookup func(string) (string, bool)) error {
+	fields := map[string]*string{
+		EnvEntry:        &m.Entry,
+		EnvErrorNode:    &m.ErrorNode,
+		EnvTools:        &m.Tools,
+		EnvInterpolator: &m.Interpolator,
+		EnvLocale:       &m.Locale,
+		EnvStore:        &m.Store.Backend,
+		EnvStoreURL:     &m.Store.URL,
+		EnvStorePath:    &m.Store.Path,
+	}
+	for key, field := range fields {
+		if val, ok := lookup(key); ok && val != "" {
+			*field = val
+		}
+	}
+
+	ints := map[string]*int{
+		EnvPort:         &m.Server.Port,
+		EnvMaxInputSize: &m.Server.MaxInputSize,
+	}
+	for key, field := range ints {
+		val, ok := lookup(key)
+		if !ok || val == "" {
+			continue
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("%s: expected an integer, got %q", key, val)
+		}
+		*field = n
+	}
+
+	// Env-provided tool paths are relative to the working directory, not the manifest.
+	if val, ok := lookup(EnvTools); ok && val != "" {
+		if abs, err := filepath.Abs(val); err == nil {
+			m.Tools = abs
+		}
+	}
+
+	return m.Validate()
+}
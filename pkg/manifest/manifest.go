@@ -0,0 +1,196 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the manifest names looked up in a project directory, in order.
+var FileNames = []string{"trellis.yaml", "trellis.yml"}
+
+// Project layout for flow packages.
+const (
+	// LockFileName records the exact packages used by the project.
+	LockFileName = "trellis.lock"
+	// VendorDir holds vendored packages (one directory per dependency).
+	VendorDir = "vendor"
+)
+
+// DocumentID is the ID the manifest file gets in document repositories (e.g. Loam),
+// so graph loaders can tell it apart from nodes.
+const DocumentID = "trellis"
+
+// Manifest is the project definition (`trellis.yaml`).
+// Every field is optional; empty values keep the engine and CLI conventions.
+type Manifest struct {
+	// Name labels the project (defaults to the directory name).
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Version of the flow package.
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+	// Entry is the initial node ID (default: start/main/index conventions).
+	Entry string `yaml:"entry,omitempty" json:"entry,omitempty"`
+	// ErrorNode is the global fallback node for tool errors (default: "error" if it exists).
+	ErrorNode string `yaml:"error_node,omitempty" json:"error_node,omitempty"`
+	// Context lists the keys callers provide when a session starts (--context, Engine.Start).
+	// It documents the initial context for static analysis; the entry node's required_context enforces it.
+	Context []string `yaml:"context,omitempty" json:"context,omitempty"`
+	// Tools is the tool registry file, relative to the manifest (default: tools.yaml).
+	Tools string `yaml:"tools,omitempty" json:"tools,omitempty"`
+	// Interpolator selects the template engine: "template" (default), "html" or "legacy".
+	Interpolator string `yaml:"interpolator,omitempty" json:"interpolator,omitempty"`
+	// Locale is the default session locale (e.g. "pt-BR").
+	Locale string `yaml:"locale,omitempty" json:"locale,omitempty"`
+	// Strict rejects unknown node keys and mistyped values (same as --strict).
+	Strict bool `yaml:"strict,omitempty" json:"strict,omitempty"`
+	// Store configures session persistence.
+	Store Store `yaml:"store,omitempty" json:"store,omitempty"`
+	// Server configures `trellis serve` and `trellis mcp`.
+	Server Server `yaml:"server,omitempty" json:"server,omitempty"`
+	// Dependencies declares other flow packages by name.
+	Dependencies map[string]Dependency `yaml:"dependencies,omitempty" json:"dependencies,omitempty"`
+
+	// Dir is the project directory; relative paths are resolved against it.
+	Dir string `yaml:"-" json:"-"`
+	// Path is the manifest file (empty when the project has none).
+	Path string `yaml:"-" json:"-"`
+}
+
+// Store configures the session store and its distributed locker.
+type Store struct {
+	// Backend is "file" (default), "redis" or "memory".
+	Backend string `yaml:"backend,omitempty" json:"backend,omitempty"`
+	// URL is the connection URL for network backends (e.g. redis://localhost:6379).
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// Path is the session directory for the file backend (default: .trellis/sessions).
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// Locker is "auto" (default: the backend's own locker) or "none".
+	Locker string `yaml:"locker,omitempty" json:"locker,omitempty"`
+}
+
+// Server configures the network adapters.
+type Server struct {
+	// Port for `trellis serve` and `trellis mcp --transport sse`.
+	Port int `yaml:"port,omitempty" json:"port,omitempty"`
+	// MaxInputSize limits user input in bytes (same as TRELLIS_MAX_INPUT_SIZE).
+	MaxInputSize int `yaml:"max_input_size,omitempty" json:"max_input_size,omitempty"`
+}
+
+// Dependency points to another flow package (a local directory or a vendored archive).
+type Dependency struct {
+	// Path is the package directory, relative to the manifest.
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// Archive is a .tar.gz or .zip file with the package, relative to the manifest.
+	Archive string `yaml:"archive,omitempty" json:"archive,omitempty"`
+	// Version is the required package version (matched against the package manifest).
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+	// Checksum is the expected archive digest ("sha256:<hex>").
+	Checksum string `yaml:"checksum,omitempty" json:"checksum,omitempty"`
+}
+
+// Parse decodes a manifest, rejecting unknown keys, and validates it.
+// Environment overrides are not applied (see Load).
+func Parse(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Load reads the manifest at path and applies environment overrides.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	m.Dir = filepath.Dir(path)
+	m.Path = path
+	if err := m.applyEnv(os.LookupEnv); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return m, nil
+}
+
+// Find loads the manifest of the project at repoPath (a directory or a single-file flow).
+// Without a manifest file it returns an empty manifest, still subject to environment overrides.
+func Find(repoPath string) (*Manifest, error) {
+	dir := repoPath
+	if info, err := os.Stat(repoPath); err == nil && !info.IsDir() {
+		dir = filepath.Dir(repoPath)
+	}
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return Load(path)
+		}
+	}
+
+	m := &Manifest{Dir: dir}
+	if err := m.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FindFS loads the manifest at the root of fsys (e.g. an embedded flow).
+// Dir stays empty, so relative paths (tools, store) resolve against the working directory.
+func FindFS(fsys fs.FS) (*Manifest, error) {
+	m := &Manifest{}
+	for _, name := range FileNames {
+		data, err := fs.ReadFile(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+		return Decode(name, data)
+	}
+	if err := m.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Decode parses manifest data that does not live in a project directory (an fs.FS or a
+// bundle) and applies environment overrides. name is recorded as Path and cited in errors.
+func Decode(name string, data []byte) (*Manifest, error) {
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	m.Path = name
+	if err := m.applyEnv(os.LookupEnv); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return m, nil
+}
+
+// Resolve returns path relative to the manifest directory (absolute paths are kept).
+func (m *Manifest) Resolve(path string) string {
+	if path == "" || filepath.IsAbs(path) || m.Dir == "" {
+		return path
+	}
+	return filepath.Join(m.Dir, path)
+}
+
+// ToolsPath returns the resolved tool registry path, or "" when the manifest does not set one.
+func (m *Manifest) ToolsPath() string {
+	return m.Resolve(m.Tools)
+}
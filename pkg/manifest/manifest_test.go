@@ -0,0 +1,154 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(`
+name: checkout
+version: 1.2.0
+entry: welcome
+error_node: oops
+context: [user_id, plan]
+tools: config/tools.yaml
+interpolator: html
+locale: pt-BR
+store:
+  backend: redis
+  url: redis://localhost:6379
+server:
+  port: 9090
+  max_input_size: 8192
+dependencies:
+  auth:
+    path: ../auth
+    version: 0.3.0
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "welcome", m.Entry)
+	assert.Equal(t, "oops", m.ErrorNode)
+	assert.Equal(t, []string{"user_id", "plan"}, m.Context)
+	assert.Equal(t, "redis", m.Store.Backend)
+	assert.Equal(t, 9090, m.Server.Port)
+	assert.Equal(t, Dependency{Path: "../auth", Version: "0.3.0"}, m.Dependencies["auth"])
+
+	empty, err := Parse(nil)
+	require.NoError(t, err)
+	assert.Equal(t, &Manifest{}, empty)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{"Unknown key", "entyr: start", "field entyr not found"},
+		{"Type mismatch", "server:\n  port: high", "cannot unmarshal"},
+		{"Reserved context key", "context: [sys.user]", "context[0]: invalid key \"sys.user\""},
+		{"Unknown interpolator", "interpolator: jinja", "interpolator: unknown value \"jinja\""},
+		{"Unknown backend", "store:\n  backend: s3", "store.backend"},
+		{"Redis without URL", "store:\n  backend: redis", "store.url: required"},
+		{"Port out of range", "server:\n  port: 70000", "server.port"},
+		{"Dependency without source", "dependencies:\n  auth: {version: 1.0.0}", "dependencies.auth: path or archive required"},
+		{"Dependency with two sources", "dependencies:\n  auth: {path: ../auth, archive: auth.tgz}", "mutually exclusive"},
+		{"Invalid checksum", "dependencies:\n  auth: {archive: auth.tgz, checksum: md5:abc}", "dependencies.auth.checksum"},
+		{"Invalid dependency name", "dependencies:\n  Auth Kit: {path: ../auth}", "dependencies.Auth Kit: invalid name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	t.Run("Manifest next to the flow", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "trellis.yaml"), []byte("entry: main\ntools: tools/registry.yaml\nstore:\n  path: data/sessions\n"), 0644))
+		flow := filepath.Join(dir, "flow.yaml")
+		require.NoError(t, os.WriteFile(flow, []byte("nodes: {}"), 0644))
+
+		for _, path := range []string{dir, flow} {
+			m, err := Find(path)
+			require.NoError(t, err)
+			assert.Equal(t, "main", m.Entry)
+			assert.Equal(t, filepath.Join(dir, "trellis.yaml"), m.Path)
+			assert.Equal(t, filepath.Join(dir, "tools", "registry.yaml"), m.ToolsPath())
+			assert.Equal(t, filepath.Join(dir, "data", "sessions"), m.Resolve(m.Store.Path))
+		}
+	})
+
+	t.Run("No manifest", func(t *testing.T) {
+		dir := t.TempDir()
+		m, err := Find(dir)
+		require.NoError(t, err)
+		assert.Empty(t, m.Path)
+		assert.Equal(t, dir, m.Dir)
+		assert.Empty(t, m.Entry)
+	})
+
+	t.Run("Error names the file", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "trellis.yml"), []byte("locale: [pt]"), 0644))
+		_, err := Find(dir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "trellis.yml")
+	})
+}
+
+func TestFindFS(t *testing.T) {
+	m, err := FindFS(fstest.MapFS{"trellis.yml": {Data: []byte("name: embedded\nentry: main\n")}})
+	require.NoError(t, err)
+	assert.Equal(t, "embedded", m.Name)
+	assert.Equal(t, "main", m.Entry)
+	assert.Equal(t, "trellis.yml", m.Path)
+	assert.Empty(t, m.Dir)
+
+	m, err = FindFS(fstest.MapFS{})
+	require.NoError(t, err)
+	assert.Empty(t, m.Path)
+
+	_, err = FindFS(fstest.MapFS{"trellis.yaml": {Data: []byte("locale: [pt]")}})
+	assert.ErrorContains(t, err, "trellis.yaml")
+}
+
+func TestEnvOverrides(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "trellis.yaml"), []byte("entry: start\nlocale: en\nserver:\n  port: 8080\n"), 0644))
+
+	t.Setenv(EnvEntry, "main")
+	t.Setenv(EnvPort, "9000")
+	t.Setenv(EnvStore, "memory")
+
+	m, err := Find(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "main", m.Entry)
+	assert.Equal(t, "en", m.Locale, "unset variables keep manifest values")
+	assert.Equal(t, 9000, m.Server.Port)
+	assert.Equal(t, "memory", m.Store.Backend)
+
+	t.Run("Overrides are validated", func(t *testing.T) {
+		t.Setenv(EnvStore, "redis")
+		_, err := Find(dir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "store.url")
+	})
+
+	t.Run("Invalid integer", func(t *testing.T) {
+		t.Setenv(EnvPort, "eighty")
+		_, err := Find(dir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), EnvPort)
+	})
+}
@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	interpolators = []string{"", "template", "html", "legacy"}
+	backends      = []string{"", "file", "redis", "memory"}
+	lockers       = []string{"", "auto", "none"}
+
+	dependencyName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+	checksumFormat = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+)
+
+// Validate checks the manifest against its schema and reports every violation.
+func (m *Manifest) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	for i, key := range m.Context {
+		if key == "" || key == "sys" || strings.HasPrefix(key, "sys.") {
+			fail(fmt.Sprintf("context[%d]", i), "invalid key %q (sys is reserved)", key)
+		}
+	}
+	if !oneOf(m.Interpolator, interpolators) {
+		fail("interpolator", "unknown value %q (expected template, html or legacy)", m.Interpolator)
+	}
+	if !oneOf(m.Store.Backend, backends) {
+		fail("store.backend", "unknown value %q (expected file, redis or memory)", m.Store.Backend)
+	}
+	if m.Store.Backend == "redis" && m.Store.URL == "" {
+		fail("store.url", "required for the redis backend")
+	}
+	if !oneOf(m.Store.Locker, lockers) {
+		fail("store.locker", "unknown value %q (expected auto or none)", m.Store.Locker)
+	}
+	if m.Server.Port < 0 || m.Server.Port > 65535 {
+		fail("server.port", "out of range: %d", m.Server.Port)
+	}
+	if m.Server.MaxInputSize < 0 {
+		fail("server.max_input_size", "must be positive: %d", m.Server.MaxInputSize)
+	}
+
+	names := make([]string, 0, len(m.Dependencies))
+	for name := range m.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !dependencyName.MatchString(name) {
+			fail("dependencies."+name, "invalid name (use lowercase letters, digits, '.', '_' or '-')")
+		}
+		dep := m.Dependencies[name]
+		switch {
+		case dep.Path == "" && dep.Archive == "":
+			fail("dependencies."+name, "path or archive required")
+		case dep.Path != "" && dep.Archive != "":
+			fail("dependencies."+name, "path and archive are mutually exclusive")
+		}
+		if dep.Checksum != "" && !checksumFormat.MatchString(dep.Checksum) {
+			fail("dependencies."+name+".checksum", "expected sha256:<hex>")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func oneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package observability
+
+import (
+	"context"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// UsageMetrics exports tool usage (calls, tokens, cost, units) as Prometheus counters.
+// It is fed by the engine's OnToolReturn lifecycle hook.
+type UsageMetrics struct {
+	calls  *prometheus.CounterVec
+	tokens *prometheus.CounterVec
+	cost   *prometheus.CounterVec
+	units  *prometheus.CounterVec
+}
+
+// NewUsageMetrics creates the usage collectors and registers them in the given registerer.
+func NewUsageMetrics(reg prometheus.Registerer) (*UsageMetrics, error) {
+	labels := []string{"tool", "status"}
+	m := &UsageMetrics{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "trellis_tool_calls_total",
+			Help: "Total number of completed tool calls.",
+		}, labels),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "trellis_tool_tokens_total",
+			Help: "Total tokens reported by tool calls.",
+		}, labels),
+		cost: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "trellis_tool_cost_total",
+			Help: "Total cost reported by tool calls.",
+		}, labels),
+		units: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "trellis_tool_units_total",
+			Help: "Total generic units reported by tool calls.",
+		}, labels),
+	}
+
+	for _, c := range []prometheus.Collector{m.calls, m.tokens, m.cost, m.units} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Observe records a tool return event.
+func (m *UsageMetrics) Observe(evt *domain.ToolEvent) {
+	if evt == nil {
+		return
+	}
+	status := "success"
+	if evt.IsError {
+		status = "error"
+	}
+
+	m.calls.WithLabelValues(evt.ToolName, status).Inc()
+	if evt.Usage == nil {
+		return
+	}
+	m.tokens.WithLabelValues(evt.ToolName, status).Add(float64(evt.Usage.Tokens))
+	m.cost.WithLabelValues(evt.ToolName, status).Add(evt.Usage.Cost)
+	m.units.WithLabelValues(evt.ToolName, status).Add(evt.Usage.Units)
+}
+
+// Hooks returns an OnToolReturn hook that observes every tool and llm call of the engine.
+func (m *UsageMetrics) Hooks() domain.LifecycleHooks {
+	return domain.LifecycleHooks{
+		OnToolReturn: func(_ context.Context, evt *domain.ToolEvent) {
+			m.Observe(evt)
+		},
+	}
+}
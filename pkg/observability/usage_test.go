@@ -0,0 +1,42 @@
+package observability
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestUsageMetrics_Observe(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := NewUsageMetrics(reg)
+	if err != nil {
+		t.Fatalf("failed to create metrics: %v", err)
+	}
+
+	hooks := m.Hooks()
+	hooks.OnToolReturn(context.Background(), &domain.ToolEvent{
+		ToolName: "llm",
+		Usage:    &domain.ToolUsage{Tokens: 100, Cost: 0.25},
+	})
+	hooks.OnToolReturn(context.Background(), &domain.ToolEvent{
+		ToolName: "llm",
+		Usage:    &domain.ToolUsage{Tokens: 50, Cost: 0.5},
+	})
+	hooks.OnToolReturn(context.Background(), &domain.ToolEvent{ToolName: "llm", IsError: true})
+
+	if got := testutil.ToFloat64(m.calls.WithLabelValues("llm", "success")); got != 2 {
+		t.Errorf("expected 2 successful calls, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.calls.WithLabelValues("llm", "error")); got != 1 {
+		t.Errorf("expected 1 failed call, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.tokens.WithLabelValues("llm", "success")); got != 150 {
+		t.Errorf("expected 150 tokens, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.cost.WithLabelValues("llm", "success")); got != 0.75 {
+		t.Errorf("expected cost 0.75, got %v", got)
+	}
+}
@@ -0,0 +1,16 @@
+// Package packages shares whole subgraphs across projects.
+//
+// Dependencies are declared in the project manifest (`trellis.yaml`) as local
+// directories or archives, and their nodes are mounted under a namespace:
+//
+//	dependencies:
+//	  auth:
+//	    path: ../auth-flows
+//	    version: 1.2.0
+//
+// A node reaches the package with `jump_to: pkg:auth/start`. Inside the package,
+// references stay relative (`to: verify` resolves to `pkg:auth/verify`).
+//
+// `trellis vendor` copies every dependency into `vendor/<name>` and records
+// their checksums in `trellis.lock`; no network access is involved.
+package packages
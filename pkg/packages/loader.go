@@ -0,0 +1,120 @@
+package packages
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/ports"
+)
+
+// Loader mounts package graphs under the "pkg:<name>/" namespace of a root graph.
+type Loader struct {
+	root     ports.GraphLoader
+	packages map[string]ports.GraphLoader
+}
+
+// NewLoader creates a loader that serves the root graph plus the given packages.
+func NewLoader(root ports.GraphLoader, packages map[string]ports.GraphLoader) *Loader {
+	return &Loader{root: root, packages: packages}
+}
+
+// GetNode implements ports.GraphLoader.
+// Package nodes are returned with their ID and every node reference qualified.
+func (l *Loader) GetNode(id string) ([]byte, error) {
+	pkg, nodeID, ok := ParseRef(id)
+	if !ok {
+		return l.root.GetNode(id)
+	}
+
+	loader, found := l.packages[pkg]
+	if !found {
+		return nil, fmt.Errorf("%w: unknown package '%s' (declare it in trellis.yaml dependencies)", domain.ErrNodeNotFound, pkg)
+	}
+	raw, err := loader.GetNode(nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("package %s: %w", pkg, err)
+	}
+	return namespace(pkg, raw)
+}
+
+// ListNodes implements ports.GraphLoader.
+func (l *Loader) ListNodes() ([]string, error) {
+	ids, err := l.root.ListNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(l.packages))
+	for name := range l.packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pkgIDs, err := l.packages[name].ListNodes()
+		if err != nil {
+			return nil, fmt.Errorf("package %s: %w", name, err)
+		}
+		for _, id := range pkgIDs {
+			ids = append(ids, Ref(name, id))
+		}
+	}
+	return ids, nil
+}
+
+// Watch implements ports.Watchable by delegating to the root graph.
+// Packages are pinned (vendored or locked), so they are not watched.
+func (l *Loader) Watch(ctx context.Context) (<-chan string, error) {
+	if w, ok := l.root.(ports.Watchable); ok {
+		return w.Watch(ctx)
+	}
+	return nil, fmt.Errorf("root %w", domain.ErrWatchUnsupported)
+}
+
+// namespace qualifies the node ID and all node references of a raw package node.
+func namespace(pkg string, raw []byte) ([]byte, error) {
+	var node map[string]any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber() // keep integers intact through the round-trip
+	if err := dec.Decode(&node); err != nil {
+		return nil, fmt.Errorf("package %s: invalid node data: %w", pkg, err)
+	}
+
+	for _, key := range []string{"id", "on_error", "on_denied", "on_unclear"} {
+		if target, ok := node[key].(string); ok {
+			node[key] = qualify(pkg, target)
+		}
+	}
+	for _, key := range []string{"on_signal", "on_signal_default"} {
+		if handlers, ok := node[key].(map[string]any); ok {
+			for signal, target := range handlers {
+				if s, ok := target.(string); ok {
+					handlers[signal] = qualify(pkg, s)
+				}
+			}
+		}
+	}
+	if transitions, ok := node["transitions"].([]any); ok {
+		for _, t := range transitions {
+			if transition, ok := t.(map[string]any); ok {
+				for _, key := range []string{"to_node_id", "from_node_id"} {
+					if target, ok := transition[key].(string); ok {
+						transition[key] = qualify(pkg, target)
+					}
+				}
+			}
+		}
+	}
+	// Positions point into the package, e.g. "pkg:auth/start.md".
+	if source, ok := node["source"].(map[string]any); ok {
+		if file, ok := source["file"].(string); ok && file != "" {
+			source["file"] = Ref(pkg, file)
+		}
+	}
+
+	return json.Marshal(node)
+}
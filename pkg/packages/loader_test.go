@@ -0,0 +1,95 @@
+package packages_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/packages"
+	"github.com/aretw0/trellis/pkg/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		id, pkg, node string
+		ok            bool
+	}{
+		{"pkg:auth/start", "auth", "start", true},
+		{"pkg:auth/flows/login", "auth", "flows/login", true},
+		{"pkg:auth", "auth", "start", true},
+		{"start", "", "", false},
+		{"pkg:", "", "", false},
+	}
+	for _, tt := range tests {
+		pkg, node, ok := packages.ParseRef(tt.id)
+		assert.Equal(t, tt.ok, ok, tt.id)
+		assert.Equal(t, tt.pkg, pkg, tt.id)
+		assert.Equal(t, tt.node, node, tt.id)
+	}
+}
+
+func TestLoader(t *testing.T) {
+	root := memory.NewLoader(map[string]string{
+		"start": `{"id": "start", "transitions": [{"to_node_id": "pkg:auth/start"}]}`,
+	})
+	auth := memory.NewLoader(map[string]string{
+		"start": `{"id": "start", "type": "tool", "do": {"id": "check", "name": "check", "args": {"retries": 9007199254740993}},
+			"on_error": "failed", "on_signal": {"interrupt": "pkg:billing/cancel"},
+			"transitions": [{"to_node_id": "verify", "condition": "input == 'ok'"}]}`,
+		"verify": `{"id": "verify"}`,
+	})
+	loader := packages.NewLoader(root, map[string]ports.GraphLoader{"auth": auth})
+
+	t.Run("Root nodes are untouched", func(t *testing.T) {
+		raw, err := loader.GetNode("start")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id": "start", "transitions": [{"to_node_id": "pkg:auth/start"}]}`, string(raw))
+	})
+
+	t.Run("Package references are qualified", func(t *testing.T) {
+		raw, err := loader.GetNode("pkg:auth/start")
+		require.NoError(t, err)
+
+		var node domain.Node
+		require.NoError(t, json.Unmarshal(raw, &node))
+		assert.Equal(t, "pkg:auth/start", node.ID)
+		assert.Equal(t, "pkg:auth/failed", node.OnError)
+		assert.Equal(t, "pkg:billing/cancel", node.OnSignal["interrupt"], "other packages are kept")
+		assert.Equal(t, "pkg:auth/verify", node.Transitions[0].ToNodeID)
+		assert.Contains(t, string(raw), "9007199254740993", "integers survive the rewrite")
+	})
+
+	t.Run("Rollback stays reserved", func(t *testing.T) {
+		pay := memory.NewLoader(map[string]string{
+			"charge": `{"id": "charge", "do": {"id": "charge", "name": "charge"}, "on_error": "rollback",
+				"transitions": [{"to_node_id": "Rollback", "condition": "input == 'undo'"}, {"to_node_id": "done"}]}`,
+		})
+		raw, err := packages.NewLoader(root, map[string]ports.GraphLoader{"pay": pay}).GetNode("pkg:pay/charge")
+		require.NoError(t, err)
+
+		var node domain.Node
+		require.NoError(t, json.Unmarshal(raw, &node))
+		assert.Equal(t, "rollback", node.OnError, "rollback is a keyword, not a node")
+		assert.Equal(t, "Rollback", node.Transitions[0].ToNodeID)
+		assert.Equal(t, "pkg:pay/done", node.Transitions[1].ToNodeID)
+	})
+
+	t.Run("Unknown package", func(t *testing.T) {
+		_, err := loader.GetNode("pkg:billing/start")
+		assert.ErrorContains(t, err, "unknown package 'billing'")
+	})
+
+	t.Run("Missing node", func(t *testing.T) {
+		_, err := loader.GetNode("pkg:auth/nope")
+		assert.ErrorContains(t, err, "package auth")
+	})
+
+	t.Run("ListNodes", func(t *testing.T) {
+		ids, err := loader.ListNodes()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"start", "pkg:auth/start", "pkg:auth/verify"}, ids)
+	})
+}
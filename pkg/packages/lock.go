@@ -0,0 +1,124 @@
+package packages
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LockFile records the exact packages vendored into a project (`trellis.lock`).
+type LockFile struct {
+	Packages map[string]LockedPackage `yaml:"packages"`
+}
+
+// LockedPackage pins one dependency.
+type LockedPackage struct {
+	// Version declared by the package manifest.
+	Version string `yaml:"version,omitempty"`
+	// Source is the path or archive the package was vendored from.
+	Source string `yaml:"source"`
+	// Checksum is the DirHash of the vendored directory.
+	Checksum string `yaml:"checksum"`
+}
+
+const lockHeader = "# Generated by `trellis vendor`. DO NOT EDIT.\n"
+
+// ReadLockFile loads a lockfile. A missing file returns (nil, nil).
+func ReadLockFile(path string) (*LockFile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+	var lock LockFile
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return &lock, nil
+}
+
+// WriteLockFile saves the lockfile (keys are sorted, so the output is stable).
+func WriteLockFile(path string, lock *LockFile) error {
+	var buf bytes.Buffer
+	buf.WriteString(lockHeader)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(lock); err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// DirHash computes a stable digest of a package directory ("sha256:<hex>").
+// Each file contributes its slash-separated relative path and content digest;
+// hidden files and directories (e.g. .trellis sessions) are ignored.
+func DirHash(dir string) (string, error) {
+	var lines []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		sum, err := fileHash(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, sum+"  "+filepath.ToSlash(rel)+"\n")
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", dir, err)
+	}
+
+	sort.Strings(lines)
+	h := sha256.New()
+	for _, line := range lines {
+		io.WriteString(h, line)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FileHash computes the digest of a single file ("sha256:<hex>"), e.g. an archive.
+func FileHash(path string) (string, error) {
+	sum, err := fileHash(path)
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + sum, nil
+}
+
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
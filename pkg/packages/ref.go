@@ -0,0 +1,38 @@
+package packages
+
+import (
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// Prefix marks node IDs that live in a package ("pkg:auth/start").
+const Prefix = "pkg:"
+
+// Ref builds the qualified ID of a node inside a package.
+func Ref(pkg, nodeID string) string {
+	return Prefix + pkg + "/" + nodeID
+}
+
+// ParseRef splits a qualified ID into package name and node ID.
+// A bare package reference ("pkg:auth") points to the package's "start" node.
+func ParseRef(id string) (pkg, nodeID string, ok bool) {
+	rest, ok := strings.CutPrefix(id, Prefix)
+	if !ok || rest == "" {
+		return "", "", false
+	}
+	pkg, nodeID, _ = strings.Cut(rest, "/")
+	if nodeID == "" {
+		nodeID = "start"
+	}
+	return pkg, nodeID, true
+}
+
+// qualify rewrites a package-relative target into a qualified ID.
+// Empty targets, the rollback keyword and references to other packages are kept.
+func qualify(pkg, target string) string {
+	if target == "" || domain.IsRollback(target) || strings.HasPrefix(target, Prefix) {
+		return target
+	}
+	return Ref(pkg, target)
+}
@@ -0,0 +1,406 @@
+package packages
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/manifest"
+)
+
+// Resolve returns the directory of every dependency declared in m.
+// Vendored copies take precedence and are verified against the lockfile;
+// otherwise local paths are used as-is. Archives must be vendored first.
+func Resolve(m *manifest.Manifest) (map[string]string, error) {
+	lock, err := ReadLockFile(filepath.Join(m.Dir, manifest.LockFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make(map[string]string, len(m.Dependencies))
+	for _, name := range sortedNames(m) {
+		dep := m.Dependencies[name]
+		vendored := filepath.Join(m.Dir, manifest.VendorDir, name)
+
+		var dir string
+		switch {
+		case isDir(vendored):
+			if lock != nil {
+				if err := verify(name, vendored, lock); err != nil {
+					return nil, err
+				}
+			}
+			dir = vendored
+		case dep.Path != "":
+			dir = m.Resolve(dep.Path)
+			if !isDir(dir) {
+				return nil, fmt.Errorf("package %s: directory not found: %s", name, dir)
+			}
+		default:
+			return nil, fmt.Errorf("package %s: archive is not vendored (run 'trellis vendor')", name)
+		}
+
+		if err := checkVersion(name, dep, dir); err != nil {
+			return nil, err
+		}
+		dirs[name] = dir
+	}
+	return dirs, nil
+}
+
+// Vendor copies every dependency into the project's vendor directory and writes the lockfile.
+// Sources are local paths or archives, so vendoring never touches the network.
+// Packages of the previous lockfile that are no longer declared are removed; other
+// directories under vendor/ are left alone.
+func Vendor(m *manifest.Manifest) (*LockFile, error) {
+	vendorDir := filepath.Join(m.Dir, manifest.VendorDir)
+	lockPath := filepath.Join(m.Dir, manifest.LockFileName)
+	previous, err := ReadLockFile(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	lock := &LockFile{Packages: make(map[string]LockedPackage)}
+
+	for _, name := range sortedNames(m) {
+		dep := m.Dependencies[name]
+		dst := filepath.Join(vendorDir, name)
+		tmp := dst + ".tmp"
+		if err := os.RemoveAll(tmp); err != nil {
+			return nil, err
+		}
+
+		source := dep.Path
+		if dep.Path != "" {
+			if err := copyDir(m.Resolve(dep.Path), tmp); err != nil {
+				return nil, fmt.Errorf("package %s: %w", name, err)
+			}
+		} else {
+			source = dep.Archive
+			archive := m.Resolve(dep.Archive)
+			if dep.Checksum != "" {
+				sum, err := FileHash(archive)
+				if err != nil {
+					return nil, fmt.Errorf("package %s: %w", name, err)
+				}
+				if sum != dep.Checksum {
+					return nil, fmt.Errorf("package %s: archive checksum mismatch: expected %s, got %s", name, dep.Checksum, sum)
+				}
+			}
+			if err := extract(archive, tmp); err != nil {
+				os.RemoveAll(tmp)
+				return nil, fmt.Errorf("package %s: %w", name, err)
+			}
+		}
+
+		if err := checkVersion(name, dep, tmp); err != nil {
+			os.RemoveAll(tmp)
+			return nil, err
+		}
+		if err := os.RemoveAll(dst); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(tmp, dst); err != nil {
+			return nil, err
+		}
+
+		sum, err := DirHash(dst)
+		if err != nil {
+			return nil, err
+		}
+		version, _ := packageVersion(dst)
+		lock.Packages[name] = LockedPackage{Version: version, Source: source, Checksum: sum}
+	}
+
+	// Drop packages that were vendored before and are no longer declared
+	if previous != nil {
+		for name := range previous.Packages {
+			if _, declared := m.Dependencies[name]; declared || !isPackageName(name) {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(vendorDir, name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := WriteLockFile(lockPath, lock); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// isPackageName reports whether name is a single path element, so a tampered lockfile
+// cannot point pruning outside the vendor directory.
+func isPackageName(name string) bool {
+	return filepath.IsLocal(name) && !strings.ContainsAny(name, `/\`)
+}
+
+// verify checks a vendored package against its locked checksum.
+func verify(name, dir string, lock *LockFile) error {
+	locked, ok := lock.Packages[name]
+	if !ok {
+		return fmt.Errorf("package %s: vendored but missing from %s (run 'trellis vendor')", name, manifest.LockFileName)
+	}
+	sum, err := DirHash(dir)
+	if err != nil {
+		return err
+	}
+	if sum != locked.Checksum {
+		return fmt.Errorf("package %s: vendored files do not match %s (run 'trellis vendor')", name, manifest.LockFileName)
+	}
+	return nil
+}
+
+// checkVersion matches the required version against the package's own manifest.
+func checkVersion(name string, dep manifest.Dependency, dir string) error {
+	if dep.Version == "" {
+		return nil
+	}
+	version, err := packageVersion(dir)
+	if err != nil {
+		return fmt.Errorf("package %s: %w", name, err)
+	}
+	if version != dep.Version {
+		return fmt.Errorf("package %s: version %q does not match required %q", name, version, dep.Version)
+	}
+	return nil
+}
+
+// packageVersion reads the version declared by a package manifest ("" without one).
+// Environment overrides are deliberately not applied: they target the root project.
+func packageVersion(dir string) (string, error) {
+	for _, file := range manifest.FileNames {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			continue
+		}
+		m, err := manifest.Parse(data)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", file, err)
+		}
+		return m.Version, nil
+	}
+	return "", nil
+}
+
+func sortedNames(m *manifest.Manifest) []string {
+	names := make([]string, 0, len(m.Dependencies))
+	for name := range m.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// copyDir copies a package tree, skipping hidden files and directories.
+func copyDir(src, dst string) error {
+	if !isDir(src) {
+		return fmt.Errorf("directory not found: %s", src)
+	}
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		if rel != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return writeFile(target, f)
+	})
+}
+
+// extract unpacks a .tar.gz/.tgz or .zip archive into dst.
+// A single top-level directory (e.g. "auth-1.2.0/") is stripped.
+func extract(archive, dst string) error {
+	switch {
+	case strings.HasSuffix(archive, ".zip"):
+		return extractZip(archive, dst)
+	case strings.HasSuffix(archive, ".tar.gz"), strings.HasSuffix(archive, ".tgz"):
+		return extractTarGz(archive, dst)
+	default:
+		return fmt.Errorf("unsupported archive format: %s (expected .tar.gz, .tgz or .zip)", filepath.Base(archive))
+	}
+}
+
+func extractZip(archive, dst string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	strip := commonRoot(names)
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		target, err := entryPath(dst, f.Name, strip)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(target, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTarGz(archive, dst string) error {
+	open := func() (*tar.Reader, func(), error) {
+		f, err := os.Open(archive)
+		if err != nil {
+			return nil, nil, err
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return tar.NewReader(gz), func() { gz.Close(); f.Close() }, nil
+	}
+
+	// First pass: find the common root to strip
+	tr, closeFn, err := open()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			closeFn()
+			return err
+		}
+		names = append(names, hdr.Name)
+	}
+	closeFn()
+	strip := commonRoot(names)
+
+	tr, closeFn, err = open()
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue // directories are created on demand; links are not supported
+		}
+		target, err := entryPath(dst, hdr.Name, strip)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+		if err := writeFile(target, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// commonRoot returns the single top-level directory shared by all entries ("" if none).
+func commonRoot(names []string) string {
+	root := ""
+	for _, name := range names {
+		clean := strings.TrimPrefix(path.Clean("/"+name), "/")
+		first, _, nested := strings.Cut(clean, "/")
+		if !nested && !strings.HasSuffix(name, "/") {
+			return "" // a file at the top level
+		}
+		if root == "" {
+			root = first
+		} else if root != first {
+			return ""
+		}
+	}
+	return root
+}
+
+// entryPath maps an archive entry to a path inside dst, rejecting entries that escape it.
+// Hidden entries return "".
+func entryPath(dst, name, strip string) (string, error) {
+	clean := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if strip != "" {
+		clean = strings.TrimPrefix(strings.TrimPrefix(clean, strip), "/")
+	}
+	if clean == "" {
+		return "", nil
+	}
+	for _, part := range strings.Split(clean, "/") {
+		if strings.HasPrefix(part, ".") {
+			return "", nil
+		}
+	}
+	target := filepath.Join(dst, filepath.FromSlash(clean))
+	if !strings.HasPrefix(target, filepath.Clean(dst)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("archive entry escapes the package directory: %s", name)
+	}
+	return target, nil
+}
+
+func writeFile(target string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,166 @@
+package packages_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aretw0/trellis/pkg/manifest"
+	"github.com/aretw0/trellis/pkg/packages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func writeTarGz(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+}
+
+func TestVendor_Path(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "app")
+	writeFiles(t, filepath.Join(root, "auth"), map[string]string{
+		"trellis.yaml":             "version: 1.2.0\n",
+		"start.md":                 "Login",
+		"flows/verify.md":          "Verify",
+		".trellis/sessions/x.json": "{}",
+	})
+	m := &manifest.Manifest{Dir: project, Dependencies: map[string]manifest.Dependency{
+		"auth": {Path: "../auth", Version: "1.2.0"},
+	}}
+	require.NoError(t, os.MkdirAll(project, 0755))
+
+	lock, err := packages.Vendor(m)
+	require.NoError(t, err)
+
+	vendored := filepath.Join(project, "vendor", "auth")
+	assert.FileExists(t, filepath.Join(vendored, "flows", "verify.md"))
+	assert.NoDirExists(t, filepath.Join(vendored, ".trellis"), "hidden files are not vendored")
+
+	sum, err := packages.DirHash(vendored)
+	require.NoError(t, err)
+	assert.Equal(t, packages.LockedPackage{Version: "1.2.0", Source: "../auth", Checksum: sum}, lock.Packages["auth"])
+
+	saved, err := packages.ReadLockFile(filepath.Join(project, "trellis.lock"))
+	require.NoError(t, err)
+	assert.Equal(t, lock, saved)
+
+	dirs, err := packages.Resolve(m)
+	require.NoError(t, err)
+	assert.Equal(t, vendored, dirs["auth"], "vendored copy wins over the source path")
+
+	// Tampering with the vendored copy is detected
+	require.NoError(t, os.WriteFile(filepath.Join(vendored, "start.md"), []byte("Hacked"), 0644))
+	_, err = packages.Resolve(m)
+	assert.ErrorContains(t, err, "do not match trellis.lock")
+
+	// Undeclared packages are pruned; directories trellis did not vendor are kept
+	notes := filepath.Join(project, "vendor", "notes")
+	writeFiles(t, notes, map[string]string{"README.md": "Not a package"})
+	m.Dependencies = map[string]manifest.Dependency{}
+	_, err = packages.Vendor(m)
+	require.NoError(t, err)
+	assert.NoDirExists(t, vendored)
+	assert.DirExists(t, notes)
+}
+
+func TestVendor_Archive(t *testing.T) {
+	project := t.TempDir()
+	archive := filepath.Join(project, "deps", "auth-1.0.0.tar.gz")
+	require.NoError(t, os.MkdirAll(filepath.Dir(archive), 0755))
+	writeTarGz(t, archive, map[string]string{
+		"auth-1.0.0/trellis.yaml": "version: 1.0.0\n",
+		"auth-1.0.0/start.md":     "Login",
+	})
+	sum, err := packages.FileHash(archive)
+	require.NoError(t, err)
+
+	m := &manifest.Manifest{Dir: project, Dependencies: map[string]manifest.Dependency{
+		"auth": {Archive: "deps/auth-1.0.0.tar.gz", Version: "1.0.0", Checksum: sum},
+	}}
+
+	_, err = packages.Resolve(m)
+	assert.ErrorContains(t, err, "archive is not vendored")
+
+	_, err = packages.Vendor(m)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(project, "vendor", "auth", "start.md"), "top-level directory is stripped")
+
+	dirs, err := packages.Resolve(m)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(project, "vendor", "auth"), dirs["auth"])
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		bad := *m
+		bad.Dependencies = map[string]manifest.Dependency{
+			"auth": {Archive: "deps/auth-1.0.0.tar.gz", Checksum: "sha256:0000000000000000000000000000000000000000000000000000000000000000"},
+		}
+		_, err := packages.Vendor(&bad)
+		assert.ErrorContains(t, err, "archive checksum mismatch")
+	})
+}
+
+func TestVendor_Errors(t *testing.T) {
+	t.Run("Version mismatch", func(t *testing.T) {
+		root := t.TempDir()
+		writeFiles(t, filepath.Join(root, "auth"), map[string]string{"trellis.yaml": "version: 2.0.0\n", "start.md": "Login"})
+		m := &manifest.Manifest{Dir: root, Dependencies: map[string]manifest.Dependency{
+			"auth": {Path: "auth", Version: "1.0.0"},
+		}}
+		_, err := packages.Resolve(m)
+		assert.ErrorContains(t, err, `version "2.0.0" does not match required "1.0.0"`)
+		_, err = packages.Vendor(m)
+		assert.ErrorContains(t, err, "does not match")
+		assert.NoDirExists(t, filepath.Join(root, "vendor", "auth"))
+	})
+
+	t.Run("Archive entries cannot escape", func(t *testing.T) {
+		root := t.TempDir()
+		archive := filepath.Join(root, "evil.zip")
+		f, err := os.Create(archive)
+		require.NoError(t, err)
+		zw := zip.NewWriter(f)
+		w, err := zw.Create("../../outside.md")
+		require.NoError(t, err)
+		_, _ = w.Write([]byte("x"))
+		require.NoError(t, zw.Close())
+		require.NoError(t, f.Close())
+
+		m := &manifest.Manifest{Dir: root, Dependencies: map[string]manifest.Dependency{"evil": {Archive: "evil.zip"}}}
+		_, err = packages.Vendor(m)
+		require.NoError(t, err)
+		assert.NoFileExists(t, filepath.Join(filepath.Dir(root), "outside.md"))
+		assert.FileExists(t, filepath.Join(root, "vendor", "evil", "outside.md"), "the entry is confined to the package")
+	})
+
+	t.Run("Unsupported archive", func(t *testing.T) {
+		root := t.TempDir()
+		writeFiles(t, root, map[string]string{"auth.rar": "x"})
+		m := &manifest.Manifest{Dir: root, Dependencies: map[string]manifest.Dependency{"auth": {Archive: "auth.rar"}}}
+		_, err := packages.Vendor(m)
+		assert.ErrorContains(t, err, "unsupported archive format")
+	})
+}
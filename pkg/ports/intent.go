@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// IntentClassifier maps free-text input onto one of the candidate intents of a route node.
+// It returns an empty Target when nothing matches; low confidence is handled by the Engine.
+type IntentClassifier interface {
+	Classify(ctx context.Context, input string, candidates []domain.Intent) (domain.IntentMatch, error)
+}
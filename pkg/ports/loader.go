@@ -7,6 +7,7 @@ import "context"
 type GraphLoader interface {
 	// GetNode retrieves the raw definition of a node by ID.
 	// It returns the raw bytes (which the compiler will parse) or an error.
+	// Missing nodes are reported with an error wrapping domain.ErrNodeNotFound.
 	GetNode(id string) ([]byte, error)
 
 	// ListNodes returns a simplified list of all node IDs available in the graph.
@@ -19,5 +20,6 @@ type GraphLoader interface {
 type Watchable interface {
 	// Watch returns a channel that is signaled when the underlying graph changes.
 	// It returns a string (the event type, e.g. "reload") or an error.
+	// Static sources report an error wrapping domain.ErrWatchUnsupported.
 	Watch(ctx context.Context) (<-chan string, error)
 }
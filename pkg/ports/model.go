@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// ModelProvider executes completions for llm nodes.
+// Implementations must honor ModelRequest.Schema (structured output) and
+// ModelRequest.Routes (route selection) when they are set.
+type ModelProvider interface {
+	Complete(ctx context.Context, req domain.ModelRequest) (domain.ModelResponse, error)
+}
@@ -1,8 +1,10 @@
 package tests
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/aretw0/trellis/pkg/domain"
 	"github.com/aretw0/trellis/pkg/ports"
 )
 
@@ -28,6 +30,8 @@ func GraphLoaderContractTest(t *testing.T, loader ports.GraphLoader, setupData m
 		_, err := loader.GetNode("non-existent-node")
 		if err == nil {
 			t.Error("expected error for non-existent node, got nil")
+		} else if !errors.Is(err, domain.ErrNodeNotFound) {
+			t.Errorf("expected domain.ErrNodeNotFound, got %v", err)
 		}
 	})
 
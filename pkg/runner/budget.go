@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"context"
+	"maps"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+type stateContextKey struct{}
+
+// ContextWithState attaches the current execution state to the context.
+// The Runner does this before invoking interceptors so that policies can
+// make decisions based on the session (e.g. accumulated usage).
+func ContextWithState(ctx context.Context, state *domain.State) context.Context {
+	return context.WithValue(ctx, stateContextKey{}, state)
+}
+
+// StateFromContext retrieves the execution state attached by ContextWithState.
+func StateFromContext(ctx context.Context) *domain.State {
+	state, _ := ctx.Value(stateContextKey{}).(*domain.State)
+	return state
+}
+
+// BudgetMiddleware denies tool calls once the session has exhausted its budget.
+// The effective budget is the tightest combination of the given session budget and
+// the flow-level budget declared on the entry node (SystemContext["budget"]).
+// Denied calls return IsDenied, routing the flow to on_denied.
+func BudgetMiddleware(session domain.Budget) ToolInterceptor {
+	return func(ctx context.Context, call domain.ToolCall) (bool, domain.ToolResult, error) {
+		state := StateFromContext(ctx)
+		// Compensations (SAGA undo) must never be blocked by spending limits.
+		if state == nil || state.Status == domain.StatusRollingBack {
+			return true, domain.ToolResult{}, nil
+		}
+
+		budget := session.Merge(domain.BudgetFromState(state))
+		if budget.IsZero() {
+			return true, domain.ToolResult{}, nil
+		}
+
+		usage := domain.UsageFromState(state)
+		if exhausted, reason := budget.Exhausted(usage.UsageTotals); exhausted {
+			return false, domain.ToolResult{
+				ID:       call.ID,
+				IsDenied: true,
+				Error:    reason,
+			}, nil
+		}
+		return true, domain.ToolResult{}, nil
+	}
+}
+
+// reserveCall returns a copy of state whose usage ledger counts one more call of the tool.
+// Usage metadata is unknown until the call returns, so only the call count grows.
+func reserveCall(state *domain.State, toolName string) *domain.State {
+	reserved := *state
+	reserved.SystemContext = maps.Clone(state.SystemContext)
+	if reserved.SystemContext == nil {
+		reserved.SystemContext = make(map[string]any)
+	}
+	reserved.SystemContext[domain.SysKeyUsage] = domain.UsageFromState(state).Record(toolName, nil).ToMap()
+	return &reserved
+}
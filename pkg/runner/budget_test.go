@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetMiddleware(t *testing.T) {
+	call := domain.ToolCall{ID: "c1", Name: "llm"}
+
+	t.Run("Allows without state or budget", func(t *testing.T) {
+		allowed, _, err := BudgetMiddleware(domain.Budget{MaxCalls: 1})(context.Background(), call)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+
+		state := domain.NewState("s", "start")
+		allowed, _, _ = BudgetMiddleware(domain.Budget{})(ContextWithState(context.Background(), state), call)
+		assert.True(t, allowed)
+	})
+
+	t.Run("Denies when session budget is exhausted", func(t *testing.T) {
+		state := domain.NewState("s", "start")
+		state.SystemContext[domain.SysKeyUsage] = domain.UsageReport{}.Record("llm", &domain.ToolUsage{Cost: 1}).ToMap()
+
+		allowed, result, err := BudgetMiddleware(domain.Budget{MaxCost: 1})(ContextWithState(context.Background(), state), call)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		assert.True(t, result.IsDenied)
+		assert.Equal(t, "c1", result.ID)
+		assert.Contains(t, result.Error, "cost budget exhausted")
+	})
+
+	t.Run("Honors flow budget from system context", func(t *testing.T) {
+		state := domain.NewState("s", "start")
+		state.SystemContext[domain.SysKeyBudget] = domain.Budget{MaxCalls: 1}.ToMap()
+		state.SystemContext[domain.SysKeyUsage] = domain.UsageReport{}.Record("llm", nil).ToMap()
+
+		allowed, _, _ := BudgetMiddleware(domain.Budget{})(ContextWithState(context.Background(), state), call)
+		assert.False(t, allowed)
+	})
+
+	t.Run("Never blocks compensations", func(t *testing.T) {
+		state := domain.NewState("s", "start")
+		state.Status = domain.StatusRollingBack
+		state.SystemContext[domain.SysKeyUsage] = domain.UsageReport{}.Record("llm", nil).ToMap()
+
+		allowed, _, _ := BudgetMiddleware(domain.Budget{MaxCalls: 1})(ContextWithState(context.Background(), state), call)
+		assert.True(t, allowed)
+	})
+}
+
+func TestRunner_BudgetRoutesToOnDenied(t *testing.T) {
+	loader, _ := memory.NewFromNodes(
+		domain.Node{
+			ID:          "start",
+			Type:        domain.NodeTypeTool,
+			Do:          &domain.ToolCall{ID: "first", Name: "llm"},
+			Transitions: []domain.Transition{{ToNodeID: "second"}},
+		},
+		domain.Node{
+			ID:          "second",
+			Type:        domain.NodeTypeTool,
+			Do:          &domain.ToolCall{ID: "second", Name: "llm"},
+			OnDenied:    "over_budget",
+			Transitions: []domain.Transition{{ToNodeID: "done"}},
+		},
+		domain.Node{ID: "over_budget", Type: domain.NodeTypeText},
+		domain.Node{ID: "done", Type: domain.NodeTypeText},
+	)
+	engine, _ := trellis.New("", trellis.WithLoader(loader))
+
+	mockHandler := &MockToolHandler{
+		Tools: map[string]func(map[string]any) (any, error){
+			"llm": func(map[string]any) (any, error) { return "ok", nil },
+		},
+	}
+
+	r := NewRunner(
+		WithInputHandler(mockHandler),
+		WithHeadless(true),
+		WithEngine(engine),
+		WithBudget(domain.Budget{MaxCalls: 1}),
+	)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
+
+	require.NoError(t, r.Run(ctx))
+
+	final := r.State()
+	assert.Equal(t, "over_budget", final.CurrentNodeID)
+	assert.Len(t, mockHandler.Calls, 1, "second call must be denied before execution")
+	assert.Equal(t, 1, domain.UsageFromState(final).Calls)
+}
+
+func TestRunner_BudgetCountsBatchCalls(t *testing.T) {
+	loader, _ := memory.NewFromNodes(
+		domain.Node{
+			ID:   "start",
+			Type: domain.NodeTypeTool,
+			Batch: []domain.BatchCall{
+				{ToolCall: domain.ToolCall{ID: "a", Name: "llm"}},
+				{ToolCall: domain.ToolCall{ID: "b", Name: "llm"}},
+				{ToolCall: domain.ToolCall{ID: "c", Name: "llm"}},
+			},
+			BatchPolicy: domain.BatchPolicyContinue,
+			Transitions: []domain.Transition{{ToNodeID: "done"}},
+		},
+		domain.Node{ID: "done", Type: domain.NodeTypeText},
+	)
+	engine, _ := trellis.New("", trellis.WithLoader(loader))
+
+	mockHandler := &MockToolHandler{
+		Tools: map[string]func(map[string]any) (any, error){
+			"llm": func(map[string]any) (any, error) { return "ok", nil },
+		},
+	}
+	r := NewRunner(
+		WithInputHandler(mockHandler),
+		WithHeadless(true),
+		WithEngine(engine),
+		WithBudget(domain.Budget{MaxCalls: 2}),
+	)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, r.Run(ctx))
+
+	assert.Len(t, mockHandler.Calls, 2, "calls past the budget must be denied before execution, within the batch too")
+	assert.Equal(t, 2, domain.UsageFromState(r.State()).Calls)
+}
@@ -18,6 +18,8 @@ type JSONHandler struct {
 	Writer   io.Writer
 	Encoder  *json.Encoder
 	Registry *registry.Registry
+	// MaxInputSize limits each input in bytes (0 uses the SanitizeInput default).
+	MaxInputSize int
 
 	// Async reading fields
 	linesCh chan string
@@ -35,6 +37,13 @@ func WithJSONHandlerRegistry(reg *registry.Registry) JSONHandlerOption {
 	}
 }
 
+// WithJSONMaxInputSize limits each input in bytes (see SanitizeInputLimit).
+func WithJSONMaxInputSize(size int) JSONHandlerOption {
+	return func(h *JSONHandler) {
+		h.MaxInputSize = size
+	}
+}
+
 // WithJSONInputBufferSize sets the size of the input buffer.
 func WithJSONInputBufferSize(size int) JSONHandlerOption {
 	return func(h *JSONHandler) {
@@ -115,7 +124,7 @@ func (h *JSONHandler) Input(ctx context.Context) (string, error) {
 		// Fallback: return raw text
 
 		// Sanitize Input
-		clean, err := SanitizeInput(text)
+		clean, err := SanitizeInputLimit(text, h.MaxInputSize)
 		if err != nil {
 			slog.Warn("Input Rejected", "tool_name", "JSONHandler", "error", err, "size", len(text))
 			return "", err
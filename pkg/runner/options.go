@@ -6,6 +6,7 @@ import (
 	"github.com/aretw0/trellis"
 	"github.com/aretw0/trellis/pkg/domain"
 	"github.com/aretw0/trellis/pkg/ports"
+	"github.com/aretw0/trellis/pkg/transcript"
 )
 
 // DefaultInputBufferSize is the default number of lines to buffer for input handlers.
@@ -64,6 +65,14 @@ func WithInterceptor(interceptor ToolInterceptor) Option {
 	}
 }
 
+// WithBudget sets a per-session spending limit for tool calls.
+// It is combined with any flow-level budget declared on the entry node.
+func WithBudget(budget domain.Budget) Option {
+	return func(r *Runner) {
+		r.Budget = budget
+	}
+}
+
 // WithToolRunner configures the strategy for executing side-effects.
 func WithToolRunner(tr ToolRunner) Option {
 	return func(r *Runner) {
@@ -71,6 +80,14 @@ func WithToolRunner(tr ToolRunner) Option {
 	}
 }
 
+// WithTranscript records the session (rendered content, inputs, tool calls and timings)
+// as a JSON Lines transcript. See the transcript package.
+func WithTranscript(rec *transcript.Recorder) Option {
+	return func(r *Runner) {
+		r.Transcript = rec
+	}
+}
+
 // WithInterruptSource sets a channel that signals the runner to interrupt current execution.
 func WithInterruptSource(ch <-chan struct{}) Option {
 	return func(r *Runner) {
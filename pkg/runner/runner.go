@@ -16,6 +16,7 @@ import (
 	"github.com/aretw0/trellis"
 	"github.com/aretw0/trellis/pkg/domain"
 	"github.com/aretw0/trellis/pkg/ports"
+	"github.com/aretw0/trellis/pkg/transcript"
 )
 
 // Runner handles the execution loop of the Trellis engine using provided IO.
@@ -33,13 +34,16 @@ type Runner struct {
 	SessionID       string
 	Renderer        ContentRenderer
 	Interceptor     ToolInterceptor
+	Budget          domain.Budget
 	ToolRunner      ToolRunner
 	InterruptSource <-chan struct{}
+	Transcript      *transcript.Recorder
 
 	// Worker Pattern: Self-contained execution context
 	engine       *trellis.Engine
 	initialState *domain.State
 	finalState   *domain.State
+	started      bool // The start entry was recorded
 
 	// State Watching
 	stateMu   sync.RWMutex
@@ -143,6 +147,12 @@ func NewRunner(opts ...Option) *Runner {
 // Run executes the engine loop until termination.
 // This method implements the lifecycle.Worker interface (Run(context.Context) error).
 func (r *Runner) Run(ctx context.Context) error {
+	err := r.run(ctx)
+	r.recordEnd(err)
+	return err
+}
+
+func (r *Runner) run(ctx context.Context) error {
 	// 1. Setup Phase
 	engine := r.engine
 	initialState := r.initialState
@@ -158,6 +168,8 @@ func (r *Runner) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	r.record(transcript.Entry{Kind: transcript.KindStart, Node: state.CurrentNodeID, Status: state.Status, State: state.Snapshot()})
+	r.started = true
 
 	lastRenderedID := ""
 
@@ -178,12 +190,21 @@ func (r *Runner) Run(ctx context.Context) error {
 		// Update Observability State
 		r.broadcastState(state)
 
+		// Async: every pending call runs in the background. The session was persisted
+		// in WaitingForTool, so release it; results arrive via session.Manager.CompleteTool.
+		if state.AwaitingAsync() {
+			r.Logger.Info("session suspended awaiting async tool results", "session_id", r.SessionID, "node_id", state.CurrentNodeID)
+			break
+		}
+
 		// A. Render
 		actions, isTerminal, err := engine.Render(ctx, state)
 		if err != nil {
 			r.finalState = state
 			return fmt.Errorf("render error: %w", err)
 		}
+		r.record(transcript.View(state, actions))
+		renderedAt := time.Now()
 
 		// B. Output
 		stepTimeout := r.detectTimeout(actions)
@@ -212,7 +233,11 @@ func (r *Runner) Run(ctx context.Context) error {
 		} else if state.Status == domain.StatusWaitingForTool || state.Status == domain.StatusRollingBack {
 			nextInput, err = r.handleTool(ctx, actions, state, handler, interceptor)
 		} else {
-			nextInput, nextState, err = r.handleInput(inputCtx, handler, needsInput, engine, state)
+			// The state tells handlers that answer on their own (answers.Handler) which node asks.
+			nextInput, nextState, err = r.handleInput(ContextWithState(inputCtx, state), handler, needsInput, engine, state)
+			if err == nil && nextState == nil {
+				r.record(transcript.Entry{Kind: transcript.KindInput, Node: state.CurrentNodeID, Input: nextInput, DurationMS: time.Since(renderedAt).Milliseconds()})
+			}
 		}
 
 		inputCancel() // Clean up input context for this step
@@ -258,12 +283,8 @@ func (r *Runner) Run(ctx context.Context) error {
 			break
 		}
 
-		if nextState.Terminated || nextState.Status == domain.StatusTerminated || (isTerminal && !needsInput && nextState.CurrentNodeID == state.CurrentNodeID) {
+		if domain.FlowEnded(state, nextState, isTerminal, needsInput) {
 			state = nextState
-			if isTerminal && !state.Terminated {
-				state.Terminated = true
-				state.Status = domain.StatusTerminated
-			}
 			break
 		}
 		state = nextState
@@ -305,14 +326,17 @@ func (r *Runner) resolveHandler() IOHandler {
 }
 
 // resolveInterceptor returns the configured or default interceptor.
+// Budget enforcement always runs first, so exhausted sessions are denied
+// before the user is asked to confirm anything.
 func (r *Runner) resolveInterceptor(h IOHandler) ToolInterceptor {
+	budget := BudgetMiddleware(r.Budget)
 	if r.Interceptor != nil {
-		return r.Interceptor
+		return MultiInterceptor(budget, r.Interceptor)
 	}
 	if r.Headless {
-		return AutoApproveMiddleware()
+		return MultiInterceptor(budget, AutoApproveMiddleware())
 	}
-	return ConfirmationMiddleware(h)
+	return MultiInterceptor(budget, ConfirmationMiddleware(h))
 }
 
 func (r *Runner) resolveInitialState(ctx context.Context, engine *trellis.Engine, initial *domain.State) (*domain.State, error) {
@@ -352,6 +376,10 @@ func (r *Runner) handleTool(
 	handler IOHandler,
 	interceptor ToolInterceptor,
 ) (any, error) {
+	if len(state.PendingToolCalls) > 0 {
+		return r.handleBatch(ctx, actions, state, handler, interceptor)
+	}
+
 	var pendingCall *domain.ToolCall
 	for _, act := range actions {
 		if act.Type == domain.ActionCallTool {
@@ -367,27 +395,130 @@ func (r *Runner) handleTool(
 		return nil, fmt.Errorf("state is waiting for tool %s but no corresponding action produced", state.PendingToolCall)
 	}
 
-	allowed, policyResult, err := interceptor(ctx, *pendingCall)
+	allowed, policyResult, err := interceptor(ContextWithState(ctx, state), *pendingCall)
 	if err != nil {
 		return nil, fmt.Errorf("tool interceptor error: %w", err)
 	}
 
 	if !allowed {
+		r.recordTool(state.CurrentNodeID, *pendingCall, policyResult, 0)
 		return policyResult, nil
 	}
 
 	// Priority: Explicit ToolRunner > Handler (Legacy/IO-bound)
+	started := time.Now()
 	if r.ToolRunner != nil {
-		return r.ToolRunner.Execute(ctx, *pendingCall)
+		result, err := r.ToolRunner.Execute(ctx, *pendingCall)
+		if err != nil {
+			r.recordToolError(state.CurrentNodeID, *pendingCall, err, time.Since(started))
+			return result, err
+		}
+		r.recordTool(state.CurrentNodeID, *pendingCall, result, time.Since(started))
+		return result, nil
 	}
 
 	result, err := handler.HandleTool(ctx, *pendingCall)
 	if err != nil {
+		r.recordToolError(state.CurrentNodeID, *pendingCall, err, time.Since(started))
 		return nil, fmt.Errorf("tool execution failed: %w", err)
 	}
+	r.recordTool(state.CurrentNodeID, *pendingCall, result, time.Since(started))
 	return result, nil
 }
 
+// handleBatch executes every pending call of a batch and returns the results in call order.
+// Interceptors run sequentially (they may prompt the user); allowed calls then run
+// concurrently through the ToolRunner. The legacy IOHandler path stays sequential.
+func (r *Runner) handleBatch(
+	ctx context.Context,
+	actions []domain.ActionRequest,
+	state *domain.State,
+	handler IOHandler,
+	interceptor ToolInterceptor,
+) (any, error) {
+	var calls []domain.ToolCall
+	for _, act := range actions {
+		if act.Type != domain.ActionCallTool {
+			continue
+		}
+		if call, ok := act.Payload.(domain.ToolCall); ok && state.IsPending(call.ID) {
+			calls = append(calls, call)
+		}
+	}
+	if len(calls) == 0 {
+		return nil, fmt.Errorf("state is waiting for tools %v but no corresponding action produced", state.PendingToolCalls)
+	}
+
+	results := make([]domain.ToolResult, len(calls))
+	allowed := make([]bool, len(calls))
+	durations := make([]time.Duration, len(calls))
+
+	// Each call is checked as if the calls allowed before it had already run, so a batch
+	// cannot overshoot the budget.
+	policyState := state
+	for i, call := range calls {
+		ok, policyResult, err := interceptor(ContextWithState(ctx, policyState), call)
+		if err != nil {
+			return nil, fmt.Errorf("tool interceptor error: %w", err)
+		}
+		allowed[i] = ok
+		if !ok {
+			results[i] = policyResult
+			continue
+		}
+		policyState = reserveCall(policyState, call.Name)
+	}
+
+	execute := func(i int) {
+		call := calls[i]
+		var (
+			result domain.ToolResult
+			err    error
+		)
+		started := time.Now()
+		defer func() { durations[i] = time.Since(started) }()
+		if r.ToolRunner != nil {
+			result, err = r.ToolRunner.Execute(ctx, call)
+		} else {
+			result, err = handler.HandleTool(ctx, call)
+		}
+		if err != nil {
+			// Keep sibling results: a transport failure becomes a tool error for this call only.
+			result = domain.ToolResult{ID: call.ID, IsError: true, Error: err.Error()}
+		}
+		if result.ID == "" {
+			result.ID = call.ID
+		}
+		results[i] = result
+	}
+
+	if r.ToolRunner != nil {
+		var wg sync.WaitGroup
+		for i := range calls {
+			if !allowed[i] {
+				continue
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				execute(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range calls {
+			if allowed[i] {
+				execute(i)
+			}
+		}
+	}
+
+	for i, call := range calls {
+		r.recordTool(state.CurrentNodeID, call, results[i], durations[i])
+	}
+	return results, nil
+}
+
 func (r *Runner) handleInput(
 	ctx context.Context,
 	handler IOHandler,
@@ -476,6 +607,7 @@ func (r *Runner) handleSignal(ctx context.Context, engine *trellis.Engine, state
 	nextState, sigErr := engine.Signal(ctx, state, signalName)
 	if sigErr == nil {
 		r.Logger.Debug("Runner: Signal transition success", "signal", signalName)
+		r.record(transcript.Entry{Kind: transcript.KindSignal, Node: state.CurrentNodeID, Signal: signalName})
 		if signalName == domain.SignalInterrupt {
 			lifecycle.ResetSignalCount(ctx)
 		}
@@ -492,3 +624,39 @@ func (r *Runner) handleSignal(ctx context.Context, engine *trellis.Engine, state
 		return nil, nil, fmt.Errorf("signal %s failed: %w", signalName, sigErr)
 	}
 }
+
+// record writes a transcript entry when a Recorder is configured. Write errors are kept
+// by the Recorder (see transcript.Recorder.Err), so a full disk does not stop the session.
+func (r *Runner) record(e transcript.Entry) {
+	if r.Transcript != nil {
+		_ = r.Transcript.Record(e)
+	}
+}
+
+func (r *Runner) recordTool(node string, call domain.ToolCall, result domain.ToolResult, d time.Duration) {
+	if result.ID == "" {
+		result.ID = call.ID
+	}
+	r.record(transcript.Entry{Kind: transcript.KindTool, Node: node, Tool: call.Name, Result: &result, DurationMS: d.Milliseconds()})
+}
+
+// recordToolError records a call that failed to execute, so the transcript shows what the
+// run ended on.
+func (r *Runner) recordToolError(node string, call domain.ToolCall, err error, d time.Duration) {
+	r.recordTool(node, call, domain.ToolResult{ID: call.ID, IsError: true, Error: err.Error()}, d)
+}
+
+// recordEnd closes the transcript with the final state and the error the run ended with.
+func (r *Runner) recordEnd(err error) {
+	if r.Transcript == nil || !r.started {
+		return
+	}
+	e := transcript.Entry{Kind: transcript.KindEnd}
+	if state := r.finalState; state != nil {
+		e.Node, e.Status, e.State = state.CurrentNodeID, state.Status, state.Snapshot()
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	r.record(e)
+}
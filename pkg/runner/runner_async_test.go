@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acceptingToolRunner starts every call in the "background" and returns immediately.
+type acceptingToolRunner struct {
+	calls []domain.ToolCall
+}
+
+func (a *acceptingToolRunner) Execute(ctx context.Context, call domain.ToolCall) (domain.ToolResult, error) {
+	a.calls = append(a.calls, call)
+	return domain.ToolResult{ID: call.ID, Accepted: true, Handle: "job-" + call.ID}, nil
+}
+
+func TestRunner_AsyncToolSuspendsSession(t *testing.T) {
+	loader := memory.NewLoader(map[string]string{
+		"start": `{"id": "start", "do": {"id": "deploy", "name": "deploy"}, "save_to": "deploy", "transitions": [{"to_node_id": "done"}]}`,
+		"done":  `{"id": "done", "type": "text"}`,
+	})
+	engine, _ := trellis.New("", trellis.WithLoader(loader))
+	store := memory.NewStore()
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
+
+	initial, err := engine.Start(ctx, "job", nil)
+	require.NoError(t, err)
+
+	tr := &acceptingToolRunner{}
+	r := NewRunner(
+		WithInputHandler(&MockToolHandler{}),
+		WithHeadless(true),
+		WithEngine(engine),
+		WithToolRunner(tr),
+		WithStore(store),
+		WithSessionID("job"),
+		WithInitialState(initial),
+	)
+	require.NoError(t, r.Run(ctx))
+
+	// The runner released the session while the job runs elsewhere.
+	require.Len(t, tr.calls, 1)
+	saved, err := store.Load(ctx, "job")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusWaitingForTool, saved.Status)
+	assert.True(t, saved.AwaitingAsync())
+
+	// Resuming the runner does not fire the call again.
+	r = NewRunner(
+		WithInputHandler(&MockToolHandler{}),
+		WithHeadless(true),
+		WithEngine(engine),
+		WithToolRunner(tr),
+		WithStore(store),
+		WithSessionID("job"),
+		WithInitialState(saved),
+	)
+	require.NoError(t, r.Run(ctx))
+	assert.Len(t, tr.calls, 1)
+
+	// The callback completes the call and the flow moves on.
+	mgr := session.NewManager(store, session.WithEngine(engine))
+	state, err := mgr.CompleteTool(ctx, "job", tr.calls[0].IdempotencyKey, domain.ToolResult{ID: "deploy", Result: "live"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", state.CurrentNodeID)
+	assert.Equal(t, "live", state.Context["deploy"])
+}
@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// barrierToolRunner only completes once every expected call is in flight,
+// proving that batch calls are executed concurrently.
+type barrierToolRunner struct {
+	mu       sync.Mutex
+	expected int
+	arrived  int
+	release  chan struct{}
+}
+
+func (b *barrierToolRunner) Execute(ctx context.Context, call domain.ToolCall) (domain.ToolResult, error) {
+	b.mu.Lock()
+	b.arrived++
+	if b.arrived == b.expected {
+		close(b.release)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.release:
+	case <-time.After(time.Second):
+		return domain.ToolResult{ID: call.ID, IsError: true, Error: "calls were not concurrent"}, nil
+	}
+	return domain.ToolResult{ID: call.ID, Result: call.Name + "_ok"}, nil
+}
+
+func TestRunner_BatchExecutesConcurrently(t *testing.T) {
+	loader := memory.NewLoader(map[string]string{
+		"start":  `{"id": "start", "do": [{"name": "a"}, {"name": "b"}, {"name": "c"}], "save_to": "out", "on_error": "failed", "transitions": [{"to_node_id": "done"}]}`,
+		"done":   `{"id": "done", "type": "text"}`,
+		"failed": `{"id": "failed", "type": "text"}`,
+	})
+	engine, _ := trellis.New("", trellis.WithLoader(loader))
+
+	tr := &barrierToolRunner{expected: 3, release: make(chan struct{})}
+	r := NewRunner(
+		WithInputHandler(&MockToolHandler{}),
+		WithHeadless(true),
+		WithEngine(engine),
+		WithToolRunner(tr),
+	)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 3*time.Second)
+	defer cancel()
+	require.NoError(t, r.Run(ctx))
+
+	final := r.State()
+	assert.Equal(t, "done", final.CurrentNodeID)
+	out := final.Context["out"].(map[string]any)
+	assert.Equal(t, "b_ok", out["b"].(map[string]any)["result"])
+}
+
+func TestRunner_BatchInterceptorDeniesSingleCall(t *testing.T) {
+	loader := memory.NewLoader(map[string]string{
+		"start": `{"id": "start", "do": [{"name": "safe"}, {"name": "rm_rf"}], "batch_policy": "continue", "transitions": [{"to_node_id": "done"}]}`,
+		"done":  `{"id": "done", "type": "text"}`,
+	})
+	engine, _ := trellis.New("", trellis.WithLoader(loader))
+
+	handler := &MockToolHandler{Tools: map[string]func(map[string]any) (any, error){
+		"safe":  func(map[string]any) (any, error) { return "ok", nil },
+		"rm_rf": func(map[string]any) (any, error) { return "boom", nil },
+	}}
+	deny := func(ctx context.Context, call domain.ToolCall) (bool, domain.ToolResult, error) {
+		if call.Name == "rm_rf" {
+			return false, domain.ToolResult{ID: call.ID, IsDenied: true, Error: "nope"}, nil
+		}
+		return true, domain.ToolResult{}, nil
+	}
+
+	r := NewRunner(
+		WithInputHandler(handler),
+		WithHeadless(true),
+		WithEngine(engine),
+		WithInterceptor(deny),
+	)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, r.Run(ctx))
+
+	assert.Equal(t, []string{"safe"}, handler.Calls)
+	final := r.State()
+	assert.Equal(t, "done", final.CurrentNodeID)
+	results := final.Context["tool_results"].(map[string]any)
+	assert.Equal(t, true, results["rm_rf"].(map[string]any)["_denied"])
+}
@@ -24,9 +24,18 @@ var (
 
 // SanitizeInput cleans user input by enforcing size limits,
 // validating UTF-8, and stripping dangerous control characters.
+// The size limit is TRELLIS_MAX_INPUT_SIZE, or DefaultMaxInputSize.
 func SanitizeInput(input string) (string, error) {
+	return SanitizeInputLimit(input, 0)
+}
+
+// SanitizeInputLimit is SanitizeInput with an explicit size limit in bytes, such as the
+// manifest `server.max_input_size`. Zero or less uses the SanitizeInput limit.
+func SanitizeInputLimit(input string, limit int) (string, error) {
 	// 1. Enforce Size Limit
-	limit := getMaxInputSize()
+	if limit <= 0 {
+		limit = getMaxInputSize()
+	}
 	if len(input) > limit {
 		// We explicitly reject rather than truncate to ensure deterministic state.
 		return "", fmt.Errorf("%w: size=%d limit=%d", ErrInputTooLarge, len(input), limit)
@@ -78,6 +78,20 @@ func TestSanitizeInput_EnvOverride(t *testing.T) {
 	}
 }
 
+func TestSanitizeInputLimit_ExplicitLimit(t *testing.T) {
+	// An explicit limit (manifest server.max_input_size) replaces the default
+	if _, err := SanitizeInputLimit("12345678901", 10); err == nil {
+		t.Error("Expected error for input > explicit limit 10")
+	}
+	if _, err := SanitizeInputLimit(strings.Repeat("a", 5000), 8192); err != nil {
+		t.Errorf("Unexpected error under explicit limit: %v", err)
+	}
+	// Zero falls back to the default
+	if _, err := SanitizeInputLimit(strings.Repeat("a", 5000), 0); err == nil {
+		t.Error("Expected error for input > default limit")
+	}
+}
+
 func TestSanitizeInput_InvalidUTF8(t *testing.T) {
 	// Invalid UTF-8 sequence
 	input := "\xbd\xb2\x3d\xbc\x20\xe2\x8c\x98"
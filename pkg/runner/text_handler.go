@@ -20,6 +20,8 @@ type TextHandler struct {
 	Writer   io.Writer
 	Renderer ContentRenderer
 	Registry *registry.Registry
+	// MaxInputSize limits each answer in bytes (0 uses the SanitizeInput default).
+	MaxInputSize int
 
 	inputChan chan inputResult
 	buffer    int
@@ -47,6 +49,13 @@ func WithTextHandlerRenderer(renderer ContentRenderer) TextHandlerOption {
 	}
 }
 
+// WithTextMaxInputSize limits each answer in bytes (see SanitizeInputLimit).
+func WithTextMaxInputSize(size int) TextHandlerOption {
+	return func(h *TextHandler) {
+		h.MaxInputSize = size
+	}
+}
+
 // WithTextInputBufferSize sets the size of the input buffer.
 func WithTextInputBufferSize(size int) TextHandlerOption {
 	return func(h *TextHandler) {
@@ -140,7 +149,7 @@ func (h *TextHandler) Input(ctx context.Context) (string, error) {
 			text := strings.TrimSpace(res.text)
 
 			// Sanitize Input (Security & Consistency)
-			clean, err := SanitizeInput(text)
+			clean, err := SanitizeInputLimit(text, h.MaxInputSize)
 			if err != nil {
 				// For text/interactive handler, we provide feedback and retry.
 				fmt.Fprintf(h.Writer, "Error: %v. Please try again.\n", err)
@@ -0,0 +1,272 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// CompleteTool delivers the result of a tool call that was accepted for background
+// execution. The result is matched by call ID and idempotency key; unknown, duplicate
+// and late results are rejected without touching the session.
+// On success the engine resumes the flow and the new state is persisted.
+func (m *Manager) CompleteTool(ctx context.Context, sessionID, idempotencyKey string, result domain.ToolResult) (*domain.State, error) {
+	if result.Accepted {
+		return nil, fmt.Errorf("completion for call %s cannot be another acceptance", result.ID)
+	}
+	return m.resolveAsync(ctx, sessionID, func(state *domain.State) (domain.ToolResult, error) {
+		call, ok := state.AsyncCall(result.ID)
+		if !ok || !state.IsPending(result.ID) {
+			return result, fmt.Errorf("call %s: %w", result.ID, domain.ErrAsyncCallNotFound)
+		}
+		if idempotencyKey == "" || idempotencyKey != call.IdempotencyKey {
+			return result, fmt.Errorf("call %s: %w", result.ID, domain.ErrIdempotencyMismatch)
+		}
+		if call.Expired(m.now()) {
+			return result, fmt.Errorf("call %s: %w", result.ID, domain.ErrAsyncDeadlineExceeded)
+		}
+		return result, nil
+	})
+}
+
+// CancelTool aborts a call awaiting a background result. The flow resumes as if the
+// tool had failed, so on_error (or SAGA rollback) applies.
+func (m *Manager) CancelTool(ctx context.Context, sessionID, callID, reason string) (*domain.State, error) {
+	return m.resolveAsync(ctx, sessionID, func(state *domain.State) (domain.ToolResult, error) {
+		if _, ok := state.AsyncCall(callID); !ok || !state.IsPending(callID) {
+			return domain.ToolResult{}, fmt.Errorf("call %s: %w", callID, domain.ErrAsyncCallNotFound)
+		}
+		msg := "cancelled"
+		if reason != "" {
+			msg = "cancelled: " + reason
+		}
+		return domain.ToolResult{ID: callID, IsError: true, Error: msg}, nil
+	})
+}
+
+// ExpireTools resolves every async call whose deadline has passed.
+// The node's "timeout" signal handler (on_timeout) takes precedence; otherwise the
+// call fails with a deadline error. It returns the current state (unchanged if nothing expired).
+func (m *Manager) ExpireTools(ctx context.Context, sessionID string) (*domain.State, error) {
+	if m.engine == nil {
+		return nil, errors.New("session manager has no engine configured (use WithEngine)")
+	}
+
+	var state *domain.State
+	err := m.WithLock(ctx, sessionID, func(ctx context.Context) error {
+		var err error
+		if state, err = m.load(ctx, sessionID); err != nil {
+			return err
+		}
+
+		now := m.now()
+		expired := 0
+		for _, call := range append([]domain.AsyncCall(nil), state.AsyncCalls...) {
+			// A previous expiry may already have moved the flow past this call.
+			current, ok := state.AsyncCall(call.ID)
+			if !ok || !current.Expired(now) || !state.IsPending(call.ID) {
+				continue
+			}
+
+			next, err := m.engine.Signal(ctx, state, domain.SignalTimeout)
+			if errors.Is(err, domain.ErrUnhandledSignal) {
+				next, err = m.engine.Navigate(ctx, state, domain.ToolResult{
+					ID:      call.ID,
+					IsError: true,
+					Error:   domain.ErrAsyncDeadlineExceeded.Error(),
+				})
+			}
+			if err != nil {
+				return fmt.Errorf("failed to expire call %s: %w", call.ID, err)
+			}
+			state = next
+			expired++
+		}
+		if expired == 0 {
+			m.track(sessionID, state) // Resolved elsewhere or not due yet
+			return nil
+		}
+		return m.save(ctx, sessionID, state)
+	})
+	return state, err
+}
+
+// ExpireAll runs ExpireTools on every session with an expired async call, so deadlines
+// apply even when no Host ever comes back. Only the sessions in the deadline index are
+// visited; the first sweep seeds the index from the store, covering sessions saved before
+// this Manager. It returns the number of sessions that were resumed; failures are logged
+// and do not stop the sweep.
+func (m *Manager) ExpireAll(ctx context.Context) (int, error) {
+	if err := m.seedDeadlines(ctx); err != nil {
+		return 0, err
+	}
+	resumed := 0
+	for _, id := range m.dueSessions(m.now()) {
+		if _, err := m.ExpireTools(ctx, id); err != nil {
+			if errors.Is(err, domain.ErrSessionNotFound) || errors.Is(err, domain.ErrRevisionMismatch) {
+				m.track(id, nil) // Gone, or left to a host serving its revision
+				continue
+			}
+			m.logger.Warn("Failed to expire async tool calls", "session_id", id, "err", err)
+			continue
+		}
+		resumed++
+	}
+	return resumed, nil
+}
+
+// Advance navigates a session under its lock and saves the result, so CompleteTool,
+// CancelTool and ExpireTools see the calls it accepted. navigate runs from the stored
+// state when the session exists; the state held by the client only starts sessions the
+// store does not know yet, so a client cannot overwrite a persisted session (or its
+// SystemContext, such as the usage ledger). Results for calls awaiting async completion
+// are rejected: they must go through CompleteTool.
+func (m *Manager) Advance(ctx context.Context, state *domain.State, input any, navigate func(ctx context.Context, from *domain.State) (*domain.State, error)) (*domain.State, error) {
+	var next *domain.State
+	err := m.WithLock(ctx, state.SessionID, func(ctx context.Context) error {
+		from, err := m.load(ctx, state.SessionID)
+		switch {
+		case errors.Is(err, domain.ErrSessionNotFound):
+			if err := m.checkRevision(state); err != nil {
+				return err
+			}
+			from = state
+		case err != nil:
+			return err
+		}
+		if err := CheckResults(from, input); err != nil {
+			return err
+		}
+		if next, err = navigate(ctx, from); err != nil {
+			return err
+		}
+		m.pin(next)
+		return m.save(ctx, state.SessionID, next)
+	})
+	return next, err
+}
+
+// CheckResults rejects tool results (domain.ToolResult or []domain.ToolResult) that
+// answer calls awaiting async completion in state. Other input passes.
+func CheckResults(state *domain.State, input any) error {
+	var results []domain.ToolResult
+	switch v := input.(type) {
+	case domain.ToolResult:
+		results = []domain.ToolResult{v}
+	case []domain.ToolResult:
+		results = v
+	}
+	for _, r := range results {
+		if _, ok := state.AsyncCall(r.ID); ok && state.IsPending(r.ID) {
+			return fmt.Errorf("call %s: %w", r.ID, domain.ErrAsyncCompletionRequired)
+		}
+	}
+	return nil
+}
+
+// save persists state and keeps the deadline index in sync. Callers hold the session lock.
+func (m *Manager) save(ctx context.Context, sessionID string, state *domain.State) error {
+	if err := m.store.Save(ctx, sessionID, state); err != nil {
+		return err
+	}
+	m.track(sessionID, state)
+	return nil
+}
+
+// track records the earliest deadline of the pending async calls of state, or drops the
+// session from the index when it has none (or state is nil).
+func (m *Manager) track(sessionID string, state *domain.State) {
+	deadline, ok := nextDeadline(state)
+	m.deadlinesMu.Lock()
+	defer m.deadlinesMu.Unlock()
+	if ok {
+		m.deadlines[sessionID] = deadline
+	} else {
+		delete(m.deadlines, sessionID)
+	}
+}
+
+// seedDeadlines indexes the sessions already in the store, once per Manager.
+func (m *Manager) seedDeadlines(ctx context.Context) error {
+	m.deadlinesMu.Lock()
+	indexed := m.indexed
+	m.deadlinesMu.Unlock()
+	if indexed {
+		return nil
+	}
+
+	ids, err := m.store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for _, id := range ids {
+		if state, err := m.store.Load(ctx, id); err == nil {
+			m.track(id, state)
+		}
+	}
+	m.deadlinesMu.Lock()
+	m.indexed = true
+	m.deadlinesMu.Unlock()
+	return nil
+}
+
+// dueSessions lists the indexed sessions with a deadline passed at now.
+func (m *Manager) dueSessions(now time.Time) []string {
+	m.deadlinesMu.Lock()
+	defer m.deadlinesMu.Unlock()
+	var due []string
+	for id, deadline := range m.deadlines {
+		if now.After(deadline) {
+			due = append(due, id)
+		}
+	}
+	sort.Strings(due)
+	return due
+}
+
+// nextDeadline returns the earliest deadline among the pending async calls of state.
+func nextDeadline(state *domain.State) (time.Time, bool) {
+	var next time.Time
+	found := false
+	if state == nil {
+		return next, false
+	}
+	for _, call := range state.AsyncCalls {
+		if call.Deadline == nil || !state.IsPending(call.ID) {
+			continue
+		}
+		if !found || call.Deadline.Before(next) {
+			next, found = *call.Deadline, true
+		}
+	}
+	return next, found
+}
+
+// resolveAsync loads the session under lock, builds the result to deliver and navigates.
+func (m *Manager) resolveAsync(ctx context.Context, sessionID string, build func(*domain.State) (domain.ToolResult, error)) (*domain.State, error) {
+	if m.engine == nil {
+		return nil, errors.New("session manager has no engine configured (use WithEngine)")
+	}
+
+	var next *domain.State
+	err := m.WithLock(ctx, sessionID, func(ctx context.Context) error {
+		state, err := m.load(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+		result, err := build(state)
+		if err != nil {
+			return err
+		}
+		next, err = m.engine.Navigate(ctx, state, result)
+		if err != nil {
+			return fmt.Errorf("failed to resume session %s: %w", sessionID, err)
+		}
+		return m.save(ctx, sessionID, next)
+	})
+	return next, err
+}
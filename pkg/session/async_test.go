@@ -0,0 +1,263 @@
+package session_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/ports"
+	"github.com/aretw0/trellis/pkg/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAsyncSession(t *testing.T, nodes map[string]string, now func() time.Time) (*session.Manager, string, domain.AsyncCall) {
+	t.Helper()
+	ctx := context.Background()
+
+	engine, err := trellis.New("", trellis.WithLoader(memory.NewLoader(nodes)))
+	require.NoError(t, err)
+
+	store := memory.NewStore()
+	mgr := session.NewManager(store, session.WithEngine(engine), session.WithClock(now))
+
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+	state, err = engine.Navigate(ctx, state, domain.ToolResult{ID: "build", Accepted: true, Handle: "job-1"})
+	require.NoError(t, err)
+	require.NoError(t, store.Save(ctx, "s1", state))
+
+	call, ok := state.AsyncCall("build")
+	require.True(t, ok)
+	return mgr, "s1", call
+}
+
+var asyncNodes = map[string]string{
+	"start":  `{"id": "start", "do": {"id": "build", "name": "ci"}, "timeout": "1h", "on_error": "failed", "transitions": [{"to_node_id": "done"}]}`,
+	"done":   `{"id": "done", "type": "text"}`,
+	"failed": `{"id": "failed", "type": "text"}`,
+}
+
+func TestManager_CompleteTool(t *testing.T) {
+	ctx := context.Background()
+	mgr, id, call := setupAsyncSession(t, asyncNodes, time.Now)
+
+	_, err := mgr.CompleteTool(ctx, id, "wrong-key", domain.ToolResult{ID: "build", Result: "ok"})
+	assert.ErrorIs(t, err, domain.ErrIdempotencyMismatch)
+
+	_, err = mgr.CompleteTool(ctx, id, call.IdempotencyKey, domain.ToolResult{ID: "other", Result: "ok"})
+	assert.ErrorIs(t, err, domain.ErrAsyncCallNotFound)
+
+	state, err := mgr.CompleteTool(ctx, id, call.IdempotencyKey, domain.ToolResult{ID: "build", Result: "ok"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", state.CurrentNodeID)
+
+	persisted, err := mgr.Load(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "done", persisted.CurrentNodeID)
+
+	// Duplicate delivery is rejected and leaves the session untouched.
+	_, err = mgr.CompleteTool(ctx, id, call.IdempotencyKey, domain.ToolResult{ID: "build", Result: "ok"})
+	assert.ErrorIs(t, err, domain.ErrAsyncCallNotFound)
+}
+
+func TestManager_CompleteTool_Late(t *testing.T) {
+	ctx := context.Background()
+	later := func() time.Time { return time.Now().Add(2 * time.Hour) }
+	mgr, id, call := setupAsyncSession(t, asyncNodes, later)
+
+	_, err := mgr.CompleteTool(ctx, id, call.IdempotencyKey, domain.ToolResult{ID: "build", Result: "ok"})
+	assert.ErrorIs(t, err, domain.ErrAsyncDeadlineExceeded)
+
+	// Expiry fails the call: no on_timeout, so on_error applies.
+	state, err := mgr.ExpireTools(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", state.CurrentNodeID)
+	assert.Empty(t, state.AsyncCalls)
+}
+
+func TestManager_ExpireTools_Signal(t *testing.T) {
+	ctx := context.Background()
+	nodes := map[string]string{
+		"start":   `{"id": "start", "do": {"id": "build", "name": "ci"}, "timeout": "1h", "on_signal": {"timeout": "timeout"}, "transitions": [{"to_node_id": "done"}]}`,
+		"done":    `{"id": "done", "type": "text"}`,
+		"timeout": `{"id": "timeout", "type": "text"}`,
+	}
+
+	clock := time.Now()
+	mgr, id, _ := setupAsyncSession(t, nodes, func() time.Time { return clock })
+
+	state, err := mgr.ExpireTools(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "start", state.CurrentNodeID, "nothing expired yet")
+
+	clock = clock.Add(2 * time.Hour)
+	state, err = mgr.ExpireTools(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "timeout", state.CurrentNodeID)
+}
+
+func TestManager_CancelTool(t *testing.T) {
+	ctx := context.Background()
+	mgr, id, _ := setupAsyncSession(t, asyncNodes, time.Now)
+
+	state, err := mgr.CancelTool(ctx, id, "build", "superseded")
+	require.NoError(t, err)
+	assert.Equal(t, "failed", state.CurrentNodeID)
+
+	_, err = mgr.CancelTool(ctx, id, "build", "")
+	assert.ErrorIs(t, err, domain.ErrAsyncCallNotFound)
+}
+
+func TestManager_ExpireAll(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Now()
+	mgr, id, _ := setupAsyncSession(t, asyncNodes, func() time.Time { return clock })
+	require.NoError(t, mgr.Save(ctx, "idle", domain.NewState("idle", "done")))
+
+	n, err := mgr.ExpireAll(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n, "nothing expired yet")
+
+	clock = clock.Add(2 * time.Hour)
+	n, err = mgr.ExpireAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	state, err := mgr.Load(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", state.CurrentNodeID)
+}
+
+// countingStore counts the sessions loaded from the wrapped store.
+type countingStore struct {
+	ports.StateStore
+	loads int
+}
+
+func (s *countingStore) Load(ctx context.Context, id string) (*domain.State, error) {
+	s.loads++
+	return s.StateStore.Load(ctx, id)
+}
+
+func TestManager_ExpireAllSweepsIndexedSessions(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Now()
+	engine, err := trellis.New("", trellis.WithLoader(memory.NewLoader(asyncNodes)))
+	require.NoError(t, err)
+	store := &countingStore{StateStore: memory.NewStore()}
+	mgr := session.NewManager(store, session.WithEngine(engine), session.WithClock(func() time.Time { return clock }))
+
+	// The first sweep seeds the index from the store.
+	for _, id := range []string{"idle-1", "idle-2", "idle-3"} {
+		require.NoError(t, store.Save(ctx, id, domain.NewState(id, "done")))
+	}
+	_, err = mgr.ExpireAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, store.loads)
+
+	// Later sweeps only visit sessions with a pending deadline, saved through the manager.
+	state, err := engine.Start(ctx, "s1", nil)
+	require.NoError(t, err)
+	_, err = mgr.Advance(ctx, state, domain.ToolResult{ID: "build", Accepted: true}, func(ctx context.Context, from *domain.State) (*domain.State, error) {
+		return engine.Navigate(ctx, from, domain.ToolResult{ID: "build", Accepted: true})
+	})
+	require.NoError(t, err)
+	store.loads = 0
+	n, err := mgr.ExpireAll(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Zero(t, store.loads, "nothing is due")
+
+	clock = clock.Add(2 * time.Hour)
+	n, err = mgr.ExpireAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, store.loads)
+
+	// Resolved sessions leave the index.
+	store.loads = 0
+	_, err = mgr.ExpireAll(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, store.loads)
+}
+
+func TestManager_Advance(t *testing.T) {
+	ctx := context.Background()
+	mgr, id, call := setupAsyncSession(t, asyncNodes, time.Now)
+	stored, err := mgr.Load(ctx, id)
+	require.NoError(t, err)
+
+	navigated := false
+	navigate := func(context.Context, *domain.State) (*domain.State, error) {
+		navigated = true
+		return stored, nil
+	}
+
+	// A result for the accepted call must go through CompleteTool, even when the client
+	// sends a state that forgot about it.
+	stale := domain.NewState(id, "start")
+	_, err = mgr.Advance(ctx, stale, domain.ToolResult{ID: "build", Result: "ok"}, navigate)
+	assert.ErrorIs(t, err, domain.ErrAsyncCompletionRequired)
+	_, err = mgr.Advance(ctx, stored, []domain.ToolResult{{ID: "build", Result: "ok"}}, navigate)
+	assert.ErrorIs(t, err, domain.ErrAsyncCompletionRequired)
+	assert.False(t, navigated)
+
+	// A stored session navigates from the stored state, whatever the client sends.
+	forged := stored.Snapshot()
+	forged.CurrentNodeID = "elsewhere"
+	forged.SystemContext[domain.SysKeyUsage] = map[string]any{}
+	var from *domain.State
+	_, err = mgr.Advance(ctx, forged, "hello", func(_ context.Context, base *domain.State) (*domain.State, error) {
+		from = base
+		return base, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, stored.CurrentNodeID, from.CurrentNodeID)
+	assert.Equal(t, stored.SystemContext, from.SystemContext)
+
+	// Other input navigates and saves, so a new session becomes visible to CompleteTool.
+	fresh := domain.NewState("s2", "start")
+	_, err = mgr.Advance(ctx, fresh, "hello", func(_ context.Context, base *domain.State) (*domain.State, error) {
+		assert.Same(t, fresh, base)
+		return stored.Snapshot(), nil
+	})
+	require.NoError(t, err)
+	saved, err := mgr.Load(ctx, "s2")
+	require.NoError(t, err)
+	_, ok := saved.AsyncCall(call.ID)
+	assert.True(t, ok)
+}
+
+func TestManager_Revision(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	mgr := session.NewManager(store, session.WithRevision("abc"))
+	navigate := func(_ context.Context, base *domain.State) (*domain.State, error) {
+		return base.Snapshot(), nil
+	}
+
+	// New sessions are saved pinned to the served revision.
+	started, err := mgr.LoadOrStart(ctx, "new", "start")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", started.Revision())
+	_, err = mgr.Advance(ctx, domain.NewState("client", "start"), "hi", navigate)
+	require.NoError(t, err)
+	saved, err := store.Load(ctx, "client")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", saved.Revision())
+
+	// Sessions that started on another revision are not resumed on this one.
+	other := domain.NewState("old", "start")
+	other.SystemContext[domain.SysKeyRevision] = "def"
+	require.NoError(t, store.Save(ctx, "old", other))
+	_, err = mgr.Advance(ctx, other, "hi", navigate)
+	assert.ErrorIs(t, err, domain.ErrRevisionMismatch)
+	forged := domain.NewState("forged", "start")
+	forged.SystemContext[domain.SysKeyRevision] = "def"
+	_, err = mgr.Advance(ctx, forged, "hi", navigate)
+	assert.ErrorIs(t, err, domain.ErrRevisionMismatch)
+}
@@ -30,6 +30,14 @@ type Manager struct {
 
 	locker ports.DistributedLocker // Optional distributed locker
 	logger *slog.Logger            // Logger for internal events (like deferred errors)
+
+	engine   ports.StatelessEngine // Optional engine, required to complete async tool calls
+	now      func() time.Time      // Clock used for async deadlines
+	revision string                // Graph revision sessions are pinned to (see WithRevision)
+
+	deadlinesMu sync.Mutex
+	deadlines   map[string]time.Time // Earliest pending async deadline by session (see ExpireAll)
+	indexed     bool                 // Whether deadlines covers the sessions stored before this Manager
 }
 
 // Option configures the Manager.
@@ -49,12 +57,38 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
+// WithEngine enables async tool completion (CompleteTool, CancelTool, ExpireTools).
+func WithEngine(engine ports.StatelessEngine) Option {
+	return func(m *Manager) {
+		m.engine = engine
+	}
+}
+
+// WithClock overrides the clock used to check async deadlines (useful for tests).
+func WithClock(now func() time.Time) Option {
+	return func(m *Manager) {
+		m.now = now
+	}
+}
+
+// WithRevision pins sessions to the graph revision the host serves (e.g. the commit of
+// `trellis serve --rev`). New and unpinned sessions are saved with it, and sessions that
+// started on another revision are rejected with domain.ErrRevisionMismatch.
+func WithRevision(revision string) Option {
+	return func(m *Manager) {
+		m.revision = revision
+	}
+}
+
 // NewManager creates a new Session Manager with the given persistence store.
 func NewManager(store ports.StateStore, opts ...Option) *Manager {
 	m := &Manager{
 		store:  store,
 		locks:  make(map[string]*lockEntry),
 		logger: logging.NewNop(), // Default to no-op
+		now:    time.Now,
+
+		deadlines: make(map[string]time.Time),
 	}
 	for _, opt := range opts {
 		opt(m)
@@ -123,9 +157,10 @@ func (m *Manager) LoadOrStart(ctx context.Context, sessionID string, startNode s
 			startNode = "start"
 		}
 		state = domain.NewState(sessionID, startNode)
+		m.pin(state)
 
 		// Persist immediately to reserve the ID
-		if err := m.store.Save(ctx, sessionID, state); err != nil {
+		if err := m.save(ctx, sessionID, state); err != nil {
 			return fmt.Errorf("failed to initialize session: %w", err)
 		}
 		return nil
@@ -133,17 +168,53 @@ func (m *Manager) LoadOrStart(ctx context.Context, sessionID string, startNode s
 	return state, err
 }
 
+// checkRevision rejects a session pinned to another revision than the Manager's.
+func (m *Manager) checkRevision(state *domain.State) error {
+	pinned := state.Revision()
+	if m.revision == "" || pinned == "" || pinned == m.revision {
+		return nil
+	}
+	return fmt.Errorf("session %s is pinned to revision %s, not %s: %w", state.SessionID, pinned, m.revision, domain.ErrRevisionMismatch)
+}
+
+// pin records the Manager's revision in an unpinned session.
+func (m *Manager) pin(state *domain.State) {
+	if m.revision == "" || state.Revision() != "" {
+		return
+	}
+	if state.SystemContext == nil {
+		state.SystemContext = make(map[string]any)
+	}
+	state.SystemContext[domain.SysKeyRevision] = m.revision
+}
+
+// load reads a session from the store, rejecting sessions pinned to another revision.
+func (m *Manager) load(ctx context.Context, sessionID string) (*domain.State, error) {
+	state, err := m.store.Load(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.checkRevision(state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
 // Save persists the session state.
 func (m *Manager) Save(ctx context.Context, sessionID string, state *domain.State) error {
 	return m.WithLock(ctx, sessionID, func(ctx context.Context) error {
-		return m.store.Save(ctx, sessionID, state)
+		return m.save(ctx, sessionID, state)
 	})
 }
 
 // Delete removes the session from the store.
 func (m *Manager) Delete(ctx context.Context, sessionID string) error {
 	return m.WithLock(ctx, sessionID, func(ctx context.Context) error {
-		return m.store.Delete(ctx, sessionID)
+		if err := m.store.Delete(ctx, sessionID); err != nil {
+			return err
+		}
+		m.track(sessionID, nil)
+		return nil
 	})
 }
 
@@ -0,0 +1,320 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// Export formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Export writes t as a readable conversation log: markdown (the default) or html.
+func Export(w io.Writer, format string, t *Transcript) error {
+	switch format {
+	case "", FormatMarkdown, "md":
+		return Markdown(w, t)
+	case FormatHTML:
+		return HTML(w, t)
+	}
+	return fmt.Errorf("unknown format %q (use markdown or html)", format)
+}
+
+// Who speaks in a message of the log.
+const (
+	speakerFlow   = "flow"
+	speakerUser   = "user"
+	speakerTool   = "tool"
+	speakerSignal = "signal"
+)
+
+// message is one line of the conversation: what a node showed, what the user answered,
+// a tool call with its result, or a signal.
+type message struct {
+	Who      string
+	Node     string
+	Time     time.Time
+	Content  []string
+	Prompt   string // What the node waits for, e.g. "choice: basic, pro"
+	Tool     string
+	Args     string
+	Result   string
+	Failed   bool
+	Duration time.Duration
+}
+
+// conversation is the export model shared by the Markdown and HTML logs.
+type conversation struct {
+	Session  string
+	Started  time.Time
+	Ended    time.Time
+	Node     string
+	Status   domain.ExecutionStatus
+	Error    string
+	Partial  bool
+	Context  string // Final context as indented JSON
+	Messages []message
+}
+
+func (c conversation) Duration() time.Duration {
+	if c.Started.IsZero() || c.Ended.IsZero() {
+		return 0
+	}
+	return c.Ended.Sub(c.Started).Round(time.Millisecond)
+}
+
+func converse(t *Transcript) conversation {
+	c := conversation{Partial: t.Partial}
+	calls := make(map[string]int) // Call ID → message awaiting its result
+	for _, e := range t.Entries {
+		switch e.Kind {
+		case KindStart:
+			c.Started = e.Time
+			if e.State != nil {
+				c.Session = e.State.SessionID
+			}
+		case KindRender:
+			var content []string
+			for _, text := range e.Content {
+				if strings.TrimSpace(text) != "" {
+					content = append(content, text)
+				}
+			}
+			if len(content) > 0 || e.Prompt != nil || t.Partial {
+				c.Messages = append(c.Messages, message{Who: speakerFlow, Node: e.Node, Time: e.Time, Content: content, Prompt: describePrompt(e.Prompt)})
+			}
+			for _, call := range e.ToolCalls {
+				if _, shown := calls[call.ID]; shown {
+					continue // Re-rendered while waiting for the rest of a batch
+				}
+				args := ""
+				if len(call.Args) > 0 {
+					args = display(call.Args)
+				}
+				calls[call.ID] = len(c.Messages)
+				c.Messages = append(c.Messages, message{Who: speakerTool, Node: e.Node, Time: e.Time, Tool: call.Name, Args: args})
+			}
+		case KindInput:
+			text := ""
+			if e.Input != nil {
+				text = display(e.Input)
+			}
+			c.Messages = append(c.Messages, message{Who: speakerUser, Node: e.Node, Time: e.Time, Content: []string{text}, Duration: e.Duration()})
+		case KindSignal:
+			c.Messages = append(c.Messages, message{Who: speakerSignal, Node: e.Node, Time: e.Time, Content: []string{e.Signal}})
+		case KindTool:
+			if e.Result == nil {
+				continue
+			}
+			i, ok := calls[e.Result.ID]
+			if !ok || c.Messages[i].Result != "" {
+				i = len(c.Messages)
+				c.Messages = append(c.Messages, message{Who: speakerTool, Node: e.Node, Tool: e.Tool})
+			}
+			m := &c.Messages[i]
+			m.Duration = e.Duration()
+			switch {
+			case e.Result.IsDenied:
+				m.Result, m.Failed = "denied", true
+				if e.Result.Error != "" {
+					m.Result += ": " + e.Result.Error
+				}
+			case e.Result.IsError:
+				m.Result, m.Failed = "error: "+e.Result.Error, true
+			default:
+				m.Result = display(e.Result.Result)
+			}
+		case KindEnd:
+			c.Ended, c.Node, c.Status, c.Error = e.Time, e.Node, e.Status, e.Error
+			if e.State != nil && len(e.State.Context) > 0 {
+				if data, err := json.MarshalIndent(e.State.Context, "", "  "); err == nil {
+					c.Context = string(data)
+				}
+			}
+		}
+	}
+	return c
+}
+
+func describePrompt(p *domain.InputRequest) string {
+	if p == nil {
+		return ""
+	}
+	text := string(p.Type)
+	if text == "" {
+		text = string(domain.InputText)
+	}
+	if len(p.Options) > 0 {
+		text += ": " + strings.Join(p.Options, ", ")
+	}
+	if p.Default != "" {
+		text += " (default " + p.Default + ")"
+	}
+	if p.Timeout > 0 {
+		text += fmt.Sprintf(" (timeout %s)", p.Timeout)
+	}
+	return text
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02 15:04:05 UTC")
+}
+
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	if d >= time.Second {
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.String()
+}
+
+// Markdown writes t as a Markdown conversation log.
+func Markdown(w io.Writer, t *Transcript) error {
+	c := converse(t)
+	var b strings.Builder
+	b.WriteString("# Session")
+	if c.Session != "" {
+		fmt.Fprintf(&b, " `%s`", c.Session)
+	}
+	b.WriteString("\n\n")
+	if s := formatTime(c.Started); s != "" {
+		fmt.Fprintf(&b, "- **Started:** %s\n", s)
+	}
+	if s := formatTime(c.Ended); s != "" {
+		fmt.Fprintf(&b, "- **Ended:** %s", s)
+		if d := formatDuration(c.Duration()); d != "" {
+			fmt.Fprintf(&b, " (%s)", d)
+		}
+		b.WriteString("\n")
+	}
+	if c.Node != "" {
+		fmt.Fprintf(&b, "- **Final node:** `%s` (%s)\n", c.Node, c.Status)
+	}
+	if c.Error != "" {
+		fmt.Fprintf(&b, "- **Error:** %s\n", c.Error)
+	}
+	if c.Partial {
+		b.WriteString("\n> Rebuilt from a persisted session: only the visited nodes and the final state are known.\n")
+	}
+
+	b.WriteString("\n## Conversation\n")
+	for _, m := range c.Messages {
+		b.WriteString("\n")
+		switch m.Who {
+		case speakerFlow:
+			fmt.Fprintf(&b, "**Flow** · `%s`\n", m.Node)
+			for _, text := range m.Content {
+				fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(text))
+			}
+			if m.Prompt != "" {
+				fmt.Fprintf(&b, "\n_Waiting for input (%s)_\n", m.Prompt)
+			}
+		case speakerUser:
+			text := strings.Join(m.Content, "")
+			if text == "" {
+				text = "_(empty)_"
+			}
+			fmt.Fprintf(&b, "**User:** %s", text)
+			if d := formatDuration(m.Duration); d != "" {
+				fmt.Fprintf(&b, " _(%s)_", d)
+			}
+			b.WriteString("\n")
+		case speakerSignal:
+			fmt.Fprintf(&b, "**Signal:** `%s`\n", strings.Join(m.Content, ""))
+		case speakerTool:
+			fmt.Fprintf(&b, "**Tool** · `%s` · `%s`", m.Node, m.Tool)
+			if m.Args != "" {
+				fmt.Fprintf(&b, " `%s`", m.Args)
+			}
+			if m.Result != "" {
+				fmt.Fprintf(&b, " → `%s`", m.Result)
+			}
+			if d := formatDuration(m.Duration); d != "" {
+				fmt.Fprintf(&b, " _(%s)_", d)
+			}
+			b.WriteString("\n")
+		}
+	}
+	if c.Context != "" {
+		fmt.Fprintf(&b, "\n## Final Context\n\n```json\n%s\n```\n", c.Context)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// HTML writes t as a self-contained HTML conversation log.
+func HTML(w io.Writer, t *Transcript) error {
+	return htmlLog.Execute(w, converse(t))
+}
+
+var htmlLog = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time":     formatTime,
+	"duration": formatDuration,
+	"join":     strings.Join,
+	"trim":     strings.TrimSpace,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Session {{.Session}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #1f2328; }
+dl { display: grid; grid-template-columns: max-content auto; gap: .25rem 1rem; }
+dt { font-weight: 600; }
+.note { color: #59636e; font-style: italic; }
+.msg { margin: .75rem 0; padding: .6rem .9rem; border-radius: .6rem; }
+.flow { background: #f6f8fa; margin-right: 3rem; }
+.user { background: #ddf4ff; margin-left: 3rem; }
+.tool, .signal { background: #fff8c5; font-size: .9rem; }
+.failed { background: #ffebe9; }
+.meta { color: #59636e; font-size: .8rem; margin-bottom: .25rem; }
+.text { white-space: pre-wrap; }
+code, pre { font-family: ui-monospace, monospace; }
+pre { background: #f6f8fa; padding: 1rem; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>Session{{if .Session}} <code>{{.Session}}</code>{{end}}</h1>
+<dl>
+{{- with time .Started}}<dt>Started</dt><dd>{{.}}</dd>{{end}}
+{{- with time .Ended}}<dt>Ended</dt><dd>{{.}}{{with duration $.Duration}} ({{.}}){{end}}</dd>{{end}}
+{{- if .Node}}<dt>Final node</dt><dd><code>{{.Node}}</code> ({{.Status}})</dd>{{end}}
+{{- if .Error}}<dt>Error</dt><dd>{{.Error}}</dd>{{end}}
+</dl>
+{{- if .Partial}}
+<p class="note">Rebuilt from a persisted session: only the visited nodes and the final state are known.</p>
+{{- end}}
+<h2>Conversation</h2>
+{{- range .Messages}}
+{{- if eq .Who "flow"}}
+<div class="msg flow"><div class="meta">{{.Node}}{{with time .Time}} · {{.}}{{end}}</div>
+{{- range .Content}}<div class="text">{{trim .}}</div>{{end}}
+{{- if .Prompt}}<div class="note">Waiting for input ({{.Prompt}})</div>{{end}}</div>
+{{- else if eq .Who "user"}}
+<div class="msg user"><div class="meta">You{{with duration .Duration}} · after {{.}}{{end}}</div><div class="text">{{with join .Content ""}}{{.}}{{else}}<span class="note">(empty)</span>{{end}}</div></div>
+{{- else if eq .Who "signal"}}
+<div class="msg signal"><div class="meta">{{.Node}}</div>Signal <code>{{join .Content ""}}</code></div>
+{{- else}}
+<div class="msg tool{{if .Failed}} failed{{end}}"><div class="meta">{{.Node}}{{with duration .Duration}} · {{.}}{{end}}</div><code>{{.Tool}}</code>{{with .Args}} <code>{{.}}</code>{{end}}{{with .Result}} → <code>{{.}}</code>{{end}}</div>
+{{- end}}
+{{- end}}
+{{- if .Context}}
+<h2>Final Context</h2>
+<pre>{{.Context}}</pre>
+{{- end}}
+</body>
+</html>
+`))
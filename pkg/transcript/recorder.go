@@ -0,0 +1,60 @@
+package transcript
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Recorder writes entries as JSON Lines. It is safe for concurrent use.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	now func() time.Time
+	err error
+}
+
+// RecorderOption configures a Recorder.
+type RecorderOption func(*Recorder)
+
+// WithClock replaces time.Now for the entry times (e.g. in tests).
+func WithClock(now func() time.Time) RecorderOption {
+	return func(r *Recorder) {
+		r.now = now
+	}
+}
+
+// NewRecorder creates a Recorder writing to w.
+func NewRecorder(w io.Writer, opts ...RecorderOption) *Recorder {
+	r := &Recorder{enc: json.NewEncoder(w), now: time.Now}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Record writes an entry, stamping its time when unset. After a write fails, entries
+// are dropped and the first error is returned by every call (and by Err).
+func (r *Recorder) Record(e Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	if e.Time.IsZero() {
+		e.Time = r.now().UTC()
+	}
+	if e.Kind == KindStart && e.Version == 0 {
+		e.Version = Version
+	}
+	r.err = r.enc.Encode(e)
+	return r.err
+}
+
+// Err returns the first write error.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
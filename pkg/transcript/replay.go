@@ -0,0 +1,393 @@
+package transcript
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/ports"
+)
+
+// Fields a divergence is about.
+const (
+	FieldNode      = "node"       // The session is at another node
+	FieldStatus    = "status"     // The node now waits for tools, or no longer does
+	FieldContent   = "content"    // The rendered content changed
+	FieldPrompt    = "prompt"     // The node asks for input where it did not (or the opposite), or asks differently
+	FieldToolCalls = "tool_calls" // The node calls other tools, or with other arguments
+	FieldSignal    = "signal"     // A recorded signal is no longer handled
+	FieldError     = "error"      // The engine fails where it did not (or the opposite)
+	FieldEnd       = "end"        // The flow ends where it did not (or the opposite)
+	FieldContext   = "context"    // The final context differs
+)
+
+// Divergence is a point where the current graph no longer does what the transcript recorded.
+type Divergence struct {
+	Line    int    `json:"line"` // Line of the recorded entry
+	Node    string `json:"node"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Want    any    `json:"want,omitempty"`
+	Got     any    `json:"got,omitempty"`
+	// Fatal divergences stop the replay: the recorded moves no longer fit the flow.
+	Fatal bool `json:"fatal,omitempty"`
+}
+
+// Report is the outcome of a replay.
+type Report struct {
+	Entries     int          `json:"entries"`  // Entries in the transcript
+	Replayed    int          `json:"replayed"` // Entries the replay got through
+	Steps       int          `json:"steps"`    // Moves (inputs, signals, tool results, pass-throughs) replayed
+	Divergences []Divergence `json:"divergences"`
+}
+
+// OK reports whether the replay matched the transcript.
+func (r *Report) OK() bool {
+	return len(r.Divergences) == 0
+}
+
+// Replay runs the recorded session again against engine, from the recorded start state,
+// feeding the recorded inputs, signals and tool results (tools are never executed), and
+// compares every step with the transcript: node, status, content, prompts, tool calls,
+// errors, where the flow ends and the final context.
+func Replay(ctx context.Context, engine ports.StatelessEngine, t *Transcript) (*Report, error) {
+	if t.Partial {
+		return nil, fmt.Errorf("cannot replay a transcript rebuilt from a session: it holds no inputs")
+	}
+	start := t.Start()
+	if start.State == nil {
+		return nil, fmt.Errorf("line %d: start entry without state", start.Line())
+	}
+	rp := &replayer{engine: engine, report: &Report{Entries: len(t.Entries), Divergences: []Divergence{}}}
+	for i := range t.Entries {
+		if &t.Entries[i] == start {
+			rp.entries = t.Entries[i+1:]
+			rp.report.Replayed = i + 1
+			break
+		}
+	}
+	initial := *start.State
+	rp.run(ctx, &initial)
+	return rp.report, nil
+}
+
+type replayer struct {
+	engine  ports.StatelessEngine
+	entries []Entry
+	pos     int
+	last    *Entry // The last entry consumed
+	report  *Report
+}
+
+func (rp *replayer) peek() *Entry {
+	if rp.pos < len(rp.entries) {
+		return &rp.entries[rp.pos]
+	}
+	return nil
+}
+
+func (rp *replayer) next() *Entry {
+	e := rp.peek()
+	if e != nil {
+		rp.pos++
+		rp.report.Replayed++
+		rp.last = e
+	}
+	return e
+}
+
+func (rp *replayer) diverge(e *Entry, d Divergence) {
+	d.Line = e.Line()
+	if d.Node == "" {
+		d.Node = e.Node
+	}
+	rp.report.Divergences = append(rp.report.Divergences, d)
+}
+
+func (rp *replayer) run(ctx context.Context, state *domain.State) {
+	for {
+		e := rp.next()
+		if e == nil {
+			return // The recording stopped without an end entry (e.g. a crash)
+		}
+		if e.Kind == KindEnd {
+			rp.end(e, state)
+			return
+		}
+		if e.Kind != KindRender {
+			rp.diverge(e, Divergence{Field: FieldEnd, Message: fmt.Sprintf("unexpected %s entry: the transcript is out of order", e.Kind), Fatal: true})
+			return
+		}
+
+		actions, isTerminal, err := rp.engine.Render(ctx, state)
+		if err != nil {
+			rp.failed(e, state, err)
+			return
+		}
+		view := View(state, actions)
+		if !rp.compare(e, view) {
+			return
+		}
+
+		needsInput := view.Prompt != nil
+		move := rp.peek()
+		var input any
+		switch {
+		case state.Status == domain.StatusWaitingForTool || state.Status == domain.StatusRollingBack:
+			if move != nil && move.Kind == KindEnd {
+				rp.end(rp.next(), state)
+				return
+			}
+			results, ok := rp.results(e, state, view.ToolCalls)
+			if !ok {
+				return
+			}
+			input = results
+		case !needsInput:
+			if move != nil && (move.Kind == KindInput || move.Kind == KindSignal) {
+				rp.diverge(move, Divergence{Node: state.CurrentNodeID, Field: FieldPrompt, Message: "the node no longer asks for input, so the recorded " + string(move.Kind) + " cannot be replayed", Fatal: true})
+				return
+			}
+			input = "" // Pass-through, as the runner does
+		case move == nil || move.Kind == KindEnd:
+			// The recording stopped at the prompt (end of input, interrupt...)
+			rp.end(rp.next(), state)
+			return
+		case move.Kind == KindSignal:
+			rp.next()
+			next, err := rp.engine.Signal(ctx, state, move.Signal)
+			if err != nil {
+				rp.diverge(move, Divergence{Node: state.CurrentNodeID, Field: FieldSignal, Message: fmt.Sprintf("signal %q is no longer handled: %v", move.Signal, err), Fatal: true})
+				return
+			}
+			rp.report.Steps++
+			state = next
+			continue
+		case move.Kind == KindInput:
+			rp.next()
+			input = move.Input
+			if input == nil {
+				input = ""
+			}
+		default:
+			rp.diverge(e, Divergence{Node: state.CurrentNodeID, Field: FieldPrompt, Message: "the node now asks for input, which the recording has no answer for", Want: "no prompt", Got: view.Prompt, Fatal: true})
+			return
+		}
+
+		next, err := rp.engine.Navigate(ctx, state, input)
+		if err != nil {
+			rp.failed(rp.last, state, err)
+			return
+		}
+		rp.report.Steps++
+
+		if domain.FlowEnded(state, next, isTerminal, needsInput) {
+			if after := rp.peek(); after != nil && after.Kind != KindEnd {
+				rp.diverge(after, Divergence{Node: next.CurrentNodeID, Field: FieldEnd, Message: "the flow ends here, but the recording goes on", Fatal: true})
+				return
+			}
+			if end := rp.next(); end != nil {
+				rp.end(end, next)
+			}
+			return
+		}
+		state = next
+	}
+}
+
+// compare checks a render against the recorded one. It returns false on a fatal divergence.
+func (rp *replayer) compare(e *Entry, got Entry) bool {
+	if got.Node != e.Node {
+		rp.diverge(e, Divergence{Field: FieldNode, Message: fmt.Sprintf("the session is at %q instead of %q", got.Node, e.Node), Want: e.Node, Got: got.Node, Fatal: true})
+		return false
+	}
+	if got.Status != e.Status {
+		rp.diverge(e, Divergence{Field: FieldStatus, Message: fmt.Sprintf("the node is %s instead of %s", got.Status, e.Status), Want: e.Status, Got: got.Status, Fatal: true})
+		return false
+	}
+	if want, have := strings.TrimSpace(strings.Join(e.Content, "\n")), strings.TrimSpace(strings.Join(got.Content, "\n")); want != have {
+		rp.diverge(e, Divergence{Field: FieldContent, Message: "the content changed", Want: want, Got: have})
+	}
+	switch {
+	case (e.Prompt == nil) != (got.Prompt == nil):
+		// The recorded moves tell whether this matters (see run).
+	case e.Prompt != nil && !sameJSON(e.Prompt, got.Prompt):
+		rp.diverge(e, Divergence{Field: FieldPrompt, Message: "the prompt changed", Want: e.Prompt, Got: got.Prompt})
+	}
+
+	if want, have := callNames(e.ToolCalls), callNames(got.ToolCalls); want != have {
+		rp.diverge(e, Divergence{Field: FieldToolCalls, Message: "the node calls other tools", Want: want, Got: have, Fatal: true})
+		return false
+	}
+	for i, call := range got.ToolCalls {
+		if !sameJSON(e.ToolCalls[i].Args, call.Args) {
+			rp.diverge(e, Divergence{Field: FieldToolCalls, Message: fmt.Sprintf("tool %q is called with other arguments", call.Name), Want: e.ToolCalls[i].Args, Got: call.Args})
+		}
+	}
+	return true
+}
+
+// results consumes the recorded tool entries and answers the pending calls with them,
+// the way the runner would: a batch gets results in call order, a single call one result.
+func (rp *replayer) results(e *Entry, state *domain.State, calls []domain.ToolCall) (any, bool) {
+	var recorded []*Entry
+	for move := rp.peek(); move != nil && move.Kind == KindTool; move = rp.peek() {
+		recorded = append(recorded, rp.next())
+	}
+	used := make([]bool, len(recorded))
+	answer := func(call domain.ToolCall) (domain.ToolResult, bool) {
+		pick := -1
+		for i, r := range recorded {
+			if !used[i] && r.Tool == call.Name && r.Result != nil && (pick < 0 || r.Result.ID == call.ID) {
+				pick = i
+				if r.Result.ID == call.ID {
+					break
+				}
+			}
+		}
+		if pick < 0 {
+			rp.diverge(e, Divergence{Node: state.CurrentNodeID, Field: FieldToolCalls, Message: fmt.Sprintf("no recorded result for the call to %q", call.Name), Fatal: true})
+			return domain.ToolResult{}, false
+		}
+		used[pick] = true
+		result := *recorded[pick].Result
+		result.ID = call.ID
+		return result, true
+	}
+
+	if len(state.PendingToolCalls) > 0 {
+		var results []domain.ToolResult
+		for _, call := range calls {
+			if !state.IsPending(call.ID) {
+				continue
+			}
+			result, ok := answer(call)
+			if !ok {
+				return nil, false
+			}
+			results = append(results, result)
+		}
+		return results, true
+	}
+	for _, call := range calls {
+		if call.ID == state.PendingToolCall {
+			return answer(call)
+		}
+	}
+	rp.diverge(e, Divergence{Node: state.CurrentNodeID, Field: FieldToolCalls, Message: fmt.Sprintf("the session waits for call %q, which the node no longer makes", state.PendingToolCall), Fatal: true})
+	return nil, false
+}
+
+// failed handles an engine error during the replay: it is expected only when the
+// recording ended with an error at the same point.
+func (rp *replayer) failed(e *Entry, state *domain.State, err error) {
+	if end := rp.peek(); end != nil && end.Kind == KindEnd && end.Error != "" {
+		rp.next()
+		return
+	}
+	rp.diverge(e, Divergence{Node: state.CurrentNodeID, Field: FieldError, Message: "the engine fails: " + err.Error(), Got: err.Error(), Fatal: true})
+}
+
+// end compares where the replay stopped with the recorded end.
+func (rp *replayer) end(end *Entry, state *domain.State) {
+	if end == nil {
+		return
+	}
+	// An error recorded right after a move came from the engine: the replay moved past it.
+	if end.Error != "" && rp.pos >= 2 && rp.entries[rp.pos-2].Kind != KindRender {
+		rp.diverge(end, Divergence{Node: state.CurrentNodeID, Field: FieldError, Message: "the recorded error no longer happens", Want: end.Error})
+	}
+	if end.State == nil {
+		return
+	}
+	recordedEnd := end.State.Status == domain.StatusTerminated || end.State.Terminated
+	replayEnd := state.Status == domain.StatusTerminated || state.Terminated
+	if recordedEnd && !replayEnd {
+		rp.diverge(end, Divergence{Node: state.CurrentNodeID, Field: FieldEnd, Message: "the flow no longer ends here", Want: end.Node, Got: state.CurrentNodeID})
+	}
+	if !sameJSON(end.State.Context, state.Context) {
+		rp.diverge(end, Divergence{Node: state.CurrentNodeID, Field: FieldContext, Message: "the final context differs", Want: end.State.Context, Got: state.Context})
+	}
+}
+
+func callNames(calls []domain.ToolCall) string {
+	names := make([]string, len(calls))
+	for i, call := range calls {
+		names[i] = call.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// sameJSON compares values by their JSON form, so recorded values (decoded as float64,
+// map[string]any...) match the live ones.
+func sameJSON(a, b any) bool {
+	var x, y any
+	if !normalize(a, &x) || !normalize(b, &y) {
+		return reflect.DeepEqual(a, b)
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+func normalize(v any, out *any) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, out) == nil
+}
+
+// WriteReport prints a replay report: text (the default) or json.
+func WriteReport(w io.Writer, format string, r *Report) error {
+	switch format {
+	case "", "text":
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	default:
+		return fmt.Errorf("unknown format %q (use text or json)", format)
+	}
+
+	for _, d := range r.Divergences {
+		stopped := ""
+		if d.Fatal {
+			stopped = " (replay stopped)"
+		}
+		if _, err := fmt.Fprintf(w, "--- line %d at %s: %s%s\n", d.Line, d.Node, d.Message, stopped); err != nil {
+			return err
+		}
+		for _, side := range []struct {
+			label string
+			value any
+		}{{"want", d.Want}, {"got ", d.Got}} {
+			if side.value == nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "    %s: %s\n", side.label, strings.ReplaceAll(display(side.value), "\n", "\n          ")); err != nil {
+				return err
+			}
+		}
+	}
+	if r.OK() {
+		_, err := fmt.Fprintf(w, "ok: %d entries replayed, %d steps, no divergences\n", r.Replayed, r.Steps)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "FAIL: %d divergences, %d/%d entries replayed\n", len(r.Divergences), r.Replayed, r.Entries)
+	return err
+}
+
+// display formats a value for a report or a log: strings as they are, the rest as JSON.
+func display(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,175 @@
+// Package transcript records what happens in a session — rendered content, prompts,
+// inputs, signals, tool calls and their results, with timings — as JSON Lines, one
+// Entry per line. A transcript can be replayed against the current graph to find where
+// the flow changed (Replay), or exported as a readable conversation log (Markdown, HTML).
+//
+// The runner writes transcripts when given a Recorder:
+//
+//	f, _ := os.Create("run.jsonl")
+//	r := runner.NewRunner(runner.WithEngine(engine), runner.WithTranscript(transcript.NewRecorder(f)))
+//
+// Entries follow the runner loop: a start entry with the initial state; for each step a
+// render entry, then what moved the session on (input, signal or tool entries; nothing
+// for nodes that ask for nothing); and an end entry with the final state.
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aretw0/trellis/pkg/domain"
+)
+
+// Version is the transcript format written in start entries.
+const Version = 1
+
+// Kind identifies an entry.
+type Kind string
+
+const (
+	KindStart  Kind = "start"  // The session began (or resumed): State
+	KindRender Kind = "render" // A node was shown: Content, Prompt, ToolCalls
+	KindInput  Kind = "input"  // The user answered the prompt: Input, Duration (time to answer)
+	KindSignal Kind = "signal" // A signal moved the session: Signal
+	KindTool   Kind = "tool"   // A tool call returned: Tool, Result, Duration (execution time)
+	KindEnd    Kind = "end"    // The run stopped: State, Error
+)
+
+// Entry is one line of a transcript.
+type Entry struct {
+	Kind    Kind                   `json:"kind"`
+	Time    time.Time              `json:"time"`
+	Version int                    `json:"version,omitempty"`
+	Node    string                 `json:"node,omitempty"`
+	Status  domain.ExecutionStatus `json:"status,omitempty"`
+
+	// State is the full session state of start and end entries.
+	State *domain.State `json:"state,omitempty"`
+	// Error is why the run failed, for end entries.
+	Error string `json:"error,omitempty"`
+
+	Content   []string             `json:"content,omitempty"`
+	Prompt    *domain.InputRequest `json:"prompt,omitempty"`
+	ToolCalls []domain.ToolCall    `json:"tool_calls,omitempty"`
+
+	// Input is the answer of an input entry. An empty answer is omitted.
+	Input  any    `json:"input,omitempty"`
+	Signal string `json:"signal,omitempty"`
+
+	Tool   string             `json:"tool,omitempty"`
+	Result *domain.ToolResult `json:"result,omitempty"`
+
+	// DurationMS is the time the user took to answer (input) or the tool took to run (tool).
+	DurationMS int64 `json:"duration_ms,omitempty"`
+
+	line int // Line in the file it was read from
+}
+
+// Line returns the line of the transcript the entry was read from (0 when it was not read).
+func (e Entry) Line() int {
+	return e.line
+}
+
+// Duration returns DurationMS as a time.Duration.
+func (e Entry) Duration() time.Duration {
+	return time.Duration(e.DurationMS) * time.Millisecond
+}
+
+// Transcript is a recorded session.
+type Transcript struct {
+	Entries []Entry
+	// Partial is set for transcripts rebuilt from a persisted session (FromState): only
+	// the path and the final state are known.
+	Partial bool
+}
+
+// Start returns the start entry, or nil.
+func (t *Transcript) Start() *Entry {
+	for i := range t.Entries {
+		if t.Entries[i].Kind == KindStart {
+			return &t.Entries[i]
+		}
+	}
+	return nil
+}
+
+// End returns the last end entry, or nil when the run did not finish writing it.
+func (t *Transcript) End() *Entry {
+	for i := len(t.Entries) - 1; i >= 0; i-- {
+		if t.Entries[i].Kind == KindEnd {
+			return &t.Entries[i]
+		}
+	}
+	return nil
+}
+
+// View builds the render entry of a step from the state and the actions Render returned.
+func View(state *domain.State, actions []domain.ActionRequest) Entry {
+	v := domain.NewRenderView(actions)
+	return Entry{Kind: KindRender, Node: state.CurrentNodeID, Status: state.Status, Content: v.Content, Prompt: v.Prompt, ToolCalls: v.ToolCalls}
+}
+
+// Read parses a transcript in JSON Lines. Blank lines are skipped.
+func Read(r io.Reader) (*Transcript, error) {
+	t := &Transcript{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if e.Kind == "" {
+			return nil, fmt.Errorf("line %d: entry without kind", line)
+		}
+		e.line = line
+		t.Entries = append(t.Entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if t.Start() == nil {
+		return nil, fmt.Errorf("not a transcript: no start entry")
+	}
+	return t, nil
+}
+
+// Load reads the transcript at path.
+func Load(path string) (*Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// FromState rebuilds what a persisted session still knows: the nodes it visited and its
+// current state. Content, inputs and tool results are not kept by the stores.
+func FromState(state *domain.State) *Transcript {
+	t := &Transcript{Partial: true}
+	start := Entry{Kind: KindStart, Version: Version, State: &domain.State{SessionID: state.SessionID}}
+	if len(state.History) > 0 {
+		start.Node = state.History[0]
+	}
+	t.Entries = append(t.Entries, start)
+	for _, node := range state.History {
+		t.Entries = append(t.Entries, Entry{Kind: KindRender, Node: node})
+	}
+	t.Entries = append(t.Entries, Entry{Kind: KindEnd, Node: state.CurrentNodeID, Status: state.Status, State: state})
+	return t
+}
@@ -0,0 +1,253 @@
+package transcript_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/internal/testutils/flowtest"
+	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/runner"
+	"github.com/aretw0/trellis/pkg/transcript"
+)
+
+type fakeTools struct{}
+
+func (fakeTools) Execute(_ context.Context, call domain.ToolCall) (domain.ToolResult, error) {
+	return domain.ToolResult{ID: call.ID, Result: map[string]any{"status": "paid"}}, nil
+}
+
+type brokenTools struct{}
+
+func (brokenTools) Execute(context.Context, domain.ToolCall) (domain.ToolResult, error) {
+	return domain.ToolResult{}, errors.New("connection refused")
+}
+
+// record runs the flow through the runner, answering the prompts with inputs.
+func record(t *testing.T, engine *trellis.Engine, inputs ...string) *transcript.Transcript {
+	t.Helper()
+	tr, err := run(t, engine, fakeTools{}, inputs...)
+	require.NoError(t, err)
+	return tr
+}
+
+// run records a run with the given tools, returning the transcript and the run error.
+func run(t *testing.T, engine *trellis.Engine, tools runner.ToolRunner, inputs ...string) (*transcript.Transcript, error) {
+	t.Helper()
+	ctx := context.Background()
+	state, err := engine.Start(ctx, "s1", map[string]any{"name": "Ada", "amount": 10})
+	require.NoError(t, err)
+
+	handler := runner.NewTextHandler(io.Discard)
+	for _, input := range inputs {
+		handler.FeedInput(input, nil)
+	}
+	clock := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	r := runner.NewRunner(
+		runner.WithEngine(engine),
+		runner.WithInitialState(state),
+		runner.WithInputHandler(handler),
+		runner.WithInterceptor(runner.AutoApproveMiddleware()),
+		runner.WithToolRunner(tools),
+		runner.WithTranscript(transcript.NewRecorder(&buf, transcript.WithClock(func() time.Time {
+			clock = clock.Add(time.Second)
+			return clock
+		}))),
+	)
+	runErr := r.Run(ctx)
+
+	tr, err := transcript.Read(&buf)
+	require.NoError(t, err)
+	return tr, runErr
+}
+
+func kinds(tr *transcript.Transcript) []transcript.Kind {
+	var out []transcript.Kind
+	for _, e := range tr.Entries {
+		out = append(out, e.Kind)
+	}
+	return out
+}
+
+func TestRecord(t *testing.T) {
+	tr := record(t, flowtest.NewEngine(t, flowtest.Checkout), "pay")
+
+	assert.Equal(t, []transcript.Kind{
+		transcript.KindStart,
+		transcript.KindRender, transcript.KindInput,
+		transcript.KindRender, transcript.KindTool,
+		transcript.KindRender,
+		transcript.KindEnd,
+	}, kinds(tr))
+
+	start := tr.Start()
+	assert.Equal(t, transcript.Version, start.Version)
+	assert.Equal(t, "s1", start.State.SessionID)
+	assert.Equal(t, 1, start.Line())
+
+	e := tr.Entries[1]
+	assert.Equal(t, "start", e.Node)
+	assert.Equal(t, []string{"Hi Ada\n"}, e.Content)
+	require.NotNil(t, e.Prompt)
+	assert.Equal(t, "pay", tr.Entries[2].Input)
+
+	e = tr.Entries[3]
+	assert.Equal(t, domain.StatusWaitingForTool, e.Status)
+	require.Len(t, e.ToolCalls, 1)
+	assert.Equal(t, "charge", e.ToolCalls[0].Name)
+	assert.Equal(t, "charge", tr.Entries[4].Tool)
+	assert.Equal(t, "pay", tr.Entries[4].Node)
+	assert.Equal(t, map[string]any{"status": "paid"}, tr.Entries[4].Result.Result)
+
+	end := tr.End()
+	assert.Equal(t, "done", end.Node)
+	assert.Equal(t, domain.StatusTerminated, end.Status)
+	assert.Empty(t, end.Error)
+	assert.Equal(t, map[string]any{"status": "paid"}, end.State.Context["receipt"])
+	assert.Equal(t, time.Date(2026, 1, 2, 10, 0, 1, 0, time.UTC), start.Time)
+}
+
+func TestRecord_ToolFailure(t *testing.T) {
+	tr, err := run(t, flowtest.NewEngine(t, flowtest.Checkout), brokenTools{}, "pay")
+	require.Error(t, err)
+
+	tool := tr.Entries[len(tr.Entries)-2]
+	require.Equal(t, transcript.KindTool, tool.Kind)
+	assert.Equal(t, "charge", tool.Tool)
+	require.NotNil(t, tool.Result)
+	assert.True(t, tool.Result.IsError)
+	assert.Equal(t, "connection refused", tool.Result.Error)
+	assert.Contains(t, tr.End().Error, "connection refused")
+}
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := testutils.WriteFlow(t, flowtest.Checkout)
+	tr := record(t, flowtest.Open(t, dir), "pay")
+
+	report, err := transcript.Replay(ctx, flowtest.Open(t, dir), tr)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "%+v", report.Divergences)
+	assert.Equal(t, len(tr.Entries), report.Replayed)
+	assert.Equal(t, 3, report.Steps)
+
+	t.Run("content and arguments", func(t *testing.T) {
+		engine := flowtest.NewEngine(t, map[string]string{
+			"start.md": flowtest.Checkout["start.md"],
+			"pay.yaml": "do: { name: charge, args: { amount: '{{ .amount }}', currency: EUR } }\nsave_to: receipt\nto: done\n",
+			"done.md":  "Paid in full: {{ .receipt.status }}\n",
+			"bye.md":   flowtest.Checkout["bye.md"],
+		})
+		report, err := transcript.Replay(ctx, engine, tr)
+		require.NoError(t, err)
+
+		require.Len(t, report.Divergences, 2)
+		d := report.Divergences[0]
+		assert.Equal(t, transcript.FieldToolCalls, d.Field)
+		assert.Equal(t, "pay", d.Node)
+		assert.Equal(t, 4, d.Line)
+		assert.False(t, d.Fatal)
+		d = report.Divergences[1]
+		assert.Equal(t, transcript.FieldContent, d.Field)
+		assert.Equal(t, "Paid: paid", d.Want)
+		assert.Equal(t, "Paid in full: paid", d.Got)
+		assert.Equal(t, len(tr.Entries), report.Replayed, "content changes do not stop the replay")
+	})
+
+	t.Run("path", func(t *testing.T) {
+		engine := flowtest.NewEngine(t, map[string]string{
+			"start.md": "---\nwait: true\ntransitions:\n  - { condition: input == 'pay', to: bye }\n---\nHi {{ .name }}\n",
+			"bye.md":   flowtest.Checkout["bye.md"],
+		})
+		report, err := transcript.Replay(ctx, engine, tr)
+		require.NoError(t, err)
+
+		require.Len(t, report.Divergences, 1)
+		d := report.Divergences[0]
+		assert.Equal(t, transcript.FieldNode, d.Field)
+		assert.Equal(t, "pay", d.Want)
+		assert.Equal(t, "bye", d.Got)
+		assert.True(t, d.Fatal)
+		assert.Less(t, report.Replayed, len(tr.Entries))
+
+		var out bytes.Buffer
+		require.NoError(t, transcript.WriteReport(&out, "text", report))
+		assert.Contains(t, out.String(), `--- line 4 at pay: the session is at "bye" instead of "pay" (replay stopped)`)
+		assert.Contains(t, out.String(), "FAIL: 1 divergences")
+	})
+
+	t.Run("prompt", func(t *testing.T) {
+		engine := flowtest.NewEngine(t, map[string]string{
+			"start.md": "---\ntransitions:\n  - { to: bye }\n---\nHi {{ .name }}\n",
+			"bye.md":   flowtest.Checkout["bye.md"],
+		})
+		report, err := transcript.Replay(ctx, engine, tr)
+		require.NoError(t, err)
+		require.NotEmpty(t, report.Divergences)
+		assert.Equal(t, transcript.FieldPrompt, report.Divergences[0].Field)
+		assert.Equal(t, 3, report.Divergences[0].Line)
+	})
+}
+
+func TestExport(t *testing.T) {
+	tr := record(t, flowtest.NewEngine(t, flowtest.Checkout), "pay")
+
+	var md bytes.Buffer
+	require.NoError(t, transcript.Export(&md, transcript.FormatMarkdown, tr))
+	log := md.String()
+	assert.Contains(t, log, "# Session `s1`")
+	assert.Contains(t, log, "- **Started:** 2026-01-02 10:00:01 UTC")
+	assert.Contains(t, log, "- **Final node:** `done` (terminated)")
+	assert.Contains(t, log, "**Flow** · `start`\n\nHi Ada\n\n_Waiting for input (text)_")
+	assert.Contains(t, log, "**User:** pay")
+	assert.Contains(t, log, "**Tool** · `pay` · `charge` `{\"amount\":\"10\"}` → `{\"status\":\"paid\"}`")
+	assert.Contains(t, log, "## Final Context")
+	assert.Less(t, strings.Index(log, "Hi Ada"), strings.Index(log, "Paid: paid"))
+
+	var html bytes.Buffer
+	require.NoError(t, transcript.Export(&html, transcript.FormatHTML, tr))
+	assert.Contains(t, html.String(), "<title>Session s1</title>")
+	assert.Contains(t, html.String(), `<div class="text">pay</div>`)
+	assert.Contains(t, html.String(), "&#34;status&#34;")
+
+	assert.Error(t, transcript.Export(io.Discard, "pdf", tr))
+}
+
+func TestFromState(t *testing.T) {
+	state := domain.NewState("s2", "start")
+	state.History = append(state.History, "done")
+	state.CurrentNodeID = "done"
+	state.Status = domain.StatusTerminated
+	state.Context["plan"] = "pro"
+
+	tr := transcript.FromState(state)
+	assert.True(t, tr.Partial)
+
+	var md bytes.Buffer
+	require.NoError(t, transcript.Markdown(&md, tr))
+	assert.Contains(t, md.String(), "Rebuilt from a persisted session")
+	assert.Contains(t, md.String(), "**Flow** · `start`")
+	assert.Contains(t, md.String(), "**Flow** · `done`")
+	assert.Contains(t, md.String(), `"plan": "pro"`)
+
+	_, err := transcript.Replay(context.Background(), nil, tr)
+	assert.Error(t, err)
+}
+
+func TestRead(t *testing.T) {
+	_, err := transcript.Read(strings.NewReader(`{"kind":"render","node":"a"}` + "\n"))
+	assert.ErrorContains(t, err, "no start entry")
+
+	_, err = transcript.Read(strings.NewReader("{\"kind\":\"start\"}\nnot json\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
@@ -2,15 +2,24 @@ package trellis
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/aretw0/loam"
 	"github.com/aretw0/trellis/internal/runtime"
+	"github.com/aretw0/trellis/pkg/adapters/git"
 	loamAdapter "github.com/aretw0/trellis/pkg/adapters/loam"
+	"github.com/aretw0/trellis/pkg/adapters/overlay"
+	"github.com/aretw0/trellis/pkg/bundle"
 	"github.com/aretw0/trellis/pkg/domain"
+	"github.com/aretw0/trellis/pkg/manifest"
+	"github.com/aretw0/trellis/pkg/packages"
 	"github.com/aretw0/trellis/pkg/ports"
 )
 
@@ -25,6 +34,13 @@ type Engine struct {
 	runtimeOpts        []runtime.EngineOption
 	hooks              domain.LifecycleHooks
 	logger             *slog.Logger
+	manifest           *manifest.Manifest
+	strict             bool
+	fsys               fs.FS
+	revision           string
+	overlays           []ports.GraphLoader
+	bundle             *bundle.Bundle
+	bundleKeys         []ed25519.PublicKey
 	Name               string
 }
 
@@ -80,9 +96,91 @@ func WithEntryNode(nodeID string) Option {
 	}
 }
 
+// WithModelProvider configures the provider that executes `type: llm` nodes.
+func WithModelProvider(provider ports.ModelProvider) Option {
+	return func(e *Engine) {
+		e.runtimeOpts = append(e.runtimeOpts, runtime.WithModelProvider(provider))
+	}
+}
+
+// WithIntentClassifier replaces the classifier used by `type: route` nodes (default: intent.Default()).
+func WithIntentClassifier(classifier ports.IntentClassifier) Option {
+	return func(e *Engine) {
+		e.runtimeOpts = append(e.runtimeOpts, runtime.WithIntentClassifier(classifier))
+	}
+}
+
+// WithBudget limits the tool usage of every session (calls, tokens, cost, units). It is
+// merged with the flow-level budget of the entry node, and tool results that arrive after
+// the budget ran out are treated as denied, whatever runs the tools.
+func WithBudget(budget domain.Budget) Option {
+	return func(e *Engine) {
+		e.runtimeOpts = append(e.runtimeOpts, runtime.WithBudget(budget))
+	}
+}
+
+// WithManifest uses the given project manifest instead of looking up `trellis.yaml` next to the flow.
+func WithManifest(m *manifest.Manifest) Option {
+	return func(e *Engine) {
+		e.manifest = m
+	}
+}
+
+// WithStrict rejects unknown node keys and values of the wrong type when loading
+// node files, reporting file:line diagnostics (also enabled by `strict: true` in trellis.yaml).
+// Nodes from other loaders (WithLoader, bundles) are rejected on unknown keys when compiled.
+func WithStrict() Option {
+	return func(e *Engine) {
+		e.strict = true
+	}
+}
+
+// WithFS loads the flow from fsys instead of the repoPath directory, so it can be
+// shipped inside the binary with `embed` (use fs.Sub to pick a subdirectory).
+// The manifest and vendored packages are read from fsys too.
+func WithFS(fsys fs.FS) Option {
+	return func(e *Engine) {
+		e.fsys = fsys
+	}
+}
+
+// WithRevision loads the flow directory at repoPath as of a git revision (commit, tag
+// or branch) without checking it out, and records the resolved commit in new sessions
+// (`sys.revision`) so they can be resumed on the same version (see State.Revision).
+// With WithFS or WithLoader, the graph is the caller's and rev is only recorded.
+func WithRevision(rev string) Option {
+	return func(e *Engine) {
+		e.revision = rev
+	}
+}
+
+// WithOverlay stacks loaders over the graph, in increasing order of precedence:
+// a node they define replaces the node with the same ID (see package overlay).
+func WithOverlay(loaders ...ports.GraphLoader) Option {
+	return func(e *Engine) {
+		e.overlays = append(e.overlays, loaders...)
+	}
+}
+
+// WithBundle runs the flow packed in a bundle (see package bundle): its nodes,
+// manifest, entry and error node are used, and the bundle hash is recorded in new
+// sessions (`sys.bundle`, see State.BundleHash).
+// New checks the bundle against the trusted keys, or against the policy of
+// TRELLIS_TRUSTED_KEYS when none are given, like a `.trellis` path.
+func WithBundle(b *bundle.Bundle, trusted ...ed25519.PublicKey) Option {
+	return func(e *Engine) {
+		e.bundle = b
+		e.bundleKeys = trusted
+	}
+}
+
 // New initializes a new Trellis Engine.
 // By default, it uses a Loam repository at the given path.
+// If the path is a file (.yaml, .yml, .json, .md), it is loaded as a single-file flow.
 // If WithLoader option is provided, repoPath can be empty and Loam is skipped.
+// With WithFS, the flow is read from the given fs.FS and repoPath is only a label.
+// A `.trellis` path is opened as a bundle, enforcing the trust policy of TRELLIS_TRUSTED_KEYS.
+// Settings from the project manifest (`trellis.yaml`) apply unless overridden by options.
 func New(repoPath string, opts ...Option) (*Engine, error) {
 	eng := &Engine{}
 
@@ -91,8 +189,90 @@ func New(repoPath string, opts ...Option) (*Engine, error) {
 		opt(eng)
 	}
 
+	// Settings declared by the flow itself (overridable by user options)
+	var flowOpts []runtime.EngineOption
+
+	// A git revision of the flow directory is read like an embedded flow.
+	if eng.loader == nil && eng.fsys == nil && eng.revision != "" {
+		if repoPath == "" {
+			return nil, fmt.Errorf("repoPath is required to load revision %s", eng.revision)
+		}
+		if loamAdapter.IsFlowFile(repoPath) {
+			return nil, fmt.Errorf("revision %s: single-file flows are not supported, use the flow directory", eng.revision)
+		}
+		snap, err := git.Open(context.Background(), repoPath, eng.revision)
+		if err != nil {
+			return nil, err
+		}
+		eng.fsys = snap
+		eng.revision = snap.Commit
+	}
+	if eng.revision != "" {
+		flowOpts = append(flowOpts, runtime.WithRevision(eng.revision))
+	}
+
+	if eng.bundle != nil {
+		trusted := eng.bundleKeys
+		if len(trusted) == 0 {
+			var err error
+			if trusted, err = bundle.TrustedKeysFromEnv(); err != nil {
+				return nil, err
+			}
+		}
+		if err := eng.bundle.Verify(trusted...); err != nil {
+			return nil, err
+		}
+	} else if eng.loader == nil && eng.fsys == nil && bundle.IsBundle(repoPath) {
+		trusted, err := bundle.TrustedKeysFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		b, err := bundle.Open(repoPath, trusted...)
+		if err != nil {
+			return nil, err
+		}
+		eng.bundle = b
+	}
+
 	// If no loader was injected, initialize default Loam adapter
-	if eng.loader == nil {
+	if eng.loader == nil && eng.bundle != nil {
+		b := eng.bundle
+		if eng.manifest == nil {
+			m, err := b.Manifest()
+			if err != nil {
+				return nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			eng.manifest = m
+		}
+		eng.Name = b.Name
+		if eng.Name == "" && repoPath != "" {
+			eng.Name = strings.TrimSuffix(filepath.Base(repoPath), filepath.Ext(repoPath))
+		}
+		if eng.defaultErrorNodeID == "" {
+			eng.defaultErrorNodeID = b.ErrorNode
+		}
+		eng.loader = b.Loader()
+		flowOpts = append(flowOpts, runtime.WithEntryNode(b.Entry), runtime.WithBundleHash(b.Hash))
+	} else if eng.loader == nil && eng.fsys != nil {
+		if eng.manifest == nil {
+			m, err := manifest.FindFS(eng.fsys)
+			if err != nil {
+				return nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			eng.manifest = m
+		}
+		if repoPath != "" {
+			absPath, err := filepath.Abs(repoPath)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path: %w", err)
+			}
+			eng.Name = filepath.Base(absPath)
+		}
+		if eng.manifest.Name != "" {
+			eng.Name = eng.manifest.Name
+		}
+		eng.loader = loamAdapter.NewFSLoader(eng.fsys, loamAdapter.WithStrict(eng.strict || eng.manifest.Strict))
+	} else if eng.loader == nil {
 		if repoPath == "" {
 			return nil, fmt.Errorf("repoPath is required when no custom loader is provided")
 		}
@@ -104,22 +284,36 @@ func New(repoPath string, opts ...Option) (*Engine, error) {
 
 		eng.Name = filepath.Base(absPath)
 
-		// Initialize Loam with global strict mode (v0.10.4+)
-		// This ensures that all adapters (JSON, Markdown/YAML) return consistent numeric types (json.Number),
-		// preventing "float64" ambiguity for large integers.
-		// We also enforce ReadOnly mode (v0.10.6+) to avoid Loam's "sandbox" behavior in dev mode.
-		// The Engine never modifies the graph structure, only reads it.
-		repo, err := loam.Init(absPath,
-			loam.WithStrict(true),
-			loam.WithReadOnly(true),
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to initialize loam: %w", err)
+		if eng.manifest == nil {
+			m, err := manifest.Find(absPath)
+			if err != nil {
+				return nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			eng.manifest = m
+		}
+		if eng.manifest.Name != "" {
+			eng.Name = eng.manifest.Name
 		}
+		strict := eng.strict || eng.manifest.Strict
 
-		// Setup Typed Repository and Adapter
-		typedRepo := loam.NewTypedRepository[loamAdapter.NodeMetadata](repo)
-		eng.loader = loamAdapter.New(typedRepo)
+		if loamAdapter.IsFlowFile(absPath) {
+			// Single-file flow: the whole graph is declared in one YAML/JSON/Markdown file.
+			loader, repo, err := loamAdapter.NewFileLoader(absPath, loamAdapter.WithStrict(strict))
+			if err != nil {
+				return nil, err
+			}
+			eng.loader = loader
+			eng.Name = repo.Flow().Name
+			if entry := repo.Flow().Entry; entry != "" {
+				flowOpts = append(flowOpts, runtime.WithEntryNode(entry))
+			}
+		} else {
+			loader, err := openLoam(absPath, strict)
+			if err != nil {
+				return nil, err
+			}
+			eng.loader = loader
+		}
 	} else {
 		// If custom loader is provided, we can use repoPath as a descriptive label/session prefix.
 		if repoPath != "" {
@@ -127,6 +321,27 @@ func New(repoPath string, opts ...Option) (*Engine, error) {
 		}
 	}
 
+	// Mount flow packages declared in the manifest under "pkg:<name>/"
+	// (bundles already contain their package nodes).
+	if eng.bundle == nil && eng.manifest != nil && len(eng.manifest.Dependencies) > 0 {
+		strict := eng.strict || eng.manifest.Strict
+		var loader ports.GraphLoader
+		var err error
+		if eng.fsys != nil {
+			loader, err = mountFSPackages(eng.loader, eng.fsys, eng.manifest, strict)
+		} else {
+			loader, err = mountPackages(eng.loader, eng.manifest, strict)
+		}
+		if err != nil {
+			return nil, err
+		}
+		eng.loader = loader
+	}
+
+	if len(eng.overlays) > 0 {
+		eng.loader = overlay.NewLoader(eng.loader, eng.overlays...)
+	}
+
 	// Ensure logger is initialized (so we don't pass nil to runtime, which would overwrite its default)
 	if eng.logger == nil {
 		eng.logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
@@ -138,12 +353,16 @@ func New(repoPath string, opts ...Option) (*Engine, error) {
 	}
 
 	// Initialize Core Runtime with the selected loader
+	manifestOpts := eng.manifestOptions()
 	runtimeOpts := []runtime.EngineOption{
 		runtime.WithLifecycleHooks(eng.hooks),
 		runtime.WithLogger(eng.logger),
 		runtime.WithDefaultErrorNode(eng.defaultErrorNodeID),
+		runtime.WithStrict(eng.strict || (eng.manifest != nil && eng.manifest.Strict)),
 	}
-	// Append user-defined runtime options (like WithEntryNode)
+	// Append manifest, flow-level and user-defined runtime options (like WithEntryNode)
+	runtimeOpts = append(runtimeOpts, manifestOpts...)
+	runtimeOpts = append(runtimeOpts, flowOpts...)
 	runtimeOpts = append(runtimeOpts, eng.runtimeOpts...)
 
 	eng.runtime = runtime.NewEngine(
@@ -156,6 +375,91 @@ func New(repoPath string, opts ...Option) (*Engine, error) {
 	return eng, nil
 }
 
+// openLoam opens a directory of node files as a read-only graph.
+// Nodes carry their file position; strict mode rejects unknown and mistyped keys.
+func openLoam(dir string, strict bool) (ports.GraphLoader, error) {
+	// Initialize Loam with global strict mode (v0.10.4+)
+	// This ensures that all adapters (JSON, Markdown/YAML) return consistent numeric types (json.Number),
+	// preventing "float64" ambiguity for large integers.
+	// We also enforce ReadOnly mode (v0.10.6+) to avoid Loam's "sandbox" behavior in dev mode.
+	// The Engine never modifies the graph structure, only reads it.
+	repo, err := loam.Init(dir,
+		loam.WithStrict(true),
+		loam.WithReadOnly(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize loam: %w", err)
+	}
+
+	// Setup Typed Repository and Adapter
+	// Templates (`extends`) and folder `_defaults` are merged before decoding.
+	typedRepo := loam.NewTypedRepository[loamAdapter.NodeMetadata](loamAdapter.NewInheritRepository(repo))
+	return loamAdapter.New(typedRepo, loamAdapter.WithSourceDir(dir), loamAdapter.WithStrict(strict)), nil
+}
+
+// mountPackages wraps the root graph with the manifest dependencies.
+func mountPackages(root ports.GraphLoader, m *manifest.Manifest, strict bool) (ports.GraphLoader, error) {
+	dirs, err := packages.Resolve(m)
+	if err != nil {
+		return nil, err
+	}
+	loaders := make(map[string]ports.GraphLoader, len(dirs))
+	for name, dir := range dirs {
+		loader, err := openLoam(dir, strict)
+		if err != nil {
+			return nil, fmt.Errorf("package %s: %w", name, err)
+		}
+		loaders[name] = loader
+	}
+	return packages.NewLoader(root, loaders), nil
+}
+
+// mountFSPackages mounts the dependencies vendored inside fsys (vendor/<name>).
+// Embedded packages are fixed at build time, so the lockfile is not checked again.
+func mountFSPackages(root ports.GraphLoader, fsys fs.FS, m *manifest.Manifest, strict bool) (ports.GraphLoader, error) {
+	loaders := make(map[string]ports.GraphLoader, len(m.Dependencies))
+	for name := range m.Dependencies {
+		sub, err := fs.Sub(fsys, path.Join(manifest.VendorDir, name))
+		if err == nil {
+			_, err = fs.Stat(sub, ".")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("package %s: not vendored in the embedded flow (run 'trellis vendor' before building)", name)
+		}
+		loaders[name] = loamAdapter.NewFSLoader(sub, loamAdapter.WithStrict(strict))
+	}
+	return packages.NewLoader(root, loaders), nil
+}
+
+// manifestOptions translates manifest settings into runtime options.
+// Explicit engine options (error node, interpolator) take precedence.
+func (e *Engine) manifestOptions() []runtime.EngineOption {
+	m := e.manifest
+	if m == nil {
+		return nil
+	}
+
+	var opts []runtime.EngineOption
+	if m.Entry != "" {
+		opts = append(opts, runtime.WithEntryNode(m.Entry))
+	}
+	if m.Locale != "" {
+		opts = append(opts, runtime.WithDefaultLocale(m.Locale))
+	}
+	if e.defaultErrorNodeID == "" {
+		e.defaultErrorNodeID = m.ErrorNode
+	}
+	if e.interpolator == nil {
+		switch m.Interpolator {
+		case "html":
+			e.interpolator = runtime.HTMLInterpolator
+		case "legacy":
+			e.interpolator = runtime.LegacyInterpolator
+		}
+	}
+	return opts
+}
+
 // Start creates the initial state for the flow and triggers lifecycle hooks.
 func (e *Engine) Start(ctx context.Context, sessionID string, initialContext map[string]any) (*domain.State, error) {
 	return e.runtime.Start(ctx, sessionID, initialContext)
@@ -177,6 +481,16 @@ func (e *Engine) Signal(ctx context.Context, state *domain.State, signalName str
 	return e.runtime.Signal(ctx, state, signalName)
 }
 
+// Evaluate runs the engine's condition evaluator against input, as a transition condition would.
+func (e *Engine) Evaluate(ctx context.Context, condition string, input any) (bool, error) {
+	return e.runtime.Evaluate(ctx, condition, input)
+}
+
+// Interpolate renders a template against the state's context and `sys`, as node content is rendered.
+func (e *Engine) Interpolate(ctx context.Context, templateStr string, state *domain.State) (string, error) {
+	return e.runtime.Interpolate(ctx, templateStr, state)
+}
+
 // Inspect returns the full graph definition for visualization or introspection tools.
 func (e *Engine) Inspect() ([]domain.Node, error) {
 	return e.runtime.Inspect()
@@ -188,7 +502,47 @@ func (e *Engine) Watch(ctx context.Context) (<-chan string, error) {
 	if w, ok := e.loader.(ports.Watchable); ok {
 		return w.Watch(ctx)
 	}
-	return nil, fmt.Errorf("current loader does not support watching")
+	return nil, fmt.Errorf("current %w", domain.ErrWatchUnsupported)
+}
+
+// Source returns where a node was authored: its file position or, for a node generated
+// by a `type: flow` macro, the script line that produced it.
+func (e *Engine) Source(nodeID string) (*domain.SourceRef, error) {
+	return e.runtime.Source(nodeID)
+}
+
+// Trace maps the visited nodes of a session (State.History) to their sources.
+func (e *Engine) Trace(state *domain.State) []domain.TraceStep {
+	return e.runtime.Trace(state)
+}
+
+// EntryNode returns the ID of the node new sessions start at.
+func (e *Engine) EntryNode() string {
+	return e.runtime.EntryNode()
+}
+
+// ErrorNode returns the global fallback node for tool errors ("" when none is set).
+func (e *Engine) ErrorNode() string {
+	return e.defaultErrorNodeID
+}
+
+// Revision returns the graph revision recorded in new sessions ("" when unversioned).
+// With WithRevision on a directory, it is the resolved commit hash.
+func (e *Engine) Revision() string {
+	return e.revision
+}
+
+// BundleHash returns the hash of the bundle the flow was loaded from ("" when not bundled).
+func (e *Engine) BundleHash() string {
+	if e.bundle == nil {
+		return ""
+	}
+	return e.bundle.Hash
+}
+
+// Manifest returns the project manifest in effect (nil when a custom loader is used without WithManifest).
+func (e *Engine) Manifest() *manifest.Manifest {
+	return e.manifest
 }
 
 // Loader returns the underlying GraphLoader used by the engine.
@@ -2,11 +2,21 @@ package trellis_test
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/aretw0/trellis"
+	"github.com/aretw0/trellis/internal/testutils"
+	"github.com/aretw0/trellis/pkg/adapters/memory"
+	"github.com/aretw0/trellis/pkg/bundle"
 	"github.com/aretw0/trellis/pkg/domain"
 )
 
@@ -104,3 +114,393 @@ Hello`)
 		t.Fatalf("Render failed: %v", err)
 	}
 }
+
+func TestFacade_SingleFileFlow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greeter.yaml")
+	content := []byte(`
+entry: ask
+nodes:
+  ask:
+    wait: true
+    content: "Name?"
+    save_to: name
+    to: greet
+  greet:
+    content: "Hello {{ .name }}"
+`)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := trellis.New(path)
+	if err != nil {
+		t.Fatalf("Failed to initialize engine with file %s: %v", path, err)
+	}
+	if engine.Name != "greeter" {
+		t.Errorf("Expected engine name 'greeter', got '%s'", engine.Name)
+	}
+
+	ctx := context.Background()
+	state, err := engine.Start(ctx, "test", nil)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if state.CurrentNodeID != "ask" {
+		t.Fatalf("Expected flow entry 'ask', got '%s'", state.CurrentNodeID)
+	}
+
+	state, err = engine.Navigate(ctx, state, "Ada")
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	actions, _, err := engine.Render(ctx, state)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if len(actions) == 0 || actions[0].Payload != "Hello Ada" {
+		t.Errorf("Expected 'Hello Ada', got %v", actions)
+	}
+}
+
+func TestFacade_Manifest(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"trellis.yaml": "name: onboarding\nentry: welcome\nlocale: pt-BR\n",
+		"welcome.md":   "---\nto: done\n---\nBem-vindo!\n",
+		"done.md":      "Fim.\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	engine, err := trellis.New(dir)
+	if err != nil {
+		t.Fatalf("Failed to initialize engine: %v", err)
+	}
+	if engine.Name != "onboarding" {
+		t.Errorf("Expected engine name from manifest, got '%s'", engine.Name)
+	}
+
+	ids, err := engine.Loader().ListNodes()
+	if err != nil {
+		t.Fatalf("ListNodes failed: %v", err)
+	}
+	for _, id := range ids {
+		if id == "trellis" {
+			t.Errorf("Manifest should not be listed as a node: %v", ids)
+		}
+	}
+
+	state, err := engine.Start(context.Background(), "test", nil)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if state.CurrentNodeID != "welcome" {
+		t.Errorf("Expected manifest entry 'welcome', got '%s'", state.CurrentNodeID)
+	}
+	if state.Locale != "pt-BR" {
+		t.Errorf("Expected manifest locale 'pt-BR', got '%s'", state.Locale)
+	}
+
+	// Explicit options win over the manifest
+	engine, err = trellis.New(dir, trellis.WithEntryNode("done"))
+	if err != nil {
+		t.Fatalf("Failed to initialize engine: %v", err)
+	}
+	state, _ = engine.Start(context.Background(), "test", nil)
+	if state.CurrentNodeID != "done" {
+		t.Errorf("Expected option entry 'done', got '%s'", state.CurrentNodeID)
+	}
+
+	// Invalid manifests fail fast
+	if err := os.WriteFile(filepath.Join(dir, "trellis.yaml"), []byte("entyr: welcome\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trellis.New(dir); err == nil {
+		t.Error("Expected error for unknown manifest key")
+	}
+}
+
+func TestFacade_Strict(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"start.md": "---\nto: done\n---\nHi\n",
+		"done.md":  "---\ntype: text\nsave_too: name\n---\nBye\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Lenient: unknown keys are ignored
+	lenient, err := trellis.New(dir)
+	if err != nil {
+		t.Fatalf("Failed to initialize engine: %v", err)
+	}
+	state, err := lenient.Start(context.Background(), "test", nil)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if _, err := lenient.Navigate(context.Background(), state, ""); err != nil {
+		t.Fatalf("Lenient navigate failed: %v", err)
+	}
+
+	// Strict (also enabled by `strict: true` in trellis.yaml)
+	strict, err := trellis.New(dir, trellis.WithStrict())
+	if err != nil {
+		t.Fatalf("Failed to initialize engine: %v", err)
+	}
+	state, err = strict.Start(context.Background(), "test", nil)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	_, err = strict.Navigate(context.Background(), state, "")
+	if err == nil {
+		t.Fatal("Expected strict navigate to fail")
+	}
+	want := `done.md:3: unknown field "save_too"; did you mean "save_to"?`
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected %q in error, got: %v", want, err)
+	}
+	if diags := domain.Diagnostics(err); len(diags) != 1 || diags[0].Line != 3 {
+		t.Errorf("Expected one diagnostic at line 3, got %v", diags)
+	}
+}
+
+func TestFacade_Packages(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"app/trellis.yaml":    "dependencies:\n  auth:\n    path: ../auth-flows\n",
+		"app/start.md":        "---\ntransitions:\n  - jump_to: pkg:auth/start\n---\nWelcome\n",
+		"auth-flows/start.md": "---\nwait: true\nsave_to: user\nto: done\n---\nLogin?\n",
+		"auth-flows/done.md":  "Hi {{ .user }}",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	engine, err := trellis.New(filepath.Join(root, "app"))
+	if err != nil {
+		t.Fatalf("Failed to initialize engine: %v", err)
+	}
+
+	ctx := context.Background()
+	state, err := engine.Start(ctx, "test", nil)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	state, err = engine.Navigate(ctx, state, "")
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	if state.CurrentNodeID != "pkg:auth/start" {
+		t.Fatalf("Expected 'pkg:auth/start', got '%s'", state.CurrentNodeID)
+	}
+
+	// Relative references inside the package stay inside the package
+	state, err = engine.Navigate(ctx, state, "ada")
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	if state.CurrentNodeID != "pkg:auth/done" {
+		t.Fatalf("Expected 'pkg:auth/done', got '%s'", state.CurrentNodeID)
+	}
+	actions, _, err := engine.Render(ctx, state)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if len(actions) == 0 || actions[0].Payload != "Hi ada" {
+		t.Errorf("Unexpected render: %+v", actions)
+	}
+}
+
+func TestFacade_FSAndOverlay(t *testing.T) {
+	fsys := fstest.MapFS{
+		"trellis.yaml":         {Data: []byte("name: embedded\nentry: main\ndependencies:\n  auth:\n    path: ../auth\n")},
+		"main.md":              {Data: []byte("---\nto: pkg:auth/start\n---\nWelcome\n")},
+		"vendor/auth/start.md": {Data: []byte("---\nto: done\n---\nLogin\n")},
+		"vendor/auth/done.md":  {Data: []byte("Bye")},
+	}
+	// Go-defined node overriding the embedded one
+	code, err := memory.NewFromNodes(domain.Node{ID: "main", Type: domain.NodeTypeText, Content: []byte("Welcome (custom)"), Transitions: []domain.Transition{{ToNodeID: "pkg:auth/start"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := trellis.New("", trellis.WithFS(fsys), trellis.WithOverlay(code))
+	if err != nil {
+		t.Fatalf("Failed to initialize engine: %v", err)
+	}
+	if engine.Name != "embedded" {
+		t.Errorf("Expected name from the embedded manifest, got '%s'", engine.Name)
+	}
+
+	ctx := context.Background()
+	state, err := engine.Start(ctx, "test", nil)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	actions, _, err := engine.Render(ctx, state)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if len(actions) == 0 || actions[0].Payload != "Welcome (custom)" {
+		t.Errorf("Expected the overlay node, got %+v", actions)
+	}
+
+	state, err = engine.Navigate(ctx, state, "")
+	if err != nil {
+		t.Fatalf("Navigate failed: %v", err)
+	}
+	if state.CurrentNodeID != "pkg:auth/start" {
+		t.Fatalf("Expected 'pkg:auth/start', got '%s'", state.CurrentNodeID)
+	}
+
+	ids, err := engine.Loader().ListNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ids, ",") != "main,pkg:auth/done,pkg:auth/start" {
+		t.Errorf("Unexpected nodes: %v", ids)
+	}
+
+	if _, err := engine.Watch(ctx); !errors.Is(err, domain.ErrWatchUnsupported) {
+		t.Errorf("Expected ErrWatchUnsupported for an embedded flow, got %v", err)
+	}
+}
+
+func TestFacade_Revision(t *testing.T) {
+	dir := t.TempDir()
+	testutils.GitCommit(t, dir, map[string]string{"start.md": "Version 1"}, "v1")
+	testutils.GitCommit(t, dir, map[string]string{"start.md": "Version 2"}, "v2")
+
+	engine, err := trellis.New(dir, trellis.WithRevision("v1"))
+	if err != nil {
+		t.Fatalf("Failed to initialize engine: %v", err)
+	}
+	if engine.Name != filepath.Base(dir) {
+		t.Errorf("Expected name '%s', got '%s'", filepath.Base(dir), engine.Name)
+	}
+
+	ctx := context.Background()
+	state, err := engine.Start(ctx, "test", nil)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if len(engine.Revision()) != 40 || state.Revision() != engine.Revision() {
+		t.Errorf("Expected the session pinned to the v1 commit, got '%s' (engine '%s')", state.Revision(), engine.Revision())
+	}
+	actions, _, err := engine.Render(ctx, state)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if len(actions) == 0 || actions[0].Payload != "Version 1" {
+		t.Errorf("Expected the v1 content, got %+v", actions)
+	}
+
+	// Resuming on the pinned commit serves the same version
+	pinned, err := trellis.New(dir, trellis.WithRevision(state.Revision()))
+	if err != nil {
+		t.Fatalf("Failed to initialize pinned engine: %v", err)
+	}
+	actions, _, err = pinned.Render(ctx, state)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if len(actions) == 0 || actions[0].Payload != "Version 1" {
+		t.Errorf("Expected the v1 content, got %+v", actions)
+	}
+
+	if _, err := trellis.New(dir, trellis.WithRevision("v9")); err == nil || !strings.Contains(err.Error(), `unknown revision "v9"`) {
+		t.Errorf("Expected an unknown revision error, got %v", err)
+	}
+}
+
+func TestFacade_Bundle(t *testing.T) {
+	b, err := bundle.New(bundle.Contents{
+		Name:  "support",
+		Entry: "welcome",
+		Nodes: map[string]json.RawMessage{
+			"welcome": json.RawMessage(`{"content": "Packed", "to": "end"}`),
+			"end":     json.RawMessage(`{"content": "Bye"}`),
+		},
+		Manifest: []byte("locale: pt-BR\n"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to build bundle: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "support.trellis")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.WriteTo(f); err != nil {
+		t.Fatalf("Failed to write bundle: %v", err)
+	}
+	f.Close()
+
+	engine, err := trellis.New(path)
+	if err != nil {
+		t.Fatalf("Failed to initialize engine: %v", err)
+	}
+	if engine.Name != "support" || engine.BundleHash() != b.Hash {
+		t.Errorf("Expected bundle 'support' (%s), got '%s' (%s)", b.Hash, engine.Name, engine.BundleHash())
+	}
+
+	state, err := engine.Start(context.Background(), "test", nil)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if state.CurrentNodeID != "welcome" {
+		t.Errorf("Expected the bundle entry 'welcome', got '%s'", state.CurrentNodeID)
+	}
+	if state.BundleHash() != b.Hash {
+		t.Errorf("Expected the bundle hash in the session, got '%s'", state.BundleHash())
+	}
+	if engine.Manifest().Locale != "pt-BR" {
+		t.Errorf("Expected the bundled manifest, got %+v", engine.Manifest())
+	}
+
+	// A trust policy refuses unsigned bundles
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPath := filepath.Join(t.TempDir(), "trusted.pub")
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(bundle.TrustedKeysEnv, pubPath)
+	if _, err := trellis.New(path); err == nil || !strings.Contains(err.Error(), "bundle is not signed") {
+		t.Errorf("Expected the unsigned bundle to be refused, got %v", err)
+	}
+
+	// Bundles passed in memory go through the same policy
+	if _, err := trellis.New("", trellis.WithBundle(b)); err == nil || !strings.Contains(err.Error(), "bundle is not signed") {
+		t.Errorf("Expected WithBundle to apply the environment policy, got %v", err)
+	}
+	b.Sign(key)
+	if _, err := trellis.New("", trellis.WithBundle(b)); err != nil {
+		t.Errorf("Expected the signed bundle to be trusted, got %v", err)
+	}
+	other, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trellis.New("", trellis.WithBundle(b, other)); err == nil || !strings.Contains(err.Error(), "untrusted key") {
+		t.Errorf("Expected explicit keys to replace the environment policy, got %v", err)
+	}
+}